@@ -4,18 +4,26 @@ import (
 	"context"
 	"fmt"
 	"notebit/pkg/ai"
+	"notebit/pkg/backup"
 	"notebit/pkg/chat"
 	"notebit/pkg/config"
 	"notebit/pkg/database"
+	"notebit/pkg/digest"
+	"notebit/pkg/feeds"
 	"notebit/pkg/files"
 	"notebit/pkg/graph"
+	"notebit/pkg/httpapi"
 	"notebit/pkg/indexing"
+	"notebit/pkg/jobs"
+	"notebit/pkg/journal"
 	"notebit/pkg/knowledge"
 	"notebit/pkg/logger"
 	"notebit/pkg/rag"
+	"notebit/pkg/urlmeta"
 	"notebit/pkg/watcher"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -33,8 +41,45 @@ type App struct {
 	rag      *rag.Service
 	graph    *graph.Service
 	llm      ai.LLMProvider
+	llmCache *ai.CompletionCache
 	pipeline *indexing.IndexingPipeline
 	chatSvc  *chat.Service
+	journal  *journal.Service
+	edits    *journal.EditLog
+	schedule *indexing.Scheduler
+	jobs     *jobs.Manager
+	actions  *rag.ActionStore
+	digests  *digest.Scheduler
+	backups  *backup.Scheduler
+	feeds    *feeds.Scheduler
+	urlMeta  *urlmeta.Fetcher
+
+	// streams cancels the streaming answer of each session, see RAGQueryStream
+	streamMu sync.Mutex
+	streams  map[string]context.CancelFunc
+
+	// vaultMu serializes opening vaults, see openVault
+	vaultMu sync.Mutex
+	vaults  *config.VaultRegistry
+
+	profiles *config.ProfileStore
+
+	// apiServer is the local REST API, nil while disabled
+	apiMu     sync.Mutex
+	apiServer *httpapi.Server
+
+	links          deepLinks
+	refs           references
+	completions    suggestions
+	stats          statistics
+	transcriptions transcriptions
+	imageReading   imageReading
+
+	gitSync gitSync
+	webdav  webdavSync
+	lock    vaultLock
+
+	indexEvents indexEvents
 }
 
 type watcherLogger struct {
@@ -55,15 +100,22 @@ func NewAppWithConfig(cfg *config.Config) *App {
 		cfg = config.New()
 	}
 	fm := files.NewManager()
-	dbm := database.GetInstance()
+	dbm := database.NewManager()
 	aiService := ai.NewService(cfg)
 
 	app := &App{
-		fm:  fm,
-		dbm: dbm,
-		cfg: cfg,
-		ai:  aiService,
+		fm:      fm,
+		dbm:     dbm,
+		cfg:     cfg,
+		ai:      aiService,
+		actions: rag.NewActionStore(),
+		urlMeta: urlmeta.NewFetcher(nil),
 	}
+	app.jobs = jobs.NewManager(func(event string, job jobs.Job) {
+		if app.ctx != nil {
+			runtime.EventsEmit(app.ctx, event, job)
+		}
+	})
 	return app
 }
 
@@ -78,16 +130,37 @@ func (a *App) startup(ctx context.Context) {
 		logger.ErrorWithFields(ctx, map[string]interface{}{"error": err.Error()}, "Failed to load config")
 		runtime.LogErrorf(a.ctx, "Failed to load config: %v", err)
 	}
+	a.cfg.OnSave(a.auditConfigChanges)
+	a.applyWriteOptions()
+	if dir, err := extensionsDir(); err == nil {
+		database.SetSQLiteVecExtension(database.InstalledSQLiteVec(dir))
+	}
+	a.loadVaultRegistry()
+	a.loadProfileStore()
+	_ = a.startAPIServer() // failures are logged; the app works without it
 
 	a.initializeAI()
+	a.startHealthMonitor()
 	a.initializeLLM()
 
 	// Initialize indexing pipeline after database is ready
 	if a.dbm.IsInitialized() {
 		a.pipeline = indexing.NewPipeline(a.ai, a.dbm.Repository(), a.fm)
+		a.pipeline.SetImageQueueListener(a.readQueuedImages)
+		a.applyImageReaders()
 		a.pipeline.Start()
+		a.resumeIndexQueue()
+		a.reconcileIndexOnOpen()
+		a.startScheduler()
+		a.startDigestScheduler()
+		a.startBackupScheduler()
+		a.startFeedScheduler()
 		a.ks = knowledge.NewService(a.fm, a.dbm, a.ai, a.pipeline)
 		a.initializeChat()
+		a.initializeJournal()
+		a.initializeEditLog()
+		a.initializeUsageTracking()
+		a.pruneHistory()
 	}
 
 	// Start file watcher if database is initialized and base path is set
@@ -123,6 +196,9 @@ func (a *App) applyVectorEngineConfig() {
 			"effective": effective,
 		}, "Vector engine fallback applied")
 	}
+
+	normalize, metric := a.cfg.GetVectorSpace()
+	repo.SetVectorSpace(database.VectorSpace{Normalize: normalize, Metric: metric})
 }
 
 func (a *App) loadConfig() error {
@@ -139,6 +215,10 @@ func (a *App) initializeAI() {
 	timer := logger.StartTimer()
 	logger.Debug("Initializing AI service")
 
+	a.ai.DebugRecorder().SetFullText(a.cfg.GetAIDebugFullText())
+	if err := a.ai.DebugRecorder().SetEnabled(a.cfg.GetAIDebug(), appLogDir()); err != nil {
+		logger.Warn("AI debug mode unavailable: %v", err)
+	}
 	if err := a.ai.Initialize(); err != nil {
 		logger.WarnWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "AI service initialization failed")
 		runtime.LogWarningf(a.ctx, "AI service initialization failed: %v", err)
@@ -147,47 +227,69 @@ func (a *App) initializeAI() {
 	}
 }
 
-// initializeLLM initializes the LLM provider for chat completion
-func (a *App) initializeLLM() {
-	llmConfig := a.cfg.GetLLMConfig()
-	if llmConfig.Provider == "" {
-		return // No LLM provider configured
-	}
+// llmOpenAIConfig returns the OpenAI settings for chat models
+func (a *App) llmOpenAIConfig() config.OpenAIConfig {
+	// Start with dedicated LLM OpenAI config
+	openAIConfig := a.cfg.GetLLMConfig().OpenAI
 
-	if llmConfig.Provider == "openai" {
-		// Start with dedicated LLM OpenAI config
-		openAIConfig := llmConfig.OpenAI
+	// Fallback to global AI config if API Key is missing
+	// This maintains backward compatibility and ease of use
+	globalOpenAI := a.cfg.GetOpenAIConfig()
 
-		// Fallback to global AI config if API Key is missing
-		// This maintains backward compatibility and ease of use
-		globalOpenAI := a.cfg.GetOpenAIConfig()
+	if openAIConfig.APIKey == "" {
+		openAIConfig.APIKey = globalOpenAI.APIKey
+	}
 
-		if openAIConfig.APIKey == "" {
-			openAIConfig.APIKey = globalOpenAI.APIKey
+	// Use global BaseURL if local is empty, or default
+	if openAIConfig.BaseURL == "" {
+		if globalOpenAI.BaseURL != "" {
+			openAIConfig.BaseURL = globalOpenAI.BaseURL
+		} else {
+			openAIConfig.BaseURL = "https://api.openai.com/v1"
 		}
+	}
 
-		// Use global BaseURL if local is empty, or default
-		if openAIConfig.BaseURL == "" {
-			if globalOpenAI.BaseURL != "" {
-				openAIConfig.BaseURL = globalOpenAI.BaseURL
-			} else {
-				openAIConfig.BaseURL = "https://api.openai.com/v1"
-			}
-		}
+	if openAIConfig.Organization == "" {
+		openAIConfig.Organization = globalOpenAI.Organization
+	}
+	return openAIConfig
+}
 
-		if openAIConfig.Organization == "" {
-			openAIConfig.Organization = globalOpenAI.Organization
-		}
+// initializeLLM initializes the LLM provider for chat completion
+func (a *App) initializeLLM() {
+	llmConfig := a.cfg.GetLLMConfig()
+	if llmConfig.Provider == "" {
+		return // No LLM provider configured
+	}
 
+	switch llmConfig.Provider {
+	case "openai":
+		openAIConfig := a.llmOpenAIConfig()
 		llm, err := ai.NewOpenAILLMProvider(openAIConfig)
 		if err == nil {
-			a.llm = llm
+			limited := ai.WithLLMRetry(ai.WithLLMRateLimit(ai.WithLLMDebug(llm, "openai", a.ai.DebugRecorder()), ai.NewRateLimiter("openai-chat", openAIConfig.RateLimit)))
+			a.llm = a.withLLMCache(ai.WithLLMUsage(limited, "openai", a.ai.UsageTracker()), llmConfig.Cache)
 		} else {
 			runtime.LogWarningf(a.ctx, "Failed to initialize OpenAI LLM: %v", err)
 		}
+	case "custom":
+		customCfg := a.cfg.GetCustomConfig()
+		llm, err := ai.NewCustomLLMProvider(customCfg.BaseURL, customCfg.APIKey, llmConfig.Model, time.Duration(customCfg.Timeout)*time.Second)
+		if err == nil {
+			limited := ai.WithLLMRetry(ai.WithLLMRateLimit(ai.WithLLMDebug(llm, "custom", a.ai.DebugRecorder()), ai.NewRateLimiter("custom-chat", customCfg.RateLimit)))
+			a.llm = a.withLLMCache(ai.WithLLMUsage(limited, "custom", a.ai.UsageTracker()), llmConfig.Cache)
+		} else {
+			runtime.LogWarningf(a.ctx, "Failed to initialize custom LLM: %v", err)
+		}
 	}
 }
 
+// withLLMCache starts a completion cache for cfg and puts it in front of provider
+func (a *App) withLLMCache(provider ai.LLMProvider, cfg config.LLMCacheConfig) ai.LLMProvider {
+	a.llmCache = ai.NewCompletionCache(cfg)
+	return ai.WithLLMCache(provider, a.llmCache)
+}
+
 // initializeRAG initializes the RAG service
 func (a *App) initializeRAG() {
 	if a.llm != nil && a.dbm.IsInitialized() {
@@ -199,6 +301,7 @@ func (a *App) initializeRAG() {
 func (a *App) initializeGraph() {
 	if a.dbm.IsInitialized() {
 		a.graph = graph.NewService(a.dbm, a.cfg)
+		a.graph.SetReferenceSource(a.graphReferences)
 	}
 }
 
@@ -210,41 +313,134 @@ func (a *App) initializeChat() {
 		a.chatSvc.Close()
 		a.chatSvc = nil
 	}
-	svc, err := chat.NewService(a.dbm.GetDB(), a.dbm.GetBasePath())
+	basePath := a.dbm.GetBasePath()
+	svc, err := chat.NewService(a.dbm.GetDB(), basePath)
 	if err != nil {
 		runtime.LogWarningf(a.ctx, "Failed to initialize chat service: %v", err)
 		return
 	}
+	svc.SetBackupDir(privateDataPath(basePath, "data/chat_backups"))
 	a.chatSvc = svc
 }
 
+// initializeJournal sets up the operation journal used for undo
+func (a *App) initializeJournal() {
+	if !a.dbm.IsInitialized() {
+		return
+	}
+	svc, err := journal.NewService(a.dbm.GetDB())
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to initialize operation journal: %v", err)
+		a.journal = nil
+		return
+	}
+	a.journal = svc
+}
+
 // initializeServices sets up database, pipeline, knowledge, chat, RAG, and graph
 // services for the given base path. This is the single entry point used by
-// OpenFolder, SetFolder, and startup to avoid duplicated initialization logic.
+// openVault (for OpenFolder, SetFolder and SwitchVault) to avoid duplicated
+// initialization logic.
 func (a *App) initializeServices(basePath string) error {
-	// Initialize database
-	if err := a.dbm.Init(basePath); err != nil {
+	// A locked vault keeps its database encrypted until UnlockVault
+	if a.vaultLocked(basePath) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "vault:locked", basePath)
+		}
+		return nil
+	}
+
+	// Initialize database; a locked vault's is decrypted outside the vault
+	initDB := a.dbm.Init
+	if fileExists(lockedDBPath(basePath)) {
+		initDB = func(basePath string) error { return a.dbm.InitAt(basePath, unlockedDBPath(basePath)) }
+	}
+	if err := initDB(basePath); err != nil {
 		logger.Warn("Database initialization failed for %s: %v", basePath, err)
 	}
 	a.applyVectorEngineConfig()
 
 	// Initialize pipeline, knowledge, and chat after database is ready
 	if a.dbm.IsInitialized() {
+		a.watchIndexChanges()
+		a.autoOptimizeDatabase()
 		if a.pipeline == nil {
 			a.pipeline = indexing.NewPipeline(a.ai, a.dbm.Repository(), a.fm)
+			a.pipeline.SetImageQueueListener(a.readQueuedImages)
+			a.applyImageReaders()
 			a.pipeline.Start()
+			a.resumeIndexQueue()
 		}
+		a.reconcileIndexOnOpen()
+		a.startScheduler()
+		a.startDigestScheduler()
+		a.startBackupScheduler()
+		a.startFeedScheduler()
 		if a.ks == nil {
 			a.ks = knowledge.NewService(a.fm, a.dbm, a.ai, a.pipeline)
 		}
 		a.initializeChat()
+		a.initializeJournal()
+		a.initializeEditLog()
+		a.initializeUsageTracking()
+		a.pruneHistory()
 	}
 
 	a.initializeRAG()
 	a.initializeGraph()
+	a.initializeGit(basePath)
 	return nil
 }
 
+// resumeIndexQueue requeues indexing jobs persisted by a previous session
+func (a *App) resumeIndexQueue() {
+	if a.pipeline == nil || a.fm.GetBasePath() == "" {
+		return
+	}
+	resumed, err := a.pipeline.Resume(context.Background())
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to resume indexing queue: %v", err)
+		return
+	}
+	if resumed > 0 {
+		runtime.LogInfof(a.ctx, "Resumed %d pending indexing jobs", resumed)
+	}
+}
+
+// reconcileIndexOnOpen checks the index against the vault in the background, removing
+// ghost entries and queueing files that changed while the app was closed
+func (a *App) reconcileIndexOnOpen() {
+	if a.pipeline == nil || a.fm.GetBasePath() == "" {
+		return
+	}
+	pipeline := a.pipeline
+	go func() {
+		result, err := pipeline.Reconcile(context.Background(), indexing.ReconcileOptions{Quick: true})
+		if err != nil {
+			runtime.LogWarningf(a.ctx, "Index reconciliation failed: %v", err)
+			return
+		}
+		report := result.Drift
+		if report.HasDrift() {
+			runtime.LogInfof(a.ctx, "Index drift: %d missing, %d changed, %d deleted", report.Missing, report.Changed, report.Deleted)
+		}
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "index:drift", report)
+		}
+	}()
+}
+
+// startScheduler (re)starts the periodic reindex scheduler with the current config
+func (a *App) startScheduler() {
+	if a.pipeline == nil {
+		return
+	}
+	if a.schedule == nil {
+		a.schedule = indexing.NewScheduler(a.pipeline)
+	}
+	a.schedule.Start(a.cfg.GetIndexingConfig().Schedule)
+}
+
 // startWatcher starts the file watcher service
 func (a *App) startWatcher() error {
 	watcherCfg := a.cfg.GetWatcherConfig()
@@ -271,6 +467,7 @@ func (a *App) startWatcher() error {
 	a.watcher.SetDebounceDelay(time.Duration(watcherCfg.DebounceMS) * time.Millisecond)
 	a.watcher.SetWorkerCount(watcherCfg.Workers)
 	a.watcher.SetLogger(watcherLogger{ctx: a.ctx})
+	a.watcher.SetAudioHandler(a.onAudioFile)
 
 	if err := a.watcher.Start(); err != nil {
 		return fmt.Errorf("failed to start watcher: %w", err)
@@ -315,11 +512,32 @@ func (a *App) runFullIndex() {
 
 // shutdown is called when the app is shutting down
 func (a *App) shutdown(context.Context) {
+	a.stopAPIServer()
+	a.stopGit(true)
+	a.jobs.Shutdown()
+	a.ai.StopHealthMonitor()
 	a.stopWatcher()
+	if a.schedule != nil {
+		a.schedule.Stop()
+	}
+	if a.digests != nil {
+		a.digests.Stop()
+	}
+	if a.backups != nil {
+		a.backups.Stop()
+	}
+	if a.feeds != nil {
+		a.feeds.Stop()
+	}
 	if a.pipeline != nil {
 		a.pipeline.Stop()
 	}
 	if a.chatSvc != nil {
 		a.chatSvc.Close()
 	}
+	a.closeEditLog()
+	if err := a.dbm.Close(); err != nil {
+		logger.Warn("Failed to close database: %v", err)
+	}
+	a.sealVault(a.fm.GetBasePath())
 }
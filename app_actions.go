@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"notebit/pkg/files"
+	"notebit/pkg/logger"
+	"notebit/pkg/rag"
+	"strings"
+)
+
+// ============ NOTE ACTION API METHODS ============
+
+// ListPendingNoteActions returns note edits proposed by the assistant that
+// await confirmation; an empty sessionID lists all sessions
+func (a *App) ListPendingNoteActions(sessionID string) ([]rag.NoteAction, error) {
+	return a.actions.List(sessionID), nil
+}
+
+// ConfirmNoteAction applies a proposed note edit and returns the path it changed
+func (a *App) ConfirmNoteAction(id string) (string, error) {
+	action, ok := a.actions.Take(id)
+	if !ok {
+		return "", fmt.Errorf("note action not found: %s", id)
+	}
+
+	path, err := a.applyNoteAction(action)
+	if err != nil {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{
+			"action": action.Type,
+			"path":   action.Path,
+			"error":  err.Error(),
+		}, "Failed to apply note action")
+		return "", err
+	}
+	logger.InfoWithFields(a.ctx, map[string]interface{}{
+		"action": action.Type,
+		"path":   path,
+	}, "Applied note action")
+	return path, nil
+}
+
+// RejectNoteAction discards a proposed note edit
+func (a *App) RejectNoteAction(id string) error {
+	if _, ok := a.actions.Take(id); !ok {
+		return fmt.Errorf("note action not found: %s", id)
+	}
+	return nil
+}
+
+func (a *App) applyNoteAction(action rag.NoteAction) (string, error) {
+	switch action.Type {
+	case rag.ActionCreateNote:
+		return a.CreateNote(action.Path, action.Content)
+
+	case rag.ActionAppendToNote:
+		note, err := a.fm.ReadFile(action.Path)
+		if err != nil {
+			return "", err
+		}
+		content := strings.TrimRight(note.Content, "\n") + "\n\n" + strings.TrimSpace(action.Content) + "\n"
+		return action.Path, a.writeNote(action.Path, content)
+
+	case rag.ActionAddTag:
+		note, err := a.fm.ReadFile(action.Path)
+		if err != nil {
+			return "", err
+		}
+		content, changed := files.AddFrontmatterTag(note.Content, action.Tag)
+		if !changed {
+			return action.Path, nil
+		}
+		if err := a.writeNote(action.Path, content); err != nil {
+			return "", err
+		}
+		a.tagIndexedFile(action.Path, action.Tag)
+		return action.Path, nil
+
+	default:
+		return "", fmt.Errorf("unknown note action type: %s", action.Type)
+	}
+}
+
+// tagIndexedFile records a tag in the index so tag lookups see it before the next reindex
+func (a *App) tagIndexedFile(path, tag string) {
+	if !a.dbm.IsInitialized() {
+		return
+	}
+	repo := a.dbm.Repository()
+	file, err := repo.GetFileByPath(path)
+	if err != nil || file == nil {
+		return
+	}
+	t, err := repo.GetOrCreateTag(tag)
+	if err != nil {
+		return
+	}
+	if err := repo.AddTagToFile(file.ID, t.ID); err != nil {
+		logger.Warn("Failed to record tag %s for %s: %v", tag, path, err)
+	}
+}
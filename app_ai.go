@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"notebit/pkg/ai"
 	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/jobs"
+	"notebit/pkg/logger"
+	"strings"
 	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // ============ AI SERVICE API METHODS ============
@@ -26,15 +33,14 @@ func (a *App) GetChunkingConfig() (config.ChunkingConfig, error) {
 
 // SetChunkingConfig sets the chunking configuration
 func (a *App) SetChunkingConfig(strategy string, chunkSize, chunkOverlap, minChunkSize, maxChunkSize int, preserveHeading bool, headingSeparator string) error {
-	cfg := config.ChunkingConfig{
-		Strategy:         strategy,
-		ChunkSize:        chunkSize,
-		ChunkOverlap:     chunkOverlap,
-		MinChunkSize:     minChunkSize,
-		MaxChunkSize:     maxChunkSize,
-		PreserveHeading:  preserveHeading,
-		HeadingSeparator: headingSeparator,
-	}
+	cfg := a.cfg.GetChunkingConfig()
+	cfg.Strategy = strategy
+	cfg.ChunkSize = chunkSize
+	cfg.ChunkOverlap = chunkOverlap
+	cfg.MinChunkSize = minChunkSize
+	cfg.MaxChunkSize = maxChunkSize
+	cfg.PreserveHeading = preserveHeading
+	cfg.HeadingSeparator = headingSeparator
 	a.cfg.SetChunkingConfig(cfg)
 
 	if err := a.ai.Reconfigure(); err != nil {
@@ -43,6 +49,36 @@ func (a *App) SetChunkingConfig(strategy string, chunkSize, chunkOverlap, minChu
 	return a.cfg.Save()
 }
 
+// SetStripMathForEmbedding sets whether LaTeX math is left out of the text
+// sent to the embedding model. Chunks keep their math either way; reindex
+// for existing notes to be embedded the new way.
+func (a *App) SetStripMathForEmbedding(strip bool) error {
+	cfg := a.cfg.GetChunkingConfig()
+	cfg.StripMathForEmbedding = strip
+	a.cfg.SetChunkingConfig(cfg)
+	return a.cfg.Save()
+}
+
+// GetNetworkConfig returns the proxy and TLS settings for AI providers
+func (a *App) GetNetworkConfig() (config.NetworkConfig, error) {
+	return a.cfg.GetNetworkConfig(), nil
+}
+
+// SetNetworkConfig sets the proxy and TLS settings for AI providers. They
+// apply to existing providers immediately; invalid settings are rejected.
+func (a *App) SetNetworkConfig(proxyURL, caBundlePath string, insecureSkipVerify bool) error {
+	cfg := config.NetworkConfig{
+		ProxyURL:           strings.TrimSpace(proxyURL),
+		CABundlePath:       strings.TrimSpace(caBundlePath),
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if err := ai.ApplyNetworkConfig(cfg); err != nil {
+		return err
+	}
+	a.cfg.SetNetworkConfig(cfg)
+	return a.cfg.Save()
+}
+
 // GetRAGConfig returns the RAG configuration
 func (a *App) GetRAGConfig() (config.RAGConfig, error) {
 	return a.cfg.GetRAGConfig(), nil
@@ -50,11 +86,63 @@ func (a *App) GetRAGConfig() (config.RAGConfig, error) {
 
 // SetRAGConfig sets the RAG configuration
 func (a *App) SetRAGConfig(maxContextChunks int, temperature float32, systemPrompt string) error {
-	cfg := config.RAGConfig{
-		MaxContextChunks: maxContextChunks,
-		Temperature:      temperature,
-		SystemPrompt:     systemPrompt,
+	cfg := a.cfg.GetRAGConfig()
+	cfg.MaxContextChunks = maxContextChunks
+	cfg.Temperature = temperature
+	cfg.SystemPrompt = systemPrompt
+	a.cfg.SetRAGConfig(cfg)
+	return a.cfg.Save()
+}
+
+// SetRAGRelevanceConfig sets the minimum chunk similarity for RAG context and
+// what to do when nothing passes it ("respond" or "answer")
+func (a *App) SetRAGRelevanceConfig(minSimilarity float32, noContextBehavior string) error {
+	if minSimilarity < 0 || minSimilarity > 1 {
+		return fmt.Errorf("min similarity must be between 0 and 1")
+	}
+	switch noContextBehavior {
+	case config.NoContextRespond, config.NoContextAnswer:
+	default:
+		return fmt.Errorf("unknown no-context behavior: %s", noContextBehavior)
+	}
+	cfg := a.cfg.GetRAGConfig()
+	cfg.MinSimilarity = minSimilarity
+	cfg.NoContextBehavior = noContextBehavior
+	a.cfg.SetRAGConfig(cfg)
+	return a.cfg.Save()
+}
+
+// SetRAGQueryRewrite sets the step run before retrieval: "off", "rewrite"
+// (search with an expanded query) or "hyde" (search with a hypothetical answer)
+func (a *App) SetRAGQueryRewrite(mode string) error {
+	switch mode {
+	case config.QueryRewriteOff, config.QueryRewriteRewrite, config.QueryRewriteHyDE:
+	default:
+		return fmt.Errorf("unknown query rewrite mode: %s", mode)
+	}
+	cfg := a.cfg.GetRAGConfig()
+	cfg.QueryRewrite = mode
+	a.cfg.SetRAGConfig(cfg)
+	return a.cfg.Save()
+}
+
+// SetRAGMultiQuery sets how many rephrasings of each question are searched
+// besides the question itself, 0 to turn multi-query retrieval off
+func (a *App) SetRAGMultiQuery(variations int) error {
+	if variations < 0 || variations > config.MaxMultiQuery {
+		return fmt.Errorf("variations must be between 0 and %d", config.MaxMultiQuery)
 	}
+	cfg := a.cfg.GetRAGConfig()
+	cfg.MultiQuery = variations
+	a.cfg.SetRAGConfig(cfg)
+	return a.cfg.Save()
+}
+
+// SetRAGFollowUps turns the follow-up question and action item pass after
+// each answer on or off
+func (a *App) SetRAGFollowUps(enabled bool) error {
+	cfg := a.cfg.GetRAGConfig()
+	cfg.FollowUps = enabled
 	a.cfg.SetRAGConfig(cfg)
 	return a.cfg.Save()
 }
@@ -77,6 +165,28 @@ func (a *App) GetAIStatus() (map[string]interface{}, error) {
 	}, nil
 }
 
+// GetProviderHealth returns the cached result of the latest health check of
+// each embedding provider. Transitions are also pushed as "ai:health" events.
+func (a *App) GetProviderHealth() []ai.ProviderHealth {
+	return a.ai.GetProviderHealth()
+}
+
+// CheckProviderHealth pings every embedding provider immediately
+func (a *App) CheckProviderHealth() []ai.ProviderHealth {
+	return a.ai.CheckHealthNow(context.Background())
+}
+
+// startHealthMonitor pings embedding providers in the background and emits
+// "ai:health" when one goes down or comes back
+func (a *App) startHealthMonitor() {
+	a.ai.OnHealthChange(func(status ai.ProviderHealth) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "ai:health", status)
+		}
+	})
+	a.ai.StartHealthMonitor(time.Duration(a.cfg.GetHealthCheckInterval()) * time.Second)
+}
+
 // SetAIProvider sets the current AI provider
 func (a *App) SetAIProvider(provider string) error {
 	if err := a.ai.SetProvider(provider); err != nil {
@@ -85,6 +195,47 @@ func (a *App) SetAIProvider(provider string) error {
 	return a.cfg.Save()
 }
 
+// GetBuiltinModelStatus reports whether the builtin embedding model is downloaded
+func (a *App) GetBuiltinModelStatus() (map[string]interface{}, error) {
+	provider, err := a.builtinProvider()
+	if err != nil {
+		return nil, err
+	}
+	dim, _ := provider.GetModelDimension(ai.BuiltinModel)
+	return map[string]interface{}{
+		"model":     ai.BuiltinModel,
+		"model_dir": provider.ModelDir(),
+		"available": provider.Available(),
+		"dimension": dim,
+	}, nil
+}
+
+// DownloadBuiltinModel starts a job that downloads the builtin embedding model
+// and registers the "builtin" provider once it is complete. Progress is in KiB.
+func (a *App) DownloadBuiltinModel() (string, error) {
+	provider, err := a.builtinProvider()
+	if err != nil {
+		return "", err
+	}
+	return a.jobs.Start("model_download", "Download "+ai.BuiltinModel, func(ctx context.Context, report jobs.Reporter) (any, error) {
+		err := provider.Download(ctx, func(file string, done, total int64) {
+			report(int(done/1024), int(total/1024), file)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := a.ai.Reconfigure(); err != nil {
+			return nil, err
+		}
+		return provider.ModelDir(), nil
+	}), nil
+}
+
+func (a *App) builtinProvider() (*ai.BuiltinProvider, error) {
+	cfg := a.cfg.GetBuiltinConfig()
+	return ai.NewBuiltinProvider(ai.BuiltinConfig{ModelDir: cfg.ModelDir, DownloadURL: cfg.DownloadURL})
+}
+
 // SetAIModel sets the default embedding model
 func (a *App) SetAIModel(model string) error {
 	a.cfg.SetEmbeddingModel(model)
@@ -94,6 +245,19 @@ func (a *App) SetAIModel(model string) error {
 	return a.cfg.Save()
 }
 
+// GetLanguageModels returns the multilingual embedding model set per chunk language
+func (a *App) GetLanguageModels() map[string]string {
+	return a.cfg.GetLanguageModels()
+}
+
+// SetLanguageModels sets the embedding model used per chunk language, e.g.
+// {"zh": "bge-m3"}. Notes indexed from now on get the extra embeddings;
+// reindex to add them to existing notes.
+func (a *App) SetLanguageModels(models map[string]string) error {
+	a.cfg.SetLanguageModels(models)
+	return a.cfg.Save()
+}
+
 // SetOpenAIConfig sets the OpenAI configuration
 func (a *App) SetOpenAIConfig(apiKey, baseURL, organization, embeddingModel string) error {
 	if err := a.ai.SetOpenAIConfig(apiKey, baseURL, organization, embeddingModel); err != nil {
@@ -110,6 +274,33 @@ func (a *App) SetOllamaConfig(baseURL, model string, timeout int) error {
 	return a.cfg.Save()
 }
 
+// GetCustomProviderConfig returns the OpenAI-compatible custom provider configuration
+func (a *App) GetCustomProviderConfig() (config.CustomConfig, error) {
+	return a.cfg.GetCustomConfig(), nil
+}
+
+// SetCustomProviderConfig points the "custom" provider at an OpenAI-compatible
+// server such as LM Studio, vLLM, OpenRouter or llama.cpp. An empty baseURL
+// disables it.
+func (a *App) SetCustomProviderConfig(baseURL, apiKey, embeddingModel string, timeout int) error {
+	if err := a.ai.SetCustomConfig(baseURL, apiKey, embeddingModel, timeout); err != nil {
+		return err
+	}
+	if a.cfg.GetLLMConfig().Provider == "custom" {
+		a.initializeLLM()
+		if a.rag != nil {
+			a.initializeRAG()
+		}
+	}
+	return a.cfg.Save()
+}
+
+// ListAvailableModels asks a provider ("openai", "ollama", "custom" or
+// "builtin") which models it serves, for populating settings dropdowns
+func (a *App) ListAvailableModels(provider string) ([]string, error) {
+	return a.ai.ListModels(context.Background(), provider)
+}
+
 // TestOpenAIConnection tests an OpenAI connection with given credentials
 func (a *App) TestOpenAIConnection(apiKey, baseURL, organization, model string) (map[string]interface{}, error) {
 	provider, err := ai.NewOpenAIProvider(ai.OpenAIConfig{
@@ -126,7 +317,7 @@ func (a *App) TestOpenAIConnection(apiKey, baseURL, organization, model string)
 		model = provider.GetDefaultModel()
 	}
 
-	resp, err := provider.GenerateEmbedding(&ai.EmbeddingRequest{
+	resp, err := provider.GenerateEmbedding(context.Background(), &ai.EmbeddingRequest{
 		Text:  "ping",
 		Model: model,
 	})
@@ -140,9 +331,18 @@ func (a *App) TestOpenAIConnection(apiKey, baseURL, organization, model string)
 	}, nil
 }
 
+// GetRateLimitStatus returns the adaptive rate limiter state for each provider
+func (a *App) GetRateLimitStatus() []ai.RateLimitStats {
+	stats := a.ai.GetRateLimitStats()
+	if llmStats := ai.LLMRateLimitStats(a.llm); llmStats != nil {
+		stats = append(stats, *llmStats)
+	}
+	return stats
+}
+
 // GenerateEmbedding generates an embedding for a single text
 func (a *App) GenerateEmbedding(text string) ([]float32, error) {
-	resp, err := a.ai.GenerateEmbedding(text)
+	resp, err := a.ai.GenerateEmbedding(context.Background(), text)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +351,7 @@ func (a *App) GenerateEmbedding(text string) ([]float32, error) {
 
 // GenerateEmbeddingsBatch generates embeddings for multiple texts
 func (a *App) GenerateEmbeddingsBatch(texts []string) ([][]float32, error) {
-	responses, err := a.ai.GenerateEmbeddingsBatch(texts)
+	responses, err := a.ai.GenerateEmbeddingsBatch(context.Background(), texts)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +385,7 @@ func (a *App) ChunkText(text string) ([]map[string]interface{}, error) {
 
 // ProcessDocument chunks text and generates embeddings for all chunks
 func (a *App) ProcessDocument(text string) ([]map[string]interface{}, error) {
-	chunks, err := a.ai.ProcessDocument(text)
+	chunks, err := a.ai.ProcessDocument(context.Background(), text)
 	if err != nil {
 		return nil, err
 	}
@@ -213,6 +413,7 @@ func (a *App) ReindexAllWithEmbeddings() (map[string]interface{}, error) {
 	if a.ks == nil {
 		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
 	}
+	logger.Audit(a.ctx, auditIndexReindex, a.fm.GetBasePath(), nil, nil)
 	return a.ks.ReindexAllWithEmbeddings()
 }
 
@@ -235,6 +436,7 @@ func (a *App) SetLLMConfig(provider string, model string, temperature float32, m
 		MaxTokens:   maxTokens,
 		OpenAI:      currentConfig.OpenAI,
 		Ollama:      currentConfig.Ollama,
+		Cache:       currentConfig.Cache,
 	}
 
 	if apiKey != "" {
@@ -259,3 +461,114 @@ func (a *App) SetLLMConfig(provider string, model string, temperature float32, m
 
 	return a.cfg.Save()
 }
+
+// SetLLMCacheConfig configures the cache of deterministic completions; changing
+// it starts with an empty cache
+func (a *App) SetLLMCacheConfig(enabled bool, ttlMinutes, maxEntries int) error {
+	if ttlMinutes <= 0 || maxEntries <= 0 {
+		return fmt.Errorf("ttl and max entries must be positive")
+	}
+	llmConfig := a.cfg.GetLLMConfig()
+	llmConfig.Cache = config.LLMCacheConfig{Enabled: enabled, TTLMinutes: ttlMinutes, MaxEntries: maxEntries}
+	a.cfg.SetLLMConfig(llmConfig)
+
+	a.initializeLLM()
+	if a.rag != nil {
+		a.initializeRAG()
+	}
+	return a.cfg.Save()
+}
+
+// GetLLMCacheStats returns the size and hit counts of the completion cache,
+// nil while it is disabled
+func (a *App) GetLLMCacheStats() *ai.CompletionCacheStats {
+	if a.llmCache == nil {
+		return nil
+	}
+	stats := a.llmCache.Stats()
+	return &stats
+}
+
+// ClearLLMCache drops every cached completion
+func (a *App) ClearLLMCache() {
+	if a.llmCache != nil {
+		a.llmCache.Clear()
+	}
+}
+
+// ============ AI DEBUG METHODS ============
+
+// SetAIDebugMode turns recording of sanitized AI requests and responses to
+// ai-debug.log on or off
+func (a *App) SetAIDebugMode(enabled bool) error {
+	if err := a.ai.DebugRecorder().SetEnabled(enabled, appLogDir()); err != nil {
+		return err
+	}
+	a.cfg.SetAIDebug(enabled)
+	return a.cfg.Save()
+}
+
+// GetLastAIExchange returns the last embedding or completion call recorded in
+// AI debug mode, nil if there is none
+func (a *App) GetLastAIExchange() *ai.AIExchange {
+	return a.ai.DebugRecorder().Last()
+}
+
+// ReplayLastAIExchange sends the last recorded completion request again,
+// bypassing the completion cache, and returns the new exchange
+func (a *App) ReplayLastAIExchange() (*ai.AIExchange, error) {
+	recorder := a.ai.DebugRecorder()
+	if !recorder.Enabled() {
+		return nil, fmt.Errorf("AI debug mode is off")
+	}
+	req := recorder.LastCompletionRequest()
+	if req == nil {
+		return nil, fmt.Errorf("no completion has been recorded yet")
+	}
+	if a.llm == nil {
+		return nil, fmt.Errorf("LLM provider is not configured")
+	}
+	// The exchange is recorded even if the provider fails
+	_, _ = a.llm.GenerateCompletion(ai.WithoutCache(context.Background()), req)
+	return recorder.Last(), nil
+}
+
+// ============ USAGE API METHODS ============
+
+// GetUsageReport returns token usage and estimated cost between rangeStart and
+// rangeEnd (Unix ms; 0 leaves that side open), aggregated by day and model and
+// by operation such as indexing or rag
+func (a *App) GetUsageReport(rangeStart, rangeEnd int64) (*database.UsageReport, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().GetUsageReport(rangeStart, rangeEnd)
+}
+
+// initializeUsageTracking records provider token usage into the vault database
+func (a *App) initializeUsageTracking() {
+	if !a.dbm.IsInitialized() {
+		a.ai.UsageTracker().SetRecorder(nil)
+		return
+	}
+	a.ai.UsageTracker().SetRecorder(usageRecorder{repo: a.dbm.Repository()})
+}
+
+// usageRecorder stores ai usage events in the usage_log table
+type usageRecorder struct {
+	repo *database.Repository
+}
+
+func (r usageRecorder) RecordUsage(ev ai.UsageEvent) error {
+	return r.repo.RecordUsage(database.UsageRecord{
+		Provider:         ev.Provider,
+		Model:            ev.Model,
+		Kind:             ev.Kind,
+		Operation:        ev.Operation,
+		PromptTokens:     ev.PromptTokens,
+		CompletionTokens: ev.CompletionTokens,
+		TotalTokens:      ev.TotalTokens,
+		Estimated:        ev.Estimated,
+		CostUSD:          ev.CostUSD,
+	})
+}
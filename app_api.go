@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"notebit/pkg/config"
+	"notebit/pkg/httpapi"
+	"notebit/pkg/logger"
+	"notebit/pkg/mcp"
+)
+
+// ============ LOCAL API SERVER METHODS ============
+
+// APIServerStatus is the local API configuration plus whether it is running
+type APIServerStatus struct {
+	config.APIServerConfig
+	Running bool   `json:"running"`
+	Address string `json:"address"`
+}
+
+// GetAPIServerStatus returns the local API settings, including the token
+// clients need, and the address it listens on
+func (a *App) GetAPIServerStatus() APIServerStatus {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+	status := APIServerStatus{APIServerConfig: a.cfg.GetAPIServerConfig()}
+	if a.apiServer != nil {
+		status.Running = true
+		status.Address = "http://" + a.apiServer.Addr()
+	}
+	return status
+}
+
+// SetAPIServerConfig enables or disables the local API and sets its port. A
+// token is generated the first time the API is enabled.
+func (a *App) SetAPIServerConfig(enabled bool, port int) (APIServerStatus, error) {
+	if port <= 0 || port >= 65536 {
+		return APIServerStatus{}, fmt.Errorf("invalid port: %d", port)
+	}
+	apiCfg := a.cfg.GetAPIServerConfig()
+	apiCfg.Enabled = enabled
+	apiCfg.Port = port
+	if apiCfg.Token == "" {
+		apiCfg.Token = httpapi.NewToken()
+	}
+	a.cfg.SetAPIServerConfig(apiCfg)
+	if err := a.cfg.Save(); err != nil {
+		return APIServerStatus{}, err
+	}
+	a.stopAPIServer()
+	if err := a.startAPIServer(); err != nil {
+		return a.GetAPIServerStatus(), err
+	}
+	return a.GetAPIServerStatus(), nil
+}
+
+// RegenerateAPIToken replaces the API token, invalidating existing clients
+func (a *App) RegenerateAPIToken() (APIServerStatus, error) {
+	apiCfg := a.cfg.GetAPIServerConfig()
+	apiCfg.Token = httpapi.NewToken()
+	a.cfg.SetAPIServerConfig(apiCfg)
+	if err := a.cfg.Save(); err != nil {
+		return APIServerStatus{}, err
+	}
+	a.stopAPIServer()
+	if err := a.startAPIServer(); err != nil {
+		return a.GetAPIServerStatus(), err
+	}
+	return a.GetAPIServerStatus(), nil
+}
+
+// startAPIServer starts the local API if it is enabled
+func (a *App) startAPIServer() error {
+	apiCfg := a.cfg.GetAPIServerConfig()
+	if !apiCfg.Enabled {
+		return nil
+	}
+	if apiCfg.Token == "" {
+		return errors.New("api server has no token")
+	}
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+	server := httpapi.New(apiBackend{a}, apiCfg.Token)
+	server.Mount("/mcp", mcp.NewServer(mcpBackend{a}, appVersion()))
+	if err := server.Start(apiCfg.Port); err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"port": apiCfg.Port, "error": err.Error()}, "Failed to start local API server")
+		return err
+	}
+	a.apiServer = server
+	logger.Info("Local API server listening on %s", server.Addr())
+	return nil
+}
+
+func (a *App) stopAPIServer() {
+	a.apiMu.Lock()
+	server := a.apiServer
+	a.apiServer = nil
+	a.apiMu.Unlock()
+	if server != nil {
+		if err := server.Stop(); err != nil {
+			logger.Warn("Failed to stop local API server: %v", err)
+		}
+	}
+}
+
+// apiBackend exposes the App to the local API without adding methods to the
+// Wails bindings
+type apiBackend struct {
+	a *App
+}
+
+func (b apiBackend) ListNotes() ([]string, error) {
+	if b.a.fm.GetBasePath() == "" {
+		return nil, errors.New("no vault open")
+	}
+	return b.a.fm.ListMarkdownFiles("")
+}
+
+func (b apiBackend) ReadNote(path string) (string, error) {
+	note, err := b.a.fm.ReadFile(path)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return note.Content, nil
+}
+
+func (b apiBackend) CreateNote(path, content string) (string, error) {
+	return b.a.CreateFile(path, content)
+}
+
+func (b apiBackend) SaveNote(path, content string) error {
+	if !b.a.fm.FileExists(path) {
+		return fmt.Errorf("%w: %s", httpapi.ErrNotFound, path)
+	}
+	return b.a.writeNote(path, content)
+}
+
+func (b apiBackend) DeleteNote(path string) error {
+	if !b.a.fm.FileExists(path) {
+		return fmt.Errorf("%w: %s", httpapi.ErrNotFound, path)
+	}
+	return b.a.DeleteFile(path)
+}
+
+func (b apiBackend) Search(query string, limit int) (any, error) {
+	if !b.a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	found, err := b.a.dbm.Repository().SearchFilesByTitle(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	results := make([]map[string]string, len(found))
+	for i, f := range found {
+		results[i] = map[string]string{"path": f.Path, "title": f.Title}
+	}
+	return results, nil
+}
+
+func (b apiBackend) SemanticSearch(query string, limit int) (any, error) {
+	return b.a.FindSimilar(query, limit)
+}
+
+func (b apiBackend) Ask(sessionID, query string) (any, error) {
+	if strings.TrimSpace(sessionID) == "" {
+		return b.a.RAGQuery(query)
+	}
+	return b.a.RAGQueryWithSession(sessionID, query)
+}
+
+// notFound marks missing-file errors for a 404 answer
+func notFound(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+	"notebit/pkg/knowledge"
+	"notebit/pkg/rag"
+	"path"
+	"strings"
+)
+
+// duplicateArchiveFolder is where MergeDuplicateNotes moves the merged-away note
+const duplicateArchiveFolder = "Archive"
+
+// ============ NOTE ASSIST API METHODS ============
+
+// SuggestTags asks the LLM for tags that fit a note, favoring tags already used
+// in the vault. Suggestions are only returned, never applied.
+func (a *App) SuggestTags(path string) ([]rag.TagSuggestion, error) {
+	if a.rag == nil {
+		return nil, fmt.Errorf("RAG service not initialized")
+	}
+	note, err := a.fm.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var vaultTags []database.TagCount
+	if a.dbm.IsInitialized() {
+		if vaultTags, err = a.dbm.Repository().TagUsage(); err != nil {
+			return nil, err
+		}
+	}
+	return a.rag.SuggestTags(context.Background(), note.Content, vaultTags, files.NoteTags(note.Content), 0)
+}
+
+// ============ DUPLICATE NOTE API METHODS ============
+
+// FindDuplicateNotes groups notes whose note-level embeddings are at least
+// threshold similar; threshold <= 0 uses the default. Use StartDuplicateScanJob
+// for large vaults.
+func (a *App) FindDuplicateNotes(threshold float32) ([]knowledge.DuplicateCluster, error) {
+	if a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	return a.ks.FindDuplicates(context.Background(), threshold, nil)
+}
+
+// MergeDuplicateNotes appends the body of mergePath to keepPath under a
+// "Merged from" heading and moves mergePath into the Archive folder. It
+// returns the archived note's new path.
+func (a *App) MergeDuplicateNotes(keepPath, mergePath string) (string, error) {
+	if keepPath == mergePath {
+		return "", fmt.Errorf("cannot merge a note into itself")
+	}
+	keep, err := a.fm.ReadFile(keepPath)
+	if err != nil {
+		return "", err
+	}
+	merged, err := a.fm.ReadFile(mergePath)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSuffix(path.Base(mergePath), path.Ext(mergePath))
+	content := strings.TrimRight(keep.Content, "\n") +
+		"\n\n## Merged from " + title + "\n\n" +
+		strings.TrimSpace(files.StripFrontmatter(merged.Content)) + "\n"
+	if err := a.writeNote(keepPath, content); err != nil {
+		return "", err
+	}
+
+	archived := a.archivePath(mergePath)
+	if err := a.RenameFile(mergePath, archived); err != nil {
+		return "", fmt.Errorf("merged into %s but failed to archive %s: %w", keepPath, mergePath, err)
+	}
+	return archived, nil
+}
+
+// archivePath returns a free path for notePath inside the archive folder
+func (a *App) archivePath(notePath string) string {
+	target := path.Join(duplicateArchiveFolder, notePath)
+	ext := path.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+	for i := 2; a.fm.FileExists(target); i++ {
+		target = fmt.Sprintf("%s %d%s", base, i, ext)
+	}
+	return target
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+// ============ AUDIT LOG API METHODS ============
+
+// Audit actions recorded by the App
+const (
+	auditNoteCreate       = "note.create"
+	auditNoteDelete       = "note.delete"
+	auditNoteRename       = "note.rename"
+	auditIndexRemove      = "index.remove"
+	auditIndexReindex     = "index.reindex"
+	auditIndexNamespace   = "index.delete_namespace"
+	auditIndexExport      = "index.export"
+	auditIndexImport      = "index.import"
+	auditConfigChange     = "config.change"
+	auditConfigImport     = "config.import"
+	auditConfigExport     = "config.export"
+	auditChatExport       = "chat.export"
+	auditChatBackup       = "chat.backup"
+	auditCollectionExport = "collection.export"
+	auditDiagnostics      = "diagnostics.export"
+	auditGitCommit        = "git.commit"
+	auditGitPull          = "git.pull"
+	auditGitPush          = "git.push"
+	auditSyncWebDAV       = "sync.webdav"
+	auditBackupCreate     = "backup.create"
+	auditBackupRestore    = "backup.restore"
+	auditVaultLockEnable  = "vault.lock_enable"
+	auditVaultLockDisable = "vault.lock_disable"
+	auditVaultUnlock      = "vault.unlock"
+	auditFeedSubscribe    = "feed.subscribe"
+	auditFeedUnsubscribe  = "feed.unsubscribe"
+)
+
+// GetAuditLog returns audit events matching filter, newest first. An action
+// ending in "." matches a whole group, e.g. "note.".
+func (a *App) GetAuditLog(filter logger.AuditFilter) ([]logger.AuditEvent, error) {
+	return logger.QueryAudit(filter)
+}
+
+// auditConfigChanges records every saved config change with its old and new
+// value; secrets arrive already redacted
+func (a *App) auditConfigChanges(changes []config.Change) {
+	before := make(map[string]interface{}, len(changes))
+	after := make(map[string]interface{}, len(changes))
+	for _, change := range changes {
+		before[change.Key] = change.Before
+		after[change.Key] = change.After
+	}
+	logger.Audit(a.ctx, auditConfigChange, "config.json", before, after)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/backup"
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+// ============ VAULT BACKUP METHODS ============
+
+// GetBackupConfig returns the vault backup settings
+func (a *App) GetBackupConfig() (config.BackupConfig, error) {
+	return a.cfg.GetBackupConfig(), nil
+}
+
+// SetBackupConfig updates the backup settings and restarts the backup scheduler
+func (a *App) SetBackupConfig(cfg config.BackupConfig) error {
+	cfg.Endpoint = strings.TrimSpace(cfg.Endpoint)
+	cfg.Bucket = strings.TrimSpace(cfg.Bucket)
+	cfg.Prefix = strings.Trim(strings.TrimSpace(cfg.Prefix), "/")
+	if cfg.Enabled && (cfg.Endpoint == "" || cfg.Bucket == "" || cfg.Passphrase == "") {
+		return errors.New("endpoint, bucket and passphrase are required")
+	}
+	if cfg.IntervalHours < 0 {
+		return fmt.Errorf("invalid backup interval: %d hours", cfg.IntervalHours)
+	}
+	if cfg.FullEveryDays <= 0 {
+		cfg.FullEveryDays = 7
+	}
+	a.cfg.SetBackupConfig(cfg)
+	a.startBackupScheduler()
+	return a.cfg.Save()
+}
+
+// GetBackupStatus returns the backup scheduler state, including the next planned run
+func (a *App) GetBackupStatus() (backup.Status, error) {
+	if a.backups == nil {
+		return backup.Status{}, fmt.Errorf("backup scheduler not initialized")
+	}
+	return a.backups.Status(), nil
+}
+
+// BackupVaultNow backs up the open vault right away. With full unset the
+// schedule decides between a full and an incremental backup.
+func (a *App) BackupVaultNow(full bool) (*backup.Info, error) {
+	if a.backups == nil {
+		return nil, fmt.Errorf("backup scheduler not initialized")
+	}
+	kind := ""
+	if full {
+		kind = backup.KindFull
+	}
+	return a.backups.RunNow(context.Background(), kind)
+}
+
+// ListRemoteBackups returns the backups in the configured bucket, newest first
+func (a *App) ListRemoteBackups() ([]backup.Info, error) {
+	store, err := a.backupStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(context.Background())
+}
+
+// RestoreVaultBackup restores backup id, notes and database, into targetDir.
+// The folder must be empty or not exist; it can then be opened as a vault.
+func (a *App) RestoreVaultBackup(id, targetDir string) (*backup.Info, error) {
+	targetDir = strings.TrimSpace(targetDir)
+	if targetDir == "" {
+		return nil, errors.New("target folder is required")
+	}
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return nil, err
+	}
+	store, err := a.backupStore()
+	if err != nil {
+		return nil, err
+	}
+	timer := logger.StartTimer()
+	info, err := store.Restore(context.Background(), id, absTarget)
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"id": id, "error": err.Error()}, "Backup restore failed")
+		return nil, err
+	}
+	logger.Audit(a.ctx, auditBackupRestore, id, nil, map[string]interface{}{"target": absTarget, "files": info.Files})
+	logger.InfoWithDuration(a.ctx, timer(), "Restored backup %s to %s", id, absTarget)
+	return info, nil
+}
+
+func (a *App) backupStore() (*backup.Store, error) {
+	backupCfg := a.cfg.GetBackupConfig()
+	if backupCfg.Endpoint == "" || backupCfg.Bucket == "" {
+		return nil, errors.New("backup storage is not configured")
+	}
+	client, err := backup.NewS3(backupCfg.Endpoint, backupCfg.Region, backupCfg.Bucket,
+		backupCfg.AccessKeyID, backupCfg.SecretAccessKey, ai.NewHTTPClient(10*time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	return backup.NewStore(client, backupCfg.Prefix, backupCfg.Passphrase), nil
+}
+
+// runBackup backs up the notes of the open vault and a snapshot of its
+// database, which also holds the chat history
+func (a *App) runBackup(ctx context.Context, kind string) (*backup.Info, error) {
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return nil, errors.New("no vault open")
+	}
+	store, err := a.backupStore()
+	if err != nil {
+		return nil, err
+	}
+	files, err := backup.ScanVault(basePath)
+	if err != nil {
+		return nil, err
+	}
+	if a.dbm.IsInitialized() {
+		tmpDir, err := os.MkdirTemp("", "notebit-backup-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmpDir)
+		snapshot := filepath.Join(tmpDir, "notebit.sqlite")
+		if err := a.dbm.Snapshot(snapshot); err != nil {
+			return nil, fmt.Errorf("snapshot database: %w", err)
+		}
+		files = append(files, backup.File{Path: "data/notebit.sqlite", Source: snapshot})
+	}
+	info, err := store.Run(ctx, kind, files)
+	if err != nil {
+		return nil, err
+	}
+	logger.Audit(ctx, auditBackupCreate, info.ID, nil, map[string]interface{}{"files": info.Files, "uploaded": info.Uploaded})
+	return info, nil
+}
+
+// startBackupScheduler (re)starts scheduled backups with the current config
+func (a *App) startBackupScheduler() {
+	if a.backups == nil {
+		a.backups = backup.NewScheduler(a.runBackup, func() string {
+			if basePath := a.fm.GetBasePath(); basePath != "" {
+				return filepath.Join(basePath, "data")
+			}
+			return ""
+		})
+	}
+	backupCfg := a.cfg.GetBackupConfig()
+	interval := time.Duration(backupCfg.IntervalHours) * time.Hour
+	if !backupCfg.Enabled {
+		interval = 0
+	}
+	a.backups.Start(interval, time.Duration(backupCfg.FullEveryDays)*24*time.Hour)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"notebit/pkg/logger"
+	"notebit/pkg/urlmeta"
+)
+
+// ============ BOOKMARK API METHODS ============
+
+// FetchURLMetadata fetches the title, description, site name and a Markdown
+// excerpt of a web page, for turning a pasted link into a bookmark note. When
+// the page cannot be fetched, e.g. offline, the result is marked partial and
+// holds what the URL itself tells; only invalid URLs are errors.
+func (a *App) FetchURLMetadata(rawURL string) (*urlmeta.Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	meta, err := a.urlMeta.Fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Partial {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{"url": meta.URL, "error": meta.Error}, "Could not fetch URL metadata")
+	}
+	return meta, nil
+}
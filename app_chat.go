@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strings"
 
 	"notebit/pkg/chat"
+	"notebit/pkg/logger"
+	"notebit/pkg/rag"
 )
 
 func (a *App) ensureChatService() error {
@@ -81,11 +84,36 @@ func (a *App) RenameChatSession(sessionID, title string) error {
 	return a.chatSvc.RenameSession(strings.TrimSpace(sessionID), strings.TrimSpace(title))
 }
 
+// SetChatSessionOverrides sets the model, temperature and system prompt used
+// to answer in a session. An empty model or prompt, or a negative temperature,
+// falls back to the global LLM and RAG settings.
+func (a *App) SetChatSessionOverrides(sessionID, model string, temperature float32, systemPrompt string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	var temp *float32
+	if temperature >= 0 {
+		temp = &temperature
+	}
+	if err := a.chatSvc.SetSessionModel(sessionID, model); err != nil {
+		return err
+	}
+	if err := a.chatSvc.SetSessionTemperature(sessionID, temp); err != nil {
+		return err
+	}
+	return a.chatSvc.SetSessionSystemPrompt(sessionID, systemPrompt)
+}
+
 func (a *App) DeleteChatSession(sessionID string) error {
 	if err := a.ensureChatService(); err != nil {
 		return err
 	}
-	return a.chatSvc.DeleteSession(strings.TrimSpace(sessionID))
+	sessionID = strings.TrimSpace(sessionID)
+	if a.rag != nil {
+		a.rag.ClearSessionRetrievalCache(sessionID)
+	}
+	return a.chatSvc.DeleteSession(sessionID)
 }
 
 func (a *App) SetChatSessionArchived(sessionID string, archived bool) error {
@@ -95,6 +123,50 @@ func (a *App) SetChatSessionArchived(sessionID string, archived bool) error {
 	return a.chatSvc.SetArchive(strings.TrimSpace(sessionID), archived)
 }
 
+// BulkDeleteChatSessions deletes several sessions at once and returns how many were removed
+func (a *App) BulkDeleteChatSessions(sessionIDs []string) (int64, error) {
+	if err := a.ensureChatService(); err != nil {
+		return 0, err
+	}
+	return a.chatSvc.BulkDelete(trimIDs(sessionIDs))
+}
+
+// BulkArchiveChatSessions archives or unarchives several sessions at once
+func (a *App) BulkArchiveChatSessions(sessionIDs []string, archived bool) (int64, error) {
+	if err := a.ensureChatService(); err != nil {
+		return 0, err
+	}
+	return a.chatSvc.BulkArchive(trimIDs(sessionIDs), archived)
+}
+
+// BulkTagChatSessions adds tags to several sessions at once
+func (a *App) BulkTagChatSessions(sessionIDs []string, tags []string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.BulkTag(trimIDs(sessionIDs), tags)
+}
+
+// SetChatAutoArchiveDays archives sessions inactive for this many days; 0 turns it off
+func (a *App) SetChatAutoArchiveDays(days int) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	opts := a.chatSvc.GetStorageOptions()
+	opts.AutoArchiveDays = days
+	return a.chatSvc.SetStorageOptions(opts)
+}
+
+func trimIDs(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
 func (a *App) SetChatSessionFavorite(sessionID string, favorite bool) error {
 	if err := a.ensureChatService(); err != nil {
 		return err
@@ -128,6 +200,12 @@ func (a *App) GetChatStorageOptions() (map[string]interface{}, error) {
 		"auto_backup_enabled":   opts.AutoBackupEnabled,
 		"backup_interval_mins":  opts.BackupIntervalMins,
 		"preferred_export_type": opts.PreferredExportType,
+		"auto_title":            opts.AutoTitle,
+		"backup_keep_last":      opts.BackupKeepLast,
+		"backup_keep_days":      opts.BackupKeepDays,
+		"backup_compress":       opts.BackupCompress,
+		"backup_encrypt":        opts.BackupEncrypt,
+		"auto_archive_days":     opts.AutoArchiveDays,
 	}, nil
 }
 
@@ -135,30 +213,346 @@ func (a *App) SetChatStorageOptions(encryptAtRest bool, syncMode, cloudEndpoint
 	if err := a.ensureChatService(); err != nil {
 		return err
 	}
-	return a.chatSvc.SetStorageOptions(chat.StorageOptions{
-		EncryptAtRest:       encryptAtRest,
-		SyncMode:            strings.TrimSpace(syncMode),
-		CloudEndpoint:       strings.TrimSpace(cloudEndpoint),
-		AutoBackupEnabled:   autoBackup,
-		BackupIntervalMins:  backupIntervalMins,
-		PreferredExportType: strings.TrimSpace(preferredExportType),
-	})
+	opts := a.chatSvc.GetStorageOptions()
+	opts.EncryptAtRest = encryptAtRest
+	opts.SyncMode = strings.TrimSpace(syncMode)
+	opts.CloudEndpoint = strings.TrimSpace(cloudEndpoint)
+	opts.AutoBackupEnabled = autoBackup
+	opts.BackupIntervalMins = backupIntervalMins
+	opts.PreferredExportType = strings.TrimSpace(preferredExportType)
+	return a.chatSvc.SetStorageOptions(opts)
+}
+
+// SetChatBackupOptions sets backup retention and format. keepLast and
+// keepDays of 0 disable that limit; encrypted backups need the chat unlocked.
+func (a *App) SetChatBackupOptions(keepLast, keepDays int, compress, encrypt bool) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	opts := a.chatSvc.GetStorageOptions()
+	opts.BackupKeepLast = keepLast
+	opts.BackupKeepDays = keepDays
+	opts.BackupCompress = compress
+	opts.BackupEncrypt = encrypt
+	return a.chatSvc.SetStorageOptions(opts)
+}
+
+// SetChatAutoTitle turns automatic naming of new sessions from their first exchange on or off
+func (a *App) SetChatAutoTitle(enabled bool) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	opts := a.chatSvc.GetStorageOptions()
+	opts.AutoTitle = enabled
+	return a.chatSvc.SetStorageOptions(opts)
+}
+
+// GetChatKeyStatus reports whether chat history uses a device or passphrase
+// key and whether it is currently locked
+func (a *App) GetChatKeyStatus() (*chat.KeyStatus, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	status := a.chatSvc.GetKeyStatus()
+	return &status, nil
+}
+
+// UnlockChat enters the passphrase protecting chat history
+func (a *App) UnlockChat(passphrase string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.Unlock(passphrase)
+}
+
+// LockChat forgets the chat passphrase until it is entered again
+func (a *App) LockChat() error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	a.chatSvc.Lock()
+	return nil
+}
+
+// SetChatPassphrase re-encrypts chat history under a key derived from
+// passphrase; an empty passphrase goes back to the device-bound key. Setting
+// the same passphrase again rotates the key.
+func (a *App) SetChatPassphrase(passphrase string) (*chat.RotationResult, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.RotateKey(passphrase)
+}
+
+// CountUndecryptableChatMessages counts messages the current key cannot
+// decrypt, e.g. after moving the vault to another machine
+func (a *App) CountUndecryptableChatMessages() (int, error) {
+	if err := a.ensureChatService(); err != nil {
+		return 0, err
+	}
+	return a.chatSvc.CountUndecryptableMessages()
 }
 
 func (a *App) ExportChatSession(sessionID, format string) (string, error) {
 	if err := a.ensureChatService(); err != nil {
 		return "", err
 	}
-	format = strings.ToLower(strings.TrimSpace(format))
-	if format != "txt" {
-		format = "json"
+	sessionID = strings.TrimSpace(sessionID)
+	out, err := a.chatSvc.ExportSession(sessionID, format)
+	if err == nil {
+		logger.Audit(a.ctx, auditChatExport, sessionID, nil, map[string]interface{}{"format": format})
 	}
-	return a.chatSvc.ExportSession(strings.TrimSpace(sessionID), format)
+	return out, err
+}
+
+// ExportSessionDefault exports a session in the preferred export format from the storage options
+func (a *App) ExportSessionDefault(sessionID string) (string, error) {
+	if err := a.ensureChatService(); err != nil {
+		return "", err
+	}
+	return a.chatSvc.ExportSessionDefault(strings.TrimSpace(sessionID))
 }
 
 func (a *App) BackupChatNow() (string, error) {
 	if err := a.ensureChatService(); err != nil {
 		return "", err
 	}
-	return a.chatSvc.BackupNow(context.Background())
+	path, err := a.chatSvc.BackupNow(context.Background())
+	if err == nil {
+		logger.Audit(a.ctx, auditChatBackup, path, nil, nil)
+	}
+	return path, err
+}
+
+// SetChatMessagePinned pins or unpins a chat message
+func (a *App) SetChatMessagePinned(messageID string, pinned bool) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.SetMessagePinned(strings.TrimSpace(messageID), pinned)
+}
+
+// ListPinnedChatMessages lists pinned messages of a session, or of all sessions when sessionID is empty
+func (a *App) ListPinnedChatMessages(sessionID string) ([]chat.MessageDTO, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.ListPinnedMessages(strings.TrimSpace(sessionID))
+}
+
+// GetChatSessionNotes returns the free-form notes of a session
+func (a *App) GetChatSessionNotes(sessionID string) (string, error) {
+	if err := a.ensureChatService(); err != nil {
+		return "", err
+	}
+	return a.chatSvc.GetSessionNotes(strings.TrimSpace(sessionID))
+}
+
+// SetChatSessionNotes replaces the free-form notes of a session
+func (a *App) SetChatSessionNotes(sessionID, notes string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.SetSessionNotes(strings.TrimSpace(sessionID), notes)
+}
+
+// AttachNoteToSession makes a vault note part of every answer in a session;
+// its most relevant chunks are included ahead of retrieved ones
+func (a *App) AttachNoteToSession(sessionID, path string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	path = strings.TrimSpace(path)
+	if !a.fm.FileExists(path) {
+		return fmt.Errorf("note not found: %s", path)
+	}
+	return a.chatSvc.AttachNote(strings.TrimSpace(sessionID), path)
+}
+
+// DetachNoteFromSession stops including a note in a session's answers
+func (a *App) DetachNoteFromSession(sessionID, path string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.DetachNote(strings.TrimSpace(sessionID), path)
+}
+
+// GetSessionAttachedNotes lists the notes attached to a session
+func (a *App) GetSessionAttachedNotes(sessionID string) ([]string, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.ListAttachedNotes(strings.TrimSpace(sessionID))
+}
+
+// GetChatUsageBySession returns token usage and estimated cost per chat
+// session, most expensive first
+func (a *App) GetChatUsageBySession() ([]chat.SessionUsage, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.GetUsageBySession()
+}
+
+// GetChatUsageByDay returns chat token usage and estimated cost per day
+// between rangeStart and rangeEnd (Unix ms, 0 = unbounded)
+func (a *App) GetChatUsageByDay(rangeStart, rangeEnd int64) ([]chat.DailyUsage, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.GetUsageByDay(rangeStart, rangeEnd)
+}
+
+// ListChatBackups lists stored chat backups, newest first
+func (a *App) ListChatBackups() ([]chat.BackupInfo, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.ListBackups()
+}
+
+// DeleteChatBackup removes a backup by name
+func (a *App) DeleteChatBackup(name string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.DeleteBackup(strings.TrimSpace(name))
+}
+
+func (a *App) ExportSessionShareable(sessionID string) (string, error) {
+	if err := a.ensureChatService(); err != nil {
+		return "", err
+	}
+	return a.chatSvc.ExportShareable(strings.TrimSpace(sessionID))
+}
+
+func (a *App) GetChatShareOptions() (map[string]interface{}, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	opts := a.chatSvc.GetShareOptions()
+	return map[string]interface{}{
+		"format":           opts.Format,
+		"source_redaction": opts.SourceRedaction,
+		"redact_pii":       opts.RedactPII,
+		"custom_patterns":  opts.CustomPatterns,
+		"placeholder":      opts.Placeholder,
+	}, nil
+}
+
+func (a *App) SetChatShareOptions(format, sourceRedaction string, redactPII bool, customPatterns []string, placeholder string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.SetShareOptions(chat.ShareOptions{
+		Format:          strings.TrimSpace(format),
+		SourceRedaction: strings.TrimSpace(sourceRedaction),
+		RedactPII:       redactPII,
+		CustomPatterns:  customPatterns,
+		Placeholder:     placeholder,
+	})
+}
+
+// AttachNoteToChat snapshots a note's current content into the vault attachments
+// folder and returns the attachment to pass to RAGQueryWithAttachments
+func (a *App) AttachNoteToChat(notePath string) (*chat.Attachment, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	note, err := a.fm.ReadFile(strings.TrimSpace(notePath))
+	if err != nil {
+		return nil, err
+	}
+	return a.chatSvc.SaveNoteSnapshot(note.Path, note.Content)
+}
+
+// AttachImageToChat stores a base64-encoded image in the vault attachments folder
+func (a *App) AttachImageToChat(name, dataBase64 string) (*chat.Attachment, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	if i := strings.Index(dataBase64, ","); i >= 0 && strings.HasPrefix(dataBase64, "data:") {
+		dataBase64 = dataBase64[i+1:]
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(dataBase64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image data: %w", err)
+	}
+	return a.chatSvc.SaveImageAttachment(name, data)
+}
+
+// ReadChatAttachment returns a stored attachment as base64 for display
+func (a *App) ReadChatAttachment(path string) (string, error) {
+	if err := a.ensureChatService(); err != nil {
+		return "", err
+	}
+	data, err := a.chatSvc.ReadAttachment(strings.TrimSpace(path))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// UpdateChatMessage replaces the content of a message without re-running anything
+func (a *App) UpdateChatMessage(messageID, content string) (*chat.MessageDTO, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	return a.chatSvc.UpdateMessage(strings.TrimSpace(messageID), content)
+}
+
+// DeleteChatMessage removes a single message from its session
+func (a *App) DeleteChatMessage(messageID string) error {
+	if err := a.ensureChatService(); err != nil {
+		return err
+	}
+	return a.chatSvc.DeleteMessage(strings.TrimSpace(messageID))
+}
+
+// EditAndResendChatMessage replaces a user message, drops every message after
+// it and answers the edited question again
+func (a *App) EditAndResendChatMessage(sessionID, messageID, content string) (map[string]interface{}, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	sessionID, messageID = strings.TrimSpace(sessionID), strings.TrimSpace(messageID)
+	msg, err := a.chatSvc.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SessionID != sessionID || msg.Role != "user" {
+		return nil, fmt.Errorf("only user messages of this session can be edited and resent")
+	}
+	if msg, err = a.chatSvc.UpdateMessage(messageID, content); err != nil {
+		return nil, err
+	}
+	return a.resendFrom(msg)
+}
+
+// RegenerateLastAnswer drops everything after the session's last user message
+// and answers it again
+func (a *App) RegenerateLastAnswer(sessionID string) (map[string]interface{}, error) {
+	if err := a.ensureChatService(); err != nil {
+		return nil, err
+	}
+	msg, err := a.chatSvc.LastUserMessage(strings.TrimSpace(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("no question to regenerate an answer for: %w", err)
+	}
+	return a.resendFrom(msg)
+}
+
+// resendFrom truncates the session after a user message and re-runs the RAG query for it
+func (a *App) resendFrom(msg *chat.MessageDTO) (map[string]interface{}, error) {
+	if a.rag == nil {
+		return nil, fmt.Errorf("RAG service not initialized")
+	}
+	if msg.Undecryptable {
+		return nil, fmt.Errorf("message cannot be decrypted with the current key")
+	}
+	if _, err := a.chatSvc.TruncateAfter(msg.SessionID, msg.ID); err != nil {
+		return nil, err
+	}
+	a.notifyActivity()
+	return a.answerInSession(msg.SessionID, msg.Content, msg.Attachments, rag.QueryOptions{})
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/bibtex"
+	"notebit/pkg/graph"
+	"notebit/pkg/logger"
+)
+
+// Citation is what the editor needs to cite a reference in a note
+type Citation struct {
+	Key       string       `json:"key"`
+	Text      string       `json:"text"`      // Citation to insert, e.g. [@turing1950]
+	Label     string       `json:"label"`     // Short author-year label
+	Formatted string       `json:"formatted"` // One-line reference for a bibliography
+	Cited     bool         `json:"cited"`     // The note already cites the key
+	Reference bibtex.Entry `json:"reference"`
+}
+
+// references caches the parsed bibliography until its file changes
+type references struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	size    int64
+	entries []bibtex.Entry
+}
+
+// ============ CITATION API METHODS ============
+
+// ListReferences returns the entries of the vault bibliography whose key,
+// title, authors or year contain every word of query; an empty query lists
+// them all. A vault without a bibliography has no references.
+func (a *App) ListReferences(query string) ([]bibtex.Entry, error) {
+	entries, _, err := a.loadReferences()
+	if err != nil {
+		return nil, err
+	}
+	matches := bibtex.Search(entries, query)
+	if matches == nil {
+		matches = []bibtex.Entry{}
+	}
+	return matches, nil
+}
+
+// InsertCitation returns the citation of key for the note at notePath: the
+// [@key] text to insert and the reference it stands for. The note is not
+// changed; the editor inserts the text at the cursor.
+func (a *App) InsertCitation(notePath, key string) (*Citation, error) {
+	key = strings.TrimPrefix(strings.TrimSpace(key), "@")
+	entries, _, err := a.loadReferences()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Key != key {
+			continue
+		}
+		citation := &Citation{
+			Key:       key,
+			Text:      "[@" + key + "]",
+			Label:     entry.Label(),
+			Formatted: entry.Format(),
+			Reference: entry,
+		}
+		if notePath != "" {
+			if note, err := a.fm.ReadFile(notePath); err == nil {
+				for _, cited := range bibtex.CitationKeys(note.Content) {
+					if cited == key {
+						citation.Cited = true
+						break
+					}
+				}
+			}
+		}
+		return citation, nil
+	}
+	return nil, fmt.Errorf("no reference with key %q in %s", key, a.cfg.GetNotesConfig().BibliographyFile)
+}
+
+// SetBibliographyFile sets the BibTeX file notes cite from, relative to the
+// vault root
+func (a *App) SetBibliographyFile(bibPath string) error {
+	bibPath = strings.Trim(strings.TrimSpace(strings.ReplaceAll(bibPath, "\\", "/")), "/")
+	if bibPath == "" {
+		bibPath = "references.bib"
+	}
+	if !strings.EqualFold(path.Ext(bibPath), ".bib") {
+		return fmt.Errorf("bibliography must be a .bib file")
+	}
+	notesCfg := a.cfg.GetNotesConfig()
+	notesCfg.BibliographyFile = bibPath
+	a.cfg.SetNotesConfig(notesCfg)
+	return a.cfg.Save()
+}
+
+// loadReferences returns the entries of the vault bibliography, parsing it
+// again only when it changed, and a version that changes with it (0 when
+// there is no bibliography)
+func (a *App) loadReferences() ([]bibtex.Entry, int64, error) {
+	fullPath, err := a.fm.AbsPath(a.cfg.GetNotesConfig().BibliographyFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Stat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	version := info.ModTime().UnixNano() ^ info.Size()
+
+	a.refs.mu.Lock()
+	defer a.refs.mu.Unlock()
+	if a.refs.path == fullPath && a.refs.modTime.Equal(info.ModTime()) && a.refs.size == info.Size() {
+		return a.refs.entries, version, nil
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, errs := bibtex.Parse(string(data))
+	if len(errs) > 0 {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{
+			"path":   fullPath,
+			"errors": len(errs),
+			"first":  errs[0].Error(),
+		}, "Skipped malformed bibliography entries")
+	}
+	a.refs.path, a.refs.modTime, a.refs.size, a.refs.entries = fullPath, info.ModTime(), info.Size(), entries
+	return entries, version, nil
+}
+
+// graphReferences is the graph's reference source: the vault bibliography
+// by key
+func (a *App) graphReferences() (map[string]graph.Reference, int64) {
+	entries, version, err := a.loadReferences()
+	if err != nil || len(entries) == 0 {
+		return nil, version
+	}
+	bibFile := a.cfg.GetNotesConfig().BibliographyFile
+	refs := make(map[string]graph.Reference, len(entries))
+	for _, entry := range entries {
+		refs[entry.Key] = graph.Reference{Key: entry.Key, Label: entry.Label(), Path: bibFile}
+	}
+	return refs, version
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"notebit/pkg/database"
+	"notebit/pkg/graph"
+	"notebit/pkg/jobs"
+	"notebit/pkg/logger"
+	"notebit/pkg/rag"
+)
+
+// ============ COLLECTION API METHODS ============
+
+// CreateCollection creates a named collection
+func (a *App) CreateCollection(name, description string) (*database.Collection, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().CreateCollection(name, description)
+}
+
+// ListCollections returns all collections with their items
+func (a *App) ListCollections() ([]database.Collection, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ListCollections()
+}
+
+// GetCollection returns a collection and the note paths it currently resolves to
+func (a *App) GetCollection(id uint) (map[string]interface{}, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	repo := a.dbm.Repository()
+	collection, err := repo.GetCollection(id)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := repo.ResolveCollectionPaths(id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"collection": collection,
+		"paths":      paths,
+	}, nil
+}
+
+// UpdateCollection renames a collection and updates its description
+func (a *App) UpdateCollection(id uint, name, description string) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().UpdateCollection(id, name, description)
+}
+
+// DeleteCollection deletes a collection; the notes themselves are not touched
+func (a *App) DeleteCollection(id uint) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().DeleteCollection(id)
+}
+
+// AddToCollection adds a note, folder or tag (kind "note", "folder" or "tag")
+func (a *App) AddToCollection(id uint, kind, value string) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().AddCollectionItem(id, kind, value)
+}
+
+// RemoveFromCollection removes a note, folder or tag from a collection
+func (a *App) RemoveFromCollection(id uint, kind, value string) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().RemoveCollectionItem(id, kind, value)
+}
+
+// RAGQueryInCollection performs a RAG query that only retrieves context from the collection
+func (a *App) RAGQueryInCollection(sessionID, query string, collectionID uint) (map[string]interface{}, error) {
+	paths, err := a.collectionPaths(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("collection has no indexed notes")
+	}
+	return a.ragQuery(sessionID, query, nil, rag.QueryOptions{ScopePaths: paths})
+}
+
+// GetCollectionGraph returns the knowledge graph restricted to a collection's notes
+func (a *App) GetCollectionGraph(collectionID uint) (*graph.GraphData, error) {
+	paths, err := a.collectionPaths(collectionID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := a.GetGraphData()
+	if err != nil {
+		return nil, err
+	}
+	return graph.FilterByPaths(data, paths), nil
+}
+
+// ExportCollection writes the collection's notes into a zip archive under
+// data/collection_exports, keeping their vault-relative paths, and returns its path
+func (a *App) ExportCollection(collectionID uint) (string, error) {
+	return a.exportCollection(context.Background(), collectionID, nil)
+}
+
+// exportCollection implements ExportCollection, reporting each written note to
+// report (if set) and stopping early when ctx is cancelled
+func (a *App) exportCollection(ctx context.Context, collectionID uint, report jobs.Reporter) (string, error) {
+	paths, err := a.collectionPaths(collectionID)
+	if err != nil {
+		return "", err
+	}
+	collection, err := a.dbm.Repository().GetCollection(collectionID)
+	if err != nil {
+		return "", err
+	}
+	basePath := a.fm.GetBasePath()
+	exportDir := filepath.Join(basePath, "data", "collection_exports")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_", " ", "_").Replace(collection.Name)
+	target := filepath.Join(exportDir, fmt.Sprintf("%s_%s.zip", name, time.Now().Format("20060102_150405")))
+
+	out, err := os.Create(target)
+	if err != nil {
+		return "", err
+	}
+	zw := zip.NewWriter(out)
+	for i, p := range paths {
+		if err := ctx.Err(); err != nil {
+			zw.Close()
+			out.Close()
+			os.Remove(target)
+			return "", err
+		}
+		if report != nil {
+			report(i, len(paths), p)
+		}
+		note, err := a.fm.ReadFile(p)
+		if err != nil {
+			continue // indexed but gone from disk
+		}
+		w, err := zw.Create(filepath.ToSlash(p))
+		if err != nil {
+			zw.Close()
+			out.Close()
+			return "", err
+		}
+		if _, err := w.Write([]byte(note.Content)); err != nil {
+			zw.Close()
+			out.Close()
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	logger.Audit(a.ctx, auditCollectionExport, target, nil, map[string]interface{}{"collection_id": collectionID, "notes": len(paths)})
+	return target, nil
+}
+
+func (a *App) collectionPaths(collectionID uint) ([]string, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ResolveCollectionPaths(collectionID)
+}
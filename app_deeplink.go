@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"notebit/pkg/logger"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// DeepLinkScheme is the custom URL scheme registered for the app (see
+// wails.json "protocols")
+const DeepLinkScheme = "notebit"
+
+// DeepLink is a parsed notebit:// URL:
+//
+//	notebit://open?path=Projects/Plan.md[&vault=Work]
+//	notebit://open?vault=Work
+//	notebit://search?q=quarterly+goals[&vault=Work]
+//
+// vault is optional and must be the name of a registered vault: a link from a
+// web page must not make the app open and index an arbitrary folder. An open
+// link without a path only opens the vault.
+type DeepLink struct {
+	Action string `json:"action"` // "open" or "search"
+	Path   string `json:"path,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Vault  string `json:"vault,omitempty"`
+}
+
+// deepLinks holds the link received before the frontend could listen for
+// it, and the folder of its vault
+type deepLinks struct {
+	mu           sync.Mutex
+	pending      *DeepLink
+	pendingVault string
+}
+
+// parseDeepLink validates a notebit:// URL
+func parseDeepLink(raw string) (*DeepLink, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, DeepLinkScheme) {
+		return nil, fmt.Errorf("not a %s:// link: %s", DeepLinkScheme, raw)
+	}
+	// notebit://open?... puts the action in the host, notebit:open?... in Opaque
+	action := strings.ToLower(strings.Trim(u.Host+u.Opaque+u.Path, "/"))
+	query := u.Query()
+	link := &DeepLink{Action: action, Vault: strings.TrimSpace(query.Get("vault"))}
+	if link.Vault != "" && !isVaultName(link.Vault) {
+		return nil, fmt.Errorf("vault must be the name of a registered vault, not a path: %s", link.Vault)
+	}
+	switch action {
+	case "open":
+		link.Path = strings.TrimLeft(query.Get("path"), "/")
+		if link.Path == "" && link.Vault == "" {
+			return nil, fmt.Errorf("open link needs a path or a vault")
+		}
+		if strings.Contains("/"+link.Path+"/", "/../") {
+			return nil, fmt.Errorf("invalid note path: %s", link.Path)
+		}
+	case "search":
+		link.Query = strings.TrimSpace(query.Get("q"))
+		if link.Query == "" {
+			return nil, fmt.Errorf("search link needs a query")
+		}
+	default:
+		return nil, fmt.Errorf("unknown link action: %q", action)
+	}
+	return link, nil
+}
+
+// handleDeepLink opens the vault named in the link if needed and forwards the
+// link to the frontend as a "deeplink" event. Links arriving before startup
+// are kept for ConsumePendingDeepLink.
+func (a *App) handleDeepLink(raw string) {
+	link, err := parseDeepLink(raw)
+	if err != nil {
+		logger.Warn("Ignoring deep link: %v", err)
+		return
+	}
+	vaultPath := ""
+	if link.Vault != "" {
+		if vaultPath, err = a.resolveVault(link.Vault); err != nil {
+			logger.Warn("Ignoring deep link: %v", err)
+			return
+		}
+	}
+	a.followDeepLink(link, vaultPath)
+}
+
+// followDeepLink acts on a parsed link, opening the vault folder vaultPath
+// first unless it is "", see handleDeepLink
+func (a *App) followDeepLink(link *DeepLink, vaultPath string) {
+	logger.InfoWithFields(a.ctx, map[string]interface{}{"action": link.Action, "path": link.Path}, "Deep link received")
+
+	if a.ctx == nil {
+		a.links.mu.Lock()
+		a.links.pending, a.links.pendingVault = link, vaultPath
+		a.links.mu.Unlock()
+		return
+	}
+	if vaultPath != "" {
+		if err := a.openVault(vaultPath); err != nil {
+			runtime.LogErrorf(a.ctx, "Deep link vault %q: %v", vaultPath, err)
+			return
+		}
+	}
+	runtime.WindowUnminimise(a.ctx)
+	runtime.WindowShow(a.ctx)
+	runtime.EventsEmit(a.ctx, "deeplink", link)
+}
+
+// isVaultName reports whether name can be a vault name in a link rather
+// than a path
+func isVaultName(name string) bool {
+	return name != "." && name != ".." && !strings.ContainsAny(name, `/\:`) && !filepath.IsAbs(name)
+}
+
+// resolveVault maps the name of a registered vault to its folder. Links can
+// only open vaults the user already opened.
+func (a *App) resolveVault(name string) (string, error) {
+	if a.vaults == nil {
+		a.loadVaultRegistry() // launched with a link, before startup
+	}
+	if a.vaults != nil && isVaultName(name) {
+		for _, v := range a.vaults.List() {
+			if strings.EqualFold(v.Name, name) {
+				return v.Path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no registered vault named %q", name)
+}
+
+// ConsumePendingDeepLink returns (once) the link the app was launched with,
+// or nil. The frontend calls it after subscribing to "deeplink" events.
+func (a *App) ConsumePendingDeepLink() *DeepLink {
+	a.links.mu.Lock()
+	link, vaultPath := a.links.pending, a.links.pendingVault
+	a.links.pending, a.links.pendingVault = nil, ""
+	a.links.mu.Unlock()
+
+	if link != nil && vaultPath != "" {
+		if err := a.openVault(vaultPath); err != nil {
+			logger.Warn("Deep link vault %q: %v", link.Vault, err)
+		}
+	}
+	return link
+}
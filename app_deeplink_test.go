@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+func TestParseDeepLink(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    DeepLink
+		wantErr bool
+	}{
+		{raw: "notebit://open?path=Projects/Plan.md", want: DeepLink{Action: "open", Path: "Projects/Plan.md"}},
+		{raw: "notebit://open?path=/Plan.md&vault=Work", want: DeepLink{Action: "open", Path: "Plan.md", Vault: "Work"}},
+		{raw: "notebit:open?vault=My%20Notes", want: DeepLink{Action: "open", Vault: "My Notes"}},
+		{raw: "NOTEBIT://Search?q=+quarterly+goals", want: DeepLink{Action: "search", Query: "quarterly goals"}},
+		{raw: "notebit://open", wantErr: true},
+		{raw: "notebit://search?q=", wantErr: true},
+		{raw: "notebit://delete?path=a.md", wantErr: true},
+		{raw: "https://open?path=a.md", wantErr: true},
+		{raw: "notebit://open?path=../secret.md", wantErr: true},
+		{raw: "notebit://open?path=notes/../../secret.md", wantErr: true},
+		{raw: "notebit://open?vault=/", wantErr: true},
+		{raw: "notebit://open?vault=/home/x/.ssh", wantErr: true},
+		{raw: "notebit://open?vault=..", wantErr: true},
+		{raw: "notebit://open?vault=../Work", wantErr: true},
+		{raw: `notebit://open?vault=C:%5CUsers`, wantErr: true},
+		{raw: `notebit://open?vault=..%5C..%5CWindows`, wantErr: true},
+	}
+	for _, tt := range tests {
+		link, err := parseDeepLink(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDeepLink(%q) = %+v, want an error", tt.raw, link)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDeepLink(%q): %v", tt.raw, err)
+			continue
+		}
+		if *link != tt.want {
+			t.Errorf("parseDeepLink(%q) = %+v, want %+v", tt.raw, *link, tt.want)
+		}
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	registry, err := config.LoadVaultRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(t.TempDir(), "Work")
+	if err := registry.Touch(work, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{vaults: registry}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Work", want: work},
+		{name: "work", want: work},
+		{name: "Personal", wantErr: true},
+		{name: work, wantErr: true},
+		{name: "/", wantErr: true},
+		{name: filepath.Join(work, ".."), wantErr: true},
+		{name: "../Work", wantErr: true},
+		{name: "..", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := a.resolveVault(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("resolveVault(%q) = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveVault(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestLinkForPath(t *testing.T) {
+	registry, err := config.LoadVaultRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	work := filepath.Join(root, "Work")
+	other := filepath.Join(root, "Other")
+	for _, file := range []string{filepath.Join(work, "sub", "a.md"), filepath.Join(other, "b.md"), filepath.Join(other, "c.txt")} {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := registry.Touch(work, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{vaults: registry}
+
+	tests := []struct {
+		path      string
+		want      *DeepLink
+		wantVault string
+	}{
+		{path: "Work", want: &DeepLink{Action: "open", Vault: "Work"}, wantVault: work},
+		{path: "Work/sub/a.md", want: &DeepLink{Action: "open", Vault: "Work", Path: "sub/a.md"}, wantVault: work},
+		// Unregistered folders are opened by path, which stays out of the link
+		{path: "Other", want: &DeepLink{Action: "open"}, wantVault: other},
+		{path: "Other/b.md", want: &DeepLink{Action: "open", Path: "b.md"}, wantVault: other},
+		{path: "Other/c.txt"},
+		{path: "Missing"},
+	}
+	for _, tt := range tests {
+		link, vaultPath := a.linkForPath(filepath.FromSlash(tt.path), root)
+		if !reflect.DeepEqual(link, tt.want) || vaultPath != tt.wantVault {
+			t.Errorf("linkForPath(%q) = %+v, %q, want %+v, %q", tt.path, link, vaultPath, tt.want, tt.wantVault)
+		}
+	}
+}
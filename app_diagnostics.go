@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+// crashLogLines is how much of the log a diagnostic bundle includes
+const crashLogLines = 200
+
+// ============ DIAGNOSTICS API METHODS ============
+
+// CreateDiagnosticBundle writes a zip with goroutine stacks, the redacted
+// config, database stats and recent log lines for a support request, and
+// returns its path
+func (a *App) CreateDiagnosticBundle() (string, error) {
+	path, err := a.writeDiagnosticBundle("diagnostic", "")
+	if err != nil {
+		return "", err
+	}
+	logger.Audit(a.ctx, auditDiagnostics, path, nil, nil)
+	return path, nil
+}
+
+// writeCrashReport is the logger's panic handler: background goroutines
+// (jobs, schedulers, the watcher) report panics through it, and it writes
+// logs/crash-*.zip with the panic and its stack.
+func (a *App) writeCrashReport(r any, stack []byte) {
+	report := fmt.Sprintf("panic: %v\n\n%s", r, stack)
+	if path, err := a.writeDiagnosticBundle("crash", report); err == nil {
+		fmt.Fprintf(os.Stderr, "notebit panicked; report written to %s\n", path)
+	} else {
+		fmt.Fprintf(os.Stderr, "notebit panicked; failed to write report: %v\n", err)
+	}
+}
+
+// appLogDir is the directory of the application log
+func appLogDir() string {
+	if l := logger.GetDefault(); l != nil {
+		return l.LogDir()
+	}
+	return "logs"
+}
+
+// writeDiagnosticBundle creates <logdir>/<kind>-<timestamp>.zip. report is
+// put first in report.txt (e.g. the panic and its stack).
+func (a *App) writeDiagnosticBundle(kind, report string) (string, error) {
+	logDir := appLogDir()
+	var tail []string
+	if l := logger.GetDefault(); l != nil {
+		tail, _ = l.Tail(crashLogLines)
+	}
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(logDir, fmt.Sprintf("%s-%s.zip", kind, time.Now().Format("20060102-150405")))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	zw := zip.NewWriter(out)
+
+	files := map[string][]byte{
+		"report.txt":   []byte(a.diagnosticReport(report)),
+		"log_tail.txt": []byte(strings.Join(tail, "\n") + "\n"),
+	}
+	if cfg, err := a.cfg.Export(false); err == nil {
+		files["config.json"] = cfg
+	}
+	stats := map[string]interface{}{"logger": logger.GetMetrics()}
+	if a.dbm != nil && a.dbm.IsInitialized() {
+		if dbStats, err := a.GetDatabaseStats(); err == nil {
+			stats["database"] = dbStats
+		}
+	}
+	if data, err := json.MarshalIndent(stats, "", "  "); err == nil {
+		files["stats.json"] = data
+	}
+
+	for _, name := range []string{"report.txt", "config.json", "stats.json", "log_tail.txt"} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			zw.Close()
+			out.Close()
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// diagnosticReport describes the runtime, followed by report and the stacks
+// of all goroutines
+func (a *App) diagnosticReport(report string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "go: %s %s/%s\n", goruntime.Version(), goruntime.GOOS, goruntime.GOARCH)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "module: %s %s\n", info.Main.Path, info.Main.Version)
+	}
+	fmt.Fprintf(&b, "vault open: %t\n\n", a.fm.GetBasePath() != "")
+	if report != "" {
+		b.WriteString(report)
+		b.WriteString("\n\n")
+	}
+	buf := make([]byte, 1<<20)
+	buf = buf[:goruntime.Stack(buf, true)]
+	b.WriteString("goroutines:\n")
+	b.Write(buf)
+	return b.String()
+}
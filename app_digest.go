@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"notebit/pkg/config"
+	"notebit/pkg/digest"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============ DIGEST API METHODS ============
+
+// GenerateDigest summarizes notes modified in the last days days (<= 0 uses
+// the configured period). With save set, the digest is also written to its
+// weekly note, e.g. Digests/2025-14.md.
+func (a *App) GenerateDigest(days int, save bool) (*digest.Digest, error) {
+	d, err := a.newDigestGenerator().Generate(context.Background(), time.Now(), days)
+	if err != nil {
+		return nil, err
+	}
+	if save {
+		if err := a.writeNote(d.Path, d.Content); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// GetDigestConfig returns the digest settings
+func (a *App) GetDigestConfig() (config.DigestConfig, error) {
+	return a.cfg.GetDigestConfig(), nil
+}
+
+// SetDigestConfig updates the digest settings and restarts the digest scheduler
+func (a *App) SetDigestConfig(enabled bool, days int, folder string, weekday int, timeOfDay string) error {
+	if weekday < 0 || weekday > 6 {
+		return fmt.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	folder = strings.Trim(strings.TrimSpace(folder), "/")
+	if folder == "" {
+		folder = "Digests"
+	}
+	if days <= 0 {
+		days = 7
+	}
+	timeOfDay = strings.TrimSpace(timeOfDay)
+	if timeOfDay == "" {
+		timeOfDay = "09:00"
+	}
+	a.cfg.SetDigestConfig(config.DigestConfig{
+		Enabled: enabled,
+		Days:    days,
+		Folder:  folder,
+		Weekday: weekday,
+		Time:    timeOfDay,
+	})
+	a.startDigestScheduler()
+	return a.cfg.Save()
+}
+
+// GetDigestStatus returns the digest scheduler state, including the next planned run
+func (a *App) GetDigestStatus() (digest.Status, error) {
+	if a.digests == nil {
+		return digest.Status{}, fmt.Errorf("digest scheduler not initialized")
+	}
+	return a.digests.Status(), nil
+}
+
+func (a *App) newDigestGenerator() *digest.Generator {
+	return digest.NewGenerator(a.fm, a.dbm, a.llm, a.cfg)
+}
+
+// startDigestScheduler (re)starts the weekly digest with the current config
+func (a *App) startDigestScheduler() {
+	if a.digests == nil {
+		a.digests = digest.NewScheduler(func(ctx context.Context) (string, error) {
+			d, err := a.newDigestGenerator().Generate(ctx, time.Now(), 0)
+			if err != nil {
+				return "", err
+			}
+			return d.Path, a.writeNote(d.Path, d.Content)
+		}, func() string {
+			if basePath := a.fm.GetBasePath(); basePath != "" {
+				return filepath.Join(basePath, "data")
+			}
+			return ""
+		})
+	}
+	a.digests.Start(a.cfg.GetDigestConfig())
+}
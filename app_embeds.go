@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"notebit/pkg/files"
+	"notebit/pkg/graph"
+)
+
+// maxEmbedDepth bounds how deeply embeds inside embedded notes are expanded
+const maxEmbedDepth = 4
+
+// Reasons an embed could not be resolved, besides graph.UnresolvedMissing
+// and graph.UnresolvedAmbiguous
+const (
+	embedNoHeading = "heading not found"
+	embedNoBlock   = "block not found"
+	embedCycle     = "cycle"
+	embedTooDeep   = "too deep"
+)
+
+// Embed is a ![[Note#Heading]] transclusion in a note with the content it
+// stands for
+type Embed struct {
+	Link       files.WikiLink `json:"link"`
+	Path       string         `json:"path"`                 // Note the embed resolved to, "" if none
+	Content    string         `json:"content"`              // Embedded markdown with nested embeds expanded
+	Error      string         `json:"error,omitempty"`      // Why the embed could not be resolved
+	Candidates []string       `json:"candidates,omitempty"` // Matching notes of an ambiguous embed
+}
+
+// ============ EMBED API METHODS ============
+
+// ResolveEmbeds returns the note embeds of the note at notePath in order,
+// each with the content to render in its place. Embeds within embedded
+// content are expanded up to maxEmbedDepth levels; nested embeds that cannot
+// be expanded, e.g. because they would embed a note into itself, are left as
+// written. Image and other attachment embeds are not returned.
+func (a *App) ResolveEmbeds(notePath string) ([]Embed, error) {
+	if a.graph == nil {
+		return nil, fmt.Errorf("graph service not initialized - please open a folder first")
+	}
+	note, err := a.fm.ReadFile(notePath)
+	if err != nil {
+		return nil, err
+	}
+	resolve, err := a.graph.Resolver()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &embedResolver{fm: a.fm, resolve: resolve, notes: map[string]string{notePath: note.Content}}
+	embeds := []Embed{}
+	for _, link := range files.FindWikiLinks(note.Content) {
+		if !link.Embed {
+			continue
+		}
+		if embed, ok := r.embed(link, notePath, []string{embedKey(notePath, files.WikiLink{})}); ok {
+			embeds = append(embeds, embed)
+		}
+	}
+	return embeds, nil
+}
+
+// embedResolver resolves the embeds of one note, reading each note once
+type embedResolver struct {
+	fm      *files.Manager
+	resolve graph.ResolveFunc
+	notes   map[string]string
+}
+
+// embed resolves link, found in the note at from. stack holds the embeds
+// being expanded, outermost first. It reports false for attachment embeds.
+func (r *embedResolver) embed(link files.WikiLink, from string, stack []string) (Embed, bool) {
+	embed := Embed{Link: link}
+	target := from
+	if link.Target != "" {
+		var candidates []string
+		target, candidates = r.resolve(link.Target)
+		if target == "" {
+			if link.IsAttachment() {
+				return embed, false
+			}
+			embed.Error, embed.Candidates = graph.UnresolvedMissing, candidates
+			if len(candidates) > 0 {
+				embed.Error = graph.UnresolvedAmbiguous
+			}
+			return embed, true
+		}
+	}
+	embed.Path = target
+
+	key := embedKey(target, link)
+	for _, k := range stack {
+		if k == key {
+			embed.Error = embedCycle
+			return embed, true
+		}
+	}
+	if len(stack) > maxEmbedDepth {
+		embed.Error = embedTooDeep
+		return embed, true
+	}
+
+	content, ok := r.notes[target]
+	if !ok {
+		note, err := r.fm.ReadFile(target)
+		if err != nil {
+			embed.Error = graph.UnresolvedMissing
+			return embed, true
+		}
+		content = note.Content
+		r.notes[target] = content
+	}
+
+	switch {
+	case link.Block != "":
+		start, end, ok := files.BlockRange(content, link.Block)
+		if !ok {
+			embed.Error = embedNoBlock
+			return embed, true
+		}
+		content = content[start:end]
+	case link.Heading != "":
+		start, end, ok := files.HeadingRange(content, link.Heading)
+		if !ok {
+			embed.Error = embedNoHeading
+			return embed, true
+		}
+		content = content[start:end]
+	default:
+		content = files.StripFrontmatter(content)
+	}
+
+	embed.Content = r.expand(content, target, append(stack, key))
+	return embed, true
+}
+
+// expand replaces the embeds in content, from the note at from, with what
+// they embed, leaving those that cannot be resolved as written
+func (r *embedResolver) expand(content, from string, stack []string) string {
+	var sb strings.Builder
+	prev := 0
+	for _, link := range files.FindWikiLinks(content) {
+		if !link.Embed {
+			continue
+		}
+		nested, ok := r.embed(link, from, stack)
+		if !ok || nested.Error != "" {
+			continue
+		}
+		sb.WriteString(content[prev:link.Offset])
+		sb.WriteString(strings.TrimRight(nested.Content, "\n"))
+		prev = link.Offset + len(link.Text)
+	}
+	sb.WriteString(content[prev:])
+	return sb.String()
+}
+
+// embedKey identifies what an embed shows: a whole note, or one of its
+// headings or blocks
+func embedKey(notePath string, link files.WikiLink) string {
+	return notePath + "#" + strings.ToLower(link.Heading) + "^" + link.Block
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"notebit/pkg/files"
+	"notebit/pkg/graph"
+)
+
+// newEmbedResolver writes notes, keyed by name, to a temporary vault and
+// resolves names to them
+func newEmbedResolver(t *testing.T, notes map[string]string) *embedResolver {
+	t.Helper()
+	fm := files.NewManager()
+	if err := fm.SetBasePath(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range notes {
+		if err := fm.CreateFile(name+".md", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolve := func(target string) (string, []string) {
+		if _, ok := notes[target]; ok {
+			return target + ".md", nil
+		}
+		return "", nil
+	}
+	return &embedResolver{fm: fm, resolve: resolve, notes: map[string]string{}}
+}
+
+// rootEmbeds resolves the embeds of the note at notePath the way
+// ResolveEmbeds does
+func rootEmbeds(r *embedResolver, notePath, content string) []Embed {
+	var embeds []Embed
+	for _, link := range files.FindWikiLinks(content) {
+		if !link.Embed {
+			continue
+		}
+		if embed, ok := r.embed(link, notePath, []string{embedKey(notePath, files.WikiLink{})}); ok {
+			embeds = append(embeds, embed)
+		}
+	}
+	return embeds
+}
+
+func TestEmbedsSectionsAndErrors(t *testing.T) {
+	notes := map[string]string{
+		"Guide": "---\ntags: [a]\n---\n# Guide\n## Setup\nInstall it.\n## Use\nRun it. ^run\n",
+	}
+	r := newEmbedResolver(t, notes)
+	root := "![[Guide]] ![[Guide#Setup]] ![[Guide#^run]] ![[Guide#Nope]] ![[Guide^nope]] ![[Missing]] ![[photo.png]] [[Guide]]"
+	embeds := rootEmbeds(r, "Root.md", root)
+
+	want := []struct{ content, err string }{
+		{"# Guide\n## Setup\nInstall it.\n## Use\nRun it. ^run\n", ""},
+		{"## Setup\nInstall it.\n", ""},
+		{"Run it.", ""},
+		{"", embedNoHeading},
+		{"", embedNoBlock},
+		{"", graph.UnresolvedMissing},
+	}
+	if len(embeds) != len(want) {
+		t.Fatalf("got %d embeds, want %d: %+v", len(embeds), len(want), embeds)
+	}
+	for i, w := range want {
+		if embeds[i].Content != w.content || embeds[i].Error != w.err {
+			t.Errorf("embed %d = %q, %q, want %q, %q", i, embeds[i].Content, embeds[i].Error, w.content, w.err)
+		}
+	}
+}
+
+func TestEmbedsStopAtCycles(t *testing.T) {
+	notes := map[string]string{
+		"A": "a ![[B]]",
+		"B": "b ![[A]]",
+	}
+	r := newEmbedResolver(t, notes)
+
+	embeds := rootEmbeds(r, "A.md", notes["A"])
+	if len(embeds) != 1 || embeds[0].Error != "" {
+		t.Fatalf("embeds = %+v", embeds)
+	}
+	// B embedding A back would repeat forever, so it is left as written
+	if embeds[0].Content != "b ![[A]]" {
+		t.Fatalf("content = %q", embeds[0].Content)
+	}
+
+	// A note embedding itself is a cycle at once
+	embeds = rootEmbeds(r, "A.md", "![[A]]")
+	if len(embeds) != 1 || embeds[0].Error != embedCycle {
+		t.Fatalf("self embed = %+v", embeds)
+	}
+}
+
+func TestEmbedsStopAtMaxDepth(t *testing.T) {
+	notes := map[string]string{}
+	names := []string{"N1", "N2", "N3", "N4", "N5", "N6", "N7"}
+	for i, name := range names {
+		content := strings.ToLower(name)
+		if i+1 < len(names) {
+			content += " ![[" + names[i+1] + "]]"
+		}
+		notes[name] = content
+	}
+	r := newEmbedResolver(t, notes)
+
+	embeds := rootEmbeds(r, "Root.md", "![[N1]]")
+	if len(embeds) != 1 || embeds[0].Error != "" {
+		t.Fatalf("embeds = %+v", embeds)
+	}
+	// N1 is one level deep; maxEmbedDepth levels are expanded
+	want := "n1 n2 n3 n4 ![[N5]]"
+	if embeds[0].Content != want {
+		t.Fatalf("content = %q, want %q", embeds[0].Content, want)
+	}
+}
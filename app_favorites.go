@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"notebit/pkg/database"
+)
+
+// ============ FAVORITE NOTE API METHODS ============
+
+// SetNoteFavorite pins the note at path, or unpins it. Pins follow the note
+// when it is renamed and are dropped when it is deleted.
+func (a *App) SetNoteFavorite(path string, favorite bool) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	path = strings.TrimSpace(path)
+	if favorite && !a.fm.FileExists(path) {
+		return fmt.Errorf("note not found: %s", path)
+	}
+	return a.dbm.Repository().SetNoteFavorite(path, favorite)
+}
+
+// ListFavoriteNotes returns the pinned notes in the order they were pinned
+func (a *App) ListFavoriteNotes() ([]database.FavoriteNote, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ListFavoriteNotes()
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/feeds"
+	"notebit/pkg/files"
+	"notebit/pkg/jobs"
+	"notebit/pkg/journal"
+	"notebit/pkg/logger"
+)
+
+// ============ FEED API METHODS ============
+
+// GetFeedsConfig returns the feed polling settings
+func (a *App) GetFeedsConfig() (config.FeedsConfig, error) {
+	return a.cfg.GetFeedsConfig(), nil
+}
+
+// SetFeedsConfig updates the feed polling settings and restarts the feed scheduler
+func (a *App) SetFeedsConfig(cfg config.FeedsConfig) error {
+	cfg.Folder = strings.Trim(strings.TrimSpace(cfg.Folder), "/")
+	if cfg.Folder == "" {
+		cfg.Folder = "Feeds"
+	}
+	if cfg.IntervalMinutes < 5 {
+		return fmt.Errorf("feeds can be polled at most every 5 minutes")
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 20
+	}
+	a.cfg.SetFeedsConfig(cfg)
+	if err := a.cfg.Save(); err != nil {
+		return err
+	}
+	a.startFeedScheduler()
+	return nil
+}
+
+// ListFeeds returns the subscribed feeds
+func (a *App) ListFeeds() ([]database.Feed, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ListFeeds()
+}
+
+// AddFeed subscribes to the RSS or Atom feed at feedURL and polls it in the
+// background. The feed is fetched first, so typos and web pages that are not
+// feeds are rejected.
+func (a *App) AddFeed(feedURL string) (*database.Feed, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	feedURL = strings.TrimSpace(feedURL)
+	if feedURL != "" && !strings.Contains(feedURL, "://") {
+		feedURL = "https://" + feedURL
+	}
+	if !strings.HasPrefix(feedURL, "http://") && !strings.HasPrefix(feedURL, "https://") {
+		return nil, fmt.Errorf("feed URL must be an http(s) URL")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := feeds.Fetch(ctx, nil, feedURL, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	feed, err := a.dbm.Repository().CreateFeed(feedURL, result.Feed.Title, feeds.FolderName(result.Feed.Title, feedURL))
+	if err != nil {
+		return nil, err
+	}
+	logger.Audit(a.ctx, auditFeedSubscribe, feedURL, nil, map[string]interface{}{"title": feed.Title})
+	a.startFeedPoll()
+	return feed, nil
+}
+
+// RemoveFeed unsubscribes from a feed. Notes written from it are kept.
+func (a *App) RemoveFeed(id uint) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	repo := a.dbm.Repository()
+	feed, err := repo.GetFeed(id)
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteFeed(id); err != nil {
+		return err
+	}
+	logger.Audit(a.ctx, auditFeedUnsubscribe, feed.URL, map[string]interface{}{"title": feed.Title}, nil)
+	return nil
+}
+
+// RefreshFeeds polls every subscribed feed now. It returns the job ID; the
+// job result is the number of new entries.
+func (a *App) RefreshFeeds() (string, error) {
+	if !a.dbm.IsInitialized() {
+		return "", fmt.Errorf("database not initialized")
+	}
+	return a.startFeedPoll(), nil
+}
+
+// GetFeedStatus returns the feed scheduler state, including the next planned poll
+func (a *App) GetFeedStatus() (feeds.Status, error) {
+	if a.feeds == nil {
+		return feeds.Status{}, fmt.Errorf("feed scheduler not initialized")
+	}
+	return a.feeds.Status(), nil
+}
+
+// startFeedScheduler (re)starts feed polling with the current config
+func (a *App) startFeedScheduler() {
+	if a.feeds == nil {
+		a.feeds = feeds.NewScheduler(a.pollFeeds)
+	}
+	cfg := a.cfg.GetFeedsConfig()
+	interval := time.Duration(cfg.IntervalMinutes) * time.Minute
+	if !cfg.Enabled {
+		interval = 0
+	}
+	a.feeds.Start(interval)
+}
+
+func (a *App) startFeedPoll() string {
+	return a.jobs.Start(jobKindFeedPoll, "Refresh feeds", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		if a.feeds == nil {
+			return a.pollFeeds(ctx)
+		}
+		return a.feeds.RunNow(ctx)
+	})
+}
+
+// pollFeeds writes the new entries of every subscribed feed to notes and
+// returns how many were written. A failing feed does not stop the others.
+func (a *App) pollFeeds(ctx context.Context) (int, error) {
+	if !a.dbm.IsInitialized() {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	subscribed, err := a.dbm.Repository().ListFeeds()
+	if err != nil {
+		return 0, err
+	}
+	cfg := a.cfg.GetFeedsConfig()
+	added, failed := 0, 0
+	for _, feed := range subscribed {
+		if ctx.Err() != nil {
+			return added, ctx.Err()
+		}
+		n, err := a.pollFeed(ctx, feed, cfg)
+		added += n
+		if err != nil {
+			failed++
+			logger.WarnWithFields(ctx, map[string]interface{}{"feed": feed.URL, "error": err.Error()}, "Failed to poll feed")
+		}
+	}
+	if added > 0 {
+		a.scheduleGitCommit()
+	}
+	if failed > 0 {
+		return added, fmt.Errorf("%d of %d feeds failed", failed, len(subscribed))
+	}
+	return added, nil
+}
+
+// pollFeed writes up to cfg.MaxEntries new entries of feed, newest first.
+// Further new entries are recorded as seen without a note, so a burst or the
+// back catalog of a new subscription does not flood the vault later.
+func (a *App) pollFeed(ctx context.Context, feed database.Feed, cfg config.FeedsConfig) (int, error) {
+	repo := a.dbm.Repository()
+	result, err := feeds.Fetch(ctx, nil, feed.URL, feed.ETag, feed.LastModified)
+	if err != nil {
+		_ = repo.RecordFeedPoll(feed.ID, "", "", "", err)
+		return 0, err
+	}
+	if result.NotModified {
+		return 0, repo.RecordFeedPoll(feed.ID, "", result.ETag, result.LastModified, nil)
+	}
+
+	title := result.Feed.Title
+	if title == "" {
+		title = feed.Title
+	}
+	folder := feed.Folder
+	if folder == "" {
+		folder = feeds.FolderName(title, feed.URL)
+	}
+	folder = path.Join(cfg.Folder, folder)
+
+	added := 0
+	for _, entry := range result.Feed.Entries {
+		seen, err := repo.HasFeedEntry(feed.ID, entry.GUID)
+		if err != nil {
+			return added, err
+		}
+		if seen {
+			continue
+		}
+		record := database.FeedEntry{FeedID: feed.ID, GUID: entry.GUID, Published: entry.Published}
+		if added < cfg.MaxEntries {
+			notePath, err := a.writeFeedEntry(folder, title, entry)
+			if err != nil {
+				_ = repo.RecordFeedPoll(feed.ID, "", "", "", err)
+				return added, err
+			}
+			record.Path = notePath
+			added++
+		}
+		if err := repo.AddFeedEntry(record); err != nil {
+			return added, err
+		}
+	}
+	return added, repo.RecordFeedPoll(feed.ID, title, result.ETag, result.LastModified, nil)
+}
+
+// writeFeedEntry writes an entry to a new note in folder and queues it for
+// indexing, so RAG can answer from it
+func (a *App) writeFeedEntry(folder, feedTitle string, entry feeds.Entry) (string, error) {
+	now := time.Now()
+	notePath, err := a.fm.ResolveNewNotePath(path.Join(folder, feeds.EntryNoteName(entry, now)), files.NamingRules{DedupeNames: true}, now)
+	if err != nil {
+		return "", err
+	}
+	content := feeds.EntryNote(feedTitle, entry)
+	if err := a.fm.CreateFile(notePath, content); err != nil {
+		return "", err
+	}
+	a.recordOperation(journal.Entry{Op: journal.OpCreate, Path: notePath})
+	logger.Audit(a.ctx, auditNoteCreate, notePath, nil, map[string]interface{}{"size": len(content), "feed": feedTitle})
+	if a.dbm.IsInitialized() {
+		_ = a.indexFileContent(notePath, content)
+	}
+	return notePath, nil
+}
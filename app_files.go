@@ -1,11 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"notebit/pkg/config"
 	"notebit/pkg/database"
 	"notebit/pkg/files"
 	"notebit/pkg/indexing"
+	"notebit/pkg/journal"
 	"notebit/pkg/logger"
+	"strings"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -30,47 +35,18 @@ func (a *App) OpenFolder() (string, error) {
 		return "", nil
 	}
 
-	// Stop existing watcher before changing folder
-	a.stopWatcher()
-
-	if err := a.fm.SetBasePath(dir); err != nil {
-		logger.ErrorWithFields(a.ctx, map[string]interface{}{"path": dir, "error": err.Error()}, "Failed to set base path")
+	if err := a.openVault(dir); err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"path": dir, "error": err.Error()}, "Failed to open vault")
 		return "", err
 	}
 
-	if err := a.initializeServices(dir); err != nil {
-		logger.WarnWithFields(a.ctx, map[string]interface{}{"path": dir, "error": err.Error()}, "Service initialization issue")
-	}
-
-	// Start watcher for new folder
-	if err := a.startWatcher(); err != nil {
-		logger.ErrorWithFields(a.ctx, map[string]interface{}{"path": dir}, "Failed to start watcher: %v", err)
-		runtime.LogErrorf(a.ctx, "Failed to start watcher: %v", err)
-	}
-
 	logger.InfoWithDuration(a.ctx, timer(), "Folder opened successfully: %s", dir)
 	return dir, nil
 }
 
 // SetFolder sets the base path without opening a dialog
 func (a *App) SetFolder(path string) error {
-	// Stop existing watcher before changing folder
-	a.stopWatcher()
-
-	if err := a.fm.SetBasePath(path); err != nil {
-		return err
-	}
-
-	if err := a.initializeServices(path); err != nil {
-		logger.Warn("Service initialization issue: %v", err)
-	}
-
-	// Start watcher for new folder
-	if err := a.startWatcher(); err != nil {
-		runtime.LogErrorf(a.ctx, "Failed to start watcher: %v", err)
-	}
-
-	return nil
+	return a.openVault(path)
 }
 
 // ListFiles returns the file tree structure
@@ -78,27 +54,70 @@ func (a *App) ListFiles() (*files.FileNode, error) {
 	return a.fm.ListFiles()
 }
 
-// ReadFile reads the content of a markdown file
+// ReadFile reads the content of a markdown file. The editor passes its Hash
+// back to SaveFile so saves can tell when the note changed in between.
 func (a *App) ReadFile(path string) (*files.NoteContent, error) {
 	return a.fm.ReadFile(path)
 }
 
-// SaveFile saves content to a markdown file
-func (a *App) SaveFile(path, content string) error {
+// SaveFile saves the editor's content of a markdown file and returns the
+// hash of the saved version, the baseHash of the next save. baseHash is the
+// Hash of the version the editor loaded or last saved; if the note on disk
+// is no longer that version, because another program or the app itself
+// wrote it, nothing is written: a *files.ConflictError is returned and a
+// "file:conflict" event carries the details, so the editor can merge
+// (MergeWithDisk) or keep its version (OverwriteFile).
+func (a *App) SaveFile(path, content, baseHash string) (string, error) {
+	return a.saveFile(path, content, baseHash, true)
+}
+
+// OverwriteFile saves content to a markdown file, replacing whatever changed
+// on disk since the note was read, and returns the hash of the saved version
+func (a *App) OverwriteFile(path, content string) (string, error) {
+	return a.saveFile(path, content, "", false)
+}
+
+// writeNote saves content to a note on behalf of the app rather than the
+// editor, e.g. for the API, link insertion or a digest
+func (a *App) writeNote(path, content string) error {
+	_, err := a.saveFile(path, content, "", false)
+	return err
+}
+
+func (a *App) saveFile(path, content, baseHash string, check bool) (string, error) {
 	timer := logger.StartTimer()
 	logger.DebugWithFields(a.ctx, map[string]interface{}{
 		"path":         path,
 		"content_size": len(content),
 	}, "Saving file")
 
-	err := a.fm.SaveFile(path, content)
+	a.notifyActivity()
+	entry := a.snapshotForWrite(path)
+	var err error
+	if check {
+		err = a.fm.SaveFileIfUnchanged(path, content, baseHash)
+	} else {
+		err = a.fm.SaveFile(path, content)
+	}
+	var conflict *files.ConflictError
+	if errors.As(err, &conflict) {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{"path": path}, "File changed on disk since it was opened; not saved")
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "file:conflict", conflict)
+		}
+		return "", err
+	}
 	if err != nil {
 		logger.ErrorWithFields(a.ctx, map[string]interface{}{
 			"path":  path,
 			"error": err.Error(),
 		}, "Failed to save file")
-		return err
+		return "", err
 	}
+	a.recordOperation(entry)
+	a.discardEdits(path)
+	a.recordNoteEdited(path)
+	a.scheduleGitCommit()
 
 	// Index the file in database after saving (pass content to avoid re-reading)
 	if a.dbm.IsInitialized() {
@@ -106,22 +125,90 @@ func (a *App) SaveFile(path, content string) error {
 	}
 
 	logger.InfoWithDuration(a.ctx, timer(), "File saved: %s", path)
-	return nil
+	return files.HashContent([]byte(content)), nil
+}
+
+// CreateFile creates a new markdown file, applying the configured naming rules.
+// It returns the path the note was actually created at. The caller chose
+// the name, so an existing note there is an error rather than a reason to
+// pick another name.
+func (a *App) CreateFile(path, content string) (string, error) {
+	rules := a.namingRules()
+	rules.DedupeNames = false
+	return a.createNote(path, content, rules)
+}
+
+// CreateNote creates a note from a title using the configured inbox and naming rules.
+// This is the entry point shared by all capture paths.
+func (a *App) CreateNote(title, content string) (string, error) {
+	title = strings.TrimSpace(strings.ReplaceAll(title, "/", "-"))
+	return a.createNote(title, content, a.namingRules())
 }
 
-// CreateFile creates a new markdown file
-func (a *App) CreateFile(path, content string) error {
-	err := a.fm.CreateFile(path, content)
+// createNote creates a note at the path rules resolve name to
+func (a *App) createNote(name, content string, rules files.NamingRules) (string, error) {
+	a.notifyActivity()
+	resolved, err := a.fm.ResolveNewNotePath(name, rules, time.Now())
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if err := a.fm.CreateFile(resolved, content); err != nil {
+		return "", err
 	}
+	a.recordOperation(journal.Entry{Op: journal.OpCreate, Path: resolved})
+	logger.Audit(a.ctx, auditNoteCreate, resolved, nil, map[string]interface{}{"size": len(content)})
+	a.scheduleGitCommit()
 
 	// Index the file in database after creating (pass content to avoid re-reading)
 	if a.dbm.IsInitialized() {
-		go a.indexFileContent(path, content)
+		go a.indexFileContent(resolved, content)
 	}
 
-	return nil
+	return resolved, nil
+}
+
+// GetNotesConfig returns the new-note placement and naming rules
+func (a *App) GetNotesConfig() (config.NotesConfig, error) {
+	return a.cfg.GetNotesConfig(), nil
+}
+
+// SetNotesConfig sets the new-note placement and naming rules
+func (a *App) SetNotesConfig(inboxFolder, dateFolderFormat string, slugifyTitles, dedupeNames bool) error {
+	notesCfg := a.cfg.GetNotesConfig()
+	notesCfg.InboxFolder = strings.Trim(strings.TrimSpace(inboxFolder), "/")
+	notesCfg.DateFolderFormat = strings.TrimSpace(dateFolderFormat)
+	notesCfg.SlugifyTitles = slugifyTitles
+	notesCfg.DedupeNames = dedupeNames
+	a.cfg.SetNotesConfig(notesCfg)
+	return a.cfg.Save()
+}
+
+// SetNoteWriteOptions sets whether saves are flushed to disk before they
+// complete and whether the previous content of a note is kept as a .bak file
+func (a *App) SetNoteWriteOptions(fsyncOnSave, keepBackup bool) error {
+	notesCfg := a.cfg.GetNotesConfig()
+	notesCfg.FsyncOnSave = fsyncOnSave
+	notesCfg.KeepBackup = keepBackup
+	a.cfg.SetNotesConfig(notesCfg)
+	a.applyWriteOptions()
+	return a.cfg.Save()
+}
+
+// applyWriteOptions passes the configured write options to the file manager
+func (a *App) applyWriteOptions() {
+	notesCfg := a.cfg.GetNotesConfig()
+	a.fm.SetWriteOptions(files.WriteOptions{Fsync: notesCfg.FsyncOnSave, KeepBackup: notesCfg.KeepBackup})
+}
+
+func (a *App) namingRules() files.NamingRules {
+	notesCfg := a.cfg.GetNotesConfig()
+	return files.NamingRules{
+		InboxFolder:      notesCfg.InboxFolder,
+		DateFolderFormat: notesCfg.DateFolderFormat,
+		SlugifyTitles:    notesCfg.SlugifyTitles,
+		DedupeNames:      notesCfg.DedupeNames,
+	}
 }
 
 // DeleteFile deletes a markdown file or directory
@@ -129,6 +216,8 @@ func (a *App) DeleteFile(path string) error {
 	timer := logger.StartTimer()
 	logger.InfoWithFields(a.ctx, map[string]interface{}{"path": path}, "Deleting file")
 
+	a.notifyActivity()
+	entries := a.snapshotForDelete(path)
 	err := a.fm.DeleteFile(path)
 	if err != nil {
 		logger.ErrorWithFields(a.ctx, map[string]interface{}{
@@ -137,6 +226,15 @@ func (a *App) DeleteFile(path string) error {
 		}, "Failed to delete file")
 		return err
 	}
+	deleted := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		a.recordOperation(entry)
+		a.discardEdits(entry.Path)
+		deleted = append(deleted, entry.Path)
+	}
+	a.discardEdits(path)
+	logger.Audit(a.ctx, auditNoteDelete, path, map[string]interface{}{"notes": deleted}, nil)
+	a.scheduleGitCommit()
 
 	// Remove from database index
 	if a.dbm.IsInitialized() {
@@ -147,6 +245,15 @@ func (a *App) DeleteFile(path string) error {
 				"error": err.Error(),
 			}, "Failed to delete file from index")
 		}
+		_ = repo.DeleteFlashcardsForPath(path)
+		for _, notePath := range deleted {
+			_ = repo.DeleteHistoryForPath(notePath)
+			_ = repo.DeleteFavoriteForPath(notePath)
+		}
+		a.forgetNoteUse()
+	}
+	if a.chatSvc != nil {
+		_ = a.chatSvc.DetachNoteEverywhere(path)
 	}
 
 	logger.InfoWithDuration(a.ctx, timer(), "File deleted: %s", path)
@@ -155,15 +262,28 @@ func (a *App) DeleteFile(path string) error {
 
 // RenameFile renames a file or directory
 func (a *App) RenameFile(oldPath, newPath string) error {
+	a.notifyActivity()
 	err := a.fm.RenameFile(oldPath, newPath)
 	if err != nil {
 		return err
 	}
+	a.moveEdits(oldPath, newPath)
+	a.recordOperation(journal.Entry{Op: journal.OpRename, Path: oldPath, NewPath: newPath})
+	logger.Audit(a.ctx, auditNoteRename, oldPath, map[string]interface{}{"path": oldPath}, map[string]interface{}{"path": newPath})
+	a.scheduleGitCommit()
 
 	// Update path in database index
 	if a.dbm.IsInitialized() {
 		repo := a.dbm.Repository()
 		_ = repo.RenameFile(oldPath, newPath)
+		_ = repo.RenameCollectionPaths(oldPath, newPath)
+		_ = repo.RenameFlashcardPaths(oldPath, newPath)
+		_ = repo.RenameHistoryPaths(oldPath, newPath)
+		_ = repo.RenameFavoritePaths(oldPath, newPath)
+		a.forgetNoteUse()
+	}
+	if a.chatSvc != nil {
+		_ = a.chatSvc.RenameAttachedNotePaths(oldPath, newPath)
 	}
 
 	return nil
@@ -220,12 +340,12 @@ func (a *App) GetIndexedFile(path string) (*database.File, error) {
 	return a.dbm.Repository().GetFileByPath(path)
 }
 
-// ListIndexedFiles returns all indexed files
-func (a *App) ListIndexedFiles() ([]database.File, error) {
+// ListIndexedFiles returns one page of indexed files matching filter
+func (a *App) ListIndexedFiles(filter database.FileFilter) (*database.FilePage, error) {
 	if !a.dbm.IsInitialized() {
 		return nil, fmt.Errorf("database not initialized")
 	}
-	return a.dbm.Repository().ListFiles()
+	return a.dbm.Repository().ListFilesPage(filter)
 }
 
 // RemoveFromIndex removes file from database index
@@ -233,7 +353,11 @@ func (a *App) RemoveFromIndex(path string) error {
 	if !a.dbm.IsInitialized() {
 		return fmt.Errorf("database not initialized")
 	}
-	return a.dbm.Repository().DeleteFile(path)
+	if err := a.dbm.Repository().DeleteFile(path); err != nil {
+		return err
+	}
+	logger.Audit(a.ctx, auditIndexRemove, path, nil, nil)
+	return nil
 }
 
 // UpdateFilePathInIndex updates file path after rename
@@ -259,6 +383,7 @@ func (a *App) GetDatabaseStats() (map[string]interface{}, error) {
 		"files":  stats["files"],
 		"chunks": stats["chunks"],
 		"tags":   stats["tags"],
+		"failed": stats["failed"],
 		"path":   a.dbm.GetDBPath(),
 	}
 
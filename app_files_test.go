@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"notebit/pkg/config"
+)
+
+func TestCreateFileKeepsTheChosenName(t *testing.T) {
+	cfg := config.New()
+	notes := cfg.GetNotesConfig()
+	notes.DedupeNames = true
+	cfg.SetNotesConfig(notes)
+	a := NewAppWithConfig(cfg)
+	if err := a.fm.SetBasePath(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CreateFile("Projects/Plan.md", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An explicit path that exists is an error, not a new name
+	if got, err := a.CreateFile("Projects/Plan.md", "second"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("CreateFile over an existing note = %q, %v, want an exists error", got, err)
+	}
+	// A title is a generated name, so it gets a free one
+	if got, err := a.CreateNote("Plan", "second"); err != nil || got != "Plan.md" {
+		t.Fatalf("CreateNote = %q, %v, want Plan.md", got, err)
+	}
+	if got, err := a.CreateNote("Plan", "third"); err != nil || got != "Plan 2.md" {
+		t.Fatalf("CreateNote for a taken title = %q, %v, want Plan 2.md", got, err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+	"time"
+)
+
+// ============ FLASHCARD API METHODS ============
+
+// GenerateFlashcards asks the LLM for question/answer pairs from a note and
+// stores them as new cards due for review now. Questions the note already has
+// cards for are skipped; the newly stored cards are returned.
+func (a *App) GenerateFlashcards(path string) ([]database.Flashcard, error) {
+	if a.rag == nil {
+		return nil, fmt.Errorf("RAG service not initialized")
+	}
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	note, err := a.fm.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := a.rag.GenerateFlashcards(context.Background(), files.StripFrontmatter(note.Content), 0)
+	if err != nil {
+		return nil, err
+	}
+	return a.dbm.Repository().AddFlashcards(path, cards, time.Now())
+}
+
+// GetFlashcards returns the cards generated from a note
+func (a *App) GetFlashcards(path string) ([]database.Flashcard, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ListFlashcards(path)
+}
+
+// GetDueCards returns up to limit cards due for review, most overdue first.
+// limit <= 0 returns all due cards.
+func (a *App) GetDueCards(limit int) ([]database.Flashcard, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().GetDueFlashcards(time.Now(), limit)
+}
+
+// RecordReview grades a review of a card from 0 (forgotten) to 5 (perfect
+// recall) and returns the card with its next due date
+func (a *App) RecordReview(id uint, quality int) (*database.Flashcard, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().RecordFlashcardReview(id, quality, time.Now())
+}
+
+// DeleteFlashcard removes a card
+func (a *App) DeleteFlashcard(id uint) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().DeleteFlashcard(id)
+}
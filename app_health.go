@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"notebit/pkg/graph"
+	"sort"
+)
+
+// ============ VAULT HEALTH API METHODS ============
+
+// VaultHealth reports notes and index entries that need attention
+type VaultHealth struct {
+	Orphans         []string               `json:"orphans"`          // Notes with no wiki links in or out
+	BrokenLinks     []graph.UnresolvedLink `json:"broken_links"`     // Wiki links whose target matches no note
+	AmbiguousLinks  []graph.UnresolvedLink `json:"ambiguous_links"`  // Wiki links matching several notes
+	MissingOnDisk   []string               `json:"missing_on_disk"`  // Indexed notes whose file no longer exists
+	UnembeddedFiles []string               `json:"unembedded_files"` // Notes not fully embedded, including notes with no chunks
+	TotalChunks     int64                  `json:"total_chunks"`
+	PendingChunks   int64                  `json:"pending_chunks"` // Chunks without an embedding
+}
+
+// GetVaultHealth reports orphan notes, broken wiki links, notes indexed but
+// missing on disk and notes whose chunks are not fully embedded
+func (a *App) GetVaultHealth() (*VaultHealth, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if a.graph == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	repo := a.dbm.Repository()
+
+	links, err := a.graph.LinkHealth()
+	if err != nil {
+		return nil, err
+	}
+	health := &VaultHealth{
+		Orphans:        links.Orphans,
+		BrokenLinks:    links.BrokenLinks,
+		AmbiguousLinks: links.AmbiguousLinks,
+		MissingOnDisk:  []string{},
+	}
+
+	indexed, err := repo.ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range indexed {
+		if !a.fm.FileExists(f.Path) {
+			health.MissingOnDisk = append(health.MissingOnDisk, f.Path)
+		}
+	}
+	sort.Strings(health.MissingOnDisk)
+
+	if health.UnembeddedFiles, err = repo.ListFilesNeedingEmbeddings(); err != nil {
+		return nil, err
+	}
+	if health.UnembeddedFiles == nil {
+		health.UnembeddedFiles = []string{}
+	}
+	sort.Strings(health.UnembeddedFiles)
+	stats, err := repo.GetEmbeddingStats()
+	if err != nil {
+		return nil, err
+	}
+	health.TotalChunks = stats.TotalChunks
+	health.PendingChunks = stats.TotalChunks - stats.EmbeddedChunks
+	return health, nil
+}
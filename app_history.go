@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"notebit/pkg/database"
+	"notebit/pkg/logger"
+)
+
+const (
+	// defaultRecentFiles is how many notes GetRecentFiles returns by default
+	defaultRecentFiles = 20
+	// mostEditedLimit is how many notes GetMostEditedFiles returns
+	mostEditedLimit = 20
+	// historyRetention is how long open and edit history is kept
+	historyRetention = 365 * 24 * time.Hour
+)
+
+// ============ HISTORY API METHODS ============
+
+// RecordNoteOpened records that the note at path was opened in the editor,
+// for GetRecentFiles and the quick switcher
+func (a *App) RecordNoteOpened(path string) error {
+	if !a.dbm.IsInitialized() {
+		return fmt.Errorf("database not initialized")
+	}
+	return a.recordHistory(path, database.HistoryOpen)
+}
+
+// GetRecentFiles returns the limit notes opened or edited most recently,
+// most recent first
+func (a *App) GetRecentFiles(limit int) ([]database.RecentFile, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if limit <= 0 {
+		limit = defaultRecentFiles
+	}
+	return a.dbm.Repository().RecentHistory(limit)
+}
+
+// GetMostEditedFiles returns the notes saved most often in the last "day",
+// "week", "month" or "year", or over "all" the history kept; "" is a week
+func (a *App) GetMostEditedFiles(period string) ([]database.EditCount, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	now := time.Now()
+	var since time.Time
+	switch period {
+	case "day":
+		since = now.AddDate(0, 0, -1)
+	case "", "week":
+		since = now.AddDate(0, 0, -7)
+	case "month":
+		since = now.AddDate(0, -1, 0)
+	case "year":
+		since = now.AddDate(-1, 0, 0)
+	case "all":
+	default:
+		return nil, fmt.Errorf("unknown period %q: use day, week, month, year or all", period)
+	}
+	return a.dbm.Repository().MostEditedFiles(since, mostEditedLimit)
+}
+
+// recordNoteEdited records a save of the note at path; failures only cost
+// history, so they are logged
+func (a *App) recordNoteEdited(path string) {
+	if !a.dbm.IsInitialized() {
+		return
+	}
+	if err := a.recordHistory(path, database.HistoryEdit); err != nil {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{"path": path, "error": err.Error()}, "Failed to record note edit")
+	}
+}
+
+func (a *App) recordHistory(path, kind string) error {
+	now := time.Now()
+	if err := a.dbm.Repository().RecordHistory(path, kind, now); err != nil {
+		return err
+	}
+	a.markNoteUsed(path, now)
+	return nil
+}
+
+// pruneHistory drops history older than historyRetention
+func (a *App) pruneHistory() {
+	if !a.dbm.IsInitialized() {
+		return
+	}
+	if err := a.dbm.Repository().PruneHistory(time.Now().Add(-historyRetention)); err != nil {
+		logger.Warn("Failed to prune note history: %v", err)
+	}
+}
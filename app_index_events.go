@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"notebit/pkg/database"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ INDEX CHANGE EVENTS ============
+
+// indexEventDelay coalesces the bursts of changes a reindex or a watcher
+// batch makes into one set of events
+const indexEventDelay = 250 * time.Millisecond
+
+// indexEvents collects repository changes until they are pushed to the
+// frontend
+type indexEvents struct {
+	mu       sync.Mutex
+	paths    map[string]struct{}
+	files    bool // a file changed, so the graph is stale
+	revision uint64
+	timer    *time.Timer
+}
+
+// IndexUpdate is the payload of the "index-updated" event
+type IndexUpdate struct {
+	Revision uint64   `json:"revision"`
+	Paths    []string `json:"paths"`
+}
+
+// watchIndexChanges pushes changes of the open vault's index to the frontend
+func (a *App) watchIndexChanges() {
+	a.dbm.Repository().SetChangeListener(a.onIndexChanged)
+}
+
+// onIndexChanged records a repository change and schedules the events for it
+func (a *App) onIndexChanged(change database.ChangeEvent) {
+	e := &a.indexEvents
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.paths == nil {
+		e.paths = make(map[string]struct{})
+	}
+	for _, p := range change.Paths {
+		e.paths[p] = struct{}{}
+	}
+	if len(change.Paths) > 0 {
+		e.files = true
+	}
+	if change.Revision > e.revision {
+		e.revision = change.Revision
+	}
+	if e.timer == nil {
+		e.timer = time.AfterFunc(indexEventDelay, a.flushIndexEvents)
+	}
+}
+
+// flushIndexEvents emits "index-updated" with the changed paths,
+// "graph-invalidated" when files changed, and "stats-changed" for any change.
+// Cached chat retrievals are dropped as well.
+func (a *App) flushIndexEvents() {
+	e := &a.indexEvents
+	e.mu.Lock()
+	paths := make([]string, 0, len(e.paths))
+	for p := range e.paths {
+		paths = append(paths, p)
+	}
+	files, revision := e.files, e.revision
+	e.paths = nil
+	e.files = false
+	e.timer = nil
+	e.mu.Unlock()
+
+	if a.ctx == nil {
+		return
+	}
+	sort.Strings(paths)
+	if a.rag != nil {
+		a.rag.ClearRetrievalCache()
+	}
+	if files {
+		runtime.EventsEmit(a.ctx, "index-updated", IndexUpdate{Revision: revision, Paths: paths})
+		runtime.EventsEmit(a.ctx, "graph-invalidated", revision)
+	}
+	runtime.EventsEmit(a.ctx, "stats-changed", revision)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/indexing"
+	"notebit/pkg/logger"
+	"os"
+	"strings"
+)
+
+// ============ INDEX MAINTENANCE API METHODS ============
+
+// GetIndexScheduleConfig returns the periodic reindex schedule
+func (a *App) GetIndexScheduleConfig() (config.IndexScheduleConfig, error) {
+	return a.cfg.GetIndexingConfig().Schedule, nil
+}
+
+// SetIndexScheduleConfig updates the periodic reindex schedule and restarts the scheduler
+func (a *App) SetIndexScheduleConfig(enabled bool, timeOfDay string, onlyWhenIdle bool, idleMinutes int, onlyOnACPower bool) error {
+	timeOfDay = strings.TrimSpace(timeOfDay)
+	if timeOfDay == "" {
+		timeOfDay = "03:00"
+	}
+	if idleMinutes <= 0 {
+		idleMinutes = 10
+	}
+	a.cfg.SetIndexScheduleConfig(config.IndexScheduleConfig{
+		Enabled:       enabled,
+		Time:          timeOfDay,
+		OnlyWhenIdle:  onlyWhenIdle,
+		IdleMinutes:   idleMinutes,
+		OnlyOnACPower: onlyOnACPower,
+	})
+	a.startScheduler()
+	return a.cfg.Save()
+}
+
+// OptimizeDatabase checkpoints, compacts and vacuums the vault database,
+// reporting its size before and after
+func (a *App) OptimizeDatabase() (*database.OptimizeResult, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	timer := logger.StartTimer()
+	result, err := a.dbm.Optimize()
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "Database optimization failed")
+		return nil, err
+	}
+	logger.InfoWithDuration(a.ctx, timer(), "Database optimized: %d -> %d bytes", result.SizeBefore, result.SizeAfter)
+	return result, nil
+}
+
+// GetDatabaseMaintenance returns the last optimization of the vault database
+// and the growth that triggers the next automatic one
+func (a *App) GetDatabaseMaintenance() (map[string]interface{}, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return map[string]interface{}{
+		"last_optimize":    a.dbm.LastOptimize(),
+		"auto_optimize_mb": a.cfg.GetIndexingConfig().AutoOptimizeMB,
+	}, nil
+}
+
+// SetAutoOptimize sets how many MB the database may grow before it is
+// optimized on the next vault open (0 = never)
+func (a *App) SetAutoOptimize(thresholdMB int) error {
+	if thresholdMB < 0 {
+		return fmt.Errorf("invalid threshold: %d MB", thresholdMB)
+	}
+	a.cfg.SetAutoOptimizeMB(thresholdMB)
+	return a.cfg.Save()
+}
+
+// autoOptimizeDatabase optimizes the database of a vault being opened if it
+// grew past the configured threshold. It runs before the indexing services
+// start so VACUUM does not hold up their writes.
+func (a *App) autoOptimizeDatabase() {
+	mb := a.cfg.GetIndexingConfig().AutoOptimizeMB
+	result, err := a.dbm.OptimizeIfGrown(int64(mb) << 20)
+	if err != nil {
+		logger.Warn("Automatic database optimization failed: %v", err)
+		return
+	}
+	if result != nil {
+		logger.Info("Database optimized automatically: %d -> %d bytes in %d ms", result.SizeBefore, result.SizeAfter, result.DurationMillis)
+	}
+}
+
+// GetIndexScheduleStatus returns the scheduler state, including the next planned run
+func (a *App) GetIndexScheduleStatus() (indexing.ScheduleStatus, error) {
+	if a.schedule == nil {
+		return indexing.ScheduleStatus{}, fmt.Errorf("indexing pipeline not initialized")
+	}
+	return a.schedule.Status(), nil
+}
+
+// RunScheduledReindexNow runs the reconcile + embedding repair pass immediately
+func (a *App) RunScheduledReindexNow() (*indexing.ReconcileResult, error) {
+	if a.schedule == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	return a.schedule.RunNow(context.Background())
+}
+
+// notifyActivity tells background maintenance that the user is active
+func (a *App) notifyActivity() {
+	if a.schedule != nil {
+		a.schedule.NotifyActivity()
+	}
+	a.touchAutoLock()
+}
+
+// ListIndexErrors returns files whose last indexing attempt failed, most recent first
+func (a *App) ListIndexErrors() ([]database.IndexError, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().ListIndexErrors()
+}
+
+// RetryFailedIndexing re-queues every file with a recorded index error and waits for the result
+func (a *App) RetryFailedIndexing() (map[string]interface{}, error) {
+	if a.pipeline == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	progress, err := a.pipeline.RetryFailed(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	<-progress.Done
+
+	remaining, _ := a.dbm.Repository().CountIndexErrors()
+	return map[string]interface{}{
+		"total":     progress.Total,
+		"processed": progress.Processed.Load(),
+		"failed":    progress.Errors.Load(),
+		"remaining": remaining,
+	}, nil
+}
+
+// RunIndexBenchmark measures chunking, embedding and database write performance on
+// sampleSize vault files and returns suggested worker, batch size and debounce values.
+// Suggestions are not applied; pass them to ApplyIndexTuning to persist them.
+func (a *App) RunIndexBenchmark(sampleSize int) (*indexing.BenchmarkResult, error) {
+	if a.pipeline == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.pipeline.Benchmark(context.Background(), sampleSize)
+}
+
+// ApplyIndexTuning saves worker, batch size and debounce settings (e.g. from
+// RunIndexBenchmark). Non-positive values keep the current setting. Worker counts
+// take effect the next time the pipeline and watcher start.
+func (a *App) ApplyIndexTuning(workerCount, batchSize, debounceMS, watcherWorkers int) error {
+	a.cfg.SetIndexTuning(workerCount, batchSize, debounceMS, watcherWorkers)
+	return a.cfg.Save()
+}
+
+// GetIndexDriftReport returns what the last on-open reconciliation found, or nil
+// if it has not run yet
+func (a *App) GetIndexDriftReport() (*indexing.DriftReport, error) {
+	if a.pipeline == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	return a.pipeline.LastDriftReport(), nil
+}
+
+// ReconcileIndexNow runs the on-open reconciliation immediately and returns its report
+func (a *App) ReconcileIndexNow() (*indexing.DriftReport, error) {
+	if a.pipeline == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	result, err := a.pipeline.Reconcile(context.Background(), indexing.ReconcileOptions{Quick: true})
+	if err != nil {
+		return nil, err
+	}
+	return result.Drift, nil
+}
+
+// ExportIndex writes the knowledge index (files, chunks and embeddings) to
+// path as JSON lines, gzipped when path ends in ".gz", so it can be imported
+// on another machine without embedding every note again
+func (a *App) ExportIndex(path string) (*database.IndexTransferStats, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("export path is required")
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	var w io.Writer = out
+	var zw *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		zw = gzip.NewWriter(out)
+		w = zw
+	}
+	stats, err := a.dbm.Repository().ExportIndex(w)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	logger.Audit(a.ctx, auditIndexExport, path, nil, map[string]interface{}{"files": stats.Files, "chunks": stats.Chunks})
+	return stats, nil
+}
+
+// ImportIndex restores an index written by ExportIndex. Files whose content
+// on disk differs from what was exported are skipped and left for the
+// pipeline to index again.
+func (a *App) ImportIndex(path string) (*database.IndexTransferStats, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	in, err := os.Open(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	timer := logger.StartTimer()
+	stats, err := a.dbm.Repository().ImportIndex(in, func(notePath, contentHash string) bool {
+		note, err := a.fm.ReadFile(notePath)
+		if err != nil {
+			return false
+		}
+		sum := sha256.Sum256([]byte(note.Content))
+		return hex.EncodeToString(sum[:]) == contentHash
+	})
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"path": path, "error": err.Error()}, "Index import failed")
+		return stats, err
+	}
+	logger.InfoWithDuration(a.ctx, timer(), "Imported index: %d files, %d chunks, %d skipped", stats.Files, stats.Chunks, stats.Skipped)
+	logger.Audit(a.ctx, auditIndexImport, path, nil, map[string]interface{}{"files": stats.Files, "chunks": stats.Chunks, "skipped": stats.Skipped})
+	return stats, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"notebit/pkg/logger"
+
+	"github.com/wailsapp/wails/v2/pkg/options"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Only one Notebit runs at a time: a second launch would fight the first over
+// the vault database and watcher. main.go sets a Wails single-instance lock
+// (a named mutex on Windows, D-Bus on Linux, a notification on macOS); the
+// second process exits after forwarding its arguments here.
+
+// onSecondInstanceLaunch runs in the first instance when the app is started
+// again. It focuses the window and handles the forwarded arguments.
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	logger.InfoWithFields(a.ctx, map[string]interface{}{"args": data.Args}, "Second instance launched")
+	if a.ctx == nil {
+		return
+	}
+	runtime.WindowUnminimise(a.ctx)
+	runtime.WindowShow(a.ctx)
+	a.handleLaunchArgs(data.Args, data.WorkingDirectory)
+}
+
+// handleLaunchArgs acts on command-line arguments: a notebit:// link, a
+// vault folder, or a markdown file (opened in the registered vault that
+// contains it, else in its folder). Relative paths are resolved against
+// workingDir.
+func (a *App) handleLaunchArgs(args []string, workingDir string) {
+	for _, arg := range args {
+		if strings.HasPrefix(strings.ToLower(arg), DeepLinkScheme+":") {
+			a.handleDeepLink(arg)
+			return
+		}
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if link, vaultPath := a.linkForPath(arg, workingDir); link != nil {
+			a.followDeepLink(link, vaultPath)
+			return
+		}
+	}
+}
+
+// linkForPath turns a folder or markdown file on disk into an open link and
+// the folder of the vault to open. The link names the vault only when it is
+// registered, like a notebit:// link would.
+func (a *App) linkForPath(path, workingDir string) (*DeepLink, string) {
+	if !filepath.IsAbs(path) && workingDir != "" {
+		path = filepath.Join(workingDir, path)
+	}
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, ""
+	}
+	if a.vaults == nil {
+		a.loadVaultRegistry() // launched with a path, before startup
+	}
+	if info.IsDir() {
+		return &DeepLink{Action: "open", Vault: a.vaultNameAt(path)}, path
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".md") {
+		return nil, ""
+	}
+
+	vault := filepath.Dir(path)
+	if a.vaults != nil {
+		for _, v := range a.vaults.List() {
+			if rel, err := filepath.Rel(v.Path, path); err == nil && !strings.HasPrefix(rel, "..") {
+				vault = v.Path
+				break
+			}
+		}
+	}
+	rel, err := filepath.Rel(vault, path)
+	if err != nil {
+		return nil, ""
+	}
+	return &DeepLink{Action: "open", Vault: a.vaultNameAt(vault), Path: filepath.ToSlash(rel)}, vault
+}
+
+// vaultNameAt returns the name of the registered vault in folder, or ""
+func (a *App) vaultNameAt(folder string) string {
+	if a.vaults == nil {
+		return ""
+	}
+	for _, v := range a.vaults.List() {
+		if filepath.Clean(v.Path) == folder {
+			return v.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notebit/pkg/indexing"
+	"notebit/pkg/jobs"
+	"notebit/pkg/logger"
+)
+
+// Job kinds
+const (
+	jobKindReindex          = "reindex"
+	jobKindRetryFailed      = "retry_failed"
+	jobKindBenchmark        = "benchmark"
+	jobKindNamespace        = "embedding_namespace"
+	jobKindCollectionExport = "collection_export"
+	jobKindDuplicateScan    = "duplicate_scan"
+	jobKindTranscribe       = "transcribe"
+	jobKindFeedPoll         = "feed_poll"
+	jobKindImageRead        = "image_read"
+)
+
+// indexProgressPollInterval is how often indexing jobs sample pipeline progress
+const indexProgressPollInterval = 250 * time.Millisecond
+
+// ============ JOB API METHODS ============
+
+// ListJobs returns running and recently finished background jobs, newest first.
+// Progress is also pushed through the job:started, job:progress and job:finished
+// events; use GetJob to fetch a finished job's result.
+func (a *App) ListJobs() []jobs.Job {
+	return a.jobs.List()
+}
+
+// GetJob returns a job's current state, including its result once finished
+func (a *App) GetJob(id string) (jobs.Job, error) {
+	return a.jobs.Get(id)
+}
+
+// CancelJob requests cancellation of a running job. Work already done is kept.
+func (a *App) CancelJob(id string) error {
+	return a.jobs.Cancel(id)
+}
+
+// StartReindexAllJob is the background variant of ReindexAllWithEmbeddings
+func (a *App) StartReindexAllJob() (string, error) {
+	if a.ks == nil {
+		return "", fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	ks := a.ks
+	logger.Audit(a.ctx, auditIndexReindex, a.fm.GetBasePath(), nil, nil)
+	return a.jobs.Start(jobKindReindex, "Reindex vault", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		progress, err := ks.StartReindexAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return waitIndexProgress(ctx, progress, report)
+	}), nil
+}
+
+// StartRetryFailedJob is the background variant of RetryFailedIndexing
+func (a *App) StartRetryFailedJob() (string, error) {
+	if a.pipeline == nil {
+		return "", fmt.Errorf("indexing pipeline not initialized")
+	}
+	pipeline := a.pipeline
+	return a.jobs.Start(jobKindRetryFailed, "Retry failed indexing", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		progress, err := pipeline.RetryFailed(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return waitIndexProgress(ctx, progress, report)
+	}), nil
+}
+
+// StartIndexBenchmarkJob is the background variant of RunIndexBenchmark
+func (a *App) StartIndexBenchmarkJob(sampleSize int) (string, error) {
+	if a.pipeline == nil {
+		return "", fmt.Errorf("indexing pipeline not initialized")
+	}
+	if !a.dbm.IsInitialized() {
+		return "", fmt.Errorf("database not initialized")
+	}
+	pipeline := a.pipeline
+	return a.jobs.Start(jobKindBenchmark, "Index benchmark", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		return pipeline.Benchmark(ctx, sampleSize)
+	}), nil
+}
+
+// StartEmbeddingNamespaceJob is the background variant of BuildEmbeddingNamespace
+func (a *App) StartEmbeddingNamespaceJob(model string) (string, error) {
+	if a.pipeline == nil {
+		return "", fmt.Errorf("indexing pipeline not initialized")
+	}
+	pipeline := a.pipeline
+	return a.jobs.Start(jobKindNamespace, "Build embeddings for "+model, func(ctx context.Context, report jobs.Reporter) (any, error) {
+		return pipeline.BuildEmbeddingNamespace(ctx, model)
+	}), nil
+}
+
+// StartCollectionExportJob is the background variant of ExportCollection; the
+// job result is the archive path
+func (a *App) StartCollectionExportJob(collectionID uint) (string, error) {
+	if !a.dbm.IsInitialized() {
+		return "", fmt.Errorf("database not initialized")
+	}
+	return a.jobs.Start(jobKindCollectionExport, "Export collection", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		return a.exportCollection(ctx, collectionID, report)
+	}), nil
+}
+
+// StartDuplicateScanJob is the background variant of FindDuplicateNotes; the
+// job result is the duplicate clusters
+func (a *App) StartDuplicateScanJob(threshold float32) (string, error) {
+	if a.ks == nil {
+		return "", fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	ks := a.ks
+	return a.jobs.Start(jobKindDuplicateScan, "Find duplicate notes", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		return ks.FindDuplicates(ctx, threshold, func(done, total int) {
+			report(done, total, "")
+		})
+	}), nil
+}
+
+// waitIndexProgress reports pipeline progress until the run completes. On
+// cancellation it still waits for in-flight files so the counts are final.
+func waitIndexProgress(ctx context.Context, progress *indexing.IndexProgress, report jobs.Reporter) (map[string]interface{}, error) {
+	ticker := time.NewTicker(indexProgressPollInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-progress.Done:
+			done = true
+		case <-ticker.C:
+			report(int(progress.Processed.Load()), progress.Total, "")
+		}
+	}
+	result := map[string]interface{}{
+		"total":     progress.Total,
+		"processed": progress.Processed.Load(),
+		"failed":    progress.Errors.Load(),
+	}
+	return result, ctx.Err()
+}
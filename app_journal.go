@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"notebit/pkg/journal"
+	"notebit/pkg/logger"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ UNDO JOURNAL API METHODS ============
+
+// UndoLastOperation reverts the most recent file operation within scope.
+// Scope is "workspace" (or empty) for the latest operation anywhere, or a note/folder path.
+func (a *App) UndoLastOperation(scope string) ([]journal.Entry, error) {
+	if a.journal == nil {
+		return nil, fmt.Errorf("operation journal not initialized")
+	}
+	reverted, err := a.journal.UndoLast(scope, journalReverter{app: a})
+	if err != nil {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{
+			"scope": scope,
+			"error": err.Error(),
+		}, "Undo failed")
+		return reverted, err
+	}
+	logger.InfoWithFields(a.ctx, map[string]interface{}{
+		"scope":      scope,
+		"operations": len(reverted),
+	}, "Undo applied")
+	return reverted, nil
+}
+
+// ListRecentOperations returns recent operations that can still be undone
+func (a *App) ListRecentOperations(limit int) ([]journal.Entry, error) {
+	if a.journal == nil {
+		return nil, fmt.Errorf("operation journal not initialized")
+	}
+	return a.journal.ListRecent(limit)
+}
+
+// snapshotForWrite captures the current content of path before it is overwritten
+func (a *App) snapshotForWrite(path string) journal.Entry {
+	entry := journal.Entry{Op: journal.OpWrite, Path: path}
+	if a.journal == nil {
+		return entry
+	}
+	if note, err := a.fm.ReadFile(path); err == nil {
+		entry.PrevContent = note.Content
+		entry.PrevExisted = true
+	}
+	return entry
+}
+
+// snapshotForDelete captures every note that deleting path would remove, grouped into one batch
+func (a *App) snapshotForDelete(path string) []journal.Entry {
+	if a.journal == nil {
+		return nil
+	}
+	paths, err := a.fm.ListMarkdownFiles(path)
+	if err != nil {
+		return nil
+	}
+	batchID := journal.NewBatchID()
+	entries := make([]journal.Entry, 0, len(paths))
+	for _, p := range paths {
+		note, err := a.fm.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, journal.Entry{
+			BatchID:     batchID,
+			Op:          journal.OpDelete,
+			Path:        p,
+			PrevContent: note.Content,
+			PrevExisted: true,
+		})
+	}
+	return entries
+}
+
+func (a *App) recordOperation(entry journal.Entry) {
+	if a.journal == nil {
+		return
+	}
+	if err := a.journal.Record(entry); err != nil {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{
+			"op":    entry.Op,
+			"path":  entry.Path,
+			"error": err.Error(),
+		}, "Failed to record operation")
+	}
+}
+
+// journalReverter applies undo steps through the file manager and keeps the index in sync.
+// It deliberately bypasses the App methods so that undo itself is not journaled.
+type journalReverter struct {
+	app *App
+}
+
+func (r journalReverter) WriteFile(path, content string) error {
+	if err := r.app.fm.SaveFile(path, content); err != nil {
+		return err
+	}
+	if r.app.dbm.IsInitialized() {
+		go r.app.indexFileContent(path, content)
+	}
+	return nil
+}
+
+func (r journalReverter) RemoveFile(path string) error {
+	if err := r.app.fm.DeleteFile(path); err != nil {
+		return err
+	}
+	if r.app.dbm.IsInitialized() {
+		_ = r.app.dbm.Repository().DeleteFile(path)
+	}
+	return nil
+}
+
+func (r journalReverter) MoveFile(oldPath, newPath string) error {
+	if err := r.app.fm.RenameFile(oldPath, newPath); err != nil {
+		return err
+	}
+	if r.app.dbm.IsInitialized() {
+		_ = r.app.dbm.Repository().RenameFile(oldPath, newPath)
+	}
+	return nil
+}
+
+// ============ EDIT JOURNAL API METHODS ============
+
+// editCompactionInterval is how often streamed edits are compacted in their journal
+var editCompactionInterval = 5 * time.Second
+
+// AppendEditDelta journals editor deltas for a note. The editor streams changes
+// here between explicit saves; they are synced to disk immediately, compacted in
+// the journal every few seconds, and replayed into the note on the next start
+// after a crash. The note itself is only written by the editor's saves.
+func (a *App) AppendEditDelta(path string, deltas []journal.EditDelta) error {
+	if a.edits == nil {
+		return fmt.Errorf("edit journal not initialized")
+	}
+	return a.edits.Append(path, deltas)
+}
+
+// FlushEditJournal compacts pending deltas for a note in its journal right away
+func (a *App) FlushEditJournal(path string) (*journal.RecoveredEdit, error) {
+	if a.edits == nil {
+		return nil, fmt.Errorf("edit journal not initialized")
+	}
+	return a.edits.Checkpoint(path)
+}
+
+// initializeEditLog opens the vault's edit journal, replays journals left by a
+// crash and starts periodic compaction
+func (a *App) initializeEditLog() {
+	a.closeEditLog()
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return
+	}
+	log, err := journal.NewEditLog(privateDataPath(basePath, journal.EditJournalDir), editLogStore{app: a})
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to initialize edit journal: %v", err)
+		return
+	}
+	// a.edits is still nil, so the recovered writes do not discard the
+	// journals being replayed
+	recovered, err := log.Recover()
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Edit journal recovery incomplete: %v", err)
+	}
+	if len(recovered) > 0 {
+		logger.InfoWithFields(a.ctx, map[string]interface{}{"notes": len(recovered)}, "Recovered unsaved edits")
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "editjournal:recovered", recovered)
+		}
+	}
+	log.Start(editCompactionInterval)
+	a.edits = log
+}
+
+func (a *App) closeEditLog() {
+	if a.edits == nil {
+		return
+	}
+	if err := a.edits.Close(); err != nil {
+		logger.Warn("Failed to flush edit journal: %v", err)
+	}
+	a.edits = nil
+}
+
+// discardEdits drops journaled deltas superseded by an explicit save or delete
+func (a *App) discardEdits(path string) {
+	if a.edits == nil {
+		return
+	}
+	if err := a.edits.Discard(path); err != nil {
+		logger.Debug("Failed to discard edit journal for %s: %v", path, err)
+	}
+}
+
+// moveEdits makes journaled deltas follow a note or folder that was renamed
+func (a *App) moveEdits(oldPath, newPath string) {
+	if a.edits == nil {
+		return
+	}
+	if err := a.edits.Move(oldPath, newPath); err != nil {
+		logger.Warn("Failed to move edit journal for %s: %v", oldPath, err)
+	}
+}
+
+// editLogStore reads notes for the edit journal through the file manager and
+// writes recovered edits like any app save, so they are journaled for undo
+// and indexed.
+type editLogStore struct {
+	app *App
+}
+
+func (s editLogStore) ReadNote(path string) (string, error) {
+	note, err := s.app.fm.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return note.Content, nil
+}
+
+func (s editLogStore) WriteNote(path, content string) error {
+	return s.app.writeNote(path, content)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/journal"
+)
+
+func newEditLogApp(t *testing.T) (*App, string) {
+	t.Helper()
+	interval := editCompactionInterval
+	editCompactionInterval = 5 * time.Millisecond
+	t.Cleanup(func() { editCompactionInterval = interval })
+
+	a := NewAppWithConfig(config.New())
+	base := t.TempDir()
+	if err := a.fm.SetBasePath(base); err != nil {
+		t.Fatal(err)
+	}
+	return a, base
+}
+
+func TestEditCompactionKeepsEditorBaseHash(t *testing.T) {
+	a, base := newEditLogApp(t)
+	if _, err := a.CreateFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := a.ReadFile("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.initializeEditLog()
+	t.Cleanup(a.closeEditLog)
+
+	if err := a.AppendEditDelta("a.md", []journal.EditDelta{{Offset: 3, Insert: " two"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Wait until compaction folded the delta, leaving only the journal header
+	dir := filepath.Join(base, filepath.FromSlash(journal.EditJournalDir))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, _ := os.ReadDir(dir)
+		if len(entries) == 1 {
+			data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+			if bytes.Count(data, []byte("\n")) == 1 && bytes.Contains(data, []byte("one two")) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("edit journal was not compacted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if disk, _ := os.ReadFile(filepath.Join(base, "a.md")); string(disk) != "one" {
+		t.Fatalf("compaction wrote the note: %q", disk)
+	}
+	if _, err := a.SaveFile("a.md", "one two", note.Hash); err != nil {
+		t.Fatalf("save with the original base hash failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("journal left after an explicit save: %d", len(entries))
+	}
+}
+
+func TestEditJournalRecoveredOnNextStart(t *testing.T) {
+	a, base := newEditLogApp(t)
+	if _, err := a.CreateFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	a.initializeEditLog()
+	if err := a.AppendEditDelta("a.md", []journal.EditDelta{{Offset: 3, Insert: " two"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Closing leaves the unsaved edit in the journal, not the note
+	a.closeEditLog()
+	if disk, _ := os.ReadFile(filepath.Join(base, "a.md")); string(disk) != "one" {
+		t.Fatalf("closing wrote the note: %q", disk)
+	}
+
+	a.initializeEditLog()
+	t.Cleanup(a.closeEditLog)
+	if disk, _ := os.ReadFile(filepath.Join(base, "a.md")); string(disk) != "one two" {
+		t.Fatalf("recovered note = %q, want %q", disk, "one two")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"notebit/pkg/files"
+)
+
+// LinkTarget is where a wiki link leads: a note and, for anchored links, the
+// heading or block within it
+type LinkTarget struct {
+	Path    string `json:"path"`
+	Heading string `json:"heading,omitempty"`
+	Block   string `json:"block,omitempty"`
+	Offset  int    `json:"offset"` // Byte offset of the heading or block, 0 for the whole note
+	Line    int    `json:"line"`   // 1-based line of Offset
+	Found   bool   `json:"found"`  // The anchor exists; when false Offset is the top of the note
+}
+
+// ============ LINK API METHODS ============
+
+// OpenLinkTarget resolves a wiki link, written as [[Note#Heading]],
+// [[Note^block-id]] or without the brackets, to the note it leads to and the
+// offset of its anchor there, for the editor to scroll to
+func (a *App) OpenLinkTarget(link string) (*LinkTarget, error) {
+	if a.graph == nil {
+		return nil, fmt.Errorf("graph service not initialized - please open a folder first")
+	}
+	body := strings.TrimSpace(link)
+	body = strings.TrimPrefix(body, "!")
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "[["), "]]")
+	wikiLink := files.ParseWikiLink(body)
+	if wikiLink.Target == "" {
+		return nil, fmt.Errorf("link %q does not name a note", link)
+	}
+
+	resolve, err := a.graph.Resolver()
+	if err != nil {
+		return nil, err
+	}
+	notePath, candidates := resolve(wikiLink.Target)
+	if notePath == "" {
+		if len(candidates) > 0 {
+			return nil, fmt.Errorf("link %q matches several notes: %s", link, strings.Join(candidates, ", "))
+		}
+		return nil, fmt.Errorf("no note matches link %q", link)
+	}
+
+	target := &LinkTarget{Path: notePath, Heading: wikiLink.Heading, Block: wikiLink.Block, Line: 1}
+	if wikiLink.Heading == "" && wikiLink.Block == "" {
+		target.Found = true
+		return target, nil
+	}
+	note, err := a.fm.ReadFile(notePath)
+	if err != nil {
+		return nil, err
+	}
+	var start int
+	if wikiLink.Block != "" {
+		start, _, target.Found = files.BlockRange(note.Content, wikiLink.Block)
+	} else {
+		start, _, target.Found = files.HeadingRange(note.Content, wikiLink.Heading)
+	}
+	if target.Found {
+		target.Offset = start
+		target.Line = strings.Count(note.Content[:start], "\n") + 1
+	}
+	return target, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/graph"
+)
+
+func TestOpenLinkTarget(t *testing.T) {
+	cfg := config.New()
+	a := NewAppWithConfig(cfg)
+	base := t.TempDir()
+	if err := a.fm.SetBasePath(base); err != nil {
+		t.Fatal(err)
+	}
+	dbm := database.NewManager()
+	if err := dbm.Init(base); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbm.Close() })
+	a.graph = graph.NewService(dbm, cfg)
+
+	notes := map[string]string{
+		"Guide.md":  "# Guide\nintro\n## Setup\nInstall it. ^install\n",
+		"x/Plan.md": "# Plan\n",
+		"y/Plan.md": "# Plan\n",
+	}
+	for notePath, content := range notes {
+		if err := a.fm.CreateFile(notePath, content); err != nil {
+			t.Fatal(err)
+		}
+		if err := dbm.Repository().IndexFile(notePath, content, database.NoteStats{}, 0, int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		link  string
+		want  LinkTarget
+		error string
+	}{
+		{link: "Guide", want: LinkTarget{Path: "Guide.md", Line: 1, Found: true}},
+		{link: "[[Guide#Setup|how]]", want: LinkTarget{Path: "Guide.md", Heading: "Setup", Offset: 14, Line: 3, Found: true}},
+		{link: "![[Guide^install]]", want: LinkTarget{Path: "Guide.md", Block: "install", Offset: 23, Line: 4, Found: true}},
+		{link: "[[Guide#Nope]]", want: LinkTarget{Path: "Guide.md", Heading: "Nope", Line: 1}},
+		{link: "[[Plan]]", error: "several notes"},
+		{link: "[[Missing]]", error: "no note"},
+		{link: "[[#Setup]]", error: "does not name a note"},
+	}
+	for _, tt := range tests {
+		got, err := a.OpenLinkTarget(tt.link)
+		if tt.error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("OpenLinkTarget(%q) error = %v, want %q", tt.link, err, tt.error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("OpenLinkTarget(%q): %v", tt.link, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("OpenLinkTarget(%q) = %+v, want %+v", tt.link, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"notebit/pkg/rag"
+)
+
+// mcpBackend serves the MCP tools (see pkg/mcp) from the knowledge and RAG
+// services. The MCP endpoint is mounted at /mcp on the local API server and
+// uses its token.
+type mcpBackend struct {
+	a *App
+}
+
+func (b mcpBackend) SearchNotes(ctx context.Context, query string, limit int) (any, error) {
+	if b.a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	return b.a.ks.FindSimilar(ctx, query, limit)
+}
+
+func (b mcpBackend) ReadNote(path string) (string, error) {
+	note, err := b.a.fm.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return note.Content, nil
+}
+
+func (b mcpBackend) ListRecent(limit int) (any, error) {
+	if !b.a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	files, err := b.a.dbm.Repository().ListRecentFiles(limit)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]map[string]interface{}, len(files))
+	for i, f := range files {
+		notes[i] = map[string]interface{}{
+			"path":          f.Path,
+			"title":         f.Title,
+			"last_modified": f.LastModified,
+		}
+	}
+	return notes, nil
+}
+
+// QueryRAG answers without a chat session, so external clients do not add
+// to the user's chat history
+func (b mcpBackend) QueryRAG(ctx context.Context, question string) (string, any, error) {
+	if b.a.rag == nil {
+		return "", nil, fmt.Errorf("RAG service not initialized")
+	}
+	b.a.notifyActivity()
+	response, err := b.a.rag.QueryWithOptions(ctx, question, rag.QueryOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	return response.Content, response.Sources, nil
+}
+
+// appVersion returns the module version the binary was built from
+func appVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"notebit/pkg/merge"
+)
+
+// ============ MERGE METHODS ============
+
+// MergeNoteVersions three-way merges local and remote edits of a note
+// against base. Non-overlapping changes are merged; the rest are returned as
+// conflict hunks for the user to settle with ResolveMerge.
+func (a *App) MergeNoteVersions(base, local, remote string) *merge.Result {
+	return merge.Merge(base, local, remote)
+}
+
+// MergeWithDisk merges the editor's unsaved content of path with the version
+// on disk, which changed since the editor loaded base
+func (a *App) MergeWithDisk(path, base, local string) (*merge.Result, error) {
+	disk, err := a.fm.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return merge.Merge(base, local, disk.Content), nil
+}
+
+// ResolveMerge returns the merged text with each conflict settled by the
+// matching choice: "local", "remote", "both" or "base"
+func (a *App) ResolveMerge(hunks []merge.Hunk, choices []string) (string, error) {
+	return merge.Resolve(hunks, choices)
+}
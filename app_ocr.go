@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/jobs"
+	"notebit/pkg/logger"
+)
+
+// imageReading tracks the background job reading images of indexed notes
+type imageReading struct {
+	mu      sync.Mutex
+	running bool
+	// pending is set when notes are queued while the job is finishing
+	pending bool
+}
+
+// ============ IMAGE READING API METHODS ============
+
+// GetOCRConfig returns the settings for reading text from images in notes
+func (a *App) GetOCRConfig() (config.OCRConfig, error) {
+	return a.cfg.GetOCRConfig(), nil
+}
+
+// SetOCRConfig sets the settings for reading text from images in notes.
+// Notes already indexed are read when they next change or on a full reindex.
+func (a *App) SetOCRConfig(cfg config.OCRConfig) error {
+	switch cfg.Provider {
+	case config.OCRTesseract, config.OCRVision:
+	default:
+		return fmt.Errorf("unknown OCR provider: %s", cfg.Provider)
+	}
+	cfg.TesseractBinary = strings.TrimSpace(cfg.TesseractBinary)
+	cfg.Languages = strings.TrimSpace(cfg.Languages)
+	cfg.VisionModel = strings.TrimSpace(cfg.VisionModel)
+	a.cfg.SetOCRConfig(cfg)
+	if err := a.cfg.Save(); err != nil {
+		return err
+	}
+	a.applyImageReaders()
+	return nil
+}
+
+// GetImageCaptionConfig returns the settings for describing images in notes
+func (a *App) GetImageCaptionConfig() (config.ImageCaptionConfig, error) {
+	return a.cfg.GetImageCaptionConfig(), nil
+}
+
+// SetImageCaptionConfig sets the settings for describing images in notes
+// with a vision model. Notes already indexed are described when they next
+// change or on a full reindex.
+func (a *App) SetImageCaptionConfig(cfg config.ImageCaptionConfig) error {
+	cfg.Model = strings.TrimSpace(cfg.Model)
+	a.cfg.SetImageCaptionConfig(cfg)
+	if err := a.cfg.Save(); err != nil {
+		return err
+	}
+	a.applyImageReaders()
+	return nil
+}
+
+// FindSimilarImages finds images embedded in notes by what they show or
+// say, e.g. "the architecture diagram", best match first
+func (a *App) FindSimilarImages(description string, limit int) ([]SimilarNote, error) {
+	if a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	results, err := a.ks.FindSimilarImages(context.Background(), description, limit)
+	if err != nil {
+		return nil, err
+	}
+	return toSimilarNotes(results), nil
+}
+
+// readQueuedImages starts the background job reading the images of the notes
+// indexing queued, or has the running job start again once it is done
+func (a *App) readQueuedImages() {
+	a.imageReading.mu.Lock()
+	defer a.imageReading.mu.Unlock()
+	if a.imageReading.running {
+		a.imageReading.pending = true
+		return
+	}
+	pipeline := a.pipeline
+	if pipeline == nil {
+		return
+	}
+	a.imageReading.running = true
+	a.jobs.Start(jobKindImageRead, "Read images in notes", func(ctx context.Context, report jobs.Reporter) (any, error) {
+		defer func() {
+			a.imageReading.mu.Lock()
+			again := a.imageReading.pending && ctx.Err() == nil
+			a.imageReading.running, a.imageReading.pending = false, false
+			a.imageReading.mu.Unlock()
+			if again {
+				a.readQueuedImages()
+			}
+		}()
+		return pipeline.ReadQueuedImages(ctx, report)
+	})
+}
+
+// applyImageReaders gives the indexing pipeline the image text extractor and
+// captioner that are enabled
+func (a *App) applyImageReaders() {
+	if a.pipeline == nil {
+		return
+	}
+	var extractor, captioner ai.ImageTextExtractor
+	var err error
+	if cfg := a.cfg.GetOCRConfig(); cfg.Enabled {
+		if extractor, err = ai.NewImageTextExtractor(cfg, a.llmOpenAIConfig()); err != nil {
+			logger.WarnWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "Image OCR unavailable")
+		}
+	}
+	if cfg := a.cfg.GetImageCaptionConfig(); cfg.Enabled {
+		if captioner, err = ai.NewImageCaptioner(cfg, a.llmOpenAIConfig()); err != nil {
+			logger.WarnWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "Image captions unavailable")
+		}
+	}
+	a.pipeline.SetImageTextExtractor(extractor)
+	a.pipeline.SetImageCaptioner(captioner)
+}
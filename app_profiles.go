@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ============ CONFIG PROFILE API METHODS ============
+
+// ConfigProfileList is the stored profiles and the one last applied
+type ConfigProfileList struct {
+	Profiles []config.Profile `json:"profiles"`
+	Active   string           `json:"active"`
+}
+
+// loadProfileStore reads the config profiles stored next to config.json
+func (a *App) loadProfileStore() {
+	path := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		path = filepath.Join(configDir, "notebit", "profiles.json")
+	}
+	store, err := config.LoadProfileStore(path)
+	if err != nil {
+		logger.Warn("Failed to read config profiles: %v", err)
+	}
+	a.profiles = store
+}
+
+// ListConfigProfiles returns the saved profiles
+func (a *App) ListConfigProfiles() (ConfigProfileList, error) {
+	if a.profiles == nil {
+		return ConfigProfileList{Profiles: []config.Profile{}}, nil
+	}
+	return ConfigProfileList{Profiles: a.profiles.List(), Active: a.profiles.Active()}, nil
+}
+
+// SaveConfigProfile stores the current provider, model, URL and RAG settings
+// as a profile, replacing a profile of the same name
+func (a *App) SaveConfigProfile(name string) (config.Profile, error) {
+	if a.profiles == nil {
+		return config.Profile{}, fmt.Errorf("config profiles not available")
+	}
+	profile := a.cfg.CaptureProfile(strings.TrimSpace(name))
+	if err := a.profiles.Put(profile); err != nil {
+		return config.Profile{}, err
+	}
+	return profile, nil
+}
+
+// DeleteConfigProfile removes a saved profile
+func (a *App) DeleteConfigProfile(name string) error {
+	if a.profiles == nil {
+		return fmt.Errorf("config profiles not available")
+	}
+	return a.profiles.Delete(strings.TrimSpace(name))
+}
+
+// ApplyProfile switches to the settings of a saved profile and reconnects
+// the embedding and chat providers
+func (a *App) ApplyProfile(name string) error {
+	if a.profiles == nil {
+		return fmt.Errorf("config profiles not available")
+	}
+	profile, ok := a.profiles.Get(strings.TrimSpace(name))
+	if !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	a.cfg.ApplyProfile(profile)
+
+	if err := a.ai.Reconfigure(); err != nil {
+		logger.Warn("AI providers not fully available after applying profile %s: %v", profile.Name, err)
+	}
+	a.llm = nil
+	a.rag = nil
+	a.initializeLLM()
+	a.initializeRAG()
+
+	if err := a.profiles.SetActive(profile.Name); err != nil {
+		return err
+	}
+	return a.cfg.Save()
+}
+
+// ============ CONFIG TRANSFER API METHODS ============
+
+// ExportConfig returns the settings as JSON for another machine or a bug
+// report. API keys are redacted unless includeSecrets is set.
+func (a *App) ExportConfig(includeSecrets bool) (string, error) {
+	data, err := a.cfg.Export(includeSecrets)
+	if err != nil {
+		return "", err
+	}
+	logger.Audit(a.ctx, auditConfigExport, "", nil, map[string]interface{}{"include_secrets": includeSecrets})
+	return string(data), nil
+}
+
+// ImportConfig applies settings exported by ExportConfig. Redacted secrets
+// keep the values already configured here.
+func (a *App) ImportConfig(data string) error {
+	if err := a.cfg.Import([]byte(data)); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	logger.Audit(a.ctx, auditConfigImport, "", nil, map[string]interface{}{"size": len(data)})
+
+	if err := a.ai.Reconfigure(); err != nil {
+		logger.Warn("AI providers not fully available after config import: %v", err)
+	}
+	a.llm = nil
+	a.rag = nil
+	a.initializeLLM()
+	a.initializeRAG()
+	if a.dbm.IsInitialized() {
+		a.applyVectorEngineConfig()
+		a.startScheduler()
+		a.startDigestScheduler()
+	}
+	return a.cfg.Save()
+}
@@ -2,11 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"notebit/pkg/ai"
+	"notebit/pkg/chat"
 	"notebit/pkg/config"
 	"notebit/pkg/database"
 	"notebit/pkg/graph"
+	"notebit/pkg/indexing"
+	"notebit/pkg/knowledge"
+	"notebit/pkg/logger"
+	"notebit/pkg/rag"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // ============ SEMANTIC SEARCH API METHODS ============
@@ -19,20 +32,34 @@ type SimilarNote struct {
 	Heading    string  `json:"heading"`
 	Similarity float32 `json:"similarity"`
 	ChunkID    uint    `json:"chunk_id"`
+	// Source is the image the content was read from, "" for note text
+	Source string `json:"source,omitempty"`
 }
 
 // FindSimilar finds semantically similar notes based on content
 func (a *App) FindSimilar(content string, limit int) ([]SimilarNote, error) {
+	return a.FindSimilarInLanguage(content, "", limit)
+}
+
+// FindSimilarInLanguage is FindSimilar with a hint that matching notes may be
+// written in language (ISO 639-1, e.g. "zh"), whose multilingual embedding
+// model is then searched too
+func (a *App) FindSimilarInLanguage(content, language string, limit int) ([]SimilarNote, error) {
 	if a.ks == nil {
 		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
 	}
 
-	results, err := a.ks.FindSimilar(content, limit)
+	results, err := a.ks.FindSimilarInLanguage(context.Background(), content, language, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to local struct to maintain API compatibility
+	return toSimilarNotes(results), nil
+}
+
+// toSimilarNotes converts knowledge results to the local struct to maintain
+// API compatibility
+func toSimilarNotes(results []knowledge.SimilarNote) []SimilarNote {
 	notes := make([]SimilarNote, len(results))
 	for i, r := range results {
 		notes[i] = SimilarNote{
@@ -42,9 +69,78 @@ func (a *App) FindSimilar(content string, limit int) ([]SimilarNote, error) {
 			Heading:    r.Heading,
 			Similarity: r.Similarity,
 			ChunkID:    r.ChunkID,
+			Source:     r.Source,
 		}
 	}
-	return notes, nil
+	return notes
+}
+
+// GetRelatedNotes returns the notes most related to the note at path, scored
+// per note rather than per chunk and excluding the note itself
+func (a *App) GetRelatedNotes(path string, limit int) ([]knowledge.RelatedNote, error) {
+	if a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	return a.ks.GetRelatedNotes(context.Background(), path, limit)
+}
+
+// GetSimilarFiles returns the notes most similar to the note at path by
+// their note-level embedding, a cheaper alternative to GetRelatedNotes
+func (a *App) GetSimilarFiles(path string, limit int) ([]database.FileSimilarity, error) {
+	if a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	return a.ks.GetSimilarFiles(path, limit)
+}
+
+// GetUnlinkedMentions returns plain-text mentions of the note's title or
+// aliases in other notes, with locations the UI can pass to LinkMention
+func (a *App) GetUnlinkedMentions(path string) ([]knowledge.UnlinkedMention, error) {
+	if a.ks == nil {
+		return nil, fmt.Errorf("knowledge service not initialized - please open a folder first")
+	}
+	return a.ks.GetUnlinkedMentions(context.Background(), path)
+}
+
+// LinkMention turns the mention at offset in sourcePath into a wiki link to
+// targetPath. It fails if the text at offset no longer matches, e.g. because
+// the note was edited after the mentions were listed.
+func (a *App) LinkMention(sourcePath string, offset, length int, text, targetPath string) error {
+	note, err := a.fm.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	content := note.Content
+	if offset < 0 || length <= 0 || offset+length > len(content) || content[offset:offset+length] != text {
+		return fmt.Errorf("mention not found at the given location - the note may have changed")
+	}
+
+	target := strings.TrimSuffix(path.Base(targetPath), path.Ext(targetPath))
+	link := "[[" + target + "]]"
+	if text != target {
+		link = "[[" + target + "|" + text + "]]"
+	}
+	return a.writeNote(sourcePath, content[:offset]+link+content[offset+length:])
+}
+
+// SimilarNotesResult wraps similarity results with index coverage metadata
+type SimilarNotesResult struct {
+	Results  []SimilarNote           `json:"results"`
+	Coverage *database.IndexCoverage `json:"coverage,omitempty"` // nil when fully embedded
+}
+
+// FindSimilarWithCoverage is FindSimilar plus a coverage report, so callers can tell
+// users when results only reflect part of the vault (e.g. during a long indexing run)
+func (a *App) FindSimilarWithCoverage(content string, limit int) (*SimilarNotesResult, error) {
+	notes, err := a.FindSimilar(content, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &SimilarNotesResult{Results: notes}
+	if coverage, err := a.ks.GetIndexCoverage(); err == nil && coverage.Partial {
+		result.Coverage = coverage
+	}
+	return result, nil
 }
 
 // GetSimilarityStatus returns the availability status of semantic search
@@ -70,21 +166,61 @@ func (a *App) GetSimilarityStatus() (map[string]interface{}, error) {
 	return a.ks.GetSimilarityStatus()
 }
 
-// GetVectorSearchEngine returns current vector search engine and available options.
+// GetVectorSearchEngine returns current vector search engine and available options,
+// the state of the sqlite-vec extension and, when sqlite-vec was requested but is
+// not in use, the reason for the fallback.
 func (a *App) GetVectorSearchEngine() (map[string]interface{}, error) {
+	status := map[string]interface{}{
+		"current":    "",
+		"configured": a.cfg.GetVectorSearchEngine(),
+		"available":  []string{database.VectorEngineBruteForce, database.VectorEngineSQLiteVec},
+		"extension":  database.GetVecExtensionStatus(),
+	}
 	if !a.dbm.IsInitialized() {
-		return map[string]interface{}{
-			"current":   "",
-			"available": []string{database.VectorEngineBruteForce, database.VectorEngineSQLiteVec},
-		}, nil
+		return status, nil
+	}
+
+	repo := a.dbm.Repository()
+	status["current"] = repo.GetVectorEngine()
+	if reason := repo.VectorEngineFallbackReason(); reason != "" {
+		status["fallback_reason"] = reason
 	}
+	return status, nil
+}
 
+// InstallSQLiteVec downloads the sqlite-vec extension for this platform,
+// verifying it against the checksum pinned in the binary. It is loaded by
+// databases opened afterwards, so the vault must be reopened to use it.
+func (a *App) InstallSQLiteVec() (map[string]interface{}, error) {
+	dir, err := extensionsDir()
+	if err != nil {
+		return nil, err
+	}
+	timer := logger.StartTimer()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	path, err := database.InstallSQLiteVec(ctx, ai.NewHTTPClient(5*time.Minute), dir)
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "sqlite-vec install failed")
+		return nil, err
+	}
+	database.SetSQLiteVecExtension(path)
+	logger.InfoWithDuration(a.ctx, timer(), "Installed sqlite-vec %s to %s", database.SQLiteVecVersion, path)
 	return map[string]interface{}{
-		"current":   a.dbm.Repository().GetVectorEngine(),
-		"available": []string{database.VectorEngineBruteForce, database.VectorEngineSQLiteVec},
+		"path":    path,
+		"version": database.SQLiteVecVersion,
 	}, nil
 }
 
+// extensionsDir is where downloaded SQLite extensions are kept
+func extensionsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "notebit", "extensions"), nil
+}
+
 // SetVectorSearchEngine updates vector search engine with fallback behavior.
 func (a *App) SetVectorSearchEngine(engine string) (map[string]interface{}, error) {
 	if !a.dbm.IsInitialized() {
@@ -103,6 +239,36 @@ func (a *App) SetVectorSearchEngine(engine string) (map[string]interface{}, erro
 	}, nil
 }
 
+// GetVectorSpace returns embedding normalization, the similarity metric and
+// how many stored embeddings are normalized
+func (a *App) GetVectorSpace() (*database.VectorSpaceStatus, error) {
+	if !a.dbm.IsInitialized() {
+		normalize, metric := a.cfg.GetVectorSpace()
+		return &database.VectorSpaceStatus{VectorSpace: database.VectorSpace{Normalize: normalize, Metric: metric}}, nil
+	}
+	return a.dbm.Repository().GetVectorSpaceStatus()
+}
+
+// SetVectorSpace sets embedding normalization and the similarity metric.
+// Normalization applies to notes indexed afterwards; until the index is
+// rebuilt, searches compare all embeddings normalized.
+func (a *App) SetVectorSpace(normalize bool, metric string) (*database.VectorSpaceStatus, error) {
+	switch metric {
+	case config.SimilarityCosine, config.SimilarityDot, config.SimilarityEuclidean:
+	default:
+		return nil, fmt.Errorf("unknown similarity metric %q", metric)
+	}
+	a.cfg.SetVectorSpace(normalize, metric)
+	if err := a.cfg.Save(); err != nil {
+		return nil, err
+	}
+	a.applyVectorEngineConfig()
+	if a.rag != nil {
+		a.rag.ClearRetrievalCache()
+	}
+	return a.GetVectorSpace()
+}
+
 // ============ RAG CHAT API METHODS ============
 
 // RAGQuery performs a RAG query
@@ -119,6 +285,57 @@ func (a *App) RAGQuery(query string) (map[string]interface{}, error) {
 
 // RAGQueryWithSession performs a RAG query and persists the chat in a given session
 func (a *App) RAGQueryWithSession(sessionID, query string) (map[string]interface{}, error) {
+	return a.RAGQueryWithAttachments(sessionID, query, nil)
+}
+
+// RAGQueryWithAttachments performs a RAG query with attachments created by
+// AttachNoteToChat or AttachImageToChat; attached note content is added to the prompt
+func (a *App) RAGQueryWithAttachments(sessionID, query string, attachments []chat.Attachment) (map[string]interface{}, error) {
+	return a.ragQuery(sessionID, query, attachments, rag.QueryOptions{})
+}
+
+// RAGQueryWithMinSimilarity performs a RAG query using minSimilarity instead of
+// the configured threshold for this query only
+func (a *App) RAGQueryWithMinSimilarity(sessionID, query string, minSimilarity float32) (map[string]interface{}, error) {
+	return a.ragQuery(sessionID, query, nil, rag.QueryOptions{MinSimilarity: &minSimilarity})
+}
+
+// RAGQueryInLanguage performs a RAG query in a session, also retrieving notes
+// written in language (ISO 639-1) through its multilingual embedding model
+func (a *App) RAGQueryInLanguage(sessionID, query, language string) (map[string]interface{}, error) {
+	return a.ragQuery(sessionID, query, nil, rag.QueryOptions{Language: language})
+}
+
+// RAGQueryWithTools performs a RAG query in which the model may search and
+// open notes itself before answering. Note edits it proposes are returned as
+// "pending_actions" and only run once confirmed with ConfirmNoteAction.
+func (a *App) RAGQueryWithTools(sessionID, query string) (map[string]interface{}, error) {
+	if a.rag == nil {
+		return nil, fmt.Errorf("RAG service not initialized")
+	}
+	proposals := rag.NewActionProposals(a.actions, sessionID)
+	tools := []rag.Tool{
+		a.rag.SearchNotesTool(),
+		rag.OpenNoteTool(func(path string) (string, error) {
+			note, err := a.fm.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			return note.Content, nil
+		}),
+	}
+	tools = append(tools, proposals.Tools()...)
+
+	result, err := a.ragQuery(sessionID, query, nil, rag.QueryOptions{Tools: tools})
+	if err != nil {
+		return nil, err
+	}
+	result["pending_actions"] = proposals.Actions()
+	return result, nil
+}
+
+// ragQuery runs a RAG query in a session with the given retrieval options
+func (a *App) ragQuery(sessionID, query string, attachments []chat.Attachment, opts rag.QueryOptions) (map[string]interface{}, error) {
 	if a.rag == nil {
 		return nil, fmt.Errorf("RAG service not initialized")
 	}
@@ -132,12 +349,20 @@ func (a *App) RAGQueryWithSession(sessionID, query string) (map[string]interface
 		return nil, fmt.Errorf("query cannot be empty")
 	}
 
-	if _, err := a.chatSvc.AppendMessage(sessionID, "user", query, nil, nil, "sent"); err != nil {
+	a.notifyActivity()
+	if _, err := a.chatSvc.AppendMessageWithAttachments(sessionID, "user", query, nil, nil, "sent", attachments); err != nil {
 		return nil, err
 	}
+	return a.answerInSession(sessionID, query, attachments, opts)
+}
 
-	response, err := a.rag.Query(context.Background(), query)
+// answerInSession runs the RAG query for a user message already stored in the
+// session and appends the answer
+func (a *App) answerInSession(sessionID, query string, attachments []chat.Attachment, opts rag.QueryOptions) (map[string]interface{}, error) {
+	opts = a.sessionQueryOptions(sessionID, attachments, opts)
+	response, err := a.rag.QueryWithOptions(context.Background(), query, opts)
 	if err != nil {
+		err = userFacingLLMError(err)
 		_, _ = a.chatSvc.AppendMessage(sessionID, "system", "Error: "+err.Error(), nil, nil, "error")
 		return nil, err
 	}
@@ -147,19 +372,243 @@ func (a *App) RAGQueryWithSession(sessionID, query string) (map[string]interface
 		used := *response.TokensUsed
 		tokensUsed = &used
 	}
-	if _, err := a.chatSvc.AppendMessage(sessionID, "assistant", response.Content, response.Sources, tokensUsed, "done"); err != nil {
+	answer, err := a.chatSvc.AppendMessage(sessionID, "assistant", response.Content, response.Sources, tokensUsed, "done")
+	if err != nil {
+		return nil, err
+	}
+	if response.CostUSD > 0 {
+		if err := a.chatSvc.SetMessageCost(answer.ID, response.CostUSD); err != nil {
+			logger.Warn("Failed to record chat message cost: %v", err)
+		}
+	}
+	a.maybeAutoTitleSession(sessionID, query, response.Content)
+
+	return map[string]interface{}{
+		"session_id":          sessionID,
+		"message_id":          response.MessageID,
+		"content":             response.Content,
+		"sources":             response.Sources,
+		"tokens_used":         response.TokensUsed,
+		"cost_usd":            response.CostUSD,
+		"coverage":            response.Coverage,
+		"no_relevant_context": response.NoRelevantContext,
+		"tool_calls":          response.ToolCalls,
+		"retrieval_query":     response.RetrievalQuery,
+		"confidence":          response.Confidence,
+		"best_similarity":     response.BestSimilarity,
+		"follow_ups":          response.FollowUps,
+		"action_items":        response.ActionItems,
+	}, nil
+}
+
+// userFacingLLMError rewords provider failures the user can act on; the
+// original error stays wrapped
+func userFacingLLMError(err error) error {
+	var rle *ai.RateLimitError
+	switch {
+	case errors.As(err, &rle) && rle.RetryAfter > 0:
+		return fmt.Errorf("the AI provider is rate limiting requests, try again in %s: %w", rle.RetryAfter.Round(time.Second), err)
+	case errors.Is(err, ai.ErrRateLimited):
+		return fmt.Errorf("the AI provider is rate limiting requests, try again shortly: %w", err)
+	case errors.Is(err, ai.ErrAuthFailed):
+		return fmt.Errorf("the AI provider rejected the API key, check it in the LLM settings: %w", err)
+	case errors.Is(err, ai.ErrContextTooLong):
+		return fmt.Errorf("the question and its notes do not fit the model's context window, lower the number of context chunks or pick a larger model: %w", err)
+	case errors.Is(err, ai.ErrProviderUnavailable):
+		return fmt.Errorf("the AI provider is temporarily unavailable, try again later: %w", err)
+	}
+	return err
+}
+
+// sessionQueryOptions adds a session's attachments, overrides and attached
+// notes to opts, and caches its retrievals
+func (a *App) sessionQueryOptions(sessionID string, attachments []chat.Attachment, opts rag.QueryOptions) rag.QueryOptions {
+	opts.AttachmentContext = a.chatSvc.AttachmentPromptContext(attachments)
+	opts.SessionID = sessionID
+	if overrides, err := a.chatSvc.GetSessionOverrides(sessionID); err == nil {
+		opts.Model = overrides.Model
+		opts.Temperature = overrides.Temperature
+		opts.SystemPrompt = overrides.SystemPrompt
+	}
+	if paths, err := a.chatSvc.ListAttachedNotes(sessionID); err == nil && len(paths) > 0 {
+		opts.PinnedPaths = paths
+	}
+	return opts
+}
+
+// streamSaveInterval is how often a streaming answer is written to the database
+const streamSaveInterval = time.Second
+
+// RAGQueryStream performs a RAG query in a session, emitting the answer as it
+// is generated. The answer is stored at once with status "streaming" and
+// saved periodically; "chat:stream-delta" events carry each piece of text and
+// "chat:stream-done" the final status. CancelChatStream stops generation and
+// keeps the partial answer with status "interrupted".
+func (a *App) RAGQueryStream(sessionID, query string) (map[string]interface{}, error) {
+	if a.rag == nil {
+		return nil, fmt.Errorf("RAG service not initialized")
+	}
+	if a.chatSvc == nil {
+		return nil, fmt.Errorf("chat service not initialized")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return nil, fmt.Errorf("session id cannot be empty")
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	a.notifyActivity()
+	if _, err := a.chatSvc.AppendMessage(sessionID, "user", query, nil, nil, chat.StatusSent); err != nil {
+		return nil, err
+	}
+	answer, err := a.chatSvc.BeginStreamingMessage(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if !a.registerStream(sessionID, cancel) {
+		cancel()
+		_ = a.chatSvc.DeleteMessage(answer.ID)
+		return nil, fmt.Errorf("an answer is already being generated in this session")
+	}
+	defer a.unregisterStream(sessionID)
+
+	var content strings.Builder
+	lastSave := time.Now()
+	opts := a.sessionQueryOptions(sessionID, nil, rag.QueryOptions{})
+	response, err := a.rag.QueryStream(ctx, query, opts, func(delta string) {
+		content.WriteString(delta)
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "chat:stream-delta", map[string]interface{}{
+				"session_id": sessionID,
+				"message_id": answer.ID,
+				"delta":      delta,
+			})
+		}
+		if time.Since(lastSave) >= streamSaveInterval {
+			_ = a.chatSvc.UpdateStreamingMessage(answer.ID, content.String())
+			lastSave = time.Now()
+		}
+	})
+
+	status := chat.StatusDone
+	if err != nil {
+		err = userFacingLLMError(err)
+	}
+	switch {
+	case err != nil && response == nil:
+		// Nothing was generated: drop the placeholder and record the error as usual
+		_ = a.chatSvc.DeleteMessage(answer.ID)
+		_, _ = a.chatSvc.AppendMessage(sessionID, "system", "Error: "+err.Error(), nil, nil, chat.StatusError)
+		a.emitStreamDone(sessionID, answer.ID, chat.StatusError)
 		return nil, err
+	case err != nil:
+		status = chat.StatusInterrupted
+		if !errors.Is(err, context.Canceled) {
+			logger.Warn("Streaming answer in session %s was interrupted: %v", sessionID, err)
+		}
+	}
+	if ferr := a.chatSvc.FinishStreamingMessage(answer.ID, response.Content, response.Sources, response.TokensUsed, status); ferr != nil {
+		return nil, ferr
+	}
+	a.emitStreamDone(sessionID, answer.ID, status)
+	if status == chat.StatusDone {
+		a.maybeAutoTitleSession(sessionID, query, response.Content)
 	}
 
 	return map[string]interface{}{
-		"session_id":  sessionID,
-		"message_id":  response.MessageID,
-		"content":     response.Content,
-		"sources":     response.Sources,
-		"tokens_used": response.TokensUsed,
+		"session_id":          sessionID,
+		"message_id":          answer.ID,
+		"content":             response.Content,
+		"sources":             response.Sources,
+		"status":              status,
+		"coverage":            response.Coverage,
+		"no_relevant_context": response.NoRelevantContext,
+		"confidence":          response.Confidence,
+		"best_similarity":     response.BestSimilarity,
+		"follow_ups":          response.FollowUps,
+		"action_items":        response.ActionItems,
 	}, nil
 }
 
+// CancelChatStream stops the answer being generated in a session; it reports
+// whether one was running
+func (a *App) CancelChatStream(sessionID string) bool {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	cancel, ok := a.streams[strings.TrimSpace(sessionID)]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (a *App) registerStream(sessionID string, cancel context.CancelFunc) bool {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if _, busy := a.streams[sessionID]; busy {
+		return false
+	}
+	if a.streams == nil {
+		a.streams = make(map[string]context.CancelFunc)
+	}
+	a.streams[sessionID] = cancel
+	return true
+}
+
+func (a *App) unregisterStream(sessionID string) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if cancel, ok := a.streams[sessionID]; ok {
+		cancel()
+		delete(a.streams, sessionID)
+	}
+}
+
+func (a *App) emitStreamDone(sessionID, messageID, status string) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "chat:stream-done", map[string]interface{}{
+			"session_id": sessionID,
+			"message_id": messageID,
+			"status":     status,
+		})
+	}
+}
+
+// maybeAutoTitleSession names a placeholder-titled session from its first
+// exchange in the background and emits "chat:session-renamed"
+func (a *App) maybeAutoTitleSession(sessionID, question, answer string) {
+	if !a.chatSvc.GetStorageOptions().AutoTitle {
+		return
+	}
+	if needs, err := a.chatSvc.NeedsAutoTitle(sessionID); err != nil || !needs {
+		return
+	}
+
+	go func() {
+		title, err := a.rag.GenerateSessionTitle(context.Background(), question, answer)
+		if err != nil {
+			logger.WarnWithFields(a.ctx, map[string]interface{}{
+				"session_id": sessionID,
+				"error":      err.Error(),
+			}, "Failed to generate chat session title")
+			return
+		}
+		if err := a.chatSvc.RenameSession(sessionID, title); err != nil {
+			logger.Warn("Failed to rename chat session %s: %v", sessionID, err)
+			return
+		}
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "chat:session-renamed", map[string]interface{}{
+				"session_id": sessionID,
+				"title":      title,
+			})
+		}
+	}()
+}
+
 // GetRAGStatus returns the status of the RAG service
 func (a *App) GetRAGStatus() (map[string]interface{}, error) {
 	if a.rag == nil {
@@ -186,12 +635,22 @@ func (a *App) GetRAGStatus() (map[string]interface{}, error) {
 // GetGraphData returns the knowledge graph data
 func (a *App) GetGraphData() (*graph.GraphData, error) {
 	if a.graph == nil {
-		return &graph.GraphData{Nodes: []graph.Node{}, Links: []graph.Link{}}, nil
+		return &graph.GraphData{Nodes: []graph.Node{}, Links: []graph.Link{}, Unresolved: []graph.UnresolvedLink{}}, nil
 	}
 
 	return a.graph.BuildGraph()
 }
 
+// GetUnresolvedLinks returns wiki links that match no note, or several notes
+// with no way to tell them apart
+func (a *App) GetUnresolvedLinks() ([]graph.UnresolvedLink, error) {
+	data, err := a.GetGraphData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Unresolved, nil
+}
+
 // GetGraphConfig returns the graph configuration
 func (a *App) GetGraphConfig() (config.GraphConfig, error) {
 	return a.cfg.GetGraphConfig(), nil
@@ -199,11 +658,110 @@ func (a *App) GetGraphConfig() (config.GraphConfig, error) {
 
 // SetGraphConfig sets the graph configuration
 func (a *App) SetGraphConfig(minSimilarityThreshold float32, maxNodes int, showImplicitLinks bool) error {
-	cfg := config.GraphConfig{
-		MinSimilarityThreshold: minSimilarityThreshold,
-		MaxNodes:               maxNodes,
-		ShowImplicitLinks:      showImplicitLinks,
-	}
+	cfg := a.cfg.GetGraphConfig()
+	cfg.MinSimilarityThreshold = minSimilarityThreshold
+	cfg.MaxNodes = maxNodes
+	cfg.ShowImplicitLinks = showImplicitLinks
 	a.cfg.SetGraphConfig(cfg)
 	return a.cfg.Save()
 }
+
+// SetGraphDimensions toggles folder nodes and same-week temporal links in the graph
+func (a *App) SetGraphDimensions(showFolderNodes, showTemporalLinks bool) error {
+	cfg := a.cfg.GetGraphConfig()
+	cfg.ShowFolderNodes = showFolderNodes
+	cfg.ShowTemporalLinks = showTemporalLinks
+	a.cfg.SetGraphConfig(cfg)
+	return a.cfg.Save()
+}
+
+// ============ EMBEDDING NAMESPACE API METHODS ============
+
+// BuildEmbeddingNamespace embeds all chunks with model into a separate namespace so
+// its retrieval quality can be compared against the primary embeddings
+func (a *App) BuildEmbeddingNamespace(model string) (*indexing.NamespaceProgress, error) {
+	if a.pipeline == nil {
+		return nil, fmt.Errorf("indexing pipeline not initialized")
+	}
+	return a.pipeline.BuildEmbeddingNamespace(context.Background(), model)
+}
+
+// DeleteEmbeddingNamespace removes the namespaced embeddings stored for model
+func (a *App) DeleteEmbeddingNamespace(model string) (int64, error) {
+	if !a.dbm.IsInitialized() {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	model = strings.TrimSpace(model)
+	deleted, err := a.dbm.Repository().DeleteEmbeddingNamespace(model)
+	if err != nil {
+		return 0, err
+	}
+	logger.Audit(a.ctx, auditIndexNamespace, model, map[string]interface{}{"embeddings": deleted}, nil)
+	return deleted, nil
+}
+
+// GetEmbeddingStats returns embedding counts, including a per-model breakdown
+func (a *App) GetEmbeddingStats() (*database.EmbeddingStats, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return a.dbm.Repository().GetEmbeddingStats()
+}
+
+// SearchSimilarInNamespace runs a similarity search against one embedding model's
+// namespace; the query is embedded with the same model
+func (a *App) SearchSimilarInNamespace(model, query string, limit int) ([]SimilarNote, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if a.ai == nil {
+		return nil, fmt.Errorf("AI service not initialized")
+	}
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	resp, err := a.ai.GenerateEmbeddingWithModel(context.Background(), model, query)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := a.dbm.Repository().SearchSimilar(model, resp.Embedding, limit)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]SimilarNote, 0, len(chunks))
+	for _, c := range chunks {
+		if c.File == nil {
+			continue
+		}
+		notes = append(notes, SimilarNote{
+			Path:       c.File.Path,
+			Title:      c.File.Title,
+			Content:    c.Content,
+			Heading:    c.Heading,
+			Similarity: c.Similarity,
+			ChunkID:    c.ChunkID,
+			Source:     c.Source,
+		})
+	}
+	return notes, nil
+}
+
+// CompareEmbeddingModels runs the same query against two model namespaces for A/B comparison
+func (a *App) CompareEmbeddingModels(query, modelA, modelB string, limit int) (map[string]interface{}, error) {
+	resultsA, err := a.SearchSimilarInNamespace(modelA, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", modelA, err)
+	}
+	resultsB, err := a.SearchSimilarInNamespace(modelB, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", modelB, err)
+	}
+	return map[string]interface{}{
+		"query":     query,
+		"model_a":   modelA,
+		"model_b":   modelB,
+		"results_a": resultsA,
+		"results_b": resultsB,
+	}, nil
+}
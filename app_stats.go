@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"notebit/pkg/database"
+	"notebit/pkg/indexing"
+)
+
+const (
+	// statisticsPeriod is how far back the created and modified heatmaps reach
+	statisticsPeriod = 365 * 24 * time.Hour
+	// readingWordsPerMinute is the reading speed reading times assume
+	readingWordsPerMinute = 200
+)
+
+// NoteStats are a note's counts for the editor status bar
+type NoteStats struct {
+	database.NoteStats
+	Path           string `json:"path"`
+	ReadingMinutes int    `json:"reading_minutes"` // Rounded up; 0 for an empty note
+	LastModified   int64  `json:"last_modified"`   // Unix seconds
+}
+
+// statistics caches the vault statistics until the index changes
+type statistics struct {
+	mu    sync.Mutex
+	repo  *database.Repository
+	rev   uint64
+	vault *database.VaultStatistics
+}
+
+// ============ STATISTICS API METHODS ============
+
+// GetVaultStatistics returns word and note counts, notes per folder, daily
+// created and modified series for the last year, tag frequency and
+// embedding coverage. The result is reused until the index changes.
+func (a *App) GetVaultStatistics() (*database.VaultStatistics, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	repo := a.dbm.Repository()
+	revision := repo.GetRevision()
+
+	a.stats.mu.Lock()
+	defer a.stats.mu.Unlock()
+	if a.stats.vault != nil && a.stats.repo == repo && a.stats.rev == revision {
+		return a.stats.vault, nil
+	}
+	vault, err := repo.VaultStatistics(time.Now().Add(-statisticsPeriod))
+	if err != nil {
+		return nil, err
+	}
+	a.stats.repo, a.stats.rev, a.stats.vault = repo, revision, vault
+	return vault, nil
+}
+
+// GetNoteStats returns the word, character, heading and link counts of the
+// note at path as of its last indexing, with its reading time. Notes not
+// indexed yet, or indexed before counts were kept, are counted from disk.
+func (a *App) GetNoteStats(path string) (*NoteStats, error) {
+	stats := &NoteStats{Path: path}
+	counted := false
+	if a.dbm.IsInitialized() {
+		file, err := a.dbm.Repository().GetFileByPath(path)
+		if err == nil && (file.Stats.Characters > 0 || file.FileSize == 0) {
+			stats.NoteStats, stats.LastModified = file.Stats, file.LastModified
+			counted = true
+		}
+	}
+	if !counted {
+		note, err := a.fm.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stats.NoteStats, stats.LastModified = indexing.CountNoteStats(note.Content), note.ModifiedTime.Unix()
+	}
+	stats.ReadingMinutes = (stats.Words + readingWordsPerMinute - 1) / readingWordsPerMinute
+	return stats, nil
+}
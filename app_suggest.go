@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+	"notebit/pkg/logger"
+	"notebit/pkg/suggest"
+)
+
+// maxSuggestions caps the suggestions returned for one keystroke
+const maxSuggestions = 50
+
+// LinkSuggestion is a note the editor can offer while a wiki link is typed
+type LinkSuggestion struct {
+	Path   string `json:"path"`
+	Title  string `json:"title"`
+	Target string `json:"target"` // Text to put between [[ and ]]
+	Match  string `json:"match"`  // Title, alias or path that matched
+	Score  int    `json:"score"`
+}
+
+// TagCompletion is a tag the editor can offer while a #tag is typed
+type TagCompletion struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"` // Notes using the tag
+	Score int    `json:"score"`
+}
+
+// suggestions caches the autocompletion indexes until the index changes
+type suggestions struct {
+	mu        sync.Mutex
+	linkRepo  *database.Repository
+	linkRev   uint64
+	links     *suggest.Index
+	notes     []LinkSuggestion
+	noteKeys  [][]string
+	modified  []int64 // Last modification of each note, Unix seconds
+	usedRepo  *database.Repository
+	used      map[string]time.Time // Last open or edit by path, from the history
+	tagRepo   *database.Repository
+	tagRev    uint64
+	tags      *suggest.Index
+	tagCounts []database.TagCount
+}
+
+// ============ AUTOCOMPLETE API METHODS ============
+
+// SuggestLinkTargets returns up to limit notes whose title, aliases or path
+// fuzzily match prefix, best first, for completing [[wiki links]]
+func (a *App) SuggestLinkTargets(prefix string, limit int) ([]LinkSuggestion, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	limit = suggestionLimit(limit)
+
+	a.completions.mu.Lock()
+	defer a.completions.mu.Unlock()
+	if err := a.loadLinkSuggestions(); err != nil {
+		return nil, err
+	}
+	return a.linkResults(a.completions.links.Search(prefix, limit)), nil
+}
+
+// SuggestTagCompletions returns up to limit tags that fuzzily match prefix,
+// best first and more used tags before less used ones, for completing #tags.
+// SuggestTags is the LLM's pick of tags for a whole note.
+func (a *App) SuggestTagCompletions(prefix string, limit int) ([]TagCompletion, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	limit = suggestionLimit(limit)
+
+	a.completions.mu.Lock()
+	defer a.completions.mu.Unlock()
+	if err := a.loadTagSuggestions(); err != nil {
+		return nil, err
+	}
+	results := []TagCompletion{}
+	for _, m := range a.completions.tags.Search(strings.TrimPrefix(prefix, "#"), limit) {
+		tag := a.completions.tagCounts[m.Item]
+		results = append(results, TagCompletion{Name: tag.Name, Count: tag.Count, Score: m.Score})
+	}
+	return results, nil
+}
+
+// QuickSwitch returns up to limit notes for the quick switcher, fuzzily
+// matching query against their titles, aliases and paths. Recently opened or
+// edited notes rank higher, so an empty query lists them newest first.
+func (a *App) QuickSwitch(query string, limit int) ([]LinkSuggestion, error) {
+	if !a.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	limit = suggestionLimit(limit)
+
+	a.completions.mu.Lock()
+	defer a.completions.mu.Unlock()
+	if err := a.loadLinkSuggestions(); err != nil {
+		return nil, err
+	}
+	used := a.loadNoteUse()
+	now := time.Now()
+	boost := func(item int) int {
+		last := time.Unix(a.completions.modified[item], 0)
+		if t := used[a.completions.notes[item].Path]; t.After(last) {
+			last = t
+		}
+		return recencyBoost(now, last)
+	}
+	return a.linkResults(a.completions.links.SearchBoosted(query, limit, boost)), nil
+}
+
+// loadNoteUse returns when each note was last opened or edited, reading the
+// history once per vault. The caller holds a.completions.mu.
+func (a *App) loadNoteUse() map[string]time.Time {
+	repo := a.dbm.Repository()
+	if a.completions.used != nil && a.completions.usedRepo == repo {
+		return a.completions.used
+	}
+	used := make(map[string]time.Time)
+	recent, err := repo.RecentHistory(0)
+	if err != nil {
+		logger.Warn("Failed to read note history: %v", err)
+	}
+	for _, f := range recent {
+		used[f.Path] = f.LastUsed
+	}
+	a.completions.used, a.completions.usedRepo = used, repo
+	return used
+}
+
+// markNoteUsed updates the quick switcher's view of the history after a note
+// was opened or edited
+func (a *App) markNoteUsed(path string, at time.Time) {
+	a.completions.mu.Lock()
+	defer a.completions.mu.Unlock()
+	if a.completions.used != nil {
+		a.completions.used[path] = at
+	}
+}
+
+// forgetNoteUse makes the quick switcher read the history again, after
+// notes were renamed or deleted
+func (a *App) forgetNoteUse() {
+	a.completions.mu.Lock()
+	defer a.completions.mu.Unlock()
+	a.completions.used = nil
+}
+
+// linkResults returns the notes of matches in the note index. The caller
+// holds a.completions.mu.
+func (a *App) linkResults(matches []suggest.Match) []LinkSuggestion {
+	results := make([]LinkSuggestion, 0, len(matches))
+	for _, m := range matches {
+		s := a.completions.notes[m.Item]
+		s.Match = a.completions.noteKeys[m.Item][m.Key]
+		s.Score = m.Score
+		results = append(results, s)
+	}
+	return results
+}
+
+// maxRecencyBoost is what a note used just now gains in QuickSwitch, about
+// one match quality band; the boost halves every recencyHalfLife
+const (
+	maxRecencyBoost = 1000
+	recencyHalfLife = 3 * 24 * time.Hour
+)
+
+// recencyBoost returns the QuickSwitch boost of a note last used at used
+func recencyBoost(now, used time.Time) int {
+	if used.IsZero() || used.Unix() <= 0 {
+		return 0
+	}
+	age := max(now.Sub(used), 0)
+	return int(maxRecencyBoost * math.Exp2(-float64(age)/float64(recencyHalfLife)))
+}
+
+func suggestionLimit(limit int) int {
+	if limit <= 0 || limit > maxSuggestions {
+		return maxSuggestions
+	}
+	return limit
+}
+
+// loadLinkSuggestions rebuilds the note index if the index changed since it
+// was built. The caller holds a.completions.mu.
+func (a *App) loadLinkSuggestions() error {
+	repo := a.dbm.Repository()
+	revision := repo.GetRevision()
+	if a.completions.links != nil && a.completions.linkRepo == repo && a.completions.linkRev == revision {
+		return nil
+	}
+	heads, err := repo.ListNoteHeads()
+	if err != nil {
+		return err
+	}
+
+	// Link by file name unless another note shares it
+	names := make(map[string]int, len(heads))
+	for _, h := range heads {
+		names[strings.ToLower(noteName(h.Path))]++
+	}
+	notes := make([]LinkSuggestion, len(heads))
+	keys := make([][]string, len(heads))
+	modified := make([]int64, len(heads))
+	items := make([]suggest.Item, len(heads))
+	for i, h := range heads {
+		target := noteName(h.Path)
+		if names[strings.ToLower(target)] > 1 {
+			target = strings.TrimSuffix(h.Path, path.Ext(h.Path))
+		}
+		notes[i] = LinkSuggestion{Path: h.Path, Title: h.Title, Target: target}
+		// A note is matched by its title, aliases and path
+		keys[i] = append(append([]string{h.Title}, files.NoteAliases(h.Content)...), h.Path)
+		modified[i] = h.LastModified
+		items[i] = suggest.Item{Keys: keys[i]}
+	}
+	a.completions.links, a.completions.notes, a.completions.noteKeys = suggest.NewIndex(items), notes, keys
+	a.completions.modified = modified
+	a.completions.linkRepo, a.completions.linkRev = repo, revision
+	return nil
+}
+
+// loadTagSuggestions rebuilds the tag index if the index changed since it
+// was built. The caller holds a.completions.mu.
+func (a *App) loadTagSuggestions() error {
+	repo := a.dbm.Repository()
+	revision := repo.GetRevision()
+	if a.completions.tags != nil && a.completions.tagRepo == repo && a.completions.tagRev == revision {
+		return nil
+	}
+	counts, err := repo.TagUsage()
+	if err != nil {
+		return err
+	}
+	items := make([]suggest.Item, len(counts))
+	for i, tag := range counts {
+		items[i] = suggest.Item{Keys: []string{tag.Name}, Weight: tag.Count}
+	}
+	a.completions.tags, a.completions.tagCounts = suggest.NewIndex(items), counts
+	a.completions.tagRepo, a.completions.tagRev = repo, revision
+	return nil
+}
+
+// noteName returns the file name of a note without its extension, the
+// shortest wiki link target for it
+func noteName(notePath string) string {
+	return strings.TrimSuffix(path.Base(notePath), path.Ext(notePath))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+	vaultsync "notebit/pkg/sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ GIT SYNC METHODS ============
+
+// gitSync holds the git working copy of the open vault, nil for vaults that
+// are not repositories
+type gitSync struct {
+	mu         sync.Mutex
+	git        *vaultsync.Git
+	autoCommit *vaultsync.AutoCommitter
+}
+
+// GitStatus returns the branch, its distance from upstream, changed files
+// and unresolved conflicts of the vault
+func (a *App) GitStatus() (*vaultsync.GitStatus, error) {
+	git, err := a.vaultGit()
+	if err != nil {
+		return nil, err
+	}
+	return git.Status(context.Background())
+}
+
+// GitCommitAll commits every change in the vault and returns the commit
+// hash, or "" when there was nothing to commit
+func (a *App) GitCommitAll(message string) (string, error) {
+	git, err := a.vaultGit()
+	if err != nil {
+		return "", err
+	}
+	hash, err := git.CommitAll(context.Background(), message)
+	if err != nil {
+		a.emitGitConflict(err)
+		return "", err
+	}
+	if hash != "" {
+		logger.Audit(a.ctx, auditGitCommit, hash, nil, map[string]interface{}{"message": message})
+	}
+	return hash, nil
+}
+
+// GitPull merges upstream changes and reindexes what they touched. Merge
+// conflicts are left in the notes and reported with a "git:conflict" event.
+func (a *App) GitPull() error {
+	git, err := a.vaultGit()
+	if err != nil {
+		return err
+	}
+	err = git.Pull(context.Background())
+	if err != nil {
+		a.emitGitConflict(err)
+		return err
+	}
+	logger.Audit(a.ctx, auditGitPull, git.Dir(), nil, nil)
+	a.reconcileIndexOnOpen()
+	return nil
+}
+
+// GitPush pushes the current branch
+func (a *App) GitPush() error {
+	git, err := a.vaultGit()
+	if err != nil {
+		return err
+	}
+	if err := git.Push(context.Background()); err != nil {
+		return err
+	}
+	logger.Audit(a.ctx, auditGitPush, git.Dir(), nil, nil)
+	return nil
+}
+
+// GetGitSyncConfig returns the git sync settings
+func (a *App) GetGitSyncConfig() config.GitSyncConfig {
+	return a.cfg.GetGitSyncConfig()
+}
+
+// SetGitSyncConfig sets the git sync settings and applies them to the open vault
+func (a *App) SetGitSyncConfig(autoCommit bool, debounceSeconds int, autoPush, pullOnOpen bool) error {
+	if debounceSeconds <= 0 {
+		return errors.New("debounce must be at least one second")
+	}
+	a.cfg.SetGitSyncConfig(config.GitSyncConfig{
+		AutoCommit:      autoCommit,
+		DebounceSeconds: debounceSeconds,
+		AutoPush:        autoPush,
+		PullOnOpen:      pullOnOpen,
+	})
+	if err := a.cfg.Save(); err != nil {
+		return err
+	}
+
+	a.gitSync.mu.Lock()
+	defer a.gitSync.mu.Unlock()
+	if a.gitSync.autoCommit != nil {
+		a.gitSync.autoCommit.Stop(true)
+		a.gitSync.autoCommit = nil
+	}
+	a.startAutoCommitLocked()
+	return nil
+}
+
+func (a *App) vaultGit() (*vaultsync.Git, error) {
+	a.gitSync.mu.Lock()
+	defer a.gitSync.mu.Unlock()
+	if a.gitSync.git == nil {
+		if a.fm.GetBasePath() == "" {
+			return nil, errors.New("no vault open")
+		}
+		return nil, vaultsync.ErrNotRepository
+	}
+	return a.gitSync.git, nil
+}
+
+// initializeGit attaches to the vault's git working copy, if any, starts
+// auto-commit and pulls when configured to
+func (a *App) initializeGit(basePath string) {
+	git, err := vaultsync.NewGit(basePath)
+	if err != nil {
+		if !errors.Is(err, vaultsync.ErrNotRepository) {
+			logger.Warn("Git sync unavailable: %v", err)
+		}
+		return
+	}
+
+	a.gitSync.mu.Lock()
+	a.gitSync.git = git
+	a.startAutoCommitLocked()
+	a.gitSync.mu.Unlock()
+
+	if a.cfg.GetGitSyncConfig().PullOnOpen {
+		go func() {
+			if err := git.Pull(context.Background()); err != nil {
+				logger.Warn("Pull on open failed: %v", err)
+				a.emitGitConflict(err)
+				return
+			}
+			a.reconcileIndexOnOpen()
+		}()
+	}
+}
+
+// startAutoCommitLocked creates the auto-committer if enabled. gitSync.mu
+// must be held.
+func (a *App) startAutoCommitLocked() {
+	gitCfg := a.cfg.GetGitSyncConfig()
+	if a.gitSync.git == nil || !gitCfg.AutoCommit {
+		return
+	}
+	delay := time.Duration(gitCfg.DebounceSeconds) * time.Second
+	a.gitSync.autoCommit = vaultsync.NewAutoCommitter(a.gitSync.git, delay, gitCfg.AutoPush, a.onAutoCommit)
+}
+
+func (a *App) onAutoCommit(hash string, err error) {
+	if err != nil {
+		logger.Warn("Auto-commit failed: %v", err)
+		a.emitGitConflict(err)
+		return
+	}
+	if hash == "" {
+		return
+	}
+	logger.Info("Auto-committed vault changes: %s", hash)
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "git:autocommit", hash)
+	}
+}
+
+// emitGitConflict tells the frontend which files need resolving when err is
+// a *vaultsync.ConflictError
+func (a *App) emitGitConflict(err error) {
+	var conflict *vaultsync.ConflictError
+	if a.ctx != nil && errors.As(err, &conflict) {
+		runtime.EventsEmit(a.ctx, "git:conflict", conflict.Files)
+	}
+}
+
+// scheduleGitCommit restarts the auto-commit delay after a change to the vault
+func (a *App) scheduleGitCommit() {
+	a.gitSync.mu.Lock()
+	defer a.gitSync.mu.Unlock()
+	if a.gitSync.autoCommit != nil {
+		a.gitSync.autoCommit.Touch()
+	}
+}
+
+// stopGit detaches from the vault's working copy, committing pending
+// changes first when flush is set
+func (a *App) stopGit(flush bool) {
+	a.gitSync.mu.Lock()
+	autoCommit := a.gitSync.autoCommit
+	a.gitSync.git, a.gitSync.autoCommit = nil, nil
+	a.gitSync.mu.Unlock()
+	if autoCommit != nil {
+		autoCommit.Stop(flush)
+	}
+}
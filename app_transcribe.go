@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/files"
+	"notebit/pkg/jobs"
+	"notebit/pkg/journal"
+	"notebit/pkg/logger"
+)
+
+// transcriptSuffix ends the name of a transcript note, so a transcript never
+// takes the name of a note the user wrote
+const transcriptSuffix = ".transcript.md"
+
+// transcriptions tracks the transcription job running for each audio file,
+// so watcher events during a long copy do not start duplicates
+type transcriptions struct {
+	mu      sync.Mutex
+	running map[string]string // Job ID by audio path
+}
+
+// ============ AUDIO TRANSCRIPTION API METHODS ============
+
+// GetTranscriptionConfig returns the audio transcription settings
+func (a *App) GetTranscriptionConfig() (config.TranscriptionConfig, error) {
+	return a.cfg.GetTranscriptionConfig(), nil
+}
+
+// SetTranscriptionConfig sets the audio transcription settings. They take
+// effect for the next audio file.
+func (a *App) SetTranscriptionConfig(cfg config.TranscriptionConfig) error {
+	switch cfg.Provider {
+	case config.TranscriptionWhisperCpp, config.TranscriptionOpenAI:
+	default:
+		return fmt.Errorf("unknown transcription provider: %s", cfg.Provider)
+	}
+	cfg.WhisperBinary = strings.TrimSpace(cfg.WhisperBinary)
+	cfg.WhisperModel = strings.TrimSpace(cfg.WhisperModel)
+	cfg.Language = strings.ToLower(strings.TrimSpace(cfg.Language))
+	a.cfg.SetTranscriptionConfig(cfg)
+	return a.cfg.Save()
+}
+
+// TranscribeAudio transcribes an audio file of the vault in the background,
+// writing the transcript to a note next to it and replacing an earlier
+// transcript of the same file. It returns the job ID, that of the running job
+// if the file is already being transcribed; the job result is the note path.
+func (a *App) TranscribeAudio(audioPath string) (string, error) {
+	if !files.IsAudioFile(audioPath) {
+		return "", fmt.Errorf("not an audio file: %s", audioPath)
+	}
+	if !a.fm.FileExists(audioPath) {
+		return "", fmt.Errorf("audio file not found: %s", audioPath)
+	}
+	return a.startTranscription(audioPath, true), nil
+}
+
+// onAudioFile is called by the watcher for new or changed audio files and
+// transcribes those without a transcript when transcription is enabled
+func (a *App) onAudioFile(audioPath string) {
+	if !a.cfg.GetTranscriptionConfig().Enabled || a.fm.FileExists(transcriptPath(audioPath)) {
+		return
+	}
+	a.startTranscription(audioPath, false)
+}
+
+func (a *App) startTranscription(audioPath string, replace bool) string {
+	a.transcriptions.mu.Lock()
+	defer a.transcriptions.mu.Unlock()
+	if id, ok := a.transcriptions.running[audioPath]; ok {
+		return id
+	}
+	if a.transcriptions.running == nil {
+		a.transcriptions.running = make(map[string]string)
+	}
+	id := a.jobs.Start(jobKindTranscribe, "Transcribe "+path.Base(audioPath), func(ctx context.Context, report jobs.Reporter) (any, error) {
+		defer func() {
+			a.transcriptions.mu.Lock()
+			delete(a.transcriptions.running, audioPath)
+			a.transcriptions.mu.Unlock()
+		}()
+		return a.transcribeAudio(ctx, audioPath, replace)
+	})
+	a.transcriptions.running[audioPath] = id
+	return id
+}
+
+// transcribeAudio transcribes audioPath into its transcript note and queues
+// the note for indexing
+func (a *App) transcribeAudio(ctx context.Context, audioPath string, replace bool) (string, error) {
+	notePath := transcriptPath(audioPath)
+	if existing, err := a.fm.ReadFile(notePath); err == nil {
+		if !isTranscriptOf(existing.Content, audioPath) {
+			return "", fmt.Errorf("%s exists and is not a transcript of %s", notePath, audioPath)
+		}
+		if !replace {
+			return notePath, nil
+		}
+	}
+	fullPath, err := a.fm.AbsPath(audioPath)
+	if err != nil {
+		return "", err
+	}
+	transcriber, err := ai.NewTranscriber(a.cfg.GetTranscriptionConfig(), a.cfg.GetOpenAIConfig())
+	if err != nil {
+		return "", err
+	}
+
+	timer := logger.StartTimer()
+	text, err := transcriber.Transcribe(ctx, fullPath)
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{
+			"path":     audioPath,
+			"provider": transcriber.Name(),
+			"error":    err.Error(),
+		}, "Transcription failed")
+		return "", err
+	}
+	content := transcriptNote(audioPath, text, time.Now())
+
+	if a.fm.FileExists(notePath) {
+		entry := a.snapshotForWrite(notePath)
+		if err := a.fm.SaveFile(notePath, content); err != nil {
+			return "", err
+		}
+		a.recordOperation(entry)
+	} else {
+		if err := a.fm.CreateFile(notePath, content); err != nil {
+			return "", err
+		}
+		a.recordOperation(journal.Entry{Op: journal.OpCreate, Path: notePath})
+		logger.Audit(a.ctx, auditNoteCreate, notePath, nil, map[string]interface{}{"size": len(content), "audio": audioPath})
+	}
+	a.scheduleGitCommit()
+	if a.dbm.IsInitialized() {
+		go a.indexFileContent(notePath, content)
+	}
+
+	logger.InfoWithDuration(a.ctx, timer(), "Transcribed %s to %s with %s", audioPath, notePath, transcriber.Name())
+	return notePath, nil
+}
+
+// transcriptPath is the note holding the transcript of an audio file: the
+// audio path with transcriptSuffix, e.g. "memo.m4a.transcript.md", so
+// memo.m4a and memo.mp3 get a transcript each
+func transcriptPath(audioPath string) string {
+	return audioPath + transcriptSuffix
+}
+
+// isTranscriptOf reports whether the note content is a transcript of the
+// audio file, which may then be replaced: its front matter names the file
+func isTranscriptOf(content, audioPath string) bool {
+	return files.FrontmatterField(content, "audio") == path.Base(audioPath)
+}
+
+// transcriptNote renders a transcript note that embeds the audio it came from
+func transcriptNote(audioPath, text string, now time.Time) string {
+	name := path.Base(audioPath)
+	title := strings.TrimSuffix(name, path.Ext(name))
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("audio: " + strconv.Quote(name) + "\n")
+	b.WriteString("transcribed: " + now.Format(time.RFC3339) + "\n")
+	b.WriteString("tags: [transcript]\n")
+	b.WriteString("---\n\n")
+	b.WriteString("# " + title + "\n\n")
+	b.WriteString("![[" + name + "]]\n\n")
+	if text = strings.TrimSpace(text); text == "" {
+		text = "_No speech recognized._"
+	}
+	b.WriteString(text + "\n")
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscriptPath(t *testing.T) {
+	tests := map[string]string{
+		"memo.m4a":          "memo.m4a.transcript.md",
+		"Voice/memo.mp3":    "Voice/memo.mp3.transcript.md",
+		"Voice/2024.01.wav": "Voice/2024.01.wav.transcript.md",
+	}
+	for audio, want := range tests {
+		if got := transcriptPath(audio); got != want {
+			t.Errorf("transcriptPath(%q) = %q, want %q", audio, got, want)
+		}
+	}
+	if transcriptPath("memo.m4a") == transcriptPath("memo.mp3") {
+		t.Error("two recordings with the same name share a transcript")
+	}
+}
+
+func TestTranscriptNote(t *testing.T) {
+	now := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	note := transcriptNote("Voice/Stand-up.m4a", "  Hello team.  ", now)
+	for _, want := range []string{
+		"audio: \"Stand-up.m4a\"\n",
+		"transcribed: 2025-03-04T10:00:00Z\n",
+		"# Stand-up\n",
+		"![[Stand-up.m4a]]\n",
+		"Hello team.\n",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("transcript note lacks %q:\n%s", want, note)
+		}
+	}
+	if empty := transcriptNote("a.mp3", " ", now); !strings.Contains(empty, "_No speech recognized._") {
+		t.Errorf("empty transcript note:\n%s", empty)
+	}
+}
+
+func TestIsTranscriptOf(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		content string
+		audio   string
+		want    bool
+	}{
+		{"own transcript", transcriptNote("Voice/memo.m4a", "hi", now), "Voice/memo.m4a", true},
+		{"other recording", transcriptNote("Voice/memo.mp3", "hi", now), "Voice/memo.m4a", false},
+		{"user note", "# memo\n\nMy own notes about the memo.\n", "memo.m4a", false},
+		{"other front matter", "---\ntags: [memo]\n---\n\ntext\n", "memo.m4a", false},
+		{"unquoted", "---\naudio: memo.m4a\n---\n", "memo.m4a", true},
+	}
+	for _, tt := range tests {
+		if got := isTranscriptOf(tt.content, tt.audio); got != tt.want {
+			t.Errorf("%s: isTranscriptOf = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
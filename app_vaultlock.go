@@ -0,0 +1,641 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/backup"
+	"notebit/pkg/database"
+	"notebit/pkg/logger"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ VAULT LOCK METHODS ============
+
+// lockedDBName is the encrypted copy of the vault database. Its presence
+// marks a vault as locked with a passphrase.
+const lockedDBName = "notebit.sqlite.locked"
+
+// lockedPrivateName is the encrypted archive of privateDirs
+const lockedPrivateName = "notebit.private.locked"
+
+// privateDirs are the directories of the vault's data folder that hold chat
+// history or unsaved note text. A locked vault keeps them with its database:
+// outside the vault while unlocked, sealed in lockedPrivateName while locked.
+var privateDirs = []string{"chat_backups", "edit_journal"}
+
+// While a locked vault is unlocked, its database and privateDirs are
+// decrypted into a private directory outside the vault (see unlockedDBPath),
+// so sync tools and copies of the vault folder never see them in plain text.
+// Locking or closing the vault seals them again and removes that directory.
+// Limits: the decrypted copies exist on this machine while the vault is
+// unlocked, and if the app crashes they stay there until the vault is next
+// unlocked, which reseals them.
+
+// vaultLock holds the passphrase of the open vault while it is unlocked, so
+// the database can be sealed again on lock or close without asking
+type vaultLock struct {
+	mu         sync.Mutex
+	passphrase string
+	timer      *time.Timer
+}
+
+// VaultLockStatus describes the lock of the open vault
+type VaultLockStatus struct {
+	Enabled         bool `json:"enabled"`
+	Locked          bool `json:"locked"`
+	AutoLockMinutes int  `json:"auto_lock_minutes"`
+}
+
+// GetVaultLockStatus reports whether the open vault has a passphrase and
+// whether it is currently locked
+func (a *App) GetVaultLockStatus() VaultLockStatus {
+	basePath := a.fm.GetBasePath()
+	status := VaultLockStatus{AutoLockMinutes: a.cfg.GetVaultLockConfig().AutoLockMinutes}
+	if basePath == "" {
+		return status
+	}
+	status.Enabled = fileExists(lockedDBPath(basePath))
+	status.Locked = a.vaultLocked(basePath)
+	return status
+}
+
+// EnableVaultLock protects the database of the open vault, which holds the
+// index and chat history, with passphrase, together with the chat backups
+// and the edit journal of unsaved note text. From then on the vault folder
+// only holds them encrypted; while unlocked, the decrypted copies live
+// outside the vault and are removed on lock or close. Notes, note attachments
+// and chat exports stay plain files in the vault.
+func (a *App) EnableVaultLock(passphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return errors.New("no vault open")
+	}
+	if !a.dbm.IsInitialized() {
+		return errors.New("database not initialized")
+	}
+	if fileExists(lockedDBPath(basePath)) {
+		return errors.New("vault lock is already enabled")
+	}
+	if len(passphrase) < 8 {
+		return errors.New("passphrase must be at least 8 characters")
+	}
+
+	// Seal a snapshot first, so a failure leaves the vault as it was
+	snapshot := unlockedDBPath(basePath) + ".snapshot"
+	if err := os.MkdirAll(filepath.Dir(snapshot), 0700); err != nil {
+		return err
+	}
+	defer os.Remove(snapshot)
+	if err := a.dbm.Snapshot(snapshot); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+	if err := backup.SealFile(snapshot, lockedDBPath(basePath), passphrase); err != nil {
+		return fmt.Errorf("encrypt database: %w", err)
+	}
+
+	// Reopen the vault so its database moves out of the vault folder
+	a.lock.mu.Lock()
+	a.lock.passphrase = passphrase
+	a.lock.mu.Unlock()
+	a.closeVault() // seals the database and removes the plain copy
+	unlockedDir := filepath.Dir(unlockedDBPath(basePath))
+	for _, name := range privateDirs {
+		if err := moveDir(filepath.Join(basePath, "data", name), filepath.Join(unlockedDir, name)); err != nil {
+			logger.Warn("Failed to move %s out of the vault: %v", name, err)
+		}
+	}
+	if err := a.unlockVault(basePath, passphrase); err != nil {
+		return err
+	}
+	logger.Audit(a.ctx, auditVaultLockEnable, basePath, nil, nil)
+	return nil
+}
+
+// DisableVaultLock removes the passphrase of the open, unlocked vault
+func (a *App) DisableVaultLock(passphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return errors.New("no vault open")
+	}
+	if a.vaultLocked(basePath) {
+		return errors.New("unlock the vault first")
+	}
+	sealed := lockedDBPath(basePath)
+	if err := checkVaultPassphrase(sealed, passphrase); err != nil {
+		return err
+	}
+
+	// Move the database back into the vault, then drop the sealed copy
+	a.lock.mu.Lock()
+	a.lock.passphrase = ""
+	a.lock.mu.Unlock()
+	a.closeVault()
+	unlocked := unlockedDBPath(basePath)
+	if err := moveDatabase(unlocked, plainDBPath(basePath)); err != nil {
+		return fmt.Errorf("move database into the vault: %w", err)
+	}
+	for _, name := range privateDirs {
+		if err := moveDir(filepath.Join(filepath.Dir(unlocked), name), filepath.Join(basePath, "data", name)); err != nil {
+			return fmt.Errorf("move %s into the vault: %w", name, err)
+		}
+	}
+	_ = os.RemoveAll(filepath.Dir(unlocked))
+	if err := os.Remove(lockedPrivatePath(basePath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(sealed); err != nil {
+		return err
+	}
+	a.dbm = database.NewManager()
+	if err := a.initializeServices(basePath); err != nil {
+		logger.Warn("Service initialization issue: %v", err)
+	}
+	if err := a.startWatcher(); err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to start watcher: %v", err)
+	}
+	logger.Audit(a.ctx, auditVaultLockDisable, basePath, nil, nil)
+	return nil
+}
+
+// LockVault stops the services of the open vault and encrypts its database.
+// The notes stay listed; search, chat and indexing return once unlocked.
+func (a *App) LockVault() error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return errors.New("no vault open")
+	}
+	if !fileExists(lockedDBPath(basePath)) {
+		return errors.New("vault lock is not enabled")
+	}
+	if a.vaultLocked(basePath) {
+		return nil
+	}
+	a.closeVault() // seals the database
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "vault:locked", basePath)
+	}
+	logger.Info("Vault locked: %s", basePath)
+	return nil
+}
+
+// UnlockVault decrypts the database of the open vault and starts its services
+func (a *App) UnlockVault(passphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return errors.New("no vault open")
+	}
+	if !a.vaultLocked(basePath) {
+		return nil
+	}
+	if err := a.unlockVault(basePath, passphrase); err != nil {
+		if errors.Is(err, errWrongPassphrase) {
+			logger.Audit(a.ctx, auditVaultUnlock, basePath, nil, map[string]interface{}{"success": false})
+		}
+		return err
+	}
+	logger.Audit(a.ctx, auditVaultUnlock, basePath, nil, map[string]interface{}{"success": true})
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "vault:unlocked", basePath)
+	}
+	return nil
+}
+
+// unlockVault decrypts the database of the vault at basePath outside the
+// vault and starts its services. Callers hold vaultMu.
+func (a *App) unlockVault(basePath, passphrase string) error {
+	dbPath, recovered, err := unlockDatabase(basePath, passphrase)
+	if err != nil {
+		return err
+	}
+
+	a.lock.mu.Lock()
+	a.lock.passphrase = passphrase
+	a.lock.mu.Unlock()
+
+	a.dbm = database.NewManager()
+	if err := a.initializeServices(basePath); err != nil {
+		logger.Warn("Service initialization issue: %v", err)
+	}
+	if recovered && a.dbm.IsInitialized() {
+		// Bring the sealed copy up to date with what the crash left behind
+		snapshot := dbPath + ".snapshot"
+		if err := a.dbm.Snapshot(snapshot); err == nil {
+			if err := backup.SealFile(snapshot, lockedDBPath(basePath), passphrase); err != nil {
+				logger.Warn("Failed to reseal recovered database: %v", err)
+			}
+		}
+		os.Remove(snapshot)
+	}
+	if err := a.startWatcher(); err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to start watcher: %v", err)
+	}
+	a.armAutoLock()
+	return nil
+}
+
+// SetVaultAutoLock sets how many idle minutes lock an unlocked vault (0 = never)
+func (a *App) SetVaultAutoLock(minutes int) error {
+	if minutes < 0 {
+		return fmt.Errorf("invalid auto-lock timeout: %d minutes", minutes)
+	}
+	lockCfg := a.cfg.GetVaultLockConfig()
+	lockCfg.AutoLockMinutes = minutes
+	a.cfg.SetVaultLockConfig(lockCfg)
+	a.armAutoLock()
+	return a.cfg.Save()
+}
+
+// vaultLocked reports whether the vault at basePath has a passphrase that
+// has not been entered this session
+func (a *App) vaultLocked(basePath string) bool {
+	if !fileExists(lockedDBPath(basePath)) {
+		return false
+	}
+	a.lock.mu.Lock()
+	defer a.lock.mu.Unlock()
+	return a.lock.passphrase == ""
+}
+
+// sealVault encrypts the closed database of the vault at basePath and
+// removes the plain files, if the vault was unlocked this session
+func (a *App) sealVault(basePath string) {
+	a.lock.mu.Lock()
+	passphrase := a.lock.passphrase
+	a.lock.passphrase = ""
+	if a.lock.timer != nil {
+		a.lock.timer.Stop()
+		a.lock.timer = nil
+	}
+	a.lock.mu.Unlock()
+	if passphrase == "" || basePath == "" {
+		return
+	}
+	dbPath := a.dbm.GetDBPath()
+	if dbPath == "" {
+		dbPath = unlockedDBPath(basePath)
+	}
+	if err := sealDatabase(basePath, dbPath, passphrase); err != nil {
+		// Keep the plain database rather than lose data; the next unlock uses it
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "Failed to encrypt vault database")
+	}
+}
+
+// unlockDatabase decrypts the sealed database and private directories of the
+// vault at basePath next to unlockedDBPath and returns that path. Copies left
+// there by a crash are newer than the sealed ones and are used instead;
+// recovered reports that. A plain database in the vault folder is not used:
+// anyone who can write to the folder could have put it there.
+func unlockDatabase(basePath, passphrase string) (dbPath string, recovered bool, err error) {
+	sealed := lockedDBPath(basePath)
+	if err := checkVaultPassphrase(sealed, passphrase); err != nil {
+		return "", false, err
+	}
+	dbPath = unlockedDBPath(basePath)
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", false, err
+	}
+	if plain := plainDBPath(basePath); fileExists(plain) {
+		logger.Warn("Ignoring plain database %s in a locked vault; the sealed copy is used", plain)
+	}
+	if !fileExists(dbPath) {
+		if err := backup.UnsealFile(sealed, dbPath, passphrase); err != nil {
+			return "", false, fmt.Errorf("decrypt database: %w", err)
+		}
+	} else {
+		recovered = true
+	}
+	if err := unsealPrivateDirs(basePath, dir, passphrase); err != nil {
+		return "", false, fmt.Errorf("decrypt private data: %w", err)
+	}
+	return dbPath, recovered, nil
+}
+
+// sealDatabase encrypts the closed database at dbPath into the sealed copy
+// of the vault at basePath and removes the plain files. When dbPath is the
+// unlocked copy, the private directories next to it are sealed too.
+func sealDatabase(basePath, dbPath, passphrase string) error {
+	unlocked := dbPath == unlockedDBPath(basePath)
+	if unlocked {
+		if err := sealPrivateDirs(basePath, filepath.Dir(dbPath), passphrase); err != nil {
+			return err
+		}
+	}
+	if err := backup.SealFile(dbPath, lockedDBPath(basePath), passphrase); err != nil {
+		return err
+	}
+	for _, p := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to remove %s: %v", p, err)
+		}
+	}
+	if unlocked {
+		return os.RemoveAll(filepath.Dir(dbPath))
+	}
+	return nil
+}
+
+// sealPrivateDirs archives the privateDirs in dir into the sealed private
+// archive of the vault at basePath
+func sealPrivateDirs(basePath, dir, passphrase string) error {
+	archive := filepath.Join(dir, "private.tar")
+	defer os.Remove(archive)
+	if err := tarDirs(archive, dir, privateDirs); err != nil {
+		return err
+	}
+	return backup.SealFile(archive, lockedPrivatePath(basePath), passphrase)
+}
+
+// unsealPrivateDirs restores the privateDirs of the vault at basePath into
+// dir, unless a crash left them there already
+func unsealPrivateDirs(basePath, dir, passphrase string) error {
+	sealed := lockedPrivatePath(basePath)
+	if !fileExists(sealed) {
+		return nil
+	}
+	for _, name := range privateDirs {
+		if fileExists(filepath.Join(dir, name)) {
+			return nil
+		}
+	}
+	archive := filepath.Join(dir, "private.tar")
+	defer os.Remove(archive)
+	if err := backup.UnsealFile(sealed, archive, passphrase); err != nil {
+		return err
+	}
+	return untarDirs(archive, dir, privateDirs)
+}
+
+// tarDirs writes the regular files of the named subdirectories of dir to a
+// tar archive at dst
+func tarDirs(dst, dir string, names []string) error {
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(out)
+	for _, name := range names {
+		entries, err := os.ReadDir(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if err := tarFile(tw, filepath.Join(dir, name, e.Name()), name+"/"+e.Name()); err != nil {
+				out.Close()
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func tarFile(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// untarDirs extracts an archive written by tarDirs into dir, skipping
+// entries outside the named subdirectories
+func untarDirs(src, dir string, names []string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	tr := tar.NewReader(in)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sub, file, ok := strings.Cut(hdr.Name, "/")
+		if !ok || !allowed[sub] || hdr.Typeflag != tar.TypeReg || file == "" || file == "." || file == ".." || strings.ContainsAny(file, `/\`) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(filepath.Join(dir, sub, file), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// moveDir moves the directory src to dst, merging into dst if it exists and
+// copying files when they are on different file systems. A missing src is
+// not an error.
+func moveDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !fileExists(dst) {
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err == nil {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		from, to := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())
+		if err := os.Rename(from, to); err == nil {
+			continue
+		}
+		if err := copyFile(from, to); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(src)
+}
+
+// moveDatabase moves a SQLite database and its WAL files from src to dst,
+// copying when they are on different file systems
+func moveDatabase(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(dst + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !fileExists(src + suffix) {
+			continue
+		}
+		if err := os.Rename(src+suffix, dst+suffix); err == nil {
+			continue
+		}
+		if err := copyFile(src+suffix, dst+suffix); err != nil {
+			return err
+		}
+		if err := os.Remove(src + suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// armAutoLock (re)starts the idle timer that locks the unlocked vault
+func (a *App) armAutoLock() {
+	minutes := a.cfg.GetVaultLockConfig().AutoLockMinutes
+	a.lock.mu.Lock()
+	defer a.lock.mu.Unlock()
+	if a.lock.timer != nil {
+		a.lock.timer.Stop()
+		a.lock.timer = nil
+	}
+	if a.lock.passphrase == "" || minutes <= 0 {
+		return
+	}
+	a.lock.timer = time.AfterFunc(time.Duration(minutes)*time.Minute, func() {
+		if err := a.LockVault(); err != nil {
+			logger.Warn("Auto-lock failed: %v", err)
+		}
+	})
+}
+
+// touchAutoLock postpones the auto-lock after user activity
+func (a *App) touchAutoLock() {
+	minutes := a.cfg.GetVaultLockConfig().AutoLockMinutes
+	a.lock.mu.Lock()
+	defer a.lock.mu.Unlock()
+	if a.lock.timer != nil && minutes > 0 {
+		a.lock.timer.Reset(time.Duration(minutes) * time.Minute)
+	}
+}
+
+var errWrongPassphrase = errors.New("wrong passphrase")
+
+func checkVaultPassphrase(sealed, passphrase string) error {
+	err := backup.CheckSealPassphrase(sealed, passphrase)
+	if errors.Is(err, backup.ErrWrongPassphrase) {
+		return errWrongPassphrase
+	}
+	return err
+}
+
+func lockedDBPath(basePath string) string {
+	return filepath.Join(basePath, "data", lockedDBName)
+}
+
+func lockedPrivatePath(basePath string) string {
+	return filepath.Join(basePath, "data", lockedPrivateName)
+}
+
+func plainDBPath(basePath string) string {
+	return filepath.Join(basePath, "data", "notebit.sqlite")
+}
+
+// privateDataPath is where the data directory rel of the vault at basePath,
+// e.g. "data/chat_backups", lives: in the vault, or for a locked vault with
+// its unlocked database outside it
+func privateDataPath(basePath, rel string) string {
+	if !fileExists(lockedDBPath(basePath)) {
+		return filepath.Join(basePath, filepath.FromSlash(rel))
+	}
+	return filepath.Join(filepath.Dir(unlockedDBPath(basePath)), path.Base(rel))
+}
+
+// unlockedDBPath is where the database of a locked vault lives while it is
+// unlocked: a per-vault directory in the user's cache, outside the vault
+func unlockedDBPath(basePath string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(basePath))
+	return filepath.Join(dir, "notebit", "unlocked", hex.EncodeToString(sum[:8]), "notebit.sqlite")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
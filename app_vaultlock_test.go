@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notebit/pkg/config"
+	"notebit/pkg/files"
+)
+
+// newLockedVault creates a vault whose database holds content and is sealed
+// with passphrase. The user cache, which holds unlocked databases, is
+// redirected to a temp dir.
+func newLockedVault(t *testing.T, content, passphrase string) string {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	vault := t.TempDir()
+	plain := plainDBPath(vault)
+	if err := os.MkdirAll(filepath.Dir(plain), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(plain, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sealDatabase(vault, plain, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(plain) {
+		t.Fatal("sealing left the plain database in the vault")
+	}
+	return vault
+}
+
+func TestLockAndUnlockDatabase(t *testing.T) {
+	vault := newLockedVault(t, "index v1", "correct horse")
+
+	if _, _, err := unlockDatabase(vault, "wrong passphrase"); !errors.Is(err, errWrongPassphrase) {
+		t.Fatalf("unlock with a wrong passphrase = %v, want errWrongPassphrase", err)
+	}
+	if fileExists(unlockedDBPath(vault)) {
+		t.Fatal("a wrong passphrase decrypted the database")
+	}
+
+	dbPath, recovered, err := unlockDatabase(vault, "correct horse")
+	if err != nil || recovered {
+		t.Fatalf("unlock = %q, %t, %v", dbPath, recovered, err)
+	}
+	if rel, err := filepath.Rel(vault, dbPath); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Fatalf("unlocked database %s is inside the vault", dbPath)
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "index v1" {
+		t.Fatalf("unlocked database = %q", data)
+	}
+	if fileExists(plainDBPath(vault)) {
+		t.Fatal("unlocking wrote a plain database into the vault")
+	}
+
+	// Changes made while unlocked are sealed on lock, and the plain copy goes
+	if err := os.WriteFile(dbPath, []byte("index v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sealDatabase(vault, dbPath, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(dbPath)); !os.IsNotExist(err) {
+		t.Fatalf("locking left the unlocked directory behind: %v", err)
+	}
+	if data, _ := os.ReadFile(lockedDBPath(vault)); strings.Contains(string(data), "index v2") {
+		t.Fatal("sealed database holds plain text")
+	}
+
+	dbPath, _, err = unlockDatabase(vault, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "index v2" {
+		t.Fatalf("database after relock = %q, want the changes kept", data)
+	}
+}
+
+func TestUnlockDatabaseIgnoresPlainCopyInVault(t *testing.T) {
+	vault := newLockedVault(t, "sealed", "correct horse")
+	// Anyone who can write to the vault folder could plant a database there
+	if err := os.WriteFile(plainDBPath(vault), []byte("planted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath, recovered, err := unlockDatabase(vault, "correct horse")
+	if err != nil || recovered {
+		t.Fatalf("unlock = %q, %t, %v, want the sealed copy", dbPath, recovered, err)
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "sealed" {
+		t.Fatalf("unlocked database = %q, want the sealed copy", data)
+	}
+}
+
+func TestUnlockDatabaseRecoversCrashCopy(t *testing.T) {
+	vault := newLockedVault(t, "sealed", "correct horse")
+	// A crash left the unlocked copy, newer than the sealed one, behind
+	dbPath := unlockedDBPath(vault)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dbPath, []byte("newer"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, recovered, err := unlockDatabase(vault, "correct horse")
+	if err != nil || !recovered || got != dbPath {
+		t.Fatalf("unlock = %q, %t, %v, want the crash copy recovered", got, recovered, err)
+	}
+	if data, _ := os.ReadFile(dbPath); string(data) != "newer" {
+		t.Fatalf("unlocked database = %q, want the newer copy", data)
+	}
+}
+
+func TestLockSealsPrivateDirs(t *testing.T) {
+	vault := newLockedVault(t, "index", "correct horse")
+	if got := privateDataPath(vault, "data/edit_journal"); filepath.Dir(got) != filepath.Dir(unlockedDBPath(vault)) {
+		t.Fatalf("edit journal of a locked vault at %s, want it beside the unlocked database", got)
+	}
+	dbPath, _, err := unlockDatabase(vault, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalFile := filepath.Join(privateDataPath(vault, "data/edit_journal"), "a.jsonl")
+	backupFile := filepath.Join(privateDataPath(vault, "data/chat_backups"), "chat_backup_1.json")
+	for file, content := range map[string]string{journalFile: "unsaved note text", backupFile: "chat history"} {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sealDatabase(vault, dbPath, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(journalFile) || fileExists(backupFile) {
+		t.Fatal("locking left private data in plain text")
+	}
+	if data, _ := os.ReadFile(lockedPrivatePath(vault)); strings.Contains(string(data), "unsaved note text") {
+		t.Fatal("sealed private data holds plain text")
+	}
+	for _, name := range privateDirs {
+		if fileExists(filepath.Join(vault, "data", name)) {
+			t.Fatalf("%s is in the locked vault", name)
+		}
+	}
+
+	if _, _, err := unlockDatabase(vault, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(journalFile); string(data) != "unsaved note text" {
+		t.Fatalf("edit journal after unlock = %q", data)
+	}
+	if data, _ := os.ReadFile(backupFile); string(data) != "chat history" {
+		t.Fatalf("chat backup after unlock = %q", data)
+	}
+}
+
+func TestUnlockVaultWrongPassphrase(t *testing.T) {
+	vault := newLockedVault(t, "index", "correct horse")
+	a := &App{fm: files.NewManager(), cfg: config.New()}
+	if err := a.fm.SetBasePath(vault); err != nil {
+		t.Fatal(err)
+	}
+	if status := a.GetVaultLockStatus(); !status.Enabled || !status.Locked {
+		t.Fatalf("status = %+v, want an enabled, locked vault", status)
+	}
+	if err := a.UnlockVault("wrong passphrase"); !errors.Is(err, errWrongPassphrase) {
+		t.Fatalf("UnlockVault = %v, want errWrongPassphrase", err)
+	}
+	if !a.GetVaultLockStatus().Locked {
+		t.Fatal("vault unlocked with a wrong passphrase")
+	}
+}
+
+func TestMoveDir(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "vault", "data", "edit_journal")
+	dst := filepath.Join(root, "cache", "edit_journal")
+	if err := moveDir(src, dst); err != nil {
+		t.Fatalf("moving a missing dir: %v", err)
+	}
+	for _, name := range []string{"a.jsonl", "b.jsonl"} {
+		if err := os.MkdirAll(src, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// The second round merges into the existing dst
+		if err := moveDir(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		if fileExists(src) {
+			t.Fatalf("%s left behind", src)
+		}
+	}
+	for _, name := range []string{"a.jsonl", "b.jsonl"} {
+		if data, _ := os.ReadFile(filepath.Join(dst, name)); string(data) != name {
+			t.Errorf("%s = %q", name, data)
+		}
+	}
+}
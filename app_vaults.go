@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ VAULT API METHODS ============
+
+// loadVaultRegistry reads the recent vault list stored next to config.json
+func (a *App) loadVaultRegistry() {
+	path := ""
+	if configDir, err := os.UserConfigDir(); err == nil {
+		path = filepath.Join(configDir, "notebit", "vaults.json")
+	}
+	registry, err := config.LoadVaultRegistry(path)
+	if err != nil {
+		logger.Warn("Failed to read vault registry: %v", err)
+	}
+	a.vaults = registry
+}
+
+// ListVaults returns the recently opened vaults, most recent first
+func (a *App) ListVaults() ([]config.Vault, error) {
+	if a.vaults == nil {
+		return []config.Vault{}, nil
+	}
+	return a.vaults.List(), nil
+}
+
+// SwitchVault closes the current vault and opens the one at path
+func (a *App) SwitchVault(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("vault path is required")
+	}
+	return a.openVault(path)
+}
+
+// RenameVault sets the display name shown for a vault
+func (a *App) RenameVault(path, name string) error {
+	if a.vaults == nil {
+		return fmt.Errorf("vault registry not available")
+	}
+	return a.vaults.Rename(strings.TrimSpace(path), name)
+}
+
+// ForgetVault removes a vault from the recent list without touching its files
+func (a *App) ForgetVault(path string) error {
+	if a.vaults == nil {
+		return nil
+	}
+	return a.vaults.Remove(strings.TrimSpace(path))
+}
+
+// GetVaultConfigOverrides returns the config sections overridden by the open
+// vault's .notebit/config.json; the settings UI shows them as vault-managed
+func (a *App) GetVaultConfigOverrides() ([]string, error) {
+	return a.cfg.VaultOverrides(), nil
+}
+
+// applyVaultConfig layers the vault's config file over the global config.
+// A broken file is reported and the global config is used unchanged.
+func (a *App) applyVaultConfig(vaultDir string) {
+	sections, err := a.cfg.ApplyVaultOverrides(vaultDir)
+	if err != nil {
+		logger.WarnWithFields(a.ctx, map[string]interface{}{"vault": vaultDir, "error": err.Error()}, "Ignoring invalid vault config")
+		runtime.LogWarningf(a.ctx, "Ignoring invalid %s: %v", config.VaultConfigFile, err)
+		return
+	}
+	if len(sections) > 0 {
+		logger.InfoWithFields(a.ctx, map[string]interface{}{"vault": vaultDir, "sections": sections}, "Applied vault config overrides")
+	}
+}
+
+// openVault tears down every service bound to the current vault and rebinds
+// them to path with a database manager of its own
+func (a *App) openVault(path string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	timer := logger.StartTimer()
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	if previous := a.fm.GetBasePath(); absPath != previous || !a.dbm.IsInitialized() {
+		a.closeVault()
+		if err := a.bindVault(absPath); err != nil {
+			// Reopen the previous vault rather than leave none open
+			if previous != "" && previous != absPath {
+				if restoreErr := a.bindVault(previous); restoreErr != nil {
+					logger.Warn("Failed to reopen vault %s: %v", previous, restoreErr)
+				}
+			}
+			return err
+		}
+	}
+
+	if a.vaults != nil {
+		if err := a.vaults.Touch(absPath, time.Now()); err != nil {
+			logger.Warn("Failed to update vault registry: %v", err)
+		}
+	}
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "vault:opened", absPath)
+	}
+	logger.InfoWithDuration(a.ctx, timer(), "Vault opened: %s", absPath)
+	return nil
+}
+
+// bindVault points the file manager at path and starts the services of the
+// vault there with a database manager of its own
+func (a *App) bindVault(path string) error {
+	if err := a.fm.SetBasePath(path); err != nil {
+		return err
+	}
+	a.dbm = database.NewManager()
+	a.applyVaultConfig(path)
+	if err := a.initializeServices(path); err != nil {
+		logger.Warn("Service initialization issue: %v", err)
+	}
+	if err := a.startWatcher(); err != nil {
+		runtime.LogErrorf(a.ctx, "Failed to start watcher: %v", err)
+	}
+	return nil
+}
+
+// closeVaultJobsTimeout bounds how long closing a vault waits for its
+// cancelled jobs to return
+const closeVaultJobsTimeout = 10 * time.Second
+
+// closeVault stops the services of the current vault and closes its database
+func (a *App) closeVault() {
+	// Jobs (imports, exports, reindexing) work on this vault; stop them first
+	ctx, cancel := context.WithTimeout(context.Background(), closeVaultJobsTimeout)
+	if err := a.jobs.CancelAll(ctx); err != nil {
+		logger.Warn("Jobs still running while closing the vault: %v", err)
+	}
+	cancel()
+	a.stopWatcher()
+	a.stopGit(true)
+	if a.schedule != nil {
+		a.schedule.Stop()
+		a.schedule = nil
+	}
+	if a.digests != nil {
+		a.digests.Stop()
+	}
+	if a.backups != nil {
+		a.backups.Stop()
+	}
+	if a.feeds != nil {
+		a.feeds.Stop()
+	}
+	a.streamMu.Lock()
+	for _, cancel := range a.streams {
+		cancel()
+	}
+	a.streamMu.Unlock()
+	if a.pipeline != nil {
+		a.pipeline.Stop()
+		a.pipeline = nil
+	}
+	if a.chatSvc != nil {
+		a.chatSvc.Close()
+		a.chatSvc = nil
+	}
+	a.closeEditLog()
+	a.journal = nil
+	a.ks = nil
+	a.rag = nil
+	a.graph = nil
+	a.ai.UsageTracker().SetRecorder(nil)
+	if err := a.dbm.Close(); err != nil {
+		logger.Warn("Failed to close vault database: %v", err)
+	}
+	a.sealVault(a.fm.GetBasePath())
+}
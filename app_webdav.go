@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/logger"
+	vaultsync "notebit/pkg/sync"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============ WEBDAV SYNC METHODS ============
+
+// webdavSync tracks the WebDAV sync runs of this session
+type webdavSync struct {
+	mu       sync.Mutex
+	running  bool
+	lastSync time.Time
+	lastErr  string
+	last     *vaultsync.SyncResult
+}
+
+// SyncStatus is the WebDAV sync setup and the outcome of the last run. The
+// password is never returned.
+type SyncStatus struct {
+	Configured bool                  `json:"configured"`
+	URL        string                `json:"url"`
+	Username   string                `json:"username"`
+	Folders    []string              `json:"folders"`
+	Running    bool                  `json:"running"`
+	LastSync   *time.Time            `json:"last_sync,omitempty"`
+	LastError  string                `json:"last_error,omitempty"`
+	LastResult *vaultsync.SyncResult `json:"last_result,omitempty"`
+}
+
+// ConfigureSync points the vault at a WebDAV folder and checks that it is
+// reachable, creating it if needed. An empty password keeps the saved one;
+// an empty URL turns WebDAV sync off.
+func (a *App) ConfigureSync(url, username, password string, folders []string) (SyncStatus, error) {
+	url = strings.TrimSpace(url)
+	current := a.cfg.GetWebDAVSyncConfig()
+	if url == "" {
+		current.Enabled = false
+		a.cfg.SetWebDAVSyncConfig(current)
+		return a.GetSyncStatus(), a.cfg.Save()
+	}
+	if password == "" && url == current.URL && username == current.Username {
+		password = current.Password
+	}
+
+	remote, err := vaultsync.NewWebDAV(url, username, password, ai.NewHTTPClient(time.Minute))
+	if err != nil {
+		return SyncStatus{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := remote.Check(ctx); err != nil {
+		return SyncStatus{}, err
+	}
+
+	// The saved state describes the old target; a new one starts from scratch
+	if url != current.URL && a.dbm.IsInitialized() {
+		if err := a.dbm.Repository().ClearSyncState(); err != nil {
+			return SyncStatus{}, err
+		}
+	}
+	a.cfg.SetWebDAVSyncConfig(config.WebDAVSyncConfig{
+		Enabled:  true,
+		URL:      url,
+		Username: username,
+		Password: password,
+		Folders:  folders,
+	})
+	if err := a.cfg.Save(); err != nil {
+		return SyncStatus{}, err
+	}
+	return a.GetSyncStatus(), nil
+}
+
+// SyncNow runs a two-way WebDAV sync of the open vault. Notes changed on both
+// sides are merged when the edits do not overlap; otherwise the local version
+// is kept and the remote one is saved as a conflict copy.
+func (a *App) SyncNow() (*vaultsync.SyncResult, error) {
+	davCfg := a.cfg.GetWebDAVSyncConfig()
+	if !davCfg.Enabled || davCfg.URL == "" {
+		return nil, errors.New("WebDAV sync is not configured")
+	}
+	basePath := a.fm.GetBasePath()
+	if basePath == "" {
+		return nil, errors.New("no vault open")
+	}
+	if !a.dbm.IsInitialized() {
+		return nil, errors.New("database not initialized")
+	}
+	remote, err := vaultsync.NewWebDAV(davCfg.URL, davCfg.Username, davCfg.Password, ai.NewHTTPClient(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	a.webdav.mu.Lock()
+	if a.webdav.running {
+		a.webdav.mu.Unlock()
+		return nil, errors.New("a sync is already running")
+	}
+	a.webdav.running = true
+	a.webdav.mu.Unlock()
+
+	timer := logger.StartTimer()
+	mirror := vaultsync.NewMirror(basePath, remote, syncStateStore{a.dbm.Repository()}, davCfg.Folders)
+	mirror.KeepBases(filepath.Join(basePath, "data", "sync-base"))
+	result, err := mirror.Sync(context.Background())
+
+	a.webdav.mu.Lock()
+	a.webdav.running = false
+	a.webdav.lastSync = time.Now()
+	a.webdav.lastErr = ""
+	if err != nil {
+		a.webdav.lastErr = err.Error()
+	} else {
+		a.webdav.last = result
+	}
+	a.webdav.mu.Unlock()
+
+	if err != nil {
+		logger.ErrorWithFields(a.ctx, map[string]interface{}{"error": err.Error()}, "WebDAV sync failed")
+		return nil, err
+	}
+	logger.InfoWithDuration(a.ctx, timer(), "WebDAV sync: %d up, %d down, %d merged, %d conflicts, %d errors",
+		len(result.Uploaded)+len(result.DeletedRemote), len(result.Downloaded)+len(result.DeletedLocal), len(result.Merged), len(result.Conflicts), len(result.Errors))
+	logger.Audit(a.ctx, auditSyncWebDAV, davCfg.URL, nil, map[string]interface{}{
+		"uploaded":       len(result.Uploaded),
+		"downloaded":     len(result.Downloaded),
+		"deleted_local":  len(result.DeletedLocal),
+		"deleted_remote": len(result.DeletedRemote),
+		"merged":         result.Merged,
+		"conflicts":      result.Conflicts,
+	})
+	if result.Changed() {
+		a.reconcileIndexOnOpen()
+	}
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "sync:completed", result)
+	}
+	return result, nil
+}
+
+// GetSyncStatus returns the WebDAV sync setup and the outcome of the last run
+func (a *App) GetSyncStatus() SyncStatus {
+	davCfg := a.cfg.GetWebDAVSyncConfig()
+	status := SyncStatus{
+		Configured: davCfg.Enabled && davCfg.URL != "",
+		URL:        davCfg.URL,
+		Username:   davCfg.Username,
+		Folders:    davCfg.Folders,
+	}
+	if status.Folders == nil {
+		status.Folders = []string{}
+	}
+	a.webdav.mu.Lock()
+	defer a.webdav.mu.Unlock()
+	status.Running = a.webdav.running
+	if !a.webdav.lastSync.IsZero() {
+		lastSync := a.webdav.lastSync
+		status.LastSync = &lastSync
+	}
+	status.LastError = a.webdav.lastErr
+	status.LastResult = a.webdav.last
+	return status
+}
+
+// syncStateStore keeps the WebDAV sync state in the vault database
+type syncStateStore struct {
+	repo *database.Repository
+}
+
+func (s syncStateStore) ListSyncStates() ([]vaultsync.FileState, error) {
+	records, err := s.repo.ListSyncStates()
+	if err != nil {
+		return nil, err
+	}
+	states := make([]vaultsync.FileState, len(records))
+	for i, r := range records {
+		states[i] = vaultsync.FileState{Path: r.Path, LocalHash: r.LocalHash, RemoteETag: r.RemoteETag, SyncedAt: r.SyncedAt}
+	}
+	return states, nil
+}
+
+func (s syncStateStore) SaveSyncState(state vaultsync.FileState) error {
+	return s.repo.SaveSyncState(database.SyncState{
+		Path:       state.Path,
+		LocalHash:  state.LocalHash,
+		RemoteETag: state.RemoteETag,
+		SyncedAt:   state.SyncedAt,
+	})
+}
+
+func (s syncStateStore) DeleteSyncState(path string) error {
+	return s.repo.DeleteSyncState(path)
+}
@@ -19,6 +19,8 @@ export const useFileOperations = (options = {}) => {
   const [fileTree, setFileTree] = useState(null);
   const [currentFile, setCurrentFile] = useState(null);
   const [currentContent, setCurrentContent] = useState('');
+  // Hash of the version on disk the editor content is based on
+  const [currentHash, setCurrentHash] = useState('');
   const [basePath, setBasePath] = useState('');
 
   // UI state
@@ -71,6 +73,7 @@ export const useFileOperations = (options = {}) => {
         const name = isObject && nodeOrPath.name ? nodeOrPath.name : path.split('/').pop();
         setCurrentFile({ ...(isObject ? nodeOrPath : {}), path, name, isDir: false });
         setCurrentContent(typeof result?.content === 'string' ? result.content : '');
+        setCurrentHash(result?.hash || '');
         return result;
       },
       ERROR_MESSAGES.READ_FILE
@@ -83,14 +86,15 @@ export const useFileOperations = (options = {}) => {
     createHandler(
       async (content) => {
         if (!currentFile) return null;
-        await fileService.saveFile(currentFile.path, content);
+        const hash = await fileService.saveFile(currentFile.path, content, currentHash);
         setCurrentContent(content);
+        setCurrentHash(hash);
         onSuccess?.('File saved successfully');
         return true;
       },
       ERROR_MESSAGES.SAVE_FILE
     ),
-    [currentFile, onSuccess]
+    [currentFile, currentHash, onSuccess]
   );
 
   // Update content (for editor onChange)
@@ -102,6 +106,7 @@ export const useFileOperations = (options = {}) => {
   const clearFile = useCallback(() => {
     setCurrentFile(null);
     setCurrentContent('');
+    setCurrentHash('');
   }, []);
 
   return {
@@ -75,12 +75,14 @@ export const fileService = {
   },
 
   /**
-   * Save content to file
+   * Save content to file, unless it changed on disk since it was loaded
    * @param {string} path - Relative file path
    * @param {string} content - File content
+   * @param {string} baseHash - Hash of the version the editor loaded or last saved
+   * @returns {Promise<string>} Hash of the saved version
    */
-  async saveFile(path, content) {
-    return wrapCall('saveFile', () => SaveFile(path, content));
+  async saveFile(path, content, baseHash) {
+    return wrapCall('saveFile', () => SaveFile(path, content, baseHash));
   },
 
   /**
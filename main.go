@@ -2,12 +2,14 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"notebit/pkg/logger"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/mac"
 )
 
 //go:embed all:frontend/dist
@@ -21,6 +23,7 @@ func main() {
 		Level:         logger.INFO,
 		LogDir:        "logs",
 		FileName:      "notebit.log",
+		AuditFileName: "audit.log",
 		MaxFileSize:   100 * 1024 * 1024, // 100MB
 		MaxBackups:    15,                // 15 days
 		ConsoleOutput: true,
@@ -37,6 +40,10 @@ func main() {
 
 	// Create an instance of the app structure
 	app := NewApp()
+	logger.SetPanicHandler(app.writeCrashReport)
+	if wd, err := os.Getwd(); err == nil {
+		app.handleLaunchArgs(os.Args[1:], wd)
+	}
 
 	// Create application with options
 	err = wails.Run(&options.App{
@@ -47,8 +54,15 @@ func main() {
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
-		OnStartup:        app.startup,
-		OnShutdown:       app.shutdown,
+		SingleInstanceLock: &options.SingleInstanceLock{
+			UniqueId:               "com.imicola.notebit",
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
+		},
+		Mac: &mac.Options{
+			OnUrlOpen: app.handleDeepLink,
+		},
+		OnStartup:  app.startup,
+		OnShutdown: app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
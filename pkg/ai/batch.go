@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BatchError reports the texts of a batch that could not be embedded. The
+// batch results are still returned, with nil at the failed indexes.
+type BatchError struct {
+	Total  int
+	Failed []EmbeddingResult // Index and Error of each failed text
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Failed) == 0 {
+		return "batch embedding failed"
+	}
+	return fmt.Sprintf("%d of %d texts failed to embed, first at index %d: %v", len(e.Failed), e.Total, e.Failed[0].Index, e.Failed[0].Error)
+}
+
+// Unwrap exposes the item errors to errors.Is and errors.As
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		errs = append(errs, f.Error)
+	}
+	return errs
+}
+
+// FailedIndexes returns the indexes of the texts that failed, ascending
+func (e *BatchError) FailedIndexes() []int {
+	indexes := make([]int, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		indexes = append(indexes, f.Index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// newBatchError returns a BatchError for the non-nil entries of errs, or nil
+// if there are none
+func newBatchError(errs []error) error {
+	var failed []EmbeddingResult
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, EmbeddingResult{Index: i, Error: err})
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BatchError{Total: len(errs), Failed: failed}
+}
@@ -0,0 +1,266 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"notebit/pkg/ai/minilm"
+)
+
+// BuiltinModel is the model the builtin provider runs
+const BuiltinModel = "all-MiniLM-L6-v2"
+
+// BuiltinProvider implements EmbeddingProvider with an in-process model, so
+// semantic search works without Ollama or an API key
+type BuiltinProvider struct {
+	dir         string
+	downloadURL string
+	httpClient  *http.Client
+
+	mu    sync.Mutex
+	model *minilm.Model
+}
+
+// BuiltinConfig holds the configuration for the builtin provider
+type BuiltinConfig struct {
+	ModelDir    string // Empty selects DefaultBuiltinModelDir
+	DownloadURL string // Base URL of the model files, used by Download
+}
+
+// DefaultBuiltinModelDir returns where the builtin model is stored by default
+func DefaultBuiltinModelDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "notebit", "models", BuiltinModel), nil
+}
+
+// NewBuiltinProvider creates a builtin provider. The model is loaded on first use.
+func NewBuiltinProvider(cfg BuiltinConfig) (*BuiltinProvider, error) {
+	dir := cfg.ModelDir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultBuiltinModelDir(); err != nil {
+			return nil, err
+		}
+	}
+	return &BuiltinProvider{
+		dir:         dir,
+		downloadURL: strings.TrimRight(cfg.DownloadURL, "/"),
+		httpClient:  NewHTTPClient(0),
+	}, nil
+}
+
+// ModelDir returns the directory the model is read from
+func (p *BuiltinProvider) ModelDir() string {
+	return p.dir
+}
+
+// Available reports whether the model files are present
+func (p *BuiltinProvider) Available() bool {
+	return minilm.ModelPresent(p.dir)
+}
+
+func (p *BuiltinProvider) load() (*minilm.Model, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.model != nil {
+		return p.model, nil
+	}
+	if !p.Available() {
+		return nil, fmt.Errorf("builtin model is not downloaded to %s", p.dir)
+	}
+	model, err := minilm.Load(p.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load builtin model: %w", err)
+	}
+	p.model = model
+	return model, nil
+}
+
+// GenerateEmbedding creates an embedding for a single text. The provider runs a
+// single model, so req.Model is ignored.
+func (p *BuiltinProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if req.Text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	model, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := model.Embed(ctx, req.Text)
+	if err != nil {
+		return nil, err
+	}
+	tokens := model.CountTokens(req.Text)
+	return &EmbeddingResponse{
+		Embedding: embedding,
+		Model:     BuiltinModel,
+		Usage:     &Usage{PromptTokens: tokens, TotalTokens: tokens},
+	}, nil
+}
+
+// GenerateEmbeddingsBatch embeds texts in parallel, one worker per CPU
+func (p *BuiltinProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+	if _, err := p.load(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*EmbeddingResponse, len(texts))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	var firstErr error
+	var errOnce sync.Once
+
+	workers := min(runtime.NumCPU(), len(texts))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp, err := p.GenerateEmbedding(ctx, &EmbeddingRequest{Text: texts[i]})
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("error at index %d: %w", i, err)
+						cancel()
+					})
+					continue
+				}
+				results[i] = resp
+			}
+		}()
+	}
+
+Feed:
+	for i := range texts {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break Feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// GetModelDimension returns the output dimension of the builtin model
+func (p *BuiltinProvider) GetModelDimension(model string) (int, error) {
+	p.mu.Lock()
+	loaded := p.model
+	p.mu.Unlock()
+	if loaded != nil {
+		return loaded.Dimension(), nil
+	}
+	return knownModelDimensions[BuiltinModel], nil
+}
+
+// GetDefaultModel returns the default model name
+func (p *BuiltinProvider) GetDefaultModel() string {
+	return BuiltinModel
+}
+
+// ValidateConfig checks that the model files are present
+func (p *BuiltinProvider) ValidateConfig() error {
+	if !p.Available() {
+		return fmt.Errorf("builtin model is not downloaded to %s", p.dir)
+	}
+	return nil
+}
+
+// Name returns the provider name
+func (p *BuiltinProvider) Name() string {
+	return "builtin"
+}
+
+// Download fetches missing model files from the download URL. progress, if
+// set, receives the file being fetched and its byte counts (total is -1 when
+// the server does not send a length).
+func (p *BuiltinProvider) Download(ctx context.Context, progress func(file string, done, total int64)) error {
+	if p.downloadURL == "" {
+		return fmt.Errorf("no download URL configured for the builtin model")
+	}
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range minilm.ModelFiles {
+		target := filepath.Join(p.dir, name)
+		if info, err := os.Stat(target); err == nil && info.Size() > 0 {
+			continue
+		}
+		if err := p.downloadFile(ctx, name, target, progress); err != nil {
+			return fmt.Errorf("failed to download %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *BuiltinProvider) downloadFile(ctx context.Context, name, target string, progress func(file string, done, total int64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.downloadURL+"/"+name, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	// Write to a temporary file so an interrupted download is never mistaken for the model
+	tmp, err := os.CreateTemp(p.dir, name+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var src io.Reader = resp.Body
+	if progress != nil {
+		src = &progressReader{r: resp.Body, total: resp.ContentLength, report: func(done, total int64) { progress(name, done, total) }}
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+type progressReader struct {
+	r      io.Reader
+	done   int64
+	total  int64
+	report func(done, total int64)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.done += int64(n)
+	r.report(r.done, r.total)
+	return n, err
+}
@@ -37,12 +37,14 @@ func (c *FixedSizeChunker) Chunk(text string) ([]TextChunk, error) {
 
 	start := 0
 	overlap := c.chunkOverlap
+	spans := mathSpans(runes)
 
 	for start < textLen {
 		end := start + c.chunkSize
 		if end > textLen {
 			end = textLen
 		}
+		end = mathSafeEnd(spans, start, end, c.minChunkSize)
 
 		chunk := string(runes[start:end])
 
@@ -54,14 +56,22 @@ func (c *FixedSizeChunker) Chunk(text string) ([]TextChunk, error) {
 			})
 		}
 
+		// Stop at the end, or the overlap would repeat the last chunk forever
+		if end == textLen {
+			break
+		}
+
 		// Move start position, accounting for overlap
+		prev := start
 		start = end - overlap
 		if start < 0 {
 			start = 0
 		}
+		start = mathSafeStart(spans, prev, start)
 
-		// Prevent infinite loop when overlap equals chunk size
-		if start >= end {
+		// Prevent infinite loop when the overlap covers the whole chunk,
+		// as when it was cut short before a formula
+		if start >= end || start <= prev {
 			start = end
 		}
 	}
@@ -183,6 +193,7 @@ func (c *HeadingChunker) extractHeadingChunks(text string) []headingChunk {
 	var chunks []headingChunk
 	var currentContent strings.Builder
 	var currentHeading string
+	inMath := false
 	lineIndex := 0
 	start := 0
 	for start <= len(text) {
@@ -196,8 +207,9 @@ func (c *HeadingChunker) extractHeadingChunks(text string) []headingChunk {
 
 		trimmed := strings.TrimLeft(line, " \t")
 
-		// Check if this is a markdown heading (#, ##, ###, etc.)
-		if len(trimmed) >= 2 && trimmed[0] == '#' && (trimmed[1] == ' ' || trimmed[1] == '#') {
+		// Check if this is a markdown heading (#, ##, ###, etc.); lines of a
+		// $$ block are never headings
+		if !inMath && len(trimmed) >= 2 && trimmed[0] == '#' && (trimmed[1] == ' ' || trimmed[1] == '#') {
 			// Save previous chunk
 			if currentContent.Len() > 0 {
 				chunks = append(chunks, headingChunk{
@@ -216,6 +228,9 @@ func (c *HeadingChunker) extractHeadingChunks(text string) []headingChunk {
 			}
 			currentContent.WriteString(line)
 		}
+		if strings.Count(line, "$$")%2 == 1 {
+			inMath = !inMath
+		}
 
 		if end == -1 {
 			break
@@ -281,12 +296,14 @@ func (c *SlidingWindowChunker) Chunk(text string) ([]TextChunk, error) {
 	}
 
 	var chunks []TextChunk
+	spans := mathSpans(runes)
 
-	for start := 0; start < textLen; start += c.step {
+	for start := 0; start < textLen; start = mathSafeStart(spans, start, start+c.step) {
 		end := start + c.windowSize
 		if end > textLen {
 			end = textLen
 		}
+		end = mathSafeEnd(spans, start, end, c.minChunkSize)
 
 		chunk := string(runes[start:end])
 
@@ -398,6 +415,7 @@ func (c *SentenceChunker) Chunk(text string) ([]TextChunk, error) {
 func (c *SentenceChunker) splitSentences(text string) []string {
 	var sentences []string
 	runes := []rune(text)
+	spans := mathSpans(runes)
 	start := 0
 
 	for i := 0; i < len(runes); i++ {
@@ -405,6 +423,11 @@ func (c *SentenceChunker) splitSentences(text string) []string {
 		if r != '.' && r != '!' && r != '?' {
 			continue
 		}
+		if span, ok := enclosingSpan(spans, i); ok {
+			// Punctuation inside a formula never ends a sentence
+			i = span[1] - 1
+			continue
+		}
 
 		boundary, nextNonSpace := isSentenceBoundary(runes, i)
 		if !boundary {
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// modelDiscoveryTimeout bounds model listing so a settings dropdown never hangs
+const modelDiscoveryTimeout = 10 * time.Second
+
+// NewCustomProvider creates an embedding provider for any OpenAI-compatible
+// server (LM Studio, vLLM, OpenRouter, llama.cpp). The API key is optional.
+func NewCustomProvider(cfg OpenAIConfig) (*OpenAIProvider, error) {
+	if strings.TrimSpace(cfg.BaseURL) == "" {
+		return nil, fmt.Errorf("custom provider base URL is required")
+	}
+	return newOpenAICompatibleProvider("custom", cfg), nil
+}
+
+// NewCustomLLMProvider creates a chat provider for any OpenAI-compatible server
+func NewCustomLLMProvider(baseURL, apiKey, model string, timeout time.Duration) (*OpenAILLMProvider, error) {
+	if strings.TrimSpace(baseURL) == "" {
+		return nil, fmt.Errorf("custom provider base URL is required")
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &OpenAILLMProvider{
+		name:       "custom",
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		httpClient: NewHTTPClient(timeout),
+		model:      model,
+	}, nil
+}
+
+// ListOpenAICompatibleModels returns the model IDs from GET {baseURL}/models
+func ListOpenAICompatibleModels(ctx context.Context, client *http.Client, baseURL, apiKey, organization string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if organization != "" {
+		req.Header.Set("OpenAI-Organization", organization)
+	}
+
+	var body struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := getJSON(client, req, &body); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(body.Data))
+	for _, m := range body.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// ListOllamaModels returns the locally pulled models from GET {baseURL}/api/tags
+func ListOllamaModels(ctx context.Context, baseURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	var body struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := getJSON(NewHTTPClient(modelDiscoveryTimeout), req, &body); err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(body.Models))
+	for _, m := range body.Models {
+		models = append(models, m.Name)
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+func getJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("", resp, data)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
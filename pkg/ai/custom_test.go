@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOpenAIServer is an OpenAI-compatible server such as LM Studio or vLLM
+type fakeOpenAIServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	auth  []string // Authorization header of each request
+	paths []string
+}
+
+func newFakeOpenAIServer(t *testing.T) *fakeOpenAIServer {
+	f := &fakeOpenAIServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.auth = append(f.auth, r.Header.Get("Authorization"))
+		f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+		f.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"data": [{"id": "zeta"}, {"id": ""}, {"id": "alpha"}]}`))
+		case "/v1/embeddings":
+			var body struct {
+				Model string          `json:"model"`
+				Input json.RawMessage `json:"input"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var inputs []string
+			if json.Unmarshal(body.Input, &inputs) != nil {
+				inputs = []string{""}
+			}
+			data := make([]map[string]any, len(inputs))
+			for i := range inputs {
+				data[i] = map[string]any{"index": i, "embedding": []float32{float32(i), 1}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"model": body.Model, "data": data})
+		case "/v1/chat/completions":
+			var body struct {
+				Model    string `json:"model"`
+				Stream   bool   `json:"stream"`
+				Messages []struct {
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			reply := "echo: " + body.Messages[len(body.Messages)-1].Content
+			if body.Stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, part := range strings.SplitAfter(reply, " ") {
+					delta, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"delta": map[string]string{"content": part}}}})
+					fmt.Fprintf(w, "data: %s\n\n", delta)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"model":   body.Model,
+				"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+				"usage":   map[string]int{"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestNewCustomProviderRequiresBaseURL(t *testing.T) {
+	if _, err := NewCustomProvider(OpenAIConfig{BaseURL: "  "}); err == nil {
+		t.Error("NewCustomProvider without a base URL succeeded")
+	}
+	if _, err := NewCustomLLMProvider("", "", "model", 0); err == nil {
+		t.Error("NewCustomLLMProvider without a base URL succeeded")
+	}
+}
+
+func TestCustomProviderEmbeddings(t *testing.T) {
+	srv := newFakeOpenAIServer(t)
+	provider, err := NewCustomProvider(OpenAIConfig{BaseURL: srv.URL + "/v1", EmbeddingModel: "local-embed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Name() != "custom" {
+		t.Fatalf("Name = %q", provider.Name())
+	}
+	// No API key is needed for a local server
+	if err := provider.ValidateConfig(); err != nil {
+		t.Fatalf("ValidateConfig without a key: %v", err)
+	}
+
+	ctx := context.Background()
+	single, err := provider.GenerateEmbedding(ctx, &EmbeddingRequest{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.Model != "local-embed" || len(single.Embedding) != 2 {
+		t.Fatalf("embedding = %+v", single)
+	}
+	batch, err := provider.GenerateEmbeddingsBatch(ctx, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 3 || batch[2].Embedding[0] != 2 {
+		t.Fatalf("batch = %+v", batch)
+	}
+	for _, auth := range srv.auth {
+		if auth != "" {
+			t.Fatalf("sent Authorization %q without a key", auth)
+		}
+	}
+}
+
+func TestCustomLLMProviderCompletion(t *testing.T) {
+	srv := newFakeOpenAIServer(t)
+	llm, err := NewCustomLLMProvider(srv.URL+"/v1/", "secret", "local-chat", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.httpClient.Timeout != 60*time.Second {
+		t.Fatalf("default timeout = %s, want 60s", llm.httpClient.Timeout)
+	}
+
+	ctx := context.Background()
+	resp, err := llm.GenerateCompletion(ctx, &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "echo: hi" || resp.Model != "local-chat" || resp.TokensUsed.TotalTokens != 5 {
+		t.Fatalf("completion = %+v", resp)
+	}
+
+	stream, err := llm.GenerateCompletionStream(ctx, &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "there"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatal(chunk.Error)
+		}
+		sb.WriteString(chunk.Content)
+	}
+	if sb.String() != "echo: there" {
+		t.Fatalf("streamed %q", sb.String())
+	}
+	for _, auth := range srv.auth {
+		if auth != "Bearer secret" {
+			t.Fatalf("Authorization = %q", auth)
+		}
+	}
+}
+
+func TestListOpenAICompatibleModels(t *testing.T) {
+	srv := newFakeOpenAIServer(t)
+	models, err := ListOpenAICompatibleModels(context.Background(), srv.Client(), srv.URL+"/v1/", "key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(models, []string{"alpha", "zeta"}) {
+		t.Fatalf("models = %q, want sorted non-empty IDs", models)
+	}
+	if srv.auth[0] != "Bearer key" || srv.paths[0] != "GET /v1/models" {
+		t.Fatalf("request = %s with %q", srv.paths[0], srv.auth[0])
+	}
+
+	_, err = ListOpenAICompatibleModels(context.Background(), srv.Client(), srv.URL+"/missing", "", "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("listing from a wrong URL = %v, want a 404 APIError", err)
+	}
+}
@@ -0,0 +1,353 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+const (
+	// aiDebugLogName is the debug log written to the log directory
+	aiDebugLogName = "ai-debug.log"
+
+	// aiDebugLogSize and aiDebugLogBackups bound the debug log on disk
+	aiDebugLogSize    = 10 * 1024 * 1024
+	aiDebugLogBackups = 5
+
+	// maxDebugTextRunes truncates each text recorded in an exchange when
+	// full texts are recorded
+	maxDebugTextRunes = 4000
+
+	// debugPreviewRunes is how much of each text is recorded by default
+	debugPreviewRunes = 80
+)
+
+// Exchange kinds
+const (
+	ExchangeEmbedding        = "embedding"
+	ExchangeCompletion       = "completion"
+	ExchangeCompletionStream = "completion_stream"
+)
+
+// AIExchange is one recorded provider call with sanitized payloads
+type AIExchange struct {
+	Time       int64           `json:"time"` // Unix ms
+	Kind       string          `json:"kind"`
+	Provider   string          `json:"provider"`
+	Model      string          `json:"model,omitempty"`
+	Operation  string          `json:"operation,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// DebugRecorder records provider calls while AI debug mode is on: the last
+// exchange is kept in memory and every exchange is appended to a rotating log
+type DebugRecorder struct {
+	enabled  atomic.Bool
+	fullText atomic.Bool
+
+	mu          sync.Mutex
+	writer      *logger.FileWriter
+	last        *AIExchange
+	lastRequest *CompletionRequest // replayable copy of the last completion
+}
+
+// SetEnabled turns debug mode on or off. The log is written to logDir.
+func (r *DebugRecorder) SetEnabled(enabled bool, logDir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !enabled {
+		r.enabled.Store(false)
+		if r.writer != nil {
+			r.writer.Close()
+			r.writer = nil
+		}
+		return nil
+	}
+	if r.writer == nil {
+		writer, err := logger.NewFileWriter(logger.Config{
+			LogDir:      logDir,
+			FileName:    aiDebugLogName,
+			MaxFileSize: aiDebugLogSize,
+			MaxBackups:  aiDebugLogBackups,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open AI debug log: %w", err)
+		}
+		r.writer = writer
+	}
+	r.enabled.Store(true)
+	return nil
+}
+
+// SetFullText records prompts and responses up to maxDebugTextRunes each.
+// By default only the start of each text is kept, with a hash and its length
+// to tell texts apart, since notes and chats end up in the log.
+func (r *DebugRecorder) SetFullText(full bool) {
+	r.fullText.Store(full)
+}
+
+// Enabled reports whether debug mode is on
+func (r *DebugRecorder) Enabled() bool {
+	return r != nil && r.enabled.Load()
+}
+
+// Last returns the most recent exchange, nil if none was recorded
+func (r *DebugRecorder) Last() *AIExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return nil
+	}
+	last := *r.last
+	return &last
+}
+
+// LastCompletionRequest returns a copy of the last recorded completion
+// request, for replaying it
+func (r *DebugRecorder) LastCompletionRequest() *CompletionRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastRequest == nil {
+		return nil
+	}
+	req := *r.lastRequest
+	req.Messages = append([]ChatMessage(nil), req.Messages...)
+	return &req
+}
+
+// remember keeps a copy of req for LastCompletionRequest
+func (r *DebugRecorder) remember(req *CompletionRequest) {
+	copied := *req
+	copied.Messages = append([]ChatMessage(nil), req.Messages...)
+	r.mu.Lock()
+	r.lastRequest = &copied
+	r.mu.Unlock()
+}
+
+// record sanitizes and stores an exchange; request and response are
+// marshalled to JSON
+func (r *DebugRecorder) record(ctx context.Context, ex AIExchange, request, response interface{}, err error) {
+	ex.Operation = operationFrom(ctx, "")
+	ex.Request = debugJSON(request)
+	if response != nil {
+		ex.Response = debugJSON(response)
+	}
+	if err != nil {
+		ex.Error = logger.RedactText(err.Error())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = &ex
+	if r.writer != nil {
+		line, _ := json.Marshal(ex)
+		if _, werr := r.writer.Write(append(line, '\n')); werr != nil {
+			logger.Warn("Failed to write AI debug log: %v", werr)
+		}
+	}
+}
+
+// debugJSON marshals v with long texts cut short and secrets masked
+func debugJSON(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(err.Error())
+	}
+	data = []byte(logger.RedactText(string(data)))
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(data))
+	}
+	return data
+}
+
+// debugText returns text as recorded: cut to maxDebugTextRunes in full
+// text mode, otherwise a short preview with its hash and length
+func (r *DebugRecorder) debugText(text string) string {
+	runes := []rune(text)
+	if r.fullText.Load() {
+		if len(runes) > maxDebugTextRunes {
+			return string(runes[:maxDebugTextRunes]) + fmt.Sprintf("… [%d more characters]", len(runes)-maxDebugTextRunes)
+		}
+		return text
+	}
+	if len(runes) <= debugPreviewRunes {
+		return text
+	}
+	sum := sha256.Sum256([]byte(text))
+	return string(runes[:debugPreviewRunes]) + fmt.Sprintf("… [%d characters, sha256:%s]", len(runes), hex.EncodeToString(sum[:6]))
+}
+
+func (r *DebugRecorder) debugTexts(texts []string) []string {
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = r.debugText(t)
+	}
+	return out
+}
+
+// debugCompletionRequest is a CompletionRequest with its messages as recorded
+func (r *DebugRecorder) debugCompletionRequest(req *CompletionRequest) *CompletionRequest {
+	copied := *req
+	copied.Messages = make([]ChatMessage, len(req.Messages))
+	for i, m := range req.Messages {
+		m.Content = r.debugText(m.Content)
+		copied.Messages[i] = m
+	}
+	return &copied
+}
+
+// debugCompletionResponse is a CompletionResponse with its text as recorded
+func (r *DebugRecorder) debugCompletionResponse(resp *CompletionResponse) *CompletionResponse {
+	copied := *resp
+	copied.Content = r.debugText(resp.Content)
+	return &copied
+}
+
+// debugEmbedding describes an embedding without its vector
+type debugEmbedding struct {
+	Model     string `json:"model,omitempty"`
+	Dimension int    `json:"dimension"`
+}
+
+func debugEmbeddings(resps ...*EmbeddingResponse) []debugEmbedding {
+	out := make([]debugEmbedding, 0, len(resps))
+	for _, resp := range resps {
+		if resp != nil {
+			out = append(out, debugEmbedding{Model: resp.Model, Dimension: len(resp.Embedding)})
+		}
+	}
+	return out
+}
+
+// debugEmbeddingProvider records embedding calls while debug mode is on
+type debugEmbeddingProvider struct {
+	EmbeddingProvider
+	name     string
+	recorder *DebugRecorder
+}
+
+// WithEmbeddingDebug wraps provider so its calls are recorded by recorder
+// while debug mode is on
+func WithEmbeddingDebug(provider EmbeddingProvider, name string, recorder *DebugRecorder) EmbeddingProvider {
+	if recorder == nil {
+		return provider
+	}
+	return &debugEmbeddingProvider{EmbeddingProvider: provider, name: name, recorder: recorder}
+}
+
+func (p *debugEmbeddingProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if !p.recorder.Enabled() {
+		return p.EmbeddingProvider.GenerateEmbedding(ctx, req)
+	}
+	start := time.Now()
+	resp, err := p.EmbeddingProvider.GenerateEmbedding(ctx, req)
+	ex := AIExchange{Time: start.UnixMilli(), Kind: ExchangeEmbedding, Provider: p.name, Model: req.Model, DurationMs: time.Since(start).Milliseconds()}
+	var response interface{}
+	if resp != nil {
+		response = debugEmbeddings(resp)
+	}
+	p.recorder.record(ctx, ex, map[string]interface{}{"model": req.Model, "texts": p.recorder.debugTexts([]string{req.Text})}, response, err)
+	return resp, err
+}
+
+func (p *debugEmbeddingProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	if !p.recorder.Enabled() {
+		return p.EmbeddingProvider.GenerateEmbeddingsBatch(ctx, texts)
+	}
+	start := time.Now()
+	resps, err := p.EmbeddingProvider.GenerateEmbeddingsBatch(ctx, texts)
+	ex := AIExchange{Time: start.UnixMilli(), Kind: ExchangeEmbedding, Provider: p.name, Model: batchModel(ctx, p.GetDefaultModel()), DurationMs: time.Since(start).Milliseconds()}
+	var response interface{}
+	if resps != nil {
+		response = debugEmbeddings(resps...)
+	}
+	p.recorder.record(ctx, ex, map[string]interface{}{"texts": p.recorder.debugTexts(texts)}, response, err)
+	return resps, err
+}
+
+// debugLLMProvider records completions while debug mode is on
+type debugLLMProvider struct {
+	LLMProvider
+	name     string
+	recorder *DebugRecorder
+}
+
+// WithLLMDebug wraps provider so its completions are recorded by recorder
+// while debug mode is on
+func WithLLMDebug(provider LLMProvider, name string, recorder *DebugRecorder) LLMProvider {
+	if recorder == nil {
+		return provider
+	}
+	return &debugLLMProvider{LLMProvider: provider, name: name, recorder: recorder}
+}
+
+func (p *debugLLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	if !p.recorder.Enabled() {
+		return p.LLMProvider.GenerateCompletion(ctx, req)
+	}
+	start := time.Now()
+	resp, err := p.LLMProvider.GenerateCompletion(ctx, req)
+	ex := AIExchange{Time: start.UnixMilli(), Kind: ExchangeCompletion, Provider: p.name, Model: req.Model, DurationMs: time.Since(start).Milliseconds()}
+	var response interface{}
+	if resp != nil {
+		response = p.recorder.debugCompletionResponse(resp)
+	}
+	p.recorder.remember(req)
+	p.recorder.record(ctx, ex, p.recorder.debugCompletionRequest(req), response, err)
+	return resp, err
+}
+
+// GenerateCompletionStream records the streamed text as a whole once the
+// stream ends
+func (p *debugLLMProvider) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
+	if !p.recorder.Enabled() {
+		return p.LLMProvider.GenerateCompletionStream(ctx, req)
+	}
+	start := time.Now()
+	p.recorder.remember(req)
+	in, err := p.LLMProvider.GenerateCompletionStream(ctx, req)
+	if err != nil {
+		ex := AIExchange{Time: start.UnixMilli(), Kind: ExchangeCompletionStream, Provider: p.name, Model: req.Model}
+		p.recorder.record(ctx, ex, p.recorder.debugCompletionRequest(req), nil, err)
+		return nil, err
+	}
+	out := make(chan *CompletionChunk, cap(in))
+	go func() {
+		defer close(out)
+		var content strings.Builder
+		var streamErr error
+		var toolCalls []ToolCall
+		defer func() {
+			ex := AIExchange{Time: start.UnixMilli(), Kind: ExchangeCompletionStream, Provider: p.name, Model: req.Model, DurationMs: time.Since(start).Milliseconds()}
+			response := &CompletionResponse{Content: p.recorder.debugText(content.String()), Model: req.Model, ToolCalls: toolCalls}
+			p.recorder.record(ctx, ex, p.recorder.debugCompletionRequest(req), response, streamErr)
+		}()
+		for chunk := range in {
+			content.WriteString(chunk.Content)
+			if chunk.Error != nil && streamErr == nil {
+				streamErr = chunk.Error
+			}
+			if len(chunk.ToolCalls) > 0 {
+				toolCalls = chunk.ToolCalls
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugRecorderRecordsPreviewsByDefault(t *testing.T) {
+	dir := t.TempDir()
+	recorder := &DebugRecorder{}
+	if err := recorder.SetEnabled(true, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.SetEnabled(false, dir)
+
+	private := strings.Repeat("my private journal entry ", 20)
+	llm := WithLLMDebug(&flakyLLM{}, "test", recorder)
+	req := &CompletionRequest{Model: "m", Messages: []ChatMessage{
+		{Role: "system", Content: "Use the key sk-abcdefghijklmnopqrstuvwxyz123456"},
+		{Role: "user", Content: private},
+	}}
+	if _, err := llm.GenerateCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	last := recorder.Last()
+	if last == nil || last.Kind != ExchangeCompletion || last.Provider != "test" {
+		t.Fatalf("unexpected exchange: %+v", last)
+	}
+	request := string(last.Request)
+	if strings.Contains(request, private) {
+		t.Fatal("whole prompt recorded without full text mode")
+	}
+	if !strings.Contains(request, "sha256:") || !strings.Contains(request, "500 characters") {
+		t.Fatalf("expected a preview with hash and length, got %s", request)
+	}
+	if strings.Contains(request, "sk-abcdefghijklmnopqrstuvwxyz123456") {
+		t.Fatal("API key not redacted")
+	}
+
+	// The replayable copy keeps the whole request and is independent of req
+	replay := recorder.LastCompletionRequest()
+	req.Messages[1].Content = "changed"
+	if replay == nil || replay.Messages[1].Content != private {
+		t.Fatalf("replay request not kept whole: %+v", replay)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, aiDebugLogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || strings.Contains(string(data), private) {
+		t.Fatalf("unexpected debug log (%d lines)", lines)
+	}
+}
+
+func TestDebugRecorderFullText(t *testing.T) {
+	recorder := &DebugRecorder{}
+	recorder.SetFullText(true)
+
+	text := strings.Repeat("a", 100)
+	if got := recorder.debugText(text); got != text {
+		t.Fatalf("full text mode changed a short text: %q", got)
+	}
+	long := strings.Repeat("b", maxDebugTextRunes+10)
+	if got := recorder.debugText(long); !strings.HasPrefix(got, long[:maxDebugTextRunes]) || !strings.HasSuffix(got, "[10 more characters]") {
+		t.Fatalf("long text not truncated: %q", got[len(got)-40:])
+	}
+
+	recorder.SetFullText(false)
+	if got := recorder.debugText("short"); got != "short" {
+		t.Fatalf("short text changed: %q", got)
+	}
+	if a, b := recorder.debugText(text+"x"), recorder.debugText(text+"y"); a == b {
+		t.Fatal("different texts recorded alike")
+	}
+}
+
+func TestDebugRecorderOffRecordsNothing(t *testing.T) {
+	recorder := &DebugRecorder{}
+	llm := WithLLMDebug(&flakyLLM{}, "test", recorder)
+	if _, err := llm.GenerateCompletion(context.Background(), &CompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Last() != nil || recorder.LastCompletionRequest() != nil {
+		t.Fatal("exchange recorded while debug mode is off")
+	}
+}
@@ -33,6 +33,9 @@ var knownModelDimensions = map[string]int{
 	"phi":               2048,
 	"gemma":             2048,
 	"gemma2":            2048,
+
+	// Builtin provider
+	"all-MiniLM-L6-v2": 384,
 }
 
 // LookupModelDimension returns the embedding dimension for a known model.
@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+// healthCheckTimeout bounds a single provider ping
+const healthCheckTimeout = 5 * time.Second
+
+// ErrProviderUnhealthy is returned for embedding work skipped because the
+// current provider failed its last health check
+var ErrProviderUnhealthy = errors.New("embedding provider is unavailable")
+
+// ProviderHealth is the latest health check result for an embedding provider
+type ProviderHealth struct {
+	Provider  string `json:"provider"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	CheckedAt int64  `json:"checked_at"` // Unix ms
+	Since     int64  `json:"since"`      // Unix ms when the current state began
+}
+
+// HealthChecker is implemented by providers that can be pinged. Providers
+// without it are checked with ValidateConfig.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// healthMonitor caches provider health and notifies listeners of transitions
+type healthMonitor struct {
+	mu        sync.Mutex
+	status    map[string]ProviderHealth
+	listeners map[int]func(ProviderHealth)
+	nextID    int
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// StartHealthMonitor pings every registered provider now and then every
+// interval until StopHealthMonitor is called
+func (s *Service) StartHealthMonitor(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	h := &s.health
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	done := h.done
+	h.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.CheckHealthNow(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// StopHealthMonitor stops periodic checks and waits for the current one to end
+func (s *Service) StopHealthMonitor() {
+	h := &s.health
+	h.mu.Lock()
+	cancel, done := h.cancel, h.done
+	h.cancel, h.done = nil, nil
+	h.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// CheckHealthNow pings every registered provider and returns the results
+func (s *Service) CheckHealthNow(ctx context.Context) []ProviderHealth {
+	s.mu.RLock()
+	providers := make(map[string]EmbeddingProvider, len(s.providers))
+	for name, p := range s.providers {
+		providers[name] = p
+	}
+	s.mu.RUnlock()
+
+	type result struct {
+		name    string
+		err     error
+		latency time.Duration
+	}
+	results := make(chan result, len(providers))
+	for name, p := range providers {
+		go func() {
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := checkProviderHealth(checkCtx, p)
+			results <- result{name: name, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	h := &s.health
+	var changed []ProviderHealth
+	now := time.Now().UnixMilli()
+	h.mu.Lock()
+	previous := h.status
+	h.status = make(map[string]ProviderHealth, len(providers))
+	for range providers {
+		r := <-results
+		if ctx.Err() != nil {
+			// Shutting down: keep the last known state rather than reporting a false outage
+			if prev, ok := previous[r.name]; ok {
+				h.status[r.name] = prev
+			}
+			continue
+		}
+		status := ProviderHealth{
+			Provider:  r.name,
+			Healthy:   r.err == nil,
+			LatencyMS: r.latency.Milliseconds(),
+			CheckedAt: now,
+			Since:     now,
+		}
+		if r.err != nil {
+			status.Error = r.err.Error()
+		}
+		prev, known := previous[r.name]
+		if known && prev.Healthy == status.Healthy {
+			status.Since = prev.Since
+		} else if known || !status.Healthy {
+			changed = append(changed, status)
+		}
+		h.status[r.name] = status
+	}
+	listeners := make([]func(ProviderHealth), 0, len(h.listeners))
+	for _, fn := range h.listeners {
+		listeners = append(listeners, fn)
+	}
+	h.mu.Unlock()
+
+	for _, status := range changed {
+		logger.InfoWithFields(ctx, map[string]interface{}{
+			"provider": status.Provider,
+			"healthy":  status.Healthy,
+			"error":    status.Error,
+		}, "Embedding provider health changed")
+		for _, fn := range listeners {
+			fn(status)
+		}
+	}
+	return s.GetProviderHealth()
+}
+
+// GetProviderHealth returns the cached health of every checked provider
+func (s *Service) GetProviderHealth() []ProviderHealth {
+	h := &s.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ProviderHealth, 0, len(h.status))
+	for _, status := range h.status {
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+// IsHealthy reports whether the current provider passed its last health check.
+// A provider that has not been checked yet counts as healthy.
+func (s *Service) IsHealthy() bool {
+	s.mu.RLock()
+	current := s.currentProvider
+	s.mu.RUnlock()
+	healthy, _ := s.cachedHealth(current)
+	return healthy
+}
+
+// cachedHealth returns the last check result for provider and whether one exists
+func (s *Service) cachedHealth(provider string) (healthy, known bool) {
+	h := &s.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status, ok := h.status[provider]
+	if !ok {
+		return true, false
+	}
+	return status.Healthy, true
+}
+
+// OnHealthChange registers fn to be called when a provider becomes healthy or
+// unhealthy; the returned function unregisters it
+func (s *Service) OnHealthChange(fn func(ProviderHealth)) func() {
+	h := &s.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listeners == nil {
+		h.listeners = make(map[int]func(ProviderHealth))
+	}
+	id := h.nextID
+	h.nextID++
+	h.listeners[id] = fn
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.listeners, id)
+	}
+}
+
+// CurrentProviderName returns the name of the active embedding provider
+func (s *Service) CurrentProviderName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.currentProvider
+}
+
+// checkProviderHealth pings the innermost provider beneath any wrappers
+func checkProviderHealth(ctx context.Context, p EmbeddingProvider) error {
+	for {
+		if checker, ok := p.(HealthChecker); ok {
+			return checker.CheckHealth(ctx)
+		}
+		wrapper, ok := p.(interface{ Unwrap() EmbeddingProvider })
+		if !ok {
+			return p.ValidateConfig()
+		}
+		p = wrapper.Unwrap()
+	}
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"notebit/pkg/config"
+)
+
+// modelsServer answers GET /models with status, or a model list for 200
+func modelsServer(t *testing.T, status *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		code := int(status.Load())
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			_, _ = w.Write([]byte(`{"data": [{"id": "m"}]}`))
+		} else {
+			_, _ = w.Write([]byte(`{"error": {"message": "nope"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAICheckHealth(t *testing.T) {
+	var status atomic.Int32
+	srv := modelsServer(t, &status)
+	provider, err := NewCustomProvider(OpenAIConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status  int
+		healthy bool
+	}{
+		{http.StatusOK, true},
+		// Servers without GET /models are still reachable
+		{http.StatusNotFound, true},
+		{http.StatusMethodNotAllowed, true},
+		{http.StatusUnauthorized, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		status.Store(int32(tt.status))
+		err := provider.CheckHealth(context.Background())
+		if (err == nil) != tt.healthy {
+			t.Errorf("status %d: CheckHealth = %v, want healthy %v", tt.status, err, tt.healthy)
+		}
+	}
+	status.Store(http.StatusUnauthorized)
+	if err := provider.CheckHealth(context.Background()); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("401 error = %v, want ErrAuthFailed", err)
+	}
+
+	srv.Close()
+	if err := provider.CheckHealth(context.Background()); err == nil {
+		t.Error("CheckHealth of a stopped server succeeded")
+	}
+}
+
+func TestCheckHealthNowReportsTransitions(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := modelsServer(t, &status)
+	provider, err := NewCustomProvider(OpenAIConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Health is checked on the provider beneath wrappers
+	limiter := NewRateLimiter("custom", config.RateLimitConfig{MaxConcurrency: 1})
+	s := &Service{
+		providers:       map[string]EmbeddingProvider{"custom": WithEmbeddingRateLimit(provider, limiter)},
+		currentProvider: "custom",
+	}
+	var changes []ProviderHealth
+	s.OnHealthChange(func(h ProviderHealth) { changes = append(changes, h) })
+
+	ctx := context.Background()
+	if !s.IsHealthy() {
+		t.Fatal("an unchecked provider should count as healthy")
+	}
+	s.CheckHealthNow(ctx)
+	if !s.IsHealthy() || len(changes) != 0 {
+		t.Fatalf("healthy at first check: healthy %v, changes %+v", s.IsHealthy(), changes)
+	}
+
+	status.Store(http.StatusBadGateway)
+	s.CheckHealthNow(ctx)
+	s.CheckHealthNow(ctx)
+	if s.IsHealthy() || len(changes) != 1 || changes[0].Healthy || changes[0].Error == "" {
+		t.Fatalf("after an outage: healthy %v, changes %+v", s.IsHealthy(), changes)
+	}
+
+	status.Store(http.StatusOK)
+	health := s.CheckHealthNow(ctx)
+	if !s.IsHealthy() || len(changes) != 2 || !changes[1].Healthy {
+		t.Fatalf("after recovery: healthy %v, changes %+v", s.IsHealthy(), changes)
+	}
+	if len(health) != 1 || health[0].Provider != "custom" || !health[0].Healthy {
+		t.Fatalf("health = %+v", health)
+	}
+}
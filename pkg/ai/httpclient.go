@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+// sharedTransport is the transport of every provider client. Clients hold
+// networkTransport, which reads it per request, so applying a new network
+// config takes effect without recreating providers.
+var sharedTransport atomic.Pointer[http.Transport]
+
+func init() {
+	sharedTransport.Store(http.DefaultTransport.(*http.Transport).Clone())
+}
+
+type networkTransport struct{}
+
+func (networkTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return sharedTransport.Load().RoundTrip(req)
+}
+
+// NewHTTPClient returns a client for provider requests honoring the network
+// config last passed to ApplyNetworkConfig. A zero timeout means none.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: networkTransport{}}
+}
+
+// ApplyNetworkConfig builds the proxy and TLS settings used by all provider
+// clients. The previous settings stay in place if cfg is invalid.
+func ApplyNetworkConfig(cfg config.NetworkConfig) error {
+	transport, err := buildTransport(cfg)
+	if err != nil {
+		return err
+	}
+	if old := sharedTransport.Swap(transport); old != nil {
+		old.CloseIdleConnections()
+	}
+	return nil
+}
+
+func buildTransport(cfg config.NetworkConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy := strings.TrimSpace(cfg.ProxyURL); proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CABundlePath == "" && !cfg.InsecureSkipVerify {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CABundlePath != "" {
+		pem, err := os.ReadFile(cfg.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CABundlePath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notebit/pkg/config"
+)
+
+func TestBuildTransport_TLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// The rejected handshake is expected, keep it out of the test output
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.NetworkConfig
+		buildErr string
+		trusted  bool
+	}{
+		{name: "system roots only", cfg: config.NetworkConfig{}},
+		{name: "CA bundle", cfg: config.NetworkConfig{CABundlePath: bundle}, trusted: true},
+		{name: "skip verify", cfg: config.NetworkConfig{InsecureSkipVerify: true}, trusted: true},
+		{name: "missing bundle", cfg: config.NetworkConfig{CABundlePath: filepath.Join(dir, "none.pem")}, buildErr: "failed to read CA bundle"},
+		{name: "bundle without certificates", cfg: config.NetworkConfig{CABundlePath: empty}, buildErr: "no certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := buildTransport(tt.cfg)
+			if tt.buildErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.buildErr) {
+					t.Fatalf("buildTransport error = %v, want %q", err, tt.buildErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer transport.CloseIdleConnections()
+			resp, err := (&http.Client{Transport: transport}).Get(srv.URL)
+			if tt.trusted {
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				resp.Body.Close()
+			} else if err == nil {
+				resp.Body.Close()
+				t.Fatal("request to an untrusted server succeeded")
+			}
+		})
+	}
+}
+
+func TestBuildTransport_Proxy(t *testing.T) {
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	transport, err := buildTransport(config.NetworkConfig{ProxyURL: " " + proxy.URL + " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.CloseIdleConnections()
+	resp, err := (&http.Client{Transport: transport}).Get("http://api.example.invalid/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := <-proxied; got != "http://api.example.invalid/v1/models" {
+		t.Errorf("proxy saw %q", got)
+	}
+
+	for _, bad := range []string{"not a url", "://missing-scheme", "http://"} {
+		if _, err := buildTransport(config.NetworkConfig{ProxyURL: bad}); err == nil {
+			t.Errorf("buildTransport(proxy %q) succeeded", bad)
+		}
+	}
+}
+
+func TestApplyNetworkConfig_KeepsPreviousOnError(t *testing.T) {
+	before := sharedTransport.Load()
+	if err := ApplyNetworkConfig(config.NetworkConfig{ProxyURL: "http://"}); err == nil {
+		t.Fatal("ApplyNetworkConfig accepted an invalid proxy")
+	}
+	if sharedTransport.Load() != before {
+		t.Error("invalid config replaced the shared transport")
+	}
+}
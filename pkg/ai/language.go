@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLanguageLetters is how many letters a text needs before its language is
+// guessed at all
+const minLanguageLetters = 3
+
+// latinStopwords are frequent short words that tell Latin-script languages apart
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "with", "for", "this", "are"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "zu", "auf", "ich"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "pas", "que", "pour", "dans", "du"},
+	"es": {"el", "los", "las", "y", "es", "que", "una", "por", "con", "para", "del", "como"},
+	"pt": {"o", "os", "e", "não", "que", "uma", "com", "para", "do", "da", "em", "por"},
+	"it": {"il", "gli", "e", "è", "che", "una", "non", "per", "con", "del", "della", "sono"},
+}
+
+// DetectLanguage guesses the ISO 639-1 language of text from its script, and
+// for Latin script from common words. It returns "" when text has too few
+// letters to tell.
+func DetectLanguage(text string) string {
+	var letters, han, kana, hangul, cyrillic, arabic, hebrew, greek, thai, devanagari, latin int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if letters < minLanguageLetters {
+		return ""
+	}
+
+	// CJK characters carry a word each, so a modest share outweighs the Latin
+	// letters of code and names mixed into the text
+	cjk := han + kana + hangul
+	if cjk*4 >= letters {
+		switch {
+		case kana > 0 && kana*10 >= cjk:
+			return "ja"
+		case hangul > han:
+			return "ko"
+		default:
+			return "zh"
+		}
+	}
+
+	scripts := []struct {
+		count int
+		lang  string
+	}{
+		{cyrillic, "ru"}, {arabic, "ar"}, {hebrew, "he"}, {greek, "el"}, {thai, "th"}, {devanagari, "hi"},
+	}
+	for _, s := range scripts {
+		if s.count*2 > letters {
+			return s.lang
+		}
+	}
+	if latin*2 > letters {
+		return detectLatinLanguage(text)
+	}
+	return ""
+}
+
+// detectLatinLanguage picks the language whose stopwords occur most often,
+// defaulting to English
+func detectLatinLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		counts[word]++
+	}
+	best, bestScore := "en", 0
+	for _, lang := range []string{"en", "de", "fr", "es", "pt", "it"} {
+		score := 0
+		for _, w := range latinStopwords[lang] {
+			score += counts[w]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	return best
+}
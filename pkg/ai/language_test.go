@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"ok", ""},
+		{"12345 !!", ""},
+		{"知识管理是一种方法", "zh"},
+		{"これは日本語のノートです", "ja"},
+		{"東京で会議があります", "ja"},
+		{"이것은 한국어 노트입니다", "ko"},
+		{"Это заметка на русском языке", "ru"},
+		{"هذه ملاحظة باللغة العربية", "ar"},
+		{"This is a note about the garden and the house", "en"},
+		{"Das ist nicht die Notiz, und der Garten ist mit einer Mauer", "de"},
+		{"Le jardin est dans la maison et les fleurs pour la table", "fr"},
+		{"Zettelkasten", "en"},
+		// Code and names mixed into Chinese text do not outvote it
+		{"使用 func main() 函数启动程序 golang", "zh"},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBatchEmbeddingsUseRequestedModel(t *testing.T) {
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		models = append(models, body.Model)
+		data := make([]map[string]any, len(body.Input))
+		for i := range body.Input {
+			data[i] = map[string]any{"index": i, "embedding": []float32{1, 0}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"model": body.Model, "data": data})
+	}))
+	defer srv.Close()
+
+	provider, err := NewOpenAIProvider(OpenAIConfig{APIKey: "key", BaseURL: srv.URL, EmbeddingModel: "default-model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := provider.GenerateEmbeddingsBatch(ctx, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	resps, err := provider.GenerateEmbeddingsBatch(withBatchModel(ctx, "language-model"), []string{"a", "b"})
+	if err != nil || len(resps) != 2 {
+		t.Fatalf("batch = %v, %v", resps, err)
+	}
+	if len(models) != 2 || models[0] != "default-model" || models[1] != "language-model" {
+		t.Fatalf("requested models = %v", models)
+	}
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
@@ -9,11 +10,12 @@ import (
 // This interface extends the AI service capabilities from embeddings to text generation
 type LLMProvider interface {
 	// GenerateCompletion generates a text completion
-	GenerateCompletion(req *CompletionRequest) (*CompletionResponse, error)
+	GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error)
 
 	// GenerateCompletionStream generates a streaming completion
-	// Returns a channel that receives chunks as they are generated
-	GenerateCompletionStream(req *CompletionRequest) (<-chan *CompletionChunk, error)
+	// Returns a channel that receives chunks as they are generated; the channel is
+	// closed when the stream ends or ctx is cancelled
+	GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error)
 
 	// GetAvailableModels returns a list of available models
 	GetAvailableModels() ([]string, error)
@@ -35,12 +37,59 @@ type CompletionRequest struct {
 	Temperature float32      `json:"temperature"`
 	MaxTokens   int          `json:"max_tokens"`
 	Stream      bool         `json:"stream"`
+	// Tools the model may call instead of (or before) answering
+	Tools []Tool `json:"tools,omitempty"`
+	// ToolChoice is "auto" (default), "none" or "required"
+	ToolChoice string `json:"tool_choice,omitempty"`
 }
 
 // ChatMessage represents a message in a chat conversation
 type ChatMessage struct {
-	Role    string `json:"role"`    // "system", "user", "assistant"
+	Role    string `json:"role"`    // "system", "user", "assistant", "tool"
 	Content string `json:"content"`
+	// ToolCalls are the calls an assistant message requested
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a "tool" message to the call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is a callable function and its JSON Schema parameters
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// NewFunctionTool builds a Tool from a name, description and JSON Schema
+func NewFunctionTool(name, description, parametersSchema string) Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters:  json.RawMessage(parametersSchema),
+		},
+	}
+}
+
+// ToolCall is a model's request to call a tool
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // always "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the function to call; Arguments is a JSON object
+// produced by the model and may be malformed
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // CompletionResponse represents a response from LLM
@@ -49,6 +98,7 @@ type CompletionResponse struct {
 	Model        string      `json:"model"`
 	TokensUsed   *TokenUsage `json:"tokens_used,omitempty"`
 	FinishReason string      `json:"finish_reason,omitempty"`
+	ToolCalls    []ToolCall  `json:"tool_calls,omitempty"`
 }
 
 // CompletionChunk represents a streaming chunk from LLM
@@ -56,6 +106,8 @@ type CompletionChunk struct {
 	Content string `json:"content"`
 	Done    bool   `json:"done"`
 	Error   error  `json:"error,omitempty"`
+	// ToolCalls is set on the final chunk when the model requested tool calls
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
 // TokenUsage represents token usage statistics
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of provider errors, matched with errors.Is
+var (
+	ErrRateLimited         = errors.New("provider rate limit exceeded")
+	ErrAuthFailed          = errors.New("provider rejected the credentials")
+	ErrContextTooLong      = errors.New("prompt exceeds the model's context window")
+	ErrProviderUnavailable = errors.New("provider is temporarily unavailable")
+)
+
+// Is makes a RateLimitError match ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// APIError is an unsuccessful response from a provider API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// Is matches the error kind the status code and message stand for
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAuthFailed:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrContextTooLong:
+		msg := strings.ToLower(e.Message)
+		return (e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusRequestEntityTooLarge) &&
+			(strings.Contains(msg, "context_length_exceeded") || strings.Contains(msg, "context length") || strings.Contains(msg, "too many tokens"))
+	case ErrProviderUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// newAPIError builds the error for an unsuccessful response
+func newAPIError(provider string, resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(provider, resp, body)
+	}
+	return &APIError{Provider: provider, StatusCode: resp.StatusCode, Message: apiErrorMessage(body)}
+}
+
+// apiErrorMessage returns the message of an OpenAI or Ollama style error
+// body, or the body itself
+func apiErrorMessage(body []byte) string {
+	var openAIErr openAIErrorResponse
+	if err := json.Unmarshal(body, &openAIErr); err == nil && openAIErr.Error.Message != "" {
+		return fmt.Sprintf("%s (type: %s, code: %s)", openAIErr.Error.Message, openAIErr.Error.Type, openAIErr.Error.Code)
+	}
+	var ollamaErr ollamaErrorResponse
+	if err := json.Unmarshal(body, &ollamaErr); err == nil && ollamaErr.Error != "" {
+		return ollamaErr.Error
+	}
+	return string(body)
+}
+
+// transportError is a request that failed before a response arrived
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return fmt.Sprintf("request failed: %v", e.err) }
+func (e *transportError) Unwrap() error { return e.err }
+
+// retryableCompletionError reports whether a failed completion may succeed
+// when sent again
+func retryableCompletionError(err error) bool {
+	var te *transportError
+	if errors.As(err, &te) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrProviderUnavailable)
+}
+
+// retryableEmbeddingError reports whether a failed embedding request may
+// succeed when sent again. Unlike completions, failures of no known kind are
+// retried too, e.g. a batch that came back with embeddings missing.
+func retryableEmbeddingError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(err, ErrProviderUnavailable)
+	}
+	return true
+}
+
+// retryingLLMProvider retries completions that failed for transient reasons
+type retryingLLMProvider struct {
+	LLMProvider
+}
+
+// WithLLMRetry wraps provider so completions failing with a rate limit, a
+// server error or a network error are retried with jittered backoff,
+// honouring Retry-After. Wrap it around rate limiting so every attempt waits
+// for its turn.
+func WithLLMRetry(provider LLMProvider) LLMProvider {
+	return &retryingLLMProvider{LLMProvider: provider}
+}
+
+func (p *retryingLLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	var resp *CompletionResponse
+	err := retryWithBackoff(ctx, p.policy(), func() error {
+		var err error
+		resp, err = p.LLMProvider.GenerateCompletion(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateCompletionStream retries a stream that fails before delivering
+// anything; once text has been passed on, errors are returned as they come
+func (p *retryingLLMProvider) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
+	var in <-chan *CompletionChunk
+	var first *CompletionChunk
+	err := retryWithBackoff(ctx, p.policy(), func() error {
+		if in != nil {
+			// Let the failed stream's goroutine finish
+			for range in {
+			}
+		}
+		var err error
+		if in, err = p.LLMProvider.GenerateCompletionStream(ctx, req); err != nil {
+			in = nil
+			return err
+		}
+		var ok bool
+		if first, ok = <-in; !ok {
+			first = &CompletionChunk{Done: true}
+		}
+		return first.Error
+	})
+	if in == nil {
+		return nil, err
+	}
+	return prependChunk(ctx, first, in), nil
+}
+
+// policy is completionRetry, naming the provider in log messages
+func (p *retryingLLMProvider) policy() retryPolicy {
+	policy := completionRetry
+	policy.name = p.Name() + " completion"
+	return policy
+}
+
+// prependChunk returns a channel delivering first and then the rest of in
+func prependChunk(ctx context.Context, first *CompletionChunk, in <-chan *CompletionChunk) <-chan *CompletionChunk {
+	out := make(chan *CompletionChunk, cap(in)+1)
+	go func() {
+		defer close(out)
+		if first != nil {
+			select {
+			case out <- first:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for chunk := range in {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
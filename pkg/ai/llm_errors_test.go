@@ -0,0 +1,236 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"1.5", 1500 * time.Millisecond},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, want about a minute", date, got)
+	}
+}
+
+func TestAPIErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		kind   error
+	}{
+		{http.StatusUnauthorized, `{"error":{"message":"bad key","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrAuthFailed},
+		{http.StatusForbidden, `forbidden`, ErrAuthFailed},
+		{http.StatusBadRequest, `{"error":{"message":"too long","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrContextTooLong},
+		{http.StatusBadRequest, `{"error":"input exceeds the context length"}`, ErrContextTooLong},
+		{http.StatusBadGateway, `upstream down`, ErrProviderUnavailable},
+		{http.StatusTooManyRequests, `slow down`, ErrRateLimited},
+	}
+	kinds := []error{ErrAuthFailed, ErrContextTooLong, ErrProviderUnavailable, ErrRateLimited}
+	for _, tt := range tests {
+		err := newAPIError("test", &http.Response{StatusCode: tt.status, Header: http.Header{}}, []byte(tt.body))
+		for _, kind := range kinds {
+			if got := errors.Is(err, kind); got != (kind == tt.kind) {
+				t.Errorf("status %d %s: errors.Is(%v) = %v", tt.status, tt.body, kind, got)
+			}
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}}
+	var rle *RateLimitError
+	if err := newAPIError("test", resp, nil); !errors.As(err, &rle) || rle.RetryAfter != 7*time.Second {
+		t.Fatalf("expected a RateLimitError with Retry-After, got %v", err)
+	}
+
+	if msg := apiErrorMessage([]byte(`{"error":"model not found"}`)); msg != "model not found" {
+		t.Errorf("Ollama message = %q", msg)
+	}
+	if msg := apiErrorMessage([]byte(`<html>oops</html>`)); msg != "<html>oops</html>" {
+		t.Errorf("raw message = %q", msg)
+	}
+}
+
+func TestRetryableErrors(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	auth := &APIError{StatusCode: http.StatusUnauthorized}
+	tests := []struct {
+		name       string
+		err        error
+		completion bool
+		embedding  bool
+	}{
+		{"rate limited", &RateLimitError{}, true, true},
+		{"server error", fmt.Errorf("wrapped: %w", unavailable), true, true},
+		{"auth", auth, false, false},
+		{"network", &transportError{err: errors.New("connection reset")}, true, true},
+		{"canceled", &transportError{err: context.Canceled}, false, false},
+		{"deadline", context.DeadlineExceeded, false, false},
+		{"unknown", errors.New("2 of 3 texts failed to embed"), false, true},
+	}
+	for _, tt := range tests {
+		if got := retryableCompletionError(tt.err); got != tt.completion {
+			t.Errorf("%s: retryableCompletionError = %v", tt.name, got)
+		}
+		if got := retryableEmbeddingError(tt.err); got != tt.embedding {
+			t.Errorf("%s: retryableEmbeddingError = %v", tt.name, got)
+		}
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	policy := retryPolicy{name: "test", attempts: 3, backoff: time.Millisecond, maxWait: time.Second, retryable: retryableCompletionError}
+
+	calls := 0
+	err := retryWithBackoff(context.Background(), policy, func() error {
+		calls++
+		if calls < 3 {
+			return &APIError{StatusCode: http.StatusBadGateway}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("transient failures: err=%v calls=%d", err, calls)
+	}
+
+	calls = 0
+	err = retryWithBackoff(context.Background(), policy, func() error {
+		calls++
+		return &APIError{StatusCode: http.StatusUnauthorized}
+	})
+	if !errors.Is(err, ErrAuthFailed) || calls != 1 {
+		t.Fatalf("permanent failure: err=%v calls=%d", err, calls)
+	}
+
+	// A Retry-After beyond maxWait fails right away instead of waiting
+	calls = 0
+	err = retryWithBackoff(context.Background(), policy, func() error {
+		calls++
+		return &RateLimitError{RetryAfter: time.Minute}
+	})
+	if !errors.Is(err, ErrRateLimited) || calls != 1 {
+		t.Fatalf("long Retry-After: err=%v calls=%d", err, calls)
+	}
+
+	if wait, ok := policy.wait(&RateLimitError{RetryAfter: 200 * time.Millisecond}, 0); !ok || wait != 200*time.Millisecond {
+		t.Fatalf("Retry-After not honoured: %s %v", wait, ok)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := retryWithBackoff(ctx, policy, func() error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
+
+// flakyLLM fails its first failures calls with err, then answers
+type flakyLLM struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyLLM) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &CompletionResponse{Content: "answer"}, nil
+}
+
+func (f *flakyLLM) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
+	f.calls++
+	out := make(chan *CompletionChunk, 2)
+	if f.calls <= f.failures {
+		out <- &CompletionChunk{Error: f.err, Done: true}
+	} else {
+		out <- &CompletionChunk{Content: "answer"}
+		out <- &CompletionChunk{Done: true}
+	}
+	close(out)
+	return out, nil
+}
+
+func (f *flakyLLM) GetAvailableModels() ([]string, error) { return nil, nil }
+func (f *flakyLLM) GetDefaultModel() string               { return "flaky" }
+func (f *flakyLLM) ValidateConfig() error                 { return nil }
+func (f *flakyLLM) Name() string                          { return "flaky" }
+
+func TestRetryingLLMProvider(t *testing.T) {
+	defer func(p retryPolicy) { completionRetry = p }(completionRetry)
+	completionRetry.backoff = time.Millisecond
+
+	flaky := &flakyLLM{failures: 2, err: &APIError{StatusCode: http.StatusServiceUnavailable}}
+	resp, err := WithLLMRetry(flaky).GenerateCompletion(context.Background(), &CompletionRequest{})
+	if err != nil || resp.Content != "answer" || flaky.calls != 3 {
+		t.Fatalf("completion: resp=%v err=%v calls=%d", resp, err, flaky.calls)
+	}
+
+	flaky = &flakyLLM{failures: 1, err: &transportError{err: errors.New("connection reset")}}
+	stream, err := WithLLMRetry(flaky).GenerateCompletionStream(context.Background(), &CompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	for chunk := range stream {
+		if chunk.Error != nil {
+			t.Fatalf("stream error after retry: %v", chunk.Error)
+		}
+		text += chunk.Content
+	}
+	if text != "answer" || flaky.calls != 2 {
+		t.Fatalf("stream: text=%q calls=%d", text, flaky.calls)
+	}
+
+	// Streams failing for good deliver the error as a chunk
+	flaky = &flakyLLM{failures: 5, err: &APIError{StatusCode: http.StatusUnauthorized}}
+	stream, err = WithLLMRetry(flaky).GenerateCompletionStream(context.Background(), &CompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := <-stream
+	if first == nil || !errors.Is(first.Error, ErrAuthFailed) || flaky.calls != 1 {
+		t.Fatalf("permanent stream failure: chunk=%+v calls=%d", first, flaky.calls)
+	}
+}
+
+func TestEmbeddingErrorsAreTyped(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`)
+	}))
+	defer srv.Close()
+
+	provider, err := NewOpenAIProvider(OpenAIConfig{APIKey: "bad", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.GenerateEmbedding(context.Background(), &EmbeddingRequest{Text: "hello"})
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected ErrAuthFailed, got %v", err)
+	}
+	if retryableEmbeddingError(err) {
+		t.Fatal("a rejected key must not be retried")
+	}
+}
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+// CompletionCache keeps the replies of deterministic completions, keyed by a
+// hash of the request, for a limited time and number of entries
+type CompletionCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List // front is the most recently used
+	hits       int64
+	misses     int64
+}
+
+type completionCacheEntry struct {
+	key      string
+	response CompletionResponse
+	expires  time.Time
+}
+
+// CompletionCacheStats describes the state of a CompletionCache
+type CompletionCacheStats struct {
+	Entries    int   `json:"entries"`
+	MaxEntries int   `json:"max_entries"`
+	TTLMinutes int   `json:"ttl_minutes"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+}
+
+// NewCompletionCache creates a cache for cfg, or returns nil if it is disabled
+func NewCompletionCache(cfg config.LLMCacheConfig) *CompletionCache {
+	if !cfg.Enabled || cfg.TTLMinutes <= 0 || cfg.MaxEntries <= 0 {
+		return nil
+	}
+	return &CompletionCache{
+		ttl:        time.Duration(cfg.TTLMinutes) * time.Minute,
+		maxEntries: cfg.MaxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// cacheable reports whether req always gets the same reply: sampling is off
+// and the model cannot call tools with side effects
+func cacheable(req *CompletionRequest) bool {
+	return req.Temperature == 0 && len(req.Tools) == 0
+}
+
+// completionKey hashes everything in req that shapes the reply, along with
+// the provider and the model it resolves to, so replies never cross over
+// when the provider or its default model changes
+func completionKey(provider LLMProvider, req *CompletionRequest) string {
+	model := req.Model
+	if model == "" {
+		model = provider.GetDefaultModel()
+	}
+	data, _ := json.Marshal(struct {
+		Provider  string        `json:"provider"`
+		Model     string        `json:"model"`
+		Messages  []ChatMessage `json:"messages"`
+		MaxTokens int           `json:"max_tokens"`
+	}{provider.Name(), model, req.Messages, req.MaxTokens})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *CompletionCache) get(key string) (*CompletionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if ok && time.Now().After(elem.Value.(*completionCacheEntry).expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	resp := elem.Value.(*completionCacheEntry).response
+	return &resp, true
+}
+
+func (c *CompletionCache) put(key string, resp *CompletionResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &completionCacheEntry{key: key, response: *resp, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*completionCacheEntry).key)
+	}
+}
+
+// Clear drops every cached reply
+func (c *CompletionCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Stats returns the size and hit counts of the cache
+func (c *CompletionCache) Stats() CompletionCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CompletionCacheStats{
+		Entries:    c.lru.Len(),
+		MaxEntries: c.maxEntries,
+		TTLMinutes: int(c.ttl / time.Minute),
+		Hits:       c.hits,
+		Misses:     c.misses,
+	}
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache marks completions made with ctx to always reach the provider
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cachedLLMProvider answers repeated deterministic completions from a cache
+type cachedLLMProvider struct {
+	LLMProvider
+	cache *CompletionCache
+}
+
+// WithLLMCache wraps provider so cacheable completions are served from cache.
+// Wrap it around usage tracking, so cache hits are not counted as billed usage.
+func WithLLMCache(provider LLMProvider, cache *CompletionCache) LLMProvider {
+	if cache == nil {
+		return provider
+	}
+	return &cachedLLMProvider{LLMProvider: provider, cache: cache}
+}
+
+func (p *cachedLLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	if bypass, _ := ctx.Value(cacheBypassKey{}).(bool); bypass || !cacheable(req) {
+		return p.LLMProvider.GenerateCompletion(ctx, req)
+	}
+	key := completionKey(p.LLMProvider, req)
+	if resp, ok := p.cache.get(key); ok {
+		// Nothing was billed for this reply
+		resp.TokensUsed = nil
+		return resp, nil
+	}
+	resp, err := p.LLMProvider.GenerateCompletion(ctx, req)
+	if err == nil && resp != nil && len(resp.ToolCalls) == 0 {
+		p.cache.put(key, resp)
+	}
+	return resp, err
+}
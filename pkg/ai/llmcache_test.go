@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+// countingLLM answers every completion with the number of calls so far
+type countingLLM struct {
+	flakyLLM
+	name  string
+	model string
+}
+
+func (c *countingLLM) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	c.calls++
+	return &CompletionResponse{Content: string(rune('0' + c.calls)), TokensUsed: &TokenUsage{TotalTokens: 10}}, nil
+}
+
+func (c *countingLLM) GetDefaultModel() string { return c.model }
+func (c *countingLLM) Name() string            { return c.name }
+
+func TestCompletionCacheServesDeterministicRepeats(t *testing.T) {
+	cache := NewCompletionCache(config.LLMCacheConfig{Enabled: true, TTLMinutes: 10, MaxEntries: 10})
+	llm := &countingLLM{name: "openai", model: "gpt-a"}
+	cached := WithLLMCache(llm, cache)
+	ctx := context.Background()
+	req := &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	first, _ := cached.GenerateCompletion(ctx, req)
+	second, _ := cached.GenerateCompletion(ctx, req)
+	if llm.calls != 1 || second.Content != first.Content {
+		t.Fatalf("repeat not served from cache: calls=%d", llm.calls)
+	}
+	if second.TokensUsed != nil {
+		t.Fatal("cache hit reported billed tokens")
+	}
+
+	// Sampling, tools and an explicit bypass always reach the provider
+	_, _ = cached.GenerateCompletion(ctx, &CompletionRequest{Messages: req.Messages, Temperature: 0.7})
+	_, _ = cached.GenerateCompletion(ctx, &CompletionRequest{Messages: req.Messages, Tools: []Tool{{}}})
+	_, _ = cached.GenerateCompletion(WithoutCache(ctx), req)
+	if llm.calls != 4 {
+		t.Fatalf("uncacheable requests served from cache: calls=%d", llm.calls)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCompletionCacheKeyedOnProviderAndModel(t *testing.T) {
+	cache := NewCompletionCache(config.LLMCacheConfig{Enabled: true, TTLMinutes: 10, MaxEntries: 10})
+	ctx := context.Background()
+	req := &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	a := &countingLLM{name: "openai", model: "gpt-a"}
+	_, _ = WithLLMCache(a, cache).GenerateCompletion(ctx, req)
+
+	// The same request to another default model or provider is not a hit
+	b := &countingLLM{name: "openai", model: "gpt-b"}
+	_, _ = WithLLMCache(b, cache).GenerateCompletion(ctx, req)
+	c := &countingLLM{name: "custom", model: "gpt-a"}
+	_, _ = WithLLMCache(c, cache).GenerateCompletion(ctx, req)
+	if b.calls != 1 || c.calls != 1 {
+		t.Fatalf("reply crossed providers or models: %d %d", b.calls, c.calls)
+	}
+
+	// An explicit model equal to the default shares the entry
+	_, _ = WithLLMCache(a, cache).GenerateCompletion(ctx, &CompletionRequest{Model: "gpt-a", Messages: req.Messages})
+	if a.calls != 1 {
+		t.Fatalf("explicit default model missed the cache: calls=%d", a.calls)
+	}
+}
+
+func TestCompletionCacheExpiresAndEvicts(t *testing.T) {
+	cache := NewCompletionCache(config.LLMCacheConfig{Enabled: true, TTLMinutes: 1, MaxEntries: 2})
+	for _, key := range []string{"a", "b", "c"} {
+		cache.put(key, &CompletionResponse{Content: key})
+	}
+	if _, ok := cache.get("a"); ok {
+		t.Fatal("least recently used entry not evicted")
+	}
+	if resp, ok := cache.get("c"); !ok || resp.Content != "c" {
+		t.Fatalf("newest entry missing: %v %v", resp, ok)
+	}
+
+	cache.entries["b"].Value.(*completionCacheEntry).expires = time.Now().Add(-time.Second)
+	if _, ok := cache.get("b"); ok {
+		t.Fatal("expired entry served")
+	}
+
+	cache.Clear()
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("entries left after Clear: %d", stats.Entries)
+	}
+
+	if NewCompletionCache(config.LLMCacheConfig{Enabled: false, TTLMinutes: 1, MaxEntries: 1}) != nil {
+		t.Fatal("disabled cache created")
+	}
+}
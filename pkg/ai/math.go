@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// mathSpans returns the [start, end) rune ranges of the math in runes:
+// $$...$$ blocks, which may span lines, and inline $...$ formulas. Dollars in
+// code, escaped dollars and amounts like "$5 and $10" are not math.
+func mathSpans(runes []rune) [][2]int {
+	var spans [][2]int
+	inFence := false
+	lineStart := true
+	for i := 0; i < len(runes); i++ {
+		if lineStart {
+			lineStart = false
+			if isFenceLine(runes[i:]) {
+				inFence = !inFence
+			}
+		}
+		r := runes[i]
+		if r == '\n' {
+			lineStart = true
+			continue
+		}
+		if inFence {
+			continue
+		}
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case r == '`':
+			if end := codeSpanEnd(runes, i); end > 0 {
+				i = end - 1
+			}
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '$':
+			end := displayMathEnd(runes, i+2)
+			if end < 0 {
+				i++
+				continue
+			}
+			spans = append(spans, [2]int{i, end})
+			i = end - 1
+		case r == '$':
+			if end := inlineMathEnd(runes, i); end > 0 {
+				spans = append(spans, [2]int{i, end})
+				i = end - 1
+			}
+		}
+	}
+	return spans
+}
+
+// isFenceLine reports whether the line at the start of runes opens or closes
+// a fenced code block
+func isFenceLine(runes []rune) bool {
+	j := 0
+	for j < len(runes) && j < 3 && runes[j] == ' ' {
+		j++
+	}
+	line := runes[j:]
+	return len(line) >= 3 && (string(line[:3]) == "```" || string(line[:3]) == "~~~")
+}
+
+// codeSpanEnd returns the end of the code span opened by the backticks at
+// start, or 0 if they are not closed
+func codeSpanEnd(runes []rune, start int) int {
+	n := 0
+	for start+n < len(runes) && runes[start+n] == '`' {
+		n++
+	}
+	for i := start + n; i < len(runes); {
+		if runes[i] != '`' {
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(runes) && runes[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return 0
+}
+
+// displayMathEnd returns the end of the $$ block whose content starts at
+// from, or -1 if it is never closed
+func displayMathEnd(runes []rune, from int) int {
+	for i := from; i+1 < len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+		if runes[i] == '$' && runes[i+1] == '$' {
+			return i + 2
+		}
+	}
+	return -1
+}
+
+// inlineMathEnd returns the end of the inline formula opened at start, or 0
+// if it is not one. Like Pandoc, the opening $ must be followed by a
+// non-space, the closing $ preceded by one and not followed by a digit, and
+// the formula stays on one line.
+func inlineMathEnd(runes []rune, start int) int {
+	if start+1 >= len(runes) || unicode.IsSpace(runes[start+1]) {
+		return 0
+	}
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\n':
+			return 0
+		case '\\':
+			i++
+		case '$':
+			if i == start+1 || unicode.IsSpace(runes[i-1]) {
+				continue
+			}
+			if i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				continue
+			}
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// enclosingSpan returns the span pos falls strictly inside, if any, so that
+// splitting at pos would cut a formula in two
+func enclosingSpan(spans [][2]int, pos int) ([2]int, bool) {
+	i := sort.Search(len(spans), func(i int) bool { return spans[i][1] > pos })
+	if i < len(spans) && spans[i][0] < pos {
+		return spans[i], true
+	}
+	return [2]int{}, false
+}
+
+// StripMath removes $$...$$ blocks and inline $...$ formulas from text, for
+// embedding models that handle LaTeX poorly
+func StripMath(text string) string {
+	runes := []rune(text)
+	spans := mathSpans(runes)
+	if len(spans) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	prev := 0
+	for _, span := range spans {
+		sb.WriteString(string(runes[prev:span[0]]))
+		prev = span[1]
+	}
+	sb.WriteString(string(runes[prev:]))
+
+	// Drop the blank runs left behind, keeping line breaks
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// mathSafeEnd moves a chunk end that would cut a formula to before it, or
+// past it when that would leave less than minSize of the chunk
+func mathSafeEnd(spans [][2]int, start, end, minSize int) int {
+	span, ok := enclosingSpan(spans, end)
+	if !ok {
+		return end
+	}
+	if span[0] > start && span[0]-start >= minSize {
+		return span[0]
+	}
+	return span[1]
+}
+
+// mathSafeStart moves the start of the next chunk that would fall inside a
+// formula to the formula's start, or past it if that would not move forward
+// from the previous start
+func mathSafeStart(spans [][2]int, prev, start int) int {
+	span, ok := enclosingSpan(spans, start)
+	if !ok {
+		return start
+	}
+	if span[0] > prev {
+		return span[0]
+	}
+	return span[1]
+}
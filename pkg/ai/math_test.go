@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMathSpans(t *testing.T) {
+	tests := []struct {
+		text string
+		want [][2]int
+	}{
+		{"no math here", nil},
+		{"a $x$ b", [][2]int{{2, 5}}},
+		{"$$\na = b\n$$ after", [][2]int{{0, 11}}},
+		{"$a$ and $$b$$", [][2]int{{0, 3}, {8, 13}}},
+		{"costs $5 and $10", nil},
+		{"$ x$ is not math", nil},
+		{"`$x$` in code", nil},
+		{"an escaped \\$x$ dollar", nil},
+		{"```\n$x$\n```\n$y$", [][2]int{{12, 15}}},
+		{"$$ never closed", nil},
+		{"$x\ny$ spans lines", nil},
+	}
+	for _, tt := range tests {
+		if got := mathSpans([]rune(tt.text)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mathSpans(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStripMath(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain text, $5 and $10", "plain text, $5 and $10"},
+		{"Energy $E = mc^2$ is\n$$\n\\int f\\,dx\n$$\nconserved", "Energy is\n\nconserved"},
+		{"`$x$` stays", "`$x$` stays"},
+	}
+	for _, tt := range tests {
+		if got := StripMath(tt.text); got != tt.want {
+			t.Errorf("StripMath(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMathSafeBoundaries(t *testing.T) {
+	spans := [][2]int{{10, 20}}
+	ends := []struct {
+		start, end, minSize, want int
+	}{
+		{0, 25, 5, 25}, // outside any formula
+		{0, 10, 5, 10}, // at its start, nothing is cut
+		{0, 15, 5, 10}, // cut short before the formula
+		{8, 15, 5, 20}, // too little before it, so past it
+	}
+	for _, tt := range ends {
+		if got := mathSafeEnd(spans, tt.start, tt.end, tt.minSize); got != tt.want {
+			t.Errorf("mathSafeEnd(%d, %d, %d) = %d, want %d", tt.start, tt.end, tt.minSize, got, tt.want)
+		}
+	}
+	starts := []struct {
+		prev, start, want int
+	}{
+		{0, 5, 5},
+		{0, 15, 10},  // back to the formula's start
+		{10, 15, 20}, // which would not move forward, so past it
+	}
+	for _, tt := range starts {
+		if got := mathSafeStart(spans, tt.prev, tt.start); got != tt.want {
+			t.Errorf("mathSafeStart(%d, %d) = %d, want %d", tt.prev, tt.start, got, tt.want)
+		}
+	}
+}
+
+func chunkContents(t *testing.T, c ChunkingStrategy, text string) []string {
+	t.Helper()
+	chunks, err := c.Chunk(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		if chunk.Index != i {
+			t.Fatalf("chunk %d has index %d", i, chunk.Index)
+		}
+		contents[i] = chunk.Content
+	}
+	return contents
+}
+
+func TestFixedSizeChunker(t *testing.T) {
+	c := NewFixedSizeChunker(10, 3, 1)
+
+	// Without math the windows are the plain fixed-size ones; the last ends
+	// at the end of the text
+	got := chunkContents(t, c, "abcdefghijklmnopqrstuvwxyz")
+	want := []string{"abcdefghij", "hijklmnopq", "opqrstuvwx", "vwxyz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+	if got := chunkContents(t, c, "short"); !reflect.DeepEqual(got, []string{"short"}) {
+		t.Fatalf("short text = %q", got)
+	}
+
+	// A formula across a window edge stays whole
+	text := "abcdefg $x+y$ hijklmnop"
+	for _, chunk := range chunkContents(t, c, text) {
+		if strings.Count(chunk, "$")%2 != 0 {
+			t.Fatalf("formula cut in %q", chunk)
+		}
+	}
+	got = chunkContents(t, c, text)
+	want = []string{"abcdefg ", "fg $x+y$ h", "$x+y$ hijk", "ijklmnop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunks with math = %q, want %q", got, want)
+	}
+}
+
+func TestSlidingWindowChunkerKeepsMath(t *testing.T) {
+	c := NewSlidingWindowChunker(10, 5, 1)
+	got := chunkContents(t, c, "abcdefghijklmnopqrst")
+	want := []string{"abcdefghij", "fghijklmno", "klmnopqrst"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("chunks = %q, want %q", got, want)
+	}
+	for _, chunk := range chunkContents(t, c, "abcdefg $x+y$ hijklmnop") {
+		if strings.Count(chunk, "$")%2 != 0 {
+			t.Fatalf("formula cut in %q", chunk)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package minilm
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testVocab = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "hello", "world", "un", "##aff", "##able", ",", "!", "cafe", "中"}
+
+func TestTokenizerWordPiece(t *testing.T) {
+	tok, err := NewTokenizer(testVocab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tok.Encode("Hello, WORLD! unaffable Café 中 xyz", 0)
+	want := []int{2, 4, 9, 5, 10, 6, 7, 8, 11, 12, 1, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := tok.Encode("hello world hello world", 4); !reflect.DeepEqual(got, []int{2, 4, 5, 3}) {
+		t.Fatalf("expected truncation to 4 tokens, got %v", got)
+	}
+}
+
+func TestHalfToFloat(t *testing.T) {
+	cases := map[uint16]float32{0x3c00: 1, 0xc000: -2, 0x0001: float32(math.Pow(2, -24)), 0x7bff: 65504}
+	for bits, want := range cases {
+		if got := halfToFloat(bits); got != want {
+			t.Errorf("halfToFloat(%#04x) = %v, want %v", bits, got, want)
+		}
+	}
+}
+
+func TestLoadAndEmbed(t *testing.T) {
+	dir := t.TempDir()
+	writeTinyModel(t, dir, 8, 2)
+	if !ModelPresent(dir) {
+		t.Fatal("expected model files to be present")
+	}
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := m.Embed(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 8 {
+		t.Fatalf("expected 8 dimensions, got %d", len(a))
+	}
+	var norm float64
+	for _, v := range a {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-4 {
+		t.Fatalf("expected unit vector, got norm %v", norm)
+	}
+	again, _ := m.Embed(context.Background(), "hello world")
+	if !reflect.DeepEqual(a, again) {
+		t.Fatal("embedding is not deterministic")
+	}
+	b, _ := m.Embed(context.Background(), "unaffable!")
+	if reflect.DeepEqual(a, b) {
+		t.Fatal("different texts produced identical embeddings")
+	}
+}
+
+// writeTinyModel writes a randomly initialized BERT with the given hidden
+// size and layer count, in the same layout as a Hugging Face checkpoint
+func writeTinyModel(t *testing.T, dir string, hidden, layers int) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	tensors := map[string][]int{
+		"bert.embeddings.word_embeddings.weight":       {len(testVocab), hidden},
+		"bert.embeddings.position_embeddings.weight":   {16, hidden},
+		"bert.embeddings.token_type_embeddings.weight": {2, hidden},
+		"bert.embeddings.LayerNorm.weight":             {hidden},
+		"bert.embeddings.LayerNorm.bias":               {hidden},
+	}
+	for i := 0; i < layers; i++ {
+		p := fmt.Sprintf("bert.encoder.layer.%d.", i)
+		for _, name := range []string{"attention.self.query", "attention.self.key", "attention.self.value", "attention.output.dense"} {
+			tensors[p+name+".weight"] = []int{hidden, hidden}
+			tensors[p+name+".bias"] = []int{hidden}
+		}
+		tensors[p+"intermediate.dense.weight"] = []int{2 * hidden, hidden}
+		tensors[p+"intermediate.dense.bias"] = []int{2 * hidden}
+		tensors[p+"output.dense.weight"] = []int{hidden, 2 * hidden}
+		tensors[p+"output.dense.bias"] = []int{hidden}
+		for _, name := range []string{"attention.output.LayerNorm", "output.LayerNorm"} {
+			tensors[p+name+".weight"] = []int{hidden}
+			tensors[p+name+".bias"] = []int{hidden}
+		}
+	}
+
+	header := map[string]interface{}{"__metadata__": map[string]string{"format": "pt"}}
+	var body []byte
+	for name, shape := range tensors {
+		n := 1
+		for _, d := range shape {
+			n *= d
+		}
+		start := len(body)
+		for i := 0; i < n; i++ {
+			v := float32(rng.NormFloat64() * 0.5)
+			if strings.HasSuffix(name, "LayerNorm.weight") {
+				v = 1
+			}
+			body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+		}
+		header[name] = map[string]interface{}{"dtype": "F32", "shape": shape, "data_offsets": []int{start, len(body)}}
+	}
+	headerJSON, _ := json.Marshal(header)
+	file := binary.LittleEndian.AppendUint64(nil, uint64(len(headerJSON)))
+	file = append(append(file, headerJSON...), body...)
+
+	cfg, _ := json.Marshal(Config{
+		HiddenSize:            hidden,
+		NumHiddenLayers:       layers,
+		NumAttentionHeads:     2,
+		IntermediateSize:      2 * hidden,
+		MaxPositionEmbeddings: 16,
+		LayerNormEps:          1e-12,
+	})
+	for name, data := range map[string][]byte{
+		"model.safetensors": file,
+		"config.json":       cfg,
+		"vocab.txt":         []byte(strings.Join(testVocab, "\n") + "\n"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
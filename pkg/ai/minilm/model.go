@@ -0,0 +1,354 @@
+// Package minilm runs small BERT sentence-embedding models such as
+// all-MiniLM-L6-v2 in-process, in pure Go, from a Hugging Face model
+// directory (config.json, vocab.txt and model.safetensors).
+package minilm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ModelFiles lists the files a model directory must contain
+var ModelFiles = []string{"config.json", "vocab.txt", "model.safetensors"}
+
+// DefaultMaxTokens is the sequence length all-MiniLM models were trained with
+const DefaultMaxTokens = 256
+
+// Config is the subset of a BERT config.json the encoder needs
+type Config struct {
+	HiddenSize            int     `json:"hidden_size"`
+	NumHiddenLayers       int     `json:"num_hidden_layers"`
+	NumAttentionHeads     int     `json:"num_attention_heads"`
+	IntermediateSize      int     `json:"intermediate_size"`
+	MaxPositionEmbeddings int     `json:"max_position_embeddings"`
+	LayerNormEps          float64 `json:"layer_norm_eps"`
+}
+
+type linear struct {
+	weight []float32 // [out, in]
+	bias   []float32 // [out]
+	in     int
+	out    int
+}
+
+type layerNorm struct {
+	weight []float32
+	bias   []float32
+}
+
+type layer struct {
+	query, key, value linear
+	attnOut           linear
+	attnNorm          layerNorm
+	intermediate      linear
+	output            linear
+	outNorm           layerNorm
+}
+
+// Model is a loaded sentence-embedding model. It is safe for concurrent use.
+type Model struct {
+	cfg       Config
+	tokenizer *Tokenizer
+	maxTokens int
+
+	wordEmb  []float32
+	posEmb   []float32
+	typeEmb  []float32
+	embNorm  layerNorm
+	layers   []layer
+	eps      float32
+	headSize int
+}
+
+// ModelPresent reports whether dir contains all model files
+func ModelPresent(dir string) bool {
+	for _, name := range ModelFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Load reads a model directory
+func Load(dir string) (*Model, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config.json: %w", err)
+	}
+	tok, err := LoadTokenizer(filepath.Join(dir, "vocab.txt"))
+	if err != nil {
+		return nil, err
+	}
+	tensors, err := readSafetensors(filepath.Join(dir, "model.safetensors"))
+	if err != nil {
+		return nil, err
+	}
+	return newModel(cfg, tok, tensors)
+}
+
+func newModel(cfg Config, tok *Tokenizer, tensors map[string]tensor) (*Model, error) {
+	if cfg.HiddenSize <= 0 || cfg.NumAttentionHeads <= 0 || cfg.HiddenSize%cfg.NumAttentionHeads != 0 {
+		return nil, fmt.Errorf("invalid model config: hidden size %d, %d heads", cfg.HiddenSize, cfg.NumAttentionHeads)
+	}
+	if cfg.LayerNormEps == 0 {
+		cfg.LayerNormEps = 1e-12
+	}
+	m := &Model{
+		cfg:       cfg,
+		tokenizer: tok,
+		maxTokens: DefaultMaxTokens,
+		eps:       float32(cfg.LayerNormEps),
+		headSize:  cfg.HiddenSize / cfg.NumAttentionHeads,
+	}
+	if cfg.MaxPositionEmbeddings > 0 && cfg.MaxPositionEmbeddings < m.maxTokens {
+		m.maxTokens = cfg.MaxPositionEmbeddings
+	}
+
+	l := &loader{tensors: tensors, hidden: cfg.HiddenSize}
+	m.wordEmb = l.matrix("embeddings.word_embeddings.weight")
+	m.posEmb = l.matrix("embeddings.position_embeddings.weight")
+	m.typeEmb = l.matrix("embeddings.token_type_embeddings.weight")
+	m.embNorm = l.norm("embeddings.LayerNorm")
+	for i := 0; i < cfg.NumHiddenLayers; i++ {
+		p := fmt.Sprintf("encoder.layer.%d.", i)
+		m.layers = append(m.layers, layer{
+			query:        l.linear(p + "attention.self.query"),
+			key:          l.linear(p + "attention.self.key"),
+			value:        l.linear(p + "attention.self.value"),
+			attnOut:      l.linear(p + "attention.output.dense"),
+			attnNorm:     l.norm(p + "attention.output.LayerNorm"),
+			intermediate: l.linear(p + "intermediate.dense"),
+			output:       l.linear(p + "output.dense"),
+			outNorm:      l.norm(p + "output.LayerNorm"),
+		})
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	if len(m.posEmb)/cfg.HiddenSize < m.maxTokens {
+		m.maxTokens = len(m.posEmb) / cfg.HiddenSize
+	}
+	return m, nil
+}
+
+// loader pulls tensors out of the checkpoint, remembering the first problem
+type loader struct {
+	tensors map[string]tensor
+	hidden  int
+	err     error
+}
+
+func (l *loader) get(name string) tensor {
+	t, ok := l.tensors[name]
+	if !ok && l.err == nil {
+		l.err = fmt.Errorf("model is missing tensor %s", name)
+	}
+	return t
+}
+
+func (l *loader) matrix(name string) []float32 {
+	t := l.get(name)
+	if l.err == nil && (len(t.shape) != 2 || t.shape[1] != l.hidden) {
+		l.err = fmt.Errorf("tensor %s has shape %v, want [*, %d]", name, t.shape, l.hidden)
+	}
+	return t.data
+}
+
+func (l *loader) linear(prefix string) linear {
+	w := l.get(prefix + ".weight")
+	b := l.get(prefix + ".bias")
+	if l.err != nil {
+		return linear{}
+	}
+	if len(w.shape) != 2 || len(b.data) != w.shape[0] {
+		l.err = fmt.Errorf("tensor %s has inconsistent shape", prefix)
+		return linear{}
+	}
+	return linear{weight: w.data, bias: b.data, out: w.shape[0], in: w.shape[1]}
+}
+
+func (l *loader) norm(prefix string) layerNorm {
+	w := l.get(prefix + ".weight")
+	b := l.get(prefix + ".bias")
+	if l.err == nil && (len(w.data) != l.hidden || len(b.data) != l.hidden) {
+		l.err = fmt.Errorf("tensor %s has inconsistent shape", prefix)
+	}
+	return layerNorm{weight: w.data, bias: b.data}
+}
+
+// Dimension returns the embedding size
+func (m *Model) Dimension() int {
+	return m.cfg.HiddenSize
+}
+
+// CountTokens returns how many tokens Embed feeds the encoder for text
+func (m *Model) CountTokens(text string) int {
+	return len(m.tokenizer.Encode(text, m.maxTokens))
+}
+
+// Embed returns the mean-pooled, L2-normalized sentence embedding of text.
+// Text beyond the model's sequence length is truncated.
+func (m *Model) Embed(ctx context.Context, text string) ([]float32, error) {
+	ids := m.tokenizer.Encode(text, m.maxTokens)
+	for _, id := range ids {
+		if id < 0 || id >= len(m.wordEmb)/m.cfg.HiddenSize {
+			return nil, fmt.Errorf("token id %d outside the embedding table", id)
+		}
+	}
+	h := m.cfg.HiddenSize
+	n := len(ids)
+
+	x := make([]float32, n*h)
+	for i, id := range ids {
+		row := x[i*h : (i+1)*h]
+		word := m.wordEmb[id*h : (id+1)*h]
+		pos := m.posEmb[i*h : (i+1)*h]
+		for j := range row {
+			row[j] = word[j] + pos[j] + m.typeEmb[j]
+		}
+		m.normalize(row, m.embNorm)
+	}
+
+	for _, l := range m.layers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		x = m.encoderLayer(x, n, l)
+	}
+
+	out := make([]float32, h)
+	for i := 0; i < n; i++ {
+		for j, v := range x[i*h : (i+1)*h] {
+			out[j] += v
+		}
+	}
+	var norm float64
+	for j := range out {
+		out[j] /= float32(n)
+		norm += float64(out[j]) * float64(out[j])
+	}
+	if norm > 0 {
+		inv := float32(1 / math.Sqrt(norm))
+		for j := range out {
+			out[j] *= inv
+		}
+	}
+	return out, nil
+}
+
+func (m *Model) encoderLayer(x []float32, n int, l layer) []float32 {
+	h := m.cfg.HiddenSize
+	q := l.query.apply(x, n)
+	k := l.key.apply(x, n)
+	v := l.value.apply(x, n)
+
+	ctxOut := make([]float32, n*h)
+	scores := make([]float32, n)
+	scale := float32(1 / math.Sqrt(float64(m.headSize)))
+	for head := 0; head < m.cfg.NumAttentionHeads; head++ {
+		off := head * m.headSize
+		for i := 0; i < n; i++ {
+			qi := q[i*h+off : i*h+off+m.headSize]
+			maxScore := float32(math.Inf(-1))
+			for j := 0; j < n; j++ {
+				scores[j] = dot(qi, k[j*h+off:j*h+off+m.headSize]) * scale
+				if scores[j] > maxScore {
+					maxScore = scores[j]
+				}
+			}
+			var sum float32
+			for j := 0; j < n; j++ {
+				scores[j] = float32(math.Exp(float64(scores[j] - maxScore)))
+				sum += scores[j]
+			}
+			dst := ctxOut[i*h+off : i*h+off+m.headSize]
+			for j := 0; j < n; j++ {
+				w := scores[j] / sum
+				for d, val := range v[j*h+off : j*h+off+m.headSize] {
+					dst[d] += w * val
+				}
+			}
+		}
+	}
+
+	attn := l.attnOut.apply(ctxOut, n)
+	for i := range attn {
+		attn[i] += x[i]
+	}
+	for i := 0; i < n; i++ {
+		m.normalize(attn[i*h:(i+1)*h], l.attnNorm)
+	}
+
+	inter := l.intermediate.apply(attn, n)
+	for i, val := range inter {
+		inter[i] = gelu(val)
+	}
+	out := l.output.apply(inter, n)
+	for i := range out {
+		out[i] += attn[i]
+	}
+	for i := 0; i < n; i++ {
+		m.normalize(out[i*h:(i+1)*h], l.outNorm)
+	}
+	return out
+}
+
+// apply computes x·Wᵀ + b for n rows of x
+func (l linear) apply(x []float32, n int) []float32 {
+	out := make([]float32, n*l.out)
+	for i := 0; i < n; i++ {
+		row := x[i*l.in : (i+1)*l.in]
+		dst := out[i*l.out : (i+1)*l.out]
+		for o := range dst {
+			dst[o] = dot(row, l.weight[o*l.in:(o+1)*l.in]) + l.bias[o]
+		}
+	}
+	return out
+}
+
+func (m *Model) normalize(row []float32, ln layerNorm) {
+	var mean float32
+	for _, v := range row {
+		mean += v
+	}
+	mean /= float32(len(row))
+	var variance float32
+	for _, v := range row {
+		d := v - mean
+		variance += d * d
+	}
+	variance /= float32(len(row))
+	inv := float32(1 / math.Sqrt(float64(variance+m.eps)))
+	for i, v := range row {
+		row[i] = (v-mean)*inv*ln.weight[i] + ln.bias[i]
+	}
+}
+
+func dot(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// gelu is the exact (erf) GELU used by BERT
+func gelu(x float32) float32 {
+	return 0.5 * x * (1 + float32(math.Erf(float64(x)/math.Sqrt2)))
+}
@@ -0,0 +1,122 @@
+package minilm
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// maxHeaderSize guards against reading a corrupt header length
+const maxHeaderSize = 100 << 20
+
+type tensorInfo struct {
+	DType       string   `json:"dtype"`
+	Shape       []int    `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// tensor is a dense float32 array in row-major order
+type tensor struct {
+	shape []int
+	data  []float32
+}
+
+// readSafetensors loads every tensor of a .safetensors file as float32.
+// A leading "bert." prefix is stripped from names so bare BertModel
+// checkpoints and ones saved from a wrapping model load the same way.
+func readSafetensors(path string) (map[string]tensor, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 8 {
+		return nil, fmt.Errorf("%s: file too short", path)
+	}
+	headerLen := binary.LittleEndian.Uint64(raw[:8])
+	if headerLen > maxHeaderSize || 8+headerLen > uint64(len(raw)) {
+		return nil, fmt.Errorf("%s: invalid header length %d", path, headerLen)
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(raw[8:8+headerLen], &header); err != nil {
+		return nil, fmt.Errorf("%s: invalid header: %w", path, err)
+	}
+	body := raw[8+headerLen:]
+
+	tensors := make(map[string]tensor, len(header))
+	for name, msg := range header {
+		if name == "__metadata__" {
+			continue
+		}
+		var info tensorInfo
+		if err := json.Unmarshal(msg, &info); err != nil {
+			return nil, fmt.Errorf("%s: tensor %s: %w", path, name, err)
+		}
+		start, end := info.DataOffsets[0], info.DataOffsets[1]
+		if start < 0 || end < start || end > int64(len(body)) {
+			return nil, fmt.Errorf("%s: tensor %s: data out of range", path, name)
+		}
+		data, err := decodeFloats(info.DType, body[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("%s: tensor %s: %w", path, name, err)
+		}
+		n := 1
+		for _, d := range info.Shape {
+			n *= d
+		}
+		if n != len(data) {
+			return nil, fmt.Errorf("%s: tensor %s: shape %v does not match %d values", path, name, info.Shape, len(data))
+		}
+		tensors[strings.TrimPrefix(name, "bert.")] = tensor{shape: info.Shape, data: data}
+	}
+	return tensors, nil
+}
+
+func decodeFloats(dtype string, b []byte) ([]float32, error) {
+	switch dtype {
+	case "F32":
+		if len(b)%4 != 0 {
+			return nil, fmt.Errorf("truncated F32 data")
+		}
+		out := make([]float32, len(b)/4)
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+		}
+		return out, nil
+	case "F16":
+		if len(b)%2 != 0 {
+			return nil, fmt.Errorf("truncated F16 data")
+		}
+		out := make([]float32, len(b)/2)
+		for i := range out {
+			out[i] = halfToFloat(binary.LittleEndian.Uint16(b[i*2:]))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported dtype %s", dtype)
+	}
+}
+
+// halfToFloat converts an IEEE 754 half-precision value
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := int32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0 && frac == 0:
+		return math.Float32frombits(sign)
+	case exp == 0x1f: // inf or NaN
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	case exp == 0: // subnormal: shift until the implicit leading bit is set
+		exp = 1
+		for frac&0x400 == 0 {
+			frac <<= 1
+			exp--
+		}
+		frac &= 0x3ff
+	}
+	return math.Float32frombits(sign | uint32(exp+127-15)<<23 | frac<<13)
+}
@@ -0,0 +1,149 @@
+package minilm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordChars is the longest word WordPiece will split; longer words map to [UNK]
+const maxWordChars = 100
+
+// Tokenizer is an uncased BERT WordPiece tokenizer
+type Tokenizer struct {
+	vocab map[string]int
+	unk   int
+	cls   int
+	sep   int
+}
+
+// LoadTokenizer reads a vocab.txt file with one token per line
+func LoadTokenizer(path string) (*Tokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		tokens = append(tokens, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewTokenizer(tokens)
+}
+
+// NewTokenizer builds a tokenizer from a vocabulary ordered by token ID
+func NewTokenizer(tokens []string) (*Tokenizer, error) {
+	t := &Tokenizer{vocab: make(map[string]int, len(tokens))}
+	for id, tok := range tokens {
+		if _, dup := t.vocab[tok]; !dup {
+			t.vocab[tok] = id
+		}
+	}
+	for name, dst := range map[string]*int{"[UNK]": &t.unk, "[CLS]": &t.cls, "[SEP]": &t.sep} {
+		id, ok := t.vocab[name]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary has no %s token", name)
+		}
+		*dst = id
+	}
+	return t, nil
+}
+
+// Encode returns token IDs wrapped in [CLS] ... [SEP], truncated to maxLen
+// tokens in total
+func (t *Tokenizer) Encode(text string, maxLen int) []int {
+	ids := []int{t.cls}
+	for _, word := range basicTokenize(text) {
+		ids = append(ids, t.wordPiece(word)...)
+		if maxLen > 0 && len(ids) >= maxLen-1 {
+			ids = ids[:maxLen-1]
+			break
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// wordPiece splits a word greedily into the longest vocabulary pieces
+func (t *Tokenizer) wordPiece(word string) []int {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int{t.unk}
+	}
+	var ids []int
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := -1
+		for end > start {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := t.vocab[piece]; ok {
+				found = id
+				break
+			}
+			end--
+		}
+		if found < 0 {
+			return []int{t.unk}
+		}
+		ids = append(ids, found)
+		start = end
+	}
+	return ids
+}
+
+// basicTokenize lowercases, strips accents and splits on whitespace,
+// punctuation and CJK characters
+func basicTokenize(text string) []string {
+	text = norm.NFD.String(strings.ToLower(text))
+
+	var words []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || unicode.Is(unicode.Mn, r):
+			// dropped: invalid characters and combining accents
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.IsControl(r):
+		case isPunct(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// isPunct matches BERT's definition: all non-alphanumeric ASCII plus Unicode punctuation
+func isPunct(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) || (r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) || (r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) || (r >= 0x2F800 && r <= 0x2FA1F)
+}
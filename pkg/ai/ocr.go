@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+// ocrTimeout bounds reading one image
+const ocrTimeout = 2 * time.Minute
+
+// maxVisionImageBytes is the largest image sent to a vision model
+const maxVisionImageBytes = 20 << 20
+
+const visionOCRPrompt = "Transcribe all text visible in this image exactly, preserving line breaks. If it is a diagram or chart, also list its labels. Reply with only the text, or nothing if there is none."
+
+const imageCaptionPrompt = "Describe this image for a search index in 2-4 sentences: what kind of image it is (photo, screenshot, diagram, chart, whiteboard...), its subject, and the main elements, components or relationships shown. Mention notable labels. Reply with only the description."
+
+// imageMIMETypes maps the image extensions that can be read to MIME types
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+}
+
+// IsImageFile reports whether path has an extension of a readable image
+func IsImageFile(path string) bool {
+	_, ok := imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// ImageTextExtractor reads the text in an image
+type ImageTextExtractor interface {
+	Name() string
+	ExtractText(ctx context.Context, imagePath string) (string, error)
+}
+
+// NewImageTextExtractor creates the extractor selected by cfg. The vision
+// extractor calls the chat completions API described by llm.
+func NewImageTextExtractor(cfg config.OCRConfig, llm config.OpenAIConfig) (ImageTextExtractor, error) {
+	switch cfg.Provider {
+	case config.OCRVision:
+		return newVisionReader(llm, cfg.VisionModel, config.OCRVision, visionOCRPrompt)
+	case config.OCRTesseract, "":
+		binary := cfg.TesseractBinary
+		if binary == "" {
+			binary = "tesseract"
+		}
+		languages := cfg.Languages
+		if languages == "" {
+			languages = "eng"
+		}
+		return &TesseractOCR{binary: binary, languages: languages}, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR provider: %s", cfg.Provider)
+	}
+}
+
+// NewImageCaptioner creates a reader that describes images with the vision
+// model, so they can be found by what they show
+func NewImageCaptioner(cfg config.ImageCaptionConfig, llm config.OpenAIConfig) (ImageTextExtractor, error) {
+	return newVisionReader(llm, cfg.Model, "caption", imageCaptionPrompt)
+}
+
+func newVisionReader(llm config.OpenAIConfig, model, kind, prompt string) (ImageTextExtractor, error) {
+	if llm.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required for vision models")
+	}
+	baseURL := llm.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &VisionReader{
+		apiKey:       llm.APIKey,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		organization: llm.Organization,
+		model:        model,
+		kind:         kind,
+		prompt:       prompt,
+		httpClient:   NewHTTPClient(ocrTimeout),
+	}, nil
+}
+
+// TesseractOCR runs a local tesseract command line program
+type TesseractOCR struct {
+	binary    string
+	languages string
+}
+
+// Name identifies the extractor and its languages, since both decide the text
+func (t *TesseractOCR) Name() string {
+	return config.OCRTesseract + ":" + t.languages
+}
+
+// ExtractText runs tesseract on imagePath and returns the text it prints
+func (t *TesseractOCR) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ocrTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, t.binary, imagePath, "stdout", "-l", t.languages)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return cleanOCRText(stdout.String()), nil
+}
+
+// VisionReader asks a chat model that accepts images to transcribe or
+// describe them
+type VisionReader struct {
+	apiKey       string
+	baseURL      string
+	organization string
+	model        string
+	kind         string // what the prompt asks for, e.g. "vision" or "caption"
+	prompt       string
+	httpClient   *http.Client
+}
+
+// Name identifies the reader, its task and its model
+func (v *VisionReader) Name() string {
+	return v.kind + ":" + v.model
+}
+
+// ExtractText sends imagePath to the vision model and returns its reply
+func (v *VisionReader) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxVisionImageBytes {
+		return "", fmt.Errorf("image is larger than %d MB", maxVisionImageBytes>>20)
+	}
+	mime := imageMIMETypes[strings.ToLower(filepath.Ext(imagePath))]
+	if mime == "" {
+		mime = http.DetectContentType(data)
+	}
+
+	requestBody := map[string]interface{}{
+		"model":       v.model,
+		"temperature": 0,
+		"messages": []map[string]interface{}{{
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": v.prompt},
+				{"type": "image_url", "image_url": map[string]string{
+					"url": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data),
+				}},
+			},
+		}},
+	}
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", v.baseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+v.apiKey)
+	if v.organization != "" {
+		req.Header.Set("OpenAI-Organization", v.organization)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return "", &transportError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", newAPIError("openai", resp, body)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+	return cleanOCRText(result.Choices[0].Message.Content), nil
+}
+
+// cleanOCRText trims each line and collapses runs of blank lines
+func cleanOCRText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\f", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = len(out) > 0
+			continue
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
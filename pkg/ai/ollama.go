@@ -2,11 +2,13 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -47,7 +49,7 @@ func NewOllamaProvider(cfg OllamaConfig) (*OllamaProvider, error) {
 	return &OllamaProvider{
 		baseURL:    baseURL,
 		model:      model,
-		httpClient: &http.Client{Timeout: timeout},
+		httpClient: NewHTTPClient(timeout),
 	}, nil
 }
 
@@ -69,7 +71,7 @@ type ollamaErrorResponse struct {
 }
 
 // GenerateEmbedding creates an embedding for a single text
-func (p *OllamaProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingResponse, error) {
+func (p *OllamaProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
 	if req.Text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
@@ -93,7 +95,7 @@ func (p *OllamaProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 
 	// Create HTTP request
 	url := p.baseURL + "api/embeddings"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -104,7 +106,7 @@ func (p *OllamaProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 	// Execute request
 	httpResp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer httpResp.Body.Close()
 
@@ -116,11 +118,7 @@ func (p *OllamaProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 
 	// Check for error status
 	if httpResp.StatusCode != http.StatusOK {
-		var errResp ollamaErrorResponse
-		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error != "" {
-			return nil, fmt.Errorf("Ollama error: %s", errResp.Error)
-		}
-		return nil, fmt.Errorf("request failed with status %d: %s", httpResp.StatusCode, string(respBody))
+		return nil, newAPIError("ollama", httpResp, respBody)
 	}
 
 	// Parse response
@@ -140,52 +138,43 @@ func (p *OllamaProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 	}, nil
 }
 
-// GenerateEmbeddingsBatch creates embeddings for multiple texts
-// Note: Ollama API doesn't natively support batch embeddings,
-// so we implement it by making multiple parallel requests
-func (p *OllamaProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingResponse, error) {
+// GenerateEmbeddingsBatch creates embeddings for multiple texts.
+// Ollama has no batch endpoint, so texts are embedded with parallel requests.
+// Texts that fail are reported in a *BatchError while the others are still
+// returned.
+func (p *OllamaProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("texts cannot be empty")
 	}
 
-	// Ollama doesn't have a true batch API, so we make parallel requests
-	// Use a semaphore to limit concurrency
 	const maxConcurrency = 5
 	sem := make(chan struct{}, maxConcurrency)
 	results := make([]*EmbeddingResponse, len(texts))
-	errs := make(chan error, len(texts))
+	errs := make([]error, len(texts))
+	var wg sync.WaitGroup
 
 	for i, text := range texts {
-		sem <- struct{}{} // Acquire semaphore
-		go func(idx int, txt string) {
-			defer func() { <-sem }() // Release semaphore
-
-			resp, err := p.GenerateEmbedding(&EmbeddingRequest{Text: txt})
-			if err != nil {
-				errs <- fmt.Errorf("error at index %d: %w", idx, err)
-				return
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(texts); j++ {
+				errs[j] = ctx.Err()
 			}
-			results[idx] = resp
+		}
+		if errs[i] != nil {
+			break
+		}
+		wg.Add(1)
+		go func(idx int, txt string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Each goroutine writes only its own index
+			results[idx], errs[idx] = p.GenerateEmbedding(ctx, &EmbeddingRequest{Text: txt, Model: batchModel(ctx, "")})
 		}(i, text)
 	}
+	wg.Wait()
 
-	// Wait for all goroutines to complete
-	for i := 0; i < maxConcurrency; i++ {
-		sem <- struct{}{}
-	}
-
-	// Check for errors
-	close(errs)
-	var errList []error
-	for err := range errs {
-		errList = append(errList, err)
-	}
-
-	if len(errList) > 0 {
-		return results, fmt.Errorf("encountered %d errors: %v", len(errList), errList[0])
-	}
-
-	return results, nil
+	return results, newBatchError(errs)
 }
 
 // GetModelDimension returns the output dimension for a given model
@@ -215,7 +204,7 @@ func (p *OllamaProvider) ValidateConfig() error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 2 * time.Second}
+	client := NewHTTPClient(2 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("cannot reach Ollama server at %s: %w", p.baseURL, err)
@@ -229,6 +218,14 @@ func (p *OllamaProvider) ValidateConfig() error {
 	return nil
 }
 
+// CheckHealth confirms the Ollama server is reachable
+func (p *OllamaProvider) CheckHealth(ctx context.Context) error {
+	if _, err := ListOllamaModels(ctx, p.baseURL); err != nil {
+		return fmt.Errorf("cannot reach Ollama server at %s: %w", p.baseURL, err)
+	}
+	return nil
+}
+
 // Name returns the provider name
 func (p *OllamaProvider) Name() string {
 	return "ollama"
@@ -2,7 +2,9 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 
 // OpenAIProvider implements EmbeddingProvider for OpenAI's API
 type OpenAIProvider struct {
+	name         string
 	apiKey       string
 	baseURL      string
 	organization string
@@ -33,7 +36,12 @@ func NewOpenAIProvider(cfg OpenAIConfig) (*OpenAIProvider, error) {
 	if cfg.APIKey == "" {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
+	return newOpenAICompatibleProvider("openai", cfg), nil
+}
 
+// newOpenAICompatibleProvider creates an embedding provider for any server
+// implementing OpenAI's embeddings API
+func newOpenAICompatibleProvider(name string, cfg OpenAIConfig) *OpenAIProvider {
 	baseURL := cfg.BaseURL
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
@@ -53,14 +61,13 @@ func NewOpenAIProvider(cfg OpenAIConfig) (*OpenAIProvider, error) {
 	}
 
 	return &OpenAIProvider{
+		name:         name,
 		apiKey:       cfg.APIKey,
 		baseURL:      baseURL,
 		organization: cfg.Organization,
 		model:        model,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
-	}, nil
+		httpClient:   NewHTTPClient(timeout),
+	}
 }
 
 // openAIEmbeddingRequest is the request body for OpenAI's embeddings API
@@ -97,7 +104,7 @@ type openAIErrorResponse struct {
 }
 
 // GenerateEmbedding creates an embedding for a single text
-func (p *OpenAIProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingResponse, error) {
+func (p *OpenAIProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
 	if req.Text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
@@ -129,14 +136,16 @@ func (p *OpenAIProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 
 	// Create HTTP request
 	url := p.baseURL + "embeddings"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
 	if p.organization != "" {
 		httpReq.Header.Set("OpenAI-Organization", p.organization)
 	}
@@ -144,7 +153,7 @@ func (p *OpenAIProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 	// Execute request
 	httpResp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer httpResp.Body.Close()
 
@@ -156,12 +165,7 @@ func (p *OpenAIProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 
 	// Check for error status
 	if httpResp.StatusCode != http.StatusOK {
-		var errResp openAIErrorResponse
-		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("OpenAI error: %s (type: %s, code: %s)",
-				errResp.Error.Message, errResp.Error.Type, errResp.Error.Code)
-		}
-		return nil, fmt.Errorf("request failed with status %d: %s", httpResp.StatusCode, string(respBody))
+		return nil, newAPIError(p.name, httpResp, respBody)
 	}
 
 	// Parse response
@@ -186,7 +190,7 @@ func (p *OpenAIProvider) GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingRes
 }
 
 // GenerateEmbeddingsBatch creates embeddings for multiple texts
-func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingResponse, error) {
+func (p *OpenAIProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("texts cannot be empty")
 	}
@@ -197,7 +201,7 @@ func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingRe
 		return nil, fmt.Errorf("batch size exceeds maximum of %d", maxBatchSize)
 	}
 
-	model := p.GetDefaultModel()
+	model := batchModel(ctx, p.GetDefaultModel())
 
 	// Build request body
 	body := openAIEmbeddingRequest{
@@ -213,14 +217,16 @@ func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingRe
 
 	// Create HTTP request
 	url := p.baseURL + "embeddings"
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
 	if p.organization != "" {
 		httpReq.Header.Set("OpenAI-Organization", p.organization)
 	}
@@ -228,7 +234,7 @@ func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingRe
 	// Execute request
 	httpResp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer httpResp.Body.Close()
 
@@ -240,12 +246,7 @@ func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingRe
 
 	// Check for error status
 	if httpResp.StatusCode != http.StatusOK {
-		var errResp openAIErrorResponse
-		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("OpenAI error: %s (type: %s, code: %s)",
-				errResp.Error.Message, errResp.Error.Type, errResp.Error.Code)
-		}
-		return nil, fmt.Errorf("request failed with status %d: %s", httpResp.StatusCode, string(respBody))
+		return nil, newAPIError(p.name, httpResp, respBody)
 	}
 
 	// Parse response
@@ -266,6 +267,11 @@ func (p *OpenAIProvider) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingRe
 			Model:     resp.Model,
 		}
 	}
+	// Usage is reported for the whole batch; attach it to the first result
+	results[0].Usage = &Usage{
+		PromptTokens: resp.Usage.PromptTokens,
+		TotalTokens:  resp.Usage.TotalTokens,
+	}
 
 	return results, nil
 }
@@ -289,7 +295,7 @@ func (p *OpenAIProvider) GetDefaultModel() string {
 
 // ValidateConfig checks if the provider configuration is valid
 func (p *OpenAIProvider) ValidateConfig() error {
-	if p.apiKey == "" {
+	if p.apiKey == "" && p.name == "openai" {
 		return fmt.Errorf("API key is required")
 	}
 	if p.baseURL == "" {
@@ -298,7 +304,22 @@ func (p *OpenAIProvider) ValidateConfig() error {
 	return nil
 }
 
+// CheckHealth confirms the server is reachable and accepts the credentials
+func (p *OpenAIProvider) CheckHealth(ctx context.Context) error {
+	if err := p.ValidateConfig(); err != nil {
+		return err
+	}
+	_, err := ListOpenAICompatibleModels(ctx, p.httpClient, p.baseURL, p.apiKey, p.organization)
+	// Some OpenAI-compatible servers have no GET /models, but answering at
+	// all shows they are up
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed) {
+		return nil
+	}
+	return err
+}
+
 // Name returns the provider name
 func (p *OpenAIProvider) Name() string {
-	return "openai"
+	return p.name
 }
@@ -3,7 +3,7 @@ package ai
 import (
 	"bufio"
 	"bytes"
-	// "context"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,6 +16,7 @@ import (
 
 // OpenAILLMProvider implements LLMProvider for OpenAI chat completions
 type OpenAILLMProvider struct {
+	name         string
 	apiKey       string
 	baseURL      string
 	organization string
@@ -35,41 +36,50 @@ func NewOpenAILLMProvider(cfg config.OpenAIConfig) (*OpenAILLMProvider, error) {
 	}
 
 	return &OpenAILLMProvider{
+		name:         "openai",
 		apiKey:       cfg.APIKey,
 		baseURL:      baseURL,
 		organization: cfg.Organization,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		httpClient: NewHTTPClient(60 * time.Second),
 		model: "gpt-4o-mini",
 	}, nil
 }
 
 // Name returns the provider name
 func (p *OpenAILLMProvider) Name() string {
-	return "openai"
+	return p.name
 }
 
 // GetDefaultModel returns the default model
 func (p *OpenAILLMProvider) GetDefaultModel() string {
-	return "gpt-4o-mini"
+	return p.model
 }
 
-// GetAvailableModels returns available models
+// GetAvailableModels asks the server for its models, falling back to the
+// known defaults when discovery fails
 func (p *OpenAILLMProvider) GetAvailableModels() ([]string, error) {
-	return DefaultChatModels["openai"], nil
+	ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+	defer cancel()
+	models, err := ListOpenAICompatibleModels(ctx, p.httpClient, p.baseURL, p.apiKey, p.organization)
+	if err != nil {
+		if defaults, ok := DefaultChatModels[p.name]; ok {
+			return defaults, nil
+		}
+		return nil, err
+	}
+	return models, nil
 }
 
 // ValidateConfig checks if the configuration is valid
 func (p *OpenAILLMProvider) ValidateConfig() error {
-	if p.apiKey == "" {
+	if p.apiKey == "" && p.name == "openai" {
 		return fmt.Errorf("OpenAI API key is required")
 	}
 	return nil
 }
 
 // GenerateCompletion generates a text completion
-func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*CompletionResponse, error) {
+func (p *OpenAILLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
 	// Set default model if not specified
 	if req.Model == "" {
 		req.Model = p.GetDefaultModel()
@@ -90,6 +100,13 @@ func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*Complet
 		requestBody["max_tokens"] = req.MaxTokens
 	}
 
+	if len(req.Tools) > 0 {
+		requestBody["tools"] = req.Tools
+		if req.ToolChoice != "" {
+			requestBody["tool_choice"] = req.ToolChoice
+		}
+	}
+
 	// Marshal to JSON
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -98,14 +115,16 @@ func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*Complet
 
 	// Create HTTP request
 	url := fmt.Sprintf("%s/chat/completions", strings.TrimSuffix(p.baseURL, "/"))
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	}
 	if p.organization != "" {
 		httpReq.Header.Set("OpenAI-Organization", p.organization)
 	}
@@ -113,14 +132,14 @@ func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*Complet
 	// Execute request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, &transportError{err: err}
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, newAPIError(p.name, resp, body)
 	}
 
 	// Parse response
@@ -132,8 +151,9 @@ func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*Complet
 		Choices []struct {
 			Index int `json:"index"`
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -164,11 +184,12 @@ func (p *OpenAILLMProvider) GenerateCompletion(req *CompletionRequest) (*Complet
 			TotalTokens:      openAIResp.Usage.TotalTokens,
 		},
 		FinishReason: openAIResp.Choices[0].FinishReason,
+		ToolCalls:    openAIResp.Choices[0].Message.ToolCalls,
 	}, nil
 }
 
 // GenerateCompletionStream generates a streaming completion
-func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-chan *CompletionChunk, error) {
+func (p *OpenAILLMProvider) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
 	// Set default model if not specified
 	if req.Model == "" {
 		req.Model = p.GetDefaultModel()
@@ -192,6 +213,13 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 		requestBody["max_tokens"] = req.MaxTokens
 	}
 
+	if len(req.Tools) > 0 {
+		requestBody["tools"] = req.Tools
+		if req.ToolChoice != "" {
+			requestBody["tool_choice"] = req.ToolChoice
+		}
+	}
+
 	// Marshal to JSON
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
@@ -201,7 +229,7 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 
 	// Create HTTP request
 	url := fmt.Sprintf("%s/chat/completions", strings.TrimSuffix(p.baseURL, "/"))
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
 	if err != nil {
 		close(chunkChan)
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -209,7 +237,9 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+	}
 	if p.organization != "" {
 		httpReq.Header.Set("OpenAI-Organization", p.organization)
 	}
@@ -218,9 +248,20 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 	go func() {
 		defer close(chunkChan)
 
+		// send gives up once ctx is cancelled so an abandoned reader cannot
+		// leave this goroutine blocked
+		send := func(chunk *CompletionChunk) bool {
+			select {
+			case chunkChan <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
 		resp, err := p.httpClient.Do(httpReq)
 		if err != nil {
-			chunkChan <- &CompletionChunk{Error: fmt.Errorf("request failed: %w", err)}
+			send(&CompletionChunk{Error: &transportError{err: err}})
 			return
 		}
 		defer resp.Body.Close()
@@ -228,10 +269,14 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 		// Check status code
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			chunkChan <- &CompletionChunk{Error: fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))}
+			send(&CompletionChunk{Error: newAPIError(p.name, resp, body)})
 			return
 		}
 
+		// Tool calls arrive in fragments keyed by index and are delivered whole
+		// with the final chunk
+		var toolCalls toolCallAccumulator
+
 		// Read SSE stream
 		scanner := bufio.NewScanner(resp.Body)
 		for scanner.Scan() {
@@ -246,7 +291,7 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 
 			// Stream end marker
 			if data == "[DONE]" {
-				chunkChan <- &CompletionChunk{Done: true}
+				send(&CompletionChunk{Done: true, ToolCalls: toolCalls.calls()})
 				return
 			}
 
@@ -259,8 +304,9 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 				Choices []struct {
 					Index int `json:"index"`
 					Delta struct {
-						Role    string `json:"role,omitempty"`
-						Content string `json:"content,omitempty"`
+						Role      string          `json:"role,omitempty"`
+						Content   string          `json:"content,omitempty"`
+						ToolCalls []toolCallDelta `json:"tool_calls,omitempty"`
 					} `json:"delta"`
 					FinishReason string `json:"finish_reason,omitempty"`
 				} `json:"choices"`
@@ -273,24 +319,75 @@ func (p *OpenAILLMProvider) GenerateCompletionStream(req *CompletionRequest) (<-
 			// Extract content
 			if len(streamChunk.Choices) > 0 {
 				delta := streamChunk.Choices[0].Delta
+				toolCalls.add(delta.ToolCalls)
 				if delta.Content != "" {
-					chunkChan <- &CompletionChunk{
-						Content: delta.Content,
+					if !send(&CompletionChunk{Content: delta.Content}) {
+						return
 					}
 				}
 			}
 
 			// Check for finish reason
 			if len(streamChunk.Choices) > 0 && streamChunk.Choices[0].FinishReason != "" {
-				chunkChan <- &CompletionChunk{Done: true}
+				send(&CompletionChunk{Done: true, ToolCalls: toolCalls.calls()})
 				return
 			}
 		}
 
 		if err := scanner.Err(); err != nil {
-			chunkChan <- &CompletionChunk{Error: fmt.Errorf("stream read error: %w", err)}
+			send(&CompletionChunk{Error: fmt.Errorf("stream read error: %w", err)})
 		}
 	}()
 
 	return chunkChan, nil
 }
+
+// toolCallDelta is a streamed fragment of a tool call
+type toolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// toolCallAccumulator joins streamed tool call fragments
+type toolCallAccumulator struct {
+	byIndex map[int]*ToolCall
+	order   []int
+}
+
+func (a *toolCallAccumulator) add(deltas []toolCallDelta) {
+	for _, d := range deltas {
+		if a.byIndex == nil {
+			a.byIndex = make(map[int]*ToolCall)
+		}
+		call, ok := a.byIndex[d.Index]
+		if !ok {
+			call = &ToolCall{Type: "function"}
+			a.byIndex[d.Index] = call
+			a.order = append(a.order, d.Index)
+		}
+		if d.ID != "" {
+			call.ID = d.ID
+		}
+		if d.Type != "" {
+			call.Type = d.Type
+		}
+		call.Function.Name += d.Function.Name
+		call.Function.Arguments += d.Function.Arguments
+	}
+}
+
+func (a *toolCallAccumulator) calls() []ToolCall {
+	if len(a.order) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, 0, len(a.order))
+	for _, idx := range a.order {
+		out = append(out, *a.byIndex[idx])
+	}
+	return out
+}
@@ -0,0 +1,393 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+const (
+	// defaultRateLimitPause is how long requests pause after a 429 without Retry-After
+	defaultRateLimitPause = 2 * time.Second
+
+	// minRateFactor bounds how far adaptive backoff can lower the configured rates
+	minRateFactor = 0.1
+
+	// recoveryStreak is the number of consecutive successes before rates grow back
+	recoveryStreak = 20
+
+	// concurrencyPoll is how often a request waiting for a free slot re-checks
+	concurrencyPoll = 25 * time.Millisecond
+)
+
+// RateLimitError is returned by providers when the API answers 429 Too Many Requests
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration // zero when the server did not say
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s rate limit exceeded (retry after %s): %s", e.Provider, e.RetryAfter, e.Message)
+	}
+	return fmt.Sprintf("%s rate limit exceeded: %s", e.Provider, e.Message)
+}
+
+// newRateLimitError builds a RateLimitError from a 429 response
+func newRateLimitError(provider string, resp *http.Response, body []byte) *RateLimitError {
+	return &RateLimitError{
+		Provider:   provider,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Message:    string(body),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RateLimitStats is a snapshot of a limiter's adaptive state
+type RateLimitStats struct {
+	Name              string  `json:"name"`
+	RequestsPerMinute int     `json:"requests_per_minute"`
+	TokensPerMinute   int     `json:"tokens_per_minute"`
+	RateFactor        float64 `json:"rate_factor"` // 1 = configured rate, lower after 429s
+	Concurrency       int     `json:"concurrency"`
+	MaxConcurrency    int     `json:"max_concurrency"`
+	InFlight          int     `json:"in_flight"`
+	Throttled         int64   `json:"throttled"` // 429 responses seen
+	PausedUntil       int64   `json:"paused_until,omitempty"`
+}
+
+// RateLimiter is a token bucket over requests and tokens per minute with a
+// concurrency cap. A 429 halves the rates and concurrency and pauses all
+// requests; a streak of successes grows them back towards the configured values.
+type RateLimiter struct {
+	mu   sync.Mutex
+	name string
+	cfg  config.RateLimitConfig
+
+	rpm, tpm       float64 // configured limits, <= 0 means unlimited
+	reqBucket      float64
+	tokBucket      float64
+	lastRefill     time.Time
+	factor         float64
+	pausedUntil    time.Time
+	nextPause      time.Duration
+	maxConcurrency int // <= 0 means unlimited
+	concurrency    int
+	inFlight       int
+	successes      int
+	throttled      int64
+}
+
+// NewRateLimiter creates a limiter from provider configuration. It returns nil
+// when no limit is configured; the With*RateLimit wrappers treat nil as a no-op.
+func NewRateLimiter(name string, cfg config.RateLimitConfig) *RateLimiter {
+	if cfg.RequestsPerMinute <= 0 && cfg.TokensPerMinute <= 0 && cfg.MaxConcurrency <= 0 {
+		return nil
+	}
+	l := &RateLimiter{
+		name:           name,
+		cfg:            cfg,
+		rpm:            float64(cfg.RequestsPerMinute),
+		tpm:            float64(cfg.TokensPerMinute),
+		lastRefill:     time.Now(),
+		factor:         1,
+		nextPause:      defaultRateLimitPause,
+		maxConcurrency: cfg.MaxConcurrency,
+		concurrency:    cfg.MaxConcurrency,
+	}
+	l.reqBucket = l.rpm
+	l.tokBucket = l.tpm
+	return l
+}
+
+// Acquire blocks until a request estimated at tokens may be sent, or ctx is done.
+// The returned release must be called with the request's outcome.
+func (l *RateLimiter) Acquire(ctx context.Context, tokens int) (release func(error), err error) {
+	for {
+		wait := l.tryAcquire(float64(tokens))
+		if wait == 0 {
+			var once sync.Once
+			return func(err error) { once.Do(func() { l.release(err) }) }, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// tryAcquire takes capacity and returns 0, or returns how long to wait
+func (l *RateLimiter) tryAcquire(tokens float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.maxConcurrency > 0 && l.inFlight >= l.concurrency {
+		return concurrencyPoll
+	}
+	l.refill(now)
+
+	var wait time.Duration
+	if l.rpm > 0 && l.reqBucket < 1 {
+		wait = deficitWait(1-l.reqBucket, l.rpm*l.factor)
+	}
+	if l.tpm > 0 {
+		// A request larger than the whole bucket only waits for a full bucket
+		need := min(tokens, l.tpm*l.factor)
+		if l.tokBucket < need {
+			if w := deficitWait(need-l.tokBucket, l.tpm*l.factor); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	if l.rpm > 0 {
+		l.reqBucket--
+	}
+	if l.tpm > 0 {
+		l.tokBucket -= tokens
+	}
+	l.inFlight++
+	return 0
+}
+
+// refill adds capacity for the time elapsed since the last refill
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.lastRefill).Minutes()
+	l.lastRefill = now
+	if l.rpm > 0 {
+		l.reqBucket = min(l.reqBucket+elapsed*l.rpm*l.factor, l.rpm*l.factor)
+	}
+	if l.tpm > 0 {
+		l.tokBucket = min(l.tokBucket+elapsed*l.tpm*l.factor, l.tpm*l.factor)
+	}
+}
+
+func (l *RateLimiter) release(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight > 0 {
+		l.inFlight--
+	}
+
+	var rle *RateLimitError
+	switch {
+	case errors.As(err, &rle):
+		l.throttled++
+		l.successes = 0
+		l.factor = max(l.factor/2, minRateFactor)
+		if l.maxConcurrency > 0 {
+			l.concurrency = max(l.concurrency/2, 1)
+		}
+		pause := rle.RetryAfter
+		if pause <= 0 {
+			pause = l.nextPause
+			l.nextPause = min(l.nextPause*2, time.Minute)
+		}
+		if until := time.Now().Add(pause); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+		// Drop accumulated burst so the lower rate takes effect immediately
+		l.reqBucket = 0
+		l.tokBucket = 0
+		logger.WarnWithFields(context.Background(), map[string]interface{}{
+			"provider":    l.name,
+			"pause_ms":    pause.Milliseconds(),
+			"rate_factor": l.factor,
+			"concurrency": l.concurrency,
+		}, "Provider rate limit hit, backing off")
+	case err == nil:
+		l.successes++
+		if l.successes >= recoveryStreak {
+			l.successes = 0
+			l.factor = min(l.factor*1.25, 1)
+			if l.maxConcurrency > 0 && l.concurrency < l.maxConcurrency {
+				l.concurrency++
+			}
+			l.nextPause = defaultRateLimitPause
+		}
+	}
+}
+
+// Stats returns a snapshot of the limiter state
+func (l *RateLimiter) Stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := RateLimitStats{
+		Name:              l.name,
+		RequestsPerMinute: int(l.rpm),
+		TokensPerMinute:   int(l.tpm),
+		RateFactor:        l.factor,
+		Concurrency:       l.concurrency,
+		MaxConcurrency:    l.maxConcurrency,
+		InFlight:          l.inFlight,
+		Throttled:         l.throttled,
+	}
+	if l.pausedUntil.After(time.Now()) {
+		stats.PausedUntil = l.pausedUntil.UnixMilli()
+	}
+	return stats
+}
+
+// estimateTokens approximates the token count of text (about 4 bytes per token)
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+func deficitWait(deficit, perMinute float64) time.Duration {
+	if perMinute <= 0 {
+		return concurrencyPoll
+	}
+	wait := time.Duration(deficit / perMinute * float64(time.Minute))
+	if wait < time.Millisecond {
+		wait = time.Millisecond
+	}
+	return wait
+}
+
+// rateLimitedEmbeddingProvider applies a RateLimiter to an EmbeddingProvider
+type rateLimitedEmbeddingProvider struct {
+	EmbeddingProvider
+	limiter *RateLimiter
+}
+
+// WithEmbeddingRateLimit wraps provider so every request goes through limiter
+func WithEmbeddingRateLimit(provider EmbeddingProvider, limiter *RateLimiter) EmbeddingProvider {
+	if limiter == nil {
+		return provider
+	}
+	return &rateLimitedEmbeddingProvider{EmbeddingProvider: provider, limiter: limiter}
+}
+
+// Unwrap returns the wrapped provider
+func (p *rateLimitedEmbeddingProvider) Unwrap() EmbeddingProvider {
+	return p.EmbeddingProvider
+}
+
+func (p *rateLimitedEmbeddingProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	release, err := p.limiter.Acquire(ctx, estimateTokens(req.Text))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.EmbeddingProvider.GenerateEmbedding(ctx, req)
+	release(err)
+	return resp, err
+}
+
+func (p *rateLimitedEmbeddingProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	tokens := 0
+	for _, t := range texts {
+		tokens += estimateTokens(t)
+	}
+	release, err := p.limiter.Acquire(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.EmbeddingProvider.GenerateEmbeddingsBatch(ctx, texts)
+	release(err)
+	return resp, err
+}
+
+// rateLimitedLLMProvider applies a RateLimiter to an LLMProvider
+type rateLimitedLLMProvider struct {
+	LLMProvider
+	limiter *RateLimiter
+}
+
+// WithLLMRateLimit wraps provider so every completion goes through limiter
+func WithLLMRateLimit(provider LLMProvider, limiter *RateLimiter) LLMProvider {
+	if limiter == nil {
+		return provider
+	}
+	return &rateLimitedLLMProvider{LLMProvider: provider, limiter: limiter}
+}
+
+func completionTokens(req *CompletionRequest) int {
+	return promptTokens(req) + req.MaxTokens
+}
+
+func (p *rateLimitedLLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	release, err := p.limiter.Acquire(ctx, completionTokens(req))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.LLMProvider.GenerateCompletion(ctx, req)
+	release(err)
+	return resp, err
+}
+
+// GenerateCompletionStream holds a concurrency slot until the stream ends and
+// reports the first stream error, so a 429 inside the stream still backs off
+func (p *rateLimitedLLMProvider) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
+	release, err := p.limiter.Acquire(ctx, completionTokens(req))
+	if err != nil {
+		return nil, err
+	}
+	in, err := p.LLMProvider.GenerateCompletionStream(ctx, req)
+	if err != nil {
+		release(err)
+		return nil, err
+	}
+	out := make(chan *CompletionChunk, cap(in))
+	go func() {
+		defer close(out)
+		var streamErr error
+		defer func() { release(streamErr) }()
+		for chunk := range in {
+			if chunk.Error != nil && streamErr == nil {
+				streamErr = chunk.Error
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// LLMRateLimitStats returns the limiter state of a provider wrapped by
+// WithLLMRateLimit, or nil if the provider is not rate limited
+func LLMRateLimitStats(provider LLMProvider) *RateLimitStats {
+	if p, ok := provider.(*rateLimitedLLMProvider); ok {
+		stats := p.limiter.Stats()
+		return &stats
+	}
+	return nil
+}
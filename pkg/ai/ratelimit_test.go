@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+func TestNewRateLimiterUnconfigured(t *testing.T) {
+	if l := NewRateLimiter("none", config.RateLimitConfig{}); l != nil {
+		t.Fatalf("limiter without limits = %+v, want nil", l)
+	}
+}
+
+func TestRateLimiterRefillsTokens(t *testing.T) {
+	l := NewRateLimiter("test", config.RateLimitConfig{RequestsPerMinute: 60, TokensPerMinute: 6000})
+
+	// An empty bucket waits for the deficit at the configured rate
+	l.reqBucket, l.tokBucket = 0, 0
+	if wait := l.tryAcquire(10); wait < 900*time.Millisecond || wait > time.Second {
+		t.Fatalf("wait with empty buckets = %s, want about a second", wait)
+	}
+
+	// Half a minute later half the capacity is back
+	l.reqBucket, l.tokBucket = 0, 0
+	l.lastRefill = time.Now().Add(-30 * time.Second)
+	if wait := l.tryAcquire(100); wait != 0 {
+		t.Fatalf("wait after refill = %s, want 0", wait)
+	}
+	if math.Abs(l.reqBucket-29) > 0.1 || math.Abs(l.tokBucket-2900) > 10 {
+		t.Fatalf("buckets = %v requests, %v tokens, want about 29 and 2900", l.reqBucket, l.tokBucket)
+	}
+
+	// Refilling never exceeds one minute of capacity
+	l.lastRefill = time.Now().Add(-10 * time.Minute)
+	l.refill(time.Now())
+	if l.reqBucket != 60 || l.tokBucket != 6000 {
+		t.Fatalf("buckets after a long idle = %v, %v, want 60 and 6000", l.reqBucket, l.tokBucket)
+	}
+
+	// A request larger than the bucket waits for a full bucket, not forever
+	l.tokBucket = 0
+	if wait := l.tryAcquire(1_000_000); wait > time.Minute {
+		t.Fatalf("wait for an oversized request = %s, want at most a minute", wait)
+	}
+}
+
+func TestRateLimiterAcquireHonoursCancellation(t *testing.T) {
+	l := NewRateLimiter("test", config.RateLimitConfig{RequestsPerMinute: 1})
+	release, err := l.Acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(nil)
+
+	// The next request would wait about a minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := l.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v, want the context error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Acquire returned after %s, want soon after cancellation", elapsed)
+	}
+}
+
+func TestRateLimiterConcurrencyCap(t *testing.T) {
+	l := NewRateLimiter("test", config.RateLimitConfig{MaxConcurrency: 1})
+	release, err := l.Acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		second, err := l.Acquire(context.Background(), 1)
+		if err == nil {
+			second(nil)
+		}
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("second request ran while the first was in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release(nil)
+	release(nil) // Releasing twice frees one slot only
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second request still blocked after the first was released")
+	}
+	if stats := l.Stats(); stats.InFlight != 0 {
+		t.Fatalf("in flight = %d, want 0", stats.InFlight)
+	}
+}
+
+func TestRateLimiterBacksOffAfter429(t *testing.T) {
+	l := NewRateLimiter("test", config.RateLimitConfig{RequestsPerMinute: 60, MaxConcurrency: 4})
+	release, err := l.Acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release(&RateLimitError{Provider: "test", RetryAfter: time.Minute})
+
+	stats := l.Stats()
+	if stats.RateFactor != 0.5 || stats.Concurrency != 2 || stats.Throttled != 1 || stats.PausedUntil == 0 {
+		t.Fatalf("stats after a 429 = %+v", stats)
+	}
+	if wait := l.tryAcquire(1); wait < 59*time.Second {
+		t.Fatalf("wait while paused = %s, want the Retry-After", wait)
+	}
+
+	// A streak of successes grows the rates back
+	l.pausedUntil = time.Time{}
+	for i := 0; i < recoveryStreak; i++ {
+		l.release(nil)
+	}
+	if stats := l.Stats(); stats.RateFactor != 0.625 || stats.Concurrency != 3 {
+		t.Fatalf("stats after recovery = %+v", stats)
+	}
+}
@@ -2,7 +2,10 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,8 +18,12 @@ type Service struct {
 	mu              sync.RWMutex
 	cfg             *config.Config
 	providers       map[string]EmbeddingProvider
+	limiters        map[string]*RateLimiter
+	usage           *UsageTracker
+	debug           *DebugRecorder
 	chunkers        map[string]ChunkingStrategy
 	currentProvider string
+	health          healthMonitor
 }
 
 // NewService creates a new AI service
@@ -28,6 +35,9 @@ func NewService(cfg *config.Config) *Service {
 	s := &Service{
 		cfg:       cfg,
 		providers: make(map[string]EmbeddingProvider),
+		limiters:  make(map[string]*RateLimiter),
+		usage:     &UsageTracker{},
+		debug:     &DebugRecorder{},
 		chunkers:  make(map[string]ChunkingStrategy),
 	}
 
@@ -47,6 +57,10 @@ func (s *Service) Initialize() error {
 
 	s.currentProvider = s.cfg.GetProvider()
 
+	if err := ApplyNetworkConfig(s.cfg.GetNetworkConfig()); err != nil {
+		logger.WarnWithFields(context.TODO(), map[string]interface{}{"error": err.Error()}, "Ignoring invalid network config")
+	}
+
 	// Initialize OpenAI provider if configured
 	if s.cfg.IsOpenAIConfigured() {
 		openaiCfg := s.cfg.GetOpenAIConfig()
@@ -58,7 +72,7 @@ func (s *Service) Initialize() error {
 			EmbeddingModel: openaiCfg.EmbeddingModel,
 		})
 		if err == nil {
-			s.providers["openai"] = provider
+			s.providers["openai"] = WithEmbeddingUsage(s.withRateLimit("openai", openaiCfg.RateLimit, WithEmbeddingDebug(provider, "openai", s.debug)), "openai", s.usage)
 			logger.Debug("OpenAI provider initialized")
 		} else {
 			logger.WarnWithFields(context.TODO(), map[string]interface{}{"error": err.Error()}, "Failed to initialize OpenAI provider")
@@ -73,7 +87,7 @@ func (s *Service) Initialize() error {
 		Timeout: time.Duration(ollamaCfg.Timeout) * time.Second,
 	})
 	if err == nil {
-		s.providers["ollama"] = provider
+		s.providers["ollama"] = WithEmbeddingUsage(s.withRateLimit("ollama", ollamaCfg.RateLimit, WithEmbeddingDebug(provider, "ollama", s.debug)), "ollama", s.usage)
 		logger.DebugWithFields(context.TODO(), map[string]interface{}{
 			"base_url": ollamaCfg.BaseURL,
 			"model":    ollamaCfg.EmbeddingModel,
@@ -82,6 +96,40 @@ func (s *Service) Initialize() error {
 		logger.WarnWithFields(context.TODO(), map[string]interface{}{"error": err.Error()}, "Failed to initialize Ollama provider")
 	}
 
+	// Initialize the custom OpenAI-compatible provider when a base URL is set
+	customCfg := s.cfg.GetCustomConfig()
+	if customCfg.BaseURL != "" {
+		custom, err := NewCustomProvider(OpenAIConfig{
+			APIKey:         customCfg.APIKey,
+			BaseURL:        customCfg.BaseURL,
+			Timeout:        time.Duration(customCfg.Timeout) * time.Second,
+			EmbeddingModel: customCfg.EmbeddingModel,
+		})
+		if err == nil {
+			s.providers["custom"] = WithEmbeddingUsage(s.withRateLimit("custom", customCfg.RateLimit, WithEmbeddingDebug(custom, "custom", s.debug)), "custom", s.usage)
+			logger.DebugWithFields(context.TODO(), map[string]interface{}{"base_url": customCfg.BaseURL}, "Custom provider initialized")
+		} else {
+			logger.WarnWithFields(context.TODO(), map[string]interface{}{"error": err.Error()}, "Failed to initialize custom provider")
+		}
+	} else {
+		delete(s.providers, "custom")
+	}
+
+	// Initialize the builtin provider once its model files are present
+	builtinCfg := s.cfg.GetBuiltinConfig()
+	builtin, err := NewBuiltinProvider(BuiltinConfig{
+		ModelDir:    builtinCfg.ModelDir,
+		DownloadURL: builtinCfg.DownloadURL,
+	})
+	if err != nil {
+		logger.WarnWithFields(context.TODO(), map[string]interface{}{"error": err.Error()}, "Failed to initialize builtin provider")
+	} else if builtin.Available() {
+		s.providers["builtin"] = WithEmbeddingUsage(WithEmbeddingDebug(builtin, "builtin", s.debug), "builtin", s.usage)
+		logger.DebugWithFields(context.TODO(), map[string]interface{}{"model_dir": builtin.ModelDir()}, "Builtin provider initialized")
+	} else {
+		delete(s.providers, "builtin")
+	}
+
 	// Initialize chunkers
 	chunkCfg := s.cfg.GetChunkingConfig()
 
@@ -113,7 +161,7 @@ func (s *Service) Initialize() error {
 	// Validate that we have at least one provider
 	if len(s.providers) == 0 {
 		logger.Error("No embedding provider available")
-		return fmt.Errorf("no embedding provider available - please configure OpenAI or ensure Ollama is running")
+		return fmt.Errorf("no embedding provider available - please configure OpenAI, ensure Ollama is running or download the builtin model")
 	}
 
 	// Validate that the current provider is available
@@ -128,6 +176,42 @@ func (s *Service) Initialize() error {
 	return nil
 }
 
+// withRateLimit wraps provider with a limiter when any limit is configured.
+// Limiters are kept across re-initialization so adaptive state survives config saves.
+func (s *Service) withRateLimit(name string, cfg config.RateLimitConfig, provider EmbeddingProvider) EmbeddingProvider {
+	limiter, ok := s.limiters[name]
+	if !ok || limiter.cfg != cfg {
+		limiter = NewRateLimiter(name, cfg)
+		if limiter == nil {
+			delete(s.limiters, name)
+			return provider
+		}
+		s.limiters[name] = limiter
+	}
+	return WithEmbeddingRateLimit(provider, limiter)
+}
+
+// UsageTracker returns the tracker that receives token usage of all provider calls
+func (s *Service) UsageTracker() *UsageTracker {
+	return s.usage
+}
+
+// DebugRecorder returns the recorder of provider calls for AI debug mode
+func (s *Service) DebugRecorder() *DebugRecorder {
+	return s.debug
+}
+
+// GetRateLimitStats returns the state of each embedding provider's rate limiter
+func (s *Service) GetRateLimitStats() []RateLimitStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make([]RateLimitStats, 0, len(s.limiters))
+	for _, l := range s.limiters {
+		stats = append(stats, l.Stats())
+	}
+	return stats
+}
+
 // GetProvider returns the current embedding provider
 func (s *Service) GetProvider() (EmbeddingProvider, error) {
 	s.mu.RLock()
@@ -172,16 +256,16 @@ func (s *Service) getAvailableProvidersLocked() []string {
 }
 
 // GenerateEmbedding creates an embedding for a single text using the current provider
-func (s *Service) GenerateEmbedding(text string) (*EmbeddingResponse, error) {
+func (s *Service) GenerateEmbedding(ctx context.Context, text string) (*EmbeddingResponse, error) {
 	provider, err := s.GetProvider()
 	if err != nil {
 		return nil, err
 	}
 
 	var resp *EmbeddingResponse
-	err = retryWithBackoff(func() error {
+	err = retryWithBackoff(ctx, embeddingRetry, func() error {
 		var opErr error
-		resp, opErr = provider.GenerateEmbedding(&EmbeddingRequest{
+		resp, opErr = provider.GenerateEmbedding(ctx, &EmbeddingRequest{
 			Text:  text,
 			Model: s.cfg.GetEmbeddingModel(),
 		})
@@ -191,8 +275,31 @@ func (s *Service) GenerateEmbedding(text string) (*EmbeddingResponse, error) {
 	return resp, err
 }
 
-// GenerateEmbeddingsBatch creates embeddings for multiple texts
-func (s *Service) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingResponse, error) {
+// GenerateEmbeddingWithModel creates an embedding using an explicit model on the
+// current provider, e.g. to fill a secondary embedding namespace
+func (s *Service) GenerateEmbeddingWithModel(ctx context.Context, model, text string) (*EmbeddingResponse, error) {
+	provider, err := s.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *EmbeddingResponse
+	err = retryWithBackoff(ctx, embeddingRetry, func() error {
+		var opErr error
+		resp, opErr = provider.GenerateEmbedding(ctx, &EmbeddingRequest{
+			Text:  text,
+			Model: model,
+		})
+		return opErr
+	})
+
+	return resp, err
+}
+
+// GenerateEmbeddingsBatch creates embeddings for multiple texts. The result
+// has one entry per text; if some texts cannot be embedded even after
+// retrying them, their entries are nil and a *BatchError lists them.
+func (s *Service) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
@@ -207,42 +314,107 @@ func (s *Service) GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingResponse,
 		batchSize = 32
 	}
 
-	// Process in batches if needed
-	if len(texts) <= batchSize {
-		var resp []*EmbeddingResponse
-		err = retryWithBackoff(func() error {
-			var opErr error
-			resp, opErr = provider.GenerateEmbeddingsBatch(texts)
-			return opErr
-		})
-		return resp, err
-	}
-
-	// Split into multiple batches
-	var allResults []*EmbeddingResponse
+	results := make([]*EmbeddingResponse, len(texts))
+	errs := make([]error, len(texts))
 	for i := 0; i < len(texts); i += batchSize {
-		end := i + batchSize
-		if end > len(texts) {
-			end = len(texts)
+		end := min(i+batchSize, len(texts))
+		err := embedBatch(ctx, provider, texts[i:end], results[i:end], errs[i:end])
+		if err == nil {
+			continue
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return results, ctxErr
 		}
+		if !hasResult(results) {
+			return nil, err
+		}
+		// The provider is failing whole batches: stop, keeping what was embedded
+		err = fmt.Errorf("batch %d-%d failed: %w", i, end, err)
+		for j := i; j < len(texts); j++ {
+			errs[j] = err
+		}
+		break
+	}
+	return results, newBatchError(errs)
+}
 
-		batch := texts[i:end]
-		var results []*EmbeddingResponse
+// GenerateEmbeddingsBatchWithModel is GenerateEmbeddingsBatch with an
+// explicit model on the current provider, e.g. to fill a language model's
+// namespace
+func (s *Service) GenerateEmbeddingsBatchWithModel(ctx context.Context, model string, texts []string) ([]*EmbeddingResponse, error) {
+	return s.GenerateEmbeddingsBatch(withBatchModel(ctx, model), texts)
+}
 
-		err := retryWithBackoff(func() error {
-			var opErr error
-			results, opErr = provider.GenerateEmbeddingsBatch(batch)
-			return opErr
-		})
+type batchModelKey struct{}
 
-		if err != nil {
-			return allResults, fmt.Errorf("batch %d-%d failed: %w", i, end, err)
-		}
+// withBatchModel makes batch embeddings requested with ctx use model instead
+// of the provider's default; EmbeddingProvider.GenerateEmbeddingsBatch has
+// no model argument
+func withBatchModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, batchModelKey{}, model)
+}
 
-		allResults = append(allResults, results...)
+// batchModel returns the model batch embeddings requested with ctx use
+func batchModel(ctx context.Context, fallback string) string {
+	if model, ok := ctx.Value(batchModelKey{}).(string); ok && model != "" {
+		return model
+	}
+	return fallback
+}
+
+// embedBatch embeds texts into results with retries. Retries only resend the
+// texts that failed; those still failing at the end get their error in errs.
+// An error is returned if the provider failed the batch as a whole.
+func embedBatch(ctx context.Context, provider EmbeddingProvider, texts []string, results []*EmbeddingResponse, errs []error) error {
+	pending := make([]int, len(texts))
+	for i := range pending {
+		pending[i] = i
+	}
+	err := retryWithBackoff(ctx, embeddingRetry, func() error {
+		batch := make([]string, len(pending))
+		for j, idx := range pending {
+			batch[j] = texts[idx]
+		}
+		resps, err := provider.GenerateEmbeddingsBatch(ctx, batch)
+		var batchErr *BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			return err
+		}
+		var failed []int
+		for j, idx := range pending {
+			if j < len(resps) && resps[j] != nil {
+				results[idx], errs[idx] = resps[j], nil
+				continue
+			}
+			errs[idx] = fmt.Errorf("no embedding returned")
+			failed = append(failed, idx)
+		}
+		if batchErr != nil {
+			for _, f := range batchErr.Failed {
+				if f.Index < len(pending) {
+					errs[pending[f.Index]] = f.Error
+				}
+			}
+		}
+		pending = failed
+		if len(pending) > 0 {
+			return fmt.Errorf("%d of %d texts failed to embed", len(pending), len(texts))
+		}
+		return nil
+	})
+	if err != nil && len(pending) == len(texts) && !hasResult(results) {
+		return err
 	}
+	return nil
+}
 
-	return allResults, nil
+func hasResult(results []*EmbeddingResponse) bool {
+	for _, r := range results {
+		if r != nil {
+			return true
+		}
+	}
+	return false
 }
 
 // ChunkText splits text using the configured chunking strategy
@@ -256,7 +428,7 @@ func (s *Service) ChunkText(text string) ([]TextChunk, error) {
 	}
 	s.mu.RUnlock()
 
-	return chunker.Chunk(text)
+	return detectChunkLanguages(chunker.Chunk(text))
 }
 
 // ChunkTextWithStrategy splits text using a specific strategy
@@ -269,7 +441,55 @@ func (s *Service) ChunkTextWithStrategy(text, strategy string) ([]TextChunk, err
 		return nil, fmt.Errorf("unknown chunking strategy: %s", strategy)
 	}
 
-	return chunker.Chunk(text)
+	return detectChunkLanguages(chunker.Chunk(text))
+}
+
+// detectChunkLanguages sets the language of each chunk
+func detectChunkLanguages(chunks []TextChunk, err error) ([]TextChunk, error) {
+	for i := range chunks {
+		chunks[i].Language = DetectLanguage(chunks[i].Content)
+	}
+	return chunks, err
+}
+
+// EmbeddingText returns the text of a chunk to send to the embedding model:
+// its content, without math if so configured. A chunk that is all math keeps
+// it, as there would be nothing left to embed.
+func (s *Service) EmbeddingText(content string) string {
+	if !s.cfg.GetChunkingConfig().StripMathForEmbedding {
+		return content
+	}
+	if stripped := StripMath(content); strings.TrimSpace(stripped) != "" {
+		return stripped
+	}
+	return content
+}
+
+// LanguageModel returns the embedding model configured for chunks in lang,
+// or "" when they only get the default embedding
+func (s *Service) LanguageModel(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	model := s.cfg.GetLanguageModels()[lang]
+	if model == s.cfg.GetEmbeddingModel() {
+		return ""
+	}
+	return model
+}
+
+// QueryModels returns the language models a query should also be embedded
+// with to reach chunks in langs, without duplicates
+func (s *Service) QueryModels(langs ...string) []string {
+	var models []string
+	seen := make(map[string]bool)
+	for _, lang := range langs {
+		if model := s.LanguageModel(lang); model != "" && !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	return models
 }
 
 // GetAvailableStrategies returns a list of available chunking strategies
@@ -288,8 +508,9 @@ func (s *Service) getAvailableStrategiesLocked() []string {
 	return strategies
 }
 
-// ProcessDocument chunks text and generates embeddings for all chunks
-func (s *Service) ProcessDocument(text string) ([]TextChunk, error) {
+// ProcessDocument chunks text and generates embeddings for all chunks. If only
+// some chunks could be embedded, they are returned with a *BatchError.
+func (s *Service) ProcessDocument(ctx context.Context, text string) ([]TextChunk, error) {
 	// First, chunk the text
 	chunks, err := s.ChunkText(text)
 	if err != nil {
@@ -303,13 +524,10 @@ func (s *Service) ProcessDocument(text string) ([]TextChunk, error) {
 	// Generate embeddings for all chunks
 	texts := make([]string, len(chunks))
 	for i, chunk := range chunks {
-		texts[i] = chunk.Content
+		texts[i] = s.EmbeddingText(chunk.Content)
 	}
 
-	embeddings, err := s.GenerateEmbeddingsBatch(texts)
-	if err != nil {
-		return chunks, fmt.Errorf("embedding generation failed: %w", err)
-	}
+	embeddings, err := s.GenerateEmbeddingsBatch(ctx, texts)
 
 	// Attach embeddings to chunks
 	for i, emb := range embeddings {
@@ -319,6 +537,9 @@ func (s *Service) ProcessDocument(text string) ([]TextChunk, error) {
 		}
 	}
 
+	if err != nil {
+		return chunks, fmt.Errorf("embedding generation failed: %w", err)
+	}
 	return chunks, nil
 }
 
@@ -371,6 +592,58 @@ func (s *Service) SetOllamaConfig(baseURL, model string, timeout int) error {
 	return s.Initialize()
 }
 
+// SetCustomConfig updates the custom OpenAI-compatible provider configuration
+func (s *Service) SetCustomConfig(baseURL, apiKey, embeddingModel string, timeout int) error {
+	s.cfg.SetCustomConfig(baseURL, apiKey, embeddingModel, timeout)
+
+	// Reinitialize to apply changes
+	return s.Initialize()
+}
+
+// ListModels discovers the models a provider currently offers, for settings
+// dropdowns. OpenAI and Ollama fall back to DefaultChatModels when the server
+// cannot be reached.
+func (s *Service) ListModels(ctx context.Context, provider string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, modelDiscoveryTimeout)
+	defer cancel()
+
+	client := NewHTTPClient(modelDiscoveryTimeout)
+	var models []string
+	var err error
+	switch provider {
+	case "openai":
+		cfg := s.cfg.GetOpenAIConfig()
+		if cfg.APIKey == "" {
+			cfg = s.cfg.GetLLMConfig().OpenAI
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		models, err = ListOpenAICompatibleModels(ctx, client, baseURL, cfg.APIKey, cfg.Organization)
+	case "ollama":
+		models, err = ListOllamaModels(ctx, s.cfg.GetOllamaConfig().BaseURL)
+	case "custom":
+		cfg := s.cfg.GetCustomConfig()
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("custom provider base URL is not configured")
+		}
+		models, err = ListOpenAICompatibleModels(ctx, client, cfg.BaseURL, cfg.APIKey, "")
+	case "builtin":
+		return []string{BuiltinModel}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider '%s'", provider)
+	}
+	if err != nil {
+		if defaults, ok := DefaultChatModels[provider]; ok {
+			logger.WarnWithFields(ctx, map[string]interface{}{"provider": provider, "error": err.Error()}, "Model discovery failed, using defaults")
+			return defaults, nil
+		}
+		return nil, err
+	}
+	return models, nil
+}
+
 // Status returns the current status of the AI service
 type ServiceStatus struct {
 	CurrentProvider     string   `json:"current_provider"`
@@ -397,7 +670,11 @@ func (s *Service) GetStatus() (*ServiceStatus, error) {
 
 	// Get model dimension
 	if provider, ok := s.providers[s.currentProvider]; ok {
-		status.ProviderHealthy = provider.ValidateConfig() == nil
+		if healthy, known := s.cachedHealth(s.currentProvider); known {
+			status.ProviderHealthy = healthy
+		} else {
+			status.ProviderHealthy = provider.ValidateConfig() == nil
+		}
 		model := status.CurrentModel
 		if model == "" {
 			model = provider.GetDefaultModel()
@@ -410,22 +687,83 @@ func (s *Service) GetStatus() (*ServiceStatus, error) {
 	return status, nil
 }
 
-// retryWithBackoff executes an operation with exponential backoff retries
-func retryWithBackoff(operation func() error) error {
-	maxRetries := 3
-	backoff := 500 * time.Millisecond
+// retryPolicy controls how retryWithBackoff retries an operation
+type retryPolicy struct {
+	// name describes the operation in log messages
+	name string
+	// attempts is how often the operation is tried in total
+	attempts int
+	// backoff is the wait before the first retry; it doubles after each
+	backoff time.Duration
+	// maxWait is the longest a retry waits; a longer Retry-After fails the
+	// operation instead
+	maxWait time.Duration
+	// retryable reports whether a failure may go away when tried again
+	retryable func(error) bool
+}
 
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		if err = operation(); err == nil {
-			return nil
-		}
+var (
+	embeddingRetry = retryPolicy{
+		name:      "embedding",
+		attempts:  3,
+		backoff:   500 * time.Millisecond,
+		maxWait:   30 * time.Second,
+		retryable: retryableEmbeddingError,
+	}
+	completionRetry = retryPolicy{
+		name:      "completion",
+		attempts:  3,
+		backoff:   time.Second,
+		maxWait:   30 * time.Second,
+		retryable: retryableCompletionError,
+	}
+)
 
-		// Don't sleep after the last attempt
-		if i < maxRetries-1 {
-			time.Sleep(backoff)
-			backoff *= 2
+// wait returns how long to wait before retry number attempt (from 0) after
+// err, and false if waiting is not worth it
+func (p retryPolicy) wait(err error, attempt int) (time.Duration, bool) {
+	base := p.backoff << attempt
+	// Jitter spreads out clients that failed together
+	wait := base/2 + rand.N(base/2+1)
+	var rle *RateLimitError
+	if errors.As(err, &rle) && rle.RetryAfter > wait {
+		wait = rle.RetryAfter
+	}
+	return wait, wait <= p.maxWait
+}
+
+// retryWithBackoff runs operation until it succeeds, fails in a way policy
+// does not retry or runs out of attempts, waiting with jittered exponential
+// backoff in between and honouring Retry-After. It stops early, returning
+// the context error, once ctx is done.
+func retryWithBackoff(ctx context.Context, policy retryPolicy, operation func() error) error {
+	for attempt := 0; ; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		err := operation()
+		if err == nil || attempt >= policy.attempts-1 || !policy.retryable(err) {
+			return err
+		}
+		wait, ok := policy.wait(err, attempt)
+		if !ok {
+			return err
+		}
+		logger.Warn("%s failed, retrying in %s: %v", policy.name, wait.Round(time.Millisecond), err)
+		if ctxErr := sleepContext(ctx, wait); ctxErr != nil {
+			return ctxErr
 		}
 	}
-	return err
+}
+
+// sleepContext waits for d, returning ctx.Err() if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
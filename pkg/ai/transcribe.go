@@ -0,0 +1,184 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"notebit/pkg/config"
+)
+
+// transcriptionTimeout bounds one transcription request; voice memos can run
+// for an hour
+const transcriptionTimeout = 10 * time.Minute
+
+// Transcriber turns speech in an audio file into text
+type Transcriber interface {
+	Name() string
+	Transcribe(ctx context.Context, audioPath string) (string, error)
+}
+
+// NewTranscriber creates the transcriber selected by cfg. The OpenAI
+// transcriber authenticates with the OpenAI embedding provider settings.
+func NewTranscriber(cfg config.TranscriptionConfig, openai config.OpenAIConfig) (Transcriber, error) {
+	switch cfg.Provider {
+	case config.TranscriptionOpenAI:
+		if openai.APIKey == "" {
+			return nil, fmt.Errorf("OpenAI API key is required for transcription")
+		}
+		baseURL := openai.BaseURL
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		model := cfg.OpenAIModel
+		if model == "" {
+			model = "whisper-1"
+		}
+		return &OpenAITranscriber{
+			apiKey:       openai.APIKey,
+			baseURL:      strings.TrimSuffix(baseURL, "/"),
+			organization: openai.Organization,
+			model:        model,
+			language:     cfg.Language,
+			httpClient:   NewHTTPClient(transcriptionTimeout),
+		}, nil
+	case config.TranscriptionWhisperCpp, "":
+		if cfg.WhisperModel == "" {
+			return nil, fmt.Errorf("whisper.cpp model path is required for transcription")
+		}
+		binary := cfg.WhisperBinary
+		if binary == "" {
+			binary = "whisper-cli"
+		}
+		return &WhisperCppTranscriber{binary: binary, model: cfg.WhisperModel, language: cfg.Language}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider: %s", cfg.Provider)
+	}
+}
+
+// WhisperCppTranscriber runs a local whisper.cpp command line program
+type WhisperCppTranscriber struct {
+	binary   string
+	model    string
+	language string
+}
+
+// Name returns the transcriber name
+func (t *WhisperCppTranscriber) Name() string {
+	return config.TranscriptionWhisperCpp
+}
+
+// Transcribe runs whisper.cpp on audioPath and returns the text it prints
+func (t *WhisperCppTranscriber) Transcribe(ctx context.Context, audioPath string) (string, error) {
+	language := t.language
+	if language == "" {
+		language = "auto"
+	}
+	ctx, cancel := context.WithTimeout(ctx, transcriptionTimeout)
+	defer cancel()
+
+	// -nt drops timestamps and -np progress output, leaving just the text
+	cmd := exec.CommandContext(ctx, t.binary, "-m", t.model, "-l", language, "-nt", "-np", "-f", audioPath)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return "", fmt.Errorf("whisper.cpp failed: %w: %s", err, msg)
+	}
+
+	lines := strings.Split(stdout.String(), "\n")
+	text := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			text = append(text, line)
+		}
+	}
+	return strings.Join(text, "\n"), nil
+}
+
+// OpenAITranscriber uses the OpenAI audio transcription API
+type OpenAITranscriber struct {
+	apiKey       string
+	baseURL      string
+	organization string
+	model        string
+	language     string
+	httpClient   *http.Client
+}
+
+// Name returns the transcriber name
+func (t *OpenAITranscriber) Name() string {
+	return config.TranscriptionOpenAI
+}
+
+// Transcribe uploads audioPath and returns the transcript
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audioPath string) (string, error) {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", fmt.Errorf("failed to read audio: %w", err)
+	}
+	fields := map[string]string{"model": t.model, "response_format": "json"}
+	if t.language != "" {
+		fields["language"] = t.language
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if t.organization != "" {
+		req.Header.Set("OpenAI-Organization", t.organization)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return "", &transportError{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", newAPIError("openai", resp, data)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
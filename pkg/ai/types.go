@@ -1,5 +1,7 @@
 package ai
 
+import "context"
+
 // EmbeddingRequest represents a request to generate embeddings
 type EmbeddingRequest struct {
 	Text   string  // The text to embed
@@ -36,10 +38,10 @@ type EmbeddingResult struct {
 // EmbeddingProvider defines the interface for embedding service providers
 type EmbeddingProvider interface {
 	// GenerateEmbedding creates an embedding for a single text
-	GenerateEmbedding(req *EmbeddingRequest) (*EmbeddingResponse, error)
+	GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
 
 	// GenerateEmbeddingsBatch creates embeddings for multiple texts in a single request
-	GenerateEmbeddingsBatch(texts []string) ([]*EmbeddingResponse, error)
+	GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error)
 
 	// GetModelDimension returns the output dimension for a given model
 	GetModelDimension(model string) (int, error)
@@ -61,6 +63,7 @@ type TextChunk struct {
 	Index     int       // Position in the original text
 	Embedding []float32 // Vector embedding (populated after processing)
 	ModelName string    // Model used to generate embedding
+	Language  string    // Detected ISO 639-1 language, "" if unknown
 }
 
 // ChunkingStrategy defines the interface for text chunking strategies
@@ -0,0 +1,285 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Usage kinds
+const (
+	UsageKindEmbedding  = "embedding"
+	UsageKindCompletion = "completion"
+)
+
+// Operation labels for WithOperation
+const (
+	OperationIndexing  = "indexing"
+	OperationRAG       = "rag"
+	OperationSearch    = "search"
+	OperationNamespace = "namespace"
+	OperationBenchmark = "benchmark"
+	OperationAssist    = "assist"
+)
+
+// UsageEvent is the token usage of one provider call
+type UsageEvent struct {
+	Provider         string
+	Model            string
+	Kind             string // embedding or completion
+	Operation        string // what the call was for, see WithOperation
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	Estimated        bool // provider reported no usage; counts are approximations
+	CostUSD          float64
+}
+
+// UsageRecorder persists usage events
+type UsageRecorder interface {
+	RecordUsage(event UsageEvent) error
+}
+
+// UsageTracker forwards usage events to a recorder that can be swapped at runtime,
+// e.g. when a different vault (and database) is opened
+type UsageTracker struct {
+	mu       sync.RWMutex
+	recorder UsageRecorder
+}
+
+// SetRecorder sets the recorder; nil stops recording
+func (t *UsageTracker) SetRecorder(r UsageRecorder) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recorder = r
+}
+
+// Record prices the event and hands it to the recorder, if any
+func (t *UsageTracker) Record(ev UsageEvent) {
+	if t == nil {
+		return
+	}
+	t.mu.RLock()
+	recorder := t.recorder
+	t.mu.RUnlock()
+	if recorder == nil {
+		return
+	}
+	if ev.TotalTokens == 0 {
+		ev.TotalTokens = ev.PromptTokens + ev.CompletionTokens
+	}
+	ev.CostUSD = EstimateCost(ev.Provider, ev.Model, ev.PromptTokens, ev.CompletionTokens)
+	// Usage tracking must never fail the call it describes
+	_ = recorder.RecordUsage(ev)
+}
+
+type operationKey struct{}
+
+// WithOperation labels calls made with ctx, e.g. "indexing" or "rag", so usage
+// reports can tell what the tokens were spent on
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+func operationFrom(ctx context.Context, fallback string) string {
+	if op, ok := ctx.Value(operationKey{}).(string); ok && op != "" {
+		return op
+	}
+	return fallback
+}
+
+// modelPrice is USD per million tokens
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// openAIPrices lists list prices per million tokens. Dated variants such as
+// gpt-4o-mini-2024-07-18 match by longest prefix.
+var openAIPrices = map[string]modelPrice{
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"text-embedding-ada-002": {Input: 0.10},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.60},
+	"gpt-4o":                 {Input: 2.50, Output: 10.00},
+	"gpt-4.1-nano":           {Input: 0.10, Output: 0.40},
+	"gpt-4.1-mini":           {Input: 0.40, Output: 1.60},
+	"gpt-4.1":                {Input: 2.00, Output: 8.00},
+	"gpt-4-turbo":            {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+}
+
+// EstimateCost returns the estimated USD cost of a call. Local providers and
+// unknown models cost 0.
+func EstimateCost(provider, model string, promptTokens, completionTokens int) float64 {
+	if provider != "openai" {
+		return 0
+	}
+	var price modelPrice
+	best := -1
+	for name, p := range openAIPrices {
+		if strings.HasPrefix(model, name) && len(name) > best {
+			price, best = p, len(name)
+		}
+	}
+	if best < 0 {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
+
+// usageEmbeddingProvider reports the usage of each embedding call
+type usageEmbeddingProvider struct {
+	EmbeddingProvider
+	name    string
+	tracker *UsageTracker
+}
+
+// WithEmbeddingUsage wraps provider so its token usage is reported to tracker
+func WithEmbeddingUsage(provider EmbeddingProvider, name string, tracker *UsageTracker) EmbeddingProvider {
+	if tracker == nil {
+		return provider
+	}
+	return &usageEmbeddingProvider{EmbeddingProvider: provider, name: name, tracker: tracker}
+}
+
+// Unwrap returns the wrapped provider
+func (p *usageEmbeddingProvider) Unwrap() EmbeddingProvider {
+	return p.EmbeddingProvider
+}
+
+func (p *usageEmbeddingProvider) GenerateEmbedding(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	resp, err := p.EmbeddingProvider.GenerateEmbedding(ctx, req)
+	if err == nil && resp != nil {
+		model := resp.Model
+		if model == "" {
+			model = req.Model
+		}
+		p.record(ctx, model, []string{req.Text}, []*EmbeddingResponse{resp})
+	}
+	return resp, err
+}
+
+func (p *usageEmbeddingProvider) GenerateEmbeddingsBatch(ctx context.Context, texts []string) ([]*EmbeddingResponse, error) {
+	resps, err := p.EmbeddingProvider.GenerateEmbeddingsBatch(ctx, texts)
+	if err == nil && len(resps) > 0 {
+		model := batchModel(ctx, p.GetDefaultModel())
+		if resps[0] != nil && resps[0].Model != "" {
+			model = resps[0].Model
+		}
+		p.record(ctx, model, texts, resps)
+	}
+	return resps, err
+}
+
+func (p *usageEmbeddingProvider) record(ctx context.Context, model string, texts []string, resps []*EmbeddingResponse) {
+	ev := UsageEvent{
+		Provider:  p.name,
+		Model:     model,
+		Kind:      UsageKindEmbedding,
+		Operation: operationFrom(ctx, UsageKindEmbedding),
+	}
+	for _, r := range resps {
+		if r != nil && r.Usage != nil {
+			ev.PromptTokens += r.Usage.PromptTokens
+			ev.TotalTokens += r.Usage.TotalTokens
+		}
+	}
+	if ev.PromptTokens == 0 {
+		for _, t := range texts {
+			ev.PromptTokens += estimateTokens(t)
+		}
+		ev.TotalTokens = ev.PromptTokens
+		ev.Estimated = true
+	}
+	p.tracker.Record(ev)
+}
+
+// usageLLMProvider reports the usage of each completion
+type usageLLMProvider struct {
+	LLMProvider
+	name    string
+	tracker *UsageTracker
+}
+
+// WithLLMUsage wraps provider so its token usage is reported to tracker
+func WithLLMUsage(provider LLMProvider, name string, tracker *UsageTracker) LLMProvider {
+	if tracker == nil {
+		return provider
+	}
+	return &usageLLMProvider{LLMProvider: provider, name: name, tracker: tracker}
+}
+
+func (p *usageLLMProvider) GenerateCompletion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.LLMProvider.GenerateCompletion(ctx, req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	ev := UsageEvent{
+		Provider:  p.name,
+		Model:     resp.Model,
+		Kind:      UsageKindCompletion,
+		Operation: operationFrom(ctx, UsageKindCompletion),
+	}
+	if ev.Model == "" {
+		ev.Model = req.Model
+	}
+	if resp.TokensUsed != nil {
+		ev.PromptTokens = resp.TokensUsed.PromptTokens
+		ev.CompletionTokens = resp.TokensUsed.CompletionTokens
+		ev.TotalTokens = resp.TokensUsed.TotalTokens
+	} else {
+		ev.PromptTokens = promptTokens(req)
+		ev.CompletionTokens = estimateTokens(resp.Content)
+		ev.Estimated = true
+	}
+	p.tracker.Record(ev)
+	return resp, nil
+}
+
+// GenerateCompletionStream estimates usage from the streamed text, since the
+// stream does not carry token counts
+func (p *usageLLMProvider) GenerateCompletionStream(ctx context.Context, req *CompletionRequest) (<-chan *CompletionChunk, error) {
+	in, err := p.LLMProvider.GenerateCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *CompletionChunk, cap(in))
+	go func() {
+		defer close(out)
+		var content strings.Builder
+		failed := false
+		for chunk := range in {
+			content.WriteString(chunk.Content)
+			if chunk.Error != nil {
+				failed = true
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if failed {
+			return
+		}
+		p.tracker.Record(UsageEvent{
+			Provider:         p.name,
+			Model:            req.Model,
+			Kind:             UsageKindCompletion,
+			Operation:        operationFrom(ctx, UsageKindCompletion),
+			PromptTokens:     promptTokens(req),
+			CompletionTokens: estimateTokens(content.String()),
+			Estimated:        true,
+		})
+	}()
+	return out, nil
+}
+
+func promptTokens(req *CompletionRequest) int {
+	tokens := 0
+	for _, m := range req.Messages {
+		tokens += estimateTokens(m.Content)
+	}
+	return tokens
+}
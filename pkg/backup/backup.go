@@ -0,0 +1,361 @@
+// Package backup stores encrypted snapshots of a vault in S3-compatible
+// storage. Files are encrypted on this machine before upload and stored by a
+// keyed content hash, so unchanged files are uploaded once and shared by
+// later incremental backups.
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup kinds
+const (
+	KindFull        = "full"
+	KindIncremental = "incremental"
+)
+
+const (
+	keyInfoName    = "keyinfo.json"
+	objectsDir     = "objects/"
+	manifestsDir   = "backups/"
+	manifestSuffix = ".manifest"
+	idTimeLayout   = "20060102T150405Z"
+)
+
+// File is one file to back up
+type File struct {
+	Path   string // vault relative, slash separated
+	Source string // absolute path to read it from
+}
+
+// Info describes a stored backup
+type Info struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     int       `json:"files,omitempty"`
+	Size      int64     `json:"size,omitempty"`     // bytes of vault data
+	Uploaded  int       `json:"uploaded,omitempty"` // objects sent by this backup
+}
+
+type manifestFile struct {
+	Path   string `json:"path"`
+	Object string `json:"object"`
+	Size   int64  `json:"size"`
+}
+
+type manifest struct {
+	ID        string         `json:"id"`
+	Kind      string         `json:"kind"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []manifestFile `json:"files"`
+}
+
+// Store backs up vaults to a bucket prefix under one passphrase
+type Store struct {
+	s3         *S3
+	prefix     string
+	passphrase string
+	tempDir    string
+	now        func() time.Time
+}
+
+// NewStore keeps backups below prefix in the bucket
+func NewStore(s3 *S3, prefix, passphrase string) *Store {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &Store{s3: s3, prefix: prefix, passphrase: passphrase, now: time.Now}
+}
+
+// key loads the bucket's key info, creating it on first use
+func (s *Store) key(ctx context.Context, create bool) ([]byte, error) {
+	body, err := s.s3.Get(ctx, s.prefix+keyInfoName)
+	if errors.Is(err, ErrNoSuchKey) {
+		if !create {
+			return nil, errors.New("no backups in this bucket")
+		}
+		info, key, err := newKeyInfo(s.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(info)
+		if err := s.s3.Put(ctx, s.prefix+keyInfoName, data); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var info keyInfo
+	if err := json.NewDecoder(body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("read backup key info: %w", err)
+	}
+	return info.key(s.passphrase)
+}
+
+// ScanVault lists the files of a vault to back up. Hidden entries and the
+// data directory are skipped; its database is added as a snapshot by the caller.
+func ScanVault(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(d.Name(), ".") || rel == "data" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, File{Path: rel, Source: p})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Run backs up files. A full backup uploads every file; an incremental one
+// only files not stored by the latest backup.
+func (s *Store) Run(ctx context.Context, kind string, files []File) (*Info, error) {
+	if kind != KindFull && kind != KindIncremental {
+		return nil, fmt.Errorf("unknown backup kind %q", kind)
+	}
+	key, err := s.key(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := map[string]bool{}
+	if kind == KindIncremental {
+		backups, err := s.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(backups) == 0 {
+			kind = KindFull
+		} else {
+			latest, err := s.manifest(ctx, key, backups[0].ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range latest.Files {
+				stored[f.Object] = true
+			}
+		}
+	}
+
+	createdAt := s.now().UTC()
+	m := manifest{ID: createdAt.Format(idTimeLayout) + "-" + kind, Kind: kind, CreatedAt: createdAt}
+	info := &Info{ID: m.ID, Kind: kind, CreatedAt: createdAt}
+	uploaded := map[string]bool{}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, size, err := hashFile(key, f.Source)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Path, err)
+		}
+		if !stored[id] && !uploaded[id] {
+			if err := s.uploadObject(ctx, key, id, f.Source); err != nil {
+				return nil, fmt.Errorf("upload %s: %w", f.Path, err)
+			}
+			uploaded[id] = true
+		}
+		m.Files = append(m.Files, manifestFile{Path: f.Path, Object: id, Size: size})
+		info.Size += size
+	}
+	info.Files = len(m.Files)
+	info.Uploaded = len(uploaded)
+
+	// The manifest goes last so a backup only shows up once it is complete
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var sealed bytes.Buffer
+	if err := encrypt(&sealed, bytes.NewReader(data), key); err != nil {
+		return nil, err
+	}
+	if err := s.s3.Put(ctx, s.prefix+manifestsDir+m.ID+manifestSuffix, sealed.Bytes()); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func hashFile(key []byte, p string) (string, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	return objectID(key, f)
+}
+
+func (s *Store) uploadObject(ctx context.Context, key []byte, id, source string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(s.tempDir, "notebit-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := encrypt(tmp, src, key); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return s.s3.PutFile(ctx, s.prefix+objectsDir+id, tmp.Name())
+}
+
+// List returns the stored backups, newest first. It needs no passphrase.
+func (s *Store) List(ctx context.Context) ([]Info, error) {
+	objects, err := s.s3.List(ctx, s.prefix+manifestsDir)
+	if err != nil {
+		return nil, err
+	}
+	backups := []Info{}
+	for _, o := range objects {
+		id := strings.TrimSuffix(path.Base(o.Key), manifestSuffix)
+		stamp, kind, ok := strings.Cut(id, "-")
+		if !ok || !strings.HasSuffix(o.Key, manifestSuffix) {
+			continue
+		}
+		createdAt, err := time.Parse(idTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Info{ID: id, Kind: kind, CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+func (s *Store) manifest(ctx context.Context, key []byte, id string) (*manifest, error) {
+	if id == "" || strings.ContainsAny(id, "/\\") {
+		return nil, fmt.Errorf("invalid backup id %q", id)
+	}
+	body, err := s.s3.Get(ctx, s.prefix+manifestsDir+id+manifestSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var plain bytes.Buffer
+	if err := decrypt(&plain, body, key); err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(plain.Bytes(), &m); err != nil {
+		return nil, fmt.Errorf("read manifest %s: %w", id, err)
+	}
+	return &m, nil
+}
+
+// Restore writes the files of backup id into targetDir, which must be empty
+// or not exist yet. Every file is authenticated before it is kept.
+func (s *Store) Restore(ctx context.Context, id, targetDir string) (*Info, error) {
+	entries, err := os.ReadDir(targetDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("restore target is not empty: %s", targetDir)
+	}
+	key, err := s.key(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.manifest(ctx, key, id)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{ID: m.ID, Kind: m.Kind, CreatedAt: m.CreatedAt, Files: len(m.Files)}
+	for _, f := range m.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		target, err := restorePath(targetDir, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.restoreObject(ctx, key, f.Object, target); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", f.Path, err)
+		}
+		info.Size += f.Size
+	}
+	return info, nil
+}
+
+// restorePath joins a manifest path to dir, refusing paths that escape it
+func restorePath(dir, rel string) (string, error) {
+	clean := path.Clean("/" + rel)
+	if clean == "/" || clean != "/"+rel {
+		return "", fmt.Errorf("invalid path in backup: %q", rel)
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), nil
+}
+
+func (s *Store) restoreObject(ctx context.Context, key []byte, id, target string) error {
+	body, err := s.s3.Get(ctx, s.prefix+objectsDir+id)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := decrypt(tmp, body, key); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	got, _, err := objectID(key, tmp)
+	if err != nil {
+		return err
+	}
+	if got != id {
+		return ErrCorrupt
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves the path-style subset of S3 the client uses
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AK/") {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		type content struct {
+			Key  string
+			Size int64
+		}
+		var result struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []content
+		}
+		prefix := r.URL.Query().Get("prefix")
+		for k, v := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				result.Contents = append(result.Contents, content{k, int64(len(v))})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>")
+			return
+		}
+		w.Write(data)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestStore(t *testing.T, passphrase string) (*Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client, err := NewS3(srv.URL, "", "bucket", "AK", "SK", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(client, "vaults/work", passphrase), fake
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := make([]byte, keySize)
+	rand.Read(key)
+	for _, size := range []int{0, 10, segmentSize, 2*segmentSize + 7} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		var sealed, opened bytes.Buffer
+		if err := encrypt(&sealed, bytes.NewReader(plain), key); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(sealed.Bytes(), plain) && size > 0 {
+			t.Fatal("plaintext leaked")
+		}
+		if err := decrypt(&opened, bytes.NewReader(sealed.Bytes()), key); err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(opened.Bytes(), plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+		if size > segmentSize {
+			// Dropping the last segment must not go unnoticed
+			truncated := sealed.Bytes()[:len(magic)+prefixSize+segmentSize+16]
+			if err := decrypt(io.Discard, bytes.NewReader(truncated), key); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("truncation not detected: %v", err)
+			}
+		}
+	}
+}
+
+func TestBackupIncrementalAndRestore(t *testing.T) {
+	store, fake := newTestStore(t, "correct horse")
+	ctx := context.Background()
+	vault := t.TempDir()
+	writeFile(t, filepath.Join(vault, "Plan.md"), "plan")
+	writeFile(t, filepath.Join(vault, "Projects", "Alpha.md"), "alpha")
+	writeFile(t, filepath.Join(vault, "data", "notebit.sqlite"), "live db")
+	writeFile(t, filepath.Join(vault, ".trash", "old.md"), "old")
+	snapshot := filepath.Join(t.TempDir(), "snapshot.sqlite")
+	writeFile(t, snapshot, "db snapshot")
+
+	files, err := ScanVault(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the two notes, got %+v", files)
+	}
+	files = append(files, File{Path: "data/notebit.sqlite", Source: snapshot})
+
+	store.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	full, err := store.Run(ctx, KindIncremental, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Kind != KindFull || full.Uploaded != 3 {
+		t.Fatalf("first backup should be full with 3 uploads: %+v", full)
+	}
+	for k, v := range fake.objects {
+		if bytes.Contains(v, []byte("alpha")) {
+			t.Fatalf("%s stored in plain text", k)
+		}
+	}
+
+	writeFile(t, filepath.Join(vault, "Plan.md"), "plan v2")
+	store.now = func() time.Time { return time.Date(2026, 1, 3, 3, 4, 5, 0, time.UTC) }
+	incr, err := store.Run(ctx, KindIncremental, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if incr.Kind != KindIncremental || incr.Uploaded != 1 {
+		t.Fatalf("incremental backup should upload only the changed note: %+v", incr)
+	}
+
+	backups, err := store.List(ctx)
+	if err != nil || len(backups) != 2 || backups[0].ID != incr.ID {
+		t.Fatalf("list: %+v %v", backups, err)
+	}
+
+	target := filepath.Join(t.TempDir(), "restored")
+	if _, err := store.Restore(ctx, full.ID, target); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]string{"Plan.md": "plan", "Projects/Alpha.md": "alpha", "data/notebit.sqlite": "db snapshot"} {
+		if got, _ := os.ReadFile(filepath.Join(target, filepath.FromSlash(rel))); string(got) != want {
+			t.Fatalf("%s: got %q want %q", rel, got, want)
+		}
+	}
+	if _, err := store.Restore(ctx, incr.ID, target); err == nil {
+		t.Fatal("restoring into a non-empty folder should fail")
+	}
+
+	wrong := NewStore(store.s3, "vaults/work", "wrong")
+	if _, err := wrong.Restore(ctx, incr.ID, t.TempDir()); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestSealFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "notebit.sqlite")
+	sealed := filepath.Join(dir, "notebit.sqlite.locked")
+	content := strings.Repeat("index and chats ", 10000)
+	writeFile(t, plain, content)
+
+	if err := SealFile(plain, sealed, "hunter2"); err != nil {
+		t.Fatalf("SealFile: %v", err)
+	}
+	if data, _ := os.ReadFile(sealed); strings.Contains(string(data), "index and chats") {
+		t.Fatal("sealed file contains plain text")
+	}
+	if err := CheckSealPassphrase(sealed, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	out := filepath.Join(dir, "restored.sqlite")
+	if err := UnsealFile(sealed, out, "hunter2"); err != nil {
+		t.Fatalf("UnsealFile: %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != content {
+		t.Fatal("content changed in round trip")
+	}
+}
@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrWrongPassphrase is returned when the passphrase does not match the one
+// the bucket was first backed up with
+var ErrWrongPassphrase = errors.New("wrong backup passphrase")
+
+// ErrCorrupt is returned for objects that fail authentication
+var ErrCorrupt = errors.New("backup data is corrupt or was tampered with")
+
+const (
+	keyIterations = 600_000
+	keySize       = 32
+
+	// Objects are encrypted in segments so large databases never have to
+	// fit in memory. Each segment is sealed with AES-GCM under a nonce made
+	// of a random per-object prefix and the segment counter; the last
+	// segment is marked so truncation is detected.
+	segmentSize = 64 << 10
+	magic       = "NBE1"
+	prefixSize  = 8
+)
+
+// keyInfo is stored unencrypted next to the backups. Check lets a wrong
+// passphrase fail early instead of on the first object.
+type keyInfo struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Check      []byte `json:"check"`
+}
+
+func newKeyInfo(passphrase string) (*keyInfo, []byte, error) {
+	info := &keyInfo{Version: 1, Salt: make([]byte, 16), Iterations: keyIterations}
+	if _, err := rand.Read(info.Salt); err != nil {
+		return nil, nil, err
+	}
+	key, err := info.key(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	info.Check = keyCheck(key)
+	return info, key, nil
+}
+
+// key derives the encryption key and verifies it against Check
+func (k *keyInfo) key(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("backup passphrase is empty")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, k.Salt, k.Iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	if k.Check != nil && !hmac.Equal(k.Check, keyCheck(key)) {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("notebit backup key check"))
+	return mac.Sum(nil)
+}
+
+// objectID names stored content by a keyed hash, so identical files are
+// stored once without revealing their plain hash
+func objectID(key []byte, r io.Reader) (string, int64, error) {
+	mac := hmac.New(sha256.New, key)
+	n, err := io.Copy(mac, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", mac.Sum(nil)), n, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func segmentNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], counter)
+	return nonce
+}
+
+func segmentAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// readSegment fills buf as far as src allows, reporting io.EOF only when
+// nothing was read
+func readSegment(src io.Reader, buf []byte) (int, error) {
+	n, err := io.ReadFull(src, buf)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
+
+// encrypt writes src to dst encrypted with key
+func encrypt(dst io.Writer, src io.Reader, key []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	if _, err := dst.Write(append([]byte(magic), prefix...)); err != nil {
+		return err
+	}
+
+	cur, next := make([]byte, segmentSize), make([]byte, segmentSize)
+	n, err := readSegment(src, cur)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	out := make([]byte, 0, segmentSize+gcm.Overhead())
+	for counter := uint32(0); ; counter++ {
+		m := 0
+		if n == segmentSize {
+			if m, err = readSegment(src, next); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		final := m == 0
+		out = gcm.Seal(out[:0], segmentNonce(prefix, counter), cur[:n], segmentAAD(final))
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		cur, next, n = next, cur, m
+	}
+}
+
+// decrypt writes the plaintext of an object produced by encrypt to dst
+func decrypt(dst io.Writer, src io.Reader, key []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(magic)+prefixSize)
+	if _, err := io.ReadFull(src, header); err != nil || string(header[:len(magic)]) != magic {
+		return ErrCorrupt
+	}
+	prefix := header[len(magic):]
+
+	size := segmentSize + gcm.Overhead()
+	cur, next := make([]byte, size), make([]byte, size)
+	n, err := readSegment(src, cur)
+	if err != nil {
+		return ErrCorrupt
+	}
+	out := make([]byte, 0, segmentSize)
+	for counter := uint32(0); ; counter++ {
+		m := 0
+		if n == size {
+			if m, err = readSegment(src, next); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		final := m == 0
+		out, err = gcm.Open(out[:0], segmentNonce(prefix, counter), cur[:n], segmentAAD(final))
+		if err != nil {
+			return ErrCorrupt
+		}
+		if _, err := dst.Write(out); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		cur, next, n = next, cur, m
+	}
+}
@@ -0,0 +1,279 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoSuchKey is returned for objects missing from the bucket
+var ErrNoSuchKey = errors.New("object not found")
+
+// S3 is a minimal client for S3-compatible storage (AWS, MinIO, R2, B2...)
+// using path-style URLs and Signature Version 4
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// ObjectInfo is an entry of a bucket listing
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// NewS3 creates a client for bucket at endpoint, e.g. https://s3.eu-west-1.amazonaws.com
+func NewS3(endpoint, region, bucket, accessKey, secretKey string, client *http.Client) (*S3, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %q", endpoint)
+	}
+	if bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{endpoint: u, region: region, bucket: bucket, accessKey: accessKey, secretKey: secretKey, client: client, now: time.Now}, nil
+}
+
+// uriEncode escapes s as SigV4 requires: everything but unreserved
+// characters, optionally keeping slashes
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func (c *S3) request(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	path := strings.TrimSuffix(c.endpoint.Path, "/") + "/" + c.bucket
+	if key != "" {
+		path += "/" + key
+	}
+	escapedPath := uriEncode(path, true)
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, uriEncode(k, false)+"="+uriEncode(query.Get(k), false))
+	}
+	canonicalQuery := strings.Join(params, "&")
+
+	rawURL := c.endpoint.Scheme + "://" + c.endpoint.Host + escapedPath
+	if canonicalQuery != "" {
+		rawURL += "?" + canonicalQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+	}
+
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{method, escapedPath, canonicalQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	signingKey = hmacSHA256(signingKey, c.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", method, key, err)
+	}
+	return resp, nil
+}
+
+var emptyPayloadHash = sha256Hex(nil)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3Error(method, key string, resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		if body.Code == "NoSuchKey" {
+			return fmt.Errorf("s3 %s %s: %w", method, key, ErrNoSuchKey)
+		}
+		return fmt.Errorf("s3 %s %s: %s: %s", method, key, body.Code, body.Message)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("s3 %s %s: %w", method, key, ErrNoSuchKey)
+	}
+	return fmt.Errorf("s3 %s %s: %s", method, key, resp.Status)
+}
+
+// PutFile uploads the file at path as key
+func (c *S3) PutFile(ctx context.Context, key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	resp, err := c.request(ctx, http.MethodPut, key, nil, f, size, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error("PUT", key, resp)
+	}
+	return nil
+}
+
+// Put uploads data as key
+func (c *S3) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := c.request(ctx, http.MethodPut, key, nil, bytes.NewReader(data), int64(len(data)), sha256Hex(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error("PUT", key, resp)
+	}
+	return nil
+}
+
+// Get downloads key. The caller closes the returned body.
+func (c *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.request(ctx, http.MethodGet, key, nil, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error("GET", key, resp)
+	}
+	return resp.Body, nil
+}
+
+// Exists reports whether key is in the bucket
+func (c *S3) Exists(ctx context.Context, key string) (bool, error) {
+	resp, err := c.request(ctx, http.MethodHead, key, nil, nil, 0, "")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("s3 HEAD %s: %s", key, resp.Status)
+	}
+}
+
+// List returns every object whose key starts with prefix
+func (c *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.request(ctx, http.MethodGet, "", query, nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error("LIST", prefix, resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 LIST %s: %w", prefix, err)
+		}
+		for _, o := range result.Contents {
+			objects = append(objects, ObjectInfo{Key: o.Key, Size: o.Size, LastModified: o.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
@@ -0,0 +1,201 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+const (
+	scheduleCheckInterval = time.Minute
+	scheduleStateFile     = "backup_state.json"
+)
+
+// RunFunc makes one backup of the given kind
+type RunFunc func(ctx context.Context, kind string) (*Info, error)
+
+// Status reports the state of the backup scheduler
+type Status struct {
+	Enabled   bool   `json:"enabled"`
+	Running   bool   `json:"running"`
+	NextRun   int64  `json:"next_run"` // Unix ms, 0 when disabled
+	LastRun   int64  `json:"last_run"` // Unix ms, 0 when never run
+	LastFull  int64  `json:"last_full"`
+	LastID    string `json:"last_id,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type scheduleState struct {
+	LastRun  time.Time `json:"last_run"`
+	LastFull time.Time `json:"last_full"`
+	LastID   string    `json:"last_id"`
+}
+
+// Scheduler backs up every interval, making a full backup when the last
+// one is older than fullEvery and incremental ones in between
+type Scheduler struct {
+	run      RunFunc
+	stateDir func() string
+
+	mu        sync.Mutex
+	interval  time.Duration
+	fullEvery time.Duration
+	state     scheduleState
+	lastErr   string
+	running   bool
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler. stateDir returns the directory the
+// schedule state is persisted in ("" disables persistence).
+func NewScheduler(run RunFunc, stateDir func() string) *Scheduler {
+	return &Scheduler{run: run, stateDir: stateDir, now: time.Now}
+}
+
+// Start begins checking the schedule; a zero interval only loads the state
+// for Status. Calling Start again applies new settings.
+func (s *Scheduler) Start(interval, fullEvery time.Duration) {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval, s.fullEvery = interval, fullEvery
+	s.state = s.loadState()
+	if interval <= 0 {
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go s.loop(s.stopCh, s.doneCh)
+}
+
+// Stop halts the scheduler
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Enabled:   s.interval > 0,
+		Running:   s.running,
+		LastID:    s.state.LastID,
+		LastError: s.lastErr,
+	}
+	if !s.state.LastRun.IsZero() {
+		status.LastRun = s.state.LastRun.UnixMilli()
+	}
+	if !s.state.LastFull.IsZero() {
+		status.LastFull = s.state.LastFull.UnixMilli()
+	}
+	if status.Enabled {
+		status.NextRun = s.state.LastRun.Add(s.interval).UnixMilli()
+		if s.state.LastRun.IsZero() {
+			status.NextRun = s.now().UnixMilli()
+		}
+	}
+	return status
+}
+
+// RunNow makes a backup immediately. An empty kind picks full or
+// incremental from the schedule.
+func (s *Scheduler) RunNow(ctx context.Context, kind string) (*Info, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("backup already running")
+	}
+	s.running = true
+	if kind == "" {
+		kind = KindIncremental
+		if s.state.LastFull.IsZero() || s.now().Sub(s.state.LastFull) >= s.fullEvery {
+			kind = KindFull
+		}
+	}
+	s.mu.Unlock()
+
+	info, err := s.run(ctx, kind)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	if err != nil {
+		s.lastErr = err.Error()
+		logger.WarnWithFields(ctx, map[string]interface{}{"kind": kind, "error": err.Error()}, "Vault backup failed")
+		return nil, err
+	}
+	s.lastErr = ""
+	s.state.LastRun = s.now()
+	s.state.LastID = info.ID
+	if info.Kind == KindFull {
+		s.state.LastFull = s.state.LastRun
+	}
+	s.saveState()
+	logger.InfoWithFields(ctx, map[string]interface{}{"id": info.ID, "files": info.Files, "uploaded": info.Uploaded}, "Vault backup written")
+	return info, nil
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer logger.RecoverPanic()
+	defer close(done)
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.shouldRun() {
+				_, _ = s.RunNow(context.Background(), "")
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) shouldRun() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running || s.interval <= 0 {
+		return false
+	}
+	return !s.now().Before(s.state.LastRun.Add(s.interval))
+}
+
+func (s *Scheduler) loadState() scheduleState {
+	var state scheduleState
+	if dir := s.stateDir(); dir != "" {
+		if data, err := os.ReadFile(filepath.Join(dir, scheduleStateFile)); err == nil {
+			_ = json.Unmarshal(data, &state)
+		}
+	}
+	return state
+}
+
+func (s *Scheduler) saveState() {
+	dir := s.stateDir()
+	if dir == "" {
+		return
+	}
+	data, _ := json.Marshal(s.state)
+	if err := os.WriteFile(filepath.Join(dir, scheduleStateFile), data, 0644); err != nil {
+		logger.Warn("Failed to save backup schedule state: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// sealMagic starts a sealed file, followed by the length of the key info,
+// the key info itself and the encrypted content
+const sealMagic = "NBS1"
+
+// SealFile encrypts src into dst with a key derived from passphrase. The key
+// parameters are stored in front of the content, so the file can be opened
+// on its own. dst is replaced atomically.
+func SealFile(src, dst, passphrase string) error {
+	info, key, err := newKeyInfo(passphrase)
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeAtomic(dst, func(w io.Writer) error {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(header)))
+		if _, err := io.WriteString(w, sealMagic); err != nil {
+			return err
+		}
+		if _, err := w.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		return encrypt(w, in, key)
+	})
+}
+
+// UnsealFile decrypts a file written by SealFile into dst, which is replaced
+// atomically. A wrong passphrase returns ErrWrongPassphrase.
+func UnsealFile(src, dst, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+	key, err := readSealHeader(r, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(dst, func(w io.Writer) error {
+		return decrypt(w, r, key)
+	})
+}
+
+// CheckSealPassphrase verifies passphrase against a sealed file without
+// decrypting it
+func CheckSealPassphrase(src, passphrase string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = readSealHeader(bufio.NewReader(in), passphrase)
+	return err
+}
+
+func readSealHeader(r io.Reader, passphrase string) ([]byte, error) {
+	var head [len(sealMagic) + 4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil || string(head[:len(sealMagic)]) != sealMagic {
+		return nil, ErrCorrupt
+	}
+	size := binary.BigEndian.Uint32(head[len(sealMagic):])
+	if size > 4096 {
+		return nil, ErrCorrupt
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrCorrupt
+	}
+	var info keyInfo
+	if err := json.Unmarshal(header, &info); err != nil {
+		return nil, fmt.Errorf("read key info: %w", err)
+	}
+	return info.key(passphrase)
+}
+
+// writeAtomic writes dst through a temp file in the same directory, so dst
+// is never left half written
+func writeAtomic(dst string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".seal-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,411 @@
+// Package bibtex reads BibTeX bibliographies and finds Pandoc-style [@key]
+// citations in notes.
+package bibtex
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Entry is one reference of a bibliography
+type Entry struct {
+	Key     string            `json:"key"`
+	Type    string            `json:"type"` // Lowercase entry type, e.g. "article" or "book"
+	Title   string            `json:"title"`
+	Authors []string          `json:"authors"` // As written, e.g. "Lovelace, Ada"
+	Year    string            `json:"year"`
+	Fields  map[string]string `json:"fields"` // Every field, names lowercased
+}
+
+// ParseError reports a malformed entry; the entries around it still parse
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads the entries of a BibTeX document in order. Malformed entries
+// are skipped and reported in the returned errors. @string macros are
+// expanded; @comment and @preamble are ignored.
+func Parse(data string) ([]Entry, []error) {
+	p := &parser{src: data, macros: defaultMacros()}
+	var entries []Entry
+	var errs []error
+	for {
+		at := strings.IndexByte(p.src[p.pos:], '@')
+		if at < 0 {
+			break
+		}
+		p.pos += at + 1
+		start := p.pos
+		entry, err := p.entry()
+		if err != nil {
+			errs = append(errs, &ParseError{Line: p.line(start), Msg: err.Error()})
+			// Resume after the @, so an entry the broken one ran into is kept
+			p.pos = start
+			continue
+		}
+		if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, errs
+}
+
+type parser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+func defaultMacros() map[string]string {
+	months := []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	names := []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	macros := make(map[string]string, len(months))
+	for i, m := range months {
+		macros[m] = names[i]
+	}
+	return macros
+}
+
+func (p *parser) line(pos int) int {
+	return strings.Count(p.src[:pos], "\n") + 1
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// ident reads an entry type, key, field name or macro name
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if unicode.IsSpace(rune(c)) || strings.IndexByte("{}(),=#\"", c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != c {
+		return fmt.Errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// entry parses what follows an @; it returns nil for entries that are not
+// references
+func (p *parser) entry() (*Entry, error) {
+	typ := strings.ToLower(p.ident())
+	p.skipSpace()
+	if typ == "" || p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+		// A stray @, e.g. in an email address in a comment
+		return nil, nil
+	}
+	closing := byte('}')
+	if p.src[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	switch typ {
+	case "comment", "preamble":
+		return nil, p.skipBody(closing)
+	case "string":
+		p.skipSpace()
+		name := strings.ToLower(p.ident())
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		p.macros[name] = value
+		return nil, p.expect(closing)
+	}
+
+	p.skipSpace()
+	key := strings.TrimSpace(p.ident())
+	if key == "" {
+		return nil, fmt.Errorf("@%s entry without a key", typ)
+	}
+	entry := &Entry{Key: key, Type: typ, Fields: make(map[string]string)}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("entry %s is not closed", key)
+		}
+		switch p.src[p.pos] {
+		case closing:
+			p.pos++
+			entry.Title = entry.Fields["title"]
+			entry.Year = entry.Fields["year"]
+			if entry.Year == "" && len(entry.Fields["date"]) >= 4 {
+				entry.Year = entry.Fields["date"][:4]
+			}
+			entry.Authors = splitNames(first(entry.Fields["author"], entry.Fields["editor"]))
+			return entry, nil
+		case ',':
+			p.pos++
+			continue
+		}
+		name := strings.ToLower(p.ident())
+		if name == "" {
+			return nil, fmt.Errorf("entry %s: unexpected %q", key, p.src[p.pos])
+		}
+		if err := p.expect('='); err != nil {
+			return nil, fmt.Errorf("entry %s, field %s: %v", key, name, err)
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("entry %s, field %s: %v", key, name, err)
+		}
+		entry.Fields[name] = value
+	}
+}
+
+// value parses a field value: braced or quoted strings, numbers and macros
+// joined with #
+func (p *parser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("missing value")
+		}
+		switch c := p.src[p.pos]; c {
+		case '{':
+			text, err := p.delimited('{', '}')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+		case '"':
+			text, err := p.delimited('"', '"')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+		default:
+			word := p.ident()
+			if word == "" {
+				return "", fmt.Errorf("missing value")
+			}
+			if macro, ok := p.macros[strings.ToLower(word)]; ok {
+				word = macro
+			}
+			b.WriteString(word)
+		}
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return cleanValue(b.String()), nil
+	}
+}
+
+// delimited returns the text between open and its matching close, with
+// nested braces kept
+func (p *parser) delimited(open, close byte) (string, error) {
+	p.pos++ // open
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\':
+			p.pos++
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == close && depth == 0:
+			text := p.src[start:p.pos]
+			p.pos++
+			return text, nil
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated value")
+}
+
+func (p *parser) skipBody(closing byte) error {
+	open := byte('{')
+	if closing == ')' {
+		open = '('
+	}
+	p.pos--
+	_, err := p.delimited(open, closing)
+	return err
+}
+
+// latexReplacer turns common LaTeX markup into plain text
+var latexReplacer = strings.NewReplacer(
+	`\&`, "&", `\%`, "%", `\$`, "$", `\_`, "_", `\#`, "#",
+	"---", "—", "--", "–", "~", " ",
+	`\"a`, "ä", `\"o`, "ö", `\"u`, "ü", `\"A`, "Ä", `\"O`, "Ö", `\"U`, "Ü",
+	`\'e`, "é", `\'a`, "á", `\'i`, "í", `\'o`, "ó", `\'u`, "ú", "\\`e", "è", "\\`a", "à",
+	`\^e`, "ê", `\^o`, "ô", `\c c`, "ç", `\c{c}`, "ç", `\ss`, "ß", `\~n`, "ñ",
+	`\textendash`, "–", `\textemdash`, "—",
+)
+
+var latexCommand = regexp.MustCompile(`\\(?:emph|textit|textbf|textsc|texttt|url)\s*`)
+
+// cleanValue strips grouping braces and LaTeX markup and collapses whitespace
+func cleanValue(s string) string {
+	s = latexCommand.ReplaceAllString(s, "")
+	s = latexReplacer.Replace(s)
+	s = strings.NewReplacer("{", "", "}", "").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var nameSeparator = regexp.MustCompile(`\s+and\s+`)
+
+// splitNames splits a BibTeX name list on " and "
+func splitNames(list string) []string {
+	if list == "" {
+		return nil
+	}
+	parts := nameSeparator.Split(list, -1)
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// lastName returns the family name of a BibTeX name, written either
+// "Last, First" or "First Last"
+func lastName(name string) string {
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		return strings.TrimSpace(name[:i])
+	}
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// Label returns a short author-year label, e.g. "Lovelace & Babbage 1843"
+// or "Turing et al. 1950"; the key stands in for missing authors
+func (e Entry) Label() string {
+	var who string
+	switch len(e.Authors) {
+	case 0:
+		who = e.Key
+	case 1:
+		who = lastName(e.Authors[0])
+	case 2:
+		who = lastName(e.Authors[0]) + " & " + lastName(e.Authors[1])
+	default:
+		who = lastName(e.Authors[0]) + " et al."
+	}
+	if e.Year == "" {
+		return who
+	}
+	return who + " " + e.Year
+}
+
+// Format returns a one-line reference, e.g.
+// "Turing, A. (1950). Computing machinery and intelligence. Mind."
+func (e Entry) Format() string {
+	var b strings.Builder
+	if len(e.Authors) > 0 {
+		b.WriteString(strings.Join(e.Authors, "; "))
+		b.WriteString(" ")
+	}
+	if e.Year != "" {
+		b.WriteString("(" + e.Year + "). ")
+	}
+	if e.Title != "" {
+		b.WriteString(strings.TrimRight(e.Title, ".") + ". ")
+	}
+	if venue := first(e.Fields["journal"], e.Fields["booktitle"], e.Fields["publisher"], e.Fields["howpublished"]); venue != "" {
+		b.WriteString(strings.TrimRight(venue, ".") + ". ")
+	}
+	if doi := e.Fields["doi"]; doi != "" {
+		b.WriteString("https://doi.org/" + doi)
+	} else if url := e.Fields["url"]; url != "" {
+		b.WriteString(url)
+	}
+	out := strings.TrimSpace(b.String())
+	if out == "" {
+		return e.Key
+	}
+	return out
+}
+
+// Search returns the entries whose key, title, authors or year contain every
+// word of query, ordered by key; an empty query matches all entries
+func Search(entries []Entry, query string) []Entry {
+	words := strings.Fields(strings.ToLower(query))
+	var matches []Entry
+	for _, e := range entries {
+		haystack := strings.ToLower(e.Key + " " + e.Title + " " + strings.Join(e.Authors, " ") + " " + e.Year)
+		ok := true
+		for _, w := range words {
+			if !strings.Contains(haystack, w) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matches = append(matches, e)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Key < matches[j].Key })
+	return matches
+}
+
+var (
+	// citationGroup matches a bracketed Pandoc citation, e.g. [see @a, p. 3; @b]
+	citationGroup = regexp.MustCompile(`\[([^\[\]]*@[^\[\]]*)\]`)
+	// citationKey matches a key inside a group; the @ must start a word, so
+	// email addresses are not keys
+	citationKey = regexp.MustCompile(`(?:^|[\s;\-])@([\p{L}\p{N}_][\p{L}\p{N}_:.#$%&+?<>~/-]*)`)
+)
+
+// CitationKeys returns the keys cited with [@key] in text, in order of first
+// appearance
+func CitationKeys(text string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, group := range citationGroup.FindAllStringSubmatch(text, -1) {
+		for _, m := range citationKey.FindAllStringSubmatch(group[1], -1) {
+			// Keys may contain : and . but not end with them
+			key := strings.TrimRight(m[1], ":.,?")
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
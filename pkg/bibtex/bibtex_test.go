@@ -0,0 +1,94 @@
+package bibtex
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testBib = `Comments outside entries are ignored, even ones mentioning me@example.com.
+
+@string{acm = "ACM Press"}
+
+@article{turing1950,
+  author  = {Turing, Alan M.},
+  title   = {Computing Machinery and {Intelligence}},
+  journal = "Mind",
+  year    = 1950,
+  month   = oct,
+}
+
+@inproceedings(lovelace1843,
+  author    = {Ada Lovelace and Charles Babbage},
+  title     = {Notes on the {A}nalytical {E}ngine},
+  booktitle = acm # " Proceedings",
+  date      = {1843-09-01}
+)
+
+@comment{ @article{ignored, title = {Not an entry}} }
+
+@book{broken,
+  title = {Unterminated
+}
+
+@misc{knuth:tex,
+  author = {Knuth, Donald and Lamport, Leslie and Others},
+  title  = {\emph{TeX} \& friends --- a history},
+  doi    = {10.1000/xyz},
+}
+`
+
+func TestParse(t *testing.T) {
+	entries, errs := Parse(testBib)
+	if len(errs) != 1 {
+		t.Errorf("expected the broken entry to be reported, got %v", errs)
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	if want := []string{"turing1950", "lovelace1843", "knuth:tex"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+
+	turing := entries[0]
+	if turing.Type != "article" || turing.Title != "Computing Machinery and Intelligence" || turing.Year != "1950" || turing.Fields["month"] != "October" {
+		t.Errorf("unexpected entry: %+v", turing)
+	}
+	lovelace := entries[1]
+	if lovelace.Year != "1843" || lovelace.Fields["booktitle"] != "ACM Press Proceedings" || len(lovelace.Authors) != 2 {
+		t.Errorf("unexpected entry: %+v", lovelace)
+	}
+	knuth := entries[2]
+	if knuth.Title != "TeX & friends — a history" {
+		t.Errorf("title = %q", knuth.Title)
+	}
+}
+
+func TestLabelAndFormat(t *testing.T) {
+	entries, _ := Parse(testBib)
+	labels := []string{"Turing 1950", "Lovelace & Babbage 1843", "Knuth et al."}
+	for i, want := range labels {
+		if got := entries[i].Label(); got != want {
+			t.Errorf("Label() = %q, want %q", got, want)
+		}
+	}
+	if got, want := entries[0].Format(), "Turing, Alan M. (1950). Computing Machinery and Intelligence. Mind."; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+	if got := entries[2].Format(); got != "Knuth, Donald; Lamport, Leslie; Others TeX & friends — a history. https://doi.org/10.1000/xyz" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	if got := Search(entries, "engine LOVELACE"); len(got) != 1 || got[0].Key != "lovelace1843" {
+		t.Errorf("Search = %+v", got)
+	}
+}
+
+func TestCitationKeys(t *testing.T) {
+	text := "As shown [@turing1950, p. 433; see also @knuth:tex]. Mail [me@example.com] " +
+		"or cite again [-@turing1950]. Not a citation: @lonely. Trailing [@lovelace1843.]"
+	want := []string{"turing1950", "knuth:tex", "lovelace1843"}
+	if got := CitationKeys(text); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CitationKeys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AttachmentKindNote  = "note"
+	AttachmentKindImage = "image"
+
+	// AttachmentsDir is the vault-relative folder chat attachments are stored in
+	AttachmentsDir = "attachments/chat"
+
+	maxAttachmentBytes      = 20 << 20
+	maxAttachmentPromptSize = 8000
+)
+
+// Attachment references a file stored under the vault attachments folder
+type Attachment struct {
+	Kind       string `json:"kind"`                  // note or image
+	Name       string `json:"name"`                  // display name
+	Path       string `json:"path"`                  // vault-relative path of the stored copy
+	SourcePath string `json:"source_path,omitempty"` // note the snapshot was taken from
+	MimeType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// SaveNoteSnapshot stores a copy of a note's current content so the message keeps
+// referring to what the note said at the time, even if it is edited later.
+func (s *Service) SaveNoteSnapshot(notePath, content string) (*Attachment, error) {
+	notePath = filepath.ToSlash(strings.TrimSpace(notePath))
+	if notePath == "" {
+		return nil, fmt.Errorf("note path is required")
+	}
+	name := filepath.Base(notePath)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	// Not .md, so the snapshot is not picked up as a note by the indexer
+	att, err := s.writeAttachment(AttachmentKindNote, stem+".snapshot.txt", []byte(content))
+	if err != nil {
+		return nil, err
+	}
+	att.Name = name
+	att.SourcePath = notePath
+	att.MimeType = "text/markdown"
+	return att, nil
+}
+
+// SaveImageAttachment stores image bytes under the vault attachments folder
+func (s *Service) SaveImageAttachment(name string, data []byte) (*Attachment, error) {
+	name = strings.TrimSpace(filepath.Base(filepath.ToSlash(name)))
+	if name == "" || name == "." {
+		name = "image.png"
+	}
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("unsupported image type: %s", name)
+	}
+	att, err := s.writeAttachment(AttachmentKindImage, name, data)
+	if err != nil {
+		return nil, err
+	}
+	att.MimeType = mimeType
+	return att, nil
+}
+
+func (s *Service) writeAttachment(kind, name string, data []byte) (*Attachment, error) {
+	if s.basePath == "" {
+		return nil, fmt.Errorf("no vault open")
+	}
+	if len(data) > maxAttachmentBytes {
+		return nil, fmt.Errorf("attachment exceeds %d MB", maxAttachmentBytes>>20)
+	}
+	dir := filepath.Join(s.basePath, filepath.FromSlash(AttachmentsDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	stored := uuid.NewString()[:8] + "_" + sanitizeFilename(name)
+	if err := os.WriteFile(filepath.Join(dir, stored), data, 0644); err != nil {
+		return nil, err
+	}
+	return &Attachment{
+		Kind:      kind,
+		Name:      name,
+		Path:      AttachmentsDir + "/" + stored,
+		Size:      int64(len(data)),
+		CreatedAt: time.Now().UnixMilli(),
+	}, nil
+}
+
+// resolveAttachmentPath maps a vault-relative attachment path to an absolute path,
+// refusing anything outside the attachments folder.
+func (s *Service) resolveAttachmentPath(relPath string) (string, error) {
+	root := filepath.Join(s.basePath, filepath.FromSlash(AttachmentsDir))
+	full := filepath.Join(s.basePath, filepath.FromSlash(relPath))
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid attachment path: %s", relPath)
+	}
+	return full, nil
+}
+
+// AttachmentPromptContext renders attached notes as prompt context. Images are
+// listed by name only since the completion request is text-only.
+func (s *Service) AttachmentPromptContext(attachments []Attachment) string {
+	var sb strings.Builder
+	for _, att := range attachments {
+		switch att.Kind {
+		case AttachmentKindNote:
+			full, err := s.resolveAttachmentPath(att.Path)
+			if err != nil {
+				continue
+			}
+			data, err := os.ReadFile(full)
+			if err != nil {
+				continue
+			}
+			content := string(data)
+			if len(content) > maxAttachmentPromptSize {
+				content = content[:maxAttachmentPromptSize]
+				for !utf8.ValidString(content) {
+					content = content[:len(content)-1]
+				}
+				content += "\n..."
+			}
+			sb.WriteString(fmt.Sprintf("Attached note (%s):\n%s\n\n", att.Name, content))
+		case AttachmentKindImage:
+			sb.WriteString(fmt.Sprintf("Attached image: %s\n\n", att.Name))
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// ReadAttachment returns the stored bytes of an attachment
+func (s *Service) ReadAttachment(relPath string) ([]byte, error) {
+	full, err := s.resolveAttachmentPath(relPath)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(full)
+}
+
+func attachmentLabel(att Attachment) string {
+	label := att.Name
+	if att.Kind == AttachmentKindImage {
+		label = "image: " + label
+	}
+	return label + " (" + att.Path + ")"
+}
@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBackupKeepLast = 20
+	defaultBackupKeepDays = 30
+
+	backupPrefix = "chat_backup_"
+	gzipSuffix   = ".gz"
+	encSuffix    = ".enc"
+)
+
+// BackupInfo describes a stored chat backup
+type BackupInfo struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	CreatedAt  int64  `json:"created_at"` // Unix ms
+	Compressed bool   `json:"compressed"`
+	Encrypted  bool   `json:"encrypted"`
+}
+
+func (s *Service) backupDir() string {
+	if dir := s.backupRoot.Load(); dir != nil {
+		return *dir
+	}
+	return filepath.Join(s.basePath, "data", "chat_backups")
+}
+
+// SetBackupDir stores backups in dir instead of the vault's data folder, e.g.
+// next to the decrypted database of a locked vault
+func (s *Service) SetBackupDir(dir string) {
+	s.backupRoot.Store(&dir)
+}
+
+// writeBackup stores a JSON payload, compressed and encrypted as configured,
+// then applies the retention policy. Encrypted backups use the current chat
+// key, so they become unreadable after the key is rotated.
+func (s *Service) writeBackup(payload []byte, now time.Time) (string, error) {
+	s.mu.RLock()
+	opts := s.options
+	s.mu.RUnlock()
+
+	if err := os.MkdirAll(s.backupDir(), 0755); err != nil {
+		return "", err
+	}
+	name := backupPrefix + now.Format("20060102_150405") + ".json"
+	if opts.BackupCompress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		payload = buf.Bytes()
+		name += gzipSuffix
+	}
+	if opts.BackupEncrypt {
+		s.keyMu.RLock()
+		key := s.key
+		s.keyMu.RUnlock()
+		if key == nil {
+			return "", ErrChatLocked
+		}
+		enc, err := encryptWithKey(key, string(payload))
+		if err != nil {
+			return "", err
+		}
+		payload = []byte(enc)
+		name += encSuffix
+	}
+
+	filePath := filepath.Join(s.backupDir(), name)
+	if err := os.WriteFile(filePath, payload, 0644); err != nil {
+		return "", err
+	}
+	if _, err := s.pruneBackups(opts.BackupKeepLast, opts.BackupKeepDays, now); err != nil {
+		return filePath, err
+	}
+	return filePath, nil
+}
+
+// ListBackups returns stored backups, newest first
+func (s *Service) ListBackups() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupInfo{}, nil
+		}
+		return nil, err
+	}
+	backups := make([]BackupInfo, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Name:       name,
+			Size:       info.Size(),
+			CreatedAt:  info.ModTime().UnixMilli(),
+			Compressed: strings.Contains(name, ".json"+gzipSuffix),
+			Encrypted:  strings.HasSuffix(name, encSuffix),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt != backups[j].CreatedAt {
+			return backups[i].CreatedAt > backups[j].CreatedAt
+		}
+		return backups[i].Name > backups[j].Name
+	})
+	return backups, nil
+}
+
+// DeleteBackup removes a backup by the name returned from ListBackups
+func (s *Service) DeleteBackup(name string) error {
+	filePath, err := s.backupPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// ReadBackup returns the JSON payload of a backup, decrypting and
+// decompressing it as needed
+func (s *Service) ReadBackup(name string) ([]byte, error) {
+	filePath, err := s.backupPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(name, encSuffix) {
+		s.keyMu.RLock()
+		key := s.key
+		s.keyMu.RUnlock()
+		if key == nil {
+			return nil, ErrChatLocked
+		}
+		plain, err := decryptWithKey(key, string(data))
+		if err != nil {
+			return nil, fmt.Errorf("backup cannot be decrypted with the current key: %w", err)
+		}
+		data = []byte(plain)
+		name = strings.TrimSuffix(name, encSuffix)
+	}
+	if strings.HasSuffix(name, gzipSuffix) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (s *Service) backupPath(name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, backupPrefix) {
+		return "", fmt.Errorf("invalid backup name: %s", name)
+	}
+	return filepath.Join(s.backupDir(), name), nil
+}
+
+// pruneBackups keeps the newest keepLast backups and drops those older than
+// keepDays; zero disables either limit. It returns how many were removed.
+func (s *Service) pruneBackups(keepLast, keepDays int, now time.Time) (int, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := int64(0)
+	if keepDays > 0 {
+		cutoff = now.AddDate(0, 0, -keepDays).UnixMilli()
+	}
+	removed := 0
+	for i, b := range backups {
+		if (keepLast > 0 && i >= keepLast) || (cutoff > 0 && b.CreatedAt < cutoff) {
+			if err := s.DeleteBackup(b.Name); err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
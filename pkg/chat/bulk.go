@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BulkDelete deletes sessions with their messages, tags and attached notes,
+// returning how many sessions were removed
+func (s *Service) BulkDelete(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var deleted int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id IN ?", ids).Delete(&SessionTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id IN ?", ids).Delete(&ContextNote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id IN ?", ids).Delete(&Message{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&Session{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// BulkArchive archives or unarchives sessions, returning how many were updated
+func (s *Service) BulkArchive(ids []string, archived bool) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := s.db.Model(&Session{}).Where("id IN ?", ids).Updates(map[string]any{
+		"archived":        archived,
+		"updated_at_unix": time.Now().UnixMilli(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// BulkTag adds tags to sessions, keeping their existing tags
+func (s *Service) BulkTag(ids []string, tags []string) error {
+	tags = normalizeTags(tags)
+	if len(ids) == 0 || len(tags) == 0 {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		if err := tx.Model(&Session{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		rows := make([]SessionTag, 0, len(existing)*len(tags))
+		for _, id := range existing {
+			for _, tag := range tags {
+				rows = append(rows, SessionTag{SessionID: id, Tag: tag})
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Session{}).Where("id IN ?", existing).Update("updated_at_unix", time.Now().UnixMilli()).Error
+	})
+}
+
+// ArchiveInactiveSessions archives sessions whose last message is more than
+// days old; new sessions count their creation as a message. Favorites are
+// never archived.
+func (s *Service) ArchiveInactiveSessions(days int, now time.Time) (int64, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -days).UnixMilli()
+	result := s.db.Model(&Session{}).
+		Where("archived = ? AND favorite = ?", false, false).
+		Where("last_message_at < ?", cutoff).
+		Updates(map[string]any{
+			"archived":        true,
+			"updated_at_unix": now.UnixMilli(),
+		})
+	return result.RowsAffected, result.Error
+}
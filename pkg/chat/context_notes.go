@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ContextNote is a vault note attached to a session whose content is always
+// offered to the model, whatever retrieval finds
+type ContextNote struct {
+	SessionID string `gorm:"primaryKey;size:64" json:"session_id"`
+	Path      string `gorm:"primaryKey;size:1024;index" json:"path"`
+	CreatedAt time.Time
+}
+
+func (ContextNote) TableName() string {
+	return "chat_session_context_notes"
+}
+
+// AttachNote adds a note to the session's always-included context
+func (s *Service) AttachNote(sessionID, path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("note path is required")
+	}
+	var session Session
+	if err := s.db.Select("id").First(&session, "id = ?", sessionID).Error; err != nil {
+		return err
+	}
+	note := ContextNote{SessionID: sessionID, Path: path}
+	return s.db.Where(note).FirstOrCreate(&note).Error
+}
+
+// DetachNote removes a note from the session's always-included context
+func (s *Service) DetachNote(sessionID, path string) error {
+	return s.db.Where("session_id = ? AND path = ?", sessionID, strings.TrimSpace(path)).Delete(&ContextNote{}).Error
+}
+
+// ListAttachedNotes returns the paths attached to a session, in attach order
+func (s *Service) ListAttachedNotes(sessionID string) ([]string, error) {
+	paths := []string{}
+	err := s.db.Model(&ContextNote{}).Where("session_id = ?", sessionID).
+		Order("created_at ASC, path ASC").Pluck("path", &paths).Error
+	return paths, err
+}
+
+// RenameAttachedNotePaths keeps attachments pointing at a renamed note or folder
+func (s *Service) RenameAttachedNotePaths(oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	if err := s.db.Model(&ContextNote{}).Where("path = ?", oldPath).Update("path", newPath).Error; err != nil {
+		return err
+	}
+	return s.db.Model(&ContextNote{}).
+		Where("path LIKE ? ESCAPE '\\'", escapeLike(oldPath)+"/%").
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, utf8.RuneCountInString(oldPath)+1)).Error
+}
+
+// DetachNoteEverywhere removes a deleted note or folder from every session
+func (s *Service) DetachNoteEverywhere(path string) error {
+	path = strings.TrimSuffix(path, "/")
+	return s.db.Where("path = ? OR path LIKE ? ESCAPE '\\'", path, escapeLike(path)+"/%").Delete(&ContextNote{}).Error
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
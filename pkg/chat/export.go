@@ -0,0 +1,204 @@
+package chat
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// Export formats accepted by ExportSession
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatText     = "txt"
+	ExportFormatMarkdown = "markdown"
+	ExportFormatHTML     = "html"
+)
+
+// normalizeExportFormat maps user input to an export format, defaulting to JSON
+func normalizeExportFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "txt", "text":
+		return ExportFormatText
+	case "md", "markdown":
+		return ExportFormatMarkdown
+	case "html", "htm":
+		return ExportFormatHTML
+	default:
+		return ExportFormatJSON
+	}
+}
+
+func exportExtension(format string) string {
+	switch format {
+	case ExportFormatMarkdown:
+		return "md"
+	case ExportFormatHTML:
+		return "html"
+	default:
+		return format
+	}
+}
+
+// exportSource is a citation as written to Markdown and HTML exports
+type exportSource struct {
+	title   string
+	path    string
+	excerpt string
+}
+
+func exportSources(sources []map[string]any) []exportSource {
+	out := make([]exportSource, 0, len(sources))
+	for _, src := range sources {
+		title, _ := src["title"].(string)
+		path, _ := src["path"].(string)
+		content, _ := src["content"].(string)
+		if title == "" {
+			title = path
+		}
+		if title == "" && content == "" {
+			continue
+		}
+		out = append(out, exportSource{title: title, path: path, excerpt: strings.Join(strings.Fields(content), " ")})
+	}
+	return out
+}
+
+// renderMarkdownExport writes messages verbatim, so code fences in answers
+// survive, with citations folded into a <details> block
+func renderMarkdownExport(session *SessionListItem, messages []MessageDTO) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", session.Title))
+	if session.Category != "" {
+		sb.WriteString(fmt.Sprintf("Category: %s  \n", session.Category))
+	}
+	sb.WriteString(fmt.Sprintf("Exported: %s\n\n", time.Now().Format("2006-01-02 15:04")))
+
+	for _, m := range messages {
+		when := time.UnixMilli(m.Timestamp).Format("2006-01-02 15:04")
+		sb.WriteString(fmt.Sprintf("## %s · %s\n\n", roleLabel(m.Role), when))
+		sb.WriteString(strings.TrimRight(m.Content, "\n"))
+		sb.WriteString("\n\n")
+		// An unclosed fence would swallow everything after it
+		if strings.Count(m.Content, "```")%2 == 1 {
+			sb.WriteString("```\n\n")
+		}
+		for _, att := range m.Attachments {
+			if att.Kind == AttachmentKindImage {
+				sb.WriteString(fmt.Sprintf("![%s](%s)\n\n", att.Name, att.Path))
+				continue
+			}
+			sb.WriteString("Attached note: " + attachmentLabel(att) + "\n\n")
+		}
+		if sources := exportSources(m.Sources); len(sources) > 0 {
+			sb.WriteString(fmt.Sprintf("<details>\n<summary>Sources (%d)</summary>\n\n", len(sources)))
+			for _, src := range sources {
+				line := "- **" + src.title + "**"
+				if src.path != "" && src.path != src.title {
+					line += " (`" + src.path + "`)"
+				}
+				if src.excerpt != "" {
+					line += ": " + src.excerpt
+				}
+				sb.WriteString(line + "\n")
+			}
+			sb.WriteString("\n</details>\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderHTMLExport produces a self-contained page. Message text is escaped
+// rather than rendered as Markdown; fenced code becomes <pre><code> blocks.
+func renderHTMLExport(session *SessionListItem, messages []MessageDTO) string {
+	var sb strings.Builder
+	title := html.EscapeString(session.Title)
+	sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", title))
+	sb.WriteString("<style>body{font-family:sans-serif;max-width:48rem;margin:2rem auto;padding:0 1rem;line-height:1.5}" +
+		"section{border-top:1px solid #ddd;padding:.5rem 0}h3 small{color:#888;font-weight:normal}" +
+		".text{white-space:pre-wrap}pre{background:#f5f5f5;padding:.75rem;overflow-x:auto}" +
+		"details{color:#555;font-size:.9em}img{max-width:100%}</style>\n")
+	sb.WriteString("</head><body>\n")
+	sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", title))
+	if session.Category != "" {
+		sb.WriteString(fmt.Sprintf("<p>Category: %s</p>\n", html.EscapeString(session.Category)))
+	}
+
+	for _, m := range messages {
+		when := time.UnixMilli(m.Timestamp).Format("2006-01-02 15:04")
+		sb.WriteString(fmt.Sprintf("<section class=\"%s\"><h3>%s <small>%s</small></h3>\n",
+			html.EscapeString(m.Role), html.EscapeString(roleLabel(m.Role)), when))
+		writeHTMLContent(&sb, m.Content)
+		for _, att := range m.Attachments {
+			if att.Kind == AttachmentKindImage {
+				sb.WriteString(fmt.Sprintf("<figure><img src=\"%s\" alt=\"%s\"><figcaption>%s</figcaption></figure>\n",
+					html.EscapeString(att.Path), html.EscapeString(att.Name), html.EscapeString(att.Name)))
+				continue
+			}
+			sb.WriteString("<p>Attached note: " + html.EscapeString(attachmentLabel(att)) + "</p>\n")
+		}
+		if sources := exportSources(m.Sources); len(sources) > 0 {
+			sb.WriteString(fmt.Sprintf("<details><summary>Sources (%d)</summary><ul>\n", len(sources)))
+			for _, src := range sources {
+				sb.WriteString("<li><strong>" + html.EscapeString(src.title) + "</strong>")
+				if src.path != "" && src.path != src.title {
+					sb.WriteString(" (<code>" + html.EscapeString(src.path) + "</code>)")
+				}
+				if src.excerpt != "" {
+					sb.WriteString(": " + html.EscapeString(src.excerpt))
+				}
+				sb.WriteString("</li>\n")
+			}
+			sb.WriteString("</ul></details>\n")
+		}
+		sb.WriteString("</section>\n")
+	}
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
+
+// writeHTMLContent splits content on ``` fences, emitting code blocks as
+// <pre><code> with the fence language as a class and the rest as text
+func writeHTMLContent(sb *strings.Builder, content string) {
+	var text, code []string
+	inCode := false
+	lang := ""
+	flushText := func() {
+		if body := strings.Trim(strings.Join(text, "\n"), "\n"); body != "" {
+			sb.WriteString("<div class=\"text\">" + html.EscapeString(body) + "</div>\n")
+		}
+		text = nil
+	}
+	flushCode := func() {
+		class := ""
+		if lang != "" {
+			class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang))
+		}
+		sb.WriteString(fmt.Sprintf("<pre><code%s>%s</code></pre>\n", class, html.EscapeString(strings.Join(code, "\n"))))
+		code = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				flushCode()
+			} else {
+				flushText()
+				lang = strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+		} else {
+			text = append(text, line)
+		}
+	}
+	if inCode {
+		flushCode()
+	}
+	flushText()
+}
@@ -0,0 +1,267 @@
+package chat
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"gorm.io/gorm"
+)
+
+// Key modes for encryption at rest
+const (
+	// KeyModeDevice derives the key from the vault path and host name, so the
+	// history is only readable on this machine at this location
+	KeyModeDevice = "device"
+	// KeyModePassphrase derives the key from a user passphrase with Argon2id;
+	// the history is locked until Unlock is called
+	KeyModePassphrase = "passphrase"
+)
+
+const (
+	keySettingsScope = "chat.key"
+	keyCheckPlain    = "notebit-chat-key-check"
+
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// ErrChatLocked is returned when encrypted history is accessed before the passphrase is entered
+var ErrChatLocked = errors.New("chat history is locked - enter the passphrase to unlock it")
+
+// ErrWrongPassphrase is returned by Unlock when the passphrase does not match the stored key
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+type keyState struct {
+	Mode  string
+	Salt  []byte
+	Check string // keyCheckPlain encrypted with the key, to verify passphrases
+}
+
+// KeyStatus describes the chat encryption key
+type KeyStatus struct {
+	Mode   string `json:"mode"`   // "device" or "passphrase"
+	Locked bool   `json:"locked"` // Passphrase not entered yet
+}
+
+// RotationResult reports a key rotation
+type RotationResult struct {
+	Reencrypted   int `json:"reencrypted"`   // Messages re-encrypted under the new key
+	Undecryptable int `json:"undecryptable"` // Messages left as they were because the old key could not decrypt them
+}
+
+func (s *Service) loadKeyState() error {
+	var settings []Setting
+	if err := s.db.Where("scope = ?", keySettingsScope).Find(&settings).Error; err != nil {
+		return err
+	}
+	s.keyState = keyState{Mode: KeyModeDevice}
+	for _, item := range settings {
+		switch item.Key {
+		case "mode":
+			if item.Value == KeyModePassphrase {
+				s.keyState.Mode = KeyModePassphrase
+			}
+		case "salt":
+			salt, err := base64.StdEncoding.DecodeString(item.Value)
+			if err != nil {
+				return fmt.Errorf("invalid chat key salt: %w", err)
+			}
+			s.keyState.Salt = salt
+		case "check":
+			s.keyState.Check = item.Value
+		}
+	}
+	return nil
+}
+
+func saveKeyState(tx *gorm.DB, state keyState) error {
+	values := map[string]string{
+		"mode":  state.Mode,
+		"salt":  base64.StdEncoding.EncodeToString(state.Salt),
+		"check": state.Check,
+	}
+	for key, value := range values {
+		setting := Setting{Scope: keySettingsScope, Key: key, Value: value}
+		if err := tx.Where("scope = ? AND key = ?", setting.Scope, setting.Key).Assign(setting).FirstOrCreate(&setting).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func derivePassphraseKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+}
+
+// GetKeyStatus reports the key mode and whether history is locked
+func (s *Service) GetKeyStatus() KeyStatus {
+	s.keyMu.RLock()
+	defer s.keyMu.RUnlock()
+	return KeyStatus{Mode: s.keyState.Mode, Locked: s.key == nil}
+}
+
+// Unlock derives the passphrase key and checks it against the stored verifier
+func (s *Service) Unlock(passphrase string) error {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	if s.keyState.Mode != KeyModePassphrase {
+		return nil
+	}
+	key := derivePassphraseKey(passphrase, s.keyState.Salt)
+	if plain, err := decryptWithKey(key, s.keyState.Check); err != nil || plain != keyCheckPlain {
+		return ErrWrongPassphrase
+	}
+	s.key = key
+	return nil
+}
+
+// Lock forgets the passphrase key until the next Unlock. It has no effect with a device key.
+func (s *Service) Lock() {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	if s.keyState.Mode == KeyModePassphrase {
+		s.key = nil
+	}
+}
+
+// RotateKey re-encrypts all encrypted messages under a new key: one derived
+// from newPassphrase with a fresh salt, or the device key when newPassphrase
+// is empty. Calling it with the current passphrase rotates the key in place.
+// Messages the current key cannot decrypt are left untouched and counted.
+func (s *Service) RotateKey(newPassphrase string) (*RotationResult, error) {
+	s.keyMu.Lock()
+	defer s.keyMu.Unlock()
+	oldKey := s.key
+	if oldKey == nil {
+		return nil, ErrChatLocked
+	}
+
+	state := keyState{Mode: KeyModeDevice}
+	newKey := s.deriveKey()
+	if newPassphrase != "" {
+		salt := make([]byte, argonSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		state = keyState{Mode: KeyModePassphrase, Salt: salt}
+		newKey = derivePassphraseKey(newPassphrase, salt)
+	}
+	check, err := encryptWithKey(newKey, keyCheckPlain)
+	if err != nil {
+		return nil, err
+	}
+	state.Check = check
+
+	result := &RotationResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var batch []Message
+		if err := tx.Where("encrypted = ? OR sources_encrypted = ? OR attachments_encrypted = ?", true, true, true).
+			FindInBatches(&batch, 200, func(btx *gorm.DB, _ int) error {
+				for _, row := range batch {
+					updates, ok := reencryptMessage(row, oldKey, newKey)
+					if !ok {
+						result.Undecryptable++
+						continue
+					}
+					if err := tx.Model(&Message{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+						return err
+					}
+					result.Reencrypted++
+				}
+				return nil
+			}).Error; err != nil {
+			return err
+		}
+		if err := reencryptPreviews(tx, oldKey, newKey); err != nil {
+			return err
+		}
+		return saveKeyState(tx, state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.key = newKey
+	s.keyState = state
+	return result, nil
+}
+
+// reencryptMessage returns the column updates that move row's encrypted
+// fields from oldKey to newKey, or false if any field cannot be decrypted
+func reencryptMessage(row Message, oldKey, newKey []byte) (map[string]any, bool) {
+	updates := make(map[string]any)
+	fields := []struct {
+		column    string
+		value     string
+		encrypted bool
+	}{
+		{"content", row.Content, row.Encrypted},
+		{"sources", row.Sources, row.SourcesEncrypted},
+		{"attachments", row.Attachments, row.AttachmentsEncrypted},
+	}
+	for _, f := range fields {
+		if !f.encrypted || f.value == "" {
+			continue
+		}
+		plain, err := decryptWithKey(oldKey, f.value)
+		if err != nil {
+			return nil, false
+		}
+		enc, err := encryptWithKey(newKey, plain)
+		if err != nil {
+			return nil, false
+		}
+		updates[f.column] = enc
+	}
+	return updates, true
+}
+
+// reencryptPreviews moves stored session previews to newKey; previews the
+// old key cannot read are marked stale so they are rebuilt from messages
+func reencryptPreviews(tx *gorm.DB, oldKey, newKey []byte) error {
+	var sessions []Session
+	if err := tx.Select("id", "last_message_preview").Where("preview_encrypted = ?", true).Find(&sessions).Error; err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		updates := map[string]any{"summary_ready": false}
+		if plain, err := decryptWithKey(oldKey, session.Preview); err == nil {
+			if enc, err := encryptWithKey(newKey, plain); err == nil {
+				updates = map[string]any{"last_message_preview": enc}
+			}
+		}
+		if err := tx.Model(&Session{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountUndecryptableMessages counts encrypted messages the current key cannot
+// decrypt, e.g. history encrypted on another machine with a device key
+func (s *Service) CountUndecryptableMessages() (int, error) {
+	s.keyMu.RLock()
+	key := s.key
+	s.keyMu.RUnlock()
+	if key == nil {
+		return 0, ErrChatLocked
+	}
+	count := 0
+	var batch []Message
+	err := s.db.Select("id", "content").Where("encrypted = ?", true).
+		FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+			for _, row := range batch {
+				if _, err := decryptWithKey(key, row.Content); err != nil {
+					count++
+				}
+			}
+			return nil
+		}).Error
+	return count, err
+}
@@ -11,8 +11,23 @@ type Session struct {
 	CreatedAtUnix int64  `gorm:"index" json:"created_at_unix"`
 	UpdatedAtUnix int64  `gorm:"index" json:"updated_at_unix"`
 	LastMessageAt int64  `gorm:"index" json:"last_message_at"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Per-session overrides of the global LLM and RAG settings; empty or nil uses the global value
+	Model        string   `gorm:"size:128" json:"model"`
+	Temperature  *float32 `json:"temperature"`
+	SystemPrompt string   `gorm:"type:text" json:"system_prompt"`
+	// Notes is a free-form scratchpad for the session
+	Notes string `gorm:"type:text" json:"notes"`
+	// MessageCount, TotalTokens and Preview (the start of the last message,
+	// encrypted like message content) are updated with every message change,
+	// so listing sessions does not read their messages. SummaryReady is false
+	// while Preview needs rebuilding, e.g. after an upgrade or while locked.
+	MessageCount     int64  `json:"message_count"`
+	TotalTokens      int64  `json:"total_tokens"`
+	Preview          string `gorm:"column:last_message_preview;type:text" json:"-"`
+	PreviewEncrypted bool   `json:"-"`
+	SummaryReady     bool   `json:"-"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 func (Session) TableName() string {
@@ -20,18 +35,23 @@ func (Session) TableName() string {
 }
 
 type Message struct {
-	ID               string `gorm:"primaryKey;size:64" json:"id"`
-	SessionID        string `gorm:"index;size:64;not null" json:"session_id"`
-	Role             string `gorm:"index;size:16" json:"role"`
-	Content          string `gorm:"type:text" json:"content"`
-	Encrypted        bool   `gorm:"index" json:"encrypted"`
-	Sources          string `gorm:"type:text" json:"sources"`
-	SourcesEncrypted bool   `gorm:"index" json:"sources_encrypted"`
-	Status           string `gorm:"index;size:16" json:"status"`
-	Timestamp        int64  `gorm:"index" json:"timestamp"`
-	TokensUsed       *int   `json:"tokens_used,omitempty"`
-	CreatedAt        time.Time
-	UpdatedAt        time.Time
+	ID                   string `gorm:"primaryKey;size:64" json:"id"`
+	SessionID            string `gorm:"index;size:64;not null" json:"session_id"`
+	Role                 string `gorm:"index;size:16" json:"role"`
+	Content              string `gorm:"type:text" json:"content"`
+	Encrypted            bool   `gorm:"index" json:"encrypted"`
+	Sources              string `gorm:"type:text" json:"sources"`
+	SourcesEncrypted     bool   `gorm:"index" json:"sources_encrypted"`
+	Attachments          string `gorm:"type:text" json:"attachments"`
+	AttachmentsEncrypted bool   `json:"attachments_encrypted"`
+	Status               string `gorm:"index;size:16" json:"status"`
+	Timestamp            int64  `gorm:"index" json:"timestamp"`
+	TokensUsed           *int   `json:"tokens_used,omitempty"`
+	// CostUSD is the estimated cost of generating the message, 0 for local or unknown models
+	CostUSD   float64 `json:"cost_usd"`
+	Pinned    bool    `gorm:"index" json:"pinned"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (Message) TableName() string {
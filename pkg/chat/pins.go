@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SetMessagePinned pins or unpins a message
+func (s *Service) SetMessagePinned(messageID string, pinned bool) error {
+	result := s.db.Model(&Message{}).Where("id = ?", messageID).Update("pinned", pinned)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListPinnedMessages returns pinned messages in timestamp order, for one
+// session or for all sessions when sessionID is empty
+func (s *Service) ListPinnedMessages(sessionID string) ([]MessageDTO, error) {
+	q := s.db.Model(&Message{}).Where("pinned = ?", true)
+	if sessionID != "" {
+		q = q.Where("session_id = ?", sessionID)
+	}
+	var rows []Message
+	if err := q.Order("timestamp ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]MessageDTO, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, s.messageDTO(row))
+	}
+	return items, nil
+}
+
+// SetSessionNotes replaces the free-form notes of a session
+func (s *Service) SetSessionNotes(sessionID, notes string) error {
+	return s.updateSession(sessionID, map[string]any{"notes": strings.TrimSpace(notes)})
+}
+
+// GetSessionNotes returns the free-form notes of a session
+func (s *Service) GetSessionNotes(sessionID string) (string, error) {
+	var session Session
+	if err := s.db.Select("notes").First(&session, "id = ?", sessionID).Error; err != nil {
+		return "", err
+	}
+	return session.Notes, nil
+}
@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,6 +38,18 @@ type StorageOptions struct {
 	AutoBackupEnabled   bool   `json:"auto_backup_enabled"`
 	BackupIntervalMins  int    `json:"backup_interval_mins"`
 	PreferredExportType string `json:"preferred_export_type"`
+	// AutoTitle renames placeholder-titled sessions after their first reply
+	AutoTitle bool `json:"auto_title"`
+	// BackupKeepLast keeps at most this many backups (0 = no limit)
+	BackupKeepLast int `json:"backup_keep_last"`
+	// BackupKeepDays deletes backups older than this many days (0 = no limit)
+	BackupKeepDays int `json:"backup_keep_days"`
+	// BackupCompress gzips backup files
+	BackupCompress bool `json:"backup_compress"`
+	// BackupEncrypt encrypts backup files with the chat key
+	BackupEncrypt bool `json:"backup_encrypt"`
+	// AutoArchiveDays archives sessions without activity for this many days (0 = off)
+	AutoArchiveDays int `json:"auto_archive_days"`
 }
 
 type SessionFilter struct {
@@ -63,6 +76,20 @@ type SessionListItem struct {
 	LastMessageAt int64    `json:"last_message_at"`
 	MessageCount  int64    `json:"message_count"`
 	Preview       string   `json:"preview"`
+	// TotalTokens and CostUSD sum the usage recorded on the session's messages
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+	Notes       string  `json:"notes,omitempty"`
+	PinnedCount int64   `json:"pinned_count"`
+	SessionOverrides
+}
+
+// SessionOverrides replace the global model, temperature and system prompt
+// for one session; zero values fall back to the global configuration
+type SessionOverrides struct {
+	Model        string   `json:"model,omitempty"`
+	Temperature  *float32 `json:"temperature,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
 }
 
 type SessionListResult struct {
@@ -73,14 +100,20 @@ type SessionListResult struct {
 }
 
 type MessageDTO struct {
-	ID         string           `json:"id"`
-	SessionID  string           `json:"session_id"`
-	Role       string           `json:"role"`
-	Content    string           `json:"content"`
-	Sources    []map[string]any `json:"sources,omitempty"`
-	TokensUsed *int             `json:"tokens_used,omitempty"`
-	Status     string           `json:"status"`
-	Timestamp  int64            `json:"timestamp"`
+	ID          string           `json:"id"`
+	SessionID   string           `json:"session_id"`
+	Role        string           `json:"role"`
+	Content     string           `json:"content"`
+	Sources     []map[string]any `json:"sources,omitempty"`
+	Attachments []Attachment     `json:"attachments,omitempty"`
+	TokensUsed  *int             `json:"tokens_used,omitempty"`
+	CostUSD     float64          `json:"cost_usd,omitempty"`
+	Pinned      bool             `json:"pinned,omitempty"`
+	Status      string           `json:"status"`
+	Timestamp   int64            `json:"timestamp"`
+	// Undecryptable is set when the content cannot be decrypted with the
+	// current key, e.g. while a passphrase key is locked
+	Undecryptable bool `json:"undecryptable,omitempty"`
 }
 
 type MessageListResult struct {
@@ -95,10 +128,14 @@ type Service struct {
 	basePath  string
 	mu        sync.RWMutex
 	options   StorageOptions
-	key       []byte
+	keyMu     sync.RWMutex
+	key       []byte // nil while a passphrase key is locked
+	keyState  keyState
 	stopCh    chan struct{}
 	doneCh    chan struct{}
 	closeOnce sync.Once
+
+	backupRoot atomic.Pointer[string] // see SetBackupDir
 }
 
 func NewService(db *gorm.DB, basePath string) (*Service, error) {
@@ -113,6 +150,10 @@ func NewService(db *gorm.DB, basePath string) (*Service, error) {
 		AutoBackupEnabled:   true,
 		BackupIntervalMins:  30,
 		PreferredExportType: "json",
+		AutoTitle:           true,
+		BackupKeepLast:      defaultBackupKeepLast,
+		BackupKeepDays:      defaultBackupKeepDays,
+		BackupCompress:      true,
 	}
 
 	if err := s.autoMigrate(); err != nil {
@@ -121,8 +162,19 @@ func NewService(db *gorm.DB, basePath string) (*Service, error) {
 	if err := s.loadOptions(); err != nil {
 		return nil, err
 	}
-	s.key = s.deriveKey()
-	s.startBackupTicker()
+	if err := s.loadKeyState(); err != nil {
+		return nil, err
+	}
+	if s.keyState.Mode != KeyModePassphrase {
+		s.key = s.deriveKey()
+	}
+	if _, err := s.MarkInterruptedMessages(); err != nil {
+		return nil, err
+	}
+	if s.options.AutoArchiveDays > 0 {
+		_, _ = s.ArchiveInactiveSessions(s.options.AutoArchiveDays, time.Now())
+	}
+	s.startMaintenanceTicker()
 	return s, nil
 }
 
@@ -145,9 +197,19 @@ func (s *Service) Close() {
 }
 
 func (s *Service) autoMigrate() error {
-	if err := s.db.AutoMigrate(&Session{}, &Message{}, &SessionTag{}, &Setting{}); err != nil {
+	needsCounters := s.db.Migrator().HasTable(&Session{}) && !s.db.Migrator().HasColumn(&Session{}, "TotalTokens")
+	if err := migrateLegacyPreview(s.db); err != nil {
+		return err
+	}
+	if err := s.db.AutoMigrate(&Session{}, &Message{}, &SessionTag{}, &ContextNote{}, &Setting{}); err != nil {
 		return err
 	}
+	if needsCounters {
+		// Previews need the chat key and are rebuilt when sessions are listed
+		if err := backfillSessionCounters(s.db); err != nil {
+			return err
+		}
+	}
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_chat_sessions_last_message_at ON chat_sessions(last_message_at)",
 		"CREATE INDEX IF NOT EXISTS idx_chat_messages_session_time ON chat_messages(session_id, timestamp)",
@@ -185,8 +247,29 @@ func (s *Service) loadOptions() error {
 			}
 		case "preferred_export_type":
 			if item.Value != "" {
-				s.options.PreferredExportType = item.Value
+				s.options.PreferredExportType = normalizeExportFormat(item.Value)
+			}
+		case "auto_title":
+			s.options.AutoTitle = item.Value == "true"
+		case "backup_keep_last":
+			var n int
+			if _, err := fmt.Sscanf(item.Value, "%d", &n); err == nil && n >= 0 {
+				s.options.BackupKeepLast = n
+			}
+		case "backup_keep_days":
+			var n int
+			if _, err := fmt.Sscanf(item.Value, "%d", &n); err == nil && n >= 0 {
+				s.options.BackupKeepDays = n
+			}
+		case "auto_archive_days":
+			var n int
+			if _, err := fmt.Sscanf(item.Value, "%d", &n); err == nil && n >= 0 {
+				s.options.AutoArchiveDays = n
 			}
+		case "backup_compress":
+			s.options.BackupCompress = item.Value == "true"
+		case "backup_encrypt":
+			s.options.BackupEncrypt = item.Value == "true"
 		}
 	}
 	if s.options.SyncMode == "" {
@@ -216,32 +299,56 @@ func (s *Service) encryptText(plain string) (string, bool, error) {
 	if !s.options.EncryptAtRest {
 		return plain, false, nil
 	}
-	block, err := aes.NewCipher(s.key)
+	s.keyMu.RLock()
+	key := s.key
+	s.keyMu.RUnlock()
+	if key == nil {
+		return "", false, ErrChatLocked
+	}
+	enc, err := encryptWithKey(key, plain)
 	if err != nil {
 		return "", false, err
 	}
+	return enc, true, nil
+}
+
+func (s *Service) decryptText(content string, encrypted bool) (string, error) {
+	if !encrypted {
+		return content, nil
+	}
+	s.keyMu.RLock()
+	key := s.key
+	s.keyMu.RUnlock()
+	if key == nil {
+		return "", ErrChatLocked
+	}
+	return decryptWithKey(key, content)
+}
+
+func encryptWithKey(key []byte, plain string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", false, err
+		return "", err
 	}
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", false, err
+		return "", err
 	}
 	ciphertext := gcm.Seal(nil, nonce, []byte(plain), nil)
 	payload := append(nonce, ciphertext...)
-	return base64.StdEncoding.EncodeToString(payload), true, nil
+	return base64.StdEncoding.EncodeToString(payload), nil
 }
 
-func (s *Service) decryptText(content string, encrypted bool) (string, error) {
-	if !encrypted {
-		return content, nil
-	}
+func decryptWithKey(key []byte, content string) (string, error) {
 	payload, err := base64.StdEncoding.DecodeString(content)
 	if err != nil {
 		return "", err
 	}
-	block, err := aes.NewCipher(s.key)
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
@@ -276,6 +383,10 @@ func (s *Service) SetStorageOptions(opts StorageOptions) error {
 	if opts.BackupIntervalMins <= 0 {
 		opts.BackupIntervalMins = 30
 	}
+	opts.PreferredExportType = normalizeExportFormat(opts.PreferredExportType)
+	opts.BackupKeepLast = max(opts.BackupKeepLast, 0)
+	opts.BackupKeepDays = max(opts.BackupKeepDays, 0)
+	opts.AutoArchiveDays = max(opts.AutoArchiveDays, 0)
 	s.options = opts
 	if err := s.persistOption("encrypt_at_rest", fmt.Sprintf("%t", opts.EncryptAtRest)); err != nil {
 		return err
@@ -295,7 +406,28 @@ func (s *Service) SetStorageOptions(opts StorageOptions) error {
 	if err := s.persistOption("preferred_export_type", opts.PreferredExportType); err != nil {
 		return err
 	}
-	s.startBackupTicker()
+	if err := s.persistOption("auto_title", fmt.Sprintf("%t", opts.AutoTitle)); err != nil {
+		return err
+	}
+	if err := s.persistOption("backup_keep_last", fmt.Sprintf("%d", opts.BackupKeepLast)); err != nil {
+		return err
+	}
+	if err := s.persistOption("backup_keep_days", fmt.Sprintf("%d", opts.BackupKeepDays)); err != nil {
+		return err
+	}
+	if err := s.persistOption("backup_compress", fmt.Sprintf("%t", opts.BackupCompress)); err != nil {
+		return err
+	}
+	if err := s.persistOption("backup_encrypt", fmt.Sprintf("%t", opts.BackupEncrypt)); err != nil {
+		return err
+	}
+	if err := s.persistOption("auto_archive_days", fmt.Sprintf("%d", opts.AutoArchiveDays)); err != nil {
+		return err
+	}
+	if opts.AutoArchiveDays > 0 {
+		_, _ = s.ArchiveInactiveSessions(opts.AutoArchiveDays, time.Now())
+	}
+	s.startMaintenanceTicker()
 	return nil
 }
 
@@ -311,6 +443,7 @@ func (s *Service) CreateSession(title, category string, tags []string) (*Session
 		CreatedAtUnix: now,
 		UpdatedAtUnix: now,
 		LastMessageAt: now,
+		SummaryReady:  true,
 	}
 	if err := s.db.Create(&session).Error; err != nil {
 		return nil, err
@@ -342,23 +475,11 @@ func (s *Service) GetSession(sessionID string) (*SessionListItem, error) {
 	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
 		return nil, err
 	}
-	tags, _ := s.getTags(session.ID)
-	var count int64
-	_ = s.db.Model(&Message{}).Where("session_id = ?", session.ID).Count(&count).Error
-	preview, _ := s.getSessionPreview(session.ID)
-	return &SessionListItem{
-		ID:            session.ID,
-		Title:         session.Title,
-		Category:      session.Category,
-		Archived:      session.Archived,
-		Favorite:      session.Favorite,
-		Tags:          tags,
-		CreatedAt:     session.CreatedAtUnix,
-		UpdatedAt:     session.UpdatedAtUnix,
-		LastMessageAt: session.LastMessageAt,
-		MessageCount:  count,
-		Preview:       preview,
-	}, nil
+	items, err := s.listItems([]Session{session})
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
 }
 
 func (s *Service) ListSessions(filter SessionFilter) (*SessionListResult, error) {
@@ -387,6 +508,13 @@ func (s *Service) ListSessions(filter SessionFilter) (*SessionListResult, error)
 	if filter.Tag != "" {
 		q = q.Joins("JOIN chat_session_tags ON chat_session_tags.session_id = chat_sessions.id").Where("chat_session_tags.tag = ?", filter.Tag)
 	}
+	if strings.TrimSpace(filter.Keyword) != "" {
+		ids, err := s.sessionsMatchingKeyword(filter.Keyword)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where("chat_sessions.id IN ?", ids)
+	}
 
 	countQuery := q
 	var total int64
@@ -402,60 +530,13 @@ func (s *Service) ListSessions(filter SessionFilter) (*SessionListResult, error)
 		return nil, err
 	}
 
-	items := make([]SessionListItem, 0, len(sessions))
-	for _, session := range sessions {
-		tags, _ := s.getTags(session.ID)
-		var count int64
-		_ = s.db.Model(&Message{}).Where("session_id = ?", session.ID).Count(&count).Error
-		preview, _ := s.getSessionPreview(session.ID)
-		item := SessionListItem{
-			ID:            session.ID,
-			Title:         session.Title,
-			Category:      session.Category,
-			Archived:      session.Archived,
-			Favorite:      session.Favorite,
-			Tags:          tags,
-			CreatedAt:     session.CreatedAtUnix,
-			UpdatedAt:     session.UpdatedAtUnix,
-			LastMessageAt: session.LastMessageAt,
-			MessageCount:  count,
-			Preview:       preview,
-		}
-		items = append(items, item)
-	}
-
-	if strings.TrimSpace(filter.Keyword) != "" {
-		kw := strings.ToLower(strings.TrimSpace(filter.Keyword))
-		filtered := make([]SessionListItem, 0, len(items))
-		for _, item := range items {
-			if strings.Contains(strings.ToLower(item.Title), kw) || strings.Contains(strings.ToLower(item.Preview), kw) || s.sessionContainsKeyword(item.ID, kw) {
-				filtered = append(filtered, item)
-			}
-		}
-		total = int64(len(filtered))
-		items = filtered
+	items, err := s.listItems(sessions)
+	if err != nil {
+		return nil, err
 	}
-
 	return &SessionListResult{Items: items, Total: total, Page: filter.Page, Size: filter.PageSize}, nil
 }
 
-func (s *Service) sessionContainsKeyword(sessionID, keyword string) bool {
-	var messages []Message
-	if err := s.db.Where("session_id = ?", sessionID).Order("timestamp DESC").Limit(30).Find(&messages).Error; err != nil {
-		return false
-	}
-	for _, msg := range messages {
-		text, err := s.decryptText(msg.Content, msg.Encrypted)
-		if err != nil {
-			continue
-		}
-		if strings.Contains(strings.ToLower(text), keyword) {
-			return true
-		}
-	}
-	return false
-}
-
 func (s *Service) ListMessages(sessionID string, page, pageSize int) (*MessageListResult, error) {
 	if page <= 0 {
 		page = 1
@@ -474,32 +555,149 @@ func (s *Service) ListMessages(sessionID string, page, pageSize int) (*MessageLi
 	}
 	items := make([]MessageDTO, 0, len(rows))
 	for _, row := range rows {
-		text, err := s.decryptText(row.Content, row.Encrypted)
-		if err != nil {
-			continue
+		items = append(items, s.messageDTO(row))
+	}
+	return &MessageListResult{Items: items, Total: total, Page: page, Size: pageSize}, nil
+}
+
+// messageDTO decrypts a stored message. Messages that cannot be decrypted are
+// returned without content and flagged Undecryptable.
+func (s *Service) messageDTO(row Message) MessageDTO {
+	text, err := s.decryptText(row.Content, row.Encrypted)
+	if err != nil {
+		// Keep the row visible so the UI can tell history is locked or was
+		// encrypted with another key, instead of silently dropping it
+		return MessageDTO{
+			ID:            row.ID,
+			SessionID:     row.SessionID,
+			Role:          row.Role,
+			Status:        row.Status,
+			Timestamp:     row.Timestamp,
+			Pinned:        row.Pinned,
+			Undecryptable: true,
 		}
-		var sources []map[string]any
-		if row.Sources != "" {
-			srcText, decErr := s.decryptText(row.Sources, row.SourcesEncrypted)
-			if decErr == nil {
-				_ = json.Unmarshal([]byte(srcText), &sources)
-			}
+	}
+	var sources []map[string]any
+	if row.Sources != "" {
+		srcText, decErr := s.decryptText(row.Sources, row.SourcesEncrypted)
+		if decErr == nil {
+			_ = json.Unmarshal([]byte(srcText), &sources)
 		}
-		items = append(items, MessageDTO{
-			ID:         row.ID,
-			SessionID:  row.SessionID,
-			Role:       row.Role,
-			Content:    text,
-			Sources:    sources,
-			TokensUsed: row.TokensUsed,
-			Status:     row.Status,
-			Timestamp:  row.Timestamp,
-		})
 	}
-	return &MessageListResult{Items: items, Total: total, Page: page, Size: pageSize}, nil
+	var attachments []Attachment
+	if row.Attachments != "" {
+		attText, decErr := s.decryptText(row.Attachments, row.AttachmentsEncrypted)
+		if decErr == nil {
+			_ = json.Unmarshal([]byte(attText), &attachments)
+		}
+	}
+	return MessageDTO{
+		ID:          row.ID,
+		SessionID:   row.SessionID,
+		Role:        row.Role,
+		Content:     text,
+		Sources:     sources,
+		Attachments: attachments,
+		TokensUsed:  row.TokensUsed,
+		CostUSD:     row.CostUSD,
+		Pinned:      row.Pinned,
+		Status:      row.Status,
+		Timestamp:   row.Timestamp,
+	}
+}
+
+// GetMessage returns a single message
+func (s *Service) GetMessage(messageID string) (*MessageDTO, error) {
+	var row Message
+	if err := s.db.First(&row, "id = ?", messageID).Error; err != nil {
+		return nil, err
+	}
+	item := s.messageDTO(row)
+	return &item, nil
+}
+
+// LastUserMessage returns the most recent user message of a session
+func (s *Service) LastUserMessage(sessionID string) (*MessageDTO, error) {
+	var row Message
+	if err := s.db.Where("session_id = ? AND role = ?", sessionID, "user").
+		Order("timestamp DESC, created_at DESC").
+		First(&row).Error; err != nil {
+		return nil, err
+	}
+	item := s.messageDTO(row)
+	return &item, nil
+}
+
+// UpdateMessage replaces the content of a message, keeping its position in the session
+func (s *Service) UpdateMessage(messageID, content string) (*MessageDTO, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("message content cannot be empty")
+	}
+	var row Message
+	if err := s.db.First(&row, "id = ?", messageID).Error; err != nil {
+		return nil, err
+	}
+	encContent, encrypted, err := s.encryptText(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&row).Updates(map[string]any{
+			"content":   encContent,
+			"encrypted": encrypted,
+		}).Error; err != nil {
+			return err
+		}
+		return s.touchSession(tx, row.SessionID)
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetMessage(messageID)
+}
+
+// DeleteMessage removes a single message
+func (s *Service) DeleteMessage(messageID string) error {
+	var row Message
+	if err := s.db.First(&row, "id = ?", messageID).Error; err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&row).Error; err != nil {
+			return err
+		}
+		return s.touchSession(tx, row.SessionID)
+	})
+}
+
+// TruncateAfter deletes every message of the session that came after
+// messageID, returning how many were removed
+func (s *Service) TruncateAfter(sessionID, messageID string) (int64, error) {
+	var row Message
+	if err := s.db.First(&row, "id = ? AND session_id = ?", messageID, sessionID).Error; err != nil {
+		return 0, err
+	}
+	var removed int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Timestamps are in ms, so messages appended in the same ms are ordered by created_at
+		res := tx.Where("session_id = ? AND id <> ?", sessionID, messageID).
+			Where("timestamp > ? OR (timestamp = ? AND created_at > ?)", row.Timestamp, row.Timestamp, row.CreatedAt).
+			Delete(&Message{})
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+		return s.touchSession(tx, sessionID)
+	})
+	return removed, err
 }
 
 func (s *Service) AppendMessage(sessionID, role, content string, sources any, tokensUsed *int, status string) (*MessageDTO, error) {
+	return s.AppendMessageWithAttachments(sessionID, role, content, sources, tokensUsed, status, nil)
+}
+
+// AppendMessageWithAttachments stores a message together with references to attachments
+// previously saved with SaveNoteSnapshot or SaveImageAttachment.
+func (s *Service) AppendMessageWithAttachments(sessionID, role, content string, sources any, tokensUsed *int, status string, attachments []Attachment) (*MessageDTO, error) {
 	if strings.TrimSpace(sessionID) == "" {
 		return nil, fmt.Errorf("session id is required")
 	}
@@ -526,27 +724,50 @@ func (s *Service) AppendMessage(sessionID, role, content string, sources any, to
 			message.SourcesEncrypted = srcEncrypted
 		}
 	}
-	if err := s.db.Create(&message).Error; err != nil {
-		return nil, err
+	if len(attachments) > 0 {
+		payload, _ := json.Marshal(attachments)
+		encAtt, attEncrypted, attErr := s.encryptText(string(payload))
+		if attErr == nil {
+			message.Attachments = encAtt
+			message.AttachmentsEncrypted = attEncrypted
+		}
 	}
-	_ = s.db.Model(&Session{}).Where("id = ?", sessionID).Updates(map[string]any{
+	sessionUpdates := map[string]any{
 		"last_message_at": now,
 		"updated_at_unix": now,
-	}).Error
+		"message_count":   gorm.Expr("message_count + 1"),
+	}
+	if tokensUsed != nil {
+		sessionUpdates["total_tokens"] = gorm.Expr("total_tokens + ?", *tokensUsed)
+	}
+	if preview, err := s.previewUpdates(content); err == nil {
+		for k, v := range preview {
+			sessionUpdates[k] = v
+		}
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Session{}).Where("id = ?", sessionID).Updates(sessionUpdates).Error
+	}); err != nil {
+		return nil, err
+	}
 	var srcList []map[string]any
 	if sources != nil {
 		payload, _ := json.Marshal(sources)
 		_ = json.Unmarshal(payload, &srcList)
 	}
 	return &MessageDTO{
-		ID:         message.ID,
-		SessionID:  message.SessionID,
-		Role:       role,
-		Content:    content,
-		Sources:    srcList,
-		TokensUsed: tokensUsed,
-		Status:     status,
-		Timestamp:  now,
+		ID:          message.ID,
+		SessionID:   message.SessionID,
+		Role:        role,
+		Content:     content,
+		Sources:     srcList,
+		Attachments: attachments,
+		TokensUsed:  tokensUsed,
+		Status:      status,
+		Timestamp:   now,
 	}, nil
 }
 
@@ -557,19 +778,28 @@ func (s *Service) RenameSession(sessionID, title string) error {
 	}).Error
 }
 
+// NeedsAutoTitle reports whether a session still has a placeholder title and
+// has received exactly one assistant reply
+func (s *Service) NeedsAutoTitle(sessionID string) (bool, error) {
+	var session Session
+	if err := s.db.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return false, err
+	}
+	switch strings.TrimSpace(session.Title) {
+	case "", NewSessionTitle, DefaultSessionTitle:
+	default:
+		return false, nil
+	}
+	var replies int64
+	if err := s.db.Model(&Message{}).Where("session_id = ? AND role = ? AND status = ?", sessionID, "assistant", "done").Count(&replies).Error; err != nil {
+		return false, err
+	}
+	return replies == 1, nil
+}
+
 func (s *Service) DeleteSession(sessionID string) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("session_id = ?", sessionID).Delete(&SessionTag{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("session_id = ?", sessionID).Delete(&Message{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("id = ?", sessionID).Delete(&Session{}).Error; err != nil {
-			return err
-		}
-		return nil
-	})
+	_, err := s.BulkDelete([]string{sessionID})
+	return err
 }
 
 func (s *Service) SetArchive(sessionID string, archived bool) error {
@@ -593,25 +823,51 @@ func (s *Service) SetCategory(sessionID, category string) error {
 	}).Error
 }
 
+// GetSessionOverrides returns the model, temperature and system prompt set on a session
+func (s *Service) GetSessionOverrides(sessionID string) (SessionOverrides, error) {
+	var session Session
+	if err := s.db.Select("model", "temperature", "system_prompt").First(&session, "id = ?", sessionID).Error; err != nil {
+		return SessionOverrides{}, err
+	}
+	return SessionOverrides{Model: session.Model, Temperature: session.Temperature, SystemPrompt: session.SystemPrompt}, nil
+}
+
+// SetSessionModel sets the LLM model for a session; empty uses the configured model
+func (s *Service) SetSessionModel(sessionID, model string) error {
+	return s.updateSession(sessionID, map[string]any{"model": strings.TrimSpace(model)})
+}
+
+// SetSessionTemperature sets the sampling temperature for a session; nil uses the RAG temperature
+func (s *Service) SetSessionTemperature(sessionID string, temperature *float32) error {
+	if temperature != nil && (*temperature < 0 || *temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	return s.updateSession(sessionID, map[string]any{"temperature": temperature})
+}
+
+// SetSessionSystemPrompt sets the persona for a session; empty uses the RAG system prompt
+func (s *Service) SetSessionSystemPrompt(sessionID, prompt string) error {
+	return s.updateSession(sessionID, map[string]any{"system_prompt": strings.TrimSpace(prompt)})
+}
+
+func (s *Service) updateSession(sessionID string, updates map[string]any) error {
+	updates["updated_at_unix"] = time.Now().UnixMilli()
+	result := s.db.Model(&Session{}).Where("id = ?", sessionID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (s *Service) ReplaceTags(sessionID string, tags []string) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("session_id = ?", sessionID).Delete(&SessionTag{}).Error; err != nil {
 			return err
 		}
-		uniq := make(map[string]struct{})
-		for _, tag := range tags {
-			tag = strings.TrimSpace(strings.ToLower(tag))
-			if tag == "" {
-				continue
-			}
-			uniq[tag] = struct{}{}
-		}
-		tagList := make([]string, 0, len(uniq))
-		for tag := range uniq {
-			tagList = append(tagList, tag)
-		}
-		sort.Strings(tagList)
-		for _, tag := range tagList {
+		for _, tag := range normalizeTags(tags) {
 			if err := tx.Create(&SessionTag{SessionID: sessionID, Tag: tag}).Error; err != nil {
 				return err
 			}
@@ -620,6 +876,24 @@ func (s *Service) ReplaceTags(sessionID string, tags []string) error {
 	})
 }
 
+// normalizeTags lowercases, trims, dedupes and sorts tags
+func normalizeTags(tags []string) []string {
+	uniq := make(map[string]struct{})
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.ToLower(tag))
+		if tag == "" {
+			continue
+		}
+		uniq[tag] = struct{}{}
+	}
+	tagList := make([]string, 0, len(uniq))
+	for tag := range uniq {
+		tagList = append(tagList, tag)
+	}
+	sort.Strings(tagList)
+	return tagList
+}
+
 func (s *Service) getTags(sessionID string) ([]string, error) {
 	var rows []SessionTag
 	if err := s.db.Where("session_id = ?", sessionID).Order("tag ASC").Find(&rows).Error; err != nil {
@@ -632,27 +906,10 @@ func (s *Service) getTags(sessionID string) ([]string, error) {
 	return tags, nil
 }
 
-func (s *Service) getSessionPreview(sessionID string) (string, error) {
-	var row Message
-	if err := s.db.Where("session_id = ?", sessionID).Order("timestamp DESC").First(&row).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return "", nil
-		}
-		return "", err
-	}
-	text, err := s.decryptText(row.Content, row.Encrypted)
-	if err != nil {
-		return "", err
-	}
-	text = strings.TrimSpace(text)
-	r := []rune(text)
-	if len(r) > 120 {
-		return string(r[:120]) + "...", nil
-	}
-	return text, nil
-}
-
+// ExportSession writes a session to the export directory as json, txt,
+// markdown or html and returns the file path
 func (s *Service) ExportSession(sessionID, format string) (string, error) {
+	format = normalizeExportFormat(format)
 	session, err := s.GetSession(sessionID)
 	if err != nil {
 		return "", err
@@ -670,8 +927,11 @@ func (s *Service) ExportSession(sessionID, format string) (string, error) {
 	if base == "" {
 		base = sessionID
 	}
-	if format == "txt" {
-		path := filepath.Join(exportDir, fmt.Sprintf("%s_%s.txt", base, ts))
+	path := filepath.Join(exportDir, fmt.Sprintf("%s_%s.%s", base, ts, exportExtension(format)))
+
+	var content []byte
+	switch format {
+	case ExportFormatText:
 		var sb strings.Builder
 		sb.WriteString("Notebit Chat Export\n")
 		sb.WriteString(fmt.Sprintf("Session: %s\n", session.Title))
@@ -679,27 +939,36 @@ func (s *Service) ExportSession(sessionID, format string) (string, error) {
 		for _, m := range messages.Items {
 			sb.WriteString(fmt.Sprintf("[%s] %s\n", strings.ToUpper(m.Role), time.UnixMilli(m.Timestamp).Format(time.RFC3339)))
 			sb.WriteString(m.Content)
-			sb.WriteString("\n\n")
+			sb.WriteString("\n")
+			for _, att := range m.Attachments {
+				sb.WriteString("Attachment: " + attachmentLabel(att) + "\n")
+			}
+			sb.WriteString("\n")
 		}
-		if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
-			return "", err
+		content = []byte(sb.String())
+	case ExportFormatMarkdown:
+		content = []byte(renderMarkdownExport(session, messages.Items))
+	case ExportFormatHTML:
+		content = []byte(renderHTMLExport(session, messages.Items))
+	default:
+		payload := map[string]any{
+			"session":  session,
+			"messages": messages.Items,
+			"exported": time.Now().UnixMilli(),
 		}
-		return path, nil
+		content, _ = json.MarshalIndent(payload, "", "  ")
 	}
-
-	path := filepath.Join(exportDir, fmt.Sprintf("%s_%s.json", base, ts))
-	payload := map[string]any{
-		"session":  session,
-		"messages": messages.Items,
-		"exported": time.Now().UnixMilli(),
-	}
-	b, _ := json.MarshalIndent(payload, "", "  ")
-	if err := os.WriteFile(path, b, 0644); err != nil {
+	if err := os.WriteFile(path, content, 0644); err != nil {
 		return "", err
 	}
 	return path, nil
 }
 
+// ExportSessionDefault exports a session in the preferred export format
+func (s *Service) ExportSessionDefault(sessionID string) (string, error) {
+	return s.ExportSession(sessionID, s.GetStorageOptions().PreferredExportType)
+}
+
 func sanitizeFilename(name string) string {
 	name = strings.TrimSpace(name)
 	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", "\"", "_", "<", "_", ">", "_", "|", "_")
@@ -707,6 +976,10 @@ func sanitizeFilename(name string) string {
 }
 
 func (s *Service) BackupNow(ctx context.Context) (string, error) {
+	if s.GetKeyStatus().Locked {
+		// Locked messages would be written without content
+		return "", ErrChatLocked
+	}
 	result, err := s.ListSessions(SessionFilter{Page: 1, PageSize: 500})
 	if err != nil {
 		return "", err
@@ -731,11 +1004,6 @@ func (s *Service) BackupNow(ctx context.Context) (string, error) {
 		dump = append(dump, sessionDump{Session: item, Messages: messages.Items})
 	}
 
-	backupDir := filepath.Join(s.basePath, "data", "chat_backups")
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return "", err
-	}
-	filePath := filepath.Join(backupDir, fmt.Sprintf("chat_backup_%s.json", time.Now().Format("20060102_150405")))
 	s.mu.RLock()
 	syncMode := s.options.SyncMode
 	s.mu.RUnlock()
@@ -744,20 +1012,21 @@ func (s *Service) BackupNow(ctx context.Context) (string, error) {
 		"sync_mode":  syncMode,
 		"sessions":   dump,
 	}, "", "  ")
-	if err := os.WriteFile(filePath, b, 0644); err != nil {
-		return "", err
-	}
-	return filePath, nil
+	return s.writeBackup(b, time.Now())
 }
 
-func (s *Service) startBackupTicker() {
+// startMaintenanceTicker runs auto-backup and auto-archive on the backup
+// interval, restarting the loop so option changes take effect
+func (s *Service) startMaintenanceTicker() {
 	if s.stopCh != nil {
 		close(s.stopCh)
 		<-s.doneCh
 		s.stopCh = nil
 		s.doneCh = nil
 	}
-	if !s.options.AutoBackupEnabled {
+	backup := s.options.AutoBackupEnabled
+	archiveDays := s.options.AutoArchiveDays
+	if !backup && archiveDays <= 0 {
 		return
 	}
 	interval := time.Duration(s.options.BackupIntervalMins) * time.Minute
@@ -773,7 +1042,12 @@ func (s *Service) startBackupTicker() {
 		for {
 			select {
 			case <-ticker.C:
-				_, _ = s.BackupNow(context.Background())
+				if archiveDays > 0 {
+					_, _ = s.ArchiveInactiveSessions(archiveDays, time.Now())
+				}
+				if backup {
+					_, _ = s.BackupNow(context.Background())
+				}
 			case <-stop:
 				return
 			}
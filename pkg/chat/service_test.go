@@ -3,6 +3,7 @@ package chat
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -187,3 +188,865 @@ func TestStorageOptionsReload(t *testing.T) {
 		t.Fatalf("backup timing assertion failed")
 	}
 }
+
+func TestRenderShareableRedactsPIIAndSources(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, err := svc.CreateSession("share", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = svc.AppendMessage(session.ID, "user", "mail me at alice@example.com", nil, nil, "sent")
+	sources := []map[string]any{{"path": "private/salary.md", "title": "Salary", "content": "secret numbers"}}
+	_, _ = svc.AppendMessage(session.ID, "assistant", "see notes", sources, nil, "done")
+
+	out, err := svc.RenderShareable(session.ID, DefaultShareOptions())
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if strings.Contains(out, "alice@example.com") {
+		t.Fatalf("expected email to be redacted: %s", out)
+	}
+	if strings.Contains(out, "secret numbers") || strings.Contains(out, "private/salary.md") {
+		t.Fatalf("expected source content to be redacted: %s", out)
+	}
+	if !strings.Contains(out, "Salary") {
+		t.Fatalf("expected source title to be kept: %s", out)
+	}
+}
+
+func TestMessageAttachmentsPersistAndRender(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, err := svc.CreateSession("Attachments", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note, err := svc.SaveNoteSnapshot("ideas/plan.md", "# Plan\n\nship the feature")
+	if err != nil {
+		t.Fatalf("SaveNoteSnapshot failed: %v", err)
+	}
+	image, err := svc.SaveImageAttachment("diagram.png", []byte{0x89, 'P', 'N', 'G'})
+	if err != nil {
+		t.Fatalf("SaveImageAttachment failed: %v", err)
+	}
+	if _, err := svc.AppendMessageWithAttachments(session.ID, "user", "see attached", nil, nil, "sent", []Attachment{*note, *image}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := svc.ListMessages(session.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages.Items) != 1 || len(messages.Items[0].Attachments) != 2 {
+		t.Fatalf("expected message with 2 attachments, got %+v", messages.Items)
+	}
+
+	prompt := svc.AttachmentPromptContext(messages.Items[0].Attachments)
+	if !strings.Contains(prompt, "ship the feature") || !strings.Contains(prompt, "diagram.png") {
+		t.Fatalf("prompt context missing attachment content: %q", prompt)
+	}
+	if _, err := svc.ReadAttachment("../chat.sqlite"); err == nil {
+		t.Fatal("expected path outside attachments folder to be rejected")
+	}
+
+	rendered, err := svc.RenderShareable(session.ID, ShareOptions{Format: "markdown", SourceRedaction: SourceRedactionNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered, "Attached note: plan.md") || !strings.Contains(rendered, "![diagram.png]("+image.Path+")") {
+		t.Fatalf("attachments not rendered in export: %s", rendered)
+	}
+}
+
+func TestNeedsAutoTitle(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	if !svc.GetStorageOptions().AutoTitle {
+		t.Fatal("auto title should be enabled by default")
+	}
+
+	session, err := svc.CreateSession("", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	needs := func() bool {
+		t.Helper()
+		ok, err := svc.NeedsAutoTitle(session.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if _, err := svc.AppendMessage(session.ID, "user", "how do I bake bread?", nil, nil, "sent"); err != nil {
+		t.Fatal(err)
+	}
+	if needs() {
+		t.Fatal("session without a reply should not be titled yet")
+	}
+	if _, err := svc.AppendMessage(session.ID, "assistant", "knead and proof", nil, nil, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if !needs() {
+		t.Fatal("placeholder session with its first reply should be titled")
+	}
+	if _, err := svc.AppendMessage(session.ID, "assistant", "then bake", nil, nil, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if needs() {
+		t.Fatal("only the first reply should trigger a title")
+	}
+
+	renamed, err := svc.CreateSession("Bread", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AppendMessage(renamed.ID, "assistant", "hi", nil, nil, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := svc.NeedsAutoTitle(renamed.ID); ok {
+		t.Fatal("user-chosen titles must not be replaced")
+	}
+}
+
+func TestEditDeleteAndTruncateMessages(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, err := svc.CreateSession("", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(role, content string) *MessageDTO {
+		t.Helper()
+		msg, err := svc.AppendMessage(session.ID, role, content, nil, nil, "done")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	first := add("user", "what is teh plan?")
+	add("assistant", "answer one")
+	second := add("user", "and the budget?")
+	reply := add("assistant", "answer two")
+
+	edited, err := svc.UpdateMessage(first.ID, "what is the plan?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Content != "what is the plan?" || edited.Timestamp != first.Timestamp {
+		t.Fatalf("unexpected edited message: %+v", edited)
+	}
+	if _, err := svc.UpdateMessage(first.ID, "  "); err == nil {
+		t.Fatal("expected empty content to be rejected")
+	}
+
+	last, err := svc.LastUserMessage(session.ID)
+	if err != nil || last.ID != second.ID {
+		t.Fatalf("expected last user message %s, got %+v, %v", second.ID, last, err)
+	}
+
+	if err := svc.DeleteMessage(reply.ID); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := svc.TruncateAfter(session.ID, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 messages removed, got %d", removed)
+	}
+	msgs, err := svc.ListMessages(session.ID, 1, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs.Items) != 1 || msgs.Items[0].ID != first.ID || msgs.Items[0].Content != "what is the plan?" {
+		t.Fatalf("unexpected messages after truncate: %+v", msgs.Items)
+	}
+}
+
+func TestPassphraseKeyRotationAndLocking(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, err := svc.CreateSession("", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AppendMessage(session.ID, "user", "secret question", []map[string]any{{"path": "a.md"}}, nil, "sent"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.RotateKey("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reencrypted != 1 || result.Undecryptable != 0 {
+		t.Fatalf("unexpected rotation result: %+v", result)
+	}
+	if status := svc.GetKeyStatus(); status.Mode != KeyModePassphrase || status.Locked {
+		t.Fatalf("unexpected key status after rotation: %+v", status)
+	}
+
+	// A fresh service over the same database starts locked
+	reopened, err := NewService(svc.db, svc.basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if !reopened.GetKeyStatus().Locked {
+		t.Fatal("expected passphrase key to start locked")
+	}
+	msgs, err := reopened.ListMessages(session.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs.Items) != 1 || !msgs.Items[0].Undecryptable || msgs.Items[0].Content != "" {
+		t.Fatalf("locked history should be listed as undecryptable: %+v", msgs.Items)
+	}
+	if _, err := reopened.AppendMessage(session.ID, "user", "more", nil, nil, "sent"); err != ErrChatLocked {
+		t.Fatalf("expected ErrChatLocked, got %v", err)
+	}
+
+	if err := reopened.Unlock("wrong"); err != ErrWrongPassphrase {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err = reopened.ListMessages(session.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs.Items[0].Content != "secret question" || len(msgs.Items[0].Sources) != 1 {
+		t.Fatalf("unexpected decrypted message: %+v", msgs.Items[0])
+	}
+
+	if _, err := reopened.RotateKey(""); err != nil {
+		t.Fatal(err)
+	}
+	if status := reopened.GetKeyStatus(); status.Mode != KeyModeDevice || status.Locked {
+		t.Fatalf("expected device key after clearing the passphrase: %+v", status)
+	}
+	if n, err := reopened.CountUndecryptableMessages(); err != nil || n != 0 {
+		t.Fatalf("expected no undecryptable messages, got %d, %v", n, err)
+	}
+}
+
+func TestBackupRetentionCompressionAndEncryption(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("backup", "", nil)
+	_, _ = svc.AppendMessage(session.ID, "user", "secret question", nil, nil, "sent")
+
+	opts := svc.GetStorageOptions()
+	opts.BackupKeepLast = 2
+	opts.BackupKeepDays = 0
+	opts.BackupCompress = true
+	opts.BackupEncrypt = true
+	if err := svc.SetStorageOptions(opts); err != nil {
+		t.Fatalf("set options failed: %v", err)
+	}
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 3; i++ {
+		p, err := svc.writeBackup([]byte(`{"sessions":["secret question"]}`), base.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("write backup failed: %v", err)
+		}
+		// Stamp distinct mtimes so ordering does not depend on filesystem resolution
+		mtime := time.Now().Add(time.Duration(i-3) * time.Second)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("chtimes failed: %v", err)
+		}
+		paths = append(paths, p)
+	}
+	if _, err := svc.pruneBackups(2, 0, time.Now()); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+
+	backups, err := svc.ListBackups()
+	if err != nil {
+		t.Fatalf("list backups failed: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups after pruning, got %d", len(backups))
+	}
+	if backups[0].Name != filepath.Base(paths[2]) || !backups[0].Compressed || !backups[0].Encrypted {
+		t.Fatalf("unexpected newest backup: %+v", backups[0])
+	}
+
+	raw, _ := os.ReadFile(paths[2])
+	if strings.Contains(string(raw), "secret question") {
+		t.Fatalf("encrypted backup contains plaintext")
+	}
+	data, err := svc.ReadBackup(backups[0].Name)
+	if err != nil {
+		t.Fatalf("read backup failed: %v", err)
+	}
+	if !strings.Contains(string(data), "secret question") {
+		t.Fatalf("unexpected backup payload: %s", data)
+	}
+
+	// Age-based retention
+	old := time.Now().AddDate(0, 0, -10)
+	_ = os.Chtimes(filepath.Join(svc.backupDir(), backups[1].Name), old, old)
+	removed, err := svc.pruneBackups(0, 7, time.Now())
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 old backup removed, got %d (%v)", removed, err)
+	}
+
+	if err := svc.DeleteBackup("../chat.db"); err == nil {
+		t.Fatalf("expected invalid name to be rejected")
+	}
+	if err := svc.DeleteBackup(backups[0].Name); err != nil {
+		t.Fatalf("delete backup failed: %v", err)
+	}
+	if backups, _ = svc.ListBackups(); len(backups) != 0 {
+		t.Fatalf("expected no backups left, got %d", len(backups))
+	}
+}
+
+func TestExportMarkdownAndHTML(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("Export <formats>", "", nil)
+	_, _ = svc.AppendMessage(session.ID, "user", "show code", nil, nil, "sent")
+	answer := "Here:\n\n```go\nfmt.Println(\"<hi>\")\n```\n"
+	sources := []map[string]any{{"title": "Go notes", "path": "go.md", "content": "Println prints"}}
+	_, _ = svc.AppendMessage(session.ID, "assistant", answer, sources, nil, "done")
+
+	mdPath, err := svc.ExportSession(session.ID, "md")
+	if err != nil {
+		t.Fatalf("export markdown failed: %v", err)
+	}
+	md, _ := os.ReadFile(mdPath)
+	if filepath.Ext(mdPath) != ".md" || !strings.Contains(string(md), "```go\nfmt.Println(\"<hi>\")\n```") {
+		t.Fatalf("code fence not preserved in markdown export:\n%s", md)
+	}
+	if !strings.Contains(string(md), "<summary>Sources (1)</summary>") || !strings.Contains(string(md), "**Go notes** (`go.md`): Println prints") {
+		t.Fatalf("sources not collapsed in markdown export:\n%s", md)
+	}
+
+	htmlPath, err := svc.ExportSession(session.ID, "html")
+	if err != nil {
+		t.Fatalf("export html failed: %v", err)
+	}
+	page, _ := os.ReadFile(htmlPath)
+	if !strings.Contains(string(page), "<title>Export &lt;formats&gt;</title>") {
+		t.Fatalf("title not escaped in html export:\n%s", page)
+	}
+	if !strings.Contains(string(page), "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>") {
+		t.Fatalf("code block not rendered in html export:\n%s", page)
+	}
+
+	opts := svc.GetStorageOptions()
+	opts.PreferredExportType = "HTML"
+	if err := svc.SetStorageOptions(opts); err != nil {
+		t.Fatalf("set options failed: %v", err)
+	}
+	defaultPath, err := svc.ExportSessionDefault(session.ID)
+	if err != nil {
+		t.Fatalf("default export failed: %v", err)
+	}
+	if filepath.Ext(defaultPath) != ".html" {
+		t.Fatalf("expected preferred html export, got %s", defaultPath)
+	}
+}
+
+func TestSessionOverrides(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("cite only", "", nil)
+	temp := float32(0)
+	if err := svc.SetSessionModel(session.ID, " gpt-strict "); err != nil {
+		t.Fatalf("set model failed: %v", err)
+	}
+	if err := svc.SetSessionTemperature(session.ID, &temp); err != nil {
+		t.Fatalf("set temperature failed: %v", err)
+	}
+	if err := svc.SetSessionSystemPrompt(session.ID, "Only answer with citations."); err != nil {
+		t.Fatalf("set system prompt failed: %v", err)
+	}
+
+	overrides, err := svc.GetSessionOverrides(session.ID)
+	if err != nil {
+		t.Fatalf("get overrides failed: %v", err)
+	}
+	if overrides.Model != "gpt-strict" || overrides.Temperature == nil || *overrides.Temperature != 0 || overrides.SystemPrompt != "Only answer with citations." {
+		t.Fatalf("unexpected overrides: %+v", overrides)
+	}
+	item, _ := svc.GetSession(session.ID)
+	if item.Model != "gpt-strict" {
+		t.Fatalf("expected overrides on session item, got %+v", item.SessionOverrides)
+	}
+
+	bad := float32(3)
+	if err := svc.SetSessionTemperature(session.ID, &bad); err == nil {
+		t.Fatalf("expected out-of-range temperature to be rejected")
+	}
+	if err := svc.SetSessionTemperature(session.ID, nil); err != nil {
+		t.Fatalf("clear temperature failed: %v", err)
+	}
+	if overrides, _ = svc.GetSessionOverrides(session.ID); overrides.Temperature != nil {
+		t.Fatalf("expected temperature cleared, got %v", *overrides.Temperature)
+	}
+	if err := svc.SetSessionModel("missing", "x"); err == nil {
+		t.Fatalf("expected error for unknown session")
+	}
+}
+
+func TestUsageBySessionAndDay(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	cheap, _ := svc.CreateSession("cheap", "", nil)
+	pricey, _ := svc.CreateSession("pricey", "", nil)
+	tokens := func(n int) *int { return &n }
+
+	_, _ = svc.AppendMessage(cheap.ID, "user", "q", nil, nil, "sent")
+	_, _ = svc.AppendMessage(cheap.ID, "assistant", "a", nil, tokens(100), "done")
+	for i := 0; i < 2; i++ {
+		msg, _ := svc.AppendMessage(pricey.ID, "assistant", "long answer", nil, tokens(1000), "done")
+		if err := svc.SetMessageCost(msg.ID, 0.01); err != nil {
+			t.Fatalf("set cost failed: %v", err)
+		}
+	}
+
+	bySession, err := svc.GetUsageBySession()
+	if err != nil {
+		t.Fatalf("usage by session failed: %v", err)
+	}
+	if len(bySession) != 2 || bySession[0].SessionID != pricey.ID || bySession[0].TotalTokens != 2000 || bySession[0].Messages != 2 {
+		t.Fatalf("unexpected usage by session: %+v", bySession)
+	}
+	if bySession[0].Title != "pricey" || bySession[1].TotalTokens != 100 || bySession[1].CostUSD != 0 {
+		t.Fatalf("unexpected usage by session: %+v", bySession)
+	}
+
+	item, _ := svc.GetSession(pricey.ID)
+	if item.TotalTokens != 2000 || item.CostUSD < 0.0199 || item.CostUSD > 0.0201 {
+		t.Fatalf("unexpected session totals: tokens=%d cost=%f", item.TotalTokens, item.CostUSD)
+	}
+
+	byDay, err := svc.GetUsageByDay(0, 0)
+	if err != nil {
+		t.Fatalf("usage by day failed: %v", err)
+	}
+	if len(byDay) != 1 || byDay[0].Day != time.Now().Format("2006-01-02") || byDay[0].TotalTokens != 2100 || byDay[0].Messages != 3 {
+		t.Fatalf("unexpected usage by day: %+v", byDay)
+	}
+	if future, _ := svc.GetUsageByDay(time.Now().Add(time.Hour).UnixMilli(), 0); len(future) != 0 {
+		t.Fatalf("expected no usage after range start, got %+v", future)
+	}
+}
+
+func TestPinnedMessagesAndSessionNotes(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	research, _ := svc.CreateSession("research", "", nil)
+	other, _ := svc.CreateSession("other", "", nil)
+	first, _ := svc.AppendMessage(research.ID, "assistant", "key finding", nil, nil, "done")
+	_, _ = svc.AppendMessage(research.ID, "assistant", "filler", nil, nil, "done")
+	elsewhere, _ := svc.AppendMessage(other.ID, "assistant", "other finding", nil, nil, "done")
+
+	if err := svc.SetMessagePinned(first.ID, true); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if err := svc.SetMessagePinned(elsewhere.ID, true); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if err := svc.SetMessagePinned("missing", true); err == nil {
+		t.Fatalf("expected error pinning unknown message")
+	}
+
+	pinned, err := svc.ListPinnedMessages(research.ID)
+	if err != nil {
+		t.Fatalf("list pinned failed: %v", err)
+	}
+	if len(pinned) != 1 || pinned[0].Content != "key finding" || !pinned[0].Pinned {
+		t.Fatalf("unexpected pinned messages: %+v", pinned)
+	}
+	if all, _ := svc.ListPinnedMessages(""); len(all) != 2 {
+		t.Fatalf("expected 2 pinned messages across sessions, got %d", len(all))
+	}
+
+	if err := svc.SetSessionNotes(research.ID, "  check sources for chapter 2 \n"); err != nil {
+		t.Fatalf("set notes failed: %v", err)
+	}
+	notes, _ := svc.GetSessionNotes(research.ID)
+	item, _ := svc.GetSession(research.ID)
+	if notes != "check sources for chapter 2" || item.Notes != notes || item.PinnedCount != 1 {
+		t.Fatalf("unexpected session notes or pin count: %q %+v", notes, item)
+	}
+
+	if err := svc.SetMessagePinned(first.ID, false); err != nil {
+		t.Fatalf("unpin failed: %v", err)
+	}
+	if pinned, _ = svc.ListPinnedMessages(research.ID); len(pinned) != 0 {
+		t.Fatalf("expected no pinned messages, got %d", len(pinned))
+	}
+}
+
+func TestAttachedContextNotes(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("discuss", "", nil)
+	for _, path := range []string{"papers/a.md", "papers/b.md", "papers/a.md", "todo.md"} {
+		if err := svc.AttachNote(session.ID, path); err != nil {
+			t.Fatalf("attach %s failed: %v", path, err)
+		}
+	}
+	if err := svc.AttachNote("missing", "x.md"); err == nil {
+		t.Fatalf("expected error attaching to unknown session")
+	}
+	paths, _ := svc.ListAttachedNotes(session.ID)
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 attached notes, got %v", paths)
+	}
+
+	if err := svc.RenameAttachedNotePaths("papers", "reading"); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if err := svc.DetachNote(session.ID, "todo.md"); err != nil {
+		t.Fatalf("detach failed: %v", err)
+	}
+	paths, _ = svc.ListAttachedNotes(session.ID)
+	if strings.Join(paths, ",") != "reading/a.md,reading/b.md" {
+		t.Fatalf("unexpected attached notes after rename: %v", paths)
+	}
+
+	if err := svc.DetachNoteEverywhere("reading/a.md"); err != nil {
+		t.Fatalf("detach everywhere failed: %v", err)
+	}
+	if err := svc.DeleteSession(session.ID); err != nil {
+		t.Fatalf("delete session failed: %v", err)
+	}
+	var remaining int64
+	svc.db.Model(&ContextNote{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected attachments removed with session, got %d", remaining)
+	}
+}
+
+func TestStreamingMessageLifecycle(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("stream", "", nil)
+	msg, err := svc.BeginStreamingMessage(session.ID)
+	if err != nil {
+		t.Fatalf("begin streaming failed: %v", err)
+	}
+	if msg.Status != StatusStreaming {
+		t.Fatalf("expected streaming status, got %s", msg.Status)
+	}
+	if err := svc.UpdateStreamingMessage(msg.ID, "partial ans"); err != nil {
+		t.Fatalf("update streaming failed: %v", err)
+	}
+	got, _ := svc.GetMessage(msg.ID)
+	if got.Content != "partial ans" || got.Status != StatusStreaming {
+		t.Fatalf("unexpected streaming message: %+v", got)
+	}
+
+	tokens := 42
+	sources := []map[string]any{{"path": "a.md"}}
+	if err := svc.FinishStreamingMessage(msg.ID, "partial answer", sources, &tokens, StatusDone); err != nil {
+		t.Fatalf("finish streaming failed: %v", err)
+	}
+	got, _ = svc.GetMessage(msg.ID)
+	if got.Content != "partial answer" || got.Status != StatusDone || got.TokensUsed == nil || *got.TokensUsed != 42 || len(got.Sources) != 1 {
+		t.Fatalf("unexpected finished message: %+v", got)
+	}
+	// A finished answer is no longer updated by late saves
+	_ = svc.UpdateStreamingMessage(msg.ID, "late")
+	if got, _ = svc.GetMessage(msg.ID); got.Content != "partial answer" {
+		t.Fatalf("finished message was overwritten: %q", got.Content)
+	}
+	if err := svc.FinishStreamingMessage(msg.ID, "x", nil, nil, StatusStreaming); err == nil {
+		t.Fatalf("expected streaming to be rejected as a final status")
+	}
+
+	// Answers still streaming when the service starts were cut off by a crash
+	orphan, _ := svc.BeginStreamingMessage(session.ID)
+	restarted, err := NewService(svc.db, svc.basePath)
+	if err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	defer restarted.Close()
+	if got, _ = restarted.GetMessage(orphan.ID); got.Status != StatusInterrupted {
+		t.Fatalf("expected interrupted status after restart, got %s", got.Status)
+	}
+}
+
+func TestBulkOperationsAndAutoArchive(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	a, _ := svc.CreateSession("a", "", nil)
+	b, _ := svc.CreateSession("b", "", nil)
+	c, _ := svc.CreateSession("c", "", []string{"keep"})
+	_, _ = svc.AppendMessage(a.ID, "user", "hello", nil, nil, "sent")
+
+	if err := svc.BulkTag([]string{a.ID, c.ID, "missing"}, []string{" Research ", "keep"}); err != nil {
+		t.Fatalf("bulk tag failed: %v", err)
+	}
+	tags, _ := svc.getTags(c.ID)
+	if strings.Join(tags, ",") != "keep,research" {
+		t.Fatalf("unexpected tags after bulk tag: %v", tags)
+	}
+
+	archived, err := svc.BulkArchive([]string{a.ID, b.ID}, true)
+	if err != nil || archived != 2 {
+		t.Fatalf("expected 2 sessions archived, got %d (%v)", archived, err)
+	}
+	if item, _ := svc.GetSession(b.ID); !item.Archived {
+		t.Fatalf("expected session b archived")
+	}
+
+	deleted, err := svc.BulkDelete([]string{a.ID, b.ID})
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 sessions deleted, got %d (%v)", deleted, err)
+	}
+	var orphans int64
+	svc.db.Model(&Message{}).Where("session_id = ?", a.ID).Count(&orphans)
+	if orphans != 0 {
+		t.Fatalf("expected messages deleted with session, got %d", orphans)
+	}
+
+	// Auto-archive skips favorites and recently active sessions
+	old, _ := svc.CreateSession("old", "", nil)
+	fav, _ := svc.CreateSession("fav", "", nil)
+	_ = svc.SetFavorite(fav.ID, true)
+	stale := time.Now().AddDate(0, 0, -40).UnixMilli()
+	svc.db.Model(&Session{}).Where("id IN ?", []string{old.ID, fav.ID}).Update("last_message_at", stale)
+	count, err := svc.ArchiveInactiveSessions(30, time.Now())
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 inactive session archived, got %d (%v)", count, err)
+	}
+	if item, _ := svc.GetSession(old.ID); !item.Archived {
+		t.Fatalf("expected stale session archived")
+	}
+	if item, _ := svc.GetSession(c.ID); item.Archived {
+		t.Fatalf("recent session should not be archived")
+	}
+}
+
+func TestListSessionsUsesStoredSummaries(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	opts := svc.GetStorageOptions()
+	opts.EncryptAtRest = true
+	if err := svc.SetStorageOptions(opts); err != nil {
+		t.Fatalf("set options failed: %v", err)
+	}
+	for i := 0; i < 30; i++ {
+		session, _ := svc.CreateSession("session", "", []string{"bulk"})
+		_, _ = svc.AppendMessage(session.ID, "user", "question", nil, nil, "sent")
+		_, _ = svc.AppendMessage(session.ID, "assistant", "answer", nil, nil, "done")
+	}
+	needle, _ := svc.CreateSession("needle", "", nil)
+	_, _ = svc.AppendMessage(needle.ID, "user", "where is the Zebra?", nil, nil, "sent")
+	_, _ = svc.AppendMessage(needle.ID, "assistant", "in the zoo", nil, nil, "done")
+
+	queries := 0
+	_ = svc.db.Callback().Query().After("gorm:query").Register("count_queries", func(*gorm.DB) { queries++ })
+	_ = svc.db.Callback().Row().After("gorm:row").Register("count_rows", func(*gorm.DB) { queries++ })
+	result, err := svc.ListSessions(SessionFilter{Page: 1, PageSize: 25})
+	if err != nil {
+		t.Fatalf("list sessions failed: %v", err)
+	}
+	if queries > 5 {
+		t.Fatalf("expected a fixed number of queries for a page of 25 sessions, got %d", queries)
+	}
+	if result.Total != 31 || len(result.Items) != 25 {
+		t.Fatalf("unexpected page: total=%d items=%d", result.Total, len(result.Items))
+	}
+	first := result.Items[0]
+	if first.ID != needle.ID || first.Preview != "in the zoo" || first.MessageCount != 2 {
+		t.Fatalf("unexpected summary for newest session: %+v", first)
+	}
+	if len(result.Items[1].Tags) != 1 || result.Items[1].Tags[0] != "bulk" {
+		t.Fatalf("expected tags loaded in batch, got %v", result.Items[1].Tags)
+	}
+
+	// Keywords match encrypted message content and filter before paging
+	found, err := svc.ListSessions(SessionFilter{Keyword: "zebra", Page: 1, PageSize: 1})
+	if err != nil {
+		t.Fatalf("keyword search failed: %v", err)
+	}
+	if found.Total != 1 || len(found.Items) != 1 || found.Items[0].ID != needle.ID {
+		t.Fatalf("unexpected keyword result: %+v", found)
+	}
+
+	// Message edits keep the stored summary current
+	msgs, _ := svc.ListMessages(needle.ID, 1, 10)
+	if err := svc.DeleteMessage(msgs.Items[1].ID); err != nil {
+		t.Fatalf("delete message failed: %v", err)
+	}
+	item, _ := svc.GetSession(needle.ID)
+	if item.MessageCount != 1 || item.Preview != "where is the Zebra?" {
+		t.Fatalf("summary not refreshed after delete: %+v", item)
+	}
+
+	// Sessions stored before the summary columns existed are rebuilt on listing
+	svc.db.Model(&Session{}).Where("id = ?", needle.ID).Updates(map[string]any{"summary_ready": false, "message_count": 0, "last_message_preview": ""})
+	item, _ = svc.GetSession(needle.ID)
+	if item.MessageCount != 1 || item.Preview != "where is the Zebra?" {
+		t.Fatalf("stale summary not rebuilt: %+v", item)
+	}
+}
+
+func TestSessionCountersMaintainedAndBackfilled(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, err := svc.CreateSession("Counters", "", nil)
+	if err != nil {
+		t.Fatalf("create session failed: %v", err)
+	}
+	tokens := 40
+	_, _ = svc.AppendMessage(session.ID, "user", "question", nil, nil, "sent")
+	answer, err := svc.AppendMessage(session.ID, "assistant", "answer", nil, &tokens, "done")
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	var row Session
+	svc.db.First(&row, "id = ?", session.ID)
+	if row.MessageCount != 2 || row.TotalTokens != 40 {
+		t.Fatalf("counters not updated on append: count=%d tokens=%d", row.MessageCount, row.TotalTokens)
+	}
+
+	if err := svc.DeleteMessage(answer.ID); err != nil {
+		t.Fatalf("delete message failed: %v", err)
+	}
+	svc.db.First(&row, "id = ?", session.ID)
+	if row.MessageCount != 1 || row.TotalTokens != 0 {
+		t.Fatalf("counters not updated on delete: count=%d tokens=%d", row.MessageCount, row.TotalTokens)
+	}
+
+	// Databases created before the counter columns existed are backfilled on migration
+	_, _ = svc.AppendMessage(session.ID, "assistant", "again", nil, &tokens, "done")
+	if err := svc.db.Migrator().DropColumn(&Session{}, "TotalTokens"); err != nil {
+		t.Fatalf("drop column failed: %v", err)
+	}
+	svc.db.Model(&Session{}).Where("id = ?", session.ID).Update("message_count", 0)
+	if err := svc.autoMigrate(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	row = Session{}
+	svc.db.First(&row, "id = ?", session.ID)
+	if row.MessageCount != 2 || row.TotalTokens != 40 {
+		t.Fatalf("counters not backfilled: count=%d tokens=%d", row.MessageCount, row.TotalTokens)
+	}
+}
+
+func TestLegacyPreviewColumnMigrated(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	session, _ := svc.CreateSession("Legacy", "", nil)
+	_, _ = svc.AppendMessage(session.ID, "user", "kept preview", nil, nil, "sent")
+
+	// Earlier versions stored the preview in a column named preview
+	if err := svc.db.Migrator().RenameColumn(&Session{}, "last_message_preview", "preview"); err != nil {
+		t.Fatalf("rename column failed: %v", err)
+	}
+	if err := svc.autoMigrate(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if svc.db.Migrator().HasColumn(&Session{}, "preview") {
+		t.Fatal("legacy preview column left behind")
+	}
+	item, _ := svc.GetSession(session.ID)
+	if item.Preview != "kept preview" {
+		t.Fatalf("preview not carried over: %q", item.Preview)
+	}
+
+	// Databases where both columns already exist drop the old one and rebuild
+	if err := svc.db.Exec("ALTER TABLE chat_sessions ADD COLUMN `preview` text").Error; err != nil {
+		t.Fatalf("add column failed: %v", err)
+	}
+	svc.db.Model(&Session{}).Where("id = ?", session.ID).Update("last_message_preview", "")
+	if err := svc.autoMigrate(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if svc.db.Migrator().HasColumn(&Session{}, "preview") {
+		t.Fatal("legacy preview column left behind")
+	}
+	item, _ = svc.GetSession(session.ID)
+	if item.Preview != "kept preview" {
+		t.Fatalf("preview not rebuilt: %q", item.Preview)
+	}
+}
+
+func TestStaleSummariesRebuiltInBatch(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	for i := 0; i < 20; i++ {
+		session, _ := svc.CreateSession("session", "", nil)
+		_, _ = svc.AppendMessage(session.ID, "user", "question", nil, nil, "sent")
+		_, _ = svc.AppendMessage(session.ID, "assistant", "answer", nil, nil, "done")
+	}
+	svc.db.Model(&Session{}).Where("1 = 1").Updates(map[string]any{"summary_ready": false, "message_count": 0, "last_message_preview": ""})
+
+	queries := 0
+	_ = svc.db.Callback().Query().After("gorm:query").Register("count_queries", func(*gorm.DB) { queries++ })
+	_ = svc.db.Callback().Row().After("gorm:row").Register("count_rows", func(*gorm.DB) { queries++ })
+	result, err := svc.ListSessions(SessionFilter{Page: 1, PageSize: 20})
+	if err != nil {
+		t.Fatalf("list sessions failed: %v", err)
+	}
+	if queries > 6 {
+		t.Fatalf("expected stale summaries rebuilt with a fixed number of queries, got %d", queries)
+	}
+	for _, item := range result.Items {
+		if item.MessageCount != 2 || item.Preview != "answer" {
+			t.Fatalf("stale summary not rebuilt: %+v", item)
+		}
+	}
+}
+
+func TestKeywordSearchScansNewestEncryptedMessages(t *testing.T) {
+	svc, cleanup := setupChatTestService(t)
+	defer cleanup()
+
+	opts := svc.GetStorageOptions()
+	opts.EncryptAtRest = true
+	if err := svc.SetStorageOptions(opts); err != nil {
+		t.Fatalf("set options failed: %v", err)
+	}
+	old, _ := svc.CreateSession("old", "", nil)
+	_, _ = svc.AppendMessage(old.ID, "user", "an old zebra", nil, nil, "sent")
+	recent, _ := svc.CreateSession("recent", "", nil)
+	_, _ = svc.AppendMessage(recent.ID, "user", "a recent zebra", nil, nil, "sent")
+	svc.db.Model(&Message{}).Where("session_id = ?", old.ID).Update("timestamp", 1)
+
+	defer func(n int) { maxKeywordScanMessages = n }(maxKeywordScanMessages)
+	maxKeywordScanMessages = 1
+	ids, err := svc.sessionsMatchingKeyword("zebra")
+	if err != nil {
+		t.Fatalf("keyword search failed: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != recent.ID {
+		t.Fatalf("expected only the newest message scanned, got %v", ids)
+	}
+
+	maxKeywordScanMessages = 10
+	if ids, _ = svc.sessionsMatchingKeyword("zebra"); len(ids) != 2 {
+		t.Fatalf("expected both sessions within the bound, got %v", ids)
+	}
+}
@@ -0,0 +1,325 @@
+package chat
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const shareScope = "chat.share"
+
+const (
+	SourceRedactionNone    = "none"    // keep source paths and excerpts
+	SourceRedactionContent = "content" // keep note titles, drop excerpts
+	SourceRedactionAll     = "all"     // drop the source list entirely
+)
+
+// ShareOptions controls how a session is sanitized before being shared outside the vault
+type ShareOptions struct {
+	Format          string   `json:"format"` // "markdown" or "html"
+	SourceRedaction string   `json:"source_redaction"`
+	RedactPII       bool     `json:"redact_pii"`
+	CustomPatterns  []string `json:"custom_patterns"`
+	Placeholder     string   `json:"placeholder"`
+}
+
+// DefaultShareOptions returns conservative defaults: no excerpts, PII masked
+func DefaultShareOptions() ShareOptions {
+	return ShareOptions{
+		Format:          "markdown",
+		SourceRedaction: SourceRedactionContent,
+		RedactPII:       true,
+		Placeholder:     "[redacted]",
+	}
+}
+
+var piiPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{16,}\b`),
+	regexp.MustCompile(`\b(?:\d[ \-]?){13,19}\b`),
+	regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`),
+	regexp.MustCompile(`(?:\+?\d{1,3}[ \-.]?)?(?:\(\d{2,4}\)|\d{2,4})[ \-.]?\d{3,4}[ \-.]?\d{4}\b`),
+}
+
+// GetShareOptions returns the persisted share options
+func (s *Service) GetShareOptions() ShareOptions {
+	opts := DefaultShareOptions()
+	var settings []Setting
+	if err := s.db.Where("scope = ?", shareScope).Find(&settings).Error; err != nil {
+		return opts
+	}
+	for _, item := range settings {
+		switch item.Key {
+		case "format":
+			if item.Value != "" {
+				opts.Format = item.Value
+			}
+		case "source_redaction":
+			if item.Value != "" {
+				opts.SourceRedaction = item.Value
+			}
+		case "redact_pii":
+			opts.RedactPII = item.Value == "true"
+		case "custom_patterns":
+			opts.CustomPatterns = splitPatterns(item.Value)
+		case "placeholder":
+			if item.Value != "" {
+				opts.Placeholder = item.Value
+			}
+		}
+	}
+	return opts
+}
+
+// SetShareOptions validates and persists share options
+func (s *Service) SetShareOptions(opts ShareOptions) error {
+	opts = normalizeShareOptions(opts)
+	for _, pattern := range opts.CustomPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+	}
+	values := map[string]string{
+		"format":           opts.Format,
+		"source_redaction": opts.SourceRedaction,
+		"redact_pii":       fmt.Sprintf("%t", opts.RedactPII),
+		"custom_patterns":  strings.Join(opts.CustomPatterns, "\n"),
+		"placeholder":      opts.Placeholder,
+	}
+	for key, value := range values {
+		setting := Setting{Scope: shareScope, Key: key, Value: value}
+		if err := s.db.Where("scope = ? AND key = ?", setting.Scope, setting.Key).Assign(setting).FirstOrCreate(&setting).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeShareOptions(opts ShareOptions) ShareOptions {
+	opts.Format = strings.ToLower(strings.TrimSpace(opts.Format))
+	if opts.Format != "html" {
+		opts.Format = "markdown"
+	}
+	switch opts.SourceRedaction {
+	case SourceRedactionNone, SourceRedactionContent, SourceRedactionAll:
+	default:
+		opts.SourceRedaction = SourceRedactionContent
+	}
+	if strings.TrimSpace(opts.Placeholder) == "" {
+		opts.Placeholder = "[redacted]"
+	}
+	patterns := make([]string, 0, len(opts.CustomPatterns))
+	for _, p := range opts.CustomPatterns {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	opts.CustomPatterns = patterns
+	return opts
+}
+
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// redactor masks PII and user-defined patterns in exported text
+type redactor struct {
+	patterns    []*regexp.Regexp
+	placeholder string
+}
+
+func newRedactor(opts ShareOptions) *redactor {
+	r := &redactor{placeholder: opts.Placeholder}
+	if opts.RedactPII {
+		r.patterns = append(r.patterns, piiPatterns...)
+	}
+	for _, p := range opts.CustomPatterns {
+		if re, err := regexp.Compile(p); err == nil {
+			r.patterns = append(r.patterns, re)
+		}
+	}
+	return r
+}
+
+func (r *redactor) apply(text string) string {
+	for _, re := range r.patterns {
+		text = re.ReplaceAllString(text, r.placeholder)
+	}
+	return text
+}
+
+// RenderShareable renders a sanitized transcript of a session
+func (s *Service) RenderShareable(sessionID string, opts ShareOptions) (string, error) {
+	opts = normalizeShareOptions(opts)
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	messages, err := s.ListMessages(sessionID, 1, 5000)
+	if err != nil {
+		return "", err
+	}
+	r := newRedactor(opts)
+
+	var sb strings.Builder
+	title := r.apply(session.Title)
+	if opts.Format == "html" {
+		sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">")
+		sb.WriteString(fmt.Sprintf("<title>%s</title></head><body>\n", html.EscapeString(title)))
+		sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(title)))
+	} else {
+		sb.WriteString(fmt.Sprintf("# %s\n\n", title))
+	}
+
+	for _, m := range messages.Items {
+		if m.Role == "system" {
+			continue
+		}
+		when := time.UnixMilli(m.Timestamp).Format("2006-01-02 15:04")
+		content := r.apply(m.Content)
+		sources := shareableSources(m.Sources, opts.SourceRedaction, r)
+		attachments := shareableAttachments(m.Attachments, opts.SourceRedaction, r)
+		if opts.Format == "html" {
+			sb.WriteString(fmt.Sprintf("<section class=\"%s\"><h3>%s <small>%s</small></h3>\n", html.EscapeString(m.Role), html.EscapeString(roleLabel(m.Role)), when))
+			sb.WriteString("<div style=\"white-space: pre-wrap\">" + html.EscapeString(content) + "</div>\n")
+			if len(sources) > 0 {
+				sb.WriteString("<ul>\n")
+				for _, src := range sources {
+					sb.WriteString("<li>" + html.EscapeString(src) + "</li>\n")
+				}
+				sb.WriteString("</ul>\n")
+			}
+			for _, att := range attachments {
+				if att.Kind == AttachmentKindImage {
+					sb.WriteString(fmt.Sprintf("<figure><img src=\"%s\" alt=\"%s\"><figcaption>%s</figcaption></figure>\n",
+						html.EscapeString(att.Path), html.EscapeString(att.Name), html.EscapeString(att.Name)))
+					continue
+				}
+				sb.WriteString("<p>Attached note: " + html.EscapeString(att.Name) + "</p>\n")
+			}
+			sb.WriteString("</section>\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### %s · %s\n\n", roleLabel(m.Role), when))
+		sb.WriteString(content)
+		sb.WriteString("\n\n")
+		if len(sources) > 0 {
+			sb.WriteString("Sources:\n")
+			for _, src := range sources {
+				sb.WriteString("- " + src + "\n")
+			}
+			sb.WriteString("\n")
+		}
+		for _, att := range attachments {
+			if att.Kind == AttachmentKindImage {
+				sb.WriteString(fmt.Sprintf("![%s](%s)\n\n", att.Name, att.Path))
+				continue
+			}
+			sb.WriteString("Attached note: " + att.Name + "\n\n")
+		}
+	}
+	if opts.Format == "html" {
+		sb.WriteString("</body></html>\n")
+	}
+	return sb.String(), nil
+}
+
+// ExportShareable writes a sanitized transcript to the export directory and returns its path
+func (s *Service) ExportShareable(sessionID string) (string, error) {
+	opts := s.GetShareOptions()
+	content, err := s.RenderShareable(sessionID, opts)
+	if err != nil {
+		return "", err
+	}
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	exportDir := filepath.Join(s.basePath, "data", "chat_exports")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		return "", err
+	}
+	base := sanitizeFilename(session.Title)
+	if base == "" {
+		base = sessionID
+	}
+	ext := "md"
+	if opts.Format == "html" {
+		ext = "html"
+	}
+	path := filepath.Join(exportDir, fmt.Sprintf("%s_share_%s.%s", base, time.Now().Format("20060102_150405"), ext))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func shareableSources(sources []map[string]any, mode string, r *redactor) []string {
+	if mode == SourceRedactionAll || len(sources) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	out := make([]string, 0, len(sources))
+	for _, src := range sources {
+		title, _ := src["title"].(string)
+		path, _ := src["path"].(string)
+		if title == "" {
+			title = path
+		}
+		line := r.apply(title)
+		if mode == SourceRedactionNone {
+			if path != "" && path != title {
+				line += " (" + r.apply(path) + ")"
+			}
+			if content, _ := src["content"].(string); content != "" {
+				line += ": " + r.apply(strings.Join(strings.Fields(content), " "))
+			}
+		}
+		if _, ok := seen[line]; ok {
+			continue
+		}
+		seen[line] = struct{}{}
+		out = append(out, line)
+	}
+	return out
+}
+
+// shareableAttachments applies source redaction to attachments: "all" drops them,
+// "content" keeps names but hides image paths.
+func shareableAttachments(attachments []Attachment, mode string, r *redactor) []Attachment {
+	if mode == SourceRedactionAll {
+		return nil
+	}
+	out := make([]Attachment, 0, len(attachments))
+	for _, att := range attachments {
+		att.Name = r.apply(att.Name)
+		if mode != SourceRedactionNone && att.Kind == AttachmentKindImage {
+			// Render as a plain label, the image path may reveal vault layout
+			att.Kind = AttachmentKindNote
+			att.Name = "image " + att.Name
+		}
+		out = append(out, att)
+	}
+	return out
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	default:
+		return strings.ToUpper(role)
+	}
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Message statuses
+const (
+	StatusSent      = "sent"      // User message stored
+	StatusDone      = "done"      // Assistant answer complete
+	StatusError     = "error"     // Error notice from a failed query
+	StatusStreaming = "streaming" // Assistant answer still being generated
+	// StatusInterrupted marks an answer whose generation was cancelled or cut
+	// short by a crash; its content is whatever arrived before that
+	StatusInterrupted = "interrupted"
+)
+
+// BeginStreamingMessage stores an empty assistant message with status
+// "streaming", to be filled in by UpdateStreamingMessage and closed with
+// FinishStreamingMessage
+func (s *Service) BeginStreamingMessage(sessionID string) (*MessageDTO, error) {
+	return s.AppendMessage(sessionID, "assistant", "", nil, nil, StatusStreaming)
+}
+
+// UpdateStreamingMessage replaces the content generated so far
+func (s *Service) UpdateStreamingMessage(messageID, content string) error {
+	encContent, encrypted, err := s.encryptText(content)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&Message{}).Where("id = ? AND status = ?", messageID, StatusStreaming).Updates(map[string]any{
+		"content":   encContent,
+		"encrypted": encrypted,
+	}).Error
+}
+
+// FinishStreamingMessage stores the final content, sources and usage of a
+// streamed answer and sets its status to done, interrupted or error
+func (s *Service) FinishStreamingMessage(messageID, content string, sources any, tokensUsed *int, status string) error {
+	switch status {
+	case StatusDone, StatusInterrupted, StatusError:
+	default:
+		return fmt.Errorf("invalid final status: %s", status)
+	}
+	encContent, encrypted, err := s.encryptText(content)
+	if err != nil {
+		return err
+	}
+	updates := map[string]any{
+		"content":     encContent,
+		"encrypted":   encrypted,
+		"status":      status,
+		"tokens_used": tokensUsed,
+	}
+	if sources != nil {
+		payload, _ := json.Marshal(sources)
+		if encSrc, srcEncrypted, srcErr := s.encryptText(string(payload)); srcErr == nil {
+			updates["sources"] = encSrc
+			updates["sources_encrypted"] = srcEncrypted
+		}
+	}
+	var row Message
+	if err := s.db.Select("session_id").First(&row, "id = ?", messageID).Error; err != nil {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Message{}).Where("id = ?", messageID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return s.refreshSession(tx, row.SessionID, map[string]any{
+			"last_message_at": now,
+			"updated_at_unix": now,
+		})
+	})
+}
+
+// MarkInterruptedMessages flags answers left streaming by a previous run,
+// which ended before they completed. It returns how many were flagged.
+func (s *Service) MarkInterruptedMessages() (int64, error) {
+	result := s.db.Model(&Message{}).Where("status = ?", StatusStreaming).Update("status", StatusInterrupted)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,322 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// previewRunes is the length of a session preview
+const previewRunes = 120
+
+func makePreview(text string) string {
+	text = strings.TrimSpace(text)
+	r := []rune(text)
+	if len(r) > previewRunes {
+		return string(r[:previewRunes]) + "..."
+	}
+	return text
+}
+
+// previewUpdates returns the session columns storing preview text
+func (s *Service) previewUpdates(text string) (map[string]any, error) {
+	enc, encrypted, err := s.encryptText(makePreview(text))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"last_message_preview": enc, "preview_encrypted": encrypted}, nil
+}
+
+// refreshSession recomputes the stored counters and preview of a session
+// within tx and applies extra updates with them. If the last message cannot
+// be read (history locked) the preview is marked stale and rebuilt later.
+func (s *Service) refreshSession(tx *gorm.DB, sessionID string, extra map[string]any) error {
+	var counters struct {
+		MessageCount int64
+		TotalTokens  int64
+	}
+	if err := tx.Model(&Message{}).Where("session_id = ?", sessionID).
+		Select("COUNT(*) AS message_count, COALESCE(SUM(tokens_used), 0) AS total_tokens").
+		Scan(&counters).Error; err != nil {
+		return err
+	}
+	updates := map[string]any{
+		"message_count": counters.MessageCount,
+		"total_tokens":  counters.TotalTokens,
+	}
+	for k, v := range extra {
+		updates[k] = v
+	}
+
+	var last Message
+	err := tx.Where("session_id = ?", sessionID).Order("timestamp DESC, created_at DESC").First(&last).Error
+	switch {
+	case err == nil:
+		s.addPreviewUpdates(updates, &last)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		s.addPreviewUpdates(updates, nil)
+	default:
+		return err
+	}
+	return tx.Model(&Session{}).Where("id = ?", sessionID).Updates(updates).Error
+}
+
+// addPreviewUpdates adds the preview of last, the newest message of a
+// session (nil if it has none), to updates and marks the summary ready, or
+// marks it stale if the message cannot be decrypted
+func (s *Service) addPreviewUpdates(updates map[string]any, last *Message) {
+	updates["summary_ready"] = false
+	text := ""
+	if last != nil {
+		var err error
+		if text, err = s.decryptText(last.Content, last.Encrypted); err != nil {
+			return
+		}
+	}
+	preview, err := s.previewUpdates(text)
+	if err != nil {
+		return
+	}
+	for k, v := range preview {
+		updates[k] = v
+	}
+	updates["summary_ready"] = true
+}
+
+// refreshSessions rebuilds the summaries of several sessions with one
+// counter update and one query for their newest messages
+func (s *Service) refreshSessions(sessionIDs []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(sessionCountersSQL+" WHERE id IN ?", sessionIDs).Error; err != nil {
+			return err
+		}
+
+		var newest []Message
+		if err := tx.Raw(`SELECT id, session_id, content, encrypted FROM (
+			SELECT id, session_id, content, encrypted, ROW_NUMBER() OVER (
+				PARTITION BY session_id ORDER BY timestamp DESC, created_at DESC) AS rn
+			FROM chat_messages WHERE session_id IN ?) WHERE rn = 1`, sessionIDs).
+			Scan(&newest).Error; err != nil {
+			return err
+		}
+		lastBySession := make(map[string]*Message, len(newest))
+		for i := range newest {
+			lastBySession[newest[i].SessionID] = &newest[i]
+		}
+
+		for _, id := range sessionIDs {
+			updates := map[string]any{}
+			s.addPreviewUpdates(updates, lastBySession[id])
+			if err := tx.Model(&Session{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// touchSession refreshes a session's summary after one of its messages changed
+func (s *Service) touchSession(tx *gorm.DB, sessionID string) error {
+	return s.refreshSession(tx, sessionID, map[string]any{"updated_at_unix": time.Now().UnixMilli()})
+}
+
+// sessionCountersSQL recomputes message_count and total_tokens from the
+// messages of each session
+const sessionCountersSQL = `UPDATE chat_sessions SET
+	message_count = (SELECT COUNT(*) FROM chat_messages WHERE chat_messages.session_id = chat_sessions.id),
+	total_tokens = (SELECT COALESCE(SUM(tokens_used), 0) FROM chat_messages WHERE chat_messages.session_id = chat_sessions.id)`
+
+// backfillSessionCounters fills message_count and total_tokens for sessions
+// stored before those columns existed
+func backfillSessionCounters(db *gorm.DB) error {
+	return db.Exec(sessionCountersSQL).Error
+}
+
+// migrateLegacyPreview moves previews out of the preview column used by
+// earlier versions. If last_message_preview already exists the old column is
+// dropped and the summaries are rebuilt when sessions are listed.
+func migrateLegacyPreview(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&Session{}) || !m.HasColumn(&Session{}, "preview") {
+		return nil
+	}
+	if !m.HasColumn(&Session{}, "last_message_preview") {
+		return m.RenameColumn(&Session{}, "preview", "last_message_preview")
+	}
+	if err := m.DropColumn(&Session{}, "preview"); err != nil {
+		return err
+	}
+	return db.Model(&Session{}).Where("1 = 1").Update("summary_ready", false).Error
+}
+
+// sessionPreview decrypts the stored preview; a locked history yields ""
+func (s *Service) sessionPreview(session Session) string {
+	text, err := s.decryptText(session.Preview, session.PreviewEncrypted)
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// sessionAggregates holds per-session values computed from messages
+type sessionAggregates struct {
+	SessionID   string
+	CostUSD     float64
+	PinnedCount int64
+}
+
+// listItems builds list items for sessions with a fixed number of queries,
+// rebuilding stale summaries first
+func (s *Service) listItems(sessions []Session) ([]SessionListItem, error) {
+	items := make([]SessionListItem, 0, len(sessions))
+	if len(sessions) == 0 {
+		return items, nil
+	}
+	ids := make([]string, len(sessions))
+	var stale []string
+	for i, session := range sessions {
+		ids[i] = session.ID
+		if !session.SummaryReady {
+			stale = append(stale, session.ID)
+		}
+	}
+	if len(stale) > 0 {
+		if err := s.refreshSessions(stale); err != nil {
+			return nil, err
+		}
+		var refreshed []Session
+		if err := s.db.Where("id IN ?", stale).Find(&refreshed).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[string]Session, len(refreshed))
+		for _, session := range refreshed {
+			byID[session.ID] = session
+		}
+		for i, session := range sessions {
+			if r, ok := byID[session.ID]; ok {
+				sessions[i] = r
+			}
+		}
+	}
+
+	tags := make(map[string][]string, len(ids))
+	var tagRows []SessionTag
+	if err := s.db.Where("session_id IN ?", ids).Order("tag ASC").Find(&tagRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range tagRows {
+		tags[row.SessionID] = append(tags[row.SessionID], row.Tag)
+	}
+
+	var aggRows []sessionAggregates
+	if err := s.db.Model(&Message{}).
+		Select(`session_id, COALESCE(SUM(cost_usd), 0) AS cost_usd,
+			SUM(CASE WHEN pinned THEN 1 ELSE 0 END) AS pinned_count`).
+		Where("session_id IN ?", ids).
+		Group("session_id").
+		Scan(&aggRows).Error; err != nil {
+		return nil, err
+	}
+	aggs := make(map[string]sessionAggregates, len(aggRows))
+	for _, row := range aggRows {
+		aggs[row.SessionID] = row
+	}
+
+	for _, session := range sessions {
+		sessionTags := tags[session.ID]
+		if sessionTags == nil {
+			sessionTags = []string{}
+		}
+		agg := aggs[session.ID]
+		items = append(items, SessionListItem{
+			ID:            session.ID,
+			Title:         session.Title,
+			Category:      session.Category,
+			Archived:      session.Archived,
+			Favorite:      session.Favorite,
+			Tags:          sessionTags,
+			CreatedAt:     session.CreatedAtUnix,
+			UpdatedAt:     session.UpdatedAtUnix,
+			LastMessageAt: session.LastMessageAt,
+			MessageCount:  session.MessageCount,
+			Preview:       s.sessionPreview(session),
+			TotalTokens:   session.TotalTokens,
+			CostUSD:       agg.CostUSD,
+			Notes:         session.Notes,
+			PinnedCount:   agg.PinnedCount,
+			SessionOverrides: SessionOverrides{
+				Model:        session.Model,
+				Temperature:  session.Temperature,
+				SystemPrompt: session.SystemPrompt,
+			},
+		})
+	}
+	return items, nil
+}
+
+// maxKeywordScanMessages bounds how many encrypted messages a keyword search
+// decrypts. The newest are scanned first, so matches in older encrypted
+// history can be missed.
+var maxKeywordScanMessages = 5000
+
+// keywordScanBatch is how many encrypted messages are loaded per query
+const keywordScanBatch = 500
+
+// sessionsMatchingKeyword returns the IDs of sessions whose title or any
+// message contains keyword. Plain-text messages are matched in SQL; encrypted
+// ones cannot be searched in the database and are decrypted newest first, up
+// to maxKeywordScanMessages, skipping sessions that already matched.
+func (s *Service) sessionsMatchingKeyword(keyword string) ([]string, error) {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	pattern := "%" + escapeLike(keyword) + "%"
+	matched := make(map[string]struct{})
+
+	var ids []string
+	if err := s.db.Model(&Session{}).Where("LOWER(title) LIKE ? ESCAPE '\\'", pattern).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		matched[id] = struct{}{}
+	}
+	ids = nil
+	if err := s.db.Model(&Message{}).Distinct("session_id").
+		Where("encrypted = ? AND LOWER(content) LIKE ? ESCAPE '\\'", false, pattern).
+		Pluck("session_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		matched[id] = struct{}{}
+	}
+
+	for scanned := 0; scanned < maxKeywordScanMessages; {
+		var batch []Message
+		if err := s.db.Select("id", "session_id", "content").
+			Where("encrypted = ?", true).
+			Order("timestamp DESC, id ASC").
+			Offset(scanned).Limit(min(keywordScanBatch, maxKeywordScanMessages-scanned)).
+			Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range batch {
+			if _, ok := matched[row.SessionID]; ok {
+				continue
+			}
+			text, err := s.decryptText(row.Content, true)
+			if err == nil && strings.Contains(strings.ToLower(text), keyword) {
+				matched[row.SessionID] = struct{}{}
+			}
+		}
+		scanned += len(batch)
+		if len(batch) < keywordScanBatch {
+			break
+		}
+	}
+
+	out := make([]string, 0, len(matched))
+	for id := range matched {
+		out = append(out, id)
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"gorm.io/gorm"
+)
+
+// SessionUsage is the token usage and estimated cost of one session
+type SessionUsage struct {
+	SessionID   string  `json:"session_id"`
+	Title       string  `json:"title"`
+	Messages    int64   `json:"messages"` // Messages with recorded usage
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+// DailyUsage is the chat token usage and estimated cost of one day
+type DailyUsage struct {
+	Day         string  `json:"day"` // YYYY-MM-DD, local time
+	Messages    int64   `json:"messages"`
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+// usageScope selects messages that recorded token usage or cost
+func (s *Service) usageScope() *gorm.DB {
+	return s.db.Model(&Message{}).Where("tokens_used IS NOT NULL OR cost_usd > 0")
+}
+
+// SetMessageCost records the estimated cost of generating a message
+func (s *Service) SetMessageCost(messageID string, costUSD float64) error {
+	return s.db.Model(&Message{}).Where("id = ?", messageID).Update("cost_usd", costUSD).Error
+}
+
+// GetUsageBySession returns usage per session, most expensive first. Sessions
+// without recorded usage are omitted.
+func (s *Service) GetUsageBySession() ([]SessionUsage, error) {
+	rows := []SessionUsage{}
+	err := s.usageScope().
+		Select(`chat_messages.session_id AS session_id, chat_sessions.title AS title,
+			COUNT(*) AS messages,
+			COALESCE(SUM(chat_messages.tokens_used), 0) AS total_tokens,
+			COALESCE(SUM(chat_messages.cost_usd), 0) AS cost_usd`).
+		Joins("JOIN chat_sessions ON chat_sessions.id = chat_messages.session_id").
+		Group("chat_messages.session_id, chat_sessions.title").
+		Order("cost_usd DESC, total_tokens DESC, session_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetUsageByDay returns chat usage per day between rangeStart and rangeEnd
+// (Unix ms, inclusive start, exclusive end; 0 leaves that side open)
+func (s *Service) GetUsageByDay(rangeStart, rangeEnd int64) ([]DailyUsage, error) {
+	scope := s.usageScope()
+	if rangeStart > 0 {
+		scope = scope.Where("timestamp >= ?", rangeStart)
+	}
+	if rangeEnd > 0 {
+		scope = scope.Where("timestamp < ?", rangeEnd)
+	}
+	rows := []DailyUsage{}
+	err := scope.
+		Select(`strftime('%Y-%m-%d', timestamp / 1000, 'unixepoch', 'localtime') AS day,
+			COUNT(*) AS messages,
+			COALESCE(SUM(tokens_used), 0) AS total_tokens,
+			COALESCE(SUM(cost_usd), 0) AS cost_usd`).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error
+	return rows, err
+}
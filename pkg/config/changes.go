@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sort"
+)
+
+// Change is a setting whose value differs between two saves. Secrets are
+// reported with RedactedSecret in place of their values.
+type Change struct {
+	Key    string      `json:"key"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// secretKeys are the JSON paths of the fields in secretFields
+var secretKeys = map[string]bool{
+	"ai.openai.api_key":        true,
+	"ai.custom.api_key":        true,
+	"llm.openai.api_key":       true,
+	"network.proxy_url":        true,
+	"api_server.token":         true,
+	"sync.webdav.password":     true,
+	"backup.secret_access_key": true,
+	"backup.passphrase":        true,
+}
+
+// OnSave registers fn to be called with the changed settings after each
+// successful save. fn runs without the config lock held.
+func (c *Config) OnSave(fn func([]Change)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSave = fn
+}
+
+// diffConfigJSON compares two serialized configs leaf by leaf, sorted by key
+func diffConfigJSON(before, after []byte) []Change {
+	var b, a map[string]interface{}
+	if json.Unmarshal(before, &b) != nil || json.Unmarshal(after, &a) != nil {
+		return nil
+	}
+	oldValues := make(map[string]interface{})
+	newValues := make(map[string]interface{})
+	flattenJSON("", b, oldValues)
+	flattenJSON("", a, newValues)
+
+	keys := make(map[string]bool, len(newValues))
+	for k := range oldValues {
+		keys[k] = true
+	}
+	for k := range newValues {
+		keys[k] = true
+	}
+	var changes []Change
+	for k := range keys {
+		if reflect.DeepEqual(oldValues[k], newValues[k]) {
+			continue
+		}
+		changes = append(changes, Change{Key: k, Before: redactValue(k, oldValues[k]), After: redactValue(k, newValues[k])})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+func flattenJSON(prefix string, v map[string]interface{}, out map[string]interface{}) {
+	for k, value := range v {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenJSON(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// redactValue hides secret values, keeping proxy URLs without credentials
+func redactValue(key string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !secretKeys[key] || !ok || s == "" {
+		return v
+	}
+	if key == "network.proxy_url" {
+		if u, err := url.Parse(s); err == nil && u.User == nil {
+			return v
+		}
+	}
+	return RedactedSecret
+}
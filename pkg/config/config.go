@@ -1,17 +1,24 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Config holds the application configuration
 type Config struct {
 	mu         sync.RWMutex
 	configPath string
+	vault      *vaultLayer // overrides of the open vault, see ApplyVaultOverrides
+	vaultDir   string      // root of the open vault, layered again after loads
+	saved      []byte      // global JSON as last loaded or saved, for OnSave
+	onSave     func([]Change)
 
 	// AI Configuration
 	AI AIConfig `json:"ai"`
@@ -33,11 +40,44 @@ type Config struct {
 
 	// Indexing Configuration
 	Indexing IndexingConfig `json:"indexing"`
+
+	// Notes Configuration (placement and naming of new notes)
+	Notes NotesConfig `json:"notes"`
+
+	// Digest Configuration (periodic LLM summary of recent notes)
+	Digest DigestConfig `json:"digest"`
+
+	// Network Configuration (proxy and TLS for AI provider requests)
+	Network NetworkConfig `json:"network"`
+
+	// API Server Configuration (local REST API for integrations)
+	APIServer APIServerConfig `json:"api_server"`
+
+	// Sync Configuration (keeping the vault in step with other machines)
+	Sync SyncConfig `json:"sync"`
+
+	// Backup Configuration (encrypted vault backups to S3-compatible storage)
+	Backup BackupConfig `json:"backup"`
+
+	// Vault Lock Configuration (passphrase-encrypted vault database)
+	VaultLock VaultLockConfig `json:"vault_lock"`
+
+	// Transcription Configuration (audio files transcribed into notes)
+	Transcription TranscriptionConfig `json:"transcription"`
+
+	// OCR Configuration (text of images embedded in notes)
+	OCR OCRConfig `json:"ocr"`
+
+	// Image Caption Configuration (descriptions of images embedded in notes)
+	ImageCaptions ImageCaptionConfig `json:"image_captions"`
+
+	// Feed Configuration (RSS/Atom subscriptions written into the vault)
+	Feeds FeedsConfig `json:"feeds"`
 }
 
 // AIConfig holds AI service configuration
 type AIConfig struct {
-	// Provider is the default embedding provider ("openai" or "ollama")
+	// Provider is the default embedding provider ("openai", "ollama", "builtin" or "custom")
 	Provider string `json:"provider"`
 
 	// OpenAI Configuration
@@ -46,6 +86,14 @@ type AIConfig struct {
 	// Ollama Configuration
 	Ollama OllamaConfig `json:"ollama"`
 
+	// Builtin configures the in-process embedding model
+	Builtin BuiltinConfig `json:"builtin"`
+
+	// Custom configures an OpenAI-compatible server (LM Studio, vLLM,
+	// OpenRouter, llama.cpp) used for embeddings and, when LLM.Provider is
+	// "custom", for chat
+	Custom CustomConfig `json:"custom"`
+
 	// EmbeddingModel is the default model to use for embeddings
 	EmbeddingModel string `json:"embedding_model"`
 
@@ -57,6 +105,27 @@ type AIConfig struct {
 
 	// VectorDimension is the dimension of embeddings (default: 1536 for text-embedding-3-small)
 	VectorDimension int `json:"vector_dimension"`
+
+	// NormalizeEmbeddings scales embeddings to unit length when they are indexed
+	NormalizeEmbeddings bool `json:"normalize_embeddings"`
+
+	// SimilarityMetric scores vector search results: "cosine", "dot" or "euclidean"
+	SimilarityMetric string `json:"similarity_metric"`
+
+	// HealthCheckInterval is how often embedding providers are pinged, in seconds
+	HealthCheckInterval int `json:"health_check_interval"`
+
+	// LanguageModels maps a chunk language (ISO 639-1, e.g. "zh") to a
+	// multilingual embedding model. Chunks in that language are embedded
+	// with it as well, in that model's namespace.
+	LanguageModels map[string]string `json:"language_models,omitempty"`
+
+	// Debug records sanitized AI requests and responses to a rotating
+	// ai-debug.log in the log directory
+	Debug bool `json:"debug"`
+	// DebugFullText records whole prompts and responses in debug mode
+	// instead of a short preview and hash of each
+	DebugFullText bool `json:"debug_full_text"`
 }
 
 // OpenAIConfig holds OpenAI-specific configuration
@@ -72,6 +141,9 @@ type OpenAIConfig struct {
 
 	// Default models
 	EmbeddingModel string `json:"embedding_model"` // e.g., "text-embedding-3-small", "text-embedding-3-large"
+
+	// RateLimit throttles requests to this provider
+	RateLimit RateLimitConfig `json:"rate_limit"`
 }
 
 // OllamaConfig holds Ollama-specific configuration
@@ -84,6 +156,50 @@ type OllamaConfig struct {
 
 	// Timeout is the request timeout in seconds
 	Timeout int `json:"timeout"`
+
+	// RateLimit throttles requests to this provider
+	RateLimit RateLimitConfig `json:"rate_limit"`
+}
+
+// CustomConfig holds configuration for a generic OpenAI-compatible provider
+type CustomConfig struct {
+	// BaseURL is the API root, e.g. http://localhost:1234/v1; empty disables the provider
+	BaseURL string `json:"base_url"`
+
+	// APIKey is sent as a bearer token when set; local servers usually need none
+	APIKey string `json:"api_key"`
+
+	// EmbeddingModel is the default embedding model
+	EmbeddingModel string `json:"embedding_model"`
+
+	// Timeout is the request timeout in seconds
+	Timeout int `json:"timeout"`
+
+	// RateLimit throttles requests to this provider
+	RateLimit RateLimitConfig `json:"rate_limit"`
+}
+
+// BuiltinConfig holds configuration for the in-process embedding model
+type BuiltinConfig struct {
+	// ModelDir holds config.json, vocab.txt and model.safetensors
+	// (default: <user config dir>/notebit/models/all-MiniLM-L6-v2)
+	ModelDir string `json:"model_dir"`
+
+	// DownloadURL is the base URL the model files are fetched from when missing
+	DownloadURL string `json:"download_url"`
+}
+
+// RateLimitConfig holds client-side rate limits for a provider.
+// Zero or negative values disable the corresponding limit.
+type RateLimitConfig struct {
+	// RequestsPerMinute caps the request rate
+	RequestsPerMinute int `json:"requests_per_minute"`
+
+	// TokensPerMinute caps the estimated token throughput
+	TokensPerMinute int `json:"tokens_per_minute"`
+
+	// MaxConcurrency caps in-flight requests; it shrinks after 429 responses and recovers gradually
+	MaxConcurrency int `json:"max_concurrency"`
 }
 
 // ChunkingConfig holds text chunking configuration
@@ -108,6 +224,10 @@ type ChunkingConfig struct {
 
 	// HeadingSeparator is the separator used between heading and content (default: "\n\n")
 	HeadingSeparator string `json:"heading_separator"`
+
+	// StripMathForEmbedding leaves LaTeX math out of the text sent to the
+	// embedding model; stored chunks keep it
+	StripMathForEmbedding bool `json:"strip_math_for_embedding"`
 }
 
 // WatcherConfig holds file watcher configuration
@@ -127,7 +247,7 @@ type WatcherConfig struct {
 
 // LLMConfig holds LLM (chat completion) configuration
 type LLMConfig struct {
-	// Provider is the LLM provider ("openai")
+	// Provider is the LLM provider ("openai" or "custom", which uses AI.Custom)
 	Provider string `json:"provider"`
 
 	// Model is the default chat model
@@ -144,6 +264,23 @@ type LLMConfig struct {
 
 	// Ollama Configuration for Chat
 	Ollama OllamaConfig `json:"ollama"`
+
+	// Cache reuses the replies of repeated deterministic completions
+	Cache LLMCacheConfig `json:"cache"`
+}
+
+// LLMCacheConfig configures the completion cache. Only requests with
+// temperature 0 and no tools are cached, keyed by a hash of the prompt and
+// model.
+type LLMCacheConfig struct {
+	// Enabled turns the cache on
+	Enabled bool `json:"enabled"`
+
+	// TTLMinutes is how long a reply is reused
+	TTLMinutes int `json:"ttl_minutes"`
+
+	// MaxEntries caps the number of cached replies; the least recently used go first
+	MaxEntries int `json:"max_entries"`
 }
 
 // RAGConfig holds RAG (Retrieval Augmented Generation) configuration
@@ -156,8 +293,52 @@ type RAGConfig struct {
 
 	// SystemPrompt is the system prompt for RAG
 	SystemPrompt string `json:"system_prompt"`
+
+	// MinSimilarity drops retrieved chunks scoring below it (0 keeps all)
+	MinSimilarity float32 `json:"min_similarity"`
+
+	// NoContextBehavior decides what happens when no chunk passes MinSimilarity:
+	// "respond" replies that no relevant notes were found without calling the
+	// LLM, "answer" lets the LLM answer without note context
+	NoContextBehavior string `json:"no_context_behavior"`
+
+	// QueryRewrite is an LLM step before retrieval: "off", "rewrite" turns
+	// the question into a keyword-rich search query, "hyde" writes a
+	// hypothetical answer and searches with that
+	QueryRewrite string `json:"query_rewrite"`
+
+	// MultiQuery is how many rephrasings of the question (at most 5) are
+	// searched as well, their results merged; 0 searches the question only
+	MultiQuery int `json:"multi_query"`
+
+	// FollowUps runs a second LLM pass after each answer that suggests
+	// follow-up questions and extracts action items
+	FollowUps bool `json:"follow_ups"`
 }
 
+// MaxMultiQuery bounds RAGConfig.MultiQuery
+const MaxMultiQuery = 5
+
+// NoContextBehavior values
+const (
+	NoContextRespond = "respond"
+	NoContextAnswer  = "answer"
+)
+
+// SimilarityMetric values
+const (
+	SimilarityCosine    = "cosine"
+	SimilarityDot       = "dot"
+	SimilarityEuclidean = "euclidean"
+)
+
+// QueryRewrite values
+const (
+	QueryRewriteOff     = "off"
+	QueryRewriteRewrite = "rewrite"
+	QueryRewriteHyDE    = "hyde"
+)
+
 // GraphConfig holds knowledge graph configuration
 type GraphConfig struct {
 	// MinSimilarityThreshold is the minimum similarity for implicit links
@@ -168,6 +349,12 @@ type GraphConfig struct {
 
 	// ShowImplicitLinks controls whether to show semantic similarity links
 	ShowImplicitLinks bool `json:"show_implicit_links"`
+
+	// ShowFolderNodes adds a node per folder, linked to its notes and its parent folder
+	ShowFolderNodes bool `json:"show_folder_nodes"`
+
+	// ShowTemporalLinks links notes created in the same week, in creation order
+	ShowTemporalLinks bool `json:"show_temporal_links"`
 }
 
 // IndexingConfig holds indexing pipeline configuration
@@ -180,6 +367,220 @@ type IndexingConfig struct {
 
 	// MigrationBatchSize is the number of chunks to migrate in one batch
 	MigrationBatchSize int `json:"migration_batch_size"`
+
+	// Schedule controls the periodic reconcile + embedding repair run
+	Schedule IndexScheduleConfig `json:"schedule"`
+
+	// AutoOptimizeMB optimizes the database when a vault is opened and the
+	// database grew by this many MB since the last optimization (0 = off)
+	AutoOptimizeMB int `json:"auto_optimize_mb"`
+}
+
+// IndexScheduleConfig holds the periodic reindex schedule
+type IndexScheduleConfig struct {
+	// Enabled turns on the scheduled maintenance run
+	Enabled bool `json:"enabled"`
+
+	// Time is the local time of day to run at, in 24h "HH:MM" format
+	Time string `json:"time"`
+
+	// OnlyWhenIdle defers the run until the user has been inactive for IdleMinutes
+	OnlyWhenIdle bool `json:"only_when_idle"`
+
+	// IdleMinutes is the inactivity period required when OnlyWhenIdle is set
+	IdleMinutes int `json:"idle_minutes"`
+
+	// OnlyOnACPower defers the run while the machine is on battery
+	OnlyOnACPower bool `json:"only_on_ac_power"`
+}
+
+// NotesConfig holds rules applied when notes are created and saved
+type NotesConfig struct {
+	// InboxFolder is the folder new notes are created in when no folder is given ("" = vault root)
+	InboxFolder string `json:"inbox_folder"`
+
+	// DateFolderFormat adds date-based subfolders below the inbox, e.g. "YYYY/MM" ("" = disabled)
+	DateFolderFormat string `json:"date_folder_format"`
+
+	// SlugifyTitles converts titles to lowercase dash-separated file names
+	SlugifyTitles bool `json:"slugify_titles"`
+
+	// DedupeNames appends a numeric suffix when a note with the same name exists
+	DedupeNames bool `json:"dedupe_names"`
+
+	// FsyncOnSave flushes saved notes to disk before the save completes
+	FsyncOnSave bool `json:"fsync_on_save"`
+
+	// KeepBackup keeps the previous content of a saved note as a hidden .bak file
+	// (off by default: the backups sit in the vault, where sync and export see them)
+	KeepBackup bool `json:"keep_backup"`
+
+	// BibliographyFile is the BibTeX file notes cite with [@key], relative to the vault root
+	BibliographyFile string `json:"bibliography_file"`
+}
+
+// SyncConfig holds the vault sync settings
+type SyncConfig struct {
+	Git    GitSyncConfig    `json:"git"`
+	WebDAV WebDAVSyncConfig `json:"webdav"`
+}
+
+// GitSyncConfig controls committing for vaults that are git working copies
+type GitSyncConfig struct {
+	// AutoCommit commits all changes once saves pause for DebounceSeconds
+	AutoCommit      bool `json:"auto_commit"`
+	DebounceSeconds int  `json:"debounce_seconds"`
+	// AutoPush pushes after each automatic commit
+	AutoPush bool `json:"auto_push"`
+	// PullOnOpen pulls when the vault is opened
+	PullOnOpen bool `json:"pull_on_open"`
+}
+
+// WebDAVSyncConfig points the vault at a WebDAV folder, e.g. on Nextcloud
+type WebDAVSyncConfig struct {
+	Enabled  bool   `json:"enabled"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Folders limits the sync to these vault folders; empty syncs everything
+	Folders []string `json:"folders"`
+}
+
+// BackupConfig holds the S3-compatible bucket vault backups are written to.
+// Backups are encrypted with Passphrase before they leave the machine.
+type BackupConfig struct {
+	Enabled         bool   `json:"enabled"`
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	Passphrase      string `json:"passphrase"`
+	// IntervalHours between scheduled backups; 0 backs up on demand only
+	IntervalHours int `json:"interval_hours"`
+	// FullEveryDays makes a full backup once the last one is this old;
+	// backups in between are incremental
+	FullEveryDays int `json:"full_every_days"`
+}
+
+// VaultLockConfig holds the settings of locked vaults. The passphrase is
+// never stored.
+type VaultLockConfig struct {
+	// AutoLockMinutes locks an unlocked vault after this long without
+	// activity; 0 keeps it unlocked until the app closes
+	AutoLockMinutes int `json:"auto_lock_minutes"`
+}
+
+// TranscriptionConfig holds the settings of audio note transcription
+type TranscriptionConfig struct {
+	// Enabled transcribes audio files as they are added to the vault
+	Enabled bool `json:"enabled"`
+	// Provider is TranscriptionWhisperCpp for a local whisper.cpp binary or
+	// TranscriptionOpenAI for the OpenAI audio API, which uses the OpenAI API
+	// key and base URL
+	Provider string `json:"provider"`
+	// WhisperBinary is the whisper.cpp command line program
+	WhisperBinary string `json:"whisper_binary"`
+	// WhisperModel is the path of the whisper.cpp ggml model file
+	WhisperModel string `json:"whisper_model"`
+	// OpenAIModel is the OpenAI transcription model
+	OpenAIModel string `json:"openai_model"`
+	// Language is an ISO 639-1 hint; "" lets the model detect the language
+	Language string `json:"language"`
+}
+
+// Transcription providers
+const (
+	TranscriptionWhisperCpp = "whisper-cpp"
+	TranscriptionOpenAI     = "openai"
+)
+
+// OCRConfig holds the settings for reading text from images embedded in
+// notes, which is indexed with the note
+type OCRConfig struct {
+	// Enabled reads images when their notes are indexed
+	Enabled bool `json:"enabled"`
+	// Provider is OCRTesseract for a local tesseract binary or OCRVision for
+	// the chat model, which must accept images and uses the LLM OpenAI settings
+	Provider string `json:"provider"`
+	// TesseractBinary is the tesseract command line program
+	TesseractBinary string `json:"tesseract_binary"`
+	// Languages are tesseract language codes joined by "+", e.g. "eng+deu"
+	Languages string `json:"languages"`
+	// VisionModel is the chat model that reads images
+	VisionModel string `json:"vision_model"`
+}
+
+// OCR providers
+const (
+	OCRTesseract = "tesseract"
+	OCRVision    = "vision"
+)
+
+// ImageCaptionConfig holds the settings for describing images embedded in
+// notes with a vision model, so searches find them by what they show
+type ImageCaptionConfig struct {
+	// Enabled describes images when their notes are indexed
+	Enabled bool `json:"enabled"`
+	// Model is the chat model that describes images; it uses the LLM OpenAI
+	// settings
+	Model string `json:"model"`
+}
+
+// FeedsConfig holds the settings of RSS/Atom feed polling. The subscribed
+// feeds themselves are stored in the vault database.
+type FeedsConfig struct {
+	// Enabled polls subscribed feeds on a schedule
+	Enabled bool `json:"enabled"`
+	// IntervalMinutes is the time between polls
+	IntervalMinutes int `json:"interval_minutes"`
+	// Folder holds a subfolder of entry notes per feed
+	Folder string `json:"folder"`
+	// MaxEntries bounds the new entries written per feed and poll
+	MaxEntries int `json:"max_entries"`
+}
+
+// APIServerConfig holds the settings of the local REST API used by browser
+// extensions, launchers and scripts
+type APIServerConfig struct {
+	Enabled bool `json:"enabled"`
+	// Port on 127.0.0.1 the server listens on
+	Port int `json:"port"`
+	// Token that clients send as "Authorization: Bearer <token>"
+	Token string `json:"token"`
+}
+
+// NetworkConfig holds the proxy and TLS settings of AI provider requests
+type NetworkConfig struct {
+	// ProxyURL routes requests through an HTTP(S) proxy, e.g.
+	// "http://proxy.corp:8080" ("" = use the HTTP(S)_PROXY environment)
+	ProxyURL string `json:"proxy_url"`
+
+	// CABundlePath is a PEM file of extra certificate authorities trusted in
+	// addition to the system ones, for TLS-inspecting proxies
+	CABundlePath string `json:"ca_bundle_path"`
+
+	// InsecureSkipVerify disables TLS certificate verification entirely
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+// DigestConfig holds the periodic digest settings
+type DigestConfig struct {
+	// Enabled turns on the scheduled digest
+	Enabled bool `json:"enabled"`
+
+	// Days is how far back the digest looks for created or modified notes
+	Days int `json:"days"`
+
+	// Folder is where digest notes are written, relative to the vault root
+	Folder string `json:"folder"`
+
+	// Weekday is the day the scheduled digest runs (0 = Sunday ... 6 = Saturday)
+	Weekday int `json:"weekday"`
+
+	// Time is the local time of day to run at, in 24h "HH:MM" format
+	Time string `json:"time"`
 }
 
 var (
@@ -211,15 +612,20 @@ func (c *Config) setDefaults() {
 	c.AI.BatchSize = 32
 	c.AI.VectorSearchEngine = "brute-force"
 	c.AI.VectorDimension = 1536 // Default for text-embedding-3-small
+	c.AI.SimilarityMetric = SimilarityCosine
 
 	// OpenAI Defaults
 	c.AI.OpenAI.EmbeddingModel = "text-embedding-3-small"
 	c.AI.OpenAI.BaseURL = "https://api.openai.com/v1"
+	c.AI.OpenAI.RateLimit = RateLimitConfig{RequestsPerMinute: 3000, TokensPerMinute: 1000000, MaxConcurrency: 4}
 
 	// Ollama Defaults
 	c.AI.Ollama.BaseURL = "http://localhost:11434"
 	c.AI.Ollama.EmbeddingModel = "nomic-embed-text"
 	c.AI.Ollama.Timeout = 30
+	c.AI.Custom.Timeout = 60
+	c.AI.HealthCheckInterval = 60
+	c.AI.Builtin.DownloadURL = "https://huggingface.co/sentence-transformers/all-MiniLM-L6-v2/resolve/main"
 
 	// Set default model based on provider
 	c.AI.EmbeddingModel = c.AI.Ollama.EmbeddingModel
@@ -244,10 +650,15 @@ func (c *Config) setDefaults() {
 	c.LLM.Model = "gpt-4o-mini"
 	c.LLM.Temperature = 0.7
 	c.LLM.MaxTokens = 2000
+	c.LLM.Cache = LLMCacheConfig{TTLMinutes: 24 * 60, MaxEntries: 500}
+	c.LLM.OpenAI.RateLimit = RateLimitConfig{RequestsPerMinute: 500, TokensPerMinute: 200000, MaxConcurrency: 2}
 
 	// RAG Defaults
 	c.RAG.MaxContextChunks = 5
 	c.RAG.Temperature = 0.7
+	c.RAG.MinSimilarity = 0.3
+	c.RAG.NoContextBehavior = NoContextRespond
+	c.RAG.QueryRewrite = QueryRewriteOff
 	// SystemPrompt set at runtime, uses ai.DefaultSystemPrompt as default
 
 	// Graph Defaults
@@ -259,6 +670,55 @@ func (c *Config) setDefaults() {
 	c.Indexing.WorkerCount = 4
 	c.Indexing.QueueSize = 100
 	c.Indexing.MigrationBatchSize = 500
+	c.Indexing.Schedule.Time = "03:00"
+	c.Indexing.Schedule.OnlyWhenIdle = true
+	c.Indexing.Schedule.IdleMinutes = 10
+	c.Indexing.Schedule.OnlyOnACPower = true
+
+	// Notes Defaults
+	c.Notes.DedupeNames = true
+	c.Notes.FsyncOnSave = true
+	c.Notes.BibliographyFile = "references.bib"
+
+	// Digest Defaults
+	c.Digest.Days = 7
+	c.Digest.Folder = "Digests"
+	c.Digest.Weekday = int(time.Monday)
+	c.Digest.Time = "09:00"
+
+	// API Server Defaults (disabled until the user turns it on)
+	c.APIServer.Port = 27123
+
+	// Sync Defaults
+	c.Sync.Git.DebounceSeconds = 60
+
+	// Backup Defaults
+	c.Backup.Region = "us-east-1"
+	c.Backup.Prefix = "notebit"
+	c.Backup.IntervalHours = 24
+	c.Backup.FullEveryDays = 7
+
+	// Vault Lock Defaults
+	c.VaultLock.AutoLockMinutes = 15
+
+	// Transcription Defaults
+	c.Transcription.Provider = TranscriptionWhisperCpp
+	c.Transcription.WhisperBinary = "whisper-cli"
+	c.Transcription.OpenAIModel = "whisper-1"
+
+	// OCR Defaults
+	c.OCR.Provider = OCRTesseract
+	c.OCR.TesseractBinary = "tesseract"
+	c.OCR.Languages = "eng"
+	c.OCR.VisionModel = "gpt-4o-mini"
+
+	// Image Caption Defaults
+	c.ImageCaptions.Model = "gpt-4o-mini"
+
+	// Feed Defaults
+	c.Feeds.IntervalMinutes = 60
+	c.Feeds.Folder = "Feeds"
+	c.Feeds.MaxEntries = 20
 }
 
 // LoadFromFile loads configuration from a JSON file
@@ -267,72 +727,76 @@ func (c *Config) LoadFromFile(path string) error {
 	defer c.mu.Unlock()
 
 	c.configPath = path
+	c.restoreGlobalSections()
 
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			// File doesn't exist, use defaults
+			c.reapplyVaultOverrides()
+			c.saved, _ = c.globalJSON()
 			return nil
 		}
 		return err
 	}
 
-	// Detect which fields are explicitly set in JSON using raw map
-	var rawMap map[string]json.RawMessage
-	if err := json.Unmarshal(data, &rawMap); err != nil {
-		return err
-	}
-
 	// Create a temporary config to unmarshal into
 	temp := Config{}
 	if err := json.Unmarshal(data, &temp); err != nil {
 		return err
 	}
-
-	// Track which top-level sections exist in the JSON
-	_, hasChunking := rawMap["chunking"]
-	_, hasWatcher := rawMap["watcher"]
-	_, hasGraph := rawMap["graph"]
-	_, hasAI := rawMap["ai"]
-
-	// Parse sub-fields to detect boolean presence
-	var chunkingRaw, watcherRaw, graphRaw, aiRaw map[string]json.RawMessage
-	if hasChunking {
-		_ = json.Unmarshal(rawMap["chunking"], &chunkingRaw)
-	}
-	if hasWatcher {
-		_ = json.Unmarshal(rawMap["watcher"], &watcherRaw)
-	}
-	if hasGraph {
-		_ = json.Unmarshal(rawMap["graph"], &graphRaw)
-	}
-	if hasAI {
-		_ = json.Unmarshal(rawMap["ai"], &aiRaw)
+	present, err := jsonPresence(data)
+	if err != nil {
+		return err
 	}
 
-	// Merge with defaults (keep defaults for unset fields)
-	c.mergeWithDefaults(&temp, chunkingRaw, watcherRaw, graphRaw, aiRaw)
+	// Merge with defaults (keep defaults for keys absent from the file)
+	c.mergeWithDefaults(&temp, present)
+	c.reapplyVaultOverrides()
+	c.saved, _ = c.globalJSON()
 
 	return nil
 }
 
-// SaveToFile saves the current configuration to a JSON file
+// SaveToFile saves the current configuration to a JSON file. The OnSave
+// callback receives the settings changed since the last load or save.
 func (c *Config) SaveToFile(path string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
 
 	// Ensure directory exists
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.mu.Unlock()
 		return err
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := c.globalJSON()
 	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		c.mu.Unlock()
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	var changes []Change
+	if c.saved != nil {
+		changes = diffConfigJSON(c.saved, data)
+	}
+	c.saved = data
+	onSave := c.onSave
+	c.mu.Unlock()
+
+	if onSave != nil && len(changes) > 0 {
+		onSave(changes)
+	}
+	return nil
 }
 
 // Save saves the configuration to the last loaded path
@@ -346,147 +810,496 @@ func (c *Config) Save() error {
 	return c.SaveToFile(path)
 }
 
-// mergeWithDefaults merges loaded config with defaults.
-// Boolean fields are only updated when explicitly present in JSON (raw maps) to prevent
-// false zero-values from overwriting true defaults.
-func (c *Config) mergeWithDefaults(loaded *Config, chunkingRaw, watcherRaw, graphRaw, aiRaw map[string]json.RawMessage) {
+// mergeWithDefaults merges loaded config with defaults. A field is only
+// taken from loaded when its key is present in the file, so partial configs
+// keep the defaults of everything they leave out, including false booleans
+// and zero numbers. Present values that fail validation are ignored too.
+func (c *Config) mergeWithDefaults(loaded *Config, p presence) {
 	// AI Provider
-	if loaded.AI.Provider != "" {
+	if p.has("ai.provider") && loaded.AI.Provider != "" {
 		c.AI.Provider = loaded.AI.Provider
 	}
 
 	// OpenAI Config
-	if loaded.AI.OpenAI.APIKey != "" {
+	if p.has("ai.openai.api_key") {
 		c.AI.OpenAI.APIKey = loaded.AI.OpenAI.APIKey
 	}
-	if loaded.AI.OpenAI.BaseURL != "" {
+	if p.has("ai.openai.base_url") && loaded.AI.OpenAI.BaseURL != "" {
 		c.AI.OpenAI.BaseURL = loaded.AI.OpenAI.BaseURL
 	}
-	if loaded.AI.OpenAI.Organization != "" {
+	if p.has("ai.openai.organization") {
 		c.AI.OpenAI.Organization = loaded.AI.OpenAI.Organization
 	}
-	if loaded.AI.OpenAI.EmbeddingModel != "" {
+	if p.has("ai.openai.embedding_model") && loaded.AI.OpenAI.EmbeddingModel != "" {
 		c.AI.OpenAI.EmbeddingModel = loaded.AI.OpenAI.EmbeddingModel
 	}
+	mergeRateLimit(&c.AI.OpenAI.RateLimit, loaded.AI.OpenAI.RateLimit, p, "ai.openai.rate_limit")
 
 	// Ollama Config
-	if loaded.AI.Ollama.BaseURL != "" {
+	if p.has("ai.ollama.base_url") && loaded.AI.Ollama.BaseURL != "" {
 		c.AI.Ollama.BaseURL = loaded.AI.Ollama.BaseURL
 	}
-	if loaded.AI.Ollama.EmbeddingModel != "" {
+	if p.has("ai.ollama.embedding_model") && loaded.AI.Ollama.EmbeddingModel != "" {
 		c.AI.Ollama.EmbeddingModel = loaded.AI.Ollama.EmbeddingModel
 	}
-	if loaded.AI.Ollama.Timeout > 0 {
+	if p.has("ai.ollama.timeout") && loaded.AI.Ollama.Timeout > 0 {
 		c.AI.Ollama.Timeout = loaded.AI.Ollama.Timeout
 	}
+	mergeRateLimit(&c.AI.Ollama.RateLimit, loaded.AI.Ollama.RateLimit, p, "ai.ollama.rate_limit")
+
+	if p.has("ai.health_check_interval") && loaded.AI.HealthCheckInterval > 0 {
+		c.AI.HealthCheckInterval = loaded.AI.HealthCheckInterval
+	}
+
+	// Custom Config
+	if p.has("ai.custom.base_url") {
+		c.AI.Custom.BaseURL = loaded.AI.Custom.BaseURL
+	}
+	if p.has("ai.custom.api_key") {
+		c.AI.Custom.APIKey = loaded.AI.Custom.APIKey
+	}
+	if p.has("ai.custom.embedding_model") {
+		c.AI.Custom.EmbeddingModel = loaded.AI.Custom.EmbeddingModel
+	}
+	if p.has("ai.custom.timeout") && loaded.AI.Custom.Timeout > 0 {
+		c.AI.Custom.Timeout = loaded.AI.Custom.Timeout
+	}
+	mergeRateLimit(&c.AI.Custom.RateLimit, loaded.AI.Custom.RateLimit, p, "ai.custom.rate_limit")
+
+	// Builtin Config
+	if p.has("ai.builtin.model_dir") {
+		c.AI.Builtin.ModelDir = loaded.AI.Builtin.ModelDir
+	}
+	if p.has("ai.builtin.download_url") && loaded.AI.Builtin.DownloadURL != "" {
+		c.AI.Builtin.DownloadURL = loaded.AI.Builtin.DownloadURL
+	}
 
 	// AI Config
-	if loaded.AI.EmbeddingModel != "" {
+	if p.has("ai.embedding_model") && loaded.AI.EmbeddingModel != "" {
 		c.AI.EmbeddingModel = loaded.AI.EmbeddingModel
 	}
-	if loaded.AI.BatchSize > 0 {
+	if p.has("ai.batch_size") && loaded.AI.BatchSize > 0 {
 		c.AI.BatchSize = loaded.AI.BatchSize
 	}
-	if loaded.AI.VectorSearchEngine != "" {
+	if p.has("ai.vector_search_engine") && loaded.AI.VectorSearchEngine != "" {
 		c.AI.VectorSearchEngine = loaded.AI.VectorSearchEngine
 	}
-	if _, ok := aiRaw["vector_dimension"]; ok && loaded.AI.VectorDimension > 0 {
+	if p.has("ai.vector_dimension") && loaded.AI.VectorDimension > 0 {
 		c.AI.VectorDimension = loaded.AI.VectorDimension
 	}
+	if p.has("ai.normalize_embeddings") {
+		c.AI.NormalizeEmbeddings = loaded.AI.NormalizeEmbeddings
+	}
+	switch loaded.AI.SimilarityMetric {
+	case SimilarityCosine, SimilarityDot, SimilarityEuclidean:
+		c.AI.SimilarityMetric = loaded.AI.SimilarityMetric
+	}
+	if p.has("ai.language_models") {
+		c.AI.LanguageModels = cleanLanguageModels(loaded.AI.LanguageModels)
+	}
+	if p.has("ai.debug") {
+		c.AI.Debug = loaded.AI.Debug
+	}
+	if p.has("ai.debug_full_text") {
+		c.AI.DebugFullText = loaded.AI.DebugFullText
+	}
 
 	// Chunking Config
-	if loaded.Chunking.Strategy != "" {
+	if p.has("chunking.strategy") && loaded.Chunking.Strategy != "" {
 		c.Chunking.Strategy = loaded.Chunking.Strategy
 	}
-	if loaded.Chunking.ChunkSize > 0 {
+	if p.has("chunking.chunk_size") && loaded.Chunking.ChunkSize > 0 {
 		c.Chunking.ChunkSize = loaded.Chunking.ChunkSize
 	}
-	if loaded.Chunking.ChunkOverlap >= 0 {
+	if p.has("chunking.chunk_overlap") && loaded.Chunking.ChunkOverlap >= 0 {
 		c.Chunking.ChunkOverlap = loaded.Chunking.ChunkOverlap
 	}
-	if loaded.Chunking.MinChunkSize > 0 {
+	if p.has("chunking.min_chunk_size") && loaded.Chunking.MinChunkSize > 0 {
 		c.Chunking.MinChunkSize = loaded.Chunking.MinChunkSize
 	}
-	if loaded.Chunking.MaxChunkSize > 0 {
+	if p.has("chunking.max_chunk_size") && loaded.Chunking.MaxChunkSize > 0 {
 		c.Chunking.MaxChunkSize = loaded.Chunking.MaxChunkSize
 	}
-	// Always load boolean and string values - only override if explicitly set in JSON
-	if _, ok := chunkingRaw["preserve_heading"]; ok {
+	if p.has("chunking.preserve_heading") {
 		c.Chunking.PreserveHeading = loaded.Chunking.PreserveHeading
 	}
-	if loaded.Chunking.HeadingSeparator != "" {
+	if p.has("chunking.heading_separator") && loaded.Chunking.HeadingSeparator != "" {
 		c.Chunking.HeadingSeparator = loaded.Chunking.HeadingSeparator
 	}
+	if p.has("chunking.strip_math_for_embedding") {
+		c.Chunking.StripMathForEmbedding = loaded.Chunking.StripMathForEmbedding
+	}
 
-	// Watcher Config - only override booleans if explicitly set in JSON
-	if _, ok := watcherRaw["enabled"]; ok {
+	// Watcher Config
+	if p.has("watcher.enabled") {
 		c.Watcher.Enabled = loaded.Watcher.Enabled
 	}
-	if loaded.Watcher.DebounceMS > 0 {
+	if p.has("watcher.debounce_ms") && loaded.Watcher.DebounceMS > 0 {
 		c.Watcher.DebounceMS = loaded.Watcher.DebounceMS
 	}
-	if loaded.Watcher.Workers > 0 {
+	if p.has("watcher.workers") && loaded.Watcher.Workers > 0 {
 		c.Watcher.Workers = loaded.Watcher.Workers
 	}
-	if _, ok := watcherRaw["full_index_on_start"]; ok {
+	if p.has("watcher.full_index_on_start") {
 		c.Watcher.FullIndexOnStart = loaded.Watcher.FullIndexOnStart
 	}
 
 	// LLM Config
-	if loaded.LLM.Provider != "" {
+	if p.has("llm.provider") && loaded.LLM.Provider != "" {
 		c.LLM.Provider = loaded.LLM.Provider
 	}
-	if loaded.LLM.Model != "" {
+	if p.has("llm.model") && loaded.LLM.Model != "" {
 		c.LLM.Model = loaded.LLM.Model
 	}
-	if loaded.LLM.Temperature >= 0 {
+	if p.has("llm.temperature") && loaded.LLM.Temperature >= 0 && loaded.LLM.Temperature <= 2 {
 		c.LLM.Temperature = loaded.LLM.Temperature
 	}
-	if loaded.LLM.MaxTokens > 0 {
+	if p.has("llm.max_tokens") && loaded.LLM.MaxTokens > 0 {
 		c.LLM.MaxTokens = loaded.LLM.MaxTokens
 	}
 	// LLM OpenAI
-	if loaded.LLM.OpenAI.APIKey != "" {
+	if p.has("llm.openai.api_key") {
 		c.LLM.OpenAI.APIKey = loaded.LLM.OpenAI.APIKey
 	}
-	if loaded.LLM.OpenAI.BaseURL != "" {
+	if p.has("llm.openai.base_url") {
 		c.LLM.OpenAI.BaseURL = loaded.LLM.OpenAI.BaseURL
 	}
-	if loaded.LLM.OpenAI.Organization != "" {
+	if p.has("llm.openai.organization") {
 		c.LLM.OpenAI.Organization = loaded.LLM.OpenAI.Organization
 	}
+	mergeRateLimit(&c.LLM.OpenAI.RateLimit, loaded.LLM.OpenAI.RateLimit, p, "llm.openai.rate_limit")
+	if p.has("llm.cache.enabled") {
+		c.LLM.Cache.Enabled = loaded.LLM.Cache.Enabled
+	}
+	if p.has("llm.cache.ttl_minutes") && loaded.LLM.Cache.TTLMinutes > 0 {
+		c.LLM.Cache.TTLMinutes = loaded.LLM.Cache.TTLMinutes
+	}
+	if p.has("llm.cache.max_entries") && loaded.LLM.Cache.MaxEntries > 0 {
+		c.LLM.Cache.MaxEntries = loaded.LLM.Cache.MaxEntries
+	}
 	// LLM Ollama
-	if loaded.LLM.Ollama.BaseURL != "" {
+	if p.has("llm.ollama.base_url") {
 		c.LLM.Ollama.BaseURL = loaded.LLM.Ollama.BaseURL
 	}
-	if loaded.LLM.Ollama.EmbeddingModel != "" {
+	if p.has("llm.ollama.embedding_model") {
 		c.LLM.Ollama.EmbeddingModel = loaded.LLM.Ollama.EmbeddingModel
 	}
-	if loaded.LLM.Ollama.Timeout > 0 {
+	if p.has("llm.ollama.timeout") && loaded.LLM.Ollama.Timeout > 0 {
 		c.LLM.Ollama.Timeout = loaded.LLM.Ollama.Timeout
 	}
+	mergeRateLimit(&c.LLM.Ollama.RateLimit, loaded.LLM.Ollama.RateLimit, p, "llm.ollama.rate_limit")
 
 	// RAG Config
-	if loaded.RAG.MaxContextChunks > 0 {
+	if p.has("rag.max_context_chunks") && loaded.RAG.MaxContextChunks > 0 {
 		c.RAG.MaxContextChunks = loaded.RAG.MaxContextChunks
 	}
-	if loaded.RAG.Temperature >= 0 {
+	if p.has("rag.temperature") && loaded.RAG.Temperature >= 0 && loaded.RAG.Temperature <= 2 {
 		c.RAG.Temperature = loaded.RAG.Temperature
 	}
-	if loaded.RAG.SystemPrompt != "" {
+	if p.has("rag.system_prompt") {
 		c.RAG.SystemPrompt = loaded.RAG.SystemPrompt
 	}
+	// 0 is a meaningful threshold (keep every chunk)
+	if p.has("rag.min_similarity") && loaded.RAG.MinSimilarity >= 0 && loaded.RAG.MinSimilarity <= 1 {
+		c.RAG.MinSimilarity = loaded.RAG.MinSimilarity
+	}
+	switch loaded.RAG.NoContextBehavior {
+	case NoContextRespond, NoContextAnswer:
+		c.RAG.NoContextBehavior = loaded.RAG.NoContextBehavior
+	}
+	if p.has("rag.multi_query") && loaded.RAG.MultiQuery >= 0 && loaded.RAG.MultiQuery <= MaxMultiQuery {
+		c.RAG.MultiQuery = loaded.RAG.MultiQuery
+	}
+	if p.has("rag.follow_ups") {
+		c.RAG.FollowUps = loaded.RAG.FollowUps
+	}
+	switch loaded.RAG.QueryRewrite {
+	case QueryRewriteOff, QueryRewriteRewrite, QueryRewriteHyDE:
+		c.RAG.QueryRewrite = loaded.RAG.QueryRewrite
+	}
 
 	// Graph Config
-	if loaded.Graph.MinSimilarityThreshold >= 0 {
+	if p.has("graph.min_similarity_threshold") && loaded.Graph.MinSimilarityThreshold >= 0 && loaded.Graph.MinSimilarityThreshold <= 1 {
 		c.Graph.MinSimilarityThreshold = loaded.Graph.MinSimilarityThreshold
 	}
-	if loaded.Graph.MaxNodes > 0 {
+	if p.has("graph.max_nodes") && loaded.Graph.MaxNodes > 0 {
 		c.Graph.MaxNodes = loaded.Graph.MaxNodes
 	}
-	if _, ok := graphRaw["show_implicit_links"]; ok {
+	if p.has("graph.show_implicit_links") {
 		c.Graph.ShowImplicitLinks = loaded.Graph.ShowImplicitLinks
 	}
+	if p.has("graph.show_folder_nodes") {
+		c.Graph.ShowFolderNodes = loaded.Graph.ShowFolderNodes
+	}
+	if p.has("graph.show_temporal_links") {
+		c.Graph.ShowTemporalLinks = loaded.Graph.ShowTemporalLinks
+	}
+
+	// Indexing Config
+	if p.has("indexing.worker_count") && loaded.Indexing.WorkerCount > 0 {
+		c.Indexing.WorkerCount = loaded.Indexing.WorkerCount
+	}
+	if p.has("indexing.queue_size") && loaded.Indexing.QueueSize > 0 {
+		c.Indexing.QueueSize = loaded.Indexing.QueueSize
+	}
+	if p.has("indexing.migration_batch_size") && loaded.Indexing.MigrationBatchSize > 0 {
+		c.Indexing.MigrationBatchSize = loaded.Indexing.MigrationBatchSize
+	}
+	if p.has("indexing.auto_optimize_mb") && loaded.Indexing.AutoOptimizeMB >= 0 {
+		c.Indexing.AutoOptimizeMB = loaded.Indexing.AutoOptimizeMB
+	}
+	if p.has("indexing.schedule.enabled") {
+		c.Indexing.Schedule.Enabled = loaded.Indexing.Schedule.Enabled
+	}
+	if p.has("indexing.schedule.time") && loaded.Indexing.Schedule.Time != "" {
+		c.Indexing.Schedule.Time = loaded.Indexing.Schedule.Time
+	}
+	if p.has("indexing.schedule.only_when_idle") {
+		c.Indexing.Schedule.OnlyWhenIdle = loaded.Indexing.Schedule.OnlyWhenIdle
+	}
+	if p.has("indexing.schedule.idle_minutes") && loaded.Indexing.Schedule.IdleMinutes > 0 {
+		c.Indexing.Schedule.IdleMinutes = loaded.Indexing.Schedule.IdleMinutes
+	}
+	if p.has("indexing.schedule.only_on_ac_power") {
+		c.Indexing.Schedule.OnlyOnACPower = loaded.Indexing.Schedule.OnlyOnACPower
+	}
+
+	// Notes Config ("" places notes at the vault root / disables date folders)
+	if p.has("notes.inbox_folder") {
+		c.Notes.InboxFolder = loaded.Notes.InboxFolder
+	}
+	if p.has("notes.date_folder_format") {
+		c.Notes.DateFolderFormat = loaded.Notes.DateFolderFormat
+	}
+	if p.has("notes.slugify_titles") {
+		c.Notes.SlugifyTitles = loaded.Notes.SlugifyTitles
+	}
+	if p.has("notes.dedupe_names") {
+		c.Notes.DedupeNames = loaded.Notes.DedupeNames
+	}
+	if p.has("notes.fsync_on_save") {
+		c.Notes.FsyncOnSave = loaded.Notes.FsyncOnSave
+	}
+	if p.has("notes.keep_backup") {
+		c.Notes.KeepBackup = loaded.Notes.KeepBackup
+	}
+	if p.has("notes.bibliography_file") && loaded.Notes.BibliographyFile != "" {
+		c.Notes.BibliographyFile = loaded.Notes.BibliographyFile
+	}
+
+	// Digest Config
+	if p.has("digest.enabled") {
+		c.Digest.Enabled = loaded.Digest.Enabled
+	}
+	if p.has("digest.days") && loaded.Digest.Days > 0 {
+		c.Digest.Days = loaded.Digest.Days
+	}
+	if p.has("digest.folder") && loaded.Digest.Folder != "" {
+		c.Digest.Folder = loaded.Digest.Folder
+	}
+	if p.has("digest.weekday") && loaded.Digest.Weekday >= 0 && loaded.Digest.Weekday <= 6 {
+		c.Digest.Weekday = loaded.Digest.Weekday
+	}
+	if p.has("digest.time") && loaded.Digest.Time != "" {
+		c.Digest.Time = loaded.Digest.Time
+	}
+
+	// Network Config
+	if p.has("network.proxy_url") {
+		c.Network.ProxyURL = loaded.Network.ProxyURL
+	}
+	if p.has("network.ca_bundle_path") {
+		c.Network.CABundlePath = loaded.Network.CABundlePath
+	}
+	if p.has("network.insecure_skip_verify") {
+		c.Network.InsecureSkipVerify = loaded.Network.InsecureSkipVerify
+	}
+
+	// API Server Config
+	if p.has("api_server.enabled") {
+		c.APIServer.Enabled = loaded.APIServer.Enabled
+	}
+	if p.has("api_server.port") && loaded.APIServer.Port > 0 && loaded.APIServer.Port < 65536 {
+		c.APIServer.Port = loaded.APIServer.Port
+	}
+	if p.has("api_server.token") {
+		c.APIServer.Token = loaded.APIServer.Token
+	}
+
+	// Sync Config
+	if p.has("sync.git.auto_commit") {
+		c.Sync.Git.AutoCommit = loaded.Sync.Git.AutoCommit
+	}
+	if p.has("sync.git.debounce_seconds") && loaded.Sync.Git.DebounceSeconds > 0 {
+		c.Sync.Git.DebounceSeconds = loaded.Sync.Git.DebounceSeconds
+	}
+	if p.has("sync.git.auto_push") {
+		c.Sync.Git.AutoPush = loaded.Sync.Git.AutoPush
+	}
+	if p.has("sync.git.pull_on_open") {
+		c.Sync.Git.PullOnOpen = loaded.Sync.Git.PullOnOpen
+	}
+	if p.has("sync.webdav.enabled") {
+		c.Sync.WebDAV.Enabled = loaded.Sync.WebDAV.Enabled
+	}
+	if p.has("sync.webdav.url") {
+		c.Sync.WebDAV.URL = loaded.Sync.WebDAV.URL
+	}
+	if p.has("sync.webdav.username") {
+		c.Sync.WebDAV.Username = loaded.Sync.WebDAV.Username
+	}
+	if p.has("sync.webdav.password") {
+		c.Sync.WebDAV.Password = loaded.Sync.WebDAV.Password
+	}
+	if p.has("sync.webdav.folders") {
+		c.Sync.WebDAV.Folders = loaded.Sync.WebDAV.Folders
+	}
+
+	// Backup Config
+	if p.has("backup.enabled") {
+		c.Backup.Enabled = loaded.Backup.Enabled
+	}
+	if p.has("backup.endpoint") {
+		c.Backup.Endpoint = loaded.Backup.Endpoint
+	}
+	if p.has("backup.region") && loaded.Backup.Region != "" {
+		c.Backup.Region = loaded.Backup.Region
+	}
+	if p.has("backup.bucket") {
+		c.Backup.Bucket = loaded.Backup.Bucket
+	}
+	if p.has("backup.prefix") {
+		c.Backup.Prefix = loaded.Backup.Prefix
+	}
+	if p.has("backup.access_key_id") {
+		c.Backup.AccessKeyID = loaded.Backup.AccessKeyID
+	}
+	if p.has("backup.secret_access_key") {
+		c.Backup.SecretAccessKey = loaded.Backup.SecretAccessKey
+	}
+	if p.has("backup.passphrase") {
+		c.Backup.Passphrase = loaded.Backup.Passphrase
+	}
+	if p.has("backup.interval_hours") && loaded.Backup.IntervalHours >= 0 {
+		c.Backup.IntervalHours = loaded.Backup.IntervalHours
+	}
+	if p.has("backup.full_every_days") && loaded.Backup.FullEveryDays > 0 {
+		c.Backup.FullEveryDays = loaded.Backup.FullEveryDays
+	}
+
+	// Vault Lock Config (0 disables auto-lock)
+	if p.has("vault_lock.auto_lock_minutes") && loaded.VaultLock.AutoLockMinutes >= 0 {
+		c.VaultLock.AutoLockMinutes = loaded.VaultLock.AutoLockMinutes
+	}
+
+	// Transcription Config
+	if p.has("transcription.enabled") {
+		c.Transcription.Enabled = loaded.Transcription.Enabled
+	}
+	switch loaded.Transcription.Provider {
+	case TranscriptionWhisperCpp, TranscriptionOpenAI:
+		c.Transcription.Provider = loaded.Transcription.Provider
+	}
+	if p.has("transcription.whisper_binary") && loaded.Transcription.WhisperBinary != "" {
+		c.Transcription.WhisperBinary = loaded.Transcription.WhisperBinary
+	}
+	if p.has("transcription.whisper_model") {
+		c.Transcription.WhisperModel = loaded.Transcription.WhisperModel
+	}
+	if p.has("transcription.openai_model") && loaded.Transcription.OpenAIModel != "" {
+		c.Transcription.OpenAIModel = loaded.Transcription.OpenAIModel
+	}
+	if p.has("transcription.language") {
+		c.Transcription.Language = strings.ToLower(strings.TrimSpace(loaded.Transcription.Language))
+	}
+
+	// OCR Config
+	if p.has("ocr.enabled") {
+		c.OCR.Enabled = loaded.OCR.Enabled
+	}
+	switch loaded.OCR.Provider {
+	case OCRTesseract, OCRVision:
+		c.OCR.Provider = loaded.OCR.Provider
+	}
+	if p.has("ocr.tesseract_binary") && loaded.OCR.TesseractBinary != "" {
+		c.OCR.TesseractBinary = loaded.OCR.TesseractBinary
+	}
+	if p.has("ocr.languages") && loaded.OCR.Languages != "" {
+		c.OCR.Languages = loaded.OCR.Languages
+	}
+	if p.has("ocr.vision_model") && loaded.OCR.VisionModel != "" {
+		c.OCR.VisionModel = loaded.OCR.VisionModel
+	}
+
+	// Image Caption Config
+	if p.has("image_captions.enabled") {
+		c.ImageCaptions.Enabled = loaded.ImageCaptions.Enabled
+	}
+	if p.has("image_captions.model") && loaded.ImageCaptions.Model != "" {
+		c.ImageCaptions.Model = loaded.ImageCaptions.Model
+	}
+
+	// Feed Config
+	if p.has("feeds.enabled") {
+		c.Feeds.Enabled = loaded.Feeds.Enabled
+	}
+	if p.has("feeds.interval_minutes") && loaded.Feeds.IntervalMinutes > 0 {
+		c.Feeds.IntervalMinutes = loaded.Feeds.IntervalMinutes
+	}
+	if p.has("feeds.folder") && loaded.Feeds.Folder != "" {
+		c.Feeds.Folder = loaded.Feeds.Folder
+	}
+	if p.has("feeds.max_entries") && loaded.Feeds.MaxEntries > 0 {
+		c.Feeds.MaxEntries = loaded.Feeds.MaxEntries
+	}
+}
+
+// mergeRateLimit copies the limits present under prefix; 0 means unlimited
+// and a negative value also disables a limit
+func mergeRateLimit(dst *RateLimitConfig, loaded RateLimitConfig, p presence, prefix string) {
+	if p.has(prefix + ".requests_per_minute") {
+		dst.RequestsPerMinute = loaded.RequestsPerMinute
+	}
+	if p.has(prefix + ".tokens_per_minute") {
+		dst.TokensPerMinute = loaded.TokensPerMinute
+	}
+	if p.has(prefix + ".max_concurrency") {
+		dst.MaxConcurrency = loaded.MaxConcurrency
+	}
+}
+
+// presence records the keys set in a config file as dotted paths, e.g.
+// "indexing.schedule.enabled"
+type presence map[string]bool
+
+func (p presence) has(path string) bool {
+	return p[path]
+}
+
+// jsonPresence collects the key paths of every object in a JSON document
+func jsonPresence(data []byte) (presence, error) {
+	p := presence{}
+	var walk func(prefix string, raw json.RawMessage)
+	walk = func(prefix string, raw json.RawMessage) {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) != nil {
+			return
+		}
+		for key, value := range obj {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			p[path] = true
+			walk(path, value)
+		}
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	walk("", data)
+	return p, nil
 }
 
 // SetOpenAIConfig sets the OpenAI configuration
@@ -554,6 +1367,60 @@ func (c *Config) GetEmbeddingModel() string {
 	return c.AI.EmbeddingModel
 }
 
+// GetLanguageModels returns the embedding model configured per chunk language
+func (c *Config) GetLanguageModels() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	models := make(map[string]string, len(c.AI.LanguageModels))
+	for lang, model := range c.AI.LanguageModels {
+		models[lang] = model
+	}
+	return models
+}
+
+// SetLanguageModels replaces the embedding models used per chunk language
+func (c *Config) SetLanguageModels(models map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.AI.LanguageModels = cleanLanguageModels(models)
+}
+
+// GetAIDebug reports whether AI debug mode is on
+func (c *Config) GetAIDebug() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.AI.Debug
+}
+
+// GetAIDebugFullText reports whether AI debug mode records whole texts
+func (c *Config) GetAIDebugFullText() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.AI.DebugFullText
+}
+
+// SetAIDebug turns AI debug mode on or off
+func (c *Config) SetAIDebug(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.AI.Debug = enabled
+}
+
+// cleanLanguageModels lowercases language codes and drops empty entries
+func cleanLanguageModels(models map[string]string) map[string]string {
+	cleaned := make(map[string]string, len(models))
+	for lang, model := range models {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		model = strings.TrimSpace(model)
+		if lang != "" && model != "" {
+			cleaned[lang] = model
+		}
+	}
+	return cleaned
+}
+
 // SetVectorSearchEngine sets the vector search engine name
 func (c *Config) SetVectorSearchEngine(engine string) {
 	c.mu.Lock()
@@ -570,6 +1437,23 @@ func (c *Config) GetVectorSearchEngine() string {
 	return c.AI.VectorSearchEngine
 }
 
+// SetVectorSpace sets embedding normalization and the similarity metric
+func (c *Config) SetVectorSpace(normalize bool, metric string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.AI.NormalizeEmbeddings = normalize
+	c.AI.SimilarityMetric = metric
+}
+
+// GetVectorSpace returns whether embeddings are normalized and the similarity metric
+func (c *Config) GetVectorSpace() (bool, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.AI.NormalizeEmbeddings, c.AI.SimilarityMetric
+}
+
 // GetOpenAIConfig returns a copy of the OpenAI configuration
 func (c *Config) GetOpenAIConfig() OpenAIConfig {
 	c.mu.RLock()
@@ -586,6 +1470,51 @@ func (c *Config) GetOllamaConfig() OllamaConfig {
 	return c.AI.Ollama
 }
 
+// GetHealthCheckInterval returns the provider health check interval in seconds
+func (c *Config) GetHealthCheckInterval() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.AI.HealthCheckInterval
+}
+
+// GetCustomConfig returns a copy of the custom provider configuration
+func (c *Config) GetCustomConfig() CustomConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.AI.Custom
+}
+
+// SetCustomConfig sets the custom provider connection; rate limits are kept
+func (c *Config) SetCustomConfig(baseURL, apiKey, embeddingModel string, timeout int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.AI.Custom.BaseURL = baseURL
+	c.AI.Custom.APIKey = apiKey
+	c.AI.Custom.EmbeddingModel = embeddingModel
+	if timeout > 0 {
+		c.AI.Custom.Timeout = timeout
+	}
+}
+
+// GetBuiltinConfig returns a copy of the builtin embedding model configuration
+func (c *Config) GetBuiltinConfig() BuiltinConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.AI.Builtin
+}
+
+// SetBuiltinConfig sets the builtin embedding model configuration
+func (c *Config) SetBuiltinConfig(cfg BuiltinConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.AI.Builtin = cfg
+}
+
 // GetChunkingConfig returns a copy of the chunking configuration
 func (c *Config) GetChunkingConfig() ChunkingConfig {
 	c.mu.RLock()
@@ -715,3 +1644,220 @@ func (c *Config) SetGraphConfig(cfg GraphConfig) {
 
 	c.Graph = cfg
 }
+
+// GetNotesConfig returns a copy of the new-note configuration
+func (c *Config) GetNotesConfig() NotesConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.Notes
+}
+
+// SetNotesConfig sets the new-note configuration
+func (c *Config) SetNotesConfig(cfg NotesConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Notes = cfg
+}
+
+// GetDigestConfig returns a copy of the digest configuration
+func (c *Config) GetDigestConfig() DigestConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.Digest
+}
+
+// SetDigestConfig sets the digest configuration
+func (c *Config) SetDigestConfig(cfg DigestConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Digest = cfg
+}
+
+// GetIndexingConfig returns a copy of the indexing configuration
+func (c *Config) GetIndexingConfig() IndexingConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.Indexing
+}
+
+// SetIndexScheduleConfig sets the periodic reindex schedule
+func (c *Config) SetIndexScheduleConfig(cfg IndexScheduleConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Indexing.Schedule = cfg
+}
+
+// SetAutoOptimizeMB sets the database growth that triggers an automatic optimization
+func (c *Config) SetAutoOptimizeMB(mb int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Indexing.AutoOptimizeMB = mb
+}
+
+// SetIndexTuning sets the throughput-related indexing settings in one step
+func (c *Config) SetIndexTuning(workerCount, batchSize, debounceMS, watcherWorkers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if workerCount > 0 {
+		c.Indexing.WorkerCount = workerCount
+	}
+	if batchSize > 0 {
+		c.AI.BatchSize = batchSize
+	}
+	if debounceMS > 0 {
+		c.Watcher.DebounceMS = debounceMS
+	}
+	if watcherWorkers > 0 {
+		c.Watcher.Workers = watcherWorkers
+	}
+}
+
+// GetNetworkConfig returns the network configuration
+func (c *Config) GetNetworkConfig() NetworkConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Network
+}
+
+// SetNetworkConfig sets the network configuration
+func (c *Config) SetNetworkConfig(cfg NetworkConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Network = cfg
+}
+
+// GetGitSyncConfig returns the git sync configuration
+func (c *Config) GetGitSyncConfig() GitSyncConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Sync.Git
+}
+
+// SetGitSyncConfig sets the git sync configuration
+func (c *Config) SetGitSyncConfig(cfg GitSyncConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sync.Git = cfg
+}
+
+// GetWebDAVSyncConfig returns the WebDAV sync configuration
+func (c *Config) GetWebDAVSyncConfig() WebDAVSyncConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cfg := c.Sync.WebDAV
+	cfg.Folders = append([]string(nil), cfg.Folders...)
+	return cfg
+}
+
+// SetWebDAVSyncConfig sets the WebDAV sync configuration
+func (c *Config) SetWebDAVSyncConfig(cfg WebDAVSyncConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Sync.WebDAV = cfg
+}
+
+// GetBackupConfig returns the vault backup configuration
+func (c *Config) GetBackupConfig() BackupConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Backup
+}
+
+// SetBackupConfig sets the vault backup configuration
+func (c *Config) SetBackupConfig(cfg BackupConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Backup = cfg
+}
+
+// GetVaultLockConfig returns the vault lock configuration
+func (c *Config) GetVaultLockConfig() VaultLockConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.VaultLock
+}
+
+// SetVaultLockConfig sets the vault lock configuration
+func (c *Config) SetVaultLockConfig(cfg VaultLockConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.VaultLock = cfg
+}
+
+// GetTranscriptionConfig returns the audio transcription configuration
+func (c *Config) GetTranscriptionConfig() TranscriptionConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Transcription
+}
+
+// SetTranscriptionConfig sets the audio transcription configuration
+func (c *Config) SetTranscriptionConfig(cfg TranscriptionConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Transcription = cfg
+}
+
+// GetOCRConfig returns the image text extraction configuration
+func (c *Config) GetOCRConfig() OCRConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.OCR
+}
+
+// SetOCRConfig sets the image text extraction configuration
+func (c *Config) SetOCRConfig(cfg OCRConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.OCR = cfg
+}
+
+// GetImageCaptionConfig returns the image description configuration
+func (c *Config) GetImageCaptionConfig() ImageCaptionConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ImageCaptions
+}
+
+// SetImageCaptionConfig sets the image description configuration
+func (c *Config) SetImageCaptionConfig(cfg ImageCaptionConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ImageCaptions = cfg
+}
+
+// GetFeedsConfig returns the feed polling configuration
+func (c *Config) GetFeedsConfig() FeedsConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Feeds
+}
+
+// SetFeedsConfig sets the feed polling configuration
+func (c *Config) SetFeedsConfig(cfg FeedsConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Feeds = cfg
+}
+
+// GetAPIServerConfig returns the local API server configuration
+func (c *Config) GetAPIServerConfig() APIServerConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.APIServer
+}
+
+// SetAPIServerConfig sets the local API server configuration
+func (c *Config) SetAPIServerConfig(cfg APIServerConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.APIServer = cfg
+}
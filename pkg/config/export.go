@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+)
+
+// RedactedSecret replaces secrets in exported configs. Importing a config
+// that still holds it keeps the secret already set on this machine.
+const RedactedSecret = "__REDACTED__"
+
+// secretFields returns the settings treated as secrets by Export and Import.
+// A proxy URL counts as one only when it embeds credentials.
+func (c *Config) secretFields() []*string {
+	return []*string{
+		&c.AI.OpenAI.APIKey,
+		&c.AI.Custom.APIKey,
+		&c.LLM.OpenAI.APIKey,
+		&c.Network.ProxyURL,
+		&c.APIServer.Token,
+		&c.Sync.WebDAV.Password,
+		&c.Backup.SecretAccessKey,
+		&c.Backup.Passphrase,
+	}
+}
+
+// Export returns the global config as indented JSON, without the overrides
+// of the open vault. Unless includeSecrets is set, API keys and proxy
+// credentials are replaced by RedactedSecret.
+func (c *Config) Export(includeSecrets bool) ([]byte, error) {
+	c.mu.RLock()
+	data, err := c.globalJSON()
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !includeSecrets {
+		var out Config
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, err
+		}
+		for _, secret := range out.secretFields() {
+			if *secret == "" {
+				continue
+			}
+			if secret == &out.Network.ProxyURL {
+				if u, err := url.Parse(*secret); err == nil && u.User == nil {
+					continue
+				}
+			}
+			*secret = RedactedSecret
+		}
+		if data, err = json.Marshal(&out); err != nil {
+			return nil, err
+		}
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// Import merges an exported config over the current one. Keys absent from
+// data keep their current values and redacted secrets are left unchanged.
+// The overrides of the open vault are applied again on top.
+func (c *Config) Import(data []byte) error {
+	loaded := Config{}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return err
+	}
+	present, err := jsonPresence(data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restoreGlobalSections()
+	current := c.secretFields()
+	for i, secret := range loaded.secretFields() {
+		if *secret == RedactedSecret {
+			*secret = *current[i]
+		}
+	}
+	c.mergeWithDefaults(&loaded, present)
+	c.reapplyVaultOverrides()
+	return nil
+}
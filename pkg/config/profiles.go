@@ -0,0 +1,226 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Profile is a named set of provider, model, endpoint and RAG settings that
+// can be applied in one step, e.g. cloud OpenAI at work and Ollama at home.
+// API keys are not part of a profile; they stay in the main config.
+type Profile struct {
+	Name string `json:"name"`
+
+	// Embedding provider and model
+	AIProvider     string `json:"ai_provider"`
+	EmbeddingModel string `json:"embedding_model"`
+
+	// Chat provider and model
+	LLMProvider string `json:"llm_provider"`
+	LLMModel    string `json:"llm_model"`
+
+	// Base URLs of the providers
+	OpenAIBaseURL    string `json:"openai_base_url"`
+	OllamaBaseURL    string `json:"ollama_base_url"`
+	CustomBaseURL    string `json:"custom_base_url"`
+	LLMOpenAIBaseURL string `json:"llm_openai_base_url"`
+
+	RAG RAGConfig `json:"rag"`
+}
+
+// CaptureProfile returns the current settings as a profile named name. The
+// RAG settings are the global ones, without the overrides of an open vault.
+func (c *Config) CaptureProfile(name string) Profile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rag := c.RAG
+	if c.vault != nil {
+		rag = c.vault.rag
+	}
+	return Profile{
+		Name:             name,
+		AIProvider:       c.AI.Provider,
+		EmbeddingModel:   c.AI.EmbeddingModel,
+		LLMProvider:      c.LLM.Provider,
+		LLMModel:         c.LLM.Model,
+		OpenAIBaseURL:    c.AI.OpenAI.BaseURL,
+		OllamaBaseURL:    c.AI.Ollama.BaseURL,
+		CustomBaseURL:    c.AI.Custom.BaseURL,
+		LLMOpenAIBaseURL: c.LLM.OpenAI.BaseURL,
+		RAG:              rag,
+	}
+}
+
+// ApplyProfile copies the settings of p into the config. The embedding model
+// is also set on the matching provider so it is used right away. With a
+// vault open, its RAG overrides stay in effect over the profile's.
+func (c *Config) ApplyProfile(p Profile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p.AIProvider != "" {
+		c.AI.Provider = p.AIProvider
+	}
+	if p.EmbeddingModel != "" {
+		c.AI.EmbeddingModel = p.EmbeddingModel
+		switch c.AI.Provider {
+		case "openai":
+			c.AI.OpenAI.EmbeddingModel = p.EmbeddingModel
+		case "ollama":
+			c.AI.Ollama.EmbeddingModel = p.EmbeddingModel
+		case "custom":
+			c.AI.Custom.EmbeddingModel = p.EmbeddingModel
+		}
+	}
+	if p.LLMProvider != "" {
+		c.LLM.Provider = p.LLMProvider
+	}
+	if p.LLMModel != "" {
+		c.LLM.Model = p.LLMModel
+	}
+	if p.OpenAIBaseURL != "" {
+		c.AI.OpenAI.BaseURL = p.OpenAIBaseURL
+	}
+	if p.OllamaBaseURL != "" {
+		c.AI.Ollama.BaseURL = p.OllamaBaseURL
+	}
+	// Empty is meaningful here: no custom server, or chat uses the AI OpenAI URL
+	c.AI.Custom.BaseURL = p.CustomBaseURL
+	c.LLM.OpenAI.BaseURL = p.LLMOpenAIBaseURL
+	if p.RAG.MaxContextChunks > 0 {
+		c.RAG = p.RAG
+		if c.vault != nil {
+			// The profile sets the global values, which the vault layer keeps
+			// and Save writes; the vault's own keys stay on top
+			c.vault.rag = p.RAG
+			if raw, ok := c.vault.overrides["rag"]; ok {
+				_ = json.Unmarshal(raw, &c.RAG)
+			}
+		}
+	}
+}
+
+// ProfileStore keeps named profiles in a JSON file next to the main config
+type ProfileStore struct {
+	mu       sync.RWMutex
+	path     string
+	profiles []Profile
+	active   string
+}
+
+// LoadProfileStore reads the profiles at path; a missing file yields an
+// empty store
+func LoadProfileStore(path string) (*ProfileStore, error) {
+	s := &ProfileStore{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	var stored struct {
+		Active   string    `json:"active"`
+		Profiles []Profile `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return s, err
+	}
+	s.profiles = stored.Profiles
+	s.active = stored.Active
+	return s, nil
+}
+
+// List returns the profiles sorted by name
+func (s *ProfileStore) List() []Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Profile, len(s.profiles))
+	copy(out, s.profiles)
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+// Active returns the name of the last applied profile ("" if none)
+func (s *ProfileStore) Active() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Get returns the profile called name
+func (s *ProfileStore) Get(name string) (Profile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Put adds p or replaces the profile with the same name
+func (s *ProfileStore) Put(p Profile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("profile name cannot be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.profiles {
+		if strings.EqualFold(s.profiles[i].Name, p.Name) {
+			s.profiles[i] = p
+			return s.save()
+		}
+	}
+	s.profiles = append(s.profiles, p)
+	return s.save()
+}
+
+// Delete removes the profile called name
+func (s *ProfileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.profiles {
+		if strings.EqualFold(s.profiles[i].Name, name) {
+			s.profiles = append(s.profiles[:i], s.profiles[i+1:]...)
+			if strings.EqualFold(s.active, name) {
+				s.active = ""
+			}
+			return s.save()
+		}
+	}
+	return errors.New("profile not found: " + name)
+}
+
+// SetActive records the last applied profile
+func (s *ProfileStore) SetActive(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = name
+	return s.save()
+}
+
+// save writes the store; callers hold s.mu
+func (s *ProfileStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(struct {
+		Active   string    `json:"active"`
+		Profiles []Profile `json:"profiles"`
+	}{s.active, s.profiles}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfilesCaptureApplyAndPersist(t *testing.T) {
+	cfg := New()
+	cfg.SetProvider("openai")
+	cfg.SetEmbeddingModel("text-embedding-3-large")
+	work := cfg.CaptureProfile("Work")
+
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store, err := LoadProfileStore(path)
+	if err != nil {
+		t.Fatalf("load empty store failed: %v", err)
+	}
+	if err := store.Put(work); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	if err := store.Put(Profile{Name: "Home", AIProvider: "ollama", EmbeddingModel: "mxbai-embed-large", OllamaBaseURL: "http://nas:11434"}); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	if err := store.SetActive("Home"); err != nil {
+		t.Fatalf("set active failed: %v", err)
+	}
+
+	reloaded, err := LoadProfileStore(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if list := reloaded.List(); len(list) != 2 || list[0].Name != "Home" || reloaded.Active() != "Home" {
+		t.Fatalf("unexpected stored profiles: %+v active=%q", list, reloaded.Active())
+	}
+
+	home, ok := reloaded.Get("home")
+	if !ok {
+		t.Fatal("profile lookup should ignore case")
+	}
+	cfg.ApplyProfile(home)
+	if cfg.GetProvider() != "ollama" || cfg.GetOllamaConfig().EmbeddingModel != "mxbai-embed-large" || cfg.GetOllamaConfig().BaseURL != "http://nas:11434" {
+		t.Fatalf("home profile not applied: provider=%s ollama=%+v", cfg.GetProvider(), cfg.GetOllamaConfig())
+	}
+	if cfg.GetRAGConfig() != New().GetRAGConfig() {
+		t.Fatalf("profile without RAG settings changed them: %+v", cfg.GetRAGConfig())
+	}
+
+	cfg.ApplyProfile(work)
+	if cfg.GetProvider() != "openai" || cfg.GetEmbeddingModel() != "text-embedding-3-large" {
+		t.Fatalf("work profile not applied: provider=%s model=%s", cfg.GetProvider(), cfg.GetEmbeddingModel())
+	}
+}
+
+func TestApplyProfileWithVaultOverridingRAG(t *testing.T) {
+	path := writeConfigFile(t, `{"rag": {"max_context_chunks": 5, "temperature": 0.7}}`)
+	vault := writeVaultConfig(t, `{"rag": {"temperature": 0.1}}`)
+
+	c := New()
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ApplyVaultOverrides(vault); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.CaptureProfile("Before").RAG; got.MaxContextChunks != 5 || got.Temperature != 0.7 {
+		t.Fatalf("captured RAG = %+v, want the global values", got)
+	}
+
+	rag := c.GetRAGConfig()
+	rag.MaxContextChunks = 12
+	rag.Temperature = 0.9
+	c.ApplyProfile(Profile{Name: "Deep", RAG: rag})
+
+	// The vault's temperature stays on top of the profile while it is open
+	if got := c.GetRAGConfig(); got.MaxContextChunks != 12 || got.Temperature != 0.1 {
+		t.Fatalf("RAG with the vault open = %+v, want 12 chunks at the vault's 0.1", got)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ApplyVaultOverrides(""); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetRAGConfig(); got.MaxContextChunks != 12 || got.Temperature != 0.9 {
+		t.Fatalf("RAG after closing the vault = %+v, want the profile's", got)
+	}
+
+	reloaded := New()
+	if err := reloaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.GetRAGConfig(); got.MaxContextChunks != 12 || got.Temperature != 0.9 {
+		t.Fatalf("saved RAG = %+v, want the profile's", got)
+	}
+}
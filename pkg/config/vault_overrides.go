@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// VaultConfigFile is the per-vault override file, relative to the vault root
+const VaultConfigFile = ".notebit/config.json"
+
+// vaultOverrideSections are the top-level sections a vault may override.
+// Provider credentials stay global so they are never stored inside a vault.
+var vaultOverrideSections = []string{"chunking", "watcher", "graph", "rag"}
+
+// vaultLayer remembers the global values of the sections a vault overrides,
+// so they can be restored on switching vaults and are what gets saved
+type vaultLayer struct {
+	path      string
+	sections  []string
+	overrides map[string]json.RawMessage // the vault file's value of each section
+	chunking  ChunkingConfig
+	watcher   WatcherConfig
+	graph     GraphConfig
+	rag       RAGConfig
+}
+
+// ApplyVaultOverrides layers the vault's .notebit/config.json over the
+// global config, replacing the overrides of any previously applied vault.
+//
+// Merge order, later wins: built-in defaults, the global config.json, then
+// the vault file. Within an overridable section only the keys present in the
+// vault file change, e.g. {"chunking": {"chunk_size": 500}} keeps every other
+// chunking setting from the global config. Overridden sections are not
+// written back by Save, so edits to them last until the vault is closed.
+//
+// The vault stays layered over later loads: LoadFromFile and Import apply
+// its file again on top of the reloaded global config.
+func (c *Config) ApplyVaultOverrides(vaultDir string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.vaultDir = vaultDir
+	return c.applyVaultOverrides()
+}
+
+// reapplyVaultOverrides layers the open vault over a freshly loaded global
+// config; callers hold c.mu. A broken vault file was already reported when
+// the vault was opened, so it only leaves the global values in place here.
+func (c *Config) reapplyVaultOverrides() {
+	_, _ = c.applyVaultOverrides()
+}
+
+// applyVaultOverrides layers the file of c.vaultDir; callers hold c.mu
+func (c *Config) applyVaultOverrides() ([]string, error) {
+	c.restoreGlobalSections()
+	if c.vaultDir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(c.vaultDir, filepath.FromSlash(VaultConfigFile))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var rawMap map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawMap); err != nil {
+		return nil, err
+	}
+
+	layer := &vaultLayer{
+		path:      path,
+		overrides: make(map[string]json.RawMessage),
+		chunking:  c.Chunking,
+		watcher:   c.Watcher,
+		graph:     c.Graph,
+		rag:       c.RAG,
+	}
+	for _, section := range vaultOverrideSections {
+		raw, ok := rawMap[section]
+		if !ok {
+			continue
+		}
+		// Unmarshalling onto the current values only replaces keys present in raw
+		var target any
+		switch section {
+		case "chunking":
+			target = &c.Chunking
+		case "watcher":
+			target = &c.Watcher
+		case "graph":
+			target = &c.Graph
+		case "rag":
+			target = &c.RAG
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			c.Chunking, c.Watcher, c.Graph, c.RAG = layer.chunking, layer.watcher, layer.graph, layer.rag
+			return nil, err
+		}
+		layer.sections = append(layer.sections, section)
+		layer.overrides[section] = raw
+	}
+	if len(layer.sections) == 0 {
+		return nil, nil
+	}
+	c.vault = layer
+	return append([]string(nil), layer.sections...), nil
+}
+
+// VaultOverrides returns the sections overridden by the current vault
+func (c *Config) VaultOverrides() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.vault == nil {
+		return []string{}
+	}
+	return append([]string(nil), c.vault.sections...)
+}
+
+// restoreGlobalSections drops the vault layer; callers hold c.mu
+func (c *Config) restoreGlobalSections() {
+	if c.vault == nil {
+		return
+	}
+	c.Chunking, c.Watcher, c.Graph, c.RAG = c.vault.chunking, c.vault.watcher, c.vault.graph, c.vault.rag
+	c.vault = nil
+}
+
+// globalJSON marshals the config with vault-overridden sections replaced by
+// their global values; callers hold c.mu
+func (c *Config) globalJSON() ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil || c.vault == nil {
+		return data, err
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, err
+	}
+	globals := map[string]any{
+		"chunking": c.vault.chunking,
+		"watcher":  c.vault.watcher,
+		"graph":    c.vault.graph,
+		"rag":      c.vault.rag,
+	}
+	for _, section := range c.vault.sections {
+		raw, err := json.Marshal(globals[section])
+		if err != nil {
+			return nil, err
+		}
+		sections[section] = raw
+	}
+	return json.Marshal(sections)
+}
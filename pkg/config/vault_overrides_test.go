@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeVaultConfig(t *testing.T, content string) string {
+	t.Helper()
+	vault := t.TempDir()
+	path := filepath.Join(vault, filepath.FromSlash(VaultConfigFile))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return vault
+}
+
+func TestVaultOverridesPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{"chunking": {"chunk_size": 800, "chunk_overlap": 100}, "watcher": {"workers": 2}}`)
+	vault := writeVaultConfig(t, `{"chunking": {"chunk_size": 500}, "ai": {"provider": "vault-provider"}}`)
+
+	c := New()
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	sections, err := c.ApplyVaultOverrides(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 1 || sections[0] != "chunking" {
+		t.Fatalf("overridden sections = %v, want [chunking]", sections)
+	}
+
+	// defaults < global file < vault file, key by key
+	chunking := c.GetChunkingConfig()
+	if chunking.ChunkSize != 500 || chunking.ChunkOverlap != 100 || chunking.MaxChunkSize != New().Chunking.MaxChunkSize {
+		t.Fatalf("chunking = %+v, want the vault size over the global overlap and default max", chunking)
+	}
+	if c.GetWatcherConfig().Workers != 2 {
+		t.Fatal("a section the vault leaves out must keep the global value")
+	}
+	if c.AI.Provider == "vault-provider" {
+		t.Fatal("a vault must not override provider settings")
+	}
+
+	if _, err := c.ApplyVaultOverrides(""); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetChunkingConfig().ChunkSize; got != 800 || len(c.VaultOverrides()) != 0 {
+		t.Fatalf("chunk size after closing the vault = %d, want the global 800", got)
+	}
+}
+
+func TestVaultOverridesSurviveReload(t *testing.T) {
+	path := writeConfigFile(t, `{"chunking": {"chunk_size": 800}}`)
+	vault := writeVaultConfig(t, `{"chunking": {"chunk_size": 500}}`)
+
+	c := New()
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ApplyVaultOverrides(vault); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing a global setting elsewhere and reloading keeps the vault layer
+	if err := os.WriteFile(path, []byte(`{"chunking": {"chunk_size": 900, "chunk_overlap": 50}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	chunking := c.GetChunkingConfig()
+	if chunking.ChunkSize != 500 || chunking.ChunkOverlap != 50 {
+		t.Fatalf("chunking after reload = %+v, want the vault size over the new global overlap", chunking)
+	}
+	if overrides := c.VaultOverrides(); len(overrides) != 1 {
+		t.Fatalf("overrides after reload = %v", overrides)
+	}
+
+	if err := c.Import([]byte(`{"chunking": {"chunk_size": 1200}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetChunkingConfig().ChunkSize; got != 500 {
+		t.Fatalf("chunk size after import = %d, want the vault's 500", got)
+	}
+}
+
+func TestSaveWritesGlobalValuesUnderVaultOverrides(t *testing.T) {
+	path := writeConfigFile(t, `{"chunking": {"chunk_size": 800}}`)
+	vault := writeVaultConfig(t, `{"chunking": {"chunk_size": 500}}`)
+
+	c := New()
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ApplyVaultOverrides(vault); err != nil {
+		t.Fatal(err)
+	}
+	watcher := c.GetWatcherConfig()
+	watcher.Workers = 3
+	c.SetWatcherConfig(watcher)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Chunking.ChunkSize != 800 || saved.Watcher.Workers != 3 {
+		t.Fatalf("saved chunk size %d, workers %d; want the global 800 and the edited 3", saved.Chunking.ChunkSize, saved.Watcher.Workers)
+	}
+
+	// The saved file reloads to the same layered config
+	if err := c.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetChunkingConfig().ChunkSize != 500 || c.GetWatcherConfig().Workers != 3 {
+		t.Fatalf("reloaded chunking %+v, watcher %+v", c.GetChunkingConfig(), c.GetWatcherConfig())
+	}
+}
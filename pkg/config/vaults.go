@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecentVaults bounds how many vaults the registry remembers
+const maxRecentVaults = 20
+
+// Vault is a notes folder the user has opened
+type Vault struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	LastOpened int64  `json:"last_opened"` // Unix ms
+}
+
+// VaultRegistry keeps the recently opened vaults in a JSON file next to the
+// main config, most recently opened first
+type VaultRegistry struct {
+	mu     sync.RWMutex
+	path   string
+	vaults []Vault
+}
+
+// LoadVaultRegistry reads the registry at path; a missing file yields an
+// empty registry that is created on the first change
+func LoadVaultRegistry(path string) (*VaultRegistry, error) {
+	r := &VaultRegistry{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return r, err
+	}
+	var stored struct {
+		Vaults []Vault `json:"vaults"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return r, err
+	}
+	r.vaults = stored.Vaults
+	r.sort()
+	return r, nil
+}
+
+// List returns the known vaults, most recently opened first
+func (r *VaultRegistry) List() []Vault {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Vault, len(r.vaults))
+	copy(out, r.vaults)
+	return out
+}
+
+// Touch records that the vault at path was opened, adding it if needed.
+// New vaults are named after their folder.
+func (r *VaultRegistry) Touch(path string, now time.Time) error {
+	path = filepath.Clean(path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := false
+	for i := range r.vaults {
+		if r.vaults[i].Path == path {
+			r.vaults[i].LastOpened = now.UnixMilli()
+			found = true
+			break
+		}
+	}
+	if !found {
+		r.vaults = append(r.vaults, Vault{Path: path, Name: filepath.Base(path), LastOpened: now.UnixMilli()})
+	}
+	r.sort()
+	if len(r.vaults) > maxRecentVaults {
+		r.vaults = r.vaults[:maxRecentVaults]
+	}
+	return r.save()
+}
+
+// Rename sets the display name of a vault
+func (r *VaultRegistry) Rename(path, name string) error {
+	path = filepath.Clean(path)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("vault name cannot be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.vaults {
+		if r.vaults[i].Path == path {
+			r.vaults[i].Name = name
+			return r.save()
+		}
+	}
+	return errors.New("vault not found: " + path)
+}
+
+// Remove forgets a vault. Its files and database are left untouched.
+func (r *VaultRegistry) Remove(path string) error {
+	path = filepath.Clean(path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.vaults {
+		if r.vaults[i].Path == path {
+			r.vaults = append(r.vaults[:i], r.vaults[i+1:]...)
+			return r.save()
+		}
+	}
+	return nil
+}
+
+func (r *VaultRegistry) sort() {
+	sort.SliceStable(r.vaults, func(i, j int) bool {
+		return r.vaults[i].LastOpened > r.vaults[j].LastOpened
+	})
+}
+
+// save writes the registry; callers hold r.mu
+func (r *VaultRegistry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(struct {
+		Vaults []Vault `json:"vaults"`
+	}{r.vaults}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
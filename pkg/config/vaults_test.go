@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVaultRegistryTouchOrdersAndPersists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vaults.json")
+	r, err := LoadVaultRegistry(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(t.TempDir(), "Work")
+	home := filepath.Join(t.TempDir(), "Home")
+	now := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	if err := r.Touch(work, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Touch(home+"/", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Touch(work, now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	vaults := r.List()
+	if len(vaults) != 2 || vaults[0].Path != work || vaults[1].Path != home {
+		t.Fatalf("vaults = %+v, want Work then Home", vaults)
+	}
+	if vaults[0].Name != "Work" || vaults[0].LastOpened != now.Add(2*time.Minute).UnixMilli() {
+		t.Fatalf("unexpected entry: %+v", vaults[0])
+	}
+
+	if err := r.Rename(home, "  Personal "); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Rename(home, " "); err == nil {
+		t.Fatal("expected an empty name to be rejected")
+	}
+	if err := r.Rename(filepath.Join(t.TempDir(), "missing"), "x"); err == nil {
+		t.Fatal("expected renaming an unknown vault to fail")
+	}
+
+	reloaded, err := LoadVaultRegistry(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaults = reloaded.List()
+	if len(vaults) != 2 || vaults[0].Path != work || vaults[1].Name != "Personal" {
+		t.Fatalf("reloaded vaults = %+v", vaults)
+	}
+
+	if err := reloaded.Remove(work); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.Remove(work); err != nil {
+		t.Fatalf("removing a forgotten vault: %v", err)
+	}
+	if vaults := reloaded.List(); len(vaults) != 1 || vaults[0].Path != home {
+		t.Fatalf("vaults after remove = %+v", vaults)
+	}
+}
+
+func TestVaultRegistryKeepsMostRecent(t *testing.T) {
+	r, err := LoadVaultRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := t.TempDir()
+	now := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRecentVaults+5; i++ {
+		if err := r.Touch(filepath.Join(base, fmt.Sprintf("v%02d", i)), now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vaults := r.List()
+	if len(vaults) != maxRecentVaults {
+		t.Fatalf("registry kept %d vaults, want %d", len(vaults), maxRecentVaults)
+	}
+	if vaults[0].Name != fmt.Sprintf("v%02d", maxRecentVaults+4) || vaults[len(vaults)-1].Name != "v05" {
+		t.Fatalf("registry kept %s..%s, want the most recent", vaults[0].Name, vaults[len(vaults)-1].Name)
+	}
+}
+
+func TestLoadVaultRegistryMissingOrBroken(t *testing.T) {
+	dir := t.TempDir()
+	r, err := LoadVaultRegistry(filepath.Join(dir, "none.json"))
+	if err != nil || len(r.List()) != 0 {
+		t.Fatalf("missing registry = %+v, %v", r.List(), err)
+	}
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := LoadVaultRegistry(broken); err == nil || r == nil {
+		t.Fatal("expected an error and a usable empty registry for a broken file")
+	}
+}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Collection item kinds
+const (
+	CollectionItemNote   = "note"
+	CollectionItemFolder = "folder"
+	CollectionItemTag    = "tag"
+)
+
+// Collection is a named set of notes, folders and tags that can span the vault
+type Collection struct {
+	ID          uint             `gorm:"primarykey" json:"id"`
+	Name        string           `gorm:"uniqueIndex;not null;size:128" json:"name"`
+	Description string           `gorm:"type:text" json:"description"`
+	Items       []CollectionItem `gorm:"foreignKey:CollectionID;constraint:OnDelete:CASCADE" json:"items"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// TableName specifies the table name for Collection
+func (Collection) TableName() string {
+	return "collections"
+}
+
+// CollectionItem is one member of a collection: a note path, a folder prefix or a tag
+type CollectionItem struct {
+	CollectionID uint   `gorm:"primaryKey" json:"collection_id"`
+	Kind         string `gorm:"primaryKey;size:16" json:"kind"`
+	Value        string `gorm:"primaryKey;size:512" json:"value"`
+}
+
+// TableName specifies the table name for CollectionItem
+func (CollectionItem) TableName() string {
+	return "collection_items"
+}
+
+// CreateCollection creates an empty collection
+func (r *Repository) CreateCollection(name, description string) (*Collection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("collection name is required")
+	}
+	c := &Collection{Name: name, Description: strings.TrimSpace(description), Items: []CollectionItem{}}
+	if err := r.db.Create(c).Error; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCollections returns all collections with their items, ordered by name
+func (r *Repository) ListCollections() ([]Collection, error) {
+	var collections []Collection
+	err := r.db.Preload("Items").Order("name").Find(&collections).Error
+	return collections, err
+}
+
+// GetCollection returns a collection with its items
+func (r *Repository) GetCollection(id uint) (*Collection, error) {
+	var c Collection
+	if err := r.db.Preload("Items").First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateCollection renames a collection and updates its description
+func (r *Repository) UpdateCollection(id uint, name, description string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("collection name is required")
+	}
+	res := r.db.Model(&Collection{}).Where("id = ?", id).Updates(map[string]any{
+		"name":        name,
+		"description": strings.TrimSpace(description),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("collection %d not found", id)
+	}
+	return nil
+}
+
+// DeleteCollection removes a collection and its items
+func (r *Repository) DeleteCollection(id uint) error {
+	if err := r.db.Where("collection_id = ?", id).Delete(&CollectionItem{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&Collection{}, id).Error
+}
+
+// AddCollectionItem adds a note, folder or tag to a collection
+func (r *Repository) AddCollectionItem(id uint, kind, value string) error {
+	item, err := normalizeCollectionItem(kind, value)
+	if err != nil {
+		return err
+	}
+	item.CollectionID = id
+	if err := r.db.First(&Collection{}, id).Error; err != nil {
+		return err
+	}
+	return r.db.Exec("INSERT OR IGNORE INTO collection_items (collection_id, kind, value) VALUES (?, ?, ?)",
+		item.CollectionID, item.Kind, item.Value).Error
+}
+
+// RemoveCollectionItem removes a member from a collection
+func (r *Repository) RemoveCollectionItem(id uint, kind, value string) error {
+	item, err := normalizeCollectionItem(kind, value)
+	if err != nil {
+		return err
+	}
+	return r.db.Where("collection_id = ? AND kind = ? AND value = ?", id, item.Kind, item.Value).
+		Delete(&CollectionItem{}).Error
+}
+
+// RenameCollectionPaths keeps note and folder items pointing at a renamed path
+func (r *Repository) RenameCollectionPaths(oldPath, newPath string) error {
+	if err := r.db.Model(&CollectionItem{}).
+		Where("kind = ? AND value = ?", CollectionItemNote, oldPath).
+		Update("value", newPath).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&CollectionItem{}).
+		Where("kind = ? AND value = ?", CollectionItemFolder, strings.TrimSuffix(oldPath, "/")).
+		Update("value", strings.TrimSuffix(newPath, "/")).Error
+}
+
+// ResolveCollectionPaths expands a collection into the sorted paths of the indexed
+// notes it covers: listed notes, notes under listed folders and notes with listed tags.
+func (r *Repository) ResolveCollectionPaths(id uint) ([]string, error) {
+	c, err := r.GetCollection(id)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{})
+	for _, item := range c.Items {
+		var paths []string
+		switch item.Kind {
+		case CollectionItemNote:
+			err = r.db.Model(&File{}).Where("path = ?", item.Value).Pluck("path", &paths).Error
+		case CollectionItemFolder:
+			if item.Value == "" {
+				err = r.db.Model(&File{}).Pluck("path", &paths).Error
+			} else {
+				err = r.db.Model(&File{}).Where("path LIKE ? ESCAPE '\\'", escapeLike(item.Value)+"/%").Pluck("path", &paths).Error
+			}
+		case CollectionItemTag:
+			paths, err = r.pathsWithTag(item.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			set[p] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// pathsWithTag finds notes tagged explicitly via file_tags or with an inline #tag
+func (r *Repository) pathsWithTag(tag string) ([]string, error) {
+	var paths []string
+	if err := r.db.Model(&File{}).
+		Joins("JOIN file_tags ON files.id = file_tags.file_id").
+		Joins("JOIN tags ON tags.id = file_tags.tag_id").
+		Where("tags.name = ?", tag).
+		Pluck("files.path", &paths).Error; err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		Path    string
+		Content string
+	}
+	var candidates []candidate
+	if err := r.db.Model(&Chunk{}).
+		Select("files.path AS path, chunks.content AS content").
+		Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+		Where("chunks.content LIKE ? ESCAPE '\\'", "%#"+escapeLike(tag)+"%").
+		Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+	inline := regexp.MustCompile(`(^|[^\w\p{L}])#` + regexp.QuoteMeta(tag) + `($|[^\w\p{L}-])`)
+	for _, c := range candidates {
+		if inline.MatchString(c.Content) {
+			paths = append(paths, c.Path)
+		}
+	}
+	return paths, nil
+}
+
+func normalizeCollectionItem(kind, value string) (CollectionItem, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\\", "/"))
+	switch kind {
+	case CollectionItemNote:
+		if value == "" {
+			return CollectionItem{}, fmt.Errorf("note path is required")
+		}
+	case CollectionItemFolder:
+		value = strings.Trim(value, "/")
+	case CollectionItemTag:
+		value = strings.TrimPrefix(value, "#")
+		if value == "" {
+			return CollectionItem{}, fmt.Errorf("tag is required")
+		}
+	default:
+		return CollectionItem{}, fmt.Errorf("unknown collection item kind %q", kind)
+	}
+	return CollectionItem{Kind: kind, Value: value}, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
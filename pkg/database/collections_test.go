@@ -0,0 +1,64 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollections_ResolveAndScopedSearch(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&Tag{}, &FileTag{}, &Collection{}, &CollectionItem{}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(path, content string, vec []float32) {
+		t.Helper()
+		if err := repo.IndexFileWithChunks(path, content, NoteStats{}, 1, int64(len(content)), []ChunkInput{
+			{Content: content, Embedding: vec},
+		}); err != nil {
+			t.Fatalf("index %s: %v", path, err)
+		}
+	}
+	index("projects/alpha/plan.md", "alpha plan", []float32{1, 0})
+	index("projects/beta.md", "beta notes", []float32{1, 0.1})
+	index("journal/today.md", "worked on #launch today", []float32{0, 1})
+	index("journal/other.md", "#launchpad is unrelated", []float32{0.5, 0.5})
+
+	c, err := repo.CreateCollection("Launch", "cross-cutting project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range [][2]string{{"folder", "projects/alpha/"}, {"note", "projects/beta.md"}, {"tag", "#launch"}} {
+		if err := repo.AddCollectionItem(c.ID, item[0], item[1]); err != nil {
+			t.Fatalf("add %v: %v", item, err)
+		}
+	}
+	if err := repo.AddCollectionItem(c.ID, "bogus", "x"); err == nil {
+		t.Fatal("expected unknown kind to be rejected")
+	}
+
+	paths, err := repo.ResolveCollectionPaths(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"journal/today.md", "projects/alpha/plan.md", "projects/beta.md"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("ResolveCollectionPaths = %v, want %v", paths, want)
+	}
+
+	results, err := repo.SearchSimilarInPaths([]string{"journal/today.md"}, []float32{1, 0}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].File.Path != "journal/today.md" {
+		t.Fatalf("scoped search returned %+v", results)
+	}
+
+	if err := repo.DeleteCollection(c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := repo.ListCollections(); len(list) != 0 {
+		t.Fatalf("expected no collections after delete, got %d", len(list))
+	}
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ChunkEmbedding stores an additional embedding for a chunk under a model namespace.
+// The chunk's own embedding columns remain the primary namespace used for normal
+// search; these rows let other models be compared without re-indexing.
+type ChunkEmbedding struct {
+	ChunkID       uint      `gorm:"primaryKey" json:"chunk_id"`
+	Model         string    `gorm:"primaryKey;size:64;index" json:"model"`
+	EmbeddingBlob []byte    `gorm:"type:blob" json:"-"`
+	Dimension     int       `json:"dimension"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ChunkEmbedding
+func (ChunkEmbedding) TableName() string {
+	return "chunk_embeddings"
+}
+
+// ModelEmbeddingStats describes one embedding namespace
+type ModelEmbeddingStats struct {
+	Model          string `json:"model"`
+	EmbeddedChunks int64  `json:"embedded_chunks"`
+	Dimension      int    `json:"dimension"`
+	Primary        bool   `json:"primary"` // stored on the chunk itself
+}
+
+// PendingChunk is a chunk that still needs an embedding in some namespace
+type PendingChunk struct {
+	ID      uint
+	Content string
+}
+
+// SaveChunkEmbeddings upserts embeddings for chunks in the given model namespace
+func (r *Repository) SaveChunkEmbeddings(model string, embeddings map[uint][]float32) error {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if len(embeddings) == 0 {
+		return nil
+	}
+	rows := make([]ChunkEmbedding, 0, len(embeddings))
+	for chunkID, vec := range embeddings {
+		if len(vec) == 0 {
+			continue
+		}
+		rows = append(rows, ChunkEmbedding{
+			ChunkID:       chunkID,
+			Model:         model,
+			EmbeddingBlob: floatsToBytes(vec),
+			Dimension:     len(vec),
+		})
+	}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chunk_id"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{"embedding_blob", "dimension", "created_at"}),
+	}).CreateInBatches(rows, 200).Error
+	if err != nil {
+		return err
+	}
+	r.changed()
+	return nil
+}
+
+// ListChunksMissingModel returns up to limit live chunks without an embedding in
+// the model namespace (neither as primary embedding nor as namespaced row)
+func (r *Repository) ListChunksMissingModel(model string, limit int) ([]PendingChunk, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var chunks []PendingChunk
+	err := r.db.Raw(`
+		SELECT chunks.id, chunks.content FROM chunks
+		WHERE chunks.deleted_at IS NULL
+			AND NOT (chunks.embedding_model = ? AND chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0)
+			AND NOT EXISTS (SELECT 1 FROM chunk_embeddings ce WHERE ce.chunk_id = chunks.id AND ce.model = ?)
+		ORDER BY chunks.id
+		LIMIT ?
+	`, model, model, limit).Scan(&chunks).Error
+	return chunks, err
+}
+
+// DeleteEmbeddingNamespace removes all namespaced embeddings for model. Primary
+// embeddings on the chunks themselves are not touched.
+func (r *Repository) DeleteEmbeddingNamespace(model string) (int64, error) {
+	res := r.db.Where("model = ?", model).Delete(&ChunkEmbedding{})
+	if res.Error == nil && res.RowsAffected > 0 {
+		r.changed()
+	}
+	return res.RowsAffected, res.Error
+}
+
+// getModelStats returns per-model counts across primary and namespaced embeddings
+func (r *Repository) getModelStats() ([]ModelEmbeddingStats, error) {
+	type row struct {
+		Model string
+		Count int64
+		Dim   int
+	}
+	var primary []row
+	if err := r.db.Raw(`
+		SELECT embedding_model AS model, COUNT(*) AS count, MAX(length(embedding_blob)) / 4 AS dim
+		FROM chunks
+		WHERE deleted_at IS NULL AND embedding_blob IS NOT NULL AND length(embedding_blob) > 0
+		GROUP BY embedding_model
+	`).Scan(&primary).Error; err != nil {
+		return nil, err
+	}
+	var namespaced []row
+	if err := r.db.Raw(`
+		SELECT ce.model AS model, COUNT(*) AS count, MAX(ce.dimension) AS dim
+		FROM chunk_embeddings ce
+		JOIN chunks ON chunks.id = ce.chunk_id AND chunks.deleted_at IS NULL
+		GROUP BY ce.model
+	`).Scan(&namespaced).Error; err != nil {
+		return nil, err
+	}
+
+	byModel := make(map[string]*ModelEmbeddingStats)
+	for _, p := range primary {
+		byModel[p.Model] = &ModelEmbeddingStats{Model: p.Model, EmbeddedChunks: p.Count, Dimension: p.Dim, Primary: true}
+	}
+	for _, n := range namespaced {
+		if s, ok := byModel[n.Model]; ok {
+			s.EmbeddedChunks += n.Count
+			continue
+		}
+		byModel[n.Model] = &ModelEmbeddingStats{Model: n.Model, EmbeddedChunks: n.Count, Dimension: n.Dim}
+	}
+	out := make([]ModelEmbeddingStats, 0, len(byModel))
+	for _, s := range byModel {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Primary != out[j].Primary {
+			return out[i].Primary
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out, nil
+}
+
+// searchNamespace runs a brute-force search over one model namespace: chunks whose
+// primary embedding came from model plus namespaced rows for it
+func (r *Repository) searchNamespace(model string, queryVector []float32, limit int) ([]SimilarChunk, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := r.db.Raw(`
+		SELECT ce.chunk_id, ce.embedding_blob FROM chunk_embeddings ce
+		JOIN chunks ON chunks.id = ce.chunk_id AND chunks.deleted_at IS NULL
+		WHERE ce.model = ?
+		UNION ALL
+		SELECT chunks.id, chunks.embedding_blob FROM chunks
+		WHERE chunks.deleted_at IS NULL AND chunks.embedding_model = ?
+			AND chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0
+			AND NOT EXISTS (SELECT 1 FROM chunk_embeddings ce2 WHERE ce2.chunk_id = chunks.id AND ce2.model = ?)
+	`, model, model, model).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topK := &scoredChunkHeap{}
+	heap.Init(topK)
+	scorer := r.newScorer(queryVector)
+	for rows.Next() {
+		var id uint
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return nil, err
+		}
+		vec := bytesToFloats(blob)
+		if len(vec) != len(queryVector) {
+			continue
+		}
+		score := scoredChunk{ID: id, Similarity: scorer.score(vec, false)}
+		if topK.Len() < limit {
+			heap.Push(topK, score)
+		} else if score.Similarity > (*topK)[0].Similarity {
+			heap.Pop(topK)
+			heap.Push(topK, score)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.loadScoredChunks(*topK)
+}
+
+// SearchSimilarAcrossModels searches the primary embeddings with queryVector
+// and each model namespace with the query embedded by that model, keeping
+// each chunk's best score. It lets a multilingual model find chunks the
+// default model embeds poorly.
+func (r *Repository) SearchSimilarAcrossModels(queryVector []float32, byModel map[string][]float32, limit int) ([]SimilarChunk, error) {
+	results, err := r.SearchSimilar("", queryVector, limit)
+	if err != nil || len(byModel) == 0 {
+		return results, err
+	}
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		more, err := r.searchNamespace(model, byModel[model], limit)
+		if err != nil {
+			return nil, err
+		}
+		results = mergeSimilar(results, more)
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// mergeSimilar combines two result lists, keeping the higher score of chunks
+// found by both, ordered by similarity
+func mergeSimilar(a, b []SimilarChunk) []SimilarChunk {
+	byID := make(map[uint]int, len(a)+len(b))
+	merged := make([]SimilarChunk, 0, len(a)+len(b))
+	for _, list := range [][]SimilarChunk{a, b} {
+		for _, c := range list {
+			if i, ok := byID[c.ChunkID]; ok {
+				if c.Similarity > merged[i].Similarity {
+					merged[i].Similarity = c.Similarity
+				}
+				continue
+			}
+			byID[c.ChunkID] = len(merged)
+			merged = append(merged, c)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Similarity > merged[j].Similarity })
+	return merged
+}
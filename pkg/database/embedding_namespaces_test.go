@@ -0,0 +1,128 @@
+package database
+
+import "testing"
+
+func TestEmbeddingNamespaces_SearchAndStats(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&ChunkEmbedding{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.IndexFileWithChunks("a.md", "# A", NoteStats{}, 1, 3, []ChunkInput{
+		{Content: "alpha", Embedding: []float32{1, 0}, EmbeddingModel: "model-a"},
+		{Content: "beta", Embedding: []float32{0, 1}, EmbeddingModel: "model-a"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := repo.ListChunksMissingModel("model-b", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 chunks missing model-b, got %d", len(pending))
+	}
+	// model-b ranks the chunks the other way round and uses a different dimension
+	if err := repo.SaveChunkEmbeddings("model-b", map[uint][]float32{
+		pending[0].ID: {0, 0, 1},
+		pending[1].ID: {1, 0, 0},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resultsA, err := repo.SearchSimilar("model-a", []float32{1, 0}, 1)
+	if err != nil || len(resultsA) != 1 || resultsA[0].Content != "alpha" {
+		t.Fatalf("model-a search = %+v, %v", resultsA, err)
+	}
+	resultsB, err := repo.SearchSimilar("model-b", []float32{1, 0, 0}, 1)
+	if err != nil || len(resultsB) != 1 || resultsB[0].Content != "beta" {
+		t.Fatalf("model-b search = %+v, %v", resultsB, err)
+	}
+
+	stats, err := repo.GetEmbeddingStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.ByModel) != 2 || !stats.ByModel[0].Primary || stats.ByModel[1].Model != "model-b" || stats.ByModel[1].Dimension != 3 {
+		t.Fatalf("unexpected per-model stats: %+v", stats.ByModel)
+	}
+
+	if removed, err := repo.DeleteEmbeddingNamespace("model-b"); err != nil || removed != 2 {
+		t.Fatalf("DeleteEmbeddingNamespace = %d, %v", removed, err)
+	}
+}
+
+func TestSearchSimilarAcrossModels(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&ChunkEmbedding{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.IndexFileWithChunks("notes.md", "# Notes", NoteStats{}, 1, 7, []ChunkInput{
+		{Content: "english", Language: "en", Embedding: []float32{1, 0}, EmbeddingModel: "default"},
+		{Content: "中文笔记", Language: "zh", Embedding: []float32{0, 1}, EmbeddingModel: "default"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := repo.GetFileByPath("notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := repo.GetChunksByFileID(file.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks[1].Language != "zh" {
+		t.Fatalf("language not stored: %+v", chunks[1])
+	}
+	// Only the Chinese chunk has a multilingual embedding, close to the query
+	if err := repo.SaveChunkEmbeddings("multilingual", map[uint][]float32{chunks[1].ID: {1, 1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := repo.SearchSimilarAcrossModels([]float32{1, 0}, map[string][]float32{"multilingual": {1, 1, 0}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Similarity < 0.99 {
+			t.Fatalf("chunk %q kept a lower score: %+v", r.Content, results)
+		}
+	}
+}
+
+func TestReindexDropsNamespacedEmbeddings(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&ChunkEmbedding{}); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := []ChunkInput{{Content: "alpha", Embedding: []float32{1, 0}, EmbeddingModel: "model-a"}}
+	if err := repo.IndexFileWithChunks("a.md", "# A", NoteStats{}, 1, 3, chunks); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := repo.ListChunksMissingModel("model-b", 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("ListChunksMissingModel = %+v, %v", pending, err)
+	}
+	if err := repo.SaveChunkEmbeddings("model-b", map[uint][]float32{pending[0].ID: {0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.IndexFileWithChunks("a.md", "# A changed", NoteStats{}, 2, 11, chunks); err != nil {
+		t.Fatal(err)
+	}
+	var stale int64
+	if err := repo.db.Model(&ChunkEmbedding{}).Where("chunk_id = ?", pending[0].ID).Count(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stale != 0 {
+		t.Fatalf("%d namespaced embeddings of the old chunk survived the reindex", stale)
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FavoriteNote is a note the user pinned
+type FavoriteNote struct {
+	Path      string    `gorm:"primaryKey;size:1024" json:"path"`
+	Title     string    `gorm:"-" json:"title"` // Indexed title, "" if the note is not indexed
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for FavoriteNote
+func (FavoriteNote) TableName() string {
+	return "favorite_notes"
+}
+
+// SetNoteFavorite pins or unpins the note at path
+func (r *Repository) SetNoteFavorite(path string, favorite bool) error {
+	if !favorite {
+		return r.db.Where("path = ?", path).Delete(&FavoriteNote{}).Error
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&FavoriteNote{Path: path}).Error
+}
+
+// ListFavoriteNotes returns the pinned notes with their titles, in the order
+// they were pinned
+func (r *Repository) ListFavoriteNotes() ([]FavoriteNote, error) {
+	var favorites []FavoriteNote
+	if err := r.db.Order("created_at, path").Find(&favorites).Error; err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(favorites))
+	for i, f := range favorites {
+		paths[i] = f.Path
+	}
+	var titles []struct{ Path, Title string }
+	if len(paths) > 0 {
+		if err := r.db.Model(&File{}).Select("path, title").Where("path IN ?", paths).Scan(&titles).Error; err != nil {
+			return nil, err
+		}
+	}
+	byPath := make(map[string]string, len(titles))
+	for _, t := range titles {
+		byPath[t.Path] = t.Title
+	}
+	for i := range favorites {
+		favorites[i].Title = byPath[favorites[i].Path]
+	}
+	if favorites == nil {
+		favorites = []FavoriteNote{}
+	}
+	return favorites, nil
+}
+
+// DeleteFavoriteForPath unpins a deleted note
+func (r *Repository) DeleteFavoriteForPath(path string) error {
+	return r.SetNoteFavorite(path, false)
+}
+
+// RenameFavoritePaths keeps a renamed note, or the notes of a renamed
+// folder, pinned
+func (r *Repository) RenameFavoritePaths(oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	if err := r.db.Model(&FavoriteNote{}).Where("path = ?", oldPath).Update("path", newPath).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&FavoriteNote{}).
+		Where("path LIKE ? ESCAPE '\\'", escapeLike(oldPath)+"/%").
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, utf8.RuneCountInString(oldPath)+1)).Error
+}
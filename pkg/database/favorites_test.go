@@ -0,0 +1,39 @@
+package database
+
+import "testing"
+
+func TestNoteFavorites(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&FavoriteNote{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.IndexFile("projects/plan.md", "# The Plan", NoteStats{}, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"projects/plan.md", "inbox.md", "projects/plan.md"} {
+		if err := repo.SetNoteFavorite(path, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	favorites, err := repo.ListFavoriteNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites) != 2 || favorites[0].Path != "projects/plan.md" || favorites[0].Title != "The Plan" || favorites[1].Title != "" {
+		t.Fatalf("unexpected favorites: %+v", favorites)
+	}
+
+	if err := repo.RenameFavoritePaths("projects", "archive/projects"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetNoteFavorite("inbox.md", false); err != nil {
+		t.Fatal(err)
+	}
+	favorites, _ = repo.ListFavoriteNotes()
+	if len(favorites) != 1 || favorites[0].Path != "archive/projects/plan.md" {
+		t.Fatalf("unexpected favorites after rename: %+v", favorites)
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Feed is an RSS or Atom feed the vault is subscribed to
+type Feed struct {
+	ID    uint   `gorm:"primarykey" json:"id"`
+	URL   string `gorm:"uniqueIndex;not null;size:2048" json:"url"`
+	Title string `gorm:"size:512" json:"title"`
+	// Folder is the feed's subfolder below the feeds folder
+	Folder string `gorm:"size:256" json:"folder"`
+	// ETag and LastModified let polls skip unchanged feeds
+	ETag         string     `gorm:"column:etag;size:256" json:"-"`
+	LastModified string     `gorm:"size:128" json:"-"`
+	LastPolled   *time.Time `json:"last_polled"`
+	LastError    string     `gorm:"type:text" json:"last_error"`
+	Entries      int64      `gorm:"-" json:"entries"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for Feed
+func (Feed) TableName() string {
+	return "feeds"
+}
+
+// FeedEntry records a feed entry that was written to a note, so it is not
+// written again
+type FeedEntry struct {
+	FeedID    uint      `gorm:"primaryKey" json:"feed_id"`
+	GUID      string    `gorm:"column:guid;primaryKey;size:1024" json:"guid"`
+	Path      string    `gorm:"size:1024" json:"path"`
+	Published time.Time `json:"published"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for FeedEntry
+func (FeedEntry) TableName() string {
+	return "feed_entries"
+}
+
+// CreateFeed subscribes to the feed at url
+func (r *Repository) CreateFeed(url, title, folder string) (*Feed, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, fmt.Errorf("feed URL is required")
+	}
+	var count int64
+	if err := r.db.Model(&Feed{}).Where("url = ?", url).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("already subscribed to %s", url)
+	}
+	feed := &Feed{URL: url, Title: strings.TrimSpace(title), Folder: strings.TrimSpace(folder)}
+	if err := r.db.Create(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// ListFeeds returns all subscribed feeds with their entry counts, ordered by title
+func (r *Repository) ListFeeds() ([]Feed, error) {
+	var feeds []Feed
+	if err := r.db.Order("title, url").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		FeedID uint
+		Count  int64
+	}
+	if err := r.db.Model(&FeedEntry{}).Select("feed_id, COUNT(*) AS count").Group("feed_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byFeed := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byFeed[c.FeedID] = c.Count
+	}
+	for i := range feeds {
+		feeds[i].Entries = byFeed[feeds[i].ID]
+	}
+	return feeds, nil
+}
+
+// GetFeed returns a subscribed feed
+func (r *Repository) GetFeed(id uint) (*Feed, error) {
+	var feed Feed
+	if err := r.db.First(&feed, id).Error; err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// DeleteFeed unsubscribes from a feed. Notes written from it are kept.
+func (r *Repository) DeleteFeed(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feed_id = ?", id).Delete(&FeedEntry{}).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&Feed{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("feed %d not found", id)
+		}
+		return nil
+	})
+}
+
+// RecordFeedPoll stores the outcome of polling a feed. An empty title keeps
+// the current one.
+func (r *Repository) RecordFeedPoll(id uint, title, etag, lastModified string, pollErr error) error {
+	now := time.Now()
+	updates := map[string]any{"last_polled": &now, "last_error": ""}
+	if pollErr != nil {
+		updates["last_error"] = pollErr.Error()
+	} else {
+		updates["etag"] = etag
+		updates["last_modified"] = lastModified
+	}
+	if title = strings.TrimSpace(title); title != "" {
+		updates["title"] = title
+	}
+	return r.db.Model(&Feed{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// HasFeedEntry reports whether the entry guid of a feed was written already
+func (r *Repository) HasFeedEntry(feedID uint, guid string) (bool, error) {
+	var count int64
+	err := r.db.Model(&FeedEntry{}).Where("feed_id = ? AND guid = ?", feedID, guid).Count(&count).Error
+	return count > 0, err
+}
+
+// AddFeedEntry records a feed entry as written to its note
+func (r *Repository) AddFeedEntry(entry FeedEntry) error {
+	return r.db.Create(&entry).Error
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFeedSubscriptionsAndEntries(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&Feed{}, &FeedEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := repo.CreateFeed(" https://example.com/feed.xml ", "", "Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateFeed("https://example.com/feed.xml", "", ""); err == nil {
+		t.Fatal("expected duplicate subscription to fail")
+	}
+
+	if err := repo.AddFeedEntry(FeedEntry{FeedID: feed.ID, GUID: "post-1", Path: "Feeds/Example/Post.md", Published: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	seen, err := repo.HasFeedEntry(feed.ID, "post-1")
+	if err != nil || !seen {
+		t.Fatalf("expected post-1 to be recorded, got %v (%v)", seen, err)
+	}
+	if seen, _ := repo.HasFeedEntry(feed.ID, "post-2"); seen {
+		t.Fatal("post-2 was never written")
+	}
+
+	if err := repo.RecordFeedPoll(feed.ID, "Example Blog", `"v1"`, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RecordFeedPoll(feed.ID, "", "ignored", "", errors.New("offline")); err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := repo.ListFeeds()
+	if err != nil || len(feeds) != 1 {
+		t.Fatalf("expected one feed, got %d (%v)", len(feeds), err)
+	}
+	got := feeds[0]
+	if got.Title != "Example Blog" || got.ETag != `"v1"` || got.LastError != "offline" || got.LastPolled == nil || got.Entries != 1 {
+		t.Fatalf("unexpected feed after polls: %+v", got)
+	}
+
+	if err := repo.DeleteFeed(feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if seen, _ := repo.HasFeedEntry(feed.ID, "post-1"); seen {
+		t.Fatal("entries of a deleted feed should be forgotten")
+	}
+	if err := repo.DeleteFeed(feed.ID); err == nil {
+		t.Fatal("expected deleting a missing feed to fail")
+	}
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"strings"
+)
+
+// File list sort keys
+const (
+	FileSortPath     = "path"
+	FileSortTitle    = "title"
+	FileSortModified = "modified"
+	FileSortSize     = "size"
+)
+
+const (
+	defaultFilePageSize = 100
+	maxFilePageSize     = 1000
+)
+
+// FileFilter selects and orders one page of indexed files
+type FileFilter struct {
+	Query      string `json:"query"`       // substring of the title or path
+	PathPrefix string `json:"path_prefix"` // folder, e.g. "projects/2024"
+	Tag        string `json:"tag"`         // assigned or inline #tag
+	SortBy     string `json:"sort_by"`     // path (default), title, modified or size
+	Desc       bool   `json:"desc"`
+	Page       int    `json:"page"`      // 1-based
+	PageSize   int    `json:"page_size"` // default 100, at most 1000
+	// Brief leaves out the content hash and size
+	Brief bool `json:"brief"`
+}
+
+// FileSummary is the list projection of an indexed file
+type FileSummary struct {
+	ID           uint   `json:"id"`
+	Path         string `json:"path"`
+	Title        string `json:"title"`
+	LastModified int64  `json:"last_modified"`
+	ContentHash  string `json:"content_hash,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// FilePage is one page of indexed files and the number matching in total
+type FilePage struct {
+	Files    []FileSummary `json:"files"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// ListFilesPage returns the page of indexed files selected by filter
+func (r *Repository) ListFilesPage(filter FileFilter) (*FilePage, error) {
+	page := &FilePage{Files: []FileSummary{}, Page: filter.Page, PageSize: filter.PageSize}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.PageSize <= 0 {
+		page.PageSize = defaultFilePageSize
+	}
+	if page.PageSize > maxFilePageSize {
+		page.PageSize = maxFilePageSize
+	}
+
+	query := r.db.Model(&File{})
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("(title LIKE ? ESCAPE '\\' OR path LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+	if prefix := strings.Trim(strings.ReplaceAll(filter.PathPrefix, "\\", "/"), "/"); prefix != "" {
+		query = query.Where("path LIKE ? ESCAPE '\\'", escapeLike(prefix)+"/%")
+	}
+	if tag := strings.TrimPrefix(strings.TrimSpace(filter.Tag), "#"); tag != "" {
+		paths, err := r.pathsWithTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return page, nil
+		}
+		query = query.Where("path IN ?", paths)
+	}
+
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	columns := []string{"id", "path", "title", "last_modified"}
+	if !filter.Brief {
+		columns = append(columns, "content_hash", "file_size")
+	}
+	err := query.Select(columns).
+		Order(fileSortOrder(filter.SortBy, filter.Desc)).
+		Limit(page.PageSize).
+		Offset((page.Page - 1) * page.PageSize).
+		Scan(&page.Files).Error
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// fileSortOrder maps a sort key to an ORDER BY clause; path breaks ties so
+// pages stay stable
+func fileSortOrder(sortBy string, desc bool) string {
+	column := "path"
+	switch sortBy {
+	case FileSortTitle:
+		column = "title COLLATE NOCASE"
+	case FileSortModified:
+		column = "last_modified"
+	case FileSortSize:
+		column = "file_size"
+	}
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	if column == "path" {
+		return column + dir
+	}
+	return column + dir + ", path ASC"
+}
+
+// NoteHead is an indexed note's path and title with the content of its
+// first chunk, which holds any front matter
+type NoteHead struct {
+	Path         string `json:"path"`
+	Title        string `json:"title"`
+	LastModified int64  `json:"last_modified"`
+	Content      string `json:"content"`
+}
+
+// ListNoteHeads returns the head of every indexed note, ordered by path.
+// Notes indexed without chunks have an empty Content.
+func (r *Repository) ListNoteHeads() ([]NoteHead, error) {
+	var heads []NoteHead
+	err := r.db.Model(&File{}).
+		Select("files.path AS path, files.title AS title, files.last_modified AS last_modified, COALESCE(chunks.content, '') AS content").
+		Joins("LEFT JOIN chunks ON chunks.id = (SELECT MIN(c.id) FROM chunks c WHERE c.file_id = files.id)").
+		Order("files.path").
+		Scan(&heads).Error
+	return heads, err
+}
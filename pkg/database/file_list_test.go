@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestListFilesPage(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		content := fmt.Sprintf("# Note %d\n\nbody", i)
+		if err := repo.IndexFile(fmt.Sprintf("projects/n%d.md", i), content, NoteStats{}, int64(100-i), int64(len(content))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tagged := "# Other\n\nsee #urgent"
+	if err := repo.IndexFileWithChunks("inbox/other.md", tagged, NoteStats{}, 1, int64(len(tagged)), []ChunkInput{{Content: tagged}}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := repo.ListFilesPage(FileFilter{PathPrefix: "projects", SortBy: FileSortModified, Page: 2, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || len(page.Files) != 2 {
+		t.Fatalf("got %d of %d files, want 2 of 5", len(page.Files), page.Total)
+	}
+	if page.Files[0].Path != "projects/n2.md" || page.Files[1].Path != "projects/n1.md" {
+		t.Fatalf("unexpected order: %+v", page.Files)
+	}
+	if page.Files[0].ContentHash == "" || page.Files[0].FileSize == 0 {
+		t.Fatalf("full projection is missing fields: %+v", page.Files[0])
+	}
+
+	page, err = repo.ListFilesPage(FileFilter{Query: "note 3", Brief: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Files[0].Title != "Note 3" {
+		t.Fatalf("query matched %+v", page.Files)
+	}
+	if page.Files[0].ContentHash != "" || page.Files[0].FileSize != 0 {
+		t.Fatalf("brief projection has hash or size: %+v", page.Files[0])
+	}
+
+	page, err = repo.ListFilesPage(FileFilter{Tag: "#urgent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Files[0].Path != "inbox/other.md" {
+		t.Fatalf("tag filter matched %+v", page.Files)
+	}
+
+	if err := repo.DeleteFile("projects/n0.md"); err != nil {
+		t.Fatal(err)
+	}
+	page, err = repo.ListFilesPage(FileFilter{SortBy: FileSortTitle, Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 5 || page.Files[0].Title != "Other" {
+		t.Fatalf("deleted file listed or wrong order: %+v", page.Files)
+	}
+}
+
+func TestListNoteHeads(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	head := "---\naliases: [Plan]\n---\n# Project"
+	if err := repo.IndexFileWithChunks("b/project.md", head+"\n\nmore", NoteStats{}, 1, 10, []ChunkInput{{Content: head}, {Content: "more"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.IndexFile("a/bare.md", "# Bare", NoteStats{}, 1, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	heads, err := repo.ListNoteHeads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heads) != 2 || heads[0].Path != "a/bare.md" || heads[0].Content != "" {
+		t.Fatalf("unexpected heads: %+v", heads)
+	}
+	if heads[1].Title != "Project" || heads[1].Content != head || heads[1].LastModified != 1 {
+		t.Fatalf("unexpected head: %+v", heads[1])
+	}
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"container/heap"
+
+	"gorm.io/gorm"
+)
+
+// FileVector is a note-level embedding: the normalized mean of its chunk embeddings
+type FileVector struct {
+	Path   string
+	Title  string
+	Vector []float32
+}
+
+// FileSimilarity is a note scored by the similarity of its centroid
+type FileSimilarity struct {
+	Path       string  `json:"path"`
+	Title      string  `json:"title"`
+	Similarity float32 `json:"similarity"`
+}
+
+// ListFileVectors returns the stored centroid of every note with embedded chunks
+func (r *Repository) ListFileVectors() ([]FileVector, error) {
+	rows, err := r.db.Model(&File{}).
+		Select("path, title, centroid").
+		Where("centroid IS NOT NULL AND length(centroid) > 0").
+		Order("path ASC").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vectors []FileVector
+	for rows.Next() {
+		var v FileVector
+		var blob []byte
+		if err := rows.Scan(&v.Path, &v.Title, &blob); err != nil {
+			return nil, err
+		}
+		if v.Vector = bytesToFloats(blob); len(v.Vector) > 0 {
+			vectors = append(vectors, v)
+		}
+	}
+	return vectors, rows.Err()
+}
+
+// GetFileCentroid returns the note-level embedding of path, nil if none of
+// its chunks is embedded
+func (r *Repository) GetFileCentroid(path string) ([]float32, error) {
+	file, err := r.GetFileByPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return file.GetCentroid(), nil
+}
+
+// SearchSimilarFiles ranks notes by the cosine similarity of their centroid
+// to query, leaving out excludePath
+func (r *Repository) SearchSimilarFiles(query []float32, limit int, excludePath string) ([]FileSimilarity, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	vectors, err := r.ListFileVectors()
+	if err != nil {
+		return nil, err
+	}
+
+	topK := &scoredChunkHeap{}
+	heap.Init(topK)
+	for i, v := range vectors {
+		if v.Path == excludePath || len(v.Vector) != len(query) {
+			continue
+		}
+		score := scoredChunk{ID: uint(i), Similarity: cosineSimilarity(query, v.Vector)}
+		if topK.Len() < limit {
+			heap.Push(topK, score)
+		} else if score.Similarity > (*topK)[0].Similarity {
+			heap.Pop(topK)
+			heap.Push(topK, score)
+		}
+	}
+
+	results := make([]FileSimilarity, topK.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		best := heap.Pop(topK).(scoredChunk)
+		v := vectors[best.ID]
+		results[i] = FileSimilarity{Path: v.Path, Title: v.Title, Similarity: best.Similarity}
+	}
+	return results, nil
+}
+
+// backfillCentroids stores the centroid of files with embedded chunks that
+// have none yet. Indexing keeps centroids current, so this only runs from
+// applySchemaMigrationV3.
+func backfillCentroids(db *gorm.DB) error {
+	var ids []uint
+	if err := db.Model(&File{}).
+		Where("centroid IS NULL OR length(centroid) = 0").
+		Where("EXISTS (SELECT 1 FROM chunks WHERE chunks.file_id = files.id AND chunks.deleted_at IS NULL AND chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0)").
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var blobs [][]byte
+		if err := db.Model(&Chunk{}).
+			Where("file_id = ? AND embedding_blob IS NOT NULL AND length(embedding_blob) > 0", id).
+			Order("id").
+			Pluck("embedding_blob", &blobs).Error; err != nil {
+			return err
+		}
+		vectors := make([][]float32, 0, len(blobs))
+		for _, blob := range blobs {
+			vectors = append(vectors, bytesToFloats(blob))
+		}
+		if err := db.Model(&File{}).Where("id = ?", id).Update("centroid", centroidBlob(vectors)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkCentroid is the centroid blob of chunks about to be stored
+func chunkCentroid(chunks []ChunkInput) []byte {
+	vectors := make([][]float32, 0, len(chunks))
+	for _, c := range chunks {
+		vectors = append(vectors, c.Embedding)
+	}
+	return centroidBlob(vectors)
+}
+
+// centroidBlob averages the directions of vectors into a unit-length blob,
+// so long chunks do not outweigh short ones. Empty vectors and those whose
+// dimension differs from the first are skipped; nil if none is left.
+func centroidBlob(vectors [][]float32) []byte {
+	var sum []float32
+	for _, vec := range vectors {
+		if len(vec) == 0 {
+			continue
+		}
+		if sum == nil {
+			sum = make([]float32, len(vec))
+		}
+		if len(vec) != len(sum) {
+			continue
+		}
+		for i, v := range normalizeVector(vec) {
+			sum[i] += v
+		}
+	}
+	if sum == nil {
+		return nil
+	}
+	normalizeInPlace(sum)
+	return floatsToBytes(sum)
+}
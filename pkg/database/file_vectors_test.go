@@ -0,0 +1,84 @@
+package database
+
+import (
+	"math"
+	"testing"
+)
+
+func TestListFileVectors_AveragesAndNormalizesChunks(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.IndexFileWithChunks("a.md", "a", NoteStats{}, 1, 1, []ChunkInput{
+		// Chunk vectors are normalized first, so the longer one does not dominate
+		{Content: "one", Embedding: []float32{10, 0}},
+		{Content: "two", Embedding: []float32{0, 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.IndexFileWithChunks("b.md", "b", NoteStats{}, 1, 1, []ChunkInput{{Content: "pending"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	vectors, err := repo.ListFileVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 1 || vectors[0].Path != "a.md" {
+		t.Fatalf("only embedded notes should be listed: %+v", vectors)
+	}
+	want := float32(1 / math.Sqrt2)
+	for _, v := range vectors[0].Vector {
+		if math.Abs(float64(v-want)) > 1e-6 {
+			t.Fatalf("vector = %v, want normalized mean [%v %v]", vectors[0].Vector, want, want)
+		}
+	}
+}
+
+func TestSearchSimilarFilesUsesStoredAndBackfilledCentroids(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	for path, vec := range map[string][]float32{
+		"self.md": {1, 0},
+		"near.md": {0.9, 0.1},
+		"far.md":  {0, 1},
+	} {
+		if err := repo.IndexFileWithChunks(path, path, NoteStats{}, 1, 1, []ChunkInput{{Content: path, Embedding: vec}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Indexed before centroids were stored; the schema migration fills it in
+	if err := repo.db.Model(&File{}).Where("path = ?", "near.md").Update("centroid", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetFileCentroid("near.md"); err != nil || got != nil {
+		t.Fatalf("reads must not backfill centroids: %v, %v", got, err)
+	}
+	if err := applySchemaMigrationV3(repo.db); err != nil {
+		t.Fatal(err)
+	}
+
+	centroid, err := repo.GetFileCentroid("self.md")
+	if err != nil || len(centroid) != 2 {
+		t.Fatalf("GetFileCentroid = %v, %v", centroid, err)
+	}
+	results, err := repo.SearchSimilarFiles(centroid, 5, "self.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Path != "near.md" || results[1].Path != "far.md" {
+		t.Fatalf("unexpected ranking: %+v", results)
+	}
+	if results[0].Similarity <= results[1].Similarity {
+		t.Fatalf("scores not ordered: %+v", results)
+	}
+
+	// Reindexing without embeddings drops the centroid
+	if err := repo.IndexFileWithChunks("far.md", "far", NoteStats{}, 2, 1, []ChunkInput{{Content: "pending"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.GetFileCentroid("far.md"); err != nil || got != nil {
+		t.Fatalf("stale centroid kept: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// SM-2 defaults
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// Flashcard is a question/answer pair generated from a note, scheduled with SM-2
+type Flashcard struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	Path         string    `gorm:"index;not null;size:512" json:"path"`
+	Question     string    `gorm:"type:text;not null" json:"question"`
+	Answer       string    `gorm:"type:text;not null" json:"answer"`
+	EaseFactor   float64   `gorm:"not null;default:2.5" json:"ease_factor"`
+	Interval     int       `gorm:"not null;default:0" json:"interval"` // Days until the next review
+	Repetitions  int       `gorm:"not null;default:0" json:"repetitions"`
+	DueAt        int64     `gorm:"index" json:"due_at"` // Unix ms
+	LastReviewed int64     `json:"last_reviewed"`       // Unix ms, 0 = never
+	LastQuality  int       `json:"last_quality"`        // 0-5 grade of the last review
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Flashcard
+func (Flashcard) TableName() string {
+	return "flashcards"
+}
+
+// FlashcardInput is a new card for AddFlashcards
+type FlashcardInput struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// AddFlashcards stores new cards for a note, due immediately. Cards whose
+// question already exists for the note are skipped; the stored cards are returned.
+func (r *Repository) AddFlashcards(path string, cards []FlashcardInput, now time.Time) ([]Flashcard, error) {
+	var existing []string
+	if err := r.db.Model(&Flashcard{}).Where("path = ?", path).Pluck("question", &existing).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, q := range existing {
+		seen[strings.ToLower(strings.TrimSpace(q))] = true
+	}
+
+	added := make([]Flashcard, 0, len(cards))
+	for _, c := range cards {
+		question, answer := strings.TrimSpace(c.Question), strings.TrimSpace(c.Answer)
+		key := strings.ToLower(question)
+		if question == "" || answer == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		added = append(added, Flashcard{
+			Path:       path,
+			Question:   question,
+			Answer:     answer,
+			EaseFactor: defaultEaseFactor,
+			DueAt:      now.UnixMilli(),
+		})
+	}
+	if len(added) == 0 {
+		return added, nil
+	}
+	if err := r.db.Create(&added).Error; err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListFlashcards returns the cards generated from a note
+func (r *Repository) ListFlashcards(path string) ([]Flashcard, error) {
+	var cards []Flashcard
+	err := r.db.Where("path = ?", path).Order("id").Find(&cards).Error
+	return cards, err
+}
+
+// GetDueFlashcards returns cards due at or before now, most overdue first.
+// limit <= 0 returns all due cards.
+func (r *Repository) GetDueFlashcards(now time.Time, limit int) ([]Flashcard, error) {
+	query := r.db.Where("due_at <= ?", now.UnixMilli()).Order("due_at, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var cards []Flashcard
+	err := query.Find(&cards).Error
+	return cards, err
+}
+
+// RecordFlashcardReview grades a review from 0 (blackout) to 5 (perfect) and
+// reschedules the card with SM-2
+func (r *Repository) RecordFlashcardReview(id uint, quality int, now time.Time) (*Flashcard, error) {
+	if quality < 0 || quality > 5 {
+		return nil, fmt.Errorf("review quality must be between 0 and 5, got %d", quality)
+	}
+	var card Flashcard
+	if err := r.db.First(&card, id).Error; err != nil {
+		return nil, err
+	}
+	scheduleSM2(&card, quality, now)
+	if err := r.db.Model(&card).Updates(map[string]any{
+		"ease_factor":   card.EaseFactor,
+		"interval":      card.Interval,
+		"repetitions":   card.Repetitions,
+		"due_at":        card.DueAt,
+		"last_reviewed": card.LastReviewed,
+		"last_quality":  card.LastQuality,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// DeleteFlashcard removes a card
+func (r *Repository) DeleteFlashcard(id uint) error {
+	return r.db.Delete(&Flashcard{}, id).Error
+}
+
+// DeleteFlashcardsForPath removes the cards generated from a note
+func (r *Repository) DeleteFlashcardsForPath(path string) error {
+	return r.db.Where("path = ?", path).Delete(&Flashcard{}).Error
+}
+
+// RenameFlashcardPaths keeps cards attached to a renamed note or folder
+func (r *Repository) RenameFlashcardPaths(oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	if err := r.db.Model(&Flashcard{}).Where("path = ?", oldPath).Update("path", newPath).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&Flashcard{}).
+		Where("path LIKE ? ESCAPE '\\'", escapeLike(oldPath)+"/%").
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, utf8.RuneCountInString(oldPath)+1)).Error
+}
+
+// scheduleSM2 applies one SuperMemo-2 review to card. Failed recalls (quality
+// below 3) restart the repetition sequence; the ease factor is adjusted on
+// every review and never drops below 1.3.
+func scheduleSM2(card *Flashcard, quality int, now time.Time) {
+	if card.EaseFactor <= 0 {
+		card.EaseFactor = defaultEaseFactor
+	}
+	if quality < 3 {
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.Interval = 1
+		case 1:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.EaseFactor))
+		}
+		card.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	card.EaseFactor = math.Max(minEaseFactor, card.EaseFactor+0.1-q*(0.08+q*0.02))
+	card.LastQuality = quality
+	card.LastReviewed = now.UnixMilli()
+	card.DueAt = now.AddDate(0, 0, card.Interval).UnixMilli()
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleSM2(t *testing.T) {
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	card := &Flashcard{EaseFactor: defaultEaseFactor}
+
+	for i, want := range []int{1, 6, 16} {
+		scheduleSM2(card, 5, now)
+		if card.Interval != want || card.Repetitions != i+1 {
+			t.Fatalf("review %d: interval=%d repetitions=%d, want interval %d", i+1, card.Interval, card.Repetitions, want)
+		}
+	}
+	if card.EaseFactor <= defaultEaseFactor {
+		t.Fatalf("perfect reviews should raise the ease factor, got %v", card.EaseFactor)
+	}
+
+	scheduleSM2(card, 1, now)
+	if card.Interval != 1 || card.Repetitions != 0 {
+		t.Fatalf("failed recall should reset the card: %+v", card)
+	}
+	if want := now.AddDate(0, 0, 1).UnixMilli(); card.DueAt != want {
+		t.Fatalf("due_at = %d, want %d", card.DueAt, want)
+	}
+
+	for i := 0; i < 10; i++ {
+		scheduleSM2(card, 0, now)
+	}
+	if card.EaseFactor != minEaseFactor {
+		t.Fatalf("ease factor should bottom out at %v, got %v", minEaseFactor, card.EaseFactor)
+	}
+}
+
+func TestFlashcardsDueAndReview(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&Flashcard{}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	added, err := repo.AddFlashcards("notes/go.md", []FlashcardInput{
+		{Question: "What does defer do?", Answer: "Runs a call when the function returns"},
+		{Question: "what does defer do? ", Answer: "duplicate"},
+		{Question: "", Answer: "no question"},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 {
+		t.Fatalf("expected 1 card, got %d", len(added))
+	}
+	if again, err := repo.AddFlashcards("notes/go.md", []FlashcardInput{{Question: "What does defer do?", Answer: "x"}}, now); err != nil || len(again) != 0 {
+		t.Fatalf("expected existing question to be skipped, got %v, %v", again, err)
+	}
+
+	due, err := repo.GetDueFlashcards(now, 0)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected 1 due card, got %v, %v", due, err)
+	}
+	if _, err := repo.RecordFlashcardReview(due[0].ID, 6, now); err == nil {
+		t.Fatal("expected out-of-range quality to be rejected")
+	}
+	card, err := repo.RecordFlashcardReview(due[0].ID, 4, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Interval != 1 || card.Repetitions != 1 {
+		t.Fatalf("unexpected schedule: %+v", card)
+	}
+	if due, _ := repo.GetDueFlashcards(now, 0); len(due) != 0 {
+		t.Fatalf("reviewed card should not be due yet, got %d", len(due))
+	}
+	if due, _ := repo.GetDueFlashcards(now.AddDate(0, 0, 1), 0); len(due) != 1 {
+		t.Fatalf("card should be due after its interval, got %d", len(due))
+	}
+
+	if err := repo.RenameFlashcardPaths("notes", "archive/notes"); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := repo.ListFlashcards("archive/notes/go.md")
+	if err != nil || len(cards) != 1 {
+		t.Fatalf("expected card to follow the folder rename, got %v, %v", cards, err)
+	}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// History event kinds
+const (
+	HistoryOpen = "open"
+	HistoryEdit = "edit"
+)
+
+// historyCoalesceWindow is how close events of one kind on one note must be
+// to count as the same visit or editing session, so autosaves do not each
+// add a row
+const historyCoalesceWindow = 10 * time.Minute
+
+// HistoryEntry is a visit to a note or an editing session on it
+type HistoryEntry struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Path string `gorm:"index;not null;size:1024" json:"path"`
+	Kind string `gorm:"size:16;not null" json:"kind"` // HistoryOpen or HistoryEdit
+	// At is the time of the latest event of the visit or session
+	At time.Time `gorm:"index" json:"at"`
+	// Count is the number of events coalesced into the entry
+	Count int `json:"count"`
+}
+
+// TableName specifies the table name for HistoryEntry
+func (HistoryEntry) TableName() string {
+	return "history"
+}
+
+// RecentFile is a note with when it was last opened or edited
+type RecentFile struct {
+	Path       string    `json:"path"`
+	LastOpened time.Time `json:"last_opened"`
+	LastEdited time.Time `json:"last_edited"`
+	LastUsed   time.Time `json:"last_used"` // The later of the two
+}
+
+// EditCount is how often a note was edited in a period
+type EditCount struct {
+	Path     string    `json:"path"`
+	Edits    int       `json:"edits"`    // Saves, including autosaves
+	Sessions int       `json:"sessions"` // Editing sessions the saves fell into
+	LastEdit time.Time `json:"last_edit"`
+}
+
+// RecordHistory records that the note at path was opened or edited at at.
+// An event close to the previous one of its kind on the note extends it.
+func (r *Repository) RecordHistory(path, kind string, at time.Time) error {
+	if kind != HistoryOpen && kind != HistoryEdit {
+		return fmt.Errorf("unknown history event %q", kind)
+	}
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("path is required")
+	}
+	var last HistoryEntry
+	err := r.db.Where("path = ? AND kind = ?", path, kind).Order("at DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.ID != 0 && at.Sub(last.At) < historyCoalesceWindow && !at.Before(last.At) {
+		return r.db.Model(&last).Updates(map[string]any{"at": at, "count": gorm.Expr("count + 1")}).Error
+	}
+	return r.db.Create(&HistoryEntry{Path: path, Kind: kind, At: at, Count: 1}).Error
+}
+
+// historyAtSQL is history.at in Unix milliseconds, so aggregates compare
+// and return instants rather than the stored text
+const historyAtSQL = "CAST(ROUND((julianday(at) - 2440587.5) * 86400000) AS INTEGER)"
+
+// historyTime converts Unix milliseconds from historyAtSQL, 0 being never
+func historyTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// RecentHistory returns the limit notes opened or edited most recently,
+// most recent first
+func (r *Repository) RecentHistory(limit int) ([]RecentFile, error) {
+	var rows []struct {
+		Path       string
+		LastOpened int64
+		LastEdited int64
+	}
+	query := r.db.Model(&HistoryEntry{}).
+		Select("path, "+
+			"COALESCE(MAX(CASE WHEN kind = ? THEN "+historyAtSQL+" END), 0) AS last_opened, "+
+			"COALESCE(MAX(CASE WHEN kind = ? THEN "+historyAtSQL+" END), 0) AS last_edited", HistoryOpen, HistoryEdit).
+		Group("path").
+		Order("MAX(" + historyAtSQL + ") DESC, path")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	files := make([]RecentFile, len(rows))
+	for i, row := range rows {
+		files[i] = RecentFile{
+			Path:       row.Path,
+			LastOpened: historyTime(row.LastOpened),
+			LastEdited: historyTime(row.LastEdited),
+			LastUsed:   historyTime(max(row.LastOpened, row.LastEdited)),
+		}
+	}
+	return files, nil
+}
+
+// MostEditedFiles returns the limit notes edited most often since since, by
+// number of saves
+func (r *Repository) MostEditedFiles(since time.Time, limit int) ([]EditCount, error) {
+	var rows []struct {
+		Path     string
+		Edits    int
+		Sessions int
+		LastEdit int64
+	}
+	query := r.db.Model(&HistoryEntry{}).
+		Select("path, SUM(count) AS edits, COUNT(*) AS sessions, MAX("+historyAtSQL+") AS last_edit").
+		Where("kind = ? AND at >= ?", HistoryEdit, since).
+		Group("path").
+		Order("edits DESC, last_edit DESC, path")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make([]EditCount, len(rows))
+	for i, row := range rows {
+		counts[i] = EditCount{Path: row.Path, Edits: row.Edits, Sessions: row.Sessions, LastEdit: historyTime(row.LastEdit)}
+	}
+	return counts, nil
+}
+
+// DeleteHistoryForPath forgets the history of a deleted note
+func (r *Repository) DeleteHistoryForPath(path string) error {
+	return r.db.Where("path = ?", path).Delete(&HistoryEntry{}).Error
+}
+
+// RenameHistoryPaths keeps the history of a renamed note or folder
+func (r *Repository) RenameHistoryPaths(oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	if err := r.db.Model(&HistoryEntry{}).Where("path = ?", oldPath).Update("path", newPath).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&HistoryEntry{}).
+		Where("path LIKE ? ESCAPE '\\'", escapeLike(oldPath)+"/%").
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, utf8.RuneCountInString(oldPath)+1)).Error
+}
+
+// PruneHistory deletes history older than before
+func (r *Repository) PruneHistory(before time.Time) error {
+	return r.db.Where("at < ?", before).Delete(&HistoryEntry{}).Error
+}
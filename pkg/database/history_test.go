@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&HistoryEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	events := []struct {
+		path, kind string
+		offset     time.Duration
+	}{
+		{"a.md", HistoryOpen, 0},
+		{"a.md", HistoryEdit, time.Minute},
+		{"a.md", HistoryEdit, 3 * time.Minute}, // same session
+		{"a.md", HistoryEdit, time.Hour},       // new session
+		{"b.md", HistoryOpen, 2 * time.Hour},
+		{"notes/c.md", HistoryEdit, 3 * time.Hour},
+	}
+	for _, e := range events {
+		if err := repo.RecordHistory(e.path, e.kind, base.Add(e.offset)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.RecordHistory("a.md", "viewed", base); err == nil {
+		t.Fatal("expected unknown event kind to be rejected")
+	}
+
+	recent, err := repo.RecentHistory(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 3 || recent[0].Path != "notes/c.md" || recent[2].Path != "a.md" {
+		t.Fatalf("unexpected recent files: %+v", recent)
+	}
+	if a := recent[2]; !a.LastOpened.Equal(base) || !a.LastEdited.Equal(base.Add(time.Hour)) || !a.LastUsed.Equal(a.LastEdited) {
+		t.Fatalf("unexpected times: %+v", a)
+	}
+
+	edited, err := repo.MostEditedFiles(base, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited) != 2 || edited[0].Path != "a.md" || edited[0].Edits != 3 || edited[0].Sessions != 2 {
+		t.Fatalf("unexpected edit counts: %+v", edited)
+	}
+	if edited, _ := repo.MostEditedFiles(base.Add(2*time.Hour), 10); len(edited) != 1 || edited[0].Path != "notes/c.md" {
+		t.Fatalf("range not applied: %+v", edited)
+	}
+
+	if err := repo.RenameHistoryPaths("notes", "archive"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteHistoryForPath("b.md"); err != nil {
+		t.Fatal(err)
+	}
+	recent, _ = repo.RecentHistory(1)
+	if len(recent) != 1 || recent[0].Path != "archive/c.md" {
+		t.Fatalf("unexpected recent files after rename: %+v", recent)
+	}
+	if err := repo.PruneHistory(base.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if recent, _ = repo.RecentHistory(10); len(recent) != 1 {
+		t.Fatalf("expected only recent history to survive pruning: %+v", recent)
+	}
+}
+
+func TestHistoryComparesInstantsAcrossTimeZones(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&HistoryEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored as text, 13:00+09:00 sorts after 09:00Z but is five hours earlier
+	utc := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	tokyo := time.Date(2026, 3, 1, 13, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	if err := repo.RecordHistory("utc.md", HistoryEdit, utc); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RecordHistory("tokyo.md", HistoryEdit, tokyo); err != nil {
+		t.Fatal(err)
+	}
+
+	recent, err := repo.RecentHistory(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Path != "utc.md" || !recent[0].LastEdited.Equal(utc) || !recent[1].LastUsed.Equal(tokyo) {
+		t.Fatalf("unexpected recent files: %+v", recent)
+	}
+	edited, err := repo.MostEditedFiles(utc.Add(-24*time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edited) != 2 || edited[0].Path != "utc.md" || !edited[1].LastEdit.Equal(tokyo) {
+		t.Fatalf("unexpected edit counts: %+v", edited)
+	}
+}
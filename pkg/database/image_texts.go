@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImageText caches the text read from an image, by OCR or as a description,
+// keyed by a hash of the image bytes and the reader, so notes are re-indexed
+// without reading their images again
+type ImageText struct {
+	Hash      string    `gorm:"primaryKey;size:64" json:"hash"`
+	Extractor string    `gorm:"primaryKey;size:64" json:"extractor"`
+	Text      string    `gorm:"type:text" json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ImageText
+func (ImageText) TableName() string {
+	return "image_texts"
+}
+
+// GetImageText returns the cached text of the image with hash, and false if
+// the image was not read by extractor yet
+func (r *Repository) GetImageText(hash, extractor string) (string, bool, error) {
+	var cached ImageText
+	err := r.db.Where("hash = ? AND extractor = ?", hash, extractor).First(&cached).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return cached.Text, true, nil
+}
+
+// SaveImageText caches the text extractor read from the image with hash
+func (r *Repository) SaveImageText(hash, extractor, text string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}, {Name: "extractor"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "created_at"}),
+	}).Create(&ImageText{Hash: hash, Text: text, Extractor: extractor}).Error
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Index error stages, in pipeline order
+const (
+	IndexStageRead      = "read"
+	IndexStageStat      = "stat"
+	IndexStageEmbedding = "embedding"
+	IndexStageChunking  = "chunking"
+	IndexStageMetadata  = "metadata"
+)
+
+// IndexError records the latest indexing failure for a file. Failures at the
+// embedding stage are recorded even when a fallback later succeeded, because the
+// file is then only partially indexed.
+type IndexError struct {
+	Path      string `gorm:"primaryKey;size:1024" json:"path"`
+	Stage     string `gorm:"index;size:32" json:"stage"`
+	Message   string `gorm:"type:text" json:"message"`
+	Failures  int    `json:"failures"`
+	Timestamp int64  `gorm:"index" json:"timestamp"` // Unix ms of the latest failure
+	UpdatedAt time.Time
+}
+
+// TableName specifies the table name for IndexError
+func (IndexError) TableName() string {
+	return "index_errors"
+}
+
+// RecordIndexError stores or updates the failure record for path
+func (r *Repository) RecordIndexError(path, stage, message string) error {
+	rec := IndexError{
+		Path:      path,
+		Stage:     stage,
+		Message:   message,
+		Failures:  1,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "path"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"stage":      rec.Stage,
+			"message":    rec.Message,
+			"timestamp":  rec.Timestamp,
+			"failures":   gorm.Expr("failures + 1"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&rec).Error
+}
+
+// ClearIndexError removes the failure record for path after a successful index
+func (r *Repository) ClearIndexError(path string) error {
+	return r.db.Where("path = ?", path).Delete(&IndexError{}).Error
+}
+
+// ListIndexErrors returns recorded failures, most recent first
+func (r *Repository) ListIndexErrors() ([]IndexError, error) {
+	var errs []IndexError
+	err := r.db.Order("timestamp DESC").Find(&errs).Error
+	return errs, err
+}
+
+// CountIndexErrors returns the number of files with a recorded failure
+func (r *Repository) CountIndexErrors() (int64, error) {
+	var count int64
+	err := r.db.Model(&IndexError{}).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	indexExportFormat  = "notebit-index"
+	indexExportVersion = 1
+)
+
+// IndexTransferStats summarises an index export or import
+type IndexTransferStats struct {
+	Files   int `json:"files"`
+	Chunks  int `json:"chunks"`
+	Skipped int `json:"skipped,omitempty"` // files left out of an import
+}
+
+// indexExportHeader is the first line of an index export
+type indexExportHeader struct {
+	Type       string    `json:"type"`
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// indexExportFile is one line of an index export: a file and all its chunks
+type indexExportFile struct {
+	Type         string             `json:"type"`
+	Path         string             `json:"path"`
+	Title        string             `json:"title"`
+	ContentHash  string             `json:"content_hash"`
+	LastModified int64              `json:"last_modified"`
+	FileSize     int64              `json:"file_size"`
+	Created      int64              `json:"created,omitempty"`
+	Stats        NoteStats          `json:"stats"`
+	Chunks       []indexExportChunk `json:"chunks"`
+}
+
+type indexExportChunk struct {
+	Content        string `json:"content"`
+	Heading        string `json:"heading,omitempty"`
+	Language       string `json:"language,omitempty"`
+	Source         string `json:"source,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	Embedding      []byte `json:"embedding,omitempty"` // little-endian float32, base64 in JSON
+}
+
+// ExportIndex writes every indexed file with its chunks and embeddings to w
+// as JSON lines: a header, then one line per file
+func (r *Repository) ExportIndex(w io.Writer) (*IndexTransferStats, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	header := indexExportHeader{
+		Type:       "header",
+		Format:     indexExportFormat,
+		Version:    indexExportVersion,
+		ExportedAt: time.Now().UTC(),
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+
+	stats := &IndexTransferStats{}
+	var batch []File
+	result := r.db.Preload("Chunks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("id").FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, f := range batch {
+			record := indexExportFile{
+				Type:         "file",
+				Path:         f.Path,
+				Title:        f.Title,
+				ContentHash:  f.ContentHash,
+				LastModified: f.LastModified,
+				FileSize:     f.FileSize,
+				Created:      f.Created,
+				Stats:        f.Stats,
+				Chunks:       make([]indexExportChunk, 0, len(f.Chunks)),
+			}
+			for _, c := range f.Chunks {
+				blob := c.EmbeddingBlob
+				if len(blob) == 0 && len(c.Embedding) > 0 {
+					blob = floatsToBytes(c.Embedding)
+				}
+				record.Chunks = append(record.Chunks, indexExportChunk{
+					Content:        c.Content,
+					Heading:        c.Heading,
+					Language:       c.Language,
+					Source:         c.Source,
+					EmbeddingModel: c.EmbeddingModel,
+					Embedding:      blob,
+				})
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+			stats.Files++
+			stats.Chunks += len(record.Chunks)
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ImportIndex restores files, chunks and embeddings written by ExportIndex,
+// replacing what is indexed for the same paths. Gzipped exports are detected.
+// keep, if not nil, decides per file whether to import it, e.g. to leave out
+// files whose content on disk no longer matches the exported hash.
+func (r *Repository) ImportIndex(src io.Reader, keep func(path, contentHash string) bool) (*IndexTransferStats, error) {
+	br := bufio.NewReader(src)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	dec := json.NewDecoder(br)
+	var header indexExportHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("read index export header: %w", err)
+	}
+	if header.Type != "header" || header.Format != indexExportFormat {
+		return nil, errors.New("not a notebit index export")
+	}
+	if header.Version > indexExportVersion {
+		return nil, fmt.Errorf("index export version %d is newer than supported version %d", header.Version, indexExportVersion)
+	}
+
+	stats := &IndexTransferStats{}
+	for {
+		var record indexExportFile
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("read index export: %w", err)
+		}
+		if record.Type != "file" || record.Path == "" {
+			continue
+		}
+		if keep != nil && !keep(record.Path, record.ContentHash) {
+			stats.Skipped++
+			continue
+		}
+
+		chunks := make([]ChunkInput, 0, len(record.Chunks))
+		for _, c := range record.Chunks {
+			chunks = append(chunks, ChunkInput{
+				Content:        c.Content,
+				Heading:        c.Heading,
+				Language:       c.Language,
+				Source:         c.Source,
+				Embedding:      bytesToFloats(c.Embedding),
+				EmbeddingModel: c.EmbeddingModel,
+			})
+		}
+		file := File{
+			Path:         record.Path,
+			Title:        record.Title,
+			ContentHash:  record.ContentHash,
+			LastModified: record.LastModified,
+			FileSize:     record.FileSize,
+			Created:      record.Created,
+			Stats:        record.Stats,
+		}
+		if err := r.storeFileWithChunks(file, chunks); err != nil {
+			return stats, fmt.Errorf("import %s: %w", record.Path, err)
+		}
+		stats.Files++
+		stats.Chunks += len(chunks)
+	}
+	return stats, nil
+}
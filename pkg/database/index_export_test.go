@@ -0,0 +1,86 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestExportImportIndexRoundTrip(t *testing.T) {
+	src, cleanupSrc := setupRepositoryTestDB(t)
+	defer cleanupSrc()
+
+	kept := "# Kept\n\nbody"
+	stale := "# Stale\n\nbody"
+	if err := src.IndexFileWithChunks("kept.md", kept, NoteStats{}, 10, int64(len(kept)), []ChunkInput{
+		{Content: "first", Heading: "Kept", Embedding: []float32{0.1, 0.2, 0.3}, EmbeddingModel: "m"},
+		{Content: "second", Embedding: []float32{0.4, 0.5, 0.6}, EmbeddingModel: "m"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.IndexFileWithChunks("stale.md", stale, NoteStats{}, 20, int64(len(stale)), []ChunkInput{
+		{Content: "old", Embedding: []float32{1, 0, 0}, EmbeddingModel: "m"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	exported, err := src.ExportIndex(zw)
+	if err != nil {
+		t.Fatalf("ExportIndex: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if exported.Files != 2 || exported.Chunks != 3 {
+		t.Fatalf("exported %+v, want 2 files and 3 chunks", exported)
+	}
+
+	dst, cleanupDst := setupRepositoryTestDB(t)
+	defer cleanupDst()
+	imported, err := dst.ImportIndex(&buf, func(path, _ string) bool { return path != "stale.md" })
+	if err != nil {
+		t.Fatalf("ImportIndex: %v", err)
+	}
+	if imported.Files != 1 || imported.Chunks != 2 || imported.Skipped != 1 {
+		t.Fatalf("imported %+v, want 1 file, 2 chunks, 1 skipped", imported)
+	}
+
+	needs, err := dst.FileNeedsIndexing("kept.md", kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needs {
+		t.Fatal("imported file should not need indexing again")
+	}
+	file, err := dst.GetFileByPath("kept.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Title != "Kept" || file.LastModified != 10 {
+		t.Fatalf("file metadata not restored: %+v", file)
+	}
+	chunks, err := dst.GetChunksByFileID(file.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || chunks[0].Content != "first" || chunks[0].Heading != "Kept" {
+		t.Fatalf("chunks not restored: %+v", chunks)
+	}
+	if got := chunks[1].GetEmbedding(); len(got) != 3 || got[0] != 0.4 || chunks[1].EmbeddingModel != "m" {
+		t.Fatalf("embedding not restored: %v (%s)", got, chunks[1].EmbeddingModel)
+	}
+	if _, err := dst.GetFileByPath("stale.md"); err == nil {
+		t.Fatal("skipped file was imported")
+	}
+}
+
+func TestImportIndexRejectsOtherFormats(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if _, err := repo.ImportIndex(bytes.NewBufferString(`{"type":"header","format":"other","version":1}`+"\n"), nil); err == nil {
+		t.Fatal("expected an error for a foreign file")
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	IndexJobPending = "pending"
+	IndexJobRunning = "running"
+)
+
+// IndexJobRecord is the persisted form of a queued indexing job. Content is never
+// stored; resumed jobs re-read the file from disk.
+type IndexJobRecord struct {
+	Path                   string `gorm:"primaryKey;size:1024" json:"path"`
+	State                  string `gorm:"index;size:16" json:"state"`
+	SkipIfUnchanged        bool   `json:"skip_if_unchanged"`
+	FallbackToMetadataOnly bool   `json:"fallback_to_metadata_only"`
+	ForceReindex           bool   `json:"force_reindex"`
+	Attempts               int    `json:"attempts"`
+	EnqueuedAt             int64  `gorm:"index" json:"enqueued_at"` // Unix nanoseconds of the latest enqueue
+	UpdatedAt              time.Time
+}
+
+// TableName specifies the table name for IndexJobRecord
+func (IndexJobRecord) TableName() string {
+	return "index_jobs"
+}
+
+// SaveIndexJob records a pending job, replacing any earlier record for the same path
+func (r *Repository) SaveIndexJob(job IndexJobRecord) error {
+	job.State = IndexJobPending
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state", "skip_if_unchanged", "fallback_to_metadata_only", "force_reindex", "enqueued_at", "updated_at"}),
+	}).Create(&job).Error
+}
+
+// MarkIndexJobRunning flags a job as in flight so it can be requeued after a crash
+func (r *Repository) MarkIndexJobRunning(path string) error {
+	return r.db.Model(&IndexJobRecord{}).Where("path = ?", path).Updates(map[string]interface{}{
+		"state":    IndexJobRunning,
+		"attempts": gorm.Expr("attempts + 1"),
+	}).Error
+}
+
+// CompleteIndexJob removes a finished job. Records re-enqueued after enqueuedAt are kept.
+func (r *Repository) CompleteIndexJob(path string, enqueuedAt int64) error {
+	return r.db.Where("path = ? AND enqueued_at <= ?", path, enqueuedAt).Delete(&IndexJobRecord{}).Error
+}
+
+// RequeueRunningIndexJobs resets jobs left running by an unclean shutdown back to pending
+func (r *Repository) RequeueRunningIndexJobs() (int64, error) {
+	result := r.db.Model(&IndexJobRecord{}).Where("state = ?", IndexJobRunning).Update("state", IndexJobPending)
+	return result.RowsAffected, result.Error
+}
+
+// ListPendingIndexJobs returns queued jobs in enqueue order
+func (r *Repository) ListPendingIndexJobs() ([]IndexJobRecord, error) {
+	var jobs []IndexJobRecord
+	err := r.db.Where("state = ?", IndexJobPending).Order("enqueued_at ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// CountIndexJobs returns the number of persisted jobs
+func (r *Repository) CountIndexJobs() (int64, error) {
+	var count int64
+	err := r.db.Model(&IndexJobRecord{}).Count(&count).Error
+	return count, err
+}
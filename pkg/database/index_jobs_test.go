@@ -0,0 +1,50 @@
+package database
+
+import "testing"
+
+func TestIndexJobQueueSurvivesCrash(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&IndexJobRecord{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SaveIndexJob(IndexJobRecord{Path: "a.md", SkipIfUnchanged: true, EnqueuedAt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveIndexJob(IndexJobRecord{Path: "b.md", EnqueuedAt: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crash while a.md was being processed
+	if err := repo.MarkIndexJobRunning("a.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	requeued, err := repo.RequeueRunningIndexJobs()
+	if err != nil || requeued != 1 {
+		t.Fatalf("expected 1 requeued job, got %d (%v)", requeued, err)
+	}
+	pending, err := repo.ListPendingIndexJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Path != "a.md" || !pending[0].SkipIfUnchanged || pending[0].Attempts != 1 {
+		t.Fatalf("unexpected pending jobs: %+v", pending)
+	}
+
+	// A re-enqueue after the job started must not be removed by the older completion
+	if err := repo.SaveIndexJob(IndexJobRecord{Path: "b.md", EnqueuedAt: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CompleteIndexJob("b.md", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CompleteIndexJob("a.md", 1); err != nil {
+		t.Fatal(err)
+	}
+	count, _ := repo.CountIndexJobs()
+	if count != 1 {
+		t.Fatalf("expected only the re-enqueued job to remain, got %d", count)
+	}
+}
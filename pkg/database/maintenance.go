@@ -0,0 +1,129 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+// optimizeStateFile records the database size after the last optimization,
+// relative to the data directory
+const optimizeStateFile = "db_optimize_state.json"
+
+// OptimizeResult reports what OptimizeDatabase did
+type OptimizeResult struct {
+	SizeBefore        int64 `json:"size_before"` // bytes, database plus WAL
+	SizeAfter         int64 `json:"size_after"`
+	VecOrphansRemoved int64 `json:"vec_orphans_removed"`
+	DurationMillis    int64 `json:"duration_ms"`
+	RanAt             int64 `json:"ran_at"` // Unix ms
+}
+
+// Optimize checkpoints the WAL, drops vector index rows whose chunk is gone,
+// rebuilds the database file with VACUUM and refreshes the query planner
+// statistics. Writers wait on the busy timeout while it runs.
+func (m *Manager) Optimize() (*OptimizeResult, error) {
+	db := m.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	start := time.Now()
+	result := &OptimizeResult{SizeBefore: m.diskSize()}
+
+	if err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+		return nil, &DatabaseError{Op: "checkpoint", Err: err}
+	}
+
+	var vecTable bool
+	if err := db.Raw("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type='table' AND name='vec_chunks'").Scan(&vecTable).Error; err != nil {
+		return nil, err
+	}
+	if vecTable {
+		res := db.Exec("DELETE FROM vec_chunks WHERE chunk_id NOT IN (SELECT id FROM chunks)")
+		switch {
+		case res.Error == nil:
+			result.VecOrphansRemoved = res.RowsAffected
+		case vecUnavailable(res.Error):
+			// Without the extension the table cannot be touched; nothing reads it either
+		default:
+			return nil, &DatabaseError{Op: "compact_vec", Err: res.Error}
+		}
+	}
+
+	for _, step := range []string{"VACUUM", "ANALYZE", "PRAGMA optimize", "PRAGMA wal_checkpoint(TRUNCATE)"} {
+		if err := db.Exec(step).Error; err != nil {
+			return nil, &DatabaseError{Op: step, Err: err}
+		}
+	}
+
+	result.SizeAfter = m.diskSize()
+	result.DurationMillis = time.Since(start).Milliseconds()
+	result.RanAt = time.Now().UnixMilli()
+	m.saveOptimizeState(result)
+	return result, nil
+}
+
+// OptimizeIfGrown runs Optimize once the database has grown by threshold
+// bytes since it was last optimized. The first call only records the
+// current size. It returns nil when nothing ran.
+func (m *Manager) OptimizeIfGrown(threshold int64) (*OptimizeResult, error) {
+	if threshold <= 0 || !m.IsInitialized() {
+		return nil, nil
+	}
+	size := m.diskSize()
+	last, ok := m.loadOptimizeState()
+	if !ok {
+		m.saveOptimizeState(&OptimizeResult{SizeAfter: size, RanAt: time.Now().UnixMilli()})
+		return nil, nil
+	}
+	if size-last.SizeAfter < threshold {
+		return nil, nil
+	}
+	logger.Info("Database grew from %d to %d bytes since last optimization, optimizing", last.SizeAfter, size)
+	return m.Optimize()
+}
+
+// LastOptimize returns the last optimization recorded for this vault
+func (m *Manager) LastOptimize() *OptimizeResult {
+	last, ok := m.loadOptimizeState()
+	if !ok {
+		return nil
+	}
+	return &last
+}
+
+// diskSize is the size of the database file and its WAL
+func (m *Manager) diskSize() int64 {
+	path := m.GetDBPath()
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+func (m *Manager) optimizeStatePath() string {
+	return filepath.Join(filepath.Dir(m.GetDBPath()), optimizeStateFile)
+}
+
+func (m *Manager) loadOptimizeState() (OptimizeResult, bool) {
+	var state OptimizeResult
+	data, err := os.ReadFile(m.optimizeStatePath())
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return state, false
+	}
+	return state, true
+}
+
+func (m *Manager) saveOptimizeState(result *OptimizeResult) {
+	data, _ := json.Marshal(result)
+	if err := os.WriteFile(m.optimizeStatePath(), data, 0644); err != nil {
+		logger.Warn("Failed to save optimize state: %v", err)
+	}
+}
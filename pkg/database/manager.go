@@ -42,13 +42,25 @@ var (
 // GetInstance returns the singleton database manager
 func GetInstance() *Manager {
 	once.Do(func() {
-		instance = &Manager{}
+		instance = NewManager()
 	})
 	return instance
 }
 
-// Init initializes the database connection
+// NewManager returns a manager independent of the singleton, so each vault
+// can own its database and be closed as a whole when switching away
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Init initializes the database connection to <basePath>/data/notebit.sqlite
 func (m *Manager) Init(basePath string) error {
+	return m.InitAt(basePath, filepath.Join(basePath, "data", "notebit.sqlite"))
+}
+
+// InitAt initializes the database of the vault at basePath from dbPath,
+// e.g. a decrypted copy kept outside the vault
+func (m *Manager) InitAt(basePath, dbPath string) error {
 	timer := logger.StartTimer()
 	logger.InfoWithFields(context.TODO(), map[string]interface{}{"base_path": basePath}, "Initializing database")
 
@@ -70,7 +82,7 @@ func (m *Manager) Init(basePath string) error {
 	m.initErr = nil
 	m.mu.Unlock()
 
-	dataDir := filepath.Join(basePath, "data")
+	dataDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		logger.ErrorWithFields(context.TODO(), map[string]interface{}{
 			"data_dir": dataDir,
@@ -82,7 +94,6 @@ func (m *Manager) Init(basePath string) error {
 		return m.initErr
 	}
 
-	dbPath := filepath.Join(dataDir, "notebit.sqlite")
 	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=1", dbPath)
 	driverName := defaultSQLiteDriver
 	if registerSQLiteVecDriver() {
@@ -97,6 +108,9 @@ func (m *Manager) Init(basePath string) error {
 	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
 	})
+	if driverName == vecSQLiteDriver {
+		recordVecLoad(db, err)
+	}
 	if err != nil && driverName == vecSQLiteDriver {
 		logger.WarnWithFields(context.TODO(), map[string]interface{}{
 			"error": err.Error(),
@@ -176,8 +190,13 @@ func registerSQLiteVecDriver() bool {
 			}
 		}()
 
+		// The extension is loaded per connection from the path current at
+		// the time, so an extension installed while running is picked up by
+		// the next database opened
 		sql.Register(vecSQLiteDriver, &sqlite3.SQLiteDriver{
-			Extensions: []string{"vec0"},
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				return conn.LoadExtension(SQLiteVecExtension(), "sqlite3_vec_init")
+			},
 		})
 
 		for _, name := range sql.Drivers() {
@@ -220,11 +239,23 @@ func (m *Manager) Close() error {
 		if err != nil {
 			return err
 		}
+		m.db = nil
+		m.repo = nil
 		return sqlDB.Close()
 	}
 	return nil
 }
 
+// Snapshot writes a consistent copy of the database to dest, which must
+// not exist. The database stays usable while the copy is made.
+func (m *Manager) Snapshot(dest string) error {
+	db := m.GetDB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return db.Exec("VACUUM INTO ?", dest).Error
+}
+
 // GetDB returns the GORM DB instance (internal use)
 func (m *Manager) GetDB() *gorm.DB {
 	m.mu.RLock()
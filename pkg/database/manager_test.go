@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewManager_VaultsAreIndependent(t *testing.T) {
+	work, home := t.TempDir(), t.TempDir()
+
+	workDB := NewManager()
+	if err := workDB.Init(work); err != nil {
+		t.Fatalf("init work vault failed: %v", err)
+	}
+	homeDB := NewManager()
+	if err := homeDB.Init(home); err != nil {
+		t.Fatalf("init home vault failed: %v", err)
+	}
+	defer homeDB.Close()
+
+	if err := workDB.Repository().IndexFile("a.md", "# A", NoteStats{}, 1, 3); err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+	if err := workDB.Close(); err != nil {
+		t.Fatalf("close work vault failed: %v", err)
+	}
+	if workDB.IsInitialized() {
+		t.Fatal("closed manager should report uninitialized")
+	}
+
+	if !homeDB.IsInitialized() || homeDB.GetBasePath() != home {
+		t.Fatalf("closing one vault affected the other: base=%q", homeDB.GetBasePath())
+	}
+	var count int64
+	if err := homeDB.GetDB().Model(&File{}).Count(&count).Error; err != nil {
+		t.Fatalf("home vault unusable after switching: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected separate databases, home vault has %d files", count)
+	}
+}
+
+func TestSnapshotCopiesOpenDatabase(t *testing.T) {
+	m := NewManager()
+	if err := m.Init(t.TempDir()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	defer m.Close()
+	if err := m.Repository().IndexFile("a.md", "# A", NoteStats{}, 1, 3); err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "snapshot.sqlite")
+	if err := m.Snapshot(dest); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	copied := NewManager()
+	if err := copied.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	if err := copied.GetDB().Exec("ATTACH DATABASE ? AS snap", dest).Error; err != nil {
+		t.Fatalf("attach snapshot: %v", err)
+	}
+	var count int64
+	if err := copied.GetDB().Raw("SELECT COUNT(*) FROM snap.files").Scan(&count).Error; err != nil || count != 1 {
+		t.Fatalf("expected 1 file in snapshot, got %d (%v)", count, err)
+	}
+}
+
+func TestOptimizeShrinksAfterDeletes(t *testing.T) {
+	m := NewManager()
+	if err := m.Init(t.TempDir()); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	defer m.Close()
+	repo := m.Repository()
+	body := strings.Repeat("lorem ipsum dolor sit amet ", 2000)
+	for i := 0; i < 50; i++ {
+		if err := repo.IndexFile(fmt.Sprintf("n%d.md", i), body, NoteStats{}, 1, int64(len(body))); err != nil {
+			t.Fatalf("index failed: %v", err)
+		}
+	}
+	if err := m.GetDB().Exec("DELETE FROM files").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := m.OptimizeIfGrown(1); err != nil || result != nil {
+		t.Fatalf("first call should only record the size: %+v %v", result, err)
+	}
+	result, err := m.Optimize()
+	if err != nil {
+		t.Fatalf("Optimize: %v", err)
+	}
+	if result.SizeAfter >= result.SizeBefore {
+		t.Fatalf("expected the database to shrink: %d -> %d", result.SizeBefore, result.SizeAfter)
+	}
+	if last := m.LastOptimize(); last == nil || last.SizeAfter != result.SizeAfter {
+		t.Fatalf("last optimize not recorded: %+v", last)
+	}
+}
+
+func TestSchemaMigrationV2RequeuesVecChunks(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	file := File{Path: "a.md", Title: "A"}
+	if err := repo.db.Create(&file).Error; err != nil {
+		t.Fatal(err)
+	}
+	vec := []float32{3, 4}
+	if err := repo.db.Create(&Chunk{FileID: file.ID, Content: "a", EmbeddingBlob: floatsToBytes(vec), VecIndexed: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applySchemaMigrationV2(repo.db); err != nil {
+		t.Fatal(err)
+	}
+	var pending int64
+	if err := repo.db.Model(&Chunk{}).Where("vec_indexed = ?", false).Count(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pending != 1 {
+		t.Fatalf("%d chunks queued for vec_chunks, want 1", pending)
+	}
+}
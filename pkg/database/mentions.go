@@ -0,0 +1,33 @@
+package database
+
+import "strings"
+
+// PathsMentioning returns the sorted paths of indexed notes, other than
+// excludePath, with a chunk containing any of terms. Matching is the SQLite
+// LIKE comparison, so it is case-insensitive for ASCII only; callers verify
+// the actual occurrences.
+func (r *Repository) PathsMentioning(terms []string, excludePath string) ([]string, error) {
+	query := r.db.Model(&Chunk{}).
+		Distinct("files.path").
+		Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+		Where("files.path <> ?", excludePath)
+
+	var conditions []string
+	var args []any
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term == "" {
+			continue
+		}
+		conditions = append(conditions, "chunks.content LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	var paths []string
+	err := query.Where(strings.Join(conditions, " OR "), args...).
+		Order("files.path").
+		Pluck("files.path", &paths).Error
+	return paths, err
+}
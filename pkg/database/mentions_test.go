@@ -0,0 +1,39 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathsMentioning(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	for path, content := range map[string]string{
+		"target.md":   "Project Atlas is the target note",
+		"b.md":        "we discussed project atlas today",
+		"a.md":        "see [[Atlas]] and the Atlas roadmap",
+		"other.md":    "nothing relevant here",
+		"percent.md":  "100% done",
+		"underbar.md": "a_b",
+	} {
+		if err := repo.IndexFileWithChunks(path, content, NoteStats{}, 1, int64(len(content)), []ChunkInput{{Content: content}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := repo.PathsMentioning([]string{"Project Atlas", "Atlas"}, "target.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "b.md"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("got %v, want %v", paths, want)
+	}
+
+	if paths, err := repo.PathsMentioning([]string{"%"}, ""); err != nil || !reflect.DeepEqual(paths, []string{"percent.md"}) {
+		t.Fatalf("LIKE wildcards should be escaped, got %v, %v", paths, err)
+	}
+	if paths, err := repo.PathsMentioning([]string{" "}, ""); err != nil || paths != nil {
+		t.Fatalf("blank terms should match nothing, got %v, %v", paths, err)
+	}
+}
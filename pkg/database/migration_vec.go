@@ -189,8 +189,8 @@ func (m *Manager) CleanupLegacyEmbeddings(ctx context.Context) error {
 
 // insertVecChunk inserts a chunk embedding into the vec_chunks virtual table
 func insertVecChunk(tx *gorm.DB, chunkID uint, embedding []float32) error {
-	// Encode embedding as binary blob for sqlite-vec
-	blob := floatsToBytes(embedding)
+	// Stored unit length, so the L2 distance vec0 ranks by orders like cosine
+	blob := floatsToBytes(normalizeVector(embedding))
 
 	// sqlite-vec INSERT syntax: INSERT INTO vec_chunks(chunk_id, embedding) VALUES (?, ?)
 	// The embedding parameter should be the raw binary blob
@@ -10,7 +10,7 @@ import (
 	"gorm.io/gorm"
 )
 
-const currentSchemaVersion = 1
+const currentSchemaVersion = 3
 
 type schemaVersion struct {
 	Version int `gorm:"primaryKey"`
@@ -28,6 +28,19 @@ func (m *Manager) AutoMigrate() error {
 		&Chunk{},
 		&Tag{},
 		&FileTag{},
+		&IndexJobRecord{},
+		&IndexError{},
+		&ChunkEmbedding{},
+		&Collection{},
+		&CollectionItem{},
+		&UsageRecord{},
+		&Flashcard{},
+		&SyncState{},
+		&ImageText{},
+		&Feed{},
+		&FeedEntry{},
+		&HistoryEntry{},
+		&FavoriteNote{},
 		&schemaVersion{},
 	); err != nil {
 		return err
@@ -77,6 +90,14 @@ func (m *Manager) migrateSchemaVersion(db *gorm.DB) error {
 			if err := applySchemaMigrationV1(db); err != nil {
 				return err
 			}
+		case 2:
+			if err := applySchemaMigrationV2(db); err != nil {
+				return err
+			}
+		case 3:
+			if err := applySchemaMigrationV3(db); err != nil {
+				return err
+			}
 		}
 
 		if err := db.Create(&schemaVersion{Version: v}).Error; err != nil {
@@ -108,3 +129,19 @@ func applySchemaMigrationV1(db *gorm.DB) error {
 
 	return nil
 }
+
+// applySchemaMigrationV2 queues every chunk to be copied into vec_chunks
+// again, since vectors are now stored there unit length. MigrateToVec
+// rewrites them; until it has, searches fall back to brute force.
+func applySchemaMigrationV2(db *gorm.DB) error {
+	return db.Model(&Chunk{}).Where("vec_indexed = ?", true).Update("vec_indexed", false).Error
+}
+
+// applySchemaMigrationV3 recomputes every note centroid from unit-length
+// chunk vectors, and stores those of notes indexed before centroids were kept
+func applySchemaMigrationV3(db *gorm.DB) error {
+	if err := db.Model(&File{}).Where("centroid IS NOT NULL").Update("centroid", nil).Error; err != nil {
+		return err
+	}
+	return backfillCentroids(db)
+}
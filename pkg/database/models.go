@@ -19,17 +19,41 @@ type File struct {
 	ContentHash  string `gorm:"index;size:64" json:"content_hash"` // SHA-256 for change detection
 	LastModified int64  `json:"last_modified"`                     // Unix timestamp
 	FileSize     int64  `json:"file_size"`                         // Bytes
+	// Created is when the note was created, from its front matter or its
+	// file; 0 until the indexer records it
+	Created int64 `gorm:"index" json:"created"` // Unix timestamp
+
+	// Stats are counted from the content when the file is indexed
+	Stats NoteStats `gorm:"embedded;embeddedPrefix:stat_" json:"stats"`
+
+	// Centroid is the normalized mean of the file's normalized chunk embeddings,
+	// used for note-level similarity; nil until a chunk is embedded
+	Centroid []byte `gorm:"type:blob" json:"-"`
 
 	// Relationships
 	Chunks []Chunk `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"chunks,omitempty"`
 	Tags   []Tag   `gorm:"many2many:file_tags;" json:"tags,omitempty"`
 }
 
+// NoteStats are the counts the editor status bar shows for a note. Front
+// matter is not part of the note's text.
+type NoteStats struct {
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	Headings   int `json:"headings"`
+	Links      int `json:"links"` // Wiki links, embeds and [text](url) links
+}
+
 // TableName specifies the table name for File
 func (File) TableName() string {
 	return "files"
 }
 
+// GetCentroid returns the file's note-level embedding, nil if it has none
+func (f *File) GetCentroid() []float32 {
+	return bytesToFloats(f.Centroid)
+}
+
 // Chunk represents a text segment from a file for vectorization
 type Chunk struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -42,6 +66,11 @@ type Chunk struct {
 	File    *File  `gorm:"constraint:OnDelete:CASCADE" json:"-"`
 	Content string `gorm:"type:text" json:"content"` // Text content
 	Heading string `json:"heading"`                  // Associated heading (if any)
+	// Language is the detected ISO 639-1 language of Content, "" if unknown
+	Language string `gorm:"size:16;index" json:"language,omitempty"`
+	// Source is the vault path of the attachment Content was extracted from,
+	// e.g. an image read by OCR, or "" for the note's own text
+	Source string `gorm:"size:512" json:"source,omitempty"`
 
 	// Vector fields
 	Embedding          []float32  `gorm:"type:json;serializer:json" json:"embedding"` // Legacy JSON storage (fallback)
@@ -50,6 +79,9 @@ type Chunk struct {
 	EmbeddingCreatedAt *time.Time `json:"embedding_created_at"`                       // NULL until embedded
 	VecIndexed         bool       `gorm:"index;default:false" json:"vec_indexed"`     // Whether embedding is written to vec_chunks
 	EmbeddingDim       int        `gorm:"-" json:"embedding_dim,omitempty"`           // Computed field for UI
+
+	// EmbeddingNormalized records that the embedding was scaled to unit length when stored
+	EmbeddingNormalized bool `gorm:"index;default:false" json:"embedding_normalized"`
 }
 
 // TableName specifies the table name for Chunk
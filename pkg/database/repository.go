@@ -3,10 +3,12 @@ package database
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"notebit/pkg/logger"
 
@@ -21,6 +23,11 @@ type Repository struct {
 	db           *gorm.DB
 	vectorEngine VectorSearchEngine
 	revision     atomic.Uint64
+	listener     atomic.Pointer[ChangeListener]
+	space        atomic.Pointer[VectorSpace]
+
+	// fallbackReason says why sqlite-vec was replaced by brute-force search
+	fallbackReason string
 }
 
 // NewRepository creates a new repository
@@ -43,12 +50,15 @@ func (m *Manager) Repository() *Repository {
 type ChunkInput struct {
 	Content        string
 	Heading        string
+	Language       string
+	Source         string
 	Embedding      []float32
 	EmbeddingModel string
 }
 
-// IndexFile indexes a file in the database
-func (r *Repository) IndexFile(path, content string, lastModified int64, fileSize int64) error {
+// IndexFile indexes a file in the database with the stats counted from its
+// content
+func (r *Repository) IndexFile(path, content string, stats NoteStats, lastModified int64, fileSize int64) error {
 	// Calculate content hash
 	hash := sha256.Sum256([]byte(content))
 	contentHash := hex.EncodeToString(hash[:])
@@ -62,14 +72,43 @@ func (r *Repository) IndexFile(path, content string, lastModified int64, fileSiz
 		ContentHash:  contentHash,
 		LastModified: lastModified,
 		FileSize:     fileSize,
+		Stats:        stats,
 	}
 
 	// Use FirstOrCreate to handle updates
-	result := r.db.Where("path = ?", path).Assign(file).FirstOrCreate(&file)
-	if result.Error == nil {
-		r.revision.Add(1)
+	if err := r.db.Where("path = ?", path).Assign(file).FirstOrCreate(&file).Error; err != nil {
+		return err
+	}
+	if err := saveNoteStats(r.db, &file, stats); err != nil {
+		return err
+	}
+	r.changed(path)
+	return nil
+}
+
+// SetFileCreated records when the note at path was created, as a Unix
+// timestamp
+func (r *Repository) SetFileCreated(path string, created int64) error {
+	result := r.db.Model(&File{}).Where("path = ? AND created <> ?", path, created).Update("created", created)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		r.changed(path)
 	}
-	return result.Error
+	return nil
+}
+
+// saveNoteStats writes stats to the stored file, as Assign skips counts
+// that drop to zero and leaves the old ones in file
+func saveNoteStats(db *gorm.DB, file *File, stats NoteStats) error {
+	file.Stats = stats
+	return db.Model(file).Updates(map[string]any{
+		"stat_words":      stats.Words,
+		"stat_characters": stats.Characters,
+		"stat_headings":   stats.Headings,
+		"stat_links":      stats.Links,
+	}).Error
 }
 
 // GetFileByPath retrieves a file by its path
@@ -89,6 +128,13 @@ func (r *Repository) ListFiles() ([]File, error) {
 	return files, err
 }
 
+// ListRecentFiles returns the limit most recently modified files
+func (r *Repository) ListRecentFiles(limit int) ([]File, error) {
+	var files []File
+	err := r.db.Order("last_modified DESC").Limit(limit).Find(&files).Error
+	return files, err
+}
+
 func (r *Repository) ListFilesWithChunks() ([]File, error) {
 	var files []File
 	err := r.db.Preload("Chunks").Find(&files).Error
@@ -109,11 +155,14 @@ func (r *Repository) DeleteFile(path string) error {
 		if err := r.db.Exec("DELETE FROM vec_chunks WHERE chunk_id IN ?", chunkIDs).Error; err != nil {
 			logger.Warn("failed to delete vec_chunks entries: %v", err)
 		}
+		if err := r.db.Where("chunk_id IN ?", chunkIDs).Delete(&ChunkEmbedding{}).Error; err != nil {
+			logger.Warn("failed to delete namespaced embeddings: %v", err)
+		}
 	}
 
 	err := r.db.Where("path = ?", path).Delete(&File{}).Error
 	if err == nil {
-		r.revision.Add(1)
+		r.changed(path)
 	}
 	return err
 }
@@ -122,7 +171,7 @@ func (r *Repository) DeleteFile(path string) error {
 func (r *Repository) RenameFile(oldPath, newPath string) error {
 	err := r.db.Model(&File{}).Where("path = ?", oldPath).Update("path", newPath).Error
 	if err == nil {
-		r.revision.Add(1)
+		r.changed(oldPath, newPath)
 	}
 	return err
 }
@@ -200,11 +249,16 @@ func (r *Repository) DeleteChunksForFile(fileID uint) error {
 		if err := r.db.Exec("DELETE FROM vec_chunks WHERE chunk_id IN ?", oldIDs).Error; err != nil {
 			logger.Warn("failed to delete vec_chunks for file %d: %v", fileID, err)
 		}
+		if err := r.db.Where("chunk_id IN ?", oldIDs).Delete(&ChunkEmbedding{}).Error; err != nil {
+			logger.Warn("failed to delete namespaced embeddings for file %d: %v", fileID, err)
+		}
 	}
 
 	err := r.db.Where("file_id = ?", fileID).Delete(&Chunk{}).Error
 	if err == nil {
-		r.revision.Add(1)
+		var paths []string
+		r.db.Model(&File{}).Where("id = ?", fileID).Pluck("path", &paths)
+		r.changed(paths...)
 	}
 	return err
 }
@@ -309,11 +363,16 @@ func (r *Repository) GetStats() (map[string]int64, error) {
 	stats["chunks"] = chunkCount
 	stats["tags"] = tagCount
 
+	if failed, err := r.CountIndexErrors(); err == nil {
+		stats["failed"] = failed
+	}
+
 	return stats, nil
 }
 
-// IndexFileWithChunks indexes a file with its chunks including embeddings
-func (r *Repository) IndexFileWithChunks(path, content string, lastModified int64, fileSize int64, chunks []ChunkInput) error {
+// IndexFileWithChunks indexes a file and the stats counted from its content
+// with its chunks including embeddings
+func (r *Repository) IndexFileWithChunks(path, content string, stats NoteStats, lastModified int64, fileSize int64, chunks []ChunkInput) error {
 	// Calculate content hash
 	hash := sha256.Sum256([]byte(content))
 	contentHash := hex.EncodeToString(hash[:])
@@ -321,6 +380,20 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 	// Extract title (first # heading or filename)
 	title := extractTitle(path, content)
 
+	return r.storeFileWithChunks(File{
+		Path:         path,
+		Title:        title,
+		ContentHash:  contentHash,
+		LastModified: lastModified,
+		FileSize:     fileSize,
+		Stats:        stats,
+	}, chunks)
+}
+
+// storeFileWithChunks creates or updates file by path and replaces its chunks
+func (r *Repository) storeFileWithChunks(file File, chunks []ChunkInput) error {
+	path := file.Path
+
 	// Start transaction
 	tx := r.db.Begin()
 	if tx.Error != nil {
@@ -334,20 +407,16 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 		}
 	}()
 
-	// Create or update file
-	file := File{
-		Path:         path,
-		Title:        title,
-		ContentHash:  contentHash,
-		LastModified: lastModified,
-		FileSize:     fileSize,
-	}
-
 	// FirstOrCreate to handle updates
+	stats := file.Stats
 	if err := tx.Where("path = ?", path).Assign(file).FirstOrCreate(&file).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
+	if err := saveNoteStats(tx, &file, stats); err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	var existingChunkIDs []uint
 	if err := tx.Model(&Chunk{}).Where("file_id = ?", file.ID).Pluck("id", &existingChunkIDs).Error; err != nil {
@@ -359,6 +428,10 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 		if err := tx.Exec("DELETE FROM vec_chunks WHERE chunk_id IN ?", existingChunkIDs).Error; err != nil {
 			logger.Warn("failed to delete old vec_chunks rows: %v", err)
 		}
+		// Chunk IDs change on reindex, so embeddings in other namespaces are stale
+		if err := tx.Where("chunk_id IN ?", existingChunkIDs).Delete(&ChunkEmbedding{}).Error; err != nil {
+			logger.Warn("failed to delete old namespaced embeddings: %v", err)
+		}
 	}
 
 	if err := tx.Where("file_id = ?", file.ID).Delete(&Chunk{}).Error; err != nil {
@@ -373,11 +446,17 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 
 	// Create new chunks with embeddings
 	now := r.db.NowFunc()
+	normalize := r.GetVectorSpace().Normalize
 	for _, chunkInput := range chunks {
+		if normalize && len(chunkInput.Embedding) > 0 {
+			chunkInput.Embedding = normalizeVector(chunkInput.Embedding)
+		}
 		chunk := Chunk{
 			FileID:         file.ID,
 			Content:        chunkInput.Content,
 			Heading:        chunkInput.Heading,
+			Source:         chunkInput.Source,
+			Language:       chunkInput.Language,
 			Embedding:      chunkInput.Embedding,
 			EmbeddingModel: chunkInput.EmbeddingModel,
 		}
@@ -386,6 +465,7 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 		if len(chunkInput.Embedding) > 0 {
 			chunk.EmbeddingCreatedAt = &now
 			chunk.EmbeddingBlob = floatsToBytes(chunkInput.Embedding)
+			chunk.EmbeddingNormalized = normalize
 			chunk.VecIndexed = false
 		}
 
@@ -405,14 +485,114 @@ func (r *Repository) IndexFileWithChunks(path, content string, lastModified int6
 		}
 	}
 
+	if err := tx.Model(&File{}).Where("id = ?", file.ID).Update("centroid", chunkCentroid(chunks)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
-	r.revision.Add(1)
+	r.changed(path)
 	return nil
 }
 
 func (r *Repository) GetRevision() uint64 {
 	return r.revision.Load()
 }
+
+// ChangeEvent describes a committed change to the index
+type ChangeEvent struct {
+	Revision uint64   `json:"revision"`
+	Paths    []string `json:"paths,omitempty"` // empty when no file changed, e.g. a namespace was rebuilt
+}
+
+// ChangeListener is called after each change to the index
+type ChangeListener func(ChangeEvent)
+
+// SetChangeListener registers fn to be told about index changes, replacing
+// any previous listener; nil removes it. fn runs on the goroutine that made
+// the change and must not block.
+func (r *Repository) SetChangeListener(fn ChangeListener) {
+	if fn == nil {
+		r.listener.Store(nil)
+		return
+	}
+	r.listener.Store(&fn)
+}
+
+// changed bumps the revision and tells the listener which paths changed
+func (r *Repository) changed(paths ...string) {
+	revision := r.revision.Add(1)
+	if fn := r.listener.Load(); fn != nil {
+		(*fn)(ChangeEvent{Revision: revision, Paths: paths})
+	}
+}
+
+// ListFilesNeedingEmbeddings returns paths of indexed files that have no chunks or
+// at least one chunk without an embedding (the same condition FileNeedsIndexing checks).
+func (r *Repository) ListFilesNeedingEmbeddings() ([]string, error) {
+	var paths []string
+	err := r.db.Raw(`
+		SELECT files.path FROM files
+		LEFT JOIN chunks ON chunks.file_id = files.id AND chunks.deleted_at IS NULL
+		WHERE files.deleted_at IS NULL
+		GROUP BY files.id
+		HAVING COUNT(chunks.id) = 0
+			OR SUM(CASE WHEN chunks.embedding_blob IS NULL OR length(chunks.embedding_blob) = 0 THEN 1 ELSE 0 END) > 0
+	`).Scan(&paths).Error
+	return paths, err
+}
+
+// MeasureWriteLatency times writing a synthetic file with chunkCount embedded chunks,
+// averaged over rounds. Each round runs in a transaction that is rolled back, so the
+// index is left untouched.
+func (r *Repository) MeasureWriteLatency(chunkCount, rounds int) (time.Duration, error) {
+	if chunkCount <= 0 {
+		chunkCount = 1
+	}
+	if rounds <= 0 {
+		rounds = 1
+	}
+	embedding := make([]float32, 768)
+	for i := range embedding {
+		embedding[i] = float32(i%17) / 17
+	}
+
+	var total time.Duration
+	for round := 0; round < rounds; round++ {
+		start := time.Now()
+		tx := r.db.Begin()
+		if tx.Error != nil {
+			return 0, tx.Error
+		}
+		file := File{
+			Path:         fmt.Sprintf(".notebit-benchmark-%d.md", round),
+			Title:        "benchmark",
+			LastModified: start.Unix(),
+		}
+		if err := tx.Create(&file).Error; err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		now := r.db.NowFunc()
+		for i := 0; i < chunkCount; i++ {
+			chunk := Chunk{
+				FileID:             file.ID,
+				Content:            "benchmark chunk",
+				EmbeddingBlob:      floatsToBytes(embedding),
+				EmbeddingCreatedAt: &now,
+			}
+			if err := tx.Create(&chunk).Error; err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+		}
+		if err := tx.Rollback().Error; err != nil {
+			return 0, err
+		}
+		total += time.Since(start)
+	}
+	return total / time.Duration(rounds), nil
+}
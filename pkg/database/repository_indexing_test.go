@@ -49,7 +49,7 @@ func TestFileNeedsIndexing_WhenOnlyMetadataIndexed(t *testing.T) {
 	defer cleanup()
 
 	content := "# Title\n\ncontent"
-	if err := repo.IndexFile("a.md", content, 1, int64(len(content))); err != nil {
+	if err := repo.IndexFile("a.md", content, NoteStats{}, 1, int64(len(content))); err != nil {
 		t.Fatalf("index metadata failed: %v", err)
 	}
 
@@ -67,7 +67,7 @@ func TestFileNeedsIndexing_WhenContentChanged(t *testing.T) {
 	defer cleanup()
 
 	content := "# Title\n\nold"
-	if err := repo.IndexFile("b.md", content, 1, int64(len(content))); err != nil {
+	if err := repo.IndexFile("b.md", content, NoteStats{}, 1, int64(len(content))); err != nil {
 		t.Fatalf("index metadata failed: %v", err)
 	}
 
@@ -89,7 +89,7 @@ func TestFileNeedsIndexing_WhenEmbeddingsComplete(t *testing.T) {
 		{Content: "chunk-1", Heading: "Title", Embedding: []float32{0.1, 0.2}, EmbeddingModel: "m1"},
 		{Content: "chunk-2", Heading: "Title", Embedding: []float32{0.3, 0.4}, EmbeddingModel: "m1"},
 	}
-	if err := repo.IndexFileWithChunks("c.md", content, 1, int64(len(content)), chunks); err != nil {
+	if err := repo.IndexFileWithChunks("c.md", content, NoteStats{}, 1, int64(len(content)), chunks); err != nil {
 		t.Fatalf("index with chunks failed: %v", err)
 	}
 
@@ -101,3 +101,93 @@ func TestFileNeedsIndexing_WhenEmbeddingsComplete(t *testing.T) {
 		t.Fatalf("expected no reindex when content unchanged and embeddings complete")
 	}
 }
+
+func TestGetIndexCoverage_ReportsPartialEmbedding(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.IndexFileWithChunks("a.md", "# A", NoteStats{}, 1, 3, []ChunkInput{
+		{Content: "a", Embedding: []float32{1, 0}},
+	}); err != nil {
+		t.Fatalf("index a.md failed: %v", err)
+	}
+	if err := repo.IndexFile("b.md", "# B", NoteStats{}, 1, 3); err != nil {
+		t.Fatalf("index b.md failed: %v", err)
+	}
+
+	coverage, err := repo.GetIndexCoverage()
+	if err != nil {
+		t.Fatalf("GetIndexCoverage failed: %v", err)
+	}
+	if !coverage.Partial || coverage.TotalFiles != 2 || coverage.EmbeddedFiles != 1 {
+		t.Fatalf("unexpected coverage: %+v", coverage)
+	}
+	if coverage.FilePercent != 50 || coverage.Summary == "" {
+		t.Fatalf("expected 50%% coverage with summary, got %+v", coverage)
+	}
+}
+
+func TestChangeListenerReportsPaths(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	var events []ChangeEvent
+	repo.SetChangeListener(func(e ChangeEvent) { events = append(events, e) })
+
+	content := "# A\n\nbody"
+	if err := repo.IndexFileWithChunks("a.md", content, NoteStats{}, 1, int64(len(content)), []ChunkInput{{Content: content}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RenameFile("a.md", "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteFile("b.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	if got := events[1].Paths; len(got) != 2 || got[0] != "a.md" || got[1] != "b.md" {
+		t.Fatalf("rename reported %v", got)
+	}
+	if events[2].Revision != repo.GetRevision() || events[2].Paths[0] != "b.md" {
+		t.Fatalf("delete reported %+v at revision %d", events[2], repo.GetRevision())
+	}
+
+	repo.SetChangeListener(nil)
+	if err := repo.IndexFile("c.md", content, NoteStats{}, 1, int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatal("removed listener was still called")
+	}
+}
+
+func TestIndexFileWithChunks_StoresNoteStats(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	want := NoteStats{Words: 19, Characters: 139, Headings: 2, Links: 3}
+	if err := repo.IndexFileWithChunks("a.md", "# Title", want, 1, 7, []ChunkInput{{Content: "# Title"}}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := repo.GetFileByPath("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Stats != want {
+		t.Fatalf("Stats = %+v, want %+v", file.Stats, want)
+	}
+
+	// Counts that drop to zero are stored too
+	if err := repo.IndexFileWithChunks("a.md", "", NoteStats{}, 2, 0, []ChunkInput{{Content: ""}}); err != nil {
+		t.Fatal(err)
+	}
+	if file, err = repo.GetFileByPath("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if file.Stats != (NoteStats{}) {
+		t.Fatalf("Stats after emptying = %+v, want zero", file.Stats)
+	}
+}
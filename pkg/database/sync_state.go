@@ -0,0 +1,46 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SyncState records a vault file as it was after its last WebDAV sync, so
+// the next sync can tell which side changed
+type SyncState struct {
+	Path       string    `gorm:"primaryKey;size:1024" json:"path"`
+	LocalHash  string    `gorm:"size:64" json:"local_hash"`
+	RemoteETag string    `gorm:"column:remote_etag;size:256" json:"remote_etag"`
+	SyncedAt   time.Time `json:"synced_at"`
+}
+
+// TableName specifies the table name for SyncState
+func (SyncState) TableName() string {
+	return "sync_state"
+}
+
+// ListSyncStates returns the state of every synced file
+func (r *Repository) ListSyncStates() ([]SyncState, error) {
+	var states []SyncState
+	err := r.db.Find(&states).Error
+	return states, err
+}
+
+// SaveSyncState records the state of a file after it was synced
+func (r *Repository) SaveSyncState(state SyncState) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"local_hash", "remote_etag", "synced_at"}),
+	}).Create(&state).Error
+}
+
+// DeleteSyncState forgets a file that no longer exists on either side
+func (r *Repository) DeleteSyncState(path string) error {
+	return r.db.Where("path = ?", path).Delete(&SyncState{}).Error
+}
+
+// ClearSyncState forgets every file, e.g. when the sync target changes
+func (r *Repository) ClearSyncState() error {
+	return r.db.Where("1 = 1").Delete(&SyncState{}).Error
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncStateUpsertAndDelete(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&SyncState{}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := repo.SaveSyncState(SyncState{Path: "a.md", LocalHash: "h1", RemoteETag: `"e1"`, SyncedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveSyncState(SyncState{Path: "a.md", LocalHash: "h2", RemoteETag: `"e2"`, SyncedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveSyncState(SyncState{Path: "b.md", LocalHash: "h3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := repo.ListSyncStates()
+	if err != nil || len(states) != 2 {
+		t.Fatalf("expected 2 states, got %d (%v)", len(states), err)
+	}
+	for _, s := range states {
+		if s.Path == "a.md" && (s.LocalHash != "h2" || s.RemoteETag != `"e2"`) {
+			t.Fatalf("upsert did not update: %+v", s)
+		}
+	}
+
+	if err := repo.DeleteSyncState("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ClearSyncState(); err != nil {
+		t.Fatal(err)
+	}
+	if states, _ = repo.ListSyncStates(); len(states) != 0 {
+		t.Fatalf("expected no states, got %v", states)
+	}
+}
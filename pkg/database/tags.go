@@ -0,0 +1,71 @@
+package database
+
+import (
+	"sort"
+
+	"notebit/pkg/files"
+)
+
+// TagCount is a tag and the number of notes using it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TagUsage counts how many notes use each tag, whether assigned in file_tags
+// or written inline as #tag. Results are sorted by count, then name.
+func (r *Repository) TagUsage() ([]TagCount, error) {
+	type row struct {
+		Path string
+		Name string
+	}
+	var assigned []row
+	if err := r.db.Table("file_tags").
+		Select("files.path AS path, tags.name AS name").
+		Joins("JOIN files ON files.id = file_tags.file_id AND files.deleted_at IS NULL").
+		Joins("JOIN tags ON tags.id = file_tags.tag_id").
+		Scan(&assigned).Error; err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		Path    string
+		Content string
+	}
+	var candidates []candidate
+	if err := r.db.Model(&Chunk{}).
+		Select("files.path AS path, chunks.content AS content").
+		Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+		Where("chunks.content LIKE ?", "%#%").
+		Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	notes := make(map[string]map[string]struct{})
+	add := func(tag, path string) {
+		if notes[tag] == nil {
+			notes[tag] = make(map[string]struct{})
+		}
+		notes[tag][path] = struct{}{}
+	}
+	for _, a := range assigned {
+		add(a.Name, a.Path)
+	}
+	for _, c := range candidates {
+		for _, tag := range files.InlineTags(c.Content) {
+			add(tag, c.Path)
+		}
+	}
+
+	counts := make([]TagCount, 0, len(notes))
+	for name, paths := range notes {
+		counts = append(counts, TagCount{Name: name, Count: len(paths)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagUsage_CountsAssignedAndInlineTagsPerNote(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&Tag{}, &FileTag{}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := func(path string, chunks ...string) {
+		t.Helper()
+		inputs := make([]ChunkInput, 0, len(chunks))
+		for _, c := range chunks {
+			inputs = append(inputs, ChunkInput{Content: c})
+		}
+		if err := repo.IndexFileWithChunks(path, chunks[0], NoteStats{}, 1, 1, inputs); err != nil {
+			t.Fatalf("index %s: %v", path, err)
+		}
+	}
+	index("a.md", "about #go and #go again", "more #go plus #rust")
+	index("b.md", "# Heading only, and an issue#42")
+	index("c.md", "learning #go")
+
+	file, err := repo.GetFileByPath("b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.GetOrCreateTag("rust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddTagToFile(file.ID, tag.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := repo.TagUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TagCount{{Name: "go", Count: 2}, {Name: "rust", Count: 2}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("TagUsage = %+v, want %+v", counts, want)
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsageRecord is the token usage and estimated cost of one AI provider call
+type UsageRecord struct {
+	ID               uint    `gorm:"primarykey" json:"id"`
+	Provider         string  `gorm:"index;size:32" json:"provider"`
+	Model            string  `gorm:"index;size:128" json:"model"`
+	Kind             string  `gorm:"size:16" json:"kind"`            // embedding or completion
+	Operation        string  `gorm:"index;size:32" json:"operation"` // e.g. indexing, rag
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Estimated        bool    `json:"estimated"`
+	CostUSD          float64 `json:"cost_usd"`
+	Timestamp        int64   `gorm:"index" json:"timestamp"` // Unix ms
+}
+
+// TableName specifies the table name for UsageRecord
+func (UsageRecord) TableName() string {
+	return "usage_log"
+}
+
+// UsageReportRow aggregates usage for one day, provider, model and kind
+type UsageReportRow struct {
+	Day              string  `json:"day"` // YYYY-MM-DD, local time
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Kind             string  `json:"kind"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	Estimated        bool    `json:"estimated"` // some counts were approximated
+}
+
+// UsageOperationTotal aggregates usage for one operation across the range
+type UsageOperationTotal struct {
+	Operation   string  `json:"operation"`
+	Calls       int64   `json:"calls"`
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+// UsageReport summarizes usage over a time range
+type UsageReport struct {
+	RangeStart   int64                 `json:"range_start"` // Unix ms, 0 = unbounded
+	RangeEnd     int64                 `json:"range_end"`   // Unix ms, 0 = unbounded
+	Calls        int64                 `json:"calls"`
+	TotalTokens  int64                 `json:"total_tokens"`
+	TotalCostUSD float64               `json:"total_cost_usd"`
+	Rows         []UsageReportRow      `json:"rows"`
+	ByOperation  []UsageOperationTotal `json:"by_operation"`
+}
+
+// RecordUsage appends a usage record, stamping it with the current time if unset
+func (r *Repository) RecordUsage(rec UsageRecord) error {
+	if rec.Timestamp == 0 {
+		rec.Timestamp = time.Now().UnixMilli()
+	}
+	return r.db.Create(&rec).Error
+}
+
+// GetUsageReport aggregates usage between rangeStart and rangeEnd (Unix ms,
+// inclusive start, exclusive end; 0 leaves that side open) by day and model
+func (r *Repository) GetUsageReport(rangeStart, rangeEnd int64) (*UsageReport, error) {
+	scope := r.db.Model(&UsageRecord{})
+	if rangeStart > 0 {
+		scope = scope.Where("timestamp >= ?", rangeStart)
+	}
+	if rangeEnd > 0 {
+		scope = scope.Where("timestamp < ?", rangeEnd)
+	}
+
+	report := &UsageReport{RangeStart: rangeStart, RangeEnd: rangeEnd}
+	if err := scope.Session(&gorm.Session{}).
+		Select(`strftime('%Y-%m-%d', timestamp / 1000, 'unixepoch', 'localtime') AS day,
+			provider, model, kind,
+			COUNT(*) AS calls,
+			SUM(prompt_tokens) AS prompt_tokens,
+			SUM(completion_tokens) AS completion_tokens,
+			SUM(total_tokens) AS total_tokens,
+			SUM(cost_usd) AS cost_usd,
+			MAX(estimated) AS estimated`).
+		Group("day, provider, model, kind").
+		Order("day, provider, model, kind").
+		Scan(&report.Rows).Error; err != nil {
+		return nil, err
+	}
+	if err := scope.Session(&gorm.Session{}).
+		Select(`operation, COUNT(*) AS calls, SUM(total_tokens) AS total_tokens, SUM(cost_usd) AS cost_usd`).
+		Group("operation").
+		Order("cost_usd DESC, total_tokens DESC").
+		Scan(&report.ByOperation).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range report.Rows {
+		report.Calls += row.Calls
+		report.TotalTokens += row.TotalTokens
+		report.TotalCostUSD += row.CostUSD
+	}
+	if report.Rows == nil {
+		report.Rows = []UsageReportRow{}
+	}
+	if report.ByOperation == nil {
+		report.ByOperation = []UsageOperationTotal{}
+	}
+	return report, nil
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetUsageReportAggregatesByDayAndModel(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+
+	if err := repo.db.AutoMigrate(&UsageRecord{}); err != nil {
+		t.Fatal(err)
+	}
+
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local).UnixMilli()
+	day2 := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local).UnixMilli()
+	records := []UsageRecord{
+		{Provider: "openai", Model: "text-embedding-3-small", Kind: "embedding", Operation: "indexing", PromptTokens: 1000, TotalTokens: 1000, CostUSD: 0.00002, Timestamp: day1},
+		{Provider: "openai", Model: "text-embedding-3-small", Kind: "embedding", Operation: "indexing", PromptTokens: 500, TotalTokens: 500, CostUSD: 0.00001, Timestamp: day1 + 1000},
+		{Provider: "openai", Model: "gpt-4o-mini", Kind: "completion", Operation: "rag", PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300, CostUSD: 0.00009, Timestamp: day2},
+		{Provider: "ollama", Model: "nomic-embed-text", Kind: "embedding", Operation: "indexing", PromptTokens: 50, TotalTokens: 50, Estimated: true, Timestamp: day2 + 1000},
+	}
+	for _, rec := range records {
+		if err := repo.RecordUsage(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := repo.GetUsageReport(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Calls != 4 || report.TotalTokens != 1850 || len(report.Rows) != 3 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	first := report.Rows[0]
+	if first.Day != "2026-03-01" || first.Model != "text-embedding-3-small" || first.Calls != 2 || first.PromptTokens != 1500 {
+		t.Fatalf("unexpected first row: %+v", first)
+	}
+	if len(report.ByOperation) != 2 || report.ByOperation[0].Operation != "rag" {
+		t.Fatalf("expected operations ordered by cost, got %+v", report.ByOperation)
+	}
+
+	ranged, err := repo.GetUsageReport(day2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranged.Calls != 2 || len(ranged.Rows) != 2 || !ranged.Rows[0].Estimated && !ranged.Rows[1].Estimated {
+		t.Fatalf("unexpected ranged report: %+v", ranged)
+	}
+}
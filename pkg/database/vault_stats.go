@@ -0,0 +1,144 @@
+package database
+
+import (
+	"path"
+	"sort"
+	"time"
+)
+
+// fileCreatedSQL is the Unix time a file was created, falling back to when
+// it was first indexed
+const fileCreatedSQL = "COALESCE(NULLIF(files.created, 0), CAST(strftime('%s', files.created_at) AS INTEGER))"
+
+// VaultStatistics summarizes the indexed notes of a vault
+type VaultStatistics struct {
+	Notes        int64   `json:"notes"`
+	Words        int64   `json:"words"` // Counted when each note was indexed
+	Bytes        int64   `json:"bytes"`
+	AverageWords float64 `json:"average_words"`
+	AverageBytes float64 `json:"average_bytes"`
+
+	Folders []FolderCount `json:"folders"` // Most notes first
+	// Created and Modified are heatmap series of the days since the period
+	// start with any notes, oldest first, in local time. A note counts as
+	// created on its recorded creation date, or when it was first indexed
+	// if it has none.
+	Created  []DayCount `json:"created"`
+	Modified []DayCount `json:"modified"`
+	Tags     []TagCount `json:"tags"` // Most used first
+
+	TotalChunks    int64 `json:"total_chunks"`
+	EmbeddedChunks int64 `json:"embedded_chunks"`
+	EmbeddedNotes  int64 `json:"embedded_notes"` // Notes with at least one embedded chunk
+	// Coverage is the share of chunks with an embedding, from 0 to 1
+	Coverage float64 `json:"coverage"`
+}
+
+// FolderCount is the notes directly in a folder; "" is the vault root
+type FolderCount struct {
+	Folder string `json:"folder"`
+	Notes  int64  `json:"notes"`
+	Words  int64  `json:"words"`
+}
+
+// DayCount is the number of notes for a day, written YYYY-MM-DD
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// VaultStatistics computes the statistics of the indexed notes, with
+// created and modified series from since on
+func (r *Repository) VaultStatistics(since time.Time) (*VaultStatistics, error) {
+	stats := &VaultStatistics{Folders: []FolderCount{}}
+
+	// Words are the counts stored when each note was indexed; attachment
+	// text is not the note's own
+	var totals struct {
+		Notes, Words, Bytes int64
+	}
+	if err := r.db.Model(&File{}).
+		Select("COUNT(*) AS notes, COALESCE(SUM(files.stat_words), 0) AS words, COALESCE(SUM(files.file_size), 0) AS bytes").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	stats.Notes, stats.Words, stats.Bytes = totals.Notes, totals.Words, totals.Bytes
+	if stats.Notes > 0 {
+		stats.AverageWords = float64(stats.Words) / float64(stats.Notes)
+		stats.AverageBytes = float64(stats.Bytes) / float64(stats.Notes)
+	}
+
+	type noteRow struct {
+		Path  string
+		Words int64
+	}
+	var notes []noteRow
+	if err := r.db.Model(&File{}).Select("files.path AS path, files.stat_words AS words").Scan(&notes).Error; err != nil {
+		return nil, err
+	}
+	folders := make(map[string]*FolderCount)
+	for _, n := range notes {
+		folder := path.Dir(n.Path)
+		if folder == "." {
+			folder = ""
+		}
+		f := folders[folder]
+		if f == nil {
+			f = &FolderCount{Folder: folder}
+			folders[folder] = f
+		}
+		f.Notes++
+		f.Words += n.Words
+	}
+	for _, f := range folders {
+		stats.Folders = append(stats.Folders, *f)
+	}
+	sort.Slice(stats.Folders, func(i, j int) bool {
+		if stats.Folders[i].Notes != stats.Folders[j].Notes {
+			return stats.Folders[i].Notes > stats.Folders[j].Notes
+		}
+		return stats.Folders[i].Folder < stats.Folders[j].Folder
+	})
+
+	stats.Created = []DayCount{}
+	if err := r.db.Model(&File{}).
+		Select("date("+fileCreatedSQL+", 'unixepoch', 'localtime') AS date, COUNT(*) AS count").
+		Where(fileCreatedSQL+" >= ?", since.Unix()).
+		Group("date").Order("date").
+		Scan(&stats.Created).Error; err != nil {
+		return nil, err
+	}
+	stats.Modified = []DayCount{}
+	if err := r.db.Model(&File{}).
+		Select("date(last_modified, 'unixepoch', 'localtime') AS date, COUNT(*) AS count").
+		Where("last_modified >= ?", since.Unix()).
+		Group("date").Order("date").
+		Scan(&stats.Modified).Error; err != nil {
+		return nil, err
+	}
+
+	tags, err := r.TagUsage()
+	if err != nil {
+		return nil, err
+	}
+	stats.Tags = tags
+
+	embedded := "chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0"
+	if err := r.db.Model(&Chunk{}).Count(&stats.TotalChunks).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&Chunk{}).Where(embedded).Count(&stats.EmbeddedChunks).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&Chunk{}).
+		Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+		Where(embedded).
+		Distinct("chunks.file_id").
+		Count(&stats.EmbeddedNotes).Error; err != nil {
+		return nil, err
+	}
+	if stats.TotalChunks > 0 {
+		stats.Coverage = float64(stats.EmbeddedChunks) / float64(stats.TotalChunks)
+	}
+	return stats, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVaultStatistics(t *testing.T) {
+	repo, cleanup := setupRepositoryTestDB(t)
+	defer cleanup()
+	if err := repo.db.AutoMigrate(&Tag{}, &FileTag{}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	// Words come from the stored stats, not from chunks, which overlap and
+	// include attachment text
+	index := func(path string, modified time.Time, words int, chunks ...ChunkInput) {
+		t.Helper()
+		if err := repo.IndexFileWithChunks(path, chunks[0].Content, NoteStats{Words: words}, modified.Unix(), 100, chunks); err != nil {
+			t.Fatalf("index %s: %v", path, err)
+		}
+	}
+	index("a.md", now, 6, ChunkInput{Content: "one two  three\n\nfour #go"}, ChunkInput{Content: "five"})
+	index("notes/b.md", now, 2, ChunkInput{Content: "alpha beta"}, ChunkInput{Content: "scanned words here", Source: "notes/scan.png"})
+	index("notes/c.md", now.AddDate(0, 0, -40), 1, ChunkInput{Content: "#go"})
+
+	if err := repo.db.Model(&Chunk{}).Where("content = ?", "five").
+		Update("embedding_blob", floatsToBytes([]float32{1, 0})).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := repo.VaultStatistics(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Notes != 3 || stats.Words != 9 || stats.Bytes != 300 {
+		t.Fatalf("notes, words, bytes = %d, %d, %d, want 3, 9, 300", stats.Notes, stats.Words, stats.Bytes)
+	}
+	if stats.AverageWords != 3 {
+		t.Errorf("AverageWords = %v, want 3", stats.AverageWords)
+	}
+	if len(stats.Folders) != 2 || stats.Folders[0] != (FolderCount{Folder: "notes", Notes: 2, Words: 3}) ||
+		stats.Folders[1] != (FolderCount{Folder: "", Notes: 1, Words: 6}) {
+		t.Errorf("Folders = %+v", stats.Folders)
+	}
+	today := now.Format("2006-01-02")
+	if len(stats.Modified) != 1 || stats.Modified[0] != (DayCount{Date: today, Count: 2}) {
+		t.Errorf("Modified = %+v, want 2 notes on %s", stats.Modified, today)
+	}
+	if len(stats.Created) != 1 || stats.Created[0] != (DayCount{Date: today, Count: 3}) {
+		t.Errorf("Created = %+v, want 3 notes on %s", stats.Created, today)
+	}
+
+	// A recorded creation time replaces the first indexing, in local time
+	// like the modified days
+	threeDaysAgo := time.Date(now.Year(), now.Month(), now.Day()-3, 23, 30, 0, 0, time.Local)
+	if err := repo.SetFileCreated("notes/b.md", threeDaysAgo.Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetFileCreated("notes/c.md", now.AddDate(0, 0, -40).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err = repo.VaultStatistics(now.AddDate(0, 0, -7)); err != nil {
+		t.Fatal(err)
+	}
+	want := []DayCount{{Date: threeDaysAgo.Format("2006-01-02"), Count: 1}, {Date: today, Count: 1}}
+	if len(stats.Created) != 2 || stats.Created[0] != want[0] || stats.Created[1] != want[1] {
+		t.Errorf("Created = %+v, want %+v", stats.Created, want)
+	}
+	if len(stats.Tags) != 1 || stats.Tags[0] != (TagCount{Name: "go", Count: 2}) {
+		t.Errorf("Tags = %+v", stats.Tags)
+	}
+	if stats.TotalChunks != 5 || stats.EmbeddedChunks != 1 || stats.EmbeddedNotes != 1 || stats.Coverage != 0.2 {
+		t.Errorf("coverage = %d/%d chunks, %d notes, %v", stats.EmbeddedChunks, stats.TotalChunks, stats.EmbeddedNotes, stats.Coverage)
+	}
+}
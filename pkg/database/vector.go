@@ -1,8 +1,13 @@
 package database
 
 import (
+	"container/heap"
 	"encoding/binary"
+	"fmt"
 	"math"
+	"sort"
+
+	"notebit/pkg/logger"
 )
 
 // GetChunkEmbedding retrieves the embedding for a chunk
@@ -23,31 +28,179 @@ func (r *Repository) GetChunkEmbedding(chunkID uint) ([]float32, error) {
 	return chunk.Embedding, nil
 }
 
-// SearchSimilar performs a similarity search using cosine similarity
-// For now, this is a naive implementation that loads all vectors and computes similarity
-// In production with sqlite-vec, this will use the vector distance function
-func (r *Repository) SearchSimilar(queryVector []float32, limit int) ([]SimilarChunk, error) {
+// GetFileEmbeddings returns an indexed file and the embeddings of its embedded
+// chunks, in chunk order
+func (r *Repository) GetFileEmbeddings(path string) (*File, [][]float32, error) {
+	file, err := r.GetFileByPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chunks []Chunk
+	if err := r.db.Select("id", "embedding", "embedding_blob").
+		Where("file_id = ?", file.ID).
+		Order("id ASC").
+		Find(&chunks).Error; err != nil {
+		return nil, nil, err
+	}
+	embeddings := make([][]float32, 0, len(chunks))
+	for _, chunk := range chunks {
+		vec := bytesToFloats(chunk.EmbeddingBlob)
+		if len(vec) == 0 {
+			vec = chunk.Embedding
+		}
+		if len(vec) > 0 {
+			embeddings = append(embeddings, vec)
+		}
+	}
+	return file, embeddings, nil
+}
+
+// SearchSimilar performs a similarity search with the metric of the
+// repository's vector space.
+// model selects an embedding namespace; "" searches the primary embeddings with the
+// configured vector engine. When the sqlite-vec extension is missing the engine
+// falls back to brute force for good; while its index is still being filled,
+// single queries do.
+func (r *Repository) SearchSimilar(model string, queryVector []float32, limit int) ([]SimilarChunk, error) {
+	if model != "" {
+		return r.searchNamespace(model, queryVector, limit)
+	}
 	if r.vectorEngine == nil {
 		r.vectorEngine = NewBruteForceVectorEngine()
 	}
 
 	results, err := r.vectorEngine.Search(r, queryVector, limit)
-	if err == nil {
-		return results, nil
+	if err == nil || r.vectorEngine.Name() == VectorEngineBruteForce {
+		return results, err
+	}
+	return r.fallbackEngine(err).Search(r, queryVector, limit)
+}
+
+// fallbackEngine returns the brute-force engine after a failed accelerated
+// search, keeping it when the accelerated engine cannot work at all
+func (r *Repository) fallbackEngine(err error) VectorSearchEngine {
+	fallback := NewBruteForceVectorEngine()
+	if vecUnavailable(err) {
+		logger.Warn("sqlite-vec search unavailable, using brute-force search: %v", err)
+		r.vectorEngine = fallback
+		r.fallbackReason = err.Error()
+	}
+	return fallback
+}
+
+// SearchSimilarInPaths performs a brute-force similarity search restricted to the
+// given note paths, e.g. the members of a collection
+func (r *Repository) SearchSimilarInPaths(paths []string, queryVector []float32, limit int) ([]SimilarChunk, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if len(paths) == 0 {
+		return []SimilarChunk{}, nil
 	}
 
-	if r.vectorEngine.Name() == VectorEngineBruteForce {
+	topK := &scoredChunkHeap{}
+	heap.Init(topK)
+	scorer := r.newScorer(queryVector)
+	// Stay well below SQLite's bound parameter limit
+	const pathBatch = 500
+	for start := 0; start < len(paths); start += pathBatch {
+		end := start + pathBatch
+		if end > len(paths) {
+			end = len(paths)
+		}
+		rows, err := r.db.Model(&Chunk{}).
+			Select("chunks.id, chunks.embedding_blob, chunks.embedding_normalized").
+			Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+			Where("files.path IN ?", paths[start:end]).
+			Where("chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0").
+			Rows()
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id uint
+			var blob []byte
+			var normalized bool
+			if err := rows.Scan(&id, &blob, &normalized); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			vec := bytesToFloats(blob)
+			if len(vec) != len(queryVector) {
+				continue
+			}
+			score := scoredChunk{ID: id, Similarity: scorer.score(vec, normalized)}
+			if topK.Len() < limit {
+				heap.Push(topK, score)
+			} else if score.Similarity > (*topK)[0].Similarity {
+				heap.Pop(topK)
+				heap.Push(topK, score)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.loadScoredChunks(*topK)
+}
+
+// SearchSimilarImages performs a brute-force similarity search over the
+// chunks extracted from images and other attachments, keeping the best chunk
+// of each attachment
+func (r *Repository) SearchSimilarImages(queryVector []float32, limit int) ([]SimilarChunk, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := r.db.Model(&Chunk{}).
+		Select("chunks.id, chunks.source, chunks.embedding_blob, chunks.embedding_normalized").
+		Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+		Where("chunks.source IS NOT NULL AND chunks.source <> ''").
+		Where("chunks.embedding_blob IS NOT NULL AND length(chunks.embedding_blob) > 0").
+		Rows()
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	fallback := NewBruteForceVectorEngine()
-	r.vectorEngine = fallback
-	return fallback.Search(r, queryVector, limit)
+	scorer := r.newScorer(queryVector)
+	best := make(map[string]scoredChunk)
+	for rows.Next() {
+		var id uint
+		var source string
+		var blob []byte
+		var normalized bool
+		if err := rows.Scan(&id, &source, &blob, &normalized); err != nil {
+			return nil, err
+		}
+		vec := bytesToFloats(blob)
+		if len(vec) != len(queryVector) {
+			continue
+		}
+		score := scoredChunk{ID: id, Similarity: scorer.score(vec, normalized)}
+		if prev, ok := best[source]; !ok || score.Similarity > prev.Similarity {
+			best[source] = score
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	scores := make([]scoredChunk, 0, len(best))
+	for _, score := range best {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Similarity > scores[j].Similarity })
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return r.loadScoredChunks(scores)
 }
 
 // SearchSimilarBatch performs similarity search for multiple query vectors.
-// Refactored to use the pluggable VectorEngine and avoid O(N) memory usage.
-// For large datasets (10k+ notes), this prevents loading all chunks into memory.
+// Engines that support it answer all queries in one pass; otherwise each query
+// goes through Search, which streams chunks instead of loading them all.
 func (r *Repository) SearchSimilarBatch(queryVectors [][]float32, limit int) ([][]SimilarChunk, error) {
 	if len(queryVectors) == 0 {
 		return [][]SimilarChunk{}, nil
@@ -61,27 +214,32 @@ func (r *Repository) SearchSimilarBatch(queryVectors [][]float32, limit int) ([]
 		r.vectorEngine = NewBruteForceVectorEngine()
 	}
 
-	results := make([][]SimilarChunk, len(queryVectors))
+	if batcher, ok := r.vectorEngine.(batchVectorSearchEngine); ok {
+		results, err := batcher.SearchBatch(r, queryVectors, limit)
+		if err == nil {
+			return results, nil
+		}
+		return searchEach(r, r.fallbackEngine(err), queryVectors, limit)
+	}
+	return searchEach(r, r.vectorEngine, queryVectors, limit)
+}
 
-	// TODO: Implement optimized batch search for sqlite-vec engine
-	// Current implementation iterates queries but uses the efficient Search() method
-	// which avoids loading all chunks into memory (uses streaming or indexed search)
+// searchEach runs the queries one by one
+func searchEach(r *Repository, engine VectorSearchEngine, queryVectors [][]float32, limit int) ([][]SimilarChunk, error) {
+	results := make([][]SimilarChunk, len(queryVectors))
 	for i, query := range queryVectors {
 		if len(query) == 0 {
 			// Skip invalid query vectors
 			results[i] = []SimilarChunk{}
 			continue
 		}
-
-		// Use the optimized single-search method via VectorEngine
-		matches, err := r.vectorEngine.Search(r, query, limit)
+		matches, err := engine.Search(r, query, limit)
 		if err != nil {
 			// Fail fast on error - partial results could be misleading
 			return nil, err
 		}
 		results[i] = matches
 	}
-
 	return results, nil
 }
 
@@ -92,6 +250,8 @@ type SimilarChunk struct {
 	Heading    string  `json:"heading"`
 	Similarity float32 `json:"similarity"`
 	File       *File   `json:"file,omitempty"`
+	// Source is the attachment the content was extracted from, see Chunk.Source
+	Source string `json:"source,omitempty"`
 }
 
 // cosineSimilarity computes the cosine similarity between two vectors
@@ -134,6 +294,11 @@ func (r *Repository) GetEmbeddingStats() (*EmbeddingStats, error) {
 		Pluck("embedding_model", &models)
 	stats.Models = models
 
+	// Per-namespace stats need the chunk_embeddings table; skip them if it is missing
+	if byModel, err := r.getModelStats(); err == nil {
+		stats.ByModel = byModel
+	}
+
 	return &stats, nil
 }
 
@@ -142,6 +307,8 @@ type EmbeddingStats struct {
 	TotalChunks    int64    `json:"total_chunks"`
 	EmbeddedChunks int64    `json:"embedded_chunks"`
 	Models         []string `json:"models"`
+	// ByModel breaks embedded chunks down per model namespace
+	ByModel []ModelEmbeddingStats `json:"by_model,omitempty"`
 }
 
 func floatsToBytes(floats []float32) []byte {
@@ -164,3 +331,60 @@ func bytesToFloats(bytes []byte) []float32 {
 	}
 	return floats
 }
+
+// IndexCoverage describes how much of the index is searchable by embedding, so
+// callers can flag results computed over a partially indexed vault.
+type IndexCoverage struct {
+	TotalFiles     int64   `json:"total_files"`
+	EmbeddedFiles  int64   `json:"embedded_files"` // files whose chunks all have embeddings
+	TotalChunks    int64   `json:"total_chunks"`
+	EmbeddedChunks int64   `json:"embedded_chunks"`
+	PendingChunks  int64   `json:"pending_chunks"`
+	QueuedFiles    int64   `json:"queued_files"` // files waiting in the indexing queue
+	FilePercent    float64 `json:"file_percent"`
+	ChunkPercent   float64 `json:"chunk_percent"`
+	Partial        bool    `json:"partial"`
+	Summary        string  `json:"summary,omitempty"`
+}
+
+// GetIndexCoverage returns embedding coverage for the current index
+func (r *Repository) GetIndexCoverage() (*IndexCoverage, error) {
+	stats, err := r.GetEmbeddingStats()
+	if err != nil {
+		return nil, err
+	}
+	coverage := &IndexCoverage{
+		TotalChunks:    stats.TotalChunks,
+		EmbeddedChunks: stats.EmbeddedChunks,
+		PendingChunks:  stats.TotalChunks - stats.EmbeddedChunks,
+	}
+	if err := r.db.Model(&File{}).Count(&coverage.TotalFiles).Error; err != nil {
+		return nil, err
+	}
+	needing, err := r.ListFilesNeedingEmbeddings()
+	if err != nil {
+		return nil, err
+	}
+	coverage.EmbeddedFiles = coverage.TotalFiles - int64(len(needing))
+	if queued, err := r.CountIndexJobs(); err == nil {
+		coverage.QueuedFiles = queued
+	}
+
+	coverage.FilePercent = percentOf(coverage.EmbeddedFiles, coverage.TotalFiles)
+	coverage.ChunkPercent = percentOf(coverage.EmbeddedChunks, coverage.TotalChunks)
+	coverage.Partial = coverage.EmbeddedFiles < coverage.TotalFiles || coverage.QueuedFiles > 0
+	if coverage.Partial {
+		coverage.Summary = fmt.Sprintf("Answer based on %.0f%% of notes; %d chunks not yet embedded", coverage.FilePercent, coverage.PendingChunks)
+		if coverage.QueuedFiles > 0 {
+			coverage.Summary += fmt.Sprintf(", %d files still queued", coverage.QueuedFiles)
+		}
+	}
+	return coverage, nil
+}
+
+func percentOf(part, total int64) float64 {
+	if total <= 0 {
+		return 100
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
@@ -86,7 +86,7 @@ func TestSearchSimilarBatch_ConsistentWithSingleSearch(t *testing.T) {
 	}
 
 	for i, query := range queries {
-		singleResults, err := repo.SearchSimilar(query, 2)
+		singleResults, err := repo.SearchSimilar("", query, 2)
 		if err != nil {
 			t.Fatalf("SearchSimilar failed for query %d: %v", i, err)
 		}
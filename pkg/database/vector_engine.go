@@ -11,12 +11,20 @@ const (
 )
 
 // VectorSearchEngine defines a pluggable vector retrieval backend.
-// Current default implementation is brute-force cosine search over cached embeddings.
+// The default implementation is brute-force search over stored embeddings,
+// scored with the repository's similarity metric;
+// SQLiteVecEngine uses KNN queries when the sqlite-vec extension is loaded.
 type VectorSearchEngine interface {
 	Search(repo *Repository, queryVector []float32, limit int) ([]SimilarChunk, error)
 	Name() string
 }
 
+// batchVectorSearchEngine is implemented by engines that answer several
+// queries more cheaply together than one by one
+type batchVectorSearchEngine interface {
+	SearchBatch(repo *Repository, queryVectors [][]float32, limit int) ([][]SimilarChunk, error)
+}
+
 // BruteForceVectorEngine is the default in-process search implementation.
 type BruteForceVectorEngine struct{}
 
@@ -31,8 +39,14 @@ func (e *BruteForceVectorEngine) Name() string {
 // SetVectorEngine selects a vector search engine by name.
 // Returns the effective engine name (falls back to brute-force when unsupported).
 func (r *Repository) SetVectorEngine(name string) string {
+	r.fallbackReason = ""
 	switch name {
 	case VectorEngineSQLiteVec:
+		if err := r.db.Exec("SELECT vec_version()").Error; err != nil {
+			r.fallbackReason = "sqlite-vec extension not loaded: " + err.Error()
+			r.vectorEngine = NewBruteForceVectorEngine()
+			break
+		}
 		r.vectorEngine = NewSQLiteVecEngine()
 	default:
 		r.vectorEngine = NewBruteForceVectorEngine()
@@ -40,6 +54,15 @@ func (r *Repository) SetVectorEngine(name string) string {
 	return r.vectorEngine.Name()
 }
 
+// VectorEngineFallbackReason explains why sqlite-vec search is not in use
+// after it was requested, or returns ""
+func (r *Repository) VectorEngineFallbackReason() string {
+	if r == nil {
+		return ""
+	}
+	return r.fallbackReason
+}
+
 // GetVectorEngine returns the current vector search engine name.
 func (r *Repository) GetVectorEngine() string {
 	if r == nil {
@@ -76,7 +99,7 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 	}
 
 	rows, err := repo.db.Model(&Chunk{}).
-		Select("id, embedding_blob").
+		Select("id, embedding_blob, embedding_normalized").
 		Where("embedding_blob IS NOT NULL AND length(embedding_blob) > 0").
 		Rows()
 	if err != nil {
@@ -86,11 +109,13 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 
 	topK := &scoredChunkHeap{}
 	heap.Init(topK)
+	scorer := repo.newScorer(queryVector)
 
 	for rows.Next() {
 		var id uint
 		var blob []byte
-		if err := rows.Scan(&id, &blob); err != nil {
+		var normalized bool
+		if err := rows.Scan(&id, &blob, &normalized); err != nil {
 			return nil, err
 		}
 
@@ -99,7 +124,7 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 			continue
 		}
 
-		score := scoredChunk{ID: id, Similarity: cosineSimilarity(queryVector, vec)}
+		score := scoredChunk{ID: id, Similarity: scorer.score(vec, normalized)}
 		if topK.Len() < limit {
 			heap.Push(topK, score)
 			continue
@@ -114,13 +139,13 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 		return nil, err
 	}
 
-	if topK.Len() == 0 {
-		return []SimilarChunk{}, nil
-	}
+	return repo.loadScoredChunks(*topK)
+}
 
-	scores := make([]scoredChunk, topK.Len())
-	for i := len(scores) - 1; i >= 0; i-- {
-		scores[i] = heap.Pop(topK).(scoredChunk)
+// loadScoredChunks loads the chunks for scored IDs, ordered by descending similarity
+func (r *Repository) loadScoredChunks(scores []scoredChunk) ([]SimilarChunk, error) {
+	if len(scores) == 0 {
+		return []SimilarChunk{}, nil
 	}
 
 	sort.Slice(scores, func(i, j int) bool {
@@ -135,7 +160,7 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 	}
 
 	var fullChunks []Chunk
-	if err := repo.db.Preload("File").
+	if err := r.db.Preload("File").
 		Where("id IN ?", topIDs).
 		Find(&fullChunks).Error; err != nil {
 		return nil, err
@@ -149,6 +174,7 @@ func (e *BruteForceVectorEngine) Search(repo *Repository, queryVector []float32,
 			Heading:    chunk.Heading,
 			Similarity: scoreMap[chunk.ID],
 			File:       chunk.File,
+			Source:     chunk.Source,
 		})
 	}
 
@@ -1,10 +1,30 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 )
 
+// vecOverfetch is how many KNN candidates are fetched per requested result.
+// vec_chunks stores unit-length embeddings and is queried with a unit-length
+// vector, so its L2 ranking is the cosine ranking (and that of any metric
+// over normalized vectors). The spare candidates stand in for chunks of
+// deleted files, and for dot or euclidean scores over raw vectors, which can
+// rank differently and are re-ranked by exact score.
+const vecOverfetch = 4
+
+// errVecUnavailable means the database has no vec_chunks table, usually
+// because the sqlite-vec extension was not loaded when it was created
+var errVecUnavailable = errors.New("sqlite-vec is not available: no vec_chunks table")
+
+// errVecIncomplete means some embedded chunks are missing from vec_chunks,
+// so a KNN query could miss matches
+var errVecIncomplete = errors.New("vec_chunks index is incomplete")
+
+// SQLiteVecEngine answers similarity queries with KNN queries against the
+// vec_chunks virtual table of the sqlite-vec extension
 type SQLiteVecEngine struct{}
 
 func NewSQLiteVecEngine() *SQLiteVecEngine {
@@ -16,75 +36,161 @@ func (e *SQLiteVecEngine) Name() string {
 }
 
 func (e *SQLiteVecEngine) Search(repo *Repository, queryVector []float32, limit int) ([]SimilarChunk, error) {
+	results, err := e.SearchBatch(repo, [][]float32{queryVector}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// SearchBatch runs one KNN query per vector and loads the matched chunks of
+// all queries at once
+func (e *SQLiteVecEngine) SearchBatch(repo *Repository, queryVectors [][]float32, limit int) ([][]SimilarChunk, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	if err := repo.checkVecIndexReady(); err != nil {
+		return nil, err
+	}
 
-	type vecResult struct {
-		ChunkID  uint
-		Distance float32
+	candidates := make([][]uint, len(queryVectors))
+	seen := make(map[uint]bool)
+	var ids []uint
+	for i, query := range queryVectors {
+		if len(query) == 0 {
+			continue
+		}
+		found, err := repo.vecKNN(query, limit*vecOverfetch)
+		if err != nil {
+			return nil, err
+		}
+		candidates[i] = found
+		for _, id := range found {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	chunks, err := repo.loadLiveChunks(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]SimilarChunk, len(queryVectors))
+	for i, query := range queryVectors {
+		scorer := repo.newScorer(query)
+		matches := make([]SimilarChunk, 0, len(candidates[i]))
+		for _, id := range candidates[i] {
+			chunk, ok := chunks[id]
+			if !ok {
+				continue
+			}
+			vec := bytesToFloats(chunk.EmbeddingBlob)
+			if len(vec) == 0 {
+				vec = chunk.Embedding
+			}
+			if len(vec) != len(query) {
+				continue
+			}
+			matches = append(matches, SimilarChunk{
+				ChunkID:    chunk.ID,
+				Content:    chunk.Content,
+				Heading:    chunk.Heading,
+				Similarity: scorer.score(vec, chunk.EmbeddingNormalized),
+				File:       chunk.File,
+				Source:     chunk.Source,
+			})
+		}
+		sort.SliceStable(matches, func(a, b int) bool {
+			return matches[a].Similarity > matches[b].Similarity
+		})
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		results[i] = matches
 	}
+	return results, nil
+}
 
-	queryBlob := floatsToBytes(queryVector)
-	rows, err := repo.db.Raw(
-		"SELECT chunk_id, distance FROM vec_chunks WHERE embedding MATCH ? ORDER BY distance ASC LIMIT ?",
-		queryBlob,
-		limit,
+// vecKNN returns the IDs of the k chunks nearest to the direction of query,
+// nearest first
+func (r *Repository) vecKNN(query []float32, k int) ([]uint, error) {
+	rows, err := r.db.Raw(
+		"SELECT chunk_id FROM vec_chunks WHERE embedding MATCH ? AND k = ? ORDER BY distance",
+		floatsToBytes(normalizeVector(query)), k,
 	).Rows()
 	if err != nil {
 		return nil, fmt.Errorf("sqlite-vec query failed: %w", err)
 	}
 	defer rows.Close()
 
-	ordered := make([]vecResult, 0, limit)
-	idOrder := make(map[uint]int, limit)
+	ids := make([]uint, 0, k)
 	for rows.Next() {
-		var item vecResult
-		if err := rows.Scan(&item.ChunkID, &item.Distance); err != nil {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		idOrder[item.ChunkID] = len(ordered)
-		ordered = append(ordered, item)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		ids = append(ids, id)
 	}
+	return ids, rows.Err()
+}
 
-	if len(ordered) == 0 {
-		return []SimilarChunk{}, nil
+// loadLiveChunks loads chunks by ID with their files, leaving out chunks of
+// deleted files
+func (r *Repository) loadLiveChunks(ids []uint) (map[uint]Chunk, error) {
+	chunks := make(map[uint]Chunk, len(ids))
+	// Stay well below SQLite's bound parameter limit
+	const idBatch = 500
+	for start := 0; start < len(ids); start += idBatch {
+		end := start + idBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch []Chunk
+		if err := r.db.Preload("File").
+			Joins("JOIN files ON files.id = chunks.file_id AND files.deleted_at IS NULL").
+			Where("chunks.id IN ?", ids[start:end]).
+			Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range batch {
+			chunks[c.ID] = c
+		}
 	}
+	return chunks, nil
+}
 
-	ids := make([]uint, 0, len(ordered))
-	for _, item := range ordered {
-		ids = append(ids, item.ChunkID)
+// checkVecIndexReady fails without a vec_chunks table, and while embedded
+// chunks are still waiting to be copied into it, e.g. before MigrateToVec has
+// caught up
+func (r *Repository) checkVecIndexReady() error {
+	var exists bool
+	if err := r.db.Raw("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type='table' AND name='vec_chunks'").Scan(&exists).Error; err != nil {
+		return err
 	}
-
-	var chunks []Chunk
-	if err := repo.db.Preload("File").Where("id IN ?", ids).Find(&chunks).Error; err != nil {
-		return nil, err
+	if !exists {
+		return errVecUnavailable
 	}
-
-	distanceMap := make(map[uint]float32, len(ordered))
-	for _, item := range ordered {
-		distanceMap[item.ChunkID] = item.Distance
+	var pending bool
+	if err := r.db.Raw(
+		"SELECT EXISTS(SELECT 1 FROM chunks WHERE vec_indexed = 0 AND embedding_blob IS NOT NULL AND length(embedding_blob) > 0)",
+	).Scan(&pending).Error; err != nil {
+		return err
 	}
-
-	results := make([]SimilarChunk, 0, len(chunks))
-	for _, chunk := range chunks {
-		distance := distanceMap[chunk.ID]
-		similarity := float32(1.0 / (1.0 + distance))
-		results = append(results, SimilarChunk{
-			ChunkID:    chunk.ID,
-			Content:    chunk.Content,
-			Heading:    chunk.Heading,
-			Similarity: similarity,
-			File:       chunk.File,
-		})
+	if pending {
+		return errVecIncomplete
 	}
+	return nil
+}
 
-	sort.Slice(results, func(i, j int) bool {
-		return idOrder[results[i].ChunkID] < idOrder[results[j].ChunkID]
-	})
-
-	return results, nil
+// vecUnavailable reports whether err means the sqlite-vec extension or its
+// table is missing, as opposed to a problem with a single query
+func vecUnavailable(err error) bool {
+	if errors.Is(err, errVecUnavailable) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "no such table: vec_chunks") || strings.Contains(msg, "no such module: vec0")
 }
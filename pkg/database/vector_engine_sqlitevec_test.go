@@ -0,0 +1,244 @@
+package database
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteVecDirEnv names a directory InstallSQLiteVec installed the extension
+// into, such as the app's cache dir. Where it is set the sqlite-vec tests load
+// that extension and fail instead of skipping when it is missing or broken.
+const sqliteVecDirEnv = "NOTEBIT_SQLITE_VEC_DIR"
+
+// useInstalledSQLiteVec points new databases at the extension installed in
+// sqliteVecDirEnv and reports whether the variable is set
+func useInstalledSQLiteVec(t *testing.T) bool {
+	t.Helper()
+	dir := os.Getenv(sqliteVecDirEnv)
+	if dir == "" {
+		return false
+	}
+	path := InstalledSQLiteVec(dir)
+	if path == "" {
+		t.Fatalf("%s=%s holds no %s", sqliteVecDirEnv, dir, vecLibraryName())
+	}
+	previous := SQLiteVecExtension()
+	SetSQLiteVecExtension(path)
+	t.Cleanup(func() { SetSQLiteVecExtension(previous) })
+	return true
+}
+
+// setupSQLiteVecTestDB opens a database with the sqlite-vec extension
+// loaded. Without sqliteVecDirEnv it falls back to "vec0" on the library
+// path and skips the test where that is not installed.
+func setupSQLiteVecTestDB(t *testing.T, dimension int) *Repository {
+	t.Helper()
+	required := useInstalledSQLiteVec(t)
+	unavailable := func(format string, args ...any) {
+		t.Helper()
+		if required {
+			t.Fatalf(format, args...)
+		}
+		t.Skipf(format, args...)
+	}
+	if !registerSQLiteVecDriver() {
+		unavailable("sqlite-vec driver unavailable")
+	}
+	db, err := gorm.Open(sqlite.New(sqlite.Config{
+		DriverName: vecSQLiteDriver,
+		DSN:        filepath.Join(t.TempDir(), "vec.sqlite"),
+	}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		unavailable("sqlite-vec extension unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+	})
+	ddl := fmt.Sprintf("CREATE VIRTUAL TABLE vec_chunks USING vec0(chunk_id INTEGER PRIMARY KEY, embedding float[%d])", dimension)
+	if err := db.Exec(ddl).Error; err != nil {
+		unavailable("sqlite-vec extension unavailable: %v", err)
+	}
+	if err := db.AutoMigrate(&File{}, &Chunk{}); err != nil {
+		t.Fatal(err)
+	}
+	return &Repository{db: db, vectorEngine: NewSQLiteVecEngine()}
+}
+
+func TestSQLiteVecEngineMatchesBruteForce(t *testing.T) {
+	const dimension = 8
+	repo := setupSQLiteVecTestDB(t, dimension)
+	rng := rand.New(rand.NewSource(7))
+	// Lengths vary widely, so ranking by raw L2 distance would differ from cosine
+	randomVector := func() []float32 {
+		v := make([]float32, dimension)
+		scale := 1 + rng.Float32()*20
+		for i := range v {
+			v[i] = (rng.Float32()*2 - 1) * scale
+		}
+		return v
+	}
+
+	for f := 0; f < 5; f++ {
+		file := File{Path: fmt.Sprintf("note-%d.md", f), Title: "note"}
+		if err := repo.db.Create(&file).Error; err != nil {
+			t.Fatal(err)
+		}
+		for c := 0; c < 20; c++ {
+			vec := randomVector()
+			chunk := Chunk{FileID: file.ID, Content: fmt.Sprintf("%d-%d", f, c), Embedding: vec, EmbeddingBlob: floatsToBytes(vec), VecIndexed: true}
+			if err := repo.db.Create(&chunk).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := insertVecChunk(repo.db, chunk.ID, vec); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	queries := [][]float32{randomVector(), randomVector(), randomVector()}
+	got, err := repo.SearchSimilarBatch(queries, 5)
+	if err != nil {
+		t.Fatalf("SearchSimilarBatch: %v", err)
+	}
+	if repo.GetVectorEngine() != VectorEngineSQLiteVec {
+		t.Fatal("engine fell back to brute force")
+	}
+	brute := NewBruteForceVectorEngine()
+	for i, q := range queries {
+		want, err := brute.Search(repo, q, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got[i]) != len(want) {
+			t.Fatalf("query %d: %d results, want %d", i, len(got[i]), len(want))
+		}
+		for j := range want {
+			if got[i][j].ChunkID != want[j].ChunkID || got[i][j].Similarity != want[j].Similarity {
+				t.Fatalf("query %d result %d: got chunk %d (%f), want %d (%f)",
+					i, j, got[i][j].ChunkID, got[i][j].Similarity, want[j].ChunkID, want[j].Similarity)
+			}
+		}
+	}
+}
+
+func TestSQLiteVecEngineFallsBackWhileIndexIncomplete(t *testing.T) {
+	repo, cleanup := setupVectorEngineTestDB(t)
+	defer cleanup()
+	// A plain table stands in for the virtual one; the chunk below was never copied into it
+	if err := repo.db.Exec("CREATE TABLE vec_chunks (chunk_id INTEGER PRIMARY KEY, embedding BLOB)").Error; err != nil {
+		t.Fatal(err)
+	}
+	file := File{Path: "note.md", Title: "note"}
+	if err := repo.db.Create(&file).Error; err != nil {
+		t.Fatal(err)
+	}
+	vec := []float32{1, 0}
+	if err := repo.db.Create(&Chunk{FileID: file.ID, Content: "c", Embedding: vec, EmbeddingBlob: floatsToBytes(vec)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	repo.vectorEngine = NewSQLiteVecEngine() // SetVectorEngine would refuse without the extension
+	results, err := repo.SearchSimilar("", vec, 3)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected the brute-force result, got %v %v", results, err)
+	}
+	if repo.GetVectorEngine() != VectorEngineSQLiteVec {
+		t.Fatal("an incomplete index must not switch the engine for good")
+	}
+}
+
+func TestSetVectorEngineReportsMissingExtension(t *testing.T) {
+	repo, cleanup := setupVectorEngineTestDB(t)
+	defer cleanup()
+	if got := repo.SetVectorEngine(VectorEngineSQLiteVec); got != VectorEngineBruteForce {
+		t.Fatalf("engine without the extension: %s", got)
+	}
+	if repo.VectorEngineFallbackReason() == "" {
+		t.Fatal("expected a fallback reason")
+	}
+}
+
+func TestSQLiteVecChecksumsCoverEveryAsset(t *testing.T) {
+	for platform := range vecPlatforms {
+		goos, goarch, _ := strings.Cut(platform, "/")
+		asset, err := vecAssetNameFor(goos, goarch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sqliteVecChecksums[asset]) != sha256.Size*2 {
+			t.Errorf("%s: no SHA-256 pinned for %s", platform, asset)
+		}
+	}
+}
+
+func TestInstallSQLiteVecVerifiesChecksum(t *testing.T) {
+	asset, err := vecAssetName()
+	if err != nil {
+		t.Skip(err)
+	}
+	var archive bytes.Buffer
+	zw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(zw)
+	lib := []byte("not really a library")
+	tw.WriteHeader(&tar.Header{Name: "./" + vecLibraryName(), Mode: 0755, Size: int64(len(lib)), Typeflag: tar.TypeReg})
+	tw.Write(lib)
+	tw.Close()
+	zw.Close()
+	sum := sha256.Sum256(archive.Bytes())
+	checksumsFile := strings.Repeat("0", 64) + "  " + asset + "\n"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "checksums.txt":
+			// A tampered release would publish matching checksums; they must not be used
+			io.WriteString(w, checksumsFile)
+		case asset:
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(old string) { sqliteVecReleaseURL = old }(sqliteVecReleaseURL)
+	sqliteVecReleaseURL = srv.URL
+	defer func(old map[string]string) { sqliteVecChecksums = old }(sqliteVecChecksums)
+
+	sqliteVecChecksums = map[string]string{}
+	if _, err := InstallSQLiteVec(context.Background(), srv.Client(), t.TempDir()); err == nil || !strings.Contains(err.Error(), "no pinned checksum") {
+		t.Fatalf("expected an unpinned asset to be refused, got %v", err)
+	}
+
+	sqliteVecChecksums = map[string]string{asset: hex.EncodeToString(sum[:])}
+	dir := t.TempDir()
+	installed, err := InstallSQLiteVec(context.Background(), srv.Client(), dir)
+	if err != nil {
+		t.Fatalf("InstallSQLiteVec: %v", err)
+	}
+	if data, _ := os.ReadFile(installed); !bytes.Equal(data, lib) || InstalledSQLiteVec(dir) != installed {
+		t.Fatalf("unexpected install at %s", installed)
+	}
+
+	sqliteVecChecksums = map[string]string{asset: strings.Repeat("0", 64)}
+	if _, err := InstallSQLiteVec(context.Background(), srv.Client(), t.TempDir()); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
@@ -66,7 +67,7 @@ func TestSearchSimilar_FallbackFromSQLiteVecToBruteForce(t *testing.T) {
 
 	repo.SetVectorEngine(VectorEngineSQLiteVec)
 
-	results, err := repo.SearchSimilar([]float32{1, 0.5}, 2)
+	results, err := repo.SearchSimilar("", []float32{1, 0.5}, 2)
 	if err != nil {
 		t.Fatalf("search should fallback to brute-force, got err: %v", err)
 	}
@@ -87,3 +88,75 @@ func TestSetVectorEngine_UnknownFallsBack(t *testing.T) {
 		t.Fatalf("expected fallback to %s, got %s", VectorEngineBruteForce, effective)
 	}
 }
+
+func TestSearchSimilar_NormalizedSpaceAfterConfigChange(t *testing.T) {
+	repo, cleanup := setupVectorEngineTestDB(t)
+	defer cleanup()
+
+	repo.SetVectorSpace(VectorSpace{Normalize: true, Metric: SimilarityDot})
+	if err := repo.IndexFileWithChunks("a.md", "a", NoteStats{}, 1, 1, []ChunkInput{{Content: "a", Embedding: []float32{3, 0}}}); err != nil {
+		t.Fatalf("index a failed: %v", err)
+	}
+	_, stored, err := repo.GetFileEmbeddings("a.md")
+	if err != nil || len(stored) != 1 || stored[0][0] != 1 {
+		t.Fatalf("embedding not normalized at index time: %v %v", stored, err)
+	}
+
+	// Turning normalization off must not rank raw vectors against unit ones
+	repo.SetVectorSpace(VectorSpace{Normalize: false, Metric: SimilarityDot})
+	if err := repo.IndexFileWithChunks("b.md", "b", NoteStats{}, 1, 1, []ChunkInput{{Content: "b", Embedding: []float32{10, 9}}}); err != nil {
+		t.Fatalf("index b failed: %v", err)
+	}
+	results, err := repo.SearchSimilar("", []float32{1, 0}, 2)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Content != "a" || results[0].Similarity > 1.0001 || results[1].Similarity > 1 {
+		t.Fatalf("search mixed vector spaces: %+v", results)
+	}
+
+	status, err := repo.GetVectorSpaceStatus()
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if status.NormalizedChunks != 1 || status.RawChunks != 1 || !status.SearchNormalized {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestVectorScorer_Metrics(t *testing.T) {
+	query := []float32{1, 0}
+	vec := []float32{0, 2}
+	cases := map[string]float32{SimilarityCosine: 0, SimilarityDot: 0, SimilarityEuclidean: float32(1 / (1 + math.Sqrt(5)))}
+	for metric, want := range cases {
+		s := vectorScorer{metric: metric, query: query}
+		if got := s.score(append([]float32(nil), vec...), false); math.Abs(float64(got-want)) > 1e-6 {
+			t.Errorf("%s score = %v, want %v", metric, got, want)
+		}
+	}
+}
+
+func TestSearchSimilarImages_OnlyAttachmentsBestPerSource(t *testing.T) {
+	repo, cleanup := setupVectorEngineTestDB(t)
+	defer cleanup()
+
+	if err := repo.IndexFileWithChunks("a.md", "a", NoteStats{}, 1, 1, []ChunkInput{
+		{Content: "note text", Embedding: []float32{1, 0}},
+		{Content: "diagram text", Source: "diagram.png", Embedding: []float32{0.9, 0.1}},
+		{Content: "diagram caption", Source: "diagram.png", Embedding: []float32{0.95, 0.05}},
+		{Content: "photo caption", Source: "photo.jpg", Embedding: []float32{0, 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := repo.SearchSimilarImages([]float32{1, 0}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Content != "diagram caption" || results[1].Source != "photo.jpg" {
+		t.Fatalf("unexpected image results: %+v", results)
+	}
+	if results, _ := repo.SearchSimilarImages([]float32{1, 0}, 1); len(results) != 1 || results[0].Source != "diagram.png" {
+		t.Fatalf("limit not applied: %+v", results)
+	}
+}
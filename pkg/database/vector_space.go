@@ -0,0 +1,154 @@
+package database
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// Similarity metrics for comparing embeddings
+const (
+	SimilarityCosine    = "cosine"
+	SimilarityDot       = "dot"
+	SimilarityEuclidean = "euclidean"
+)
+
+// VectorSpace describes how chunk embeddings are stored and compared
+type VectorSpace struct {
+	// Normalize scales embeddings to unit length when they are stored
+	Normalize bool `json:"normalize"`
+	// Metric is SimilarityCosine, SimilarityDot or SimilarityEuclidean.
+	// Euclidean distance d is reported as the similarity 1/(1+d).
+	Metric string `json:"metric"`
+}
+
+// VectorSpaceStatus reports the configured vector space and how the stored
+// embeddings fit it
+type VectorSpaceStatus struct {
+	VectorSpace
+	NormalizedChunks int64 `json:"normalized_chunks"`
+	RawChunks        int64 `json:"raw_chunks"`
+	// SearchNormalized is true when searches compare unit vectors, either
+	// because Normalize is set or because normalized embeddings are still
+	// stored from an earlier setting
+	SearchNormalized bool `json:"search_normalized"`
+}
+
+// SetVectorSpace sets how new embeddings are stored and how searches score
+// them. An unknown metric selects cosine similarity. Embeddings already in the
+// index keep their form; searches never compare raw and normalized vectors
+// with each other.
+func (r *Repository) SetVectorSpace(space VectorSpace) {
+	switch space.Metric {
+	case SimilarityCosine, SimilarityDot, SimilarityEuclidean:
+	default:
+		space.Metric = SimilarityCosine
+	}
+	r.space.Store(&space)
+}
+
+// GetVectorSpace returns the configured vector space
+func (r *Repository) GetVectorSpace() VectorSpace {
+	if space := r.space.Load(); space != nil {
+		return *space
+	}
+	return VectorSpace{Metric: SimilarityCosine}
+}
+
+// GetVectorSpaceStatus counts stored embeddings by form
+func (r *Repository) GetVectorSpaceStatus() (*VectorSpaceStatus, error) {
+	status := &VectorSpaceStatus{VectorSpace: r.GetVectorSpace()}
+	embedded := r.db.Model(&Chunk{}).Where("embedding_blob IS NOT NULL AND length(embedding_blob) > 0")
+	if err := embedded.Session(&gorm.Session{}).Where("embedding_normalized = ?", true).Count(&status.NormalizedChunks).Error; err != nil {
+		return nil, err
+	}
+	if err := embedded.Session(&gorm.Session{}).Where("embedding_normalized = ?", false).Count(&status.RawChunks).Error; err != nil {
+		return nil, err
+	}
+	status.SearchNormalized = status.Normalize || status.NormalizedChunks > 0
+	return status, nil
+}
+
+// vectorScorer scores stored embeddings against one query in the
+// repository's vector space
+type vectorScorer struct {
+	metric    string
+	normalize bool
+	query     []float32
+}
+
+// newScorer prepares query for scoring. Searches run in normalized space when
+// normalization is on or any stored embedding was normalized, so a metric
+// that depends on vector length never ranks raw against unit vectors.
+func (r *Repository) newScorer(query []float32) vectorScorer {
+	space := r.GetVectorSpace()
+	s := vectorScorer{metric: space.Metric, normalize: space.Normalize, query: query}
+	if s.metric == SimilarityCosine {
+		// Cosine similarity ignores length, so raw vectors are fine as they are
+		s.normalize = false
+		return s
+	}
+	if !s.normalize {
+		r.db.Raw("SELECT EXISTS(SELECT 1 FROM chunks WHERE embedding_normalized = 1 AND deleted_at IS NULL)").Scan(&s.normalize)
+	}
+	if s.normalize {
+		s.query = normalizeVector(query)
+	}
+	return s
+}
+
+// score returns the similarity of vec to the query, higher being closer.
+// normalized says whether vec is already unit length; otherwise it may be
+// scaled in place.
+func (s vectorScorer) score(vec []float32, normalized bool) float32 {
+	if len(vec) != len(s.query) {
+		return 0
+	}
+	if s.normalize && !normalized {
+		normalizeInPlace(vec)
+	}
+	switch s.metric {
+	case SimilarityDot:
+		return dotProduct(s.query, vec)
+	case SimilarityEuclidean:
+		var sum float64
+		for i := range vec {
+			d := float64(s.query[i] - vec[i])
+			sum += d * d
+		}
+		return float32(1 / (1 + math.Sqrt(sum)))
+	default:
+		return cosineSimilarity(s.query, vec)
+	}
+}
+
+func dotProduct(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// normalizeVector returns a unit-length copy of v; zero vectors are copied
+// unchanged
+func normalizeVector(v []float32) []float32 {
+	out := make([]float32, len(v))
+	copy(out, v)
+	normalizeInPlace(out)
+	return out
+}
+
+func normalizeInPlace(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+}
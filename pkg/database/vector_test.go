@@ -82,7 +82,7 @@ func BenchmarkSearchSimilar_1000Chunks(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := repo.SearchSimilar(queryVec, 5)
+		_, err := repo.SearchSimilar("", queryVec, 5)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -118,7 +118,7 @@ func BenchmarkSearchSimilar_100Chunks(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := repo.SearchSimilar(queryVec, 5)
+		_, err := repo.SearchSimilar("", queryVec, 5)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -0,0 +1,204 @@
+// Package digest summarizes recently created and modified notes with the LLM
+// into a weekly review note.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+)
+
+const (
+	// maxNoteRunes bounds how much of each note is sent to the LLM
+	maxNoteRunes = 1500
+	// maxPromptRunes bounds the note material in one prompt; notes past it are listed by title only
+	maxPromptRunes = 24000
+)
+
+const systemPrompt = `You write a periodic review digest of a user's personal notes.
+Summarize the main themes, decisions, open questions and follow-ups across the notes in a few short sections of Markdown.
+Refer to notes by their title. Do not invent content that is not in the notes. Do not add a top-level heading.`
+
+// Note is a note covered by a digest
+type Note struct {
+	Path     string `json:"path"`
+	Title    string `json:"title"`
+	Modified int64  `json:"modified"` // Unix seconds
+	New      bool   `json:"new"`      // First indexed within the digest period
+}
+
+// Digest is a generated summary of recent notes
+type Digest struct {
+	Path    string `json:"path"` // Where the digest note is written, relative to the vault root
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Notes   []Note `json:"notes"`
+	From    int64  `json:"from"` // Unix ms
+	To      int64  `json:"to"`   // Unix ms
+}
+
+// Generator builds digests from the index and the LLM
+type Generator struct {
+	fm  *files.Manager
+	dbm *database.Manager
+	llm ai.LLMProvider
+	cfg *config.Config
+}
+
+// NewGenerator creates a digest generator
+func NewGenerator(fm *files.Manager, dbm *database.Manager, llm ai.LLMProvider, cfg *config.Config) *Generator {
+	return &Generator{fm: fm, dbm: dbm, llm: llm, cfg: cfg}
+}
+
+// Generate summarizes notes modified in the days before now. days <= 0 uses the configured period.
+func (g *Generator) Generate(ctx context.Context, now time.Time, days int) (*Digest, error) {
+	if !g.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if g.llm == nil {
+		return nil, fmt.Errorf("LLM provider is not configured")
+	}
+	digestCfg := g.cfg.GetDigestConfig()
+	if days <= 0 {
+		days = digestCfg.Days
+	}
+	from := now.AddDate(0, 0, -days)
+
+	indexed, err := g.dbm.Repository().ListFiles()
+	if err != nil {
+		return nil, err
+	}
+	notes := recentNotes(indexed, from, digestCfg.Folder)
+
+	title := "Digest " + WeekName(now)
+	d := &Digest{
+		Path:  WeekPath(digestCfg.Folder, now),
+		Title: title,
+		Notes: notes,
+		From:  from.UnixMilli(),
+		To:    now.UnixMilli(),
+	}
+
+	summary := "No notes were created or modified in this period."
+	if len(notes) > 0 {
+		prompt := g.buildPrompt(notes, from, now)
+		completion, err := g.llm.GenerateCompletion(ai.WithOperation(ctx, ai.OperationAssist), &ai.CompletionRequest{
+			Messages: []ai.ChatMessage{
+				{Role: "system", Content: systemPrompt},
+				{Role: "user", Content: prompt},
+			},
+			Model:       g.cfg.GetLLMConfig().Model,
+			Temperature: 0.3,
+			MaxTokens:   g.cfg.GetLLMConfig().MaxTokens,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate digest: %w", err)
+		}
+		summary = strings.TrimSpace(completion.Content)
+	}
+	d.Content = render(d, summary)
+	return d, nil
+}
+
+// recentNotes returns notes modified since from, newest first, skipping earlier digests
+func recentNotes(indexed []database.File, from time.Time, digestFolder string) []Note {
+	prefix := strings.Trim(digestFolder, "/") + "/"
+	var notes []Note
+	for _, f := range indexed {
+		if digestFolder != "" && strings.HasPrefix(f.Path, prefix) {
+			continue
+		}
+		if f.LastModified < from.Unix() {
+			continue
+		}
+		notes = append(notes, Note{
+			Path:     f.Path,
+			Title:    f.Title,
+			Modified: f.LastModified,
+			New:      !f.CreatedAt.Before(from),
+		})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Modified != notes[j].Modified {
+			return notes[i].Modified > notes[j].Modified
+		}
+		return notes[i].Path < notes[j].Path
+	})
+	return notes
+}
+
+func (g *Generator) buildPrompt(notes []Note, from, to time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Notes created or modified between %s and %s:\n\n", from.Format("2006-01-02"), to.Format("2006-01-02")))
+	budget := maxPromptRunes
+	var omitted []string
+	for _, n := range notes {
+		if budget <= 0 {
+			omitted = append(omitted, n.Title)
+			continue
+		}
+		note, err := g.fm.ReadFile(n.Path)
+		if err != nil {
+			omitted = append(omitted, n.Title)
+			continue
+		}
+		body := []rune(strings.TrimSpace(files.StripFrontmatter(note.Content)))
+		if len(body) > maxNoteRunes {
+			body = append(body[:maxNoteRunes], []rune("...")...)
+		}
+		if len(body) > budget {
+			body = body[:budget]
+		}
+		budget -= len(body)
+
+		status := "modified"
+		if n.New {
+			status = "new"
+		}
+		sb.WriteString(fmt.Sprintf("## %s (%s)\n%s\n\n", n.Title, status, string(body)))
+	}
+	if len(omitted) > 0 {
+		sb.WriteString("Also changed (content omitted): " + strings.Join(omitted, "; ") + "\n")
+	}
+	return sb.String()
+}
+
+func render(d *Digest, summary string) string {
+	var sb strings.Builder
+	sb.WriteString("# " + d.Title + "\n\n")
+	sb.WriteString(fmt.Sprintf("_%s – %s · %d notes_\n\n",
+		time.UnixMilli(d.From).Format("2006-01-02"),
+		time.UnixMilli(d.To).Format("2006-01-02"),
+		len(d.Notes)))
+	sb.WriteString(summary + "\n")
+	if len(d.Notes) > 0 {
+		sb.WriteString("\n## Notes\n\n")
+		for _, n := range d.Notes {
+			link := "[[" + strings.TrimSuffix(n.Path, path.Ext(n.Path)) + "|" + n.Title + "]]"
+			if n.New {
+				link += " (new)"
+			}
+			sb.WriteString("- " + link + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// WeekName returns the ISO week of t as "YYYY-WW"
+func WeekName(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-%02d", year, week)
+}
+
+// WeekPath returns the digest note path for the ISO week of t
+func WeekPath(folder string, t time.Time) string {
+	return path.Join(strings.Trim(folder, "/"), WeekName(t)+".md")
+}
@@ -0,0 +1,78 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"notebit/pkg/database"
+)
+
+func TestNextWeeklyRun(t *testing.T) {
+	loc := time.Local
+	// 2025-03-10 is a Monday
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 3, day, hour, 0, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name    string
+		lastRun time.Time
+		now     time.Time
+		want    time.Time
+	}{
+		{"never run, slot later today", time.Time{}, at(10, 8), at(10, 9)},
+		{"never run, slot passed today", time.Time{}, at(10, 10), at(10, 9)},
+		{"never run, mid-week waits for next slot", time.Time{}, at(12, 8), at(17, 9)},
+		{"ran this week", at(10, 9), at(12, 12), at(17, 9)},
+		{"missed last week's slot", at(1, 9), at(12, 12), at(10, 9)},
+	}
+	for _, tc := range cases {
+		got, err := nextWeeklyRun(time.Monday, "09:00", tc.lastRun, tc.now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := nextWeeklyRun(time.Monday, "9am", time.Time{}, at(10, 8)); err == nil {
+		t.Fatal("expected invalid time to fail")
+	}
+}
+
+func TestWeekPath(t *testing.T) {
+	got := WeekPath("/Digests/", time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	if got != "Digests/2025-01.md" {
+		t.Fatalf("WeekPath = %q", got)
+	}
+	// Dec 29 2025 belongs to ISO week 1 of 2026
+	if got := WeekName(time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC)); got != "2026-01" {
+		t.Fatalf("WeekName = %q", got)
+	}
+}
+
+func TestRecentNotesAndRender(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	from := now.AddDate(0, 0, -7)
+	indexed := []database.File{
+		{Path: "old.md", Title: "Old", LastModified: from.Add(-time.Hour).Unix()},
+		{Path: "edited.md", Title: "Edited", LastModified: now.Add(-48 * time.Hour).Unix(), CreatedAt: from.AddDate(0, -1, 0)},
+		{Path: "fresh.md", Title: "Fresh", LastModified: now.Add(-time.Hour).Unix(), CreatedAt: now.Add(-time.Hour)},
+		{Path: "Digests/2025-10.md", Title: "Digest", LastModified: now.Unix()},
+	}
+
+	notes := recentNotes(indexed, from, "Digests")
+	if len(notes) != 2 || notes[0].Path != "fresh.md" || !notes[0].New || notes[1].New {
+		t.Fatalf("unexpected notes: %+v", notes)
+	}
+
+	d := &Digest{Title: "Digest 2025-11", Notes: notes, From: from.UnixMilli(), To: now.UnixMilli()}
+	content := render(d, "Summary.")
+	for _, want := range []string{"# Digest 2025-11", "Summary.", "- [[fresh|Fresh]] (new)", "- [[edited|Edited]]\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("digest is missing %q:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+const (
+	scheduleCheckInterval = time.Minute
+	scheduleStateFile     = "digest_last_run"
+)
+
+// Status reports the state of the digest scheduler
+type Status struct {
+	Enabled   bool   `json:"enabled"`
+	Running   bool   `json:"running"`
+	NextRun   int64  `json:"next_run"` // Unix ms, 0 when disabled
+	LastRun   int64  `json:"last_run"` // Unix ms, 0 when never run
+	LastPath  string `json:"last_path,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// RunFunc generates and saves a digest, returning the note path
+type RunFunc func(ctx context.Context) (string, error)
+
+// Scheduler runs the digest once a week on the configured day and time
+type Scheduler struct {
+	run      RunFunc
+	stateDir func() string
+
+	mu       sync.Mutex
+	cfg      config.DigestConfig
+	lastRun  time.Time
+	lastPath string
+	lastErr  string
+	running  bool
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler. stateDir returns the directory the last run
+// time is persisted in ("" disables persistence).
+func NewScheduler(run RunFunc, stateDir func() string) *Scheduler {
+	return &Scheduler{run: run, stateDir: stateDir, now: time.Now}
+}
+
+// Start begins checking the schedule. Calling Start again applies a new config.
+func (s *Scheduler) Start(cfg config.DigestConfig) {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.lastRun = s.loadLastRun()
+	if !cfg.Enabled {
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go s.loop(s.stopCh, s.doneCh)
+}
+
+// Stop halts the scheduler
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Enabled:   s.cfg.Enabled,
+		Running:   s.running,
+		LastPath:  s.lastPath,
+		LastError: s.lastErr,
+	}
+	if !s.lastRun.IsZero() {
+		status.LastRun = s.lastRun.UnixMilli()
+	}
+	if s.cfg.Enabled {
+		if next, err := nextWeeklyRun(time.Weekday(s.cfg.Weekday), s.cfg.Time, s.lastRun, s.now()); err == nil {
+			status.NextRun = next.UnixMilli()
+		}
+	}
+	return status
+}
+
+// RunNow generates the digest immediately
+func (s *Scheduler) RunNow(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return "", fmt.Errorf("digest already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	path, err := s.run(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.lastRun = s.now()
+	s.saveLastRun(s.lastRun)
+	if err != nil {
+		s.lastErr = err.Error()
+		logger.WarnWithFields(ctx, map[string]interface{}{"error": err.Error()}, "Scheduled digest failed")
+		return "", err
+	}
+	s.lastErr = ""
+	s.lastPath = path
+	logger.InfoWithFields(ctx, map[string]interface{}{"path": path}, "Digest written")
+	return path, nil
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer logger.RecoverPanic()
+	defer close(done)
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.shouldRun() {
+				_, _ = s.RunNow(context.Background())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) shouldRun() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running || !s.cfg.Enabled {
+		return false
+	}
+	now := s.now()
+	next, err := nextWeeklyRun(time.Weekday(s.cfg.Weekday), s.cfg.Time, s.lastRun, now)
+	return err == nil && !now.Before(next)
+}
+
+// nextWeeklyRun returns the next run time given the last run. The result may be
+// in the past, which means a run is due (e.g. the slot passed while the app was closed).
+func nextWeeklyRun(weekday time.Weekday, hhmm string, lastRun, now time.Time) (time.Time, error) {
+	hour, minute, err := parseClock(hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// This week's slot: the configured weekday on or before today
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	slot := today.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	slot = slot.AddDate(0, 0, -((int(now.Weekday()) - int(weekday) + 7) % 7))
+	if lastRun.IsZero() {
+		// First run: wait for the next slot unless it falls today
+		if slot.Before(today) {
+			return slot.AddDate(0, 0, 7), nil
+		}
+		return slot, nil
+	}
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -7)
+	}
+	if lastRun.Before(slot) {
+		return slot, nil
+	}
+	return slot.AddDate(0, 0, 7), nil
+}
+
+func parseClock(hhmm string) (int, int, error) {
+	parts := strings.Split(strings.TrimSpace(hhmm), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid digest time %q, expected HH:MM", hhmm)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("invalid digest hour %q", parts[0])
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid digest minute %q", parts[1])
+	}
+	return hour, minute, nil
+}
+
+func (s *Scheduler) stateFilePath() string {
+	dir := s.stateDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, scheduleStateFile)
+}
+
+func (s *Scheduler) loadLastRun() time.Time {
+	path := s.stateFilePath()
+	if path == "" {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func (s *Scheduler) saveLastRun(t time.Time) {
+	path := s.stateFilePath()
+	if path == "" {
+		return
+	}
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(t.UnixMilli(), 10)), 0644); err != nil {
+		logger.Warn("Failed to persist digest state: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+// Package feeds reads RSS and Atom feeds and renders their entries as notes.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// fetchTimeout bounds one feed request
+	fetchTimeout = 30 * time.Second
+	// maxFeedBytes bounds how much of a feed is read
+	maxFeedBytes = 10 << 20
+)
+
+const userAgent = "Mozilla/5.0 (compatible; Notebit feed reader)"
+
+// ErrNotFeed is returned for documents that are neither RSS nor Atom
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Title   string  `json:"title"`
+	Link    string  `json:"link"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is one item of a feed
+type Entry struct {
+	GUID      string    `json:"guid"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Author    string    `json:"author"`
+	Published time.Time `json:"published"` // Zero when the feed has no date
+	Content   string    `json:"content"`   // HTML or plain text
+}
+
+// Result is the outcome of fetching a feed
+type Result struct {
+	Feed *Feed
+	// NotModified is set when the server reported the feed unchanged since
+	// the ETag or Last-Modified of the previous fetch; Feed is nil then
+	NotModified  bool
+	ETag         string
+	LastModified string
+}
+
+// Fetch downloads and parses the feed at url. etag and lastModified come
+// from the previous fetch and may be empty.
+func Fetch(ctx context.Context, client *http.Client, url, etag, lastModified string) (*Result, error) {
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.5")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &Result{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.ETag, result.LastModified = etag, lastModified
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return nil, err
+	}
+	result.Feed, err = Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type xmlLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type xmlItem struct {
+	Title       string    `xml:"title"`
+	Links       []xmlLink `xml:"link"`
+	GUID        string    `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string    `xml:"author"`
+	Creator     string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description string    `xml:"description"`
+	Encoded     string    `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+type xmlChannel struct {
+	Title string    `xml:"title"`
+	Links []xmlLink `xml:"link"`
+	Items []xmlItem `xml:"item"`
+}
+
+type xmlEntry struct {
+	ID        string    `xml:"id"`
+	Title     string    `xml:"title"`
+	Links     []xmlLink `xml:"link"`
+	Published string    `xml:"published"`
+	Updated   string    `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Summary atomText `xml:"summary"`
+	Content atomText `xml:"content"`
+}
+
+// atomText is Atom text that is escaped HTML or plain text, or inline XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// xmlFeed covers RSS 2.0 (rss/channel/item), RSS 1.0 (RDF with items next to
+// the channel) and Atom (feed/entry)
+type xmlFeed struct {
+	XMLName xml.Name
+	Channel *xmlChannel `xml:"channel"`
+	Items   []xmlItem   `xml:"item"`
+	Title   string      `xml:"title"`
+	Links   []xmlLink   `xml:"link"`
+	Entries []xmlEntry  `xml:"entry"`
+}
+
+// Parse parses an RSS or Atom document. Entries are ordered newest first;
+// entries without a date keep their document order after dated ones.
+func Parse(data []byte) (*Feed, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	// Feeds in the wild are often not quite XML
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	var doc xmlFeed
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFeed, err)
+	}
+
+	var feed *Feed
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		if doc.Channel == nil {
+			return nil, ErrNotFeed
+		}
+		feed = &Feed{Title: clean(doc.Channel.Title), Link: rssLink(doc.Channel.Links)}
+		for _, item := range append(doc.Channel.Items, doc.Items...) {
+			feed.Entries = append(feed.Entries, rssEntry(item))
+		}
+	case "feed":
+		feed = &Feed{Title: clean(doc.Title), Link: atomLink(doc.Links)}
+		for _, e := range doc.Entries {
+			feed.Entries = append(feed.Entries, atomEntry(e))
+		}
+	default:
+		return nil, ErrNotFeed
+	}
+
+	for i := range feed.Entries {
+		e := &feed.Entries[i]
+		if e.GUID == "" {
+			e.GUID = e.Link
+		}
+		if e.GUID == "" {
+			sum := sha256.Sum256([]byte(e.Title + "\x00" + e.Content))
+			e.GUID = hex.EncodeToString(sum[:16])
+		}
+	}
+	sort.SliceStable(feed.Entries, func(i, j int) bool {
+		a, b := feed.Entries[i].Published, feed.Entries[j].Published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.After(b)
+	})
+	return feed, nil
+}
+
+func rssEntry(item xmlItem) Entry {
+	content := item.Encoded
+	if strings.TrimSpace(content) == "" {
+		content = item.Description
+	}
+	return Entry{
+		GUID:      strings.TrimSpace(item.GUID),
+		Title:     clean(item.Title),
+		Link:      rssLink(item.Links),
+		Author:    clean(first(item.Creator, item.Author)),
+		Published: parseDate(first(item.PubDate, item.Date)),
+		Content:   strings.TrimSpace(content),
+	}
+}
+
+func atomEntry(e xmlEntry) Entry {
+	var authors []string
+	for _, a := range e.Authors {
+		if name := clean(a.Name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return Entry{
+		GUID:      strings.TrimSpace(e.ID),
+		Title:     clean(e.Title),
+		Link:      atomLink(e.Links),
+		Author:    strings.Join(authors, ", "),
+		Published: parseDate(first(e.Published, e.Updated)),
+		Content:   strings.TrimSpace(first(e.Content.String(), e.Summary.String())),
+	}
+}
+
+// rssLink returns the text of the first plain <link>, skipping atom:link
+// elements that RSS feeds use to point at themselves
+func rssLink(links []xmlLink) string {
+	for _, l := range links {
+		if text := strings.TrimSpace(l.Text); text != "" {
+			return text
+		}
+	}
+	return atomLink(links)
+}
+
+// atomLink returns the alternate link, the one pointing at the web page
+func atomLink(links []xmlLink) string {
+	for _, l := range links {
+		if (l.Rel == "" || l.Rel == "alternate") && l.Href != "" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+// dateLayouts are the date formats found in feeds, most common first
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses a feed date, returning the zero time when it cannot
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// clean collapses whitespace in a title or name
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
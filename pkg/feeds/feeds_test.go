@@ -0,0 +1,208 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Weekly   Notes</title>
+  <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+  <link>https://example.com/</link>
+  <item>
+    <title>Older post</title>
+    <link>https://example.com/older</link>
+    <pubDate>Mon, 03 Mar 2025 08:00:00 +0000</pubDate>
+    <description>Short &amp; sweet</description>
+  </item>
+  <item>
+    <title>Newer post</title>
+    <link>https://example.com/newer</link>
+    <guid isPermaLink="false">post-2</guid>
+    <pubDate>Tue, 4 Mar 2025 09:30:00 GMT</pubDate>
+    <dc:creator>Ada</dc:creator>
+    <description>Summary only</description>
+    <content:encoded><![CDATA[<p>Full <b>text</b> with a <a href="/link">link</a>.</p>]]></content:encoded>
+  </item>
+</channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Blog</title>
+  <link rel="self" href="https://blog.example.org/atom.xml"/>
+  <link href="https://blog.example.org/"/>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Hello</title>
+    <link rel="alternate" href="https://blog.example.org/hello"/>
+    <updated>2025-03-05T10:00:00Z</updated>
+    <author><name>Grace</name></author>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Inline <em>XHTML</em></p></div></content>
+  </entry>
+  <entry>
+    <id>urn:uuid:2</id>
+    <title>Escaped</title>
+    <published>2025-03-06T10:00:00Z</published>
+    <summary type="html">&lt;p&gt;Escaped HTML&lt;/p&gt;</summary>
+  </entry>
+</feed>`
+
+func TestParseRSS(t *testing.T) {
+	feed, err := Parse([]byte(testRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Weekly Notes" || feed.Link != "https://example.com/" {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Entries))
+	}
+	newer, older := feed.Entries[0], feed.Entries[1]
+	if newer.GUID != "post-2" || newer.Author != "Ada" || !strings.Contains(newer.Content, "<b>text</b>") {
+		t.Errorf("unexpected newest entry: %+v", newer)
+	}
+	if want := time.Date(2025, 3, 4, 9, 30, 0, 0, time.UTC); !newer.Published.Equal(want) {
+		t.Errorf("published = %v, want %v", newer.Published, want)
+	}
+	if older.GUID != "https://example.com/older" || older.Content != "Short & sweet" {
+		t.Errorf("unexpected older entry: %+v", older)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	feed, err := Parse([]byte(testAtom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Atom Blog" || feed.Link != "https://blog.example.org/" || len(feed.Entries) != 2 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	escaped, hello := feed.Entries[0], feed.Entries[1]
+	if escaped.Content != "<p>Escaped HTML</p>" || escaped.Link != "" {
+		t.Errorf("unexpected escaped entry: %+v", escaped)
+	}
+	if hello.Author != "Grace" || hello.Link != "https://blog.example.org/hello" || !strings.Contains(hello.Content, "<em>XHTML</em>") {
+		t.Errorf("unexpected xhtml entry: %+v", hello)
+	}
+
+	if _, err := Parse([]byte("<html><body>not a feed</body></html>")); err == nil {
+		t.Error("expected an HTML page to be rejected")
+	}
+}
+
+func TestEntryNote(t *testing.T) {
+	e := Entry{
+		Title:     "Newer post",
+		Link:      "https://example.com/newer",
+		Author:    "Ada",
+		Published: time.Date(2025, 3, 4, 9, 30, 0, 0, time.UTC),
+		Content:   `<p>Full <b>text</b> with a <a href="/link">link</a>.</p>`,
+	}
+	note := EntryNote("Weekly Notes", e)
+	for _, want := range []string{
+		"source: \"https://example.com/newer\"\n",
+		"feed: \"Weekly Notes\"\n",
+		"published: 2025-03-04T09:30:00Z\n",
+		"# Newer post\n\nFull **text** with a [link](https://example.com/link).\n",
+		"[Read the original](https://example.com/newer)",
+	} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note missing %q:\n%s", want, note)
+		}
+	}
+	if name := EntryNoteName(e, time.Now()); name != "2025-03-04 Newer post" {
+		t.Errorf("EntryNoteName = %q", name)
+	}
+	if name := FolderName("", "https://www.example.com/feed"); name != "example.com" {
+		t.Errorf("FolderName = %q", name)
+	}
+	if name := FolderName("News: A/B", ""); name != "News- A-B" {
+		t.Errorf("FolderName = %q", name)
+	}
+}
+
+func TestEntryNoteNameStaysInFolder(t *testing.T) {
+	date := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"A/B testing":         "2025-03-04 A-B testing",
+		"../../etc/passwd":    "2025-03-04 -..-etc-passwd",
+		"..":                  "2025-03-04 Untitled",
+		`..\..\Windows\notes`: "2025-03-04 -..-Windows-notes",
+		"What? Why: <this>":   "2025-03-04 What Why- this",
+	}
+	for title, want := range tests {
+		name := EntryNoteName(Entry{Title: title, Published: date}, date)
+		if name != want {
+			t.Errorf("EntryNoteName(%q) = %q, want %q", title, name, want)
+		}
+		if strings.ContainsAny(name, `/\`) || path.Clean(path.Join("Feed", name)) != path.Join("Feed", name) {
+			t.Errorf("EntryNoteName(%q) = %q leaves the feed folder", title, name)
+		}
+	}
+}
+
+func TestFetchConditional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	result, err := Fetch(context.Background(), server.Client(), server.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NotModified || result.ETag != `"v1"` || len(result.Feed.Entries) != 2 {
+		t.Fatalf("unexpected first fetch: %+v", result)
+	}
+	result, err = Fetch(context.Background(), server.Client(), server.URL, result.ETag, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.NotModified || result.Feed != nil || result.ETag != `"v1"` {
+		t.Fatalf("expected not modified, got %+v", result)
+	}
+}
+
+func TestSchedulerDue(t *testing.T) {
+	runs := 0
+	s := NewScheduler(func(ctx context.Context) (int, error) {
+		runs++
+		return 3, nil
+	})
+	now := time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.interval = time.Hour
+
+	if !s.due() {
+		t.Fatal("a scheduler that never ran should be due")
+	}
+	if added, err := s.RunNow(context.Background()); err != nil || added != 3 {
+		t.Fatalf("RunNow = %d, %v", added, err)
+	}
+	if s.due() {
+		t.Fatal("should not be due right after a run")
+	}
+	now = now.Add(time.Hour)
+	if !s.due() {
+		t.Fatal("should be due after the interval")
+	}
+	status := s.Status()
+	if !status.Enabled || status.LastNew != 3 || runs != 1 {
+		t.Fatalf("unexpected status: %+v (runs %d)", status, runs)
+	}
+}
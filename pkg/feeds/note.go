@@ -0,0 +1,90 @@
+package feeds
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"notebit/pkg/urlmeta"
+)
+
+// maxEntryRunes bounds the Markdown body of an entry note
+const maxEntryRunes = 50000
+
+// FolderName returns the folder name of a feed: its title, or the host of its
+// URL, without characters that are invalid in file names
+func FolderName(title, feedURL string) string {
+	name := clean(title)
+	if name == "" {
+		if u, err := url.Parse(feedURL); err == nil {
+			name = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+	if name = fileName(name, 80); name == "" {
+		name = "Feed"
+	}
+	return name
+}
+
+// EntryNoteName returns the note name of an entry, its date and title, e.g.
+// "2025-03-04 Weekly update". The title comes from the feed, so path
+// separators are replaced and the name never leaves the feed folder.
+func EntryNoteName(e Entry, now time.Time) string {
+	date := e.Published
+	if date.IsZero() {
+		date = now
+	}
+	title := fileName(e.Title, 100)
+	if title == "" {
+		title = "Untitled"
+	}
+	return date.Format("2006-01-02") + " " + title
+}
+
+// fileNameReplacer drops or replaces characters that are invalid in file
+// names, path separators among them
+var fileNameReplacer = strings.NewReplacer("/", "-", "\\", "-", ":", "-", "*", "", "?", "", "\"", "", "<", "", ">", "", "|", "")
+
+// fileName makes name safe as a single file or folder name of at most
+// maxRunes runes; it may return ""
+func fileName(name string, maxRunes int) string {
+	name = strings.Trim(strings.TrimSpace(fileNameReplacer.Replace(name)), ".")
+	if runes := []rune(name); len(runes) > maxRunes {
+		name = strings.Trim(strings.TrimSpace(string(runes[:maxRunes])), ".")
+	}
+	return name
+}
+
+// EntryNote renders an entry as a note with its source and publication date
+// in the frontmatter and its content converted to Markdown
+func EntryNote(feedTitle string, e Entry) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("title: " + strconv.Quote(e.Title) + "\n")
+	if e.Link != "" {
+		b.WriteString("source: " + strconv.Quote(e.Link) + "\n")
+	}
+	b.WriteString("feed: " + strconv.Quote(feedTitle) + "\n")
+	if !e.Published.IsZero() {
+		b.WriteString("published: " + e.Published.Format(time.RFC3339) + "\n")
+	}
+	if e.Author != "" {
+		b.WriteString("author: " + strconv.Quote(e.Author) + "\n")
+	}
+	b.WriteString("tags: [feed]\n")
+	b.WriteString("---\n\n")
+
+	title := e.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	b.WriteString("# " + title + "\n\n")
+	if body := urlmeta.HTMLToMarkdown(e.Content, e.Link, maxEntryRunes); body != "" {
+		b.WriteString(body + "\n\n")
+	}
+	if e.Link != "" {
+		b.WriteString("[Read the original](" + e.Link + ")\n")
+	}
+	return b.String()
+}
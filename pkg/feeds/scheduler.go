@@ -0,0 +1,151 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+const scheduleCheckInterval = time.Minute
+
+// RunFunc polls every subscribed feed, returning the number of new entries
+type RunFunc func(ctx context.Context) (int, error)
+
+// Status reports the state of the feed scheduler
+type Status struct {
+	Enabled    bool   `json:"enabled"`
+	Running    bool   `json:"running"`
+	NextRun    int64  `json:"next_run"` // Unix ms, 0 when disabled
+	LastRun    int64  `json:"last_run"` // Unix ms, 0 when never run
+	LastNew    int    `json:"last_new"` // New entries of the last run
+	LastError  string `json:"last_error,omitempty"`
+	IntervalMs int64  `json:"interval_ms"`
+}
+
+// Scheduler polls feeds every interval. The first poll after Start happens
+// at the first check; conditional requests keep it cheap for unchanged feeds.
+type Scheduler struct {
+	run RunFunc
+
+	mu       sync.Mutex
+	interval time.Duration
+	lastRun  time.Time
+	lastNew  int
+	lastErr  string
+	running  bool
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler
+func NewScheduler(run RunFunc) *Scheduler {
+	return &Scheduler{run: run, now: time.Now}
+}
+
+// Start begins polling every interval; a zero interval disables polling.
+// Calling Start again applies a new interval.
+func (s *Scheduler) Start(interval time.Duration) {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = interval
+	if interval <= 0 {
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go s.loop(s.stopCh, s.doneCh)
+}
+
+// Stop halts the scheduler
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Enabled:    s.interval > 0,
+		Running:    s.running,
+		LastNew:    s.lastNew,
+		LastError:  s.lastErr,
+		IntervalMs: s.interval.Milliseconds(),
+	}
+	if !s.lastRun.IsZero() {
+		status.LastRun = s.lastRun.UnixMilli()
+	}
+	if status.Enabled {
+		status.NextRun = s.lastRun.Add(s.interval).UnixMilli()
+		if s.lastRun.IsZero() {
+			status.NextRun = s.now().UnixMilli()
+		}
+	}
+	return status
+}
+
+// RunNow polls the feeds immediately
+func (s *Scheduler) RunNow(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("feeds are already being polled")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	added, err := s.run(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.lastRun = s.now()
+	s.lastNew = added
+	if err != nil {
+		s.lastErr = err.Error()
+		logger.WarnWithFields(ctx, map[string]interface{}{"error": err.Error()}, "Feed poll failed")
+		return added, err
+	}
+	s.lastErr = ""
+	if added > 0 {
+		logger.InfoWithFields(ctx, map[string]interface{}{"entries": added}, "New feed entries written")
+	}
+	return added, nil
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer logger.RecoverPanic()
+	defer close(done)
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.due() {
+				_, _ = s.RunNow(context.Background())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) due() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.running && s.interval > 0 && !s.now().Before(s.lastRun.Add(s.interval))
+}
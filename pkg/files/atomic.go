@@ -0,0 +1,115 @@
+package files
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteOptions controls how note contents are written to disk
+type WriteOptions struct {
+	// Fsync flushes each write to stable storage before it is reported done
+	Fsync bool
+
+	// KeepBackup keeps the previous content of a saved note next to it as
+	// a hidden ".<name>.bak" file, one generation deep
+	KeepBackup bool
+}
+
+// SetWriteOptions sets how notes are written from now on
+func (m *Manager) SetWriteOptions(opts WriteOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeOpts = opts
+}
+
+// backupPath is where the previous content of fullPath is kept
+func backupPath(fullPath string) string {
+	return filepath.Join(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".bak")
+}
+
+// writeNote replaces fullPath with data through a temp file in the same
+// directory, so a crash mid-write leaves either the old or the new content,
+// never a truncated note
+func (m *Manager) writeNote(fullPath string, data []byte) error {
+	m.mu.RLock()
+	opts := m.writeOpts
+	m.mu.RUnlock()
+
+	dir := filepath.Dir(fullPath)
+	tmp, err := os.CreateTemp(dir, ".notebit-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if opts.Fsync {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		mode = info.Mode().Perm()
+		if opts.KeepBackup {
+			keepBackup(fullPath)
+		}
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return err
+	}
+	if opts.Fsync {
+		syncDir(dir)
+	}
+	return nil
+}
+
+// keepBackup saves the current content of fullPath as its backup. The hard
+// link shares the old content, which the rename over fullPath leaves intact;
+// file systems without links get a copy. Failures only cost the backup.
+func keepBackup(fullPath string) {
+	bak := backupPath(fullPath)
+	os.Remove(bak)
+	if err := os.Link(fullPath, bak); err == nil {
+		return
+	}
+	src, err := os.Open(fullPath)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.Create(bak)
+	if err != nil {
+		return
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(bak)
+		return
+	}
+	dst.Close()
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,113 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// tempFiles lists write temp files left behind in dir
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".notebit-*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestWriteNote(t *testing.T) {
+	for _, opts := range []WriteOptions{{}, {Fsync: true}} {
+		m, dir := newTestManager(t)
+		m.SetWriteOptions(opts)
+		note := filepath.Join(dir, "a.md")
+
+		if err := m.writeNote(note, []byte("one")); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.writeNote(note, []byte("two")); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(note); string(data) != "two" {
+			t.Errorf("%+v: content = %q", opts, data)
+		}
+		if left := tempFiles(t, dir); len(left) != 0 {
+			t.Errorf("%+v: temp files left: %q", opts, left)
+		}
+		if _, err := os.Stat(backupPath(note)); !os.IsNotExist(err) {
+			t.Errorf("%+v: backup written without KeepBackup: %v", opts, err)
+		}
+	}
+}
+
+func TestWriteNote_KeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not kept on Windows")
+	}
+	m, dir := newTestManager(t)
+	note := filepath.Join(dir, "private.md")
+	if err := os.WriteFile(note, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.writeNote(note, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteNote_KeepBackup(t *testing.T) {
+	m, dir := newTestManager(t)
+	m.SetWriteOptions(WriteOptions{KeepBackup: true})
+	note := filepath.Join(dir, "a.md")
+	bak := filepath.Join(dir, ".a.md.bak")
+
+	if err := m.writeNote(note, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(bak); !os.IsNotExist(err) {
+		t.Fatalf("backup of a new note: %v", err)
+	}
+	// One generation deep: each save replaces the backup with the content it overwrites
+	for _, next := range []string{"two", "three"} {
+		before, _ := os.ReadFile(note)
+		if err := m.writeNote(note, []byte(next)); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(bak); string(data) != string(before) {
+			t.Errorf("backup = %q, want %q", data, before)
+		}
+		if data, _ := os.ReadFile(note); string(data) != next {
+			t.Errorf("content = %q, want %q", data, next)
+		}
+	}
+}
+
+func TestWriteNote_FailureLeavesNoTempFile(t *testing.T) {
+	m, dir := newTestManager(t)
+	m.SetWriteOptions(WriteOptions{KeepBackup: true})
+
+	// A directory in the way makes the final rename fail
+	target := filepath.Join(dir, "busy.md")
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.writeNote(target, []byte("data")); err == nil {
+		t.Fatal("writeNote over a directory succeeded")
+	}
+	if left := tempFiles(t, dir); len(left) != 0 {
+		t.Errorf("temp files left after a failed write: %q", left)
+	}
+
+	missing := filepath.Join(dir, "no-such-dir", "a.md")
+	if err := m.writeNote(missing, []byte("data")); err == nil || !strings.Contains(err.Error(), "no-such-dir") {
+		t.Errorf("writeNote into a missing directory: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package files
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// audioExtensions lists the audio file types that can be transcribed
+var audioExtensions = map[string]bool{
+	".m4a":  true,
+	".mp3":  true,
+	".wav":  true,
+	".ogg":  true,
+	".oga":  true,
+	".opus": true,
+	".webm": true,
+	".flac": true,
+}
+
+// IsAudioFile reports whether path has an extension of a transcribable audio file
+func IsAudioFile(path string) bool {
+	return audioExtensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,217 @@
+package files
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AddFrontmatterTag adds tag to the note's YAML front matter "tags" field,
+// creating the field or the front matter block when missing. It reports false
+// when the note already has the tag.
+func AddFrontmatterTag(content, tag string) (string, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	if tag == "" {
+		return content, false
+	}
+
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(content, newline)
+
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return "---" + newline + "tags: [" + tag + "]" + newline + "---" + newline + newline + content, true
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		// Unterminated front matter: leave the note alone rather than guess
+		return content, false
+	}
+
+	for i := 1; i < end; i++ {
+		key, value, ok := strings.Cut(lines[i], ":")
+		if !ok || strings.TrimSpace(key) != "tags" || strings.HasPrefix(lines[i], " ") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			// Block list: "tags:" followed by "- item" lines
+			last := i
+			indent := "  "
+			for j := i + 1; j < end; j++ {
+				item := strings.TrimSpace(lines[j])
+				if item == "---" || !strings.HasPrefix(item, "-") {
+					break
+				}
+				if unquoteTag(strings.TrimSpace(strings.TrimPrefix(item, "-"))) == tag {
+					return content, false
+				}
+				indent = lines[j][:len(lines[j])-len(strings.TrimLeft(lines[j], " "))]
+				last = j
+			}
+			lines = append(lines[:last+1], append([]string{indent + "- " + tag}, lines[last+1:]...)...)
+			return strings.Join(lines, newline), true
+		}
+
+		var tags []string
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		}
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				if unquoteTag(t) == tag {
+					return content, false
+				}
+				tags = append(tags, t)
+			}
+		}
+		tags = append(tags, tag)
+		lines[i] = "tags: [" + strings.Join(tags, ", ") + "]"
+		return strings.Join(lines, newline), true
+	}
+
+	lines = append(lines[:end], append([]string{"tags: [" + tag + "]"}, lines[end:]...)...)
+	return strings.Join(lines, newline), true
+}
+
+func unquoteTag(s string) string {
+	return strings.TrimPrefix(strings.Trim(s, `"'`), "#")
+}
+
+// inlineTagPattern matches #tag outside words, e.g. "#project" but not "a#b" or "# Heading"
+var inlineTagPattern = regexp.MustCompile(`(?:^|[^\w\p{L}&#])#([\p{L}\w][\p{L}\w/-]*)`)
+
+// NoteTags returns the tags of a note: the front matter "tags" field followed
+// by inline #tags, without duplicates
+func NoteTags(content string) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	add := func(tag string) {
+		tag = unquoteTag(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	values, body := frontmatterList(content, "tags")
+	for _, t := range values {
+		add(t)
+	}
+	for _, tag := range InlineTags(body) {
+		add(tag)
+	}
+	return tags
+}
+
+// InlineTags returns the #tags written in text, without the #, in order
+func InlineTags(text string) []string {
+	var tags []string
+	for _, m := range inlineTagPattern.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, m[1])
+	}
+	return tags
+}
+
+// NoteAliases returns the front matter "aliases" (or "alias") of a note
+func NoteAliases(content string) []string {
+	values, _ := frontmatterList(content, "aliases", "alias")
+	var aliases []string
+	for _, v := range values {
+		if v = strings.Trim(strings.TrimSpace(v), `"'`); v != "" {
+			aliases = append(aliases, v)
+		}
+	}
+	return aliases
+}
+
+// frontmatterList returns the raw items of the first front matter field named
+// one of keys, written either inline ("key: [a, b]") or as a block list, and
+// the note body after the front matter.
+func frontmatterList(content string, keys ...string) ([]string, string) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, content
+	}
+
+	var values []string
+	found := false
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return values, strings.Join(lines[i+1:], "\n")
+		}
+		key, value, ok := strings.Cut(lines[i], ":")
+		if !ok || found || strings.HasPrefix(lines[i], " ") || !slices.Contains(keys, strings.TrimSpace(key)) {
+			continue
+		}
+		found = true
+		value = strings.TrimSpace(value)
+		if value == "" {
+			for j := i + 1; j < len(lines); j++ {
+				item := strings.TrimSpace(lines[j])
+				if item == "---" || !strings.HasPrefix(item, "-") {
+					break
+				}
+				values = append(values, strings.TrimPrefix(item, "-"))
+			}
+			continue
+		}
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		values = append(values, strings.Split(value, ",")...)
+	}
+	// Unterminated front matter: treat the whole note as body
+	return values, content
+}
+
+// FrontmatterField returns the value of the scalar front matter field key,
+// without quotes, or "" if the note has no such field
+func FrontmatterField(content, key string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return ""
+	}
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "---" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.TrimSpace(name) != key {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return strings.Trim(value, `"'`)
+	}
+	return ""
+}
+
+// StripFrontmatter returns content without its leading YAML front matter block
+func StripFrontmatter(content string) string {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return content
+	}
+	end := strings.Index(normalized[4:], "\n---")
+	if end < 0 {
+		return content
+	}
+	rest := normalized[4+end+4:]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[i+1:]
+	} else {
+		rest = ""
+	}
+	return strings.TrimLeft(rest, "\n")
+}
@@ -0,0 +1,65 @@
+package files
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInlineTags(t *testing.T) {
+	text := "#start and #project/alpha, (#paren) but not a#b, &#39; or ## heading\n# Heading\n#über-idee #2024"
+	want := []string{"start", "project/alpha", "paren", "über-idee", "2024"}
+	if got := InlineTags(text); !reflect.DeepEqual(got, want) {
+		t.Fatalf("InlineTags = %q, want %q", got, want)
+	}
+	if got := InlineTags("no tags"); got != nil {
+		t.Fatalf("InlineTags without tags = %q", got)
+	}
+}
+
+func TestNoteTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "just text", nil},
+		{"inline only", "text #a and #b #a", []string{"a", "b"}},
+		{"inline list", "---\ntags: [one, \"#two\", 'three']\n---\nbody #four #one", []string{"one", "two", "three", "four"}},
+		{"block list", "---\ntitle: x\ntags:\n  - one\n  - two\n---\nbody", []string{"one", "two"}},
+		{"front matter is not body", "---\nnote: \"#not-a-tag\"\n---\n#real", []string{"real"}},
+		{"windows line endings", "---\r\ntags: [one]\r\n---\r\n#two", []string{"one", "two"}},
+	}
+	for _, tt := range tests {
+		if got := NoteTags(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NoteTags = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddFrontmatterTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		tag     string
+		want    string
+		added   bool
+	}{
+		{"no front matter", "# Note\nbody", "#idea", "---\ntags: [idea]\n---\n\n# Note\nbody", true},
+		{"no tags field", "---\ntitle: Note\n---\nbody", "idea", "---\ntitle: Note\ntags: [idea]\n---\nbody", true},
+		{"inline list", "---\ntags: [a, \"b\"]\n---\nbody", "idea", "---\ntags: [a, \"b\", idea]\n---\nbody", true},
+		{"bare inline value", "---\ntags: a\n---\n", "idea", "---\ntags: [a, idea]\n---\n", true},
+		{"block list", "---\ntags:\n    - a\n    - b\ntitle: Note\n---\nbody", "idea", "---\ntags:\n    - a\n    - b\n    - idea\ntitle: Note\n---\nbody", true},
+		{"empty block list", "---\ntags:\n---\nbody", "idea", "---\ntags:\n  - idea\n---\nbody", true},
+		{"duplicate inline", "---\ntags: [a, '#idea']\n---\n", "idea", "---\ntags: [a, '#idea']\n---\n", false},
+		{"duplicate block", "---\ntags:\n  - idea\n---\n", "#idea", "---\ntags:\n  - idea\n---\n", false},
+		{"windows line endings", "---\r\ntitle: x\r\n---\r\nbody", "idea", "---\r\ntitle: x\r\ntags: [idea]\r\n---\r\nbody", true},
+		{"unterminated front matter", "---\ntitle: x\nbody", "idea", "---\ntitle: x\nbody", false},
+		{"empty tag", "body", " # ", "body", false},
+	}
+	for _, tt := range tests {
+		got, added := AddFrontmatterTag(tt.content, tt.tag)
+		if got != tt.want || added != tt.added {
+			t.Errorf("%s: AddFrontmatterTag = %q, %v, want %q, %v", tt.name, got, added, tt.want, tt.added)
+		}
+	}
+}
@@ -13,13 +13,18 @@ const maxReadFileSize = 10 * 1024 * 1024
 
 // Manager handles file system operations for notes
 type Manager struct {
-	basePath string
-	mu       sync.RWMutex
+	basePath  string
+	writeOpts WriteOptions
+	mu        sync.RWMutex
+
+	// saveMu makes checking a note against the editor's version and writing
+	// it one step
+	saveMu sync.Mutex
 }
 
 // NewManager creates a new file system manager
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{writeOpts: WriteOptions{Fsync: true}}
 }
 
 // validatePath ensures the resolved path stays within basePath, preventing path traversal attacks.
@@ -37,6 +42,17 @@ func (m *Manager) validatePath(basePath, relativePath string) (string, error) {
 	return absPath, nil
 }
 
+// AbsPath resolves a vault-relative path to an absolute path inside the vault
+func (m *Manager) AbsPath(relativePath string) (string, error) {
+	m.mu.RLock()
+	basePath := m.basePath
+	m.mu.RUnlock()
+	if basePath == "" {
+		return "", &FileSystemError{Op: "resolve", Err: fmt.Errorf("no base path set")}
+	}
+	return m.validatePath(basePath, relativePath)
+}
+
 // SetBasePath sets the base directory for notes
 func (m *Manager) SetBasePath(path string) error {
 	m.mu.Lock()
@@ -161,7 +177,8 @@ func (m *Manager) buildTree(rootPath, relativePath string) (*FileNode, error) {
 	return node, nil
 }
 
-// ReadFile reads the content of a markdown file
+// ReadFile reads the content of a markdown file. Its Hash identifies the
+// version read, for SaveFileIfUnchanged.
 func (m *Manager) ReadFile(relativePath string) (*NoteContent, error) {
 	m.mu.RLock()
 	basePath := m.basePath
@@ -194,13 +211,28 @@ func (m *Manager) ReadFile(relativePath string) (*NoteContent, error) {
 	}
 
 	return &NoteContent{
-		Path:    filepath.ToSlash(relativePath),
-		Content: string(content),
+		Path:         filepath.ToSlash(relativePath),
+		Content:      string(content),
+		ModifiedTime: JSONTime{info.ModTime()},
+		Hash:         HashContent(content),
 	}, nil
 }
 
-// SaveFile saves content to a markdown file
+// SaveFile saves content to a markdown file, whatever is on disk
 func (m *Manager) SaveFile(relativePath, content string) error {
+	return m.save(relativePath, content, "")
+}
+
+// SaveFileIfUnchanged saves content to a markdown file if the file on disk
+// is still the version with baseHash, the Hash of the NoteContent the editor
+// loaded. Otherwise the file is left alone and a *ConflictError is returned,
+// whether the file was changed by another program or by the app itself. An
+// empty baseHash saves unconditionally.
+func (m *Manager) SaveFileIfUnchanged(relativePath, content, baseHash string) error {
+	return m.save(relativePath, content, baseHash)
+}
+
+func (m *Manager) save(relativePath, content, baseHash string) error {
 	m.mu.RLock()
 	basePath := m.basePath
 	m.mu.RUnlock()
@@ -223,8 +255,16 @@ func (m *Manager) SaveFile(relativePath, content string) error {
 		return &FileSystemError{Op: "mkdir", Path: dir, Err: err}
 	}
 
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	if baseHash != "" {
+		if err := checkUnchanged(fullPath, relativePath, baseHash); err != nil {
+			return err
+		}
+	}
+
 	// Write file
-	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+	if err := m.writeNote(fullPath, []byte(content)); err != nil {
 		return &FileSystemError{Op: "write", Path: fullPath, Err: err}
 	}
 
@@ -265,7 +305,7 @@ func (m *Manager) CreateFile(relativePath, content string) error {
 	}
 
 	// Write file
-	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+	if err := m.writeNote(fullPath, []byte(content)); err != nil {
 		return &FileSystemError{Op: "write", Path: fullPath, Err: err}
 	}
 
@@ -293,6 +333,7 @@ func (m *Manager) DeleteFile(relativePath string) error {
 	if err := os.RemoveAll(fullPath); err != nil {
 		return &FileSystemError{Op: "delete", Path: fullPath, Err: err}
 	}
+	os.Remove(backupPath(fullPath))
 
 	return nil
 }
@@ -328,6 +369,7 @@ func (m *Manager) RenameFile(oldPath, newPath string) error {
 	if err := os.Rename(oldFullPath, newFullPath); err != nil {
 		return &FileSystemError{Op: "rename", Path: oldFullPath, Err: err}
 	}
+	os.Rename(backupPath(oldFullPath), backupPath(newFullPath)) // the backup follows the note, if there is one
 
 	return nil
 }
@@ -349,3 +391,57 @@ func (m *Manager) FileExists(relativePath string) bool {
 	_, err = os.Stat(fullPath)
 	return err == nil
 }
+
+// ListMarkdownFiles returns the relative paths of all markdown files at or below
+// relativePath. A file path yields itself; hidden entries and the data directory are skipped.
+func (m *Manager) ListMarkdownFiles(relativePath string) ([]string, error) {
+	m.mu.RLock()
+	basePath := m.basePath
+	m.mu.RUnlock()
+
+	if basePath == "" {
+		return nil, &FileSystemError{
+			Op:  "list",
+			Err: fmt.Errorf("no base path set"),
+		}
+	}
+
+	fullPath, err := m.validatePath(basePath, relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.WalkDir(fullPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != fullPath && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if name == "data" && filepath.Dir(path) == basePath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(strings.ToLower(name), ".md") {
+			return nil
+		}
+		rel, relErr := filepath.Rel(basePath, path)
+		if relErr != nil {
+			return relErr
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, &FileSystemError{Op: "walk", Path: fullPath, Err: err}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,113 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// NamingRules controls where new notes are placed and how they are named
+type NamingRules struct {
+	// InboxFolder is the folder new notes land in when no folder is given ("" = vault root)
+	InboxFolder string
+	// DateFolderFormat adds date-based subfolders below the inbox, e.g. "YYYY/MM" ("" = none)
+	DateFolderFormat string
+	// SlugifyTitles turns titles into lowercase, dash-separated file names
+	SlugifyTitles bool
+	// DedupeNames appends a numeric suffix instead of failing when the name is
+	// taken. Only for generated names (titles, feed entries): a path the user
+	// typed must not silently become another one.
+	DedupeNames bool
+}
+
+// maxDedupeAttempts bounds the suffix search for duplicate names
+const maxDedupeAttempts = 1000
+
+// ResolveNewNotePath applies rules to a requested note path and returns the relative
+// path the note should be created at. Folder rules only apply when name has no
+// directory component; explicit paths keep their folder but still get slugified/deduplicated.
+func (m *Manager) ResolveNewNotePath(name string, rules NamingRules, now time.Time) (string, error) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" {
+		name = "Untitled"
+	}
+
+	dir, base := path.Split(name)
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		dir = strings.Trim(strings.TrimSpace(rules.InboxFolder), "/")
+		if rules.DateFolderFormat != "" {
+			dir = path.Join(dir, formatDateFolder(rules.DateFolderFormat, now))
+		}
+	}
+
+	stem := strings.TrimSuffix(base, path.Ext(base))
+	if !strings.EqualFold(path.Ext(base), ".md") {
+		stem = base
+	}
+	if rules.SlugifyTitles {
+		stem = Slugify(stem)
+	} else {
+		stem = sanitizeNoteName(stem)
+	}
+	if stem == "" {
+		stem = "untitled"
+	}
+
+	candidate := path.Join(dir, stem+".md")
+	if !m.FileExists(candidate) {
+		return candidate, nil
+	}
+	if !rules.DedupeNames {
+		return "", &FileSystemError{Op: "create", Path: candidate, Err: os.ErrExist}
+	}
+	sep := " "
+	if rules.SlugifyTitles {
+		sep = "-"
+	}
+	for i := 2; i < maxDedupeAttempts; i++ {
+		candidate = path.Join(dir, fmt.Sprintf("%s%s%d.md", stem, sep, i))
+		if !m.FileExists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", &FileSystemError{Op: "create", Path: candidate, Err: fmt.Errorf("no free name after %d attempts", maxDedupeAttempts)}
+}
+
+// Slugify lowercases s and replaces runs of non-alphanumeric characters with a dash.
+// Letters outside ASCII (e.g. CJK) are kept.
+func Slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimRight(sb.String(), "-")
+}
+
+// sanitizeNoteName strips characters that are invalid in file names on common platforms
+func sanitizeNoteName(s string) string {
+	replacer := strings.NewReplacer("/", "-", "\\", "-", ":", "-", "*", "", "?", "", "\"", "", "<", "", ">", "", "|", "")
+	return strings.TrimSpace(replacer.Replace(s))
+}
+
+// formatDateFolder expands YYYY, MM and DD tokens in format
+func formatDateFolder(format string, now time.Time) string {
+	replacer := strings.NewReplacer(
+		"YYYY", now.Format("2006"),
+		"MM", now.Format("01"),
+		"DD", now.Format("02"),
+	)
+	return strings.Trim(replacer.Replace(format), "/")
+}
@@ -0,0 +1,75 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResolveNewNotePath(t *testing.T) {
+	m, _ := newTestManager(t)
+	now := time.Date(2025, 3, 4, 9, 0, 0, 0, time.UTC)
+	for _, p := range []string{"Inbox/2025/03/Idea.md", "Projects/Plan.md", "Inbox/2025/03/big-idea.md"} {
+		if err := m.CreateFile(p, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inbox := NamingRules{InboxFolder: "/Inbox/", DateFolderFormat: "YYYY/MM"}
+
+	tests := []struct {
+		name    string
+		rules   NamingRules
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: "Untitled.md"},
+		{name: "  Meeting: notes?  ", want: "Meeting- notes.md"},
+		{name: "Thought", rules: inbox, want: "Inbox/2025/03/Thought.md"},
+		{name: `Projects\Roadmap.md`, rules: inbox, want: "Projects/Roadmap.md"},
+		{name: "Idea", rules: inbox, wantErr: true},
+		{name: "Idea", rules: NamingRules{InboxFolder: "Inbox", DateFolderFormat: "YYYY/MM", DedupeNames: true}, want: "Inbox/2025/03/Idea 2.md"},
+		{name: "Big Idea", rules: NamingRules{InboxFolder: "Inbox", DateFolderFormat: "YYYY/MM", SlugifyTitles: true, DedupeNames: true}, want: "Inbox/2025/03/big-idea-2.md"},
+		{name: "Weekly Review: Q1!", rules: NamingRules{SlugifyTitles: true}, want: "weekly-review-q1.md"},
+		{name: "Projects/Plan.md", wantErr: true},
+		{name: "Projects/Plan.md", rules: NamingRules{DedupeNames: true}, want: "Projects/Plan 2.md"},
+		{name: "v1.2 notes", want: "v1.2 notes.md"},
+	}
+	for _, tt := range tests {
+		got, err := m.ResolveNewNotePath(tt.name, tt.rules, now)
+		if tt.wantErr {
+			var fsErr *FileSystemError
+			if !errors.As(err, &fsErr) || !errors.Is(fsErr.Err, os.ErrExist) {
+				t.Errorf("ResolveNewNotePath(%q, %+v) = %q, %v, want an exists error", tt.name, tt.rules, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveNewNotePath(%q, %+v) = %q, %v, want %q", tt.name, tt.rules, got, err, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":      "hello-world",
+		"  --Already-Slug--": "already-slug",
+		"会议 记录 2025":         "会议-记录-2025",
+		"!!!":                "",
+	}
+	for in, want := range tests {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatDateFolder(t *testing.T) {
+	now := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	if got := formatDateFolder("/YYYY/MM/DD/", now); got != "2025/03/04" {
+		t.Errorf("formatDateFolder = %q", got)
+	}
+	if got := formatDateFolder("Journal YYYY-MM", now); got != "Journal 2025-03" {
+		t.Errorf("formatDateFolder = %q", got)
+	}
+}
@@ -0,0 +1,32 @@
+package files
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// markdownLinkPattern matches [text](url) links; the leading group keeps
+// ![alt](src) images out
+var markdownLinkPattern = regexp.MustCompile(`(^|[^!])\[[^\[\]\n]*\]\([^()\s]+(?:\s+"[^"\n]*")?\)`)
+
+// CountNoteStats counts the words, characters, headings and links of a
+// note; links are wiki links, embeds and [text](url) links. Front matter is
+// not part of the note's text, and headings and links inside fenced code
+// blocks do not count.
+func CountNoteStats(content string) (words, characters, headings, links int) {
+	body := StripFrontmatter(content)
+	words = len(strings.Fields(body))
+	characters = utf8.RuneCountInString(body)
+	links = len(FindWikiLinks(body))
+	for _, line := range noteLines(body) {
+		if line.fenced {
+			continue
+		}
+		if level, _ := headingLine(line.text); level > 0 {
+			headings++
+		}
+		links += len(markdownLinkPattern.FindAllStringIndex(line.text, -1))
+	}
+	return words, characters, headings, links
+}
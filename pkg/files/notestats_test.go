@@ -0,0 +1,17 @@
+package files
+
+import "testing"
+
+func TestCountNoteStats(t *testing.T) {
+	content := "---\ntags: [a]\n---\n# Title\n\nSee [[Other]], ![[Image.png]] and [docs](https://example.com).\n" +
+		"![diagram](d.png)\n\n```\n# not a heading [[Nor]] a link\n```\n\n## Élan\n"
+	words, characters, headings, links := CountNoteStats(content)
+	if words != 19 || characters != 139 || headings != 2 || links != 3 {
+		t.Fatalf("CountNoteStats = %d words, %d characters, %d headings, %d links, want 19, 139, 2, 3",
+			words, characters, headings, links)
+	}
+
+	if words, characters, headings, links := CountNoteStats("---\ntitle: x\n---\n"); words+characters+headings+links != 0 {
+		t.Fatalf("front matter counted: %d %d %d %d", words, characters, headings, links)
+	}
+}
@@ -41,8 +41,10 @@ func (t *JSONTime) UnmarshalJSON(data []byte) error {
 
 // NoteContent represents the content of a markdown file
 type NoteContent struct {
-	Path    string `json:"path"`
-	Content string `json:"content"`
+	Path         string   `json:"path"`
+	Content      string   `json:"content"`
+	ModifiedTime JSONTime `json:"modifiedTime"`
+	Hash         string   `json:"hash"` // SHA-256 of Content
 }
 
 // FileSystemError represents file system related errors
@@ -62,3 +64,20 @@ func (e *FileSystemError) Error() string {
 func (e *FileSystemError) Unwrap() error {
 	return e.Err
 }
+
+// ConflictError is returned when saving a file that changed on disk since
+// the editor loaded it, e.g. by a sync, another editor or the app itself.
+// Hash and ModifiedTime describe the version now on disk.
+type ConflictError struct {
+	Path         string   `json:"path"`
+	ModifiedTime JSONTime `json:"modifiedTime"`
+	Hash         string   `json:"hash"`
+	Deleted      bool     `json:"deleted"`
+}
+
+func (e *ConflictError) Error() string {
+	if e.Deleted {
+		return "conflict: " + e.Path + " was deleted on disk since it was opened"
+	}
+	return "conflict: " + e.Path + " was changed on disk since it was opened"
+}
@@ -0,0 +1,39 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// HashContent returns the SHA-256 of a note's content, as in NoteContent.Hash
+func HashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkUnchanged returns a ConflictError unless fullPath still holds the
+// version with baseHash. Callers hold saveMu.
+func checkUnchanged(fullPath, relativePath, baseHash string) error {
+	data, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return &ConflictError{Path: filepath.ToSlash(relativePath), Deleted: true}
+	}
+	if err != nil {
+		return &FileSystemError{Op: "read", Path: fullPath, Err: err}
+	}
+	hash := HashContent(data)
+	if hash == baseHash {
+		return nil
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return &FileSystemError{Op: "stat", Path: fullPath, Err: err}
+	}
+	return &ConflictError{
+		Path:         filepath.ToSlash(relativePath),
+		ModifiedTime: JSONTime{info.ModTime()},
+		Hash:         hash,
+	}
+}
@@ -0,0 +1,112 @@
+package files
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := NewManager()
+	if err := m.SetBasePath(dir); err != nil {
+		t.Fatal(err)
+	}
+	return m, dir
+}
+
+func TestSaveFileIfUnchanged(t *testing.T) {
+	m, dir := newTestManager(t)
+	if err := m.SaveFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := m.ReadFile("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The editor's own saves chain through the returned hash
+	if err := m.SaveFileIfUnchanged("a.md", "two", note.Hash); err != nil {
+		t.Fatalf("save of the loaded version: %v", err)
+	}
+	if err := m.SaveFileIfUnchanged("a.md", "three", HashContent([]byte("two"))); err != nil {
+		t.Fatalf("save after own save: %v", err)
+	}
+
+	// Rewriting the same content elsewhere is not a conflict
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("three"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveFileIfUnchanged("a.md", "four", HashContent([]byte("three"))); err != nil {
+		t.Fatalf("save after touch: %v", err)
+	}
+}
+
+func TestSaveFileIfUnchanged_ExternalWriteConflicts(t *testing.T) {
+	m, dir := newTestManager(t)
+	if err := m.SaveFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := m.ReadFile("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte("synced"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.SaveFileIfUnchanged("a.md", "stale edit", note.Hash)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Hash != HashContent([]byte("synced")) {
+		t.Fatalf("stale save after external write = %v, want a conflict with the disk version", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.md")); string(data) != "synced" {
+		t.Fatalf("conflicting save wrote %q", data)
+	}
+}
+
+func TestSaveFileIfUnchanged_InternalWriteConflicts(t *testing.T) {
+	m, _ := newTestManager(t)
+	if err := m.SaveFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := m.ReadFile("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The app writes the note, e.g. inserting a link, while the editor holds it
+	if err := m.SaveFile("a.md", "one [[link]]"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.SaveFileIfUnchanged("a.md", "stale edit", note.Hash)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("stale save after internal write = %v, want a conflict", err)
+	}
+	got, err := m.ReadFile("a.md")
+	if err != nil || got.Content != "one [[link]]" {
+		t.Fatalf("note = %+v, %v, want the internal write kept", got, err)
+	}
+}
+
+func TestSaveFileIfUnchanged_DeletedConflicts(t *testing.T) {
+	m, dir := newTestManager(t)
+	if err := m.SaveFile("a.md", "one"); err != nil {
+		t.Fatal(err)
+	}
+	note, err := m.ReadFile("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	err = m.SaveFileIfUnchanged("a.md", "edit", note.Hash)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !conflict.Deleted {
+		t.Fatalf("save of a deleted note = %v, want a deleted conflict", err)
+	}
+}
@@ -0,0 +1,231 @@
+package files
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// wikiLinkPattern matches [[...]] links and ![[...]] embeds
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+)\]\]`)
+
+// WikiLink is a [[Note#Heading|alias]] link, [[Note^block-id]] block link or
+// ![[...]] embed found in a note
+type WikiLink struct {
+	Text    string `json:"text"`    // The link as written
+	Target  string `json:"target"`  // Note name or path, "" for the same note
+	Heading string `json:"heading"` // Heading anchor, if any
+	Block   string `json:"block"`   // Block id without the ^, if any
+	Alias   string `json:"alias"`   // Display text after |, if any
+	Embed   bool   `json:"embed"`   // Written as ![[...]]
+	Offset  int    `json:"offset"`  // Byte offset in the note
+}
+
+// IsAttachment reports whether the link targets a file other than a note,
+// such as an image or a PDF, going by its extension
+func (l WikiLink) IsAttachment() bool {
+	ext := path.Ext(l.Target)
+	return ext != "" && !strings.EqualFold(ext, ".md") && !strings.ContainsAny(ext, " \t")
+}
+
+// ParseWikiLink parses the body of a wiki link, the text between [[ and ]].
+// Block anchors may be written Note#^id or Note^id.
+func ParseWikiLink(body string) WikiLink {
+	link := WikiLink{Text: "[[" + body + "]]"}
+	if target, alias, ok := strings.Cut(body, "|"); ok {
+		body, link.Alias = target, strings.TrimSpace(alias)
+	}
+	if target, anchor, ok := strings.Cut(body, "#"); ok {
+		body = target
+		if block, ok := strings.CutPrefix(strings.TrimSpace(anchor), "^"); ok {
+			link.Block = strings.TrimSpace(block)
+		} else {
+			link.Heading = strings.TrimSpace(anchor)
+		}
+	} else if target, block, ok := strings.Cut(body, "^"); ok {
+		body, link.Block = target, strings.TrimSpace(block)
+	}
+	link.Target = strings.TrimSpace(body)
+	return link
+}
+
+// FindWikiLinks returns the wiki links and embeds of content in order.
+// Links inside fenced code blocks are not links.
+func FindWikiLinks(content string) []WikiLink {
+	var links []WikiLink
+	fences := fencedRanges(content)
+	for _, m := range wikiLinkPattern.FindAllStringSubmatchIndex(content, -1) {
+		if inRanges(fences, m[0]) {
+			continue
+		}
+		link := ParseWikiLink(content[m[4]:m[5]])
+		link.Embed = m[3] > m[2]
+		link.Text = content[m[0]:m[1]]
+		link.Offset = m[0]
+		links = append(links, link)
+	}
+	return links
+}
+
+// HeadingRange returns the byte range of the section under heading: from its
+// heading line to the next heading of the same or a higher level. Headings
+// are matched case-insensitively, ignoring extra spaces.
+func HeadingRange(content, heading string) (start, end int, ok bool) {
+	want := normalizeHeading(heading)
+	if want == "" {
+		return 0, 0, false
+	}
+	level := 0
+	for _, line := range noteLines(content) {
+		lineLevel, text := headingLine(line.text)
+		if lineLevel == 0 || line.fenced {
+			continue
+		}
+		if level > 0 {
+			if lineLevel <= level {
+				return start, line.start, true
+			}
+			continue
+		}
+		if normalizeHeading(text) == want {
+			start, level = line.start, lineLevel
+		}
+	}
+	if level > 0 {
+		return start, len(content), true
+	}
+	return 0, 0, false
+}
+
+// BlockRange returns the byte range of the block marked with ^id: the
+// paragraph or list item ending in " ^id", or the block just above a line
+// holding only "^id". The range excludes the marker.
+func BlockRange(content, id string) (start, end int, ok bool) {
+	marker := "^" + strings.TrimPrefix(strings.TrimSpace(id), "^")
+	if marker == "^" {
+		return 0, 0, false
+	}
+	lines := noteLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line.text, " \t\r")
+		if line.fenced || !strings.HasSuffix(trimmed, marker) {
+			continue
+		}
+		before := strings.TrimSuffix(trimmed, marker)
+		switch {
+		case strings.TrimSpace(before) == "":
+			// The marker line names the block above it
+			j := i - 1
+			for j >= 0 && strings.TrimSpace(lines[j].text) == "" {
+				j--
+			}
+			if j < 0 {
+				return 0, 0, false
+			}
+			first := j
+			for first > 0 && continuesBlock(lines[first-1]) {
+				first--
+			}
+			return lines[first].start, lines[j].start + len(strings.TrimRight(lines[j].text, "\r")), true
+		case strings.HasSuffix(before, " ") || strings.HasSuffix(before, "\t"):
+			first := i
+			if !isListItem(line.text) {
+				for first > 0 && continuesBlock(lines[first-1]) && !isListItem(lines[first].text) {
+					first--
+				}
+			}
+			return lines[first].start, line.start + len(strings.TrimRight(before, " \t")), true
+		}
+	}
+	return 0, 0, false
+}
+
+type noteLine struct {
+	text   string
+	start  int
+	fenced bool // Inside or delimiting a fenced code block
+}
+
+// noteLines splits content into lines, keeping their offsets
+func noteLines(content string) []noteLine {
+	var lines []noteLine
+	inFence := false
+	for start := 0; start <= len(content); {
+		end := strings.IndexByte(content[start:], '\n')
+		if end < 0 {
+			end = len(content) - start
+		}
+		text := content[start : start+end]
+		fence := isFence(text)
+		lines = append(lines, noteLine{text: text, start: start, fenced: inFence || fence})
+		if fence {
+			inFence = !inFence
+		}
+		start += end + 1
+	}
+	return lines
+}
+
+// fencedRanges returns the byte ranges of the fenced code blocks of content
+func fencedRanges(content string) [][2]int {
+	var ranges [][2]int
+	for _, line := range noteLines(content) {
+		if !line.fenced {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1][1] >= line.start-1 {
+			ranges[n-1][1] = line.start + len(line.text)
+			continue
+		}
+		ranges = append(ranges, [2]int{line.start, line.start + len(line.text)})
+	}
+	return ranges
+}
+
+func inRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) < 4 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"))
+}
+
+// headingLine returns the level and text of an ATX heading line, or 0
+func headingLine(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(trimmed) && trimmed[level] != ' ' && trimmed[level] != '\t') {
+		return 0, ""
+	}
+	return level, strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#")
+}
+
+func normalizeHeading(heading string) string {
+	return strings.ToLower(strings.Join(strings.Fields(heading), " "))
+}
+
+// continuesBlock reports whether line belongs to the block of the line after
+// it, rather than being a blank line, heading, fence or thematic break
+func continuesBlock(line noteLine) bool {
+	trimmed := strings.TrimSpace(line.text)
+	if trimmed == "" || trimmed == "---" || isFence(line.text) {
+		return false
+	}
+	level, _ := headingLine(line.text)
+	return level == 0
+}
+
+var listItemPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s`)
+
+func isListItem(line string) bool {
+	return listItemPattern.MatchString(line)
+}
@@ -0,0 +1,95 @@
+package files
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWikiLink(t *testing.T) {
+	tests := []struct {
+		body string
+		want WikiLink
+	}{
+		{"Note", WikiLink{Target: "Note"}},
+		{" Folder/Note | shown ", WikiLink{Target: "Folder/Note", Alias: "shown"}},
+		{"Note#Some Heading", WikiLink{Target: "Note", Heading: "Some Heading"}},
+		{"Note#^abc123", WikiLink{Target: "Note", Block: "abc123"}},
+		{"Note^abc123|see", WikiLink{Target: "Note", Block: "abc123", Alias: "see"}},
+		{"#Heading", WikiLink{Heading: "Heading"}},
+		{"image.png|200", WikiLink{Target: "image.png", Alias: "200"}},
+	}
+	for _, tt := range tests {
+		tt.want.Text = "[[" + tt.body + "]]"
+		if got := ParseWikiLink(tt.body); got != tt.want {
+			t.Errorf("ParseWikiLink(%q) = %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestFindWikiLinks(t *testing.T) {
+	content := "See [[A]] and ![[b.png|100]].\n```\n[[In Code]]\n```\n[[C#H|c]] [[]] [[D\nE]]"
+	links := FindWikiLinks(content)
+	var got []WikiLink
+	for _, link := range links {
+		if content[link.Offset:link.Offset+len(link.Text)] != link.Text {
+			t.Fatalf("offset %d does not point at %q", link.Offset, link.Text)
+		}
+		got = append(got, WikiLink{Target: link.Target, Heading: link.Heading, Alias: link.Alias, Embed: link.Embed})
+	}
+	want := []WikiLink{
+		{Target: "A"},
+		{Target: "b.png", Alias: "100", Embed: true},
+		{Target: "C", Heading: "H", Alias: "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindWikiLinks = %+v, want %+v", got, want)
+	}
+	if !links[1].IsAttachment() || links[0].IsAttachment() {
+		t.Fatal("only b.png is an attachment")
+	}
+}
+
+func TestHeadingRange(t *testing.T) {
+	content := "# Title\nintro\n## Setup\nstep\n### Detail\nmore\n```\n## Not a heading\n```\n## Usage\nuse\n"
+	tests := []struct {
+		heading string
+		want    string
+		ok      bool
+	}{
+		{"Setup", "## Setup\nstep\n### Detail\nmore\n```\n## Not a heading\n```\n", true},
+		{"  detail ", "### Detail\nmore\n```\n## Not a heading\n```\n", true},
+		{"Usage", "## Usage\nuse\n", true},
+		{"Title", content, true},
+		{"Not a heading", "", false},
+		{"Missing", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		start, end, ok := HeadingRange(content, tt.heading)
+		if ok != tt.ok || (ok && content[start:end] != tt.want) {
+			t.Errorf("HeadingRange(%q) = %q, %v, want %q, %v", tt.heading, content[start:end], ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBlockRange(t *testing.T) {
+	content := "Intro line\nstill intro ^intro\n\n- one\n- two ^item\n\n> quoted\n> text\n\n^quote\n```\ncode ^code\n```\n"
+	tests := []struct {
+		id   string
+		want string
+		ok   bool
+	}{
+		{"intro", "Intro line\nstill intro", true},
+		{"^item", "- two", true},
+		{"quote", "> quoted\n> text", true},
+		{"code", "", false},
+		{"missing", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		start, end, ok := BlockRange(content, tt.id)
+		if ok != tt.ok || (ok && content[start:end] != tt.want) {
+			t.Errorf("BlockRange(%q) = %q, %v, want %q, %v", tt.id, content[start:end], ok, tt.want, tt.ok)
+		}
+	}
+}
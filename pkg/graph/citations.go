@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"sort"
+
+	"notebit/pkg/bibtex"
+	"notebit/pkg/database"
+)
+
+// LinkTypeCitation links a note to a reference it cites with [@key]
+const LinkTypeCitation = "citation"
+
+// Reference is a bibliography entry notes can cite with [@key]
+type Reference struct {
+	Key   string
+	Label string // Short author-year label shown on the node
+	Path  string // Bibliography file the reference is in
+}
+
+// ReferenceSource returns the citable references by key and a version that
+// changes whenever they do
+type ReferenceSource func() (map[string]Reference, int64)
+
+// SetReferenceSource makes the graph link notes to the references they cite;
+// nil stops it
+func (s *Service) SetReferenceSource(src ReferenceSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.references = src
+	s.cachedGraph = nil
+}
+
+// buildCitationLinks returns a node per cited reference, links from each
+// note to the references it cites, and citations of unknown keys
+func buildCitationLinks(files []database.File, refs map[string]Reference) ([]Node, []Link, []UnresolvedLink) {
+	var links []Link
+	var unresolved []UnresolvedLink
+	cited := make(map[string]bool)
+
+	for _, file := range files {
+		seen := make(map[string]bool)
+		for _, chunk := range file.Chunks {
+			for _, key := range bibtex.CitationKeys(chunk.Content) {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if _, ok := refs[key]; !ok {
+					unresolved = append(unresolved, UnresolvedLink{
+						Source: file.Path,
+						Target: "@" + key,
+						Reason: UnresolvedMissing,
+					})
+					continue
+				}
+				cited[key] = true
+				links = append(links, Link{
+					Source:   generateNodeID("file", file.Path),
+					Target:   generateNodeID("ref", key),
+					Type:     LinkTypeCitation,
+					Strength: 1.0,
+				})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(cited))
+	for key := range cited {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	nodes := make([]Node, 0, len(keys))
+	for _, key := range keys {
+		ref := refs[key]
+		label := ref.Label
+		if label == "" {
+			label = "@" + key
+		}
+		nodes = append(nodes, Node{
+			ID:    generateNodeID("ref", key),
+			Label: label,
+			Type:  "reference",
+			Path:  ref.Path,
+			Val:   1.0,
+		})
+	}
+	return nodes, links, unresolved
+}
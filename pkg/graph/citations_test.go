@@ -0,0 +1,40 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestBuildCitationLinks(t *testing.T) {
+	refs := map[string]Reference{
+		"knuth84": {Key: "knuth84", Label: "Knuth 1984", Path: "refs.bib"},
+		"doe20":   {Key: "doe20", Path: "refs.bib"},
+	}
+	files := []database.File{
+		testFile("a.md", "A", "As shown [@knuth84; @doe20].", "Again [@knuth84]."),
+		testFile("b.md", "B", "Unknown [@nobody99] and [@knuth84, p. 3]."),
+		testFile("c.md", "C", "email@example.com is not a citation"),
+	}
+	nodes, links, unresolved := buildCitationLinks(files, refs)
+
+	wantNodes := []Node{
+		{ID: "ref:doe20", Label: "@doe20", Type: "reference", Path: "refs.bib", Val: 1},
+		{ID: "ref:knuth84", Label: "Knuth 1984", Type: "reference", Path: "refs.bib", Val: 1},
+	}
+	if !reflect.DeepEqual(nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", nodes, wantNodes)
+	}
+	cite := func(source, key string) Link {
+		return Link{Source: "file:" + source, Target: "ref:" + key, Type: LinkTypeCitation, Strength: 1}
+	}
+	wantLinks := []Link{cite("a.md", "knuth84"), cite("a.md", "doe20"), cite("b.md", "knuth84")}
+	if !reflect.DeepEqual(links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", links, wantLinks)
+	}
+	wantUnresolved := []UnresolvedLink{{Source: "b.md", Target: "@nobody99", Reason: UnresolvedMissing}}
+	if !reflect.DeepEqual(unresolved, wantUnresolved) {
+		t.Errorf("unresolved = %+v, want %+v", unresolved, wantUnresolved)
+	}
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"notebit/pkg/database"
+)
+
+// Structural link types
+const (
+	LinkTypeFolder   = "folder"
+	LinkTypeTemporal = "temporal"
+)
+
+// buildFolderNodes returns a node for every folder containing a note, with
+// links from each note to its folder and from each folder to its parent.
+// Notes at the vault root are not linked.
+func buildFolderNodes(files []database.File) ([]Node, []Link) {
+	var nodes []Node
+	var links []Link
+	seen := make(map[string]bool)
+
+	var addFolder func(dir string)
+	addFolder = func(dir string) {
+		if dir == "." || dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		nodes = append(nodes, Node{
+			ID:    generateNodeID("folder", dir),
+			Label: path.Base(dir),
+			Type:  "folder",
+			Path:  dir,
+			Val:   1.0,
+		})
+		if parent := path.Dir(dir); parent != "." {
+			addFolder(parent)
+			links = append(links, Link{
+				Source:   generateNodeID("folder", dir),
+				Target:   generateNodeID("folder", parent),
+				Type:     LinkTypeFolder,
+				Strength: 1.0,
+			})
+		}
+	}
+
+	for _, file := range files {
+		dir := path.Dir(file.Path)
+		if dir == "." {
+			continue
+		}
+		addFolder(dir)
+		links = append(links, Link{
+			Source:   generateNodeID("file", file.Path),
+			Target:   generateNodeID("folder", dir),
+			Type:     LinkTypeFolder,
+			Strength: 1.0,
+		})
+	}
+	return nodes, links
+}
+
+// buildTemporalLinks chains notes created in the same ISO week in creation
+// order, so each week forms a path rather than a clique. A note's creation
+// time is approximated by the earlier of when it was first indexed and its
+// last modification, since the index of an existing vault is newer than its notes.
+func buildTemporalLinks(files []database.File) []Link {
+	type dated struct {
+		path    string
+		created time.Time
+	}
+	notes := make([]dated, 0, len(files))
+	for _, file := range files {
+		created := file.CreatedAt
+		if file.LastModified > 0 {
+			if modified := time.Unix(file.LastModified, 0); created.IsZero() || modified.Before(created) {
+				created = modified
+			}
+		}
+		if created.IsZero() {
+			continue
+		}
+		notes = append(notes, dated{path: file.Path, created: created})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].created.Equal(notes[j].created) {
+			return notes[i].created.Before(notes[j].created)
+		}
+		return notes[i].path < notes[j].path
+	})
+
+	var links []Link
+	for i := 1; i < len(notes); i++ {
+		prevYear, prevWeek := notes[i-1].created.ISOWeek()
+		year, week := notes[i].created.ISOWeek()
+		if year != prevYear || week != prevWeek {
+			continue
+		}
+		links = append(links, Link{
+			Source:   generateNodeID("file", notes[i-1].path),
+			Target:   generateNodeID("file", notes[i].path),
+			Type:     LinkTypeTemporal,
+			Strength: 1.0,
+		})
+	}
+	return links
+}
+
+// isStructural reports whether a link comes from folder or date dimensions
+// rather than note content
+func isStructural(link Link) bool {
+	return link.Type == LinkTypeFolder || link.Type == LinkTypeTemporal
+}
+
+func isFolderNode(id string) bool {
+	return strings.HasPrefix(id, "folder:")
+}
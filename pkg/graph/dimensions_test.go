@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"notebit/pkg/database"
+)
+
+func TestBuildFolderNodes(t *testing.T) {
+	files := []database.File{
+		{Path: "root.md"},
+		{Path: "a/one.md"},
+		{Path: "a/b/two.md"},
+		{Path: "a/b/three.md"},
+	}
+	nodes, links := buildFolderNodes(files)
+
+	var ids []string
+	for _, n := range nodes {
+		if n.Type != "folder" || !isFolderNode(n.ID) {
+			t.Errorf("node = %+v", n)
+		}
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"folder:a", "folder:a/b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("folder nodes = %q, want %q", ids, want)
+	}
+
+	type edge struct{ source, target string }
+	var got []edge
+	for _, l := range links {
+		if l.Type != LinkTypeFolder || !isStructural(l) {
+			t.Errorf("link = %+v", l)
+		}
+		got = append(got, edge{l.Source, l.Target})
+	}
+	want := []edge{
+		{"file:a/one.md", "folder:a"},
+		{"folder:a/b", "folder:a"},
+		{"file:a/b/two.md", "folder:a/b"},
+		{"file:a/b/three.md", "folder:a/b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("links = %v, want %v", got, want)
+	}
+}
+
+func TestBuildTemporalLinks(t *testing.T) {
+	// 2026-10-12 is a Monday, so the 18th closes the same ISO week
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		files []database.File
+		want  [][2]string
+	}{
+		{
+			name: "same week chained in creation order",
+			files: []database.File{
+				{Path: "c.md", CreatedAt: day(16)},
+				{Path: "a.md", CreatedAt: day(12)},
+				{Path: "b.md", CreatedAt: day(14)},
+			},
+			want: [][2]string{{"a.md", "b.md"}, {"b.md", "c.md"}},
+		},
+		{
+			name: "different weeks not linked",
+			files: []database.File{
+				{Path: "a.md", CreatedAt: day(18)},
+				{Path: "b.md", CreatedAt: day(19)},
+			},
+		},
+		{
+			name: "modification before indexing counts as creation",
+			files: []database.File{
+				{Path: "old.md", CreatedAt: day(20), LastModified: day(13).Unix()},
+				{Path: "new.md", CreatedAt: day(14)},
+			},
+			want: [][2]string{{"old.md", "new.md"}},
+		},
+		{
+			name: "ties ordered by path",
+			files: []database.File{
+				{Path: "b.md", CreatedAt: day(13)},
+				{Path: "a.md", CreatedAt: day(13)},
+			},
+			want: [][2]string{{"a.md", "b.md"}},
+		},
+		{
+			name:  "undated notes skipped",
+			files: []database.File{{Path: "a.md"}, {Path: "b.md", CreatedAt: day(13)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][2]string
+			for _, l := range buildTemporalLinks(tt.files) {
+				if l.Type != LinkTypeTemporal || !isStructural(l) {
+					t.Errorf("link = %+v", l)
+				}
+				got = append(got, [2]string{l.Source[len("file:"):], l.Target[len("file:"):]})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("links = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package graph
+
+import "strings"
+
+// FilterByPaths returns the subgraph containing only file nodes whose path is in
+// paths, the tag, folder and reference nodes they link to, the links between the kept
+// nodes, and the unresolved links from kept notes.
+func FilterByPaths(data *GraphData, paths []string) *GraphData {
+	if data == nil {
+		return &GraphData{Nodes: []Node{}, Links: []Link{}, Unresolved: []UnresolvedLink{}}
+	}
+	allowed := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		allowed[p] = struct{}{}
+	}
+
+	kept := make(map[string]struct{})
+	for _, n := range data.Nodes {
+		if n.Type == "tag" || n.Type == "reference" {
+			continue
+		}
+		if _, ok := allowed[n.Path]; ok {
+			kept[n.ID] = struct{}{}
+		}
+	}
+
+	links := make([]Link, 0)
+	usedHubs := make(map[string]struct{})
+	for _, l := range data.Links {
+		_, srcOK := kept[l.Source]
+		if !srcOK {
+			continue
+		}
+		if _, ok := kept[l.Target]; ok {
+			links = append(links, l)
+			continue
+		}
+		if strings.HasPrefix(l.Target, "tag:") || strings.HasPrefix(l.Target, "ref:") || isFolderNode(l.Target) {
+			usedHubs[l.Target] = struct{}{}
+			links = append(links, l)
+		}
+	}
+	// Keep folder-to-parent links between folders that are still in use
+	for _, l := range data.Links {
+		if !isFolderNode(l.Source) {
+			continue
+		}
+		_, srcOK := usedHubs[l.Source]
+		_, dstOK := usedHubs[l.Target]
+		if srcOK && dstOK {
+			links = append(links, l)
+		}
+	}
+
+	nodes := make([]Node, 0, len(kept)+len(usedHubs))
+	for _, n := range data.Nodes {
+		if _, ok := kept[n.ID]; ok {
+			nodes = append(nodes, n)
+			continue
+		}
+		if _, ok := usedHubs[n.ID]; ok {
+			nodes = append(nodes, n)
+		}
+	}
+
+	unresolved := make([]UnresolvedLink, 0)
+	for _, u := range data.Unresolved {
+		if _, ok := allowed[u.Source]; ok {
+			unresolved = append(unresolved, u)
+		}
+	}
+	return &GraphData{Nodes: nodes, Links: links, Unresolved: unresolved}
+}
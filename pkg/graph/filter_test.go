@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterByPaths(t *testing.T) {
+	link := func(source, target, typ string) Link {
+		return Link{Source: source, Target: target, Type: typ, Strength: 1}
+	}
+	data := &GraphData{
+		Nodes: []Node{
+			{ID: "file:a/one.md", Type: "file", Path: "a/one.md"},
+			{ID: "file:a/b/two.md", Type: "file", Path: "a/b/two.md"},
+			{ID: "file:c/three.md", Type: "file", Path: "c/three.md"},
+			{ID: "tag:go", Type: "tag", Path: "a/one.md"},
+			{ID: "tag:db", Type: "tag"},
+			{ID: "ref:knuth84", Type: "reference", Path: "a/one.md"},
+			{ID: "folder:a", Type: "folder", Path: "a"},
+			{ID: "folder:a/b", Type: "folder", Path: "a/b"},
+			{ID: "folder:c", Type: "folder", Path: "c"},
+		},
+		Links: []Link{
+			link("file:a/one.md", "file:a/b/two.md", "explicit"),
+			link("file:a/one.md", "file:c/three.md", "explicit"),
+			link("file:a/one.md", "tag:go", "tag"),
+			link("file:c/three.md", "tag:db", "tag"),
+			link("file:a/one.md", "ref:knuth84", LinkTypeCitation),
+			link("file:a/one.md", "folder:a", LinkTypeFolder),
+			link("file:a/b/two.md", "folder:a/b", LinkTypeFolder),
+			link("file:c/three.md", "folder:c", LinkTypeFolder),
+			link("folder:a/b", "folder:a", LinkTypeFolder),
+		},
+		Unresolved: []UnresolvedLink{
+			{Source: "a/one.md", Target: "Missing", Reason: UnresolvedMissing},
+			{Source: "c/three.md", Target: "Gone", Reason: UnresolvedMissing},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		paths      []string
+		nodes      []string
+		links      []Link
+		unresolved int
+	}{
+		{
+			name:  "notes keep their hubs and the folder chain",
+			paths: []string{"a/one.md", "a/b/two.md"},
+			nodes: []string{"file:a/one.md", "file:a/b/two.md", "tag:go", "ref:knuth84", "folder:a", "folder:a/b"},
+			links: []Link{
+				link("file:a/one.md", "file:a/b/two.md", "explicit"),
+				link("file:a/one.md", "tag:go", "tag"),
+				link("file:a/one.md", "ref:knuth84", LinkTypeCitation),
+				link("file:a/one.md", "folder:a", LinkTypeFolder),
+				link("file:a/b/two.md", "folder:a/b", LinkTypeFolder),
+				link("folder:a/b", "folder:a", LinkTypeFolder),
+			},
+			unresolved: 1,
+		},
+		{
+			name:  "links to dropped notes and unused folders removed",
+			paths: []string{"a/b/two.md"},
+			nodes: []string{"file:a/b/two.md", "folder:a/b"},
+			links: []Link{link("file:a/b/two.md", "folder:a/b", LinkTypeFolder)},
+		},
+		{
+			name:  "nothing allowed",
+			nodes: []string{},
+			links: []Link{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterByPaths(data, tt.paths)
+			ids := make([]string, 0, len(got.Nodes))
+			for _, n := range got.Nodes {
+				ids = append(ids, n.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.nodes) {
+				t.Errorf("nodes = %q, want %q", ids, tt.nodes)
+			}
+			if !reflect.DeepEqual(got.Links, tt.links) {
+				t.Errorf("links = %+v, want %+v", got.Links, tt.links)
+			}
+			if len(got.Unresolved) != tt.unresolved {
+				t.Errorf("unresolved = %+v, want %d", got.Unresolved, tt.unresolved)
+			}
+		})
+	}
+
+	t.Run("nil data", func(t *testing.T) {
+		got := FilterByPaths(nil, []string{"a/one.md"})
+		if got.Nodes == nil || got.Links == nil || got.Unresolved == nil || len(got.Nodes)+len(got.Links)+len(got.Unresolved) != 0 {
+			t.Errorf("FilterByPaths(nil) = %+v", got)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+)
+
+// Reasons a wiki link could not be resolved
+const (
+	UnresolvedMissing   = "missing"
+	UnresolvedAmbiguous = "ambiguous"
+)
+
+// UnresolvedLink is a wiki link that matches no note, or more than one
+type UnresolvedLink struct {
+	Source     string   `json:"source"`               // Path of the note containing the link
+	Target     string   `json:"target"`               // Link target as written
+	Reason     string   `json:"reason"`               // "missing" or "ambiguous"
+	Candidates []string `json:"candidates,omitempty"` // Matching paths for ambiguous links
+}
+
+// linkResolver resolves wiki link targets to note paths. Keys are compared
+// case-insensitively and tried in order of precedence: full relative path,
+// file name, title, then front matter aliases. The first kind with any match
+// decides; more than one match of that kind is ambiguous.
+type linkResolver struct {
+	byPath  map[string][]string
+	byName  map[string][]string
+	byTitle map[string][]string
+	byAlias map[string][]string
+}
+
+func newLinkResolver(indexed []database.File) *linkResolver {
+	r := &linkResolver{
+		byPath:  make(map[string][]string),
+		byName:  make(map[string][]string),
+		byTitle: make(map[string][]string),
+		byAlias: make(map[string][]string),
+	}
+	for _, f := range indexed {
+		addKey(r.byPath, f.Path, f.Path)
+		addKey(r.byPath, strings.TrimSuffix(f.Path, path.Ext(f.Path)), f.Path)
+		addKey(r.byName, strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path)), f.Path)
+		addKey(r.byTitle, f.Title, f.Path)
+		for _, alias := range fileAliases(f) {
+			addKey(r.byAlias, alias, f.Path)
+		}
+	}
+	return r
+}
+
+func addKey(index map[string][]string, key, notePath string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return
+	}
+	for _, existing := range index[key] {
+		if existing == notePath {
+			return
+		}
+	}
+	index[key] = append(index[key], notePath)
+}
+
+// fileAliases reads front matter aliases from the note's first chunk, which
+// holds the start of the note
+func fileAliases(f database.File) []string {
+	if len(f.Chunks) == 0 {
+		return nil
+	}
+	first := f.Chunks[0]
+	for _, c := range f.Chunks[1:] {
+		if c.ID < first.ID {
+			first = c
+		}
+	}
+	return files.NoteAliases(first.Content)
+}
+
+// resolve returns the path a link target refers to. When it returns "",
+// candidates lists the ambiguous matches (empty if nothing matched).
+func (r *linkResolver) resolve(target string) (string, []string) {
+	key := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(target), "./"), "/"))
+	if key == "" {
+		return "", nil
+	}
+	for _, index := range []map[string][]string{r.byPath, r.byName, r.byTitle, r.byAlias} {
+		switch matches := index[key]; len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			candidates := append([]string(nil), matches...)
+			sort.Strings(candidates)
+			return "", candidates
+		}
+	}
+	return "", nil
+}
+
+// ResolveFunc resolves a wiki link target to a note path the way the graph
+// does. When it returns "", candidates lists the ambiguous matches.
+type ResolveFunc func(target string) (notePath string, candidates []string)
+
+// Resolver returns a ResolveFunc over the notes indexed now
+func (s *Service) Resolver() (ResolveFunc, error) {
+	if !s.db.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	indexed, err := s.db.Repository().ListFilesWithChunks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	return newLinkResolver(indexed).resolve, nil
+}
+
+// linkAnchor returns the heading ("#Heading") or block ("^id") a wiki link
+// points to within its target, or ""
+func linkAnchor(link files.WikiLink) string {
+	switch {
+	case link.Block != "":
+		return "^" + link.Block
+	case link.Heading != "":
+		return "#" + link.Heading
+	}
+	return ""
+}
+
+// LinkHealth summarizes how well notes are connected by wiki links
+type LinkHealth struct {
+	Orphans        []string         `json:"orphans"`         // Notes with no wiki links in or out
+	BrokenLinks    []UnresolvedLink `json:"broken_links"`    // Links whose target matches no note
+	AmbiguousLinks []UnresolvedLink `json:"ambiguous_links"` // Links matching several notes
+}
+
+// LinkHealth checks the wiki links of every indexed note, ignoring the graph
+// node limit. Tag and semantic links do not count as connections.
+func (s *Service) LinkHealth() (*LinkHealth, error) {
+	health := &LinkHealth{Orphans: []string{}, BrokenLinks: []UnresolvedLink{}, AmbiguousLinks: []UnresolvedLink{}}
+	if !s.db.IsInitialized() {
+		return health, nil
+	}
+	indexed, err := s.db.Repository().ListFilesWithChunks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	links, unresolved := s.extractWikiLinks(indexed, newLinkResolver(indexed))
+	linked := make(map[string]bool)
+	for _, link := range links {
+		linked[link.Source] = true
+		linked[link.Target] = true
+	}
+	for _, u := range unresolved {
+		// A note whose only links are broken still links out
+		linked[generateNodeID("file", u.Source)] = true
+		if u.Reason == UnresolvedAmbiguous {
+			health.AmbiguousLinks = append(health.AmbiguousLinks, u)
+		} else {
+			health.BrokenLinks = append(health.BrokenLinks, u)
+		}
+	}
+	for _, f := range indexed {
+		if !linked[generateNodeID("file", f.Path)] {
+			health.Orphans = append(health.Orphans, f.Path)
+		}
+	}
+	sort.Strings(health.Orphans)
+	return health, nil
+}
@@ -3,14 +3,15 @@ package graph
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
 	"notebit/pkg/config"
 	"notebit/pkg/database"
+	"notebit/pkg/files"
 )
 
-var wikiLinkRegex = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
 var tagRegex = regexp.MustCompile(`#([\w\p{L}-]+)`)
 
 // Service handles knowledge graph operations
@@ -21,30 +22,34 @@ type Service struct {
 	cachedGraph    *GraphData
 	cachedRevision uint64
 	cachedConfig   config.GraphConfig
+	references     ReferenceSource
+	cachedRefs     int64
 }
 
 // Node represents a node in the knowledge graph
 type Node struct {
 	ID    string  `json:"id"`
 	Label string  `json:"label"`
-	Type  string  `json:"type"` // "file"
-	Path  string  `json:"path"` // File path (for navigation)
+	Type  string  `json:"type"` // "file", "concept", "tag", "folder" or "reference"
+	Path  string  `json:"path"` // File or folder path (for navigation)
 	Size  int     `json:"size"` // Number of connections
 	Val   float64 `json:"val"`  // Centrality/importance
 }
 
 // Link represents a link between nodes
 type Link struct {
-	Source   string  `json:"source"`
-	Target   string  `json:"target"`
-	Type     string  `json:"type"`     // "explicit" (wiki link), "implicit" (semantic)
-	Strength float32 `json:"strength"` // Similarity score for implicit links
+	Source   string   `json:"source"`
+	Target   string   `json:"target"`
+	Type     string   `json:"type"`              // "explicit" (wiki link), "implicit" (semantic), "tag", "folder", "temporal" or "citation"
+	Strength float32  `json:"strength"`          // Similarity score for implicit links
+	Anchors  []string `json:"anchors,omitempty"` // Headings (#...) and blocks (^...) explicit links point to
 }
 
 // GraphData represents the complete graph structure
 type GraphData struct {
-	Nodes []Node `json:"nodes"`
-	Links []Link `json:"links"`
+	Nodes      []Node           `json:"nodes"`
+	Links      []Link           `json:"links"`
+	Unresolved []UnresolvedLink `json:"unresolved"` // Wiki links that match no note or several, and citations of unknown keys
 }
 
 // NewService creates a new graph service
@@ -61,13 +66,18 @@ func (s *Service) BuildGraph() (*GraphData, error) {
 	defer s.mu.Unlock()
 
 	if !s.db.IsInitialized() {
-		return &GraphData{Nodes: []Node{}, Links: []Link{}}, nil
+		return &GraphData{Nodes: []Node{}, Links: []Link{}, Unresolved: []UnresolvedLink{}}, nil
 	}
 
 	repo := s.db.Repository()
 	graphConfig := s.cfg.GetGraphConfig()
 	revision := repo.GetRevision()
-	if s.cachedGraph != nil && s.cachedRevision == revision && s.cachedConfig == graphConfig {
+	var refs map[string]Reference
+	var refsVersion int64
+	if s.references != nil {
+		refs, refsVersion = s.references()
+	}
+	if s.cachedGraph != nil && s.cachedRevision == revision && s.cachedConfig == graphConfig && s.cachedRefs == refsVersion {
 		return s.cachedGraph, nil
 	}
 
@@ -77,13 +87,30 @@ func (s *Service) BuildGraph() (*GraphData, error) {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 
+	// Resolve wiki links against every note, even those cut by the node limit
+	resolver := newLinkResolver(files)
+
 	// Apply max nodes limit
 	if graphConfig.MaxNodes > 0 && len(files) > graphConfig.MaxNodes {
 		files = files[:graphConfig.MaxNodes]
 	}
 
 	nodes := s.buildNodes(files)
-	links := s.buildLinks(files, repo, graphConfig)
+	links, unresolved := s.buildLinks(files, resolver, repo, graphConfig)
+	if graphConfig.ShowFolderNodes {
+		folderNodes, folderLinks := buildFolderNodes(files)
+		nodes = append(nodes, folderNodes...)
+		links = append(links, folderLinks...)
+	}
+	if graphConfig.ShowTemporalLinks {
+		links = append(links, buildTemporalLinks(files)...)
+	}
+	if len(refs) > 0 {
+		refNodes, citationLinks, missing := buildCitationLinks(files, refs)
+		nodes = append(nodes, refNodes...)
+		links = append(links, citationLinks...)
+		unresolved = append(unresolved, missing...)
+	}
 
 	// Calculate node sizes based on connections
 	nodeSizeMap := s.calculateNodeSizes(links)
@@ -117,12 +144,14 @@ func (s *Service) BuildGraph() (*GraphData, error) {
 	}
 
 	data := &GraphData{
-		Nodes: nodes,
-		Links: links,
+		Nodes:      nodes,
+		Links:      links,
+		Unresolved: unresolved,
 	}
 	s.cachedGraph = data
 	s.cachedRevision = revision
 	s.cachedConfig = graphConfig
+	s.cachedRefs = refsVersion
 	return data, nil
 }
 
@@ -144,12 +173,12 @@ func (s *Service) buildNodes(files []database.File) []Node {
 	return nodes
 }
 
-// buildLinks creates links between nodes
-func (s *Service) buildLinks(files []database.File, repo *database.Repository, graphConfig config.GraphConfig) []Link {
+// buildLinks creates links between nodes and reports wiki links that could not be resolved
+func (s *Service) buildLinks(files []database.File, resolver *linkResolver, repo *database.Repository, graphConfig config.GraphConfig) ([]Link, []UnresolvedLink) {
 	var links []Link
 
 	// 1. Extract explicit links (wiki-style [[links]])
-	wikiLinks := s.extractWikiLinks(files)
+	wikiLinks, unresolved := s.extractWikiLinks(files, resolver)
 	links = append(links, wikiLinks...)
 
 	// 2. Extract tag links
@@ -162,7 +191,7 @@ func (s *Service) buildLinks(files []database.File, repo *database.Repository, g
 		links = append(links, implicitLinks...)
 	}
 
-	return links
+	return links, unresolved
 }
 
 // extractTagLinks parses markdown for #tags
@@ -213,74 +242,86 @@ func (s *Service) extractTagLinks(files []database.File) []Link {
 	return links
 }
 
-// extractWikiLinks parses markdown for [[wiki]] links
-func (s *Service) extractWikiLinks(files []database.File) []Link {
+// extractWikiLinks parses markdown for [[wiki]] links. Links to notes outside
+// notes (e.g. cut by the node limit) are dropped; links matching no note or
+// several notes are returned as unresolved.
+func (s *Service) extractWikiLinks(notes []database.File, resolver *linkResolver) ([]Link, []UnresolvedLink) {
 	var links []Link
+	unresolved := make([]UnresolvedLink, 0)
 
-	for _, file := range files {
-		for _, chunk := range file.Chunks {
-			matches := wikiLinkRegex.FindAllStringSubmatch(chunk.Content, -1)
+	inGraph := make(map[string]bool, len(notes))
+	for _, file := range notes {
+		inGraph[file.Path] = true
+	}
 
-			for _, match := range matches {
-				if len(match) < 2 {
+	for _, file := range notes {
+		reported := make(map[string]bool)
+		for _, chunk := range file.Chunks {
+			for _, wikiLink := range files.FindWikiLinks(chunk.Content) {
+				targetName := wikiLink.Target
+				if targetName == "" {
 					continue
 				}
 
-				targetName := match[1]
-				if idx := strings.Index(targetName, "|"); idx >= 0 {
-					targetName = targetName[:idx]
-				}
-				if idx := strings.Index(targetName, "#"); idx >= 0 {
-					targetName = targetName[:idx]
+				targetPath, candidates := resolver.resolve(targetName)
+				if targetPath == "" {
+					if !reported[targetName] {
+						reported[targetName] = true
+						reason := UnresolvedMissing
+						if len(candidates) > 0 {
+							reason = UnresolvedAmbiguous
+						}
+						unresolved = append(unresolved, UnresolvedLink{
+							Source:     file.Path,
+							Target:     targetName,
+							Reason:     reason,
+							Candidates: candidates,
+						})
+					}
+					continue
 				}
-				targetName = strings.TrimSpace(targetName)
-				if targetName == "" {
+				if targetPath == file.Path || !inGraph[targetPath] {
 					continue
 				}
-				// Try to find matching file by title or path
-				for _, targetFile := range files {
-					if s.filesMatch(targetName, &targetFile) {
-						link := Link{
-							Source:   generateNodeID("file", file.Path),
-							Target:   generateNodeID("file", targetFile.Path),
-							Type:     "explicit",
-							Strength: 1.0,
-						}
 
-						// Avoid duplicate links
-						if !linkExists(links, link) {
-							links = append(links, link)
-						}
-						break
-					}
+				link := Link{
+					Source:   generateNodeID("file", file.Path),
+					Target:   generateNodeID("file", targetPath),
+					Type:     "explicit",
+					Strength: 1.0,
+				}
+
+				// Avoid duplicate links, collecting the anchors of all of them
+				i := linkIndex(links, link)
+				if i < 0 {
+					i = len(links)
+					links = append(links, link)
+				}
+				if anchor := linkAnchor(wikiLink); anchor != "" && !slices.Contains(links[i].Anchors, anchor) {
+					links[i].Anchors = append(links[i].Anchors, anchor)
 				}
 			}
 		}
 	}
 
-	return links
+	return links, unresolved
 }
 
-// extractImplicitLinks finds semantically similar files
+// extractImplicitLinks finds semantically similar files. Each note is queried
+// by the centroid of all its chunk embeddings, as stored with the file or
+// computed for notes indexed before centroids were kept, and a neighbor's strength is
+// the mean of its top implicitTopK chunk similarities, so long notes are
+// represented by all their chunks rather than whichever comes first.
 func (s *Service) extractImplicitLinks(files []database.File, repo *database.Repository, threshold float32) []Link {
 	var links []Link
 
 	queryVectors := make([][]float32, 0, len(files))
 	queryPaths := make([]string, 0, len(files))
 	for _, file := range files {
-		if len(file.Chunks) == 0 {
-			continue
-		}
-
-		var embedding []float32
-		for _, chunk := range file.Chunks {
-			emb := chunk.GetEmbedding()
-			if len(emb) > 0 {
-				embedding = emb
-				break
-			}
+		embedding := file.GetCentroid()
+		if len(embedding) == 0 {
+			embedding = centroid(file.Chunks)
 		}
-
 		if len(embedding) == 0 {
 			continue
 		}
@@ -293,30 +334,26 @@ func (s *Service) extractImplicitLinks(files []database.File, repo *database.Rep
 		return links
 	}
 
-	batchResults, err := repo.SearchSimilarBatch(queryVectors, 10)
+	batchResults, err := repo.SearchSimilarBatch(queryVectors, implicitQueryChunks)
 	if err != nil {
 		return links
 	}
 
 	for i, similar := range batchResults {
 		sourcePath := queryPaths[i]
-		for _, sim := range similar {
-			if sim.File == nil {
-				continue
-			}
-
-			if sim.File.Path == sourcePath || sim.Similarity < threshold {
+		for _, neighbor := range aggregateNeighbors(similar, sourcePath, implicitNeighbors) {
+			if neighbor.strength < threshold {
 				continue
 			}
 
 			link := Link{
 				Source:   generateNodeID("file", sourcePath),
-				Target:   generateNodeID("file", sim.File.Path),
+				Target:   generateNodeID("file", neighbor.path),
 				Type:     "implicit",
-				Strength: sim.Similarity,
+				Strength: neighbor.strength,
 			}
 
-			if !linkExists(links, link) {
+			if linkIndex(links, link) < 0 {
 				links = append(links, link)
 			}
 		}
@@ -325,34 +362,14 @@ func (s *Service) extractImplicitLinks(files []database.File, repo *database.Rep
 	return links
 }
 
-// filesMatch checks if a target name matches a file (by title or path)
-func (s *Service) filesMatch(targetName string, file *database.File) bool {
-	// Exact title match
-	if file.Title == targetName {
-		return true
-	}
-
-	// Path contains match (case insensitive)
-	if strings.Contains(strings.ToLower(file.Path), strings.ToLower(targetName)) {
-		return true
-	}
-
-	// Filename match
-	if strings.Contains(strings.ToLower(file.Path), strings.ToLower(targetName)) {
-		return true
-	}
-
-	return false
-}
-
-// linkExists checks if a link already exists in the list
-func linkExists(links []Link, link Link) bool {
-	for _, existing := range links {
+// linkIndex returns the index of the link between the same nodes, or -1
+func linkIndex(links []Link, link Link) int {
+	for i, existing := range links {
 		if existing.Source == link.Source && existing.Target == link.Target {
-			return true
+			return i
 		}
 	}
-	return false
+	return -1
 }
 
 // calculateNodeSizes calculates the size (number of connections) for each node
@@ -361,6 +378,13 @@ func (s *Service) calculateNodeSizes(links []Link) map[string]int {
 
 	// Count connections for each node
 	for _, link := range links {
+		if isStructural(link) {
+			// Folder and date links size folder nodes but don't make a note look central
+			if isFolderNode(link.Target) {
+				sizeMap[link.Target]++
+			}
+			continue
+		}
 		sizeMap[link.Source]++
 		sizeMap[link.Target]++
 	}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+// testFile is an indexed note whose chunks hold content
+func testFile(notePath, title string, chunks ...string) database.File {
+	f := database.File{Path: notePath, Title: title}
+	for i, content := range chunks {
+		f.Chunks = append(f.Chunks, database.Chunk{ID: uint(i + 1), Content: content})
+	}
+	return f
+}
+
+func TestExtractWikiLinks_CollectsAnchors(t *testing.T) {
+	notes := []database.File{
+		testFile("a.md", "A",
+			"See [[b#Setup]], [[b^step1]] and [[b#^step1|again]].",
+			"Then [[b]], [[b#setup]] once more, [[a#Self]] and ![[b#Usage]].",
+			"```\n[[b#In Code]]\n```"),
+		testFile("b.md", "B", "# B\n## Setup\n"),
+	}
+	s := &Service{}
+	links, unresolved := s.extractWikiLinks(notes, newLinkResolver(notes))
+	if len(unresolved) != 0 {
+		t.Fatalf("unresolved = %+v", unresolved)
+	}
+	// One link per pair of notes, carrying every distinct anchor
+	want := []Link{{
+		Source:   generateNodeID("file", "a.md"),
+		Target:   generateNodeID("file", "b.md"),
+		Type:     "explicit",
+		Strength: 1,
+		Anchors:  []string{"#Setup", "^step1", "#setup", "#Usage"},
+	}}
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("links = %+v, want %+v", links, want)
+	}
+}
+
+func TestExtractWikiLinks_ReportsUnresolved(t *testing.T) {
+	notes := []database.File{
+		testFile("a.md", "A", "[[Missing]] [[Missing]] [[Plan]] [[Plan]]"),
+		testFile("x/Plan.md", "Plan"),
+		testFile("y/Plan.md", "Plan"),
+	}
+	s := &Service{}
+	links, unresolved := s.extractWikiLinks(notes, newLinkResolver(notes))
+	if len(links) != 0 {
+		t.Fatalf("links = %+v", links)
+	}
+	want := []UnresolvedLink{
+		{Source: "a.md", Target: "Missing", Reason: UnresolvedMissing},
+		{Source: "a.md", Target: "Plan", Reason: UnresolvedAmbiguous, Candidates: []string{"x/Plan.md", "y/Plan.md"}},
+	}
+	if !reflect.DeepEqual(unresolved, want) {
+		t.Fatalf("unresolved = %+v, want %+v", unresolved, want)
+	}
+}
+
+func TestExtractTagLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{name: "inline tags", chunks: []string{"Notes on #go and #rust-lang."}, want: []string{"go", "rust-lang"}},
+		{name: "unicode", chunks: []string{"Idee #über"}, want: []string{"über"}},
+		{name: "deduplicated across chunks", chunks: []string{"a #go", "b #go #db"}, want: []string{"go", "db"}},
+		{name: "line start is not a tag", chunks: []string{"#heading\n  #indented\ntext #kept"}, want: []string{"kept"}},
+		{name: "no tags", chunks: []string{"# Title\nplain"}, want: nil},
+	}
+	s := &Service{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := s.extractTagLinks([]database.File{testFile("a.md", "A", tt.chunks...)})
+			var got []string
+			for _, link := range links {
+				if link.Source != generateNodeID("file", "a.md") || link.Type != "tag" {
+					t.Errorf("link = %+v", link)
+				}
+				got = append(got, link.Target[len("tag:"):])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkHealth(t *testing.T) {
+	dbm := database.NewManager()
+	if err := dbm.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbm.Close() })
+
+	notes := map[string]string{
+		"a.md":      "[[b]] and [[Missing]]",
+		"b.md":      "# B",
+		"c.md":      "[[Plan]]",
+		"lonely.md": "no links #tag",
+		"x/Plan.md": "# Plan",
+		"y/Plan.md": "# Plan",
+	}
+	for notePath, content := range notes {
+		chunks := []database.ChunkInput{{Content: content}}
+		if err := dbm.Repository().IndexFileWithChunks(notePath, content, database.NoteStats{}, 0, int64(len(content)), chunks); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	health, err := NewService(dbm, nil).LinkHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lonely.md", "x/Plan.md", "y/Plan.md"}; !reflect.DeepEqual(health.Orphans, want) {
+		t.Errorf("Orphans = %q, want %q", health.Orphans, want)
+	}
+	wantBroken := []UnresolvedLink{{Source: "a.md", Target: "Missing", Reason: UnresolvedMissing}}
+	if !reflect.DeepEqual(health.BrokenLinks, wantBroken) {
+		t.Errorf("BrokenLinks = %+v, want %+v", health.BrokenLinks, wantBroken)
+	}
+	if len(health.AmbiguousLinks) != 1 || health.AmbiguousLinks[0].Source != "c.md" {
+		t.Errorf("AmbiguousLinks = %+v", health.AmbiguousLinks)
+	}
+}
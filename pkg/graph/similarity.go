@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"notebit/pkg/database"
+)
+
+const (
+	// implicitQueryChunks is how many chunk hits are fetched per note before aggregating
+	implicitQueryChunks = 50
+	// implicitTopK is how many of a neighbor's best chunks are averaged into its strength
+	implicitTopK = 3
+	// implicitNeighbors bounds the implicit links from one note
+	implicitNeighbors = 10
+)
+
+type neighbor struct {
+	path     string
+	strength float32
+}
+
+// centroid returns the normalized mean of a note's chunk embeddings, skipping
+// chunks whose dimension differs from the first embedded chunk
+func centroid(chunks []database.Chunk) []float32 {
+	var sum []float32
+	for i := range chunks {
+		emb := chunks[i].GetEmbedding()
+		if len(emb) == 0 {
+			continue
+		}
+		if sum == nil {
+			sum = make([]float32, len(emb))
+		}
+		if len(emb) != len(sum) {
+			continue
+		}
+		for j, v := range emb {
+			sum[j] += v
+		}
+	}
+	if sum == nil {
+		return nil
+	}
+
+	var norm float64
+	for _, v := range sum {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return nil
+	}
+	inv := float32(1 / math.Sqrt(norm))
+	for j := range sum {
+		sum[j] *= inv
+	}
+	return sum
+}
+
+// aggregateNeighbors groups chunk hits by note, excluding excludePath, and
+// scores each note by the mean of its top implicitTopK similarities. The
+// strongest limit notes are returned.
+func aggregateNeighbors(hits []database.SimilarChunk, excludePath string, limit int) []neighbor {
+	byPath := make(map[string][]float32)
+	for _, hit := range hits {
+		if hit.File == nil || hit.File.Path == excludePath {
+			continue
+		}
+		byPath[hit.File.Path] = append(byPath[hit.File.Path], hit.Similarity)
+	}
+
+	neighbors := make([]neighbor, 0, len(byPath))
+	for path, sims := range byPath {
+		sort.Slice(sims, func(i, j int) bool { return sims[i] > sims[j] })
+		if len(sims) > implicitTopK {
+			sims = sims[:implicitTopK]
+		}
+		var sum float32
+		for _, sim := range sims {
+			sum += sim
+		}
+		neighbors = append(neighbors, neighbor{path: path, strength: sum / float32(len(sims))})
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].strength != neighbors[j].strength {
+			return neighbors[i].strength > neighbors[j].strength
+		}
+		return neighbors[i].path < neighbors[j].path
+	})
+	if len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+	return neighbors
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestCentroid(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []database.Chunk
+		want   []float32
+	}{
+		{name: "no embeddings", chunks: []database.Chunk{{Content: "x"}}},
+		{
+			name:   "normalized mean",
+			chunks: []database.Chunk{{Embedding: []float32{1, 0}}, {Embedding: []float32{0, 1}}, {}},
+			want:   []float32{float32(1 / math.Sqrt2), float32(1 / math.Sqrt2)},
+		},
+		{
+			name:   "mismatched dimension skipped",
+			chunks: []database.Chunk{{Embedding: []float32{3, 4}}, {Embedding: []float32{1, 1, 1}}},
+			want:   []float32{0.6, 0.8},
+		},
+		{name: "zero vector", chunks: []database.Chunk{{Embedding: []float32{1, 0}}, {Embedding: []float32{-1, 0}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := centroid(tt.chunks)
+			if len(got) != len(tt.want) {
+				t.Fatalf("centroid = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("centroid = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateNeighbors(t *testing.T) {
+	hit := func(notePath string, sim float32) database.SimilarChunk {
+		return database.SimilarChunk{Similarity: sim, File: &database.File{Path: notePath}}
+	}
+	hits := []database.SimilarChunk{
+		hit("self.md", 1),
+		hit("a.md", 0.9), hit("a.md", 0.5), hit("a.md", 0.8), hit("a.md", 0.7),
+		hit("b.md", 0.6),
+		hit("c.md", 0.6),
+		{Similarity: 0.99},
+	}
+	tests := []struct {
+		name  string
+		limit int
+		want  []neighbor
+	}{
+		// a.md averages its top three hits, ties are ordered by path
+		{name: "all", limit: 10, want: []neighbor{{"a.md", 0.8}, {"b.md", 0.6}, {"c.md", 0.6}}},
+		{name: "limited", limit: 2, want: []neighbor{{"a.md", 0.8}, {"b.md", 0.6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregateNeighbors(hits, "self.md", tt.limit)
+			for i := range got {
+				got[i].strength = float32(math.Round(float64(got[i].strength)*1e4) / 1e4)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("neighbors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
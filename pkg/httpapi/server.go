@@ -0,0 +1,306 @@
+// Package httpapi serves a token-protected REST API on localhost so browser
+// extensions, launchers and scripts can work with the running app.
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend is the part of the app the API exposes. Paths are relative to the
+// open vault.
+type Backend interface {
+	ListNotes() ([]string, error)
+	ReadNote(path string) (string, error)
+	// CreateNote applies the naming rules and returns the path actually used
+	CreateNote(path, content string) (string, error)
+	SaveNote(path, content string) error
+	DeleteNote(path string) error
+	Search(query string, limit int) (any, error)
+	SemanticSearch(query string, limit int) (any, error)
+	// Ask runs a RAG query, in sessionID or the default chat session if empty
+	Ask(sessionID, query string) (any, error)
+}
+
+// ErrNotFound can be returned (wrapped) by a Backend to answer 404
+var ErrNotFound = errors.New("not found")
+
+const maxBodySize = 10 << 20
+
+// Server is the local REST API
+type Server struct {
+	backend Backend
+	token   string
+
+	mounts map[string]http.Handler
+
+	mu       sync.Mutex
+	srv      *http.Server
+	listener net.Listener
+}
+
+// NewToken returns a random API token
+func NewToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// New creates a server that accepts requests carrying token
+func New(backend Backend, token string) *Server {
+	return &Server{backend: backend, token: token}
+}
+
+// Mount serves h at pattern behind the same host and token checks as the
+// REST routes. It must be called before Start.
+func (s *Server) Mount(pattern string, h http.Handler) {
+	if s.mounts == nil {
+		s.mounts = make(map[string]http.Handler)
+	}
+	s.mounts[pattern] = h
+}
+
+// Start listens on 127.0.0.1:port and serves in the background
+func (s *Server) Start(port int) error {
+	if s.token == "" {
+		return errors.New("api token cannot be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.srv != nil {
+		return errors.New("api server already running")
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("listen on port %d: %w", port, err)
+	}
+	s.listener = ln
+	s.srv = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.srv.Serve(ln)
+	return nil
+}
+
+// Addr returns the listening address, or "" when stopped
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop shuts the server down, waiting briefly for requests in flight
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	srv := s.srv
+	s.srv, s.listener = nil, nil
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// Handler returns the API routes wrapped in the host and token checks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/notes", s.handleListNotes)
+	mux.HandleFunc("POST /v1/notes", s.handleCreateNote)
+	mux.HandleFunc("GET /v1/notes/{path...}", s.handleReadNote)
+	mux.HandleFunc("PUT /v1/notes/{path...}", s.handleSaveNote)
+	mux.HandleFunc("DELETE /v1/notes/{path...}", s.handleDeleteNote)
+	mux.HandleFunc("GET /v1/search", s.handleSearch)
+	mux.HandleFunc("GET /v1/semantic-search", s.handleSemanticSearch)
+	mux.HandleFunc("POST /v1/rag", s.handleRAG)
+	for pattern, h := range s.mounts {
+		mux.Handle(pattern, h)
+	}
+	return s.guard(mux)
+}
+
+// guard rejects requests addressed to another host (DNS rebinding) or
+// without the token, and answers CORS preflights for browser extensions
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host != "127.0.0.1" && host != "localhost" {
+			writeError(w, http.StatusForbidden, "invalid host")
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleListNotes(w http.ResponseWriter, r *http.Request) {
+	notes, err := s.backend.ListNotes()
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"notes": notes})
+}
+
+func (s *Server) handleReadNote(w http.ResponseWriter, r *http.Request) {
+	path := r.PathValue("path")
+	content, err := s.backend.ReadNote(path)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"path": path, "content": content})
+}
+
+type noteRequest struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+func (s *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) {
+	var req noteRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Path) == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	path, err := s.backend.CreateNote(req.Path, req.Content)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"path": path})
+}
+
+func (s *Server) handleSaveNote(w http.ResponseWriter, r *http.Request) {
+	var req noteRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	path := r.PathValue("path")
+	if err := s.backend.SaveNote(path, req.Content); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"path": path})
+}
+
+func (s *Server) handleDeleteNote(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.DeleteNote(r.PathValue("path")); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	s.search(w, r, s.backend.Search)
+}
+
+func (s *Server) handleSemanticSearch(w http.ResponseWriter, r *http.Request) {
+	s.search(w, r, s.backend.SemanticSearch)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, fn func(string, int) (any, error)) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	results, err := fn(query, limit)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"query": query, "results": results})
+}
+
+func (s *Server) handleRAG(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string `json:"query"`
+		SessionID string `json:"session_id"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	result, err := s.backend.Ask(req.SessionID, req.Query)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeBackendError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	writeError(w, status, err.Error())
+}
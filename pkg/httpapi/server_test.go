@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	notes map[string]string
+}
+
+func (f *fakeBackend) ListNotes() ([]string, error) {
+	var paths []string
+	for p := range f.notes {
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func (f *fakeBackend) ReadNote(path string) (string, error) {
+	content, ok := f.notes[path]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return content, nil
+}
+
+func (f *fakeBackend) CreateNote(path, content string) (string, error) {
+	path += ".md"
+	f.notes[path] = content
+	return path, nil
+}
+
+func (f *fakeBackend) SaveNote(path, content string) error {
+	f.notes[path] = content
+	return nil
+}
+
+func (f *fakeBackend) DeleteNote(path string) error {
+	delete(f.notes, path)
+	return nil
+}
+
+func (f *fakeBackend) Search(query string, limit int) (any, error) {
+	return []string{query}, nil
+}
+
+func (f *fakeBackend) SemanticSearch(query string, limit int) (any, error) {
+	return []string{}, nil
+}
+
+func (f *fakeBackend) Ask(sessionID, query string) (any, error) {
+	return map[string]string{"answer": "42"}, nil
+}
+
+func do(t *testing.T, h http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:27123"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerRequiresTokenAndLocalHost(t *testing.T) {
+	h := New(&fakeBackend{notes: map[string]string{}}, "secret").Handler()
+
+	if rec := do(t, h, "GET", "/v1/status", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: got %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/v1/status", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: got %d", rec.Code)
+	}
+	req := httptest.NewRequest("GET", "/v1/status", nil)
+	req.Host = "evil.example:27123"
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("foreign host: got %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/v1/status", "secret", ""); rec.Code != http.StatusOK {
+		t.Fatalf("valid request: got %d", rec.Code)
+	}
+}
+
+func TestServerNoteCRUD(t *testing.T) {
+	backend := &fakeBackend{notes: map[string]string{}}
+	h := New(backend, "secret").Handler()
+
+	rec := do(t, h, "POST", "/v1/notes", "secret", `{"path": "Inbox/Idea", "content": "hello"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: got %d %s", rec.Code, rec.Body)
+	}
+	var created struct{ Path string }
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Path != "Inbox/Idea.md" {
+		t.Fatalf("create returned %q", created.Path)
+	}
+
+	if rec := do(t, h, "PUT", "/v1/notes/Inbox/Idea.md", "secret", `{"content": "updated"}`); rec.Code != http.StatusOK {
+		t.Fatalf("save: got %d", rec.Code)
+	}
+	rec = do(t, h, "GET", "/v1/notes/Inbox/Idea.md", "secret", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "updated") {
+		t.Fatalf("read: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, "DELETE", "/v1/notes/Inbox/Idea.md", "secret", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/v1/notes/Inbox/Idea.md", "secret", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("read deleted: got %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/v1/search", "secret", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("search without q: got %d", rec.Code)
+	}
+}
@@ -0,0 +1,269 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"notebit/pkg/ai"
+)
+
+const (
+	defaultBenchmarkSample  = 20
+	maxBenchmarkEmbedTexts  = 64
+	benchmarkWriteChunks    = 20
+	minSuggestedDebounceMS  = 300
+	maxSuggestedDebounceMS  = 3000
+	throughputPlateauFactor = 0.9
+)
+
+var benchmarkBatchSizes = []int{1, 8, 16, 32, 64}
+var benchmarkConcurrency = []int{1, 2, 4, 8}
+
+// BenchmarkResult holds measured indexing performance and suggested tuning values
+type BenchmarkResult struct {
+	SampleFiles  int `json:"sample_files"`
+	SampleChunks int `json:"sample_chunks"`
+
+	ChunkingMSPerFile   float64 `json:"chunking_ms_per_file"`
+	ChunksPerSecond     float64 `json:"chunks_per_second"`
+	EmbedTextsPerSecond float64 `json:"embed_texts_per_second"` // best observed throughput
+	EmbedLatencyMS      float64 `json:"embed_latency_ms"`       // single-text round trip
+	DBWriteMSPerFile    float64 `json:"db_write_ms_per_file"`
+	EstimatedMSPerFile  float64 `json:"estimated_ms_per_file"`
+
+	BatchThroughput       map[int]float64 `json:"batch_throughput"`       // batch size -> texts/s
+	ConcurrencyThroughput map[int]float64 `json:"concurrency_throughput"` // workers -> texts/s
+
+	Suggested TuningSuggestion `json:"suggested"`
+	Warnings  []string         `json:"warnings,omitempty"`
+}
+
+// TuningSuggestion holds recommended settings derived from a benchmark
+type TuningSuggestion struct {
+	WorkerCount    int `json:"worker_count"`
+	BatchSize      int `json:"batch_size"`
+	DebounceMS     int `json:"debounce_ms"`
+	WatcherWorkers int `json:"watcher_workers"`
+}
+
+// Benchmark measures chunking, embedding throughput and database write latency on a
+// sample of vault files, then derives worker, batch size and debounce suggestions.
+// Nothing is persisted: database writes happen inside a rolled-back transaction.
+func (p *IndexingPipeline) Benchmark(ctx context.Context, sampleSize int) (*BenchmarkResult, error) {
+	if sampleSize <= 0 {
+		sampleSize = defaultBenchmarkSample
+	}
+	ctx = ai.WithOperation(ctx, ai.OperationBenchmark)
+	paths, err := p.fm.ListMarkdownFiles("")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no markdown files to benchmark")
+	}
+	paths = sampleEvenly(paths, sampleSize)
+
+	result := &BenchmarkResult{
+		BatchThroughput:       make(map[int]float64),
+		ConcurrencyThroughput: make(map[int]float64),
+	}
+
+	// Chunking
+	var texts []string
+	var chunkTime time.Duration
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		note, err := p.fm.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		chunks, err := p.ai.ChunkText(note.Content)
+		chunkTime += time.Since(start)
+		if err != nil {
+			continue
+		}
+		result.SampleFiles++
+		result.SampleChunks += len(chunks)
+		for _, c := range chunks {
+			texts = append(texts, c.Content)
+		}
+	}
+	if result.SampleFiles == 0 {
+		return nil, fmt.Errorf("no readable files in sample")
+	}
+	result.ChunkingMSPerFile = msFloat(chunkTime) / float64(result.SampleFiles)
+	if chunkTime > 0 {
+		result.ChunksPerSecond = float64(result.SampleChunks) / chunkTime.Seconds()
+	}
+	if len(texts) > maxBenchmarkEmbedTexts {
+		texts = texts[:maxBenchmarkEmbedTexts]
+	}
+
+	// Embedding throughput by batch size and by concurrency
+	bestBatch := 0
+	if len(texts) > 0 {
+		provider, err := p.ai.GetProvider()
+		if err != nil {
+			result.Warnings = append(result.Warnings, "embedding provider unavailable: "+err.Error())
+		} else {
+			start := time.Now()
+			if _, err := provider.GenerateEmbeddingsBatch(ctx, texts[:1]); err != nil {
+				result.Warnings = append(result.Warnings, "embedding failed: "+err.Error())
+			} else {
+				result.EmbedLatencyMS = msFloat(time.Since(start))
+				for _, size := range benchmarkBatchSizes {
+					if size > len(texts) && size != benchmarkBatchSizes[0] {
+						break
+					}
+					tput, err := measureBatches(ctx, texts, size, 1, provider.GenerateEmbeddingsBatch)
+					if err != nil {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("batch size %d failed: %v", size, err))
+						break
+					}
+					result.BatchThroughput[size] = tput
+				}
+				bestBatch = plateauKey(result.BatchThroughput)
+				for _, workers := range benchmarkConcurrency {
+					tput, err := measureBatches(ctx, texts, max(bestBatch, 1), workers, provider.GenerateEmbeddingsBatch)
+					if err != nil {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("%d concurrent workers failed: %v", workers, err))
+						break
+					}
+					result.ConcurrencyThroughput[workers] = tput
+				}
+				for _, tput := range result.ConcurrencyThroughput {
+					if tput > result.EmbedTextsPerSecond {
+						result.EmbedTextsPerSecond = tput
+					}
+				}
+			}
+		}
+	}
+
+	// Database writes
+	chunksPerFile := max(result.SampleChunks/result.SampleFiles, 1)
+	writeLatency, err := p.repo.MeasureWriteLatency(min(chunksPerFile, benchmarkWriteChunks), 3)
+	if err != nil {
+		result.Warnings = append(result.Warnings, "database write benchmark failed: "+err.Error())
+	} else {
+		result.DBWriteMSPerFile = msFloat(writeLatency)
+	}
+
+	// Suggestions
+	embedMSPerFile := 0.0
+	if result.EmbedTextsPerSecond > 0 {
+		embedMSPerFile = float64(chunksPerFile) / result.EmbedTextsPerSecond * 1000
+	}
+	result.EstimatedMSPerFile = result.ChunkingMSPerFile + embedMSPerFile + result.DBWriteMSPerFile
+
+	workers := plateauKey(result.ConcurrencyThroughput)
+	if workers == 0 {
+		workers = min(runtime.NumCPU(), 4)
+	}
+	if bestBatch == 0 {
+		bestBatch = 32
+	}
+	result.Suggested = TuningSuggestion{
+		WorkerCount:    workers,
+		BatchSize:      bestBatch,
+		DebounceMS:     min(max(int(result.EstimatedMSPerFile*2), minSuggestedDebounceMS), maxSuggestedDebounceMS),
+		WatcherWorkers: max(workers-1, 1),
+	}
+	return result, nil
+}
+
+// measureBatches embeds texts in batches of size using the given number of
+// concurrent workers and returns the throughput in texts per second. The
+// first failure stops the remaining batches.
+func measureBatches(ctx context.Context, texts []string, size, workers int, embed func(context.Context, []string) ([]*ai.EmbeddingResponse, error)) (float64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var batches [][]string
+	for i := 0; i < len(texts); i += size {
+		batches = append(batches, texts[i:min(i+size, len(texts))])
+	}
+
+	work := make(chan []string)
+	errCh := make(chan error, workers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				if _, err := embed(ctx, batch); err != nil {
+					errCh <- err
+					// Stop feeding the other workers
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(work)
+		for _, b := range batches {
+			select {
+			case work <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	return float64(len(texts)) / elapsed.Seconds(), nil
+}
+
+// plateauKey returns the smallest key whose value reaches 90% of the maximum,
+// so that extra workers or larger batches are only suggested when they pay off.
+func plateauKey(m map[int]float64) int {
+	best := 0.0
+	for _, v := range m {
+		if v > best {
+			best = v
+		}
+	}
+	choice := 0
+	for k, v := range m {
+		if v >= best*throughputPlateauFactor && (choice == 0 || k < choice) {
+			choice = k
+		}
+	}
+	return choice
+}
+
+// sampleEvenly picks n items spread across the slice
+func sampleEvenly(items []string, n int) []string {
+	if len(items) <= n {
+		return items
+	}
+	out := make([]string, 0, n)
+	step := float64(len(items)) / float64(n)
+	for i := 0; i < n; i++ {
+		out = append(out, items[int(float64(i)*step)])
+	}
+	return out
+}
+
+func msFloat(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,57 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"notebit/pkg/ai"
+)
+
+func TestMeasureBatches_EmbedsEveryText(t *testing.T) {
+	texts := []string{"a", "b", "c", "d", "e"}
+	var mu sync.Mutex
+	var sizes []int
+	embed := func(ctx context.Context, batch []string) ([]*ai.EmbeddingResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, len(batch))
+		return nil, nil
+	}
+	if _, err := measureBatches(context.Background(), texts, 2, 2, embed); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	if len(sizes) != 3 || total != len(texts) {
+		t.Fatalf("batch sizes = %v, want three batches of all %d texts", sizes, len(texts))
+	}
+}
+
+func TestMeasureBatches_FailureStopsFeeder(t *testing.T) {
+	texts := make([]string, 100)
+	failure := errors.New("rate limited")
+	embed := func(ctx context.Context, batch []string) ([]*ai.EmbeddingResponse, error) {
+		return nil, failure
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		if _, err := measureBatches(context.Background(), texts, 1, 2, embed); !errors.Is(err, failure) {
+			t.Fatalf("err = %v, want %v", err, failure)
+		}
+	}
+	// Every worker failed early; the feeder must not stay blocked behind them
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines left running, had %d", n, before)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package indexing
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileBirthTime returns when the file was created
+func fileBirthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Birthtimespec.Unix()), true
+}
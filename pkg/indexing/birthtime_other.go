@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package indexing
+
+import (
+	"os"
+	"time"
+)
+
+// fileBirthTime is not supported on this platform
+func fileBirthTime(string, os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package indexing
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileBirthTime returns when the file was created
+func fileBirthTime(_ string, info os.FileInfo) (time.Time, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
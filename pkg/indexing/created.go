@@ -0,0 +1,37 @@
+package indexing
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"notebit/pkg/files"
+)
+
+// createdLayouts are the front matter created date formats, tried in order
+var createdLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// noteCreated returns when a note was created: its front matter created
+// date, else the earlier of its file's birth and modification times, as
+// copies keep the modification time but get a new birth time
+func noteCreated(fullPath, content string, info os.FileInfo) time.Time {
+	if value := strings.TrimSpace(files.FrontmatterField(content, "created")); value != "" {
+		for _, layout := range createdLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	created := info.ModTime()
+	if birth, ok := fileBirthTime(fullPath, info); ok && !birth.IsZero() && birth.Before(created) {
+		created = birth
+	}
+	return created
+}
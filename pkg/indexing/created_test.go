@@ -0,0 +1,41 @@
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNoteCreated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	if err := os.WriteFile(path, []byte("# Note"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content string
+		want    time.Time
+	}{
+		{"---\ncreated: 2021-03-04\n---\n# Note", time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local)},
+		{"---\ncreated: \"2021-03-04 10:30\"\n---\n", time.Date(2021, 3, 4, 10, 30, 0, 0, time.Local)},
+		{"---\ncreated: 2021-03-04T10:30:00Z\n---\n", time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)},
+		// The file was just written, so its modification time is earlier
+		// than its birth time
+		{"---\ncreated: someday\n---\n", modified},
+		{"# Note", modified},
+	}
+	for _, tt := range tests {
+		if got := noteCreated(path, tt.content, info); !got.Equal(tt.want) {
+			t.Errorf("noteCreated(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package indexing
+
+// maxDriftSamples caps the example paths kept per category in a DriftReport
+const maxDriftSamples = 20
+
+// DriftReport describes how the index differed from the vault on disk
+type DriftReport struct {
+	CheckedAt  int64 `json:"checked_at"` // Unix ms
+	DurationMS int64 `json:"duration_ms"`
+	OnDisk     int   `json:"on_disk"`
+	Indexed    int   `json:"indexed"`
+
+	Missing int `json:"missing"` // on disk, not indexed
+	Changed int `json:"changed"` // mtime or size differs from the index
+	Deleted int `json:"deleted"` // indexed, gone from disk
+
+	Removed int `json:"removed"` // stale entries actually deleted
+	Queued  int `json:"queued"`  // files submitted for indexing
+
+	// Up to maxDriftSamples example paths per category
+	MissingPaths []string `json:"missing_paths,omitempty"`
+	ChangedPaths []string `json:"changed_paths,omitempty"`
+	DeletedPaths []string `json:"deleted_paths,omitempty"`
+}
+
+// HasDrift reports whether the index was out of sync
+func (r *DriftReport) HasDrift() bool {
+	return r.Missing+r.Changed+r.Deleted > 0
+}
+
+// LastDriftReport returns what the most recent Reconcile found, or nil
+func (p *IndexingPipeline) LastDriftReport() *DriftReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastDrift
+}
+
+func appendSample(samples []string, path string) []string {
+	if len(samples) >= maxDriftSamples {
+		return samples
+	}
+	return append(samples, path)
+}
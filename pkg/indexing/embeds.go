@@ -0,0 +1,28 @@
+package indexing
+
+import (
+	"strings"
+
+	"notebit/pkg/files"
+)
+
+// embedsAsLinks rewrites the note embeds of content, ![[Note#Heading]], as
+// plain wiki links, so chunks link to the embedded note like any other link
+// instead of carrying the embed markup as text. Image and other
+// attachment embeds are left as they are.
+func embedsAsLinks(content string) string {
+	var sb strings.Builder
+	prev := 0
+	for _, link := range files.FindWikiLinks(content) {
+		if !link.Embed || link.IsAttachment() {
+			continue
+		}
+		sb.WriteString(content[prev:link.Offset])
+		prev = link.Offset + 1 // drop the "!"
+	}
+	if prev == 0 {
+		return content
+	}
+	sb.WriteString(content[prev:])
+	return sb.String()
+}
@@ -0,0 +1,14 @@
+package indexing
+
+import "testing"
+
+func TestEmbedsAsLinks(t *testing.T) {
+	content := "![[Intro]] see ![[Design#Goals|goals]] and ![[diagram.png]].\n\n```\n![[Not an embed]]\n```\n[[Plain]] ![[report.pdf]]"
+	want := "[[Intro]] see [[Design#Goals|goals]] and ![[diagram.png]].\n\n```\n![[Not an embed]]\n```\n[[Plain]] ![[report.pdf]]"
+	if got := embedsAsLinks(content); got != want {
+		t.Fatalf("embedsAsLinks =\n%q\nwant\n%q", got, want)
+	}
+	if got := embedsAsLinks("no embeds"); got != "no embeds" {
+		t.Fatalf("embedsAsLinks changed text without embeds: %q", got)
+	}
+}
@@ -0,0 +1,357 @@
+package indexing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+	"notebit/pkg/logger"
+)
+
+const (
+	// maxImagesPerNote bounds how many images of one note are read
+	maxImagesPerNote = 20
+	// maxImageTextRunes bounds the text kept from one image
+	maxImageTextRunes = 4000
+	// imageRetryDelay is how long an image that could not be read is left
+	// alone before it is queued again
+	imageRetryDelay = 10 * time.Minute
+)
+
+// markdownImagePattern matches ![alt](target "title")
+var markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*(<[^>]+>|[^)\s]+)[^)]*\)`)
+
+// imageQueue holds the notes with images not read yet. Reading an image can
+// take minutes, so indexing only uses texts already read and leaves the rest
+// to ReadQueuedImages.
+type imageQueue struct {
+	paths  []string
+	queued map[string]bool
+	// failed is when reading an image with a reader last failed, by image
+	// hash and reader name
+	failed map[string]time.Time
+	// listener is called when a note is queued
+	listener func()
+}
+
+// SetImageTextExtractor makes the pipeline read the text of images embedded
+// in notes with x, in the background through ReadQueuedImages, and store it
+// as extra chunks of the note; nil stops it. Notes are read again when they
+// change, not when only their images do.
+func (p *IndexingPipeline) SetImageTextExtractor(x ai.ImageTextExtractor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ocr = x
+}
+
+// SetImageCaptioner makes indexing describe images embedded in notes with c,
+// so they can be found by what they show; nil stops it
+func (p *IndexingPipeline) SetImageCaptioner(c ai.ImageTextExtractor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.captioner = c
+}
+
+// imageReader is a configured way of turning an image into chunk text
+type imageReader struct {
+	reader ai.ImageTextExtractor
+	// heading is appended to the image name to form the chunk heading
+	heading string
+}
+
+func (p *IndexingPipeline) imageReaders() []imageReader {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var readers []imageReader
+	if p.ocr != nil {
+		readers = append(readers, imageReader{reader: p.ocr})
+	}
+	if p.captioner != nil {
+		readers = append(readers, imageReader{reader: p.captioner, heading: " (description)"})
+	}
+	return readers
+}
+
+// imageRefs returns the vault paths of the images embedded in a note, in
+// order of appearance. Markdown targets are relative to the note; wiki embeds
+// are looked up next to the note, then at the vault root.
+func (p *IndexingPipeline) imageRefs(notePath, content string) []string {
+	dir := path.Dir(strings.ReplaceAll(notePath, "\\", "/"))
+	seen := make(map[string]bool)
+	var refs []string
+	add := func(ref string) {
+		if ref != "" && !seen[ref] && ai.IsImageFile(ref) && len(refs) < maxImagesPerNote {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	for _, m := range markdownImagePattern.FindAllStringSubmatch(content, -1) {
+		target := strings.Trim(m[1], "<>")
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "data:") {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if strings.HasPrefix(target, "/") {
+			add(path.Clean(strings.TrimPrefix(target, "/")))
+		} else {
+			add(path.Join(dir, target))
+		}
+	}
+	for _, link := range files.FindWikiLinks(content) {
+		if !link.Embed || link.Target == "" {
+			continue
+		}
+		target := link.Target
+		if candidate := path.Join(dir, target); p.fm.FileExists(candidate) {
+			add(candidate)
+		} else {
+			add(path.Clean(target))
+		}
+	}
+	return refs
+}
+
+// SetImageQueueListener makes the pipeline call fn whenever a note is queued
+// for image reading, e.g. to start a background job running
+// ReadQueuedImages. fn must not block.
+func (p *IndexingPipeline) SetImageQueueListener(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.images.listener = fn
+}
+
+// imageChunks returns chunks for the images embedded in a note that were
+// already read, tied to their image: their text and, with a captioner, a
+// description. Images not read yet queue the note for ReadQueuedImages.
+func (p *IndexingPipeline) imageChunks(ctx context.Context, notePath, content string) []database.ChunkInput {
+	readers := p.imageReaders()
+	if len(readers) == 0 {
+		return nil
+	}
+	var chunks []database.ChunkInput
+	unread := false
+	for _, ref := range p.imageRefs(notePath, content) {
+		hash, err := p.imageHash(ref)
+		if err != nil {
+			continue
+		}
+		for _, r := range readers {
+			text, ok, err := p.repo.GetImageText(hash, r.reader.Name())
+			if err != nil || !ok {
+				unread = unread || !p.recentlyFailed(hash, r.reader)
+				continue
+			}
+			if chunk, ok := imageChunk(ref, r, text); ok {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	if unread {
+		p.queueImages(notePath)
+	}
+	return chunks
+}
+
+// imageChunk turns the text read from image ref into a chunk, if it has any
+func imageChunk(ref string, r imageReader, text string) (database.ChunkInput, bool) {
+	if text == "" {
+		return database.ChunkInput{}, false
+	}
+	if runes := []rune(text); len(runes) > maxImageTextRunes {
+		text = string(runes[:maxImageTextRunes])
+	}
+	return database.ChunkInput{
+		Content:  text,
+		Heading:  path.Base(ref) + r.heading,
+		Language: ai.DetectLanguage(text),
+		Source:   ref,
+	}, true
+}
+
+// queueImages queues notePath for ReadQueuedImages
+func (p *IndexingPipeline) queueImages(notePath string) {
+	p.mu.Lock()
+	if p.images.queued[notePath] {
+		p.mu.Unlock()
+		return
+	}
+	if p.images.queued == nil {
+		p.images.queued = make(map[string]bool)
+	}
+	p.images.queued[notePath] = true
+	p.images.paths = append(p.images.paths, notePath)
+	listener := p.images.listener
+	p.mu.Unlock()
+	if listener != nil {
+		listener()
+	}
+}
+
+// nextQueuedImages takes the next note off the image queue
+func (p *IndexingPipeline) nextQueuedImages() (string, int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.images.paths) == 0 {
+		return "", 0, false
+	}
+	notePath := p.images.paths[0]
+	p.images.paths = p.images.paths[1:]
+	delete(p.images.queued, notePath)
+	return notePath, len(p.images.paths), true
+}
+
+// recentlyFailed reports whether reading the image with hash failed within
+// imageRetryDelay
+func (p *IndexingPipeline) recentlyFailed(hash string, reader ai.ImageTextExtractor) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	failedAt, ok := p.images.failed[hash+"|"+reader.Name()]
+	return ok && time.Since(failedAt) < imageRetryDelay
+}
+
+func (p *IndexingPipeline) recordImageFailure(hash string, reader ai.ImageTextExtractor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.images.failed == nil {
+		p.images.failed = make(map[string]time.Time)
+	}
+	p.images.failed[hash+"|"+reader.Name()] = time.Now()
+}
+
+// ReadQueuedImages reads the images of the notes queued by indexing, one
+// note at a time until the queue is empty, and reindexes each note whose
+// images could be read so their text becomes searchable. report, if not
+// nil, receives the notes done and the notes known so far. It returns the
+// number of images read.
+func (p *IndexingPipeline) ReadQueuedImages(ctx context.Context, report func(done, total int, message string)) (int, error) {
+	ctx = ai.WithOperation(ctx, ai.OperationIndexing)
+	read, done := 0, 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return read, err
+		}
+		notePath, remaining, ok := p.nextQueuedImages()
+		if !ok {
+			return read, nil
+		}
+		if report != nil {
+			report(done, done+remaining+1, notePath)
+		}
+		n, readable := p.readNoteImages(ctx, notePath)
+		read += n
+		if readable {
+			if err := p.IndexFile(ctx, notePath, IndexOptions{ForceReindex: true, FallbackToMetadataOnly: true}); err != nil && ctx.Err() == nil {
+				logger.Warn("Reindexing %s with its image text failed: %v", notePath, err)
+			}
+		}
+		done++
+	}
+}
+
+// readNoteImages reads the images of a note that are not cached yet. It
+// returns how many it read and whether any image of the note has text.
+func (p *IndexingPipeline) readNoteImages(ctx context.Context, notePath string) (int, bool) {
+	note, err := p.fm.ReadFile(notePath)
+	if err != nil {
+		return 0, false
+	}
+	read, readable := 0, false
+	for _, ref := range p.imageRefs(notePath, note.Content) {
+		hash, err := p.imageHash(ref)
+		if err != nil {
+			continue
+		}
+		for _, r := range p.imageReaders() {
+			if ctx.Err() != nil {
+				return read, readable
+			}
+			if text, ok, err := p.repo.GetImageText(hash, r.reader.Name()); err == nil && ok {
+				readable = readable || text != ""
+				continue
+			}
+			if p.recentlyFailed(hash, r.reader) {
+				continue
+			}
+			text, err := p.readImage(ctx, r.reader, ref, hash)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.recordImageFailure(hash, r.reader)
+					logger.WarnWithFields(ctx, map[string]interface{}{
+						"path":   notePath,
+						"image":  ref,
+						"reader": r.reader.Name(),
+						"error":  err.Error(),
+					}, "Failed to read image")
+				}
+				continue
+			}
+			read++
+			readable = readable || text != ""
+		}
+	}
+	return read, readable
+}
+
+// imageHash returns the content hash of image ref, which its text is cached by
+func (p *IndexingPipeline) imageHash(ref string) (string, error) {
+	fullPath, err := p.fm.AbsPath(ref)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readImage reads the text of image ref with extractor and caches it
+func (p *IndexingPipeline) readImage(ctx context.Context, extractor ai.ImageTextExtractor, ref, hash string) (string, error) {
+	fullPath, err := p.fm.AbsPath(ref)
+	if err != nil {
+		return "", err
+	}
+	text, err := extractor.ExtractText(ctx, fullPath)
+	if err != nil {
+		return "", err
+	}
+	if err := p.repo.SaveImageText(hash, extractor.Name(), text); err != nil {
+		logger.Warn("Failed to cache text of %s: %v", ref, err)
+	}
+	return text, nil
+}
+
+// embedImageChunks embeds image chunks in place. Chunks that cannot be
+// embedded are kept without embeddings, which marks their note for retry.
+func (p *IndexingPipeline) embedImageChunks(ctx context.Context, chunks []database.ChunkInput) {
+	if len(chunks) == 0 {
+		return
+	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Content
+	}
+	results, err := p.ai.GenerateEmbeddingsBatch(ctx, texts)
+	if err != nil {
+		logger.WarnWithFields(ctx, map[string]interface{}{"chunks": len(chunks), "error": err.Error()}, "Failed to embed some image text")
+	}
+	for i, r := range results {
+		if i < len(chunks) && r != nil && len(r.Embedding) > 0 {
+			chunks[i].Embedding = r.Embedding
+			chunks[i].EmbeddingModel = r.Model
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+)
+
+type fakeImageReader struct {
+	name  string
+	text  string
+	calls atomic.Int32
+}
+
+func (f *fakeImageReader) Name() string { return f.name }
+
+func (f *fakeImageReader) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	f.calls.Add(1)
+	return f.text, nil
+}
+
+func TestImageRefs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes", "local.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fm := files.NewManager()
+	if err := fm.SetBasePath(dir); err != nil {
+		t.Fatal(err)
+	}
+	p := &IndexingPipeline{fm: fm}
+
+	content := "![a](img/one.png) ![b](<../shared/two%20words.jpg> \"title\") ![c](https://example.com/x.png)\n" +
+		"![[local.png|300]] ![[root.webp]] ![[doc.pdf]] ![a](img/one.png)"
+	got := p.imageRefs("notes/page.md", content)
+	want := []string{"notes/img/one.png", "shared/two words.jpg", "notes/local.png", "root.webp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("imageRefs = %q, want %q", got, want)
+	}
+}
+
+func TestIndexingPipeline_IndexesImageText(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float32{1, 0.5, 0.25}, "model": req.Model})
+	}))
+	defer server.Close()
+
+	database.Reset()
+	dbManager := database.GetInstance()
+	if err := dbManager.Init(tmpDir); err != nil {
+		t.Fatalf("database init failed: %v", err)
+	}
+	defer func() {
+		_ = dbManager.Close()
+		database.Reset()
+	}()
+
+	fm := files.NewManager()
+	if err := fm.SetBasePath(tmpDir); err != nil {
+		t.Fatalf("set base path failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "board.png"), []byte("png bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := "# Meeting\n\nPhoto of the board:\n\n![board](board.png)\n"
+	for _, name := range []string{"meeting.md", "copy.md"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.New()
+	cfg.SetOllamaConfig(server.URL, "nomic-embed-text", 3)
+	cfg.SetProvider("ollama")
+	cfg.SetEmbeddingModel("nomic-embed-text")
+	aiService := ai.NewService(cfg)
+	if err := aiService.Initialize(); err != nil {
+		t.Fatalf("ai initialize failed: %v", err)
+	}
+
+	reader := &fakeImageReader{name: "fake", text: "whiteboard: quarterly roadmap"}
+	captioner := &fakeImageReader{name: "caption:fake", text: "A whiteboard sketch of a system architecture diagram."}
+	pipeline := NewPipeline(aiService, dbManager.Repository(), fm)
+	pipeline.SetImageTextExtractor(reader)
+	pipeline.SetImageCaptioner(captioner)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	queued := make(chan struct{}, 10)
+	pipeline.SetImageQueueListener(func() { queued <- struct{}{} })
+	for _, name := range []string{"meeting.md", "copy.md"} {
+		if err := pipeline.IndexFile(ctx, name, IndexOptions{}); err != nil {
+			t.Fatalf("indexing %s failed: %v", name, err)
+		}
+	}
+	// Indexing leaves images to the background
+	if calls := reader.calls.Load() + captioner.calls.Load(); calls != 0 || len(queued) != 2 {
+		t.Fatalf("indexing read images (%d calls) or did not queue them (%d)", calls, len(queued))
+	}
+	read, err := pipeline.ReadQueuedImages(ctx, nil)
+	if err != nil || read != 2 {
+		t.Fatalf("ReadQueuedImages = %d, %v, want 2 images read", read, err)
+	}
+	if calls := reader.calls.Load(); calls != 1 {
+		t.Fatalf("image read %d times, want once", calls)
+	}
+	if calls := captioner.calls.Load(); calls != 1 {
+		t.Fatalf("image described %d times, want once", calls)
+	}
+
+	repo := pipeline.Repository()
+	file, err := repo.GetFileByPath("meeting.md")
+	if err != nil {
+		t.Fatalf("GetFileByPath failed: %v", err)
+	}
+	chunks, err := repo.GetChunksByFileID(file.ID)
+	if err != nil {
+		t.Fatalf("GetChunksByFileID failed: %v", err)
+	}
+	var image, caption *database.Chunk
+	for i := range chunks {
+		switch chunks[i].Heading {
+		case "board.png":
+			image = &chunks[i]
+		case "board.png (description)":
+			caption = &chunks[i]
+		}
+	}
+	if image == nil || image.Source != "board.png" || image.Content != "whiteboard: quarterly roadmap" || len(image.EmbeddingBlob) == 0 {
+		t.Fatalf("image chunk missing or incomplete: %+v", chunks)
+	}
+	if caption == nil || caption.Source != "board.png" || caption.Content != captioner.text || len(caption.EmbeddingBlob) == 0 {
+		t.Fatalf("caption chunk missing or incomplete: %+v", chunks)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("reindexing with image text queued the notes again")
+	}
+}
+
+type failingImageReader struct {
+	calls atomic.Int32
+}
+
+func (f *failingImageReader) Name() string { return "failing" }
+
+func (f *failingImageReader) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	f.calls.Add(1)
+	return "", errors.New("vision model unavailable")
+}
+
+func TestReadQueuedImages_FailedImagesWait(t *testing.T) {
+	dir := t.TempDir()
+	database.Reset()
+	dbManager := database.GetInstance()
+	if err := dbManager.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = dbManager.Close()
+		database.Reset()
+	}()
+	fm := files.NewManager()
+	if err := fm.SetBasePath(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scan.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := "![scan](scan.png)"
+	if err := os.WriteFile(filepath.Join(dir, "a.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := &failingImageReader{}
+	p := &IndexingPipeline{repo: dbManager.Repository(), fm: fm, ocr: reader}
+	if chunks := p.imageChunks(context.Background(), "a.md", content); len(chunks) != 0 {
+		t.Fatalf("unread image produced chunks: %+v", chunks)
+	}
+	if read, err := p.ReadQueuedImages(context.Background(), nil); err != nil || read != 0 || reader.calls.Load() != 1 {
+		t.Fatalf("ReadQueuedImages = %d, %v after %d calls", read, err, reader.calls.Load())
+	}
+
+	// The failure is remembered, so indexing the note again does not queue it
+	p.imageChunks(context.Background(), "a.md", content)
+	if _, _, ok := p.nextQueuedImages(); ok {
+		t.Fatal("image that just failed was queued again")
+	}
+}
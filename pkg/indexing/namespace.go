@@ -0,0 +1,127 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/logger"
+)
+
+const namespaceBatchSize = 50
+
+// NamespaceProgress reports the result of filling an embedding namespace
+type NamespaceProgress struct {
+	Model    string `json:"model"`
+	Embedded int    `json:"embedded"`
+	Failed   int    `json:"failed"`
+}
+
+// BuildEmbeddingNamespace embeds every chunk that has no embedding for model yet and
+// stores the vectors in that model's namespace. The primary embeddings used for
+// regular search are left alone, so two models can be compared side by side.
+func (p *IndexingPipeline) BuildEmbeddingNamespace(ctx context.Context, model string) (*NamespaceProgress, error) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	ctx = ai.WithOperation(ctx, ai.OperationNamespace)
+
+	progress := &NamespaceProgress{Model: model}
+	skip := make(map[uint]struct{}) // failed or empty chunks, excluded from later batches
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		limit := namespaceBatchSize + len(skip)
+		pending, err := p.repo.ListChunksMissingModel(model, limit)
+		if err != nil {
+			return progress, err
+		}
+
+		embeddings := make(map[uint][]float32)
+		for _, chunk := range pending {
+			if _, ok := skip[chunk.ID]; ok {
+				continue
+			}
+			if strings.TrimSpace(chunk.Content) == "" {
+				skip[chunk.ID] = struct{}{}
+				continue
+			}
+			resp, err := p.ai.GenerateEmbeddingWithModel(ctx, model, p.ai.EmbeddingText(chunk.Content))
+			if err != nil {
+				logger.Warn("Embedding chunk %d with %s failed: %v", chunk.ID, model, err)
+				skip[chunk.ID] = struct{}{}
+				progress.Failed++
+				continue
+			}
+			embeddings[chunk.ID] = resp.Embedding
+		}
+		if len(embeddings) == 0 {
+			if len(pending) < limit {
+				break
+			}
+			continue
+		}
+		if err := p.repo.SaveChunkEmbeddings(model, embeddings); err != nil {
+			return progress, err
+		}
+		progress.Embedded += len(embeddings)
+	}
+	return progress, nil
+}
+
+// embedLanguageChunks embeds the chunks of path whose language has its own
+// model with that model, next to their default embedding. Failures only cost
+// multilingual recall, so they are logged and the file stays indexed.
+func (p *IndexingPipeline) embedLanguageChunks(ctx context.Context, path string) {
+	file, err := p.repo.GetFileByPath(path)
+	if err != nil {
+		return
+	}
+	chunks, err := p.repo.GetChunksByFileID(file.ID)
+	if err != nil {
+		logger.Warn("Loading chunks of %s for language models failed: %v", path, err)
+		return
+	}
+
+	// One batch per language model instead of a request per chunk
+	type pending struct {
+		ids   []uint
+		texts []string
+	}
+	byModel := make(map[string]*pending)
+	for _, chunk := range chunks {
+		model := p.ai.LanguageModel(chunk.Language)
+		if model == "" || strings.TrimSpace(chunk.Content) == "" {
+			continue
+		}
+		if byModel[model] == nil {
+			byModel[model] = &pending{}
+		}
+		byModel[model].ids = append(byModel[model].ids, chunk.ID)
+		byModel[model].texts = append(byModel[model].texts, p.ai.EmbeddingText(chunk.Content))
+	}
+
+	ctx = ai.WithOperation(ctx, ai.OperationNamespace)
+	for model, batch := range byModel {
+		resps, err := p.ai.GenerateEmbeddingsBatchWithModel(ctx, model, batch.texts)
+		if err != nil {
+			// A *BatchError still carries the chunks that were embedded
+			logger.Warn("Embedding chunks of %s with %s failed: %v", path, model, err)
+		}
+		embeddings := make(map[uint][]float32, len(resps))
+		for i, resp := range resps {
+			if resp != nil {
+				embeddings[batch.ids[i]] = resp.Embedding
+			}
+		}
+		if len(embeddings) == 0 {
+			continue
+		}
+		if err := p.repo.SaveChunkEmbeddings(model, embeddings); err != nil {
+			logger.Warn("Saving %s embeddings of %s failed: %v", model, path, err)
+		}
+	}
+}
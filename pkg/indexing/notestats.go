@@ -0,0 +1,12 @@
+package indexing
+
+import (
+	"notebit/pkg/database"
+	"notebit/pkg/files"
+)
+
+// CountNoteStats counts the stats stored with a note from its content
+func CountNoteStats(content string) database.NoteStats {
+	words, characters, headings, links := files.CountNoteStats(content)
+	return database.NoteStats{Words: words, Characters: characters, Headings: headings, Links: links}
+}
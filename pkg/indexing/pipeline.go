@@ -29,8 +29,26 @@ type IndexingPipeline struct {
 	isStarted bool
 	mu        sync.Mutex
 
+	// baseCtx is cancelled by Stop so in-flight AI calls abort on shutdown
+	baseCtx context.Context
+	cancel  context.CancelFunc
+
 	// Deduplication map to prevent concurrent indexing of the same file
 	inProgress sync.Map // map[string]bool
+
+	// Drift found by the last Reconcile, guarded by mu
+	lastDrift *DriftReport
+
+	// stopHealthWatch unregisters the provider health listener, guarded by mu
+	stopHealthWatch func()
+
+	// ocr reads the text of images embedded in notes and captioner describes
+	// them, guarded by mu; nil skips that step
+	ocr       ai.ImageTextExtractor
+	captioner ai.ImageTextExtractor
+
+	// images are the notes waiting for ReadQueuedImages, guarded by mu
+	images imageQueue
 }
 
 var errPipelineStopped = errors.New("indexing pipeline not started")
@@ -41,6 +59,12 @@ type IndexJob struct {
 	Content string // Optional: if empty, will be read from disk
 	Opts    IndexOptions
 	ErrChan chan error // Optional: channel to receive error result
+
+	// enqueuedAt identifies the persisted record this job belongs to (Unix nanoseconds)
+	enqueuedAt int64
+
+	// ctx is the submitting caller's context; cancelling it aborts the job
+	ctx context.Context
 }
 
 // IndexOptions controls indexing behavior
@@ -95,10 +119,15 @@ func (p *IndexingPipeline) Start() {
 		return
 	}
 
+	p.baseCtx, p.cancel = context.WithCancel(context.Background())
 	for i := 0; i < p.workers; i++ {
 		go p.worker(i)
 	}
 
+	if p.ai != nil {
+		p.stopHealthWatch = p.ai.OnHealthChange(p.onProviderHealth)
+	}
+
 	p.isStarted = true
 	logger.InfoWithFields(context.Background(), map[string]interface{}{
 		"workers":    p.workers,
@@ -108,8 +137,20 @@ func (p *IndexingPipeline) Start() {
 
 // worker processes indexing jobs from the queue
 func (p *IndexingPipeline) worker(id int) {
+	defer logger.RecoverPanic()
 	for job := range p.workQueue {
+		if job.enqueuedAt != 0 {
+			if err := p.repo.MarkIndexJobRunning(job.Path); err != nil {
+				logger.Debug("Failed to mark index job running for %s: %v", job.Path, err)
+			}
+		}
 		err := p.processJob(job)
+		// Jobs interrupted by shutdown stay in the persistent queue for the next run
+		if job.enqueuedAt != 0 && !(isCancellation(err) && p.baseCtx.Err() != nil) {
+			if cerr := p.repo.CompleteIndexJob(job.Path, job.enqueuedAt); cerr != nil {
+				logger.Debug("Failed to complete index job for %s: %v", job.Path, cerr)
+			}
+		}
 		if job.ErrChan != nil {
 			job.ErrChan <- err
 			close(job.ErrChan)
@@ -118,7 +159,7 @@ func (p *IndexingPipeline) worker(id int) {
 }
 
 // processJob handles a single indexing job with deduplication
-func (p *IndexingPipeline) processJob(job *IndexJob) error {
+func (p *IndexingPipeline) processJob(job *IndexJob) (err error) {
 	// Deduplication: skip if already in progress
 	if _, loaded := p.inProgress.LoadOrStore(job.Path, true); loaded {
 		logger.InfoWithFields(context.Background(), map[string]interface{}{
@@ -128,13 +169,19 @@ func (p *IndexingPipeline) processJob(job *IndexJob) error {
 	}
 	defer p.inProgress.Delete(job.Path)
 
-	ctx := context.Background()
+	ctx, cancel := p.jobContext(job)
+	defer cancel()
+	ctx = ai.WithOperation(ctx, ai.OperationIndexing)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Read content if not provided
 	content := job.Content
 	if content == "" {
 		noteContent, err := p.fm.ReadFile(job.Path)
 		if err != nil {
+			p.recordFailure(job.Path, database.IndexStageRead, err)
 			return fmt.Errorf("read file: %w", err)
 		}
 		content = noteContent.Content
@@ -144,8 +191,17 @@ func (p *IndexingPipeline) processJob(job *IndexJob) error {
 	fullPath := filepath.Join(p.fm.GetBasePath(), job.Path)
 	stat, err := os.Stat(fullPath)
 	if err != nil {
+		p.recordFailure(job.Path, database.IndexStageStat, err)
 		return fmt.Errorf("stat file: %w", err)
 	}
+	defer func() {
+		// Unchanged notes too, so notes indexed before this was kept get it
+		if err == nil {
+			if err := p.repo.SetFileCreated(job.Path, noteCreated(fullPath, content, stat).Unix()); err != nil {
+				logger.Warn("Recording when %s was created failed: %v", job.Path, err)
+			}
+		}
+	}()
 
 	// Check if indexing is needed
 	if job.Opts.SkipIfUnchanged && !job.Opts.ForceReindex {
@@ -163,11 +219,29 @@ func (p *IndexingPipeline) processJob(job *IndexJob) error {
 		}
 	}
 
-	// Try full indexing with embeddings
-	err = p.indexWithEmbeddings(ctx, job.Path, content, stat.ModTime().Unix(), stat.Size())
+	// Try full indexing with embeddings, unless the provider is known to be down;
+	// such files are re-embedded when it recovers (see onProviderHealth)
+	if p.ai.IsHealthy() {
+		err = p.indexWithEmbeddings(ctx, job.Path, content, stat.ModTime().Unix(), stat.Size())
+	} else {
+		err = ai.ErrProviderUnhealthy
+	}
 	if err == nil {
+		p.clearFailure(job.Path)
+		return nil
+	}
+	var partial *partialEmbeddingError
+	if errors.As(err, &partial) {
+		// The embedded chunks are searchable; RetryFailed or provider recovery
+		// embed the rest
+		p.recordFailure(job.Path, database.IndexStageEmbedding, err)
 		return nil
 	}
+	// A cancelled job is neither a failure nor a reason to degrade to metadata only
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	p.recordFailure(job.Path, database.IndexStageEmbedding, err)
 
 	// If fallback disabled, return error
 	if !job.Opts.FallbackToMetadataOnly {
@@ -185,19 +259,110 @@ func (p *IndexingPipeline) processJob(job *IndexJob) error {
 			"path":  job.Path,
 			"error": err.Error(),
 		}, "Chunking failed, indexing metadata only")
+		p.recordFailure(job.Path, database.IndexStageChunking, err)
 
 		// Fallback 2: Metadata only
-		return p.repo.IndexFile(job.Path, content, stat.ModTime().Unix(), stat.Size())
+		if err := p.repo.IndexFile(job.Path, content, CountNoteStats(content), stat.ModTime().Unix(), stat.Size()); err != nil {
+			p.recordFailure(job.Path, database.IndexStageMetadata, err)
+			return err
+		}
 	}
 
 	return nil
 }
 
+// jobContext derives the context for a job from the caller's context and the
+// pipeline's own, so either a cancelled caller or Stop aborts the job.
+func (p *IndexingPipeline) jobContext(job *IndexJob) (context.Context, context.CancelFunc) {
+	if job.ctx == nil {
+		return context.WithCancel(p.baseCtx)
+	}
+	ctx, cancel := context.WithCancel(job.ctx)
+	stop := context.AfterFunc(p.baseCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// recordFailure stores an indexing failure so it can be listed and retried.
+// Missing files are not failures; their stale records are cleared instead.
+func (p *IndexingPipeline) recordFailure(path, stage string, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		p.clearFailure(path)
+		return
+	}
+	if rerr := p.repo.RecordIndexError(path, stage, err.Error()); rerr != nil {
+		logger.Debug("Failed to record index error for %s: %v", path, rerr)
+	}
+}
+
+func (p *IndexingPipeline) clearFailure(path string) {
+	if err := p.repo.ClearIndexError(path); err != nil {
+		logger.Debug("Failed to clear index error for %s: %v", path, err)
+	}
+}
+
+// onProviderHealth re-queues files whose embeddings were skipped or failed once
+// the current provider is healthy again
+func (p *IndexingPipeline) onProviderHealth(status ai.ProviderHealth) {
+	if !status.Healthy || status.Provider != p.ai.CurrentProviderName() {
+		return
+	}
+	p.mu.Lock()
+	started, ctx := p.isStarted, p.baseCtx
+	p.mu.Unlock()
+	if !started {
+		return
+	}
+	go func() {
+		failures, err := p.repo.ListIndexErrors()
+		if err != nil {
+			return
+		}
+		var paths []string
+		for _, f := range failures {
+			if f.Stage == database.IndexStageEmbedding {
+				paths = append(paths, f.Path)
+			}
+		}
+		if len(paths) == 0 {
+			return
+		}
+		logger.InfoWithFields(ctx, map[string]interface{}{
+			"provider": status.Provider,
+			"files":    len(paths),
+		}, "Embedding provider recovered, re-embedding deferred files")
+		_, _ = p.IndexAll(ctx, paths, IndexOptions{ForceReindex: true, FallbackToMetadataOnly: true})
+	}()
+}
+
+// RetryFailed re-queues every file with a recorded index error
+func (p *IndexingPipeline) RetryFailed(ctx context.Context) (*IndexProgress, error) {
+	failures, err := p.repo.ListIndexErrors()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(failures))
+	for _, f := range failures {
+		paths = append(paths, f.Path)
+	}
+	return p.IndexAll(ctx, paths, IndexOptions{
+		ForceReindex:           true,
+		FallbackToMetadataOnly: true,
+	})
+}
+
 // indexWithEmbeddings performs full indexing with AI embeddings
 func (p *IndexingPipeline) indexWithEmbeddings(ctx context.Context, path, content string, modTime, size int64) error {
 	// Process document: chunking + embeddings
-	chunks, err := p.ai.ProcessDocument(content)
-	if err != nil {
+	chunks, err := p.ai.ProcessDocument(ctx, embedsAsLinks(content))
+	var batchErr *ai.BatchError
+	if err != nil && (!errors.As(err, &batchErr) || len(batchErr.Failed) == len(chunks)) {
 		return fmt.Errorf("ProcessDocument failed: %w", err)
 	}
 
@@ -211,15 +376,29 @@ func (p *IndexingPipeline) indexWithEmbeddings(ctx context.Context, path, conten
 		chunkInputs[i] = database.ChunkInput{
 			Content:        chunk.Content,
 			Heading:        chunk.Heading,
+			Language:       chunk.Language,
 			Embedding:      chunk.Embedding,
 			EmbeddingModel: chunk.ModelName,
 		}
 	}
+	images := p.imageChunks(ctx, path, content)
+	p.embedImageChunks(ctx, images)
+	chunkInputs = append(chunkInputs, images...)
 
 	// Index file with chunks
-	if err := p.repo.IndexFileWithChunks(path, content, modTime, size, chunkInputs); err != nil {
+	if err := p.repo.IndexFileWithChunks(path, content, CountNoteStats(content), modTime, size, chunkInputs); err != nil {
 		return fmt.Errorf("IndexFileWithChunks failed: %w", err)
 	}
+	p.embedLanguageChunks(ctx, path)
+
+	if batchErr != nil {
+		logger.WarnWithFields(ctx, map[string]interface{}{
+			"path":       path,
+			"chunks":     len(chunks),
+			"unembedded": len(batchErr.Failed),
+		}, "File indexed with some chunks not embedded")
+		return &partialEmbeddingError{err: batchErr}
+	}
 
 	logger.InfoWithFields(ctx, map[string]interface{}{
 		"path":   path,
@@ -230,10 +409,22 @@ func (p *IndexingPipeline) indexWithEmbeddings(ctx context.Context, path, conten
 	return nil
 }
 
+// partialEmbeddingError means a file was indexed but some of its chunks could
+// not be embedded. The file keeps its chunks and is retried like a failure.
+type partialEmbeddingError struct {
+	err *ai.BatchError
+}
+
+func (e *partialEmbeddingError) Error() string {
+	return fmt.Sprintf("%d of %d chunks not embedded: %v", len(e.err.Failed), e.err.Total, e.err.Failed[0].Error)
+}
+
+func (e *partialEmbeddingError) Unwrap() error { return e.err }
+
 // indexWithChunking indexes file with chunks but without embeddings
 func (p *IndexingPipeline) indexWithChunking(ctx context.Context, path, content string, modTime, size int64) error {
 	// Chunk text without embeddings
-	chunks, err := p.ai.ChunkText(content)
+	chunks, err := p.ai.ChunkText(embedsAsLinks(content))
 	if err != nil {
 		return fmt.Errorf("ChunkText failed: %w", err)
 	}
@@ -246,13 +437,15 @@ func (p *IndexingPipeline) indexWithChunking(ctx context.Context, path, content
 	chunkInputs := make([]database.ChunkInput, len(chunks))
 	for i, chunk := range chunks {
 		chunkInputs[i] = database.ChunkInput{
-			Content: chunk.Content,
-			Heading: chunk.Heading,
+			Content:  chunk.Content,
+			Heading:  chunk.Heading,
+			Language: chunk.Language,
 		}
 	}
+	chunkInputs = append(chunkInputs, p.imageChunks(ctx, path, content)...)
 
 	// Index file with chunks
-	if err := p.repo.IndexFileWithChunks(path, content, modTime, size, chunkInputs); err != nil {
+	if err := p.repo.IndexFileWithChunks(path, content, CountNoteStats(content), modTime, size, chunkInputs); err != nil {
 		return fmt.Errorf("IndexFileWithChunks failed: %w", err)
 	}
 
@@ -279,6 +472,7 @@ func (p *IndexingPipeline) Enqueue(path, content string, opts IndexOptions) {
 		Content: content,
 		Opts:    opts,
 	}
+	p.persistJob(job)
 
 	if err := p.safeEnqueueNonBlocking(job); err != nil {
 		if errors.Is(err, errPipelineStopped) {
@@ -287,7 +481,7 @@ func (p *IndexingPipeline) Enqueue(path, content string, opts IndexOptions) {
 		}
 		logger.WarnWithFields(context.Background(), map[string]interface{}{
 			"path": path,
-		}, "Indexing queue full, dropping job")
+		}, "Indexing queue full, job kept in persistent queue")
 	}
 }
 
@@ -304,7 +498,9 @@ func (p *IndexingPipeline) IndexFile(ctx context.Context, path string, opts Inde
 		Path:    path,
 		Opts:    opts,
 		ErrChan: make(chan error, 1),
+		ctx:     ctx,
 	}
+	p.persistJob(job)
 
 	if err := p.safeEnqueueWithTimeout(ctx, job, 5*time.Second); err != nil {
 		return err
@@ -334,7 +530,9 @@ func (p *IndexingPipeline) IndexContent(ctx context.Context, path, content strin
 		Content: content,
 		Opts:    opts,
 		ErrChan: make(chan error, 1),
+		ctx:     ctx,
 	}
+	p.persistJob(job)
 
 	if err := p.safeEnqueueWithTimeout(ctx, job, 5*time.Second); err != nil {
 		return err
@@ -394,7 +592,9 @@ func (p *IndexingPipeline) IndexAll(ctx context.Context, paths []string, opts In
 					Path:    filePath,
 					Opts:    opts,
 					ErrChan: make(chan error, 1),
+					ctx:     ctx,
 				}
+				p.persistJob(job)
 
 				if err := p.safeEnqueueWithTimeout(ctx, job, 5*time.Second); err != nil {
 					if !errors.Is(err, context.Canceled) {
@@ -418,6 +618,79 @@ func (p *IndexingPipeline) IndexAll(ctx context.Context, paths []string, opts In
 	return progress, nil
 }
 
+// persistJob records the job in the persistent queue so it survives a restart.
+// Persistence is best-effort: indexing proceeds even if the write fails.
+func (p *IndexingPipeline) persistJob(job *IndexJob) {
+	if p.repo == nil {
+		return
+	}
+	enqueuedAt := time.Now().UnixNano()
+	err := p.repo.SaveIndexJob(database.IndexJobRecord{
+		Path:                   job.Path,
+		SkipIfUnchanged:        job.Opts.SkipIfUnchanged,
+		FallbackToMetadataOnly: job.Opts.FallbackToMetadataOnly,
+		ForceReindex:           job.Opts.ForceReindex,
+		EnqueuedAt:             enqueuedAt,
+	})
+	if err != nil {
+		logger.Debug("Failed to persist index job for %s: %v", job.Path, err)
+		return
+	}
+	job.enqueuedAt = enqueuedAt
+}
+
+// Resume requeues jobs left in the persistent queue by a previous run, including
+// jobs that were in flight when the app exited. It returns the number of jobs resumed.
+func (p *IndexingPipeline) Resume(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	started := p.isStarted
+	p.mu.Unlock()
+	if !started {
+		return 0, errPipelineStopped
+	}
+
+	requeued, err := p.repo.RequeueRunningIndexJobs()
+	if err != nil {
+		return 0, fmt.Errorf("requeue running jobs: %w", err)
+	}
+	records, err := p.repo.ListPendingIndexJobs()
+	if err != nil {
+		return 0, fmt.Errorf("list pending jobs: %w", err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	logger.InfoWithFields(ctx, map[string]interface{}{
+		"pending":   len(records),
+		"in_flight": requeued,
+	}, "Resuming persisted indexing jobs")
+
+	go func() {
+		for _, rec := range records {
+			job := &IndexJob{
+				Path: rec.Path,
+				Opts: IndexOptions{
+					SkipIfUnchanged:        rec.SkipIfUnchanged,
+					FallbackToMetadataOnly: rec.FallbackToMetadataOnly,
+					ForceReindex:           rec.ForceReindex,
+				},
+				enqueuedAt: rec.EnqueuedAt,
+				ctx:        ctx,
+			}
+			if err := p.safeEnqueueWithTimeout(ctx, job, 30*time.Second); err != nil {
+				logger.WarnWithFields(ctx, map[string]interface{}{
+					"path":  rec.Path,
+					"error": err.Error(),
+				}, "Stopped resuming persisted indexing jobs")
+				return
+			}
+		}
+	}()
+
+	return len(records), nil
+}
+
 func (p *IndexingPipeline) safeEnqueueNonBlocking(job *IndexJob) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -459,6 +732,11 @@ func (p *IndexingPipeline) Stop() {
 		return
 	}
 
+	if p.stopHealthWatch != nil {
+		p.stopHealthWatch()
+		p.stopHealthWatch = nil
+	}
+	p.cancel()
 	close(p.workQueue)
 	p.isStarted = false
 
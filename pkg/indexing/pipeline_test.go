@@ -136,3 +136,219 @@ func TestIndexingPipeline_ConcurrentSamePath(t *testing.T) {
 		t.Fatalf("expected no reindex needed after successful concurrent indexing")
 	}
 }
+
+func TestIndexingPipeline_RecordsEmbeddingFailures(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	database.Reset()
+	dbManager := database.GetInstance()
+	if err := dbManager.Init(tmpDir); err != nil {
+		t.Fatalf("database init failed: %v", err)
+	}
+	defer func() {
+		_ = dbManager.Close()
+		database.Reset()
+	}()
+
+	fm := files.NewManager()
+	if err := fm.SetBasePath(tmpDir); err != nil {
+		t.Fatalf("set base path failed: %v", err)
+	}
+	path := "broken.md"
+	if err := os.WriteFile(filepath.Join(tmpDir, path), []byte("# Broken\n\nsome text"), 0644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.SetOllamaConfig(server.URL, "nomic-embed-text", 3)
+	cfg.SetProvider("ollama")
+	cfg.SetEmbeddingModel("nomic-embed-text")
+	aiService := ai.NewService(cfg)
+	_ = aiService.Initialize()
+
+	pipeline := NewPipeline(aiService, dbManager.Repository(), fm)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := pipeline.IndexFile(ctx, path, IndexOptions{FallbackToMetadataOnly: true}); err != nil {
+		t.Fatalf("fallback indexing should succeed: %v", err)
+	}
+
+	repo := pipeline.Repository()
+	failures, err := repo.ListIndexErrors()
+	if err != nil {
+		t.Fatalf("ListIndexErrors failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Path != path || failures[0].Stage != database.IndexStageEmbedding {
+		t.Fatalf("expected one embedding failure for %s, got %+v", path, failures)
+	}
+	stats, err := repo.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats["failed"] != 1 {
+		t.Fatalf("expected failed count 1, got %d", stats["failed"])
+	}
+	if file, err := repo.GetFileByPath(path); err != nil || file.Created == 0 {
+		t.Fatalf("creation time not recorded: %+v, %v", file, err)
+	}
+}
+
+func TestIndexingPipeline_QuickReconcileDetectsDrift(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database.Reset()
+	dbManager := database.GetInstance()
+	if err := dbManager.Init(tmpDir); err != nil {
+		t.Fatalf("database init failed: %v", err)
+	}
+	defer func() {
+		_ = dbManager.Close()
+		database.Reset()
+	}()
+
+	fm := files.NewManager()
+	if err := fm.SetBasePath(tmpDir); err != nil {
+		t.Fatalf("set base path failed: %v", err)
+	}
+	write := func(path, content string) os.FileInfo {
+		t.Helper()
+		full := filepath.Join(tmpDir, path)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s failed: %v", path, err)
+		}
+		info, _ := os.Stat(full)
+		return info
+	}
+
+	repo := dbManager.Repository()
+	kept := write("kept.md", "# Kept")
+	if err := repo.IndexFile("kept.md", "# Kept", database.NoteStats{}, kept.ModTime().Unix(), kept.Size()); err != nil {
+		t.Fatal(err)
+	}
+	changed := write("changed.md", "# Changed")
+	if err := repo.IndexFile("changed.md", "# Old", database.NoteStats{}, changed.ModTime().Unix()-60, changed.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.IndexFile("ghost.md", "# Ghost", database.NoteStats{}, 1, 7); err != nil {
+		t.Fatal(err)
+	}
+	write("new.md", "# New")
+
+	pipeline := NewPipeline(ai.NewService(config.New()), repo, fm)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	result, err := pipeline.Reconcile(context.Background(), ReconcileOptions{Quick: true})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	report := result.Drift
+	if report.Missing != 1 || report.Changed != 1 || report.Deleted != 1 || report.Removed != 1 || report.Queued != 2 {
+		t.Fatalf("unexpected drift report: %+v", report)
+	}
+	if f, _ := repo.GetFileByPath("ghost.md"); f != nil {
+		t.Fatal("ghost entry should have been removed")
+	}
+	if pipeline.LastDriftReport() != report {
+		t.Fatal("LastDriftReport should return the latest report")
+	}
+}
+
+func TestIndexingPipeline_StoresPartiallyEmbeddedFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(req.Input, "poison") {
+			http.Error(w, `{"error":"input rejected"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float32{1, 0.5, 0.25}, "model": req.Model})
+	}))
+	defer server.Close()
+
+	database.Reset()
+	dbManager := database.GetInstance()
+	if err := dbManager.Init(tmpDir); err != nil {
+		t.Fatalf("database init failed: %v", err)
+	}
+	defer func() {
+		_ = dbManager.Close()
+		database.Reset()
+	}()
+
+	fm := files.NewManager()
+	if err := fm.SetBasePath(tmpDir); err != nil {
+		t.Fatalf("set base path failed: %v", err)
+	}
+	path := "partial.md"
+	content := "# Good\n\n" + strings.Repeat("alpha beta gamma ", 180) + "\n\n# Bad\n\n" + strings.Repeat("poison delta ", 240)
+	if err := os.WriteFile(filepath.Join(tmpDir, path), []byte(content), 0644); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.SetOllamaConfig(server.URL, "nomic-embed-text", 3)
+	cfg.SetProvider("ollama")
+	cfg.SetEmbeddingModel("nomic-embed-text")
+	aiService := ai.NewService(cfg)
+	if err := aiService.Initialize(); err != nil {
+		t.Fatalf("ai initialize failed: %v", err)
+	}
+
+	pipeline := NewPipeline(aiService, dbManager.Repository(), fm)
+	pipeline.Start()
+	defer pipeline.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := pipeline.IndexFile(ctx, path, IndexOptions{FallbackToMetadataOnly: true}); err != nil {
+		t.Fatalf("partial indexing should succeed: %v", err)
+	}
+
+	repo := pipeline.Repository()
+	file, err := repo.GetFileByPath(path)
+	if err != nil {
+		t.Fatalf("GetFileByPath failed: %v", err)
+	}
+	chunks, err := repo.GetChunksByFileID(file.ID)
+	if err != nil {
+		t.Fatalf("GetChunksByFileID failed: %v", err)
+	}
+	embedded, missing := 0, 0
+	for _, c := range chunks {
+		switch {
+		case len(c.EmbeddingBlob) > 0:
+			embedded++
+		case strings.Contains(c.Content, "poison"):
+			missing++
+		}
+	}
+	if embedded == 0 || missing == 0 {
+		t.Fatalf("expected embedded and unembedded chunks, got %d embedded, %d missing of %d", embedded, missing, len(chunks))
+	}
+
+	failures, err := repo.ListIndexErrors()
+	if err != nil {
+		t.Fatalf("ListIndexErrors failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Stage != database.IndexStageEmbedding {
+		t.Fatalf("expected an embedding failure to retry, got %+v", failures)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build darwin
+
+package indexing
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// onBatteryPower reports whether the machine is running on battery
+func onBatteryPower() (bool, error) {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), "'Battery Power'"), nil
+}
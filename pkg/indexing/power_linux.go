@@ -0,0 +1,31 @@
+//go:build linux
+
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// onBatteryPower reports whether the machine is running on battery. Machines
+// without a mains power supply entry (desktops, servers) are treated as on AC.
+func onBatteryPower() (bool, error) {
+	supplies, err := filepath.Glob("/sys/class/power_supply/*")
+	if err != nil {
+		return false, err
+	}
+	sawMains := false
+	for _, dir := range supplies {
+		kind, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil || strings.TrimSpace(string(kind)) != "Mains" {
+			continue
+		}
+		sawMains = true
+		online, err := os.ReadFile(filepath.Join(dir, "online"))
+		if err == nil && strings.TrimSpace(string(online)) == "1" {
+			return false, nil
+		}
+	}
+	return sawMains, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package indexing
+
+// onBatteryPower is not supported on this platform; the machine is assumed to be on AC
+func onBatteryPower() (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package indexing
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+)
+
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// onBatteryPower reports whether the machine is running on battery
+func onBatteryPower() (bool, error) {
+	var status systemPowerStatus
+	ret, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status)))
+	if ret == 0 {
+		return false, err
+	}
+	return status.ACLineStatus == 0, nil
+}
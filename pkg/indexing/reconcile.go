@@ -0,0 +1,146 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+// ReconcileOptions adjusts a Reconcile run
+type ReconcileOptions struct {
+	// Quick only compares paths, mtimes and sizes and returns once the files
+	// to index are queued, without looking for missing embeddings or waiting
+	// for indexing, so it is cheap enough to run every time a vault opens
+	Quick bool
+}
+
+// ReconcileResult summarizes a reconcile run
+type ReconcileResult struct {
+	Drift    *DriftReport `json:"drift"`    // How the index differed from the vault
+	Repaired int          `json:"repaired"` // indexed files with missing or partial embeddings
+	Indexed  int64        `json:"indexed"`
+	Failed   int64        `json:"failed"`
+}
+
+// Reconcile brings the index in line with the vault: it drops entries for files that
+// no longer exist, indexes files that were never indexed or whose mtime or size
+// changed, and re-embeds files whose chunks are missing embeddings. Unless
+// opts.Quick is set it blocks until all queued work has finished.
+func (p *IndexingPipeline) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileResult, error) {
+	start := time.Now()
+	basePath := p.fm.GetBasePath()
+	if basePath == "" {
+		return nil, fmt.Errorf("no base path set")
+	}
+
+	onDisk, err := p.fm.ListMarkdownFiles("")
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	indexed, err := p.repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+
+	report := &DriftReport{OnDisk: len(onDisk), Indexed: len(indexed)}
+	result := &ReconcileResult{Drift: report}
+	type fileState struct{ modTime, size int64 }
+	indexState := make(map[string]fileState, len(indexed))
+	for _, f := range indexed {
+		indexState[f.Path] = fileState{modTime: f.LastModified, size: f.FileSize}
+	}
+
+	queued := make(map[string]struct{})
+	diskSet := make(map[string]struct{}, len(onDisk))
+	var toIndex []string
+	for _, path := range onDisk {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		diskSet[path] = struct{}{}
+		state, ok := indexState[path]
+		if !ok {
+			report.Missing++
+			report.MissingPaths = appendSample(report.MissingPaths, path)
+			toIndex = append(toIndex, path)
+			queued[path] = struct{}{}
+			continue
+		}
+		info, err := os.Stat(filepath.Join(basePath, filepath.FromSlash(path)))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Unix() != state.modTime || info.Size() != state.size {
+			report.Changed++
+			report.ChangedPaths = appendSample(report.ChangedPaths, path)
+			toIndex = append(toIndex, path)
+			queued[path] = struct{}{}
+		}
+	}
+
+	for _, f := range indexed {
+		if _, ok := diskSet[f.Path]; ok {
+			continue
+		}
+		report.Deleted++
+		report.DeletedPaths = appendSample(report.DeletedPaths, f.Path)
+		if err := p.repo.DeleteFile(f.Path); err != nil {
+			logger.Warn("Reconcile: failed to remove stale index entry %s: %v", f.Path, err)
+			continue
+		}
+		p.clearFailure(f.Path)
+		report.Removed++
+	}
+
+	if !opts.Quick {
+		needsEmbedding, err := p.repo.ListFilesNeedingEmbeddings()
+		if err != nil {
+			return nil, fmt.Errorf("list files needing embeddings: %w", err)
+		}
+		for _, path := range needsEmbedding {
+			if _, ok := diskSet[path]; !ok {
+				continue
+			}
+			if _, ok := queued[path]; ok {
+				continue
+			}
+			toIndex = append(toIndex, path)
+			result.Repaired++
+		}
+	}
+
+	defer func() {
+		report.CheckedAt = time.Now().UnixMilli()
+		report.DurationMS = time.Since(start).Milliseconds()
+		p.mu.Lock()
+		p.lastDrift = report
+		p.mu.Unlock()
+	}()
+	if len(toIndex) == 0 {
+		return result, nil
+	}
+
+	progress, err := p.IndexAll(ctx, toIndex, IndexOptions{
+		SkipIfUnchanged:        true,
+		FallbackToMetadataOnly: true,
+	})
+	if err != nil {
+		return result, fmt.Errorf("queue files: %w", err)
+	}
+	report.Queued = len(toIndex)
+	if opts.Quick {
+		return result, nil
+	}
+	select {
+	case <-progress.Done:
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+	result.Indexed = progress.Processed.Load() - progress.Errors.Load()
+	result.Failed = progress.Errors.Load()
+	return result, nil
+}
@@ -0,0 +1,283 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+const (
+	scheduleCheckInterval = time.Minute
+	scheduleStateFile     = "index_schedule_last_run"
+)
+
+// ScheduleStatus reports the state of the maintenance scheduler
+type ScheduleStatus struct {
+	Enabled    bool             `json:"enabled"`
+	Running    bool             `json:"running"`
+	NextRun    int64            `json:"next_run"`    // Unix ms, 0 when disabled
+	LastRun    int64            `json:"last_run"`    // Unix ms, 0 when never run
+	LastResult *ReconcileResult `json:"last_result"` // nil until the first run completes
+	LastError  string           `json:"last_error,omitempty"`
+	Deferred   string           `json:"deferred,omitempty"` // why a due run is waiting
+}
+
+// Scheduler runs Reconcile once a day at the configured time, optionally only when
+// the user is idle and the machine is on AC power.
+type Scheduler struct {
+	pipeline *IndexingPipeline
+
+	mu           sync.Mutex
+	cfg          config.IndexScheduleConfig
+	lastActivity time.Time
+	lastRun      time.Time
+	lastResult   *ReconcileResult
+	lastErr      string
+	deferred     string
+	running      bool
+	cancel       context.CancelFunc
+	stopCh       chan struct{}
+	doneCh       chan struct{}
+
+	now       func() time.Time
+	onBattery func() (bool, error)
+}
+
+// NewScheduler creates a scheduler for the given pipeline
+func NewScheduler(pipeline *IndexingPipeline) *Scheduler {
+	return &Scheduler{
+		pipeline:     pipeline,
+		lastActivity: time.Now(),
+		now:          time.Now,
+		onBattery:    onBatteryPower,
+	}
+}
+
+// Start begins checking the schedule. Calling Start again applies a new config.
+func (s *Scheduler) Start(cfg config.IndexScheduleConfig) {
+	s.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.lastRun = s.loadLastRun()
+	if !cfg.Enabled {
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go s.loop(s.stopCh, s.doneCh)
+}
+
+// Stop halts the scheduler and cancels a run in progress
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh, cancel := s.stopCh, s.doneCh, s.cancel
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if stopCh != nil {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// NotifyActivity records user activity for the idle guard
+func (s *Scheduler) NotifyActivity() {
+	s.mu.Lock()
+	s.lastActivity = s.now()
+	s.mu.Unlock()
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *Scheduler) Status() ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := ScheduleStatus{
+		Enabled:    s.cfg.Enabled,
+		Running:    s.running,
+		LastResult: s.lastResult,
+		LastError:  s.lastErr,
+		Deferred:   s.deferred,
+	}
+	if !s.lastRun.IsZero() {
+		status.LastRun = s.lastRun.UnixMilli()
+	}
+	if s.cfg.Enabled {
+		if next, err := nextScheduledRun(s.cfg.Time, s.lastRun, s.now()); err == nil {
+			status.NextRun = next.UnixMilli()
+		}
+	}
+	return status
+}
+
+// RunNow runs the maintenance pass immediately, ignoring the idle and power guards
+func (s *Scheduler) RunNow(ctx context.Context) (*ReconcileResult, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("scheduled reindex already running")
+	}
+	s.running = true
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	timer := logger.StartTimer()
+	result, err := s.pipeline.Reconcile(ctx, ReconcileOptions{})
+	cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.cancel = nil
+	s.deferred = ""
+	s.lastRun = s.now()
+	s.saveLastRun(s.lastRun)
+	if err != nil {
+		s.lastErr = err.Error()
+		return result, err
+	}
+	s.lastErr = ""
+	s.lastResult = result
+	logger.InfoWithFields(ctx, map[string]interface{}{
+		"removed":     result.Drift.Removed,
+		"added":       result.Drift.Missing,
+		"changed":     result.Drift.Changed,
+		"repaired":    result.Repaired,
+		"failed":      result.Failed,
+		"duration_ms": timer().Milliseconds(),
+	}, "Scheduled reindex completed")
+	return result, nil
+}
+
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer logger.RecoverPanic()
+	defer close(done)
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.shouldRun() {
+				_, _ = s.RunNow(context.Background())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// shouldRun reports whether a run is due and all guards pass
+func (s *Scheduler) shouldRun() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running || !s.cfg.Enabled {
+		return false
+	}
+	now := s.now()
+	next, err := nextScheduledRun(s.cfg.Time, s.lastRun, now)
+	if err != nil || now.Before(next) {
+		return false
+	}
+	if s.cfg.OnlyWhenIdle {
+		idle := time.Duration(s.cfg.IdleMinutes) * time.Minute
+		if idle <= 0 {
+			idle = 10 * time.Minute
+		}
+		if now.Sub(s.lastActivity) < idle {
+			s.deferred = "waiting for idle"
+			return false
+		}
+	}
+	if s.cfg.OnlyOnACPower && s.onBattery != nil {
+		if battery, err := s.onBattery(); err == nil && battery {
+			s.deferred = "waiting for AC power"
+			return false
+		}
+	}
+	return true
+}
+
+// nextScheduledRun returns the next run time given the last run. The result may be
+// in the past, which means a run is due (e.g. a slot was missed while the app was closed).
+func nextScheduledRun(hhmm string, lastRun, now time.Time) (time.Time, error) {
+	hour, minute, err := parseClock(hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if lastRun.IsZero() {
+		return today, nil
+	}
+	// Most recent slot that is not in the future
+	slot := today
+	if now.Before(slot) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if lastRun.Before(slot) {
+		return slot, nil
+	}
+	return slot.AddDate(0, 0, 1), nil
+}
+
+func parseClock(hhmm string) (int, int, error) {
+	parts := strings.Split(strings.TrimSpace(hhmm), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid schedule time %q, expected HH:MM", hhmm)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("invalid schedule hour %q", parts[0])
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid schedule minute %q", parts[1])
+	}
+	return hour, minute, nil
+}
+
+func (s *Scheduler) stateFilePath() string {
+	basePath := s.pipeline.fm.GetBasePath()
+	if basePath == "" {
+		return ""
+	}
+	return filepath.Join(basePath, "data", scheduleStateFile)
+}
+
+func (s *Scheduler) loadLastRun() time.Time {
+	path := s.stateFilePath()
+	if path == "" {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func (s *Scheduler) saveLastRun(t time.Time) {
+	path := s.stateFilePath()
+	if path == "" {
+		return
+	}
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(t.UnixMilli(), 10)), 0644); err != nil {
+		logger.Warn("Failed to persist scheduled reindex state: %v", err)
+	}
+}
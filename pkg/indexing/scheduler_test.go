@@ -0,0 +1,39 @@
+package indexing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextScheduledRun(t *testing.T) {
+	loc := time.Local
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2025, 3, day, hour, min, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name    string
+		lastRun time.Time
+		now     time.Time
+		want    time.Time
+	}{
+		{"never run, before slot", time.Time{}, at(10, 1, 0), at(10, 3, 0)},
+		{"never run, after slot is due", time.Time{}, at(10, 5, 0), at(10, 3, 0)},
+		{"ran today", at(10, 3, 1), at(10, 9, 0), at(11, 3, 0)},
+		{"ran yesterday, before slot", at(9, 3, 5), at(10, 2, 0), at(10, 3, 0)},
+		{"missed yesterday's slot", at(8, 3, 5), at(10, 2, 0), at(9, 3, 0)},
+	}
+	for _, tc := range cases {
+		got, err := nextScheduledRun("03:00", tc.lastRun, tc.now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := nextScheduledRun("25:00", time.Time{}, at(10, 1, 0)); err == nil {
+		t.Fatalf("expected invalid time to fail")
+	}
+}
@@ -0,0 +1,285 @@
+// Package jobs runs long operations (imports, exports, reindexing) in the
+// background with progress reporting, cancellation and result retrieval, so
+// bound App methods can return a job ID instead of blocking the UI.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"notebit/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Job states
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Events emitted for job lifecycle changes; the payload is the Job snapshot
+const (
+	EventStarted  = "job:started"
+	EventProgress = "job:progress"
+	EventFinished = "job:finished"
+)
+
+const (
+	// progressInterval throttles progress events per job
+	progressInterval = 200 * time.Millisecond
+
+	// maxFinishedJobs bounds how many finished jobs are kept for result retrieval
+	maxFinishedJobs = 50
+)
+
+// Job is a snapshot of a background operation
+type Job struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`
+	Title      string `json:"title"`
+	State      string `json:"state"`
+	Done       int    `json:"done"`
+	Total      int    `json:"total"` // 0 when unknown
+	Message    string `json:"message,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Result     any    `json:"result,omitempty"`
+	StartedAt  int64  `json:"started_at"` // Unix ms
+	FinishedAt int64  `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has stopped running
+func (j Job) Finished() bool {
+	return j.State != StateRunning
+}
+
+// Reporter updates a running job's progress
+type Reporter func(done, total int, message string)
+
+// Func is the body of a job. It should return promptly once ctx is cancelled.
+type Func func(ctx context.Context, report Reporter) (any, error)
+
+// Emitter receives lifecycle events
+type Emitter func(event string, job Job)
+
+type entry struct {
+	job      Job
+	cancel   context.CancelFunc
+	lastEmit time.Time
+	done     chan struct{}
+}
+
+// Manager tracks running and recently finished jobs
+type Manager struct {
+	mu   sync.Mutex
+	jobs map[string]*entry
+	emit Emitter
+}
+
+// NewManager creates a job manager; emit may be nil
+func NewManager(emit Emitter) *Manager {
+	return &Manager{jobs: make(map[string]*entry), emit: emit}
+}
+
+// Start runs fn in the background and returns the job ID
+func (m *Manager) Start(kind, title string, fn Func) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: Job{
+			ID:        uuid.NewString(),
+			Kind:      kind,
+			Title:     title,
+			State:     StateRunning,
+			StartedAt: time.Now().UnixMilli(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.jobs[e.job.ID] = e
+	snapshot := e.job
+	m.mu.Unlock()
+	m.fire(EventStarted, snapshot)
+
+	go m.run(ctx, e, fn)
+	return snapshot.ID
+}
+
+func (m *Manager) run(ctx context.Context, e *entry, fn Func) {
+	defer close(e.done)
+	defer e.cancel()
+
+	result, err := m.call(ctx, e, fn)
+
+	m.mu.Lock()
+	e.job.FinishedAt = time.Now().UnixMilli()
+	switch {
+	case err == nil:
+		e.job.State = StateSucceeded
+		e.job.Result = result
+		if e.job.Total > 0 {
+			e.job.Done = e.job.Total
+		}
+	case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+		e.job.State = StateCancelled
+		e.job.Result = result // partial result, if any
+	default:
+		e.job.State = StateFailed
+		e.job.Error = err.Error()
+		e.job.Result = result
+	}
+	snapshot := e.job
+	m.pruneLocked()
+	m.mu.Unlock()
+	m.fire(EventFinished, snapshot)
+}
+
+// call runs fn, turning a panic into a job failure. The panic is still
+// reported so it leaves a crash report behind.
+func (m *Manager) call(ctx context.Context, e *entry, fn Func) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ReportPanic(r, debug.Stack())
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx, func(done, total int, message string) { m.report(e, done, total, message) })
+}
+
+func (m *Manager) report(e *entry, done, total int, message string) {
+	m.mu.Lock()
+	if e.job.State != StateRunning {
+		m.mu.Unlock()
+		return
+	}
+	e.job.Done = done
+	e.job.Total = total
+	if message != "" {
+		e.job.Message = message
+	}
+	now := time.Now()
+	emit := now.Sub(e.lastEmit) >= progressInterval || (total > 0 && done >= total)
+	if emit {
+		e.lastEmit = now
+	}
+	snapshot := e.job
+	m.mu.Unlock()
+	if emit {
+		m.fire(EventProgress, snapshot)
+	}
+}
+
+func (m *Manager) fire(event string, job Job) {
+	if m.emit != nil {
+		m.emit(event, job)
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs
+func (m *Manager) pruneLocked() {
+	var finished []*entry
+	for _, e := range m.jobs {
+		if e.job.Finished() {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].job.FinishedAt < finished[j].job.FinishedAt })
+	for _, e := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, e.job.ID)
+	}
+}
+
+// Get returns a snapshot of the job, including its result once finished
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	return e.job, nil
+}
+
+// List returns all known jobs, newest first. Results are omitted; use Get.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		j := e.job
+		j.Result = nil
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt > out[j].StartedAt })
+	return out
+}
+
+// Cancel requests cancellation of a running job
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	e.cancel()
+	return nil
+}
+
+// Wait blocks until the job finishes or ctx is done
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	select {
+	case <-e.done:
+		return m.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// CancelAll cancels all running jobs and waits until they have returned or
+// ctx is done, e.g. before closing what they work on
+func (m *Manager) CancelAll(ctx context.Context) error {
+	m.mu.Lock()
+	var running []*entry
+	for _, e := range m.jobs {
+		if !e.job.Finished() {
+			e.cancel()
+			running = append(running, e)
+		}
+	}
+	m.mu.Unlock()
+	for _, e := range running {
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Shutdown cancels all running jobs
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.jobs {
+		e.cancel()
+	}
+}
@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"notebit/pkg/logger"
+)
+
+type recordedEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordedEvents) emit(event string, job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func waitJob(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestJobReportsProgressAndResult(t *testing.T) {
+	rec := &recordedEvents{}
+	m := NewManager(rec.emit)
+
+	id := m.Start("export", "Export vault", func(ctx context.Context, report Reporter) (any, error) {
+		for i := 1; i <= 3; i++ {
+			report(i, 3, "file")
+		}
+		return "out.zip", nil
+	})
+	job := waitJob(t, m, id)
+	if job.State != StateSucceeded || job.Result != "out.zip" || job.Done != 3 || job.Total != 3 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.events) < 3 || rec.events[0] != EventStarted || rec.events[len(rec.events)-1] != EventFinished {
+		t.Fatalf("unexpected events: %v", rec.events)
+	}
+}
+
+func TestJobCancelAndFailure(t *testing.T) {
+	m := NewManager(nil)
+
+	started := make(chan struct{})
+	id := m.Start("reindex", "Reindex", func(ctx context.Context, report Reporter) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return 7, ctx.Err()
+	})
+	<-started
+	if err := m.Cancel(id); err != nil {
+		t.Fatal(err)
+	}
+	job := waitJob(t, m, id)
+	if job.State != StateCancelled || job.Result != 7 {
+		t.Fatalf("expected cancelled job with partial result, got %+v", job)
+	}
+
+	failID := m.Start("import", "Import", func(ctx context.Context, report Reporter) (any, error) {
+		return nil, errors.New("bad archive")
+	})
+	if job := waitJob(t, m, failID); job.State != StateFailed || job.Error != "bad archive" {
+		t.Fatalf("expected failed job, got %+v", job)
+	}
+
+	panicID := m.Start("import", "Import", func(ctx context.Context, report Reporter) (any, error) {
+		panic("boom")
+	})
+	if job := waitJob(t, m, panicID); job.State != StateFailed {
+		t.Fatalf("expected panic to fail the job, got %+v", job)
+	}
+
+	if jobs := m.List(); len(jobs) != 3 {
+		t.Fatalf("expected 3 listed jobs, got %d", len(jobs))
+	}
+}
+
+func TestJobPanicIsReported(t *testing.T) {
+	reported := make(chan any, 1)
+	logger.SetPanicHandler(func(r any, stack []byte) { reported <- r })
+	defer logger.SetPanicHandler(nil)
+
+	m := NewManager(nil)
+	id := m.Start("import", "Import", func(ctx context.Context, report Reporter) (any, error) {
+		panic("boom")
+	})
+	job := waitJob(t, m, id)
+	if job.State != StateFailed || job.Error != "job panicked: boom" {
+		t.Fatalf("expected the panic to fail the job, got %+v", job)
+	}
+	select {
+	case r := <-reported:
+		if r != "boom" {
+			t.Fatalf("reported %v, want boom", r)
+		}
+	default:
+		t.Fatal("job panic was not reported")
+	}
+}
+
+func TestCancelAllWaitsForJobs(t *testing.T) {
+	m := NewManager(nil)
+	stopped := make(chan struct{})
+	id := m.Start("reindex", "Reindex", func(ctx context.Context, report Reporter) (any, error) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // cleanup after cancellation
+		close(stopped)
+		return nil, ctx.Err()
+	})
+
+	if err := m.CancelAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("CancelAll returned before the job did")
+	}
+	if job, _ := m.Get(id); job.State != StateCancelled {
+		t.Fatalf("job state = %s, want cancelled", job.State)
+	}
+
+	m.Start("reindex", "Stuck", func(ctx context.Context, report Reporter) (any, error) {
+		time.Sleep(time.Second) // ignores cancellation
+		return nil, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.CancelAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CancelAll with a stuck job = %v, want the deadline", err)
+	}
+}
@@ -0,0 +1,488 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"notebit/pkg/logger"
+)
+
+// EditJournalDir is where edit journals live, relative to the vault root
+const EditJournalDir = "data/edit_journal"
+
+// ErrBaseChanged means the note changed on disk after its edit journal was started,
+// so the journaled deltas no longer apply to the file's content.
+var ErrBaseChanged = errors.New("note changed on disk since the edit journal started")
+
+// EditDelta is one editor change: DeleteCount units starting at Offset are replaced
+// by Insert. Offsets and counts are UTF-16 code units, as reported by the editor.
+type EditDelta struct {
+	Offset      int    `json:"offset"`
+	DeleteCount int    `json:"delete_count"`
+	Insert      string `json:"insert"`
+	Timestamp   int64  `json:"timestamp,omitempty"` // Unix ms
+}
+
+// RecoveredEdit describes a journal replayed into a note on startup, or
+// folded into its base by a checkpoint
+type RecoveredEdit struct {
+	Path string `json:"path"`
+	// ConflictPath is set when the note changed on disk; the replayed text was
+	// written there instead of overwriting the note
+	ConflictPath string `json:"conflict_path,omitempty"`
+	Deltas       int    `json:"deltas"`
+}
+
+// NoteStore reads and writes notes for the edit log
+type NoteStore interface {
+	ReadNote(path string) (string, error)
+	WriteNote(path, content string) error
+}
+
+// editHeader is the first line of a journal file. It keeps a copy of the base
+// content so the deltas can be replayed even if the note is changed meanwhile.
+// A checkpoint folds the deltas into Base, while BaseHash stays the hash of
+// the note on disk when the journal started.
+type editHeader struct {
+	Path     string `json:"path"`
+	Base     string `json:"base"`
+	BaseHash string `json:"base_hash"`
+	Started  int64  `json:"started"`
+	Deltas   int    `json:"deltas,omitempty"` // Deltas folded into Base
+}
+
+type editFile struct {
+	f      *os.File
+	deltas int
+}
+
+// EditLog is an append-only, per-note log of editor deltas. Each append is synced
+// to disk and the log is periodically compacted into a new base, so a crash
+// between explicit saves loses at most the deltas the editor had not yet sent.
+// Notes are only written by Recover, on the next start; while running, the
+// editor owns its unsaved text and saves it itself.
+type EditLog struct {
+	mu    sync.Mutex
+	dir   string
+	store NoteStore
+	open  map[string]*editFile
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewEditLog creates an edit log storing journals in dir
+func NewEditLog(dir string, store NoteStore) (*EditLog, error) {
+	if store == nil {
+		return nil, fmt.Errorf("note store is nil")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &EditLog{dir: dir, store: store, open: make(map[string]*editFile)}, nil
+}
+
+// Append journals deltas for path. The first append after a save or compaction
+// records the note's current content as the base the deltas apply to.
+func (l *EditLog) Append(path string, deltas []EditDelta) error {
+	if path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ef, err := l.openLocked(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(ef.f)
+	now := time.Now().UnixMilli()
+	for _, d := range deltas {
+		if d.Timestamp == 0 {
+			d.Timestamp = now
+		}
+		line, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := ef.f.Sync(); err != nil {
+		return err
+	}
+	ef.deltas += len(deltas)
+	return nil
+}
+
+// openLocked returns the journal for path, starting a new one if needed
+func (l *EditLog) openLocked(path string) (*editFile, error) {
+	if ef, ok := l.open[path]; ok {
+		return ef, nil
+	}
+	file := l.journalPath(path)
+	if _, err := os.Stat(file); err == nil {
+		// Checkpointed, or left over from a previous run: keep appending
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		ef := &editFile{f: f}
+		l.open[path] = ef
+		return ef, nil
+	}
+
+	base, err := l.store.ReadNote(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read base: %w", err)
+	}
+	header, err := json.Marshal(editHeader{Path: path, Base: base, BaseHash: contentHash(base), Started: time.Now().UnixMilli()})
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(append(header, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	ef := &editFile{f: f}
+	l.open[path] = ef
+	return ef, nil
+}
+
+// Checkpoint folds the pending deltas for path into the base of its journal,
+// so replaying it stays cheap. The note is not written. It returns nil when
+// there is nothing pending.
+func (l *EditLog) Checkpoint(path string) (*RecoveredEdit, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkpointLocked(path)
+}
+
+func (l *EditLog) checkpointLocked(path string) (*RecoveredEdit, error) {
+	file := l.journalPath(path)
+	if ef, ok := l.open[path]; ok {
+		ef.f.Close()
+		delete(l.open, path)
+	}
+	header, deltas, err := readEditJournal(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(deltas) == 0 {
+		return nil, nil
+	}
+	if header.Base, err = ApplyEditDeltas(header.Base, deltas); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", header.Path, err)
+	}
+	header.Deltas += len(deltas)
+	if err := writeEditJournal(file, header); err != nil {
+		return nil, err
+	}
+	return &RecoveredEdit{Path: header.Path, Deltas: header.Deltas}, nil
+}
+
+// Move makes the journals of path, and of the notes under it when it is a
+// folder, follow a rename to newPath
+func (l *EditLog) Move(path, newPath string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		file := filepath.Join(l.dir, e.Name())
+		header, deltas, err := readEditJournal(file)
+		if err != nil || (header.Path != path && !strings.HasPrefix(header.Path, path+"/")) {
+			continue
+		}
+		if ef, ok := l.open[header.Path]; ok {
+			ef.f.Close()
+			delete(l.open, header.Path)
+		}
+		if header.Base, err = ApplyEditDeltas(header.Base, deltas); err != nil {
+			errs = append(errs, fmt.Errorf("replay %s: %w", header.Path, err))
+			continue
+		}
+		header.Deltas += len(deltas)
+		header.Path = newPath + strings.TrimPrefix(header.Path, path)
+		if err := writeEditJournal(l.journalPath(header.Path), header); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recoverLocked replays the journal for path into the note and removes the
+// journal. It returns nil when the journal holds no change.
+func (l *EditLog) recoverLocked(path string) (*RecoveredEdit, error) {
+	file := l.journalPath(path)
+	if ef, ok := l.open[path]; ok {
+		ef.f.Close()
+		delete(l.open, path)
+	}
+	header, deltas, err := readEditJournal(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := ApplyEditDeltas(header.Base, deltas)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", header.Path, err)
+	}
+	if contentHash(content) == header.BaseHash {
+		return nil, os.Remove(file)
+	}
+
+	current, err := l.store.ReadNote(header.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	result := &RecoveredEdit{Path: header.Path, Deltas: header.Deltas + len(deltas)}
+	target := header.Path
+	if contentHash(current) != header.BaseHash {
+		// Someone else changed the note; keep their version and write ours beside it
+		target = conflictPath(header.Path, time.Now())
+		result.ConflictPath = target
+		logger.Warn("Edit journal for %s: %v, writing %s", header.Path, ErrBaseChanged, target)
+	}
+	if err := l.store.WriteNote(target, content); err != nil {
+		return nil, err
+	}
+	return result, os.Remove(file)
+}
+
+// Recover replays every journal on disk, as left by a crash or an unsaved
+// editor, into its note. It is meant for startup, before the editor opens
+// notes. Journals that fail to replay are kept and reported through the error.
+func (l *EditLog) Recover() ([]RecoveredEdit, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var recovered []RecoveredEdit
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		header, _, err := readEditJournal(filepath.Join(l.dir, e.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		r, err := l.recoverLocked(header.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r != nil {
+			recovered = append(recovered, *r)
+		}
+	}
+	return recovered, errors.Join(errs...)
+}
+
+// Discard drops the journal for path, e.g. after the editor saved it explicitly
+func (l *EditLog) Discard(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ef, ok := l.open[path]; ok {
+		ef.f.Close()
+		delete(l.open, path)
+	}
+	err := os.Remove(l.journalPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Start checkpoints active journals every interval until Close
+func (l *EditLog) Start(interval time.Duration) {
+	l.mu.Lock()
+	if l.stop != nil {
+		l.mu.Unlock()
+		return
+	}
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	stop, done := l.stop, l.done
+	l.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				l.compactActive()
+			}
+		}
+	}()
+}
+
+// compactActive checkpoints journals that received deltas since the last one
+func (l *EditLog) compactActive() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for path, ef := range l.open {
+		if ef.deltas == 0 {
+			continue
+		}
+		if _, err := l.checkpointLocked(path); err != nil {
+			logger.Warn("Edit journal compaction failed for %s: %v", path, err)
+		}
+	}
+}
+
+// Close stops periodic compaction and checkpoints every open journal. The
+// journals stay on disk for Recover on the next start.
+func (l *EditLog) Close() error {
+	l.mu.Lock()
+	stop, done := l.stop, l.done
+	l.stop, l.done = nil, nil
+	l.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for path := range l.open {
+		if _, err := l.checkpointLocked(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *EditLog) journalPath(path string) string {
+	return filepath.Join(l.dir, contentHash(path)[:16]+".jsonl")
+}
+
+// writeEditJournal replaces file with a journal holding only header, through
+// a temp file so a crash leaves either the old or the new journal
+func writeEditJournal(file string, header editHeader) error {
+	line, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".journal-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(append(line, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// readEditJournal parses a journal file. A truncated final line, as left by a
+// crash mid-write, is ignored.
+func readEditJournal(file string) (editHeader, []EditDelta, error) {
+	var header editHeader
+	f, err := os.Open(file)
+	if err != nil {
+		return header, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		return header, nil, fmt.Errorf("empty edit journal")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Path == "" {
+		return header, nil, fmt.Errorf("invalid edit journal header")
+	}
+	var deltas []EditDelta
+	for scanner.Scan() {
+		var d EditDelta
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			break
+		}
+		deltas = append(deltas, d)
+	}
+	return header, deltas, scanner.Err()
+}
+
+// ApplyEditDeltas applies deltas in order to content
+func ApplyEditDeltas(content string, deltas []EditDelta) (string, error) {
+	doc := utf16.Encode([]rune(content))
+	for i, d := range deltas {
+		if d.Offset < 0 || d.DeleteCount < 0 || d.Offset+d.DeleteCount > len(doc) {
+			return "", fmt.Errorf("delta %d out of range (offset %d, delete %d, length %d)", i, d.Offset, d.DeleteCount, len(doc))
+		}
+		insert := utf16.Encode([]rune(d.Insert))
+		next := make([]uint16, 0, len(doc)-d.DeleteCount+len(insert))
+		next = append(next, doc[:d.Offset]...)
+		next = append(next, insert...)
+		next = append(next, doc[d.Offset+d.DeleteCount:]...)
+		doc = next
+	}
+	return string(utf16.Decode(doc)), nil
+}
+
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// conflictPath names the copy written when a journal cannot be replayed in place
+func conflictPath(path string, now time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".recovered-" + now.Format("20060102-150405") + ext
+}
@@ -0,0 +1,214 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memNotes struct {
+	files map[string]string
+}
+
+func (m *memNotes) ReadNote(path string) (string, error) {
+	content, ok := m.files[path]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return content, nil
+}
+
+func (m *memNotes) WriteNote(path, content string) error {
+	m.files[path] = content
+	return nil
+}
+
+func TestApplyEditDeltasUsesUTF16Offsets(t *testing.T) {
+	// "😀" is two UTF-16 code units
+	got, err := ApplyEditDeltas("a😀b", []EditDelta{
+		{Offset: 3, DeleteCount: 1, Insert: "c"},
+		{Offset: 1, DeleteCount: 2, Insert: "é"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "aéc" {
+		t.Fatalf("expected %q, got %q", "aéc", got)
+	}
+	if _, err := ApplyEditDeltas("ab", []EditDelta{{Offset: 2, DeleteCount: 1}}); err == nil {
+		t.Fatal("expected out of range delta to fail")
+	}
+}
+
+func TestEditLogRecoversAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	notes := &memNotes{files: map[string]string{"note.md": "Hello"}}
+
+	log, err := NewEditLog(dir, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append("note.md", []EditDelta{{Offset: 5, Insert: ", world"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append("note.md", []EditDelta{{Offset: 12, Insert: "!"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a crash mid-write: a torn final line must be ignored
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one journal file, got %d", len(entries))
+	}
+	f, err := os.OpenFile(filepath.Join(dir, entries[0].Name()), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"offset":13,"ins`)
+	f.Close()
+
+	// A new log over the same directory plays the role of the next app start
+	restarted, err := NewEditLog(dir, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := restarted.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].Deltas != 2 || recovered[0].ConflictPath != "" {
+		t.Fatalf("unexpected recovery result: %+v", recovered)
+	}
+	if notes.files["note.md"] != "Hello, world!" {
+		t.Fatalf("expected replayed content, got %q", notes.files["note.md"])
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected journal removed after compaction, %d left", len(entries))
+	}
+}
+
+func TestEditLogWritesConflictCopyWhenNoteChanged(t *testing.T) {
+	notes := &memNotes{files: map[string]string{"dir/note.md": "base"}}
+	log, err := NewEditLog(t.TempDir(), notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append("dir/note.md", []EditDelta{{Offset: 4, Insert: " mine"}}); err != nil {
+		t.Fatal(err)
+	}
+	notes.files["dir/note.md"] = "theirs"
+
+	recovered, err := log.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 {
+		t.Fatalf("expected one recovered note, got %+v", recovered)
+	}
+	result := recovered[0]
+	if !strings.HasPrefix(result.ConflictPath, "dir/note.recovered-") {
+		t.Fatalf("expected conflict copy, got %+v", result)
+	}
+	if notes.files["dir/note.md"] != "theirs" {
+		t.Fatalf("note changed on disk must not be overwritten, got %q", notes.files["dir/note.md"])
+	}
+	if notes.files[result.ConflictPath] != "base mine" {
+		t.Fatalf("expected replayed edits in conflict copy, got %q", notes.files[result.ConflictPath])
+	}
+}
+
+func TestEditLogDiscardDropsPendingDeltas(t *testing.T) {
+	notes := &memNotes{files: map[string]string{"a.md": "x"}}
+	log, err := NewEditLog(t.TempDir(), notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Append("a.md", []EditDelta{{Offset: 1, Insert: "y"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Discard("a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := log.Recover(); err != nil || len(r) != 0 {
+		t.Fatalf("expected nothing to compact, got %+v, %v", r, err)
+	}
+	if notes.files["a.md"] != "x" {
+		t.Fatalf("discarded deltas must not be applied, got %q", notes.files["a.md"])
+	}
+}
+
+func TestEditLogCompactionLeavesNoteAlone(t *testing.T) {
+	dir := t.TempDir()
+	notes := &memNotes{files: map[string]string{"a.md": "one"}}
+	log, err := NewEditLog(dir, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Start(5 * time.Millisecond)
+	if err := log.Append("a.md", []EditDelta{{Offset: 3, Insert: " two"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for a periodic compaction to fold the delta into the journal
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		header, deltas, err := readEditJournal(log.journalPath("a.md"))
+		if err == nil && len(deltas) == 0 && header.Base == "one two" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("journal not compacted: %+v %+v %v", header, deltas, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := log.Append("a.md", []EditDelta{{Offset: 7, Insert: " three"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if notes.files["a.md"] != "one" {
+		t.Fatalf("compaction wrote the note: %q", notes.files["a.md"])
+	}
+
+	recovered, err := log.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].Deltas != 2 || recovered[0].ConflictPath != "" {
+		t.Fatalf("unexpected recovery result: %+v", recovered)
+	}
+	if notes.files["a.md"] != "one two three" {
+		t.Fatalf("expected checkpointed and pending deltas replayed, got %q", notes.files["a.md"])
+	}
+}
+
+func TestEditLogMoveFollowsRename(t *testing.T) {
+	notes := &memNotes{files: map[string]string{"a.md": "x", "dir/b.md": "b", "dirty.md": "d"}}
+	log, err := NewEditLog(t.TempDir(), notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, insert := range map[string]string{"a.md": "1", "dir/b.md": "2", "dirty.md": "3"} {
+		if err := log.Append(path, []EditDelta{{Offset: 1, Insert: insert}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Move("a.md", "z.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Move("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	// The notes were renamed on disk by the caller
+	notes.files = map[string]string{"z.md": "x", "moved/b.md": "b", "dirty.md": "d"}
+
+	if _, err := log.Recover(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"z.md": "x1", "moved/b.md": "b2", "dirty.md": "d3"}
+	if !reflect.DeepEqual(notes.files, want) {
+		t.Fatalf("notes = %v, want %v", notes.files, want)
+	}
+}
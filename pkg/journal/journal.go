@@ -0,0 +1,181 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	OpWrite  = "write"
+	OpCreate = "create"
+	OpDelete = "delete"
+	OpRename = "rename"
+
+	// ScopeWorkspace selects the most recent operation regardless of path.
+	ScopeWorkspace = "workspace"
+
+	// maxEntries bounds the journal; older entries are pruned after each record.
+	maxEntries = 500
+)
+
+// Entry is a single reversible file operation. Operations that belong to the
+// same user action (e.g. deleting a folder) share a BatchID and are undone together.
+type Entry struct {
+	ID          string `gorm:"primaryKey;size:64" json:"id"`
+	BatchID     string `gorm:"index;size:64" json:"batch_id"`
+	Op          string `gorm:"index;size:16" json:"op"`
+	Path        string `gorm:"index;size:1024" json:"path"`
+	NewPath     string `gorm:"index;size:1024" json:"new_path,omitempty"`
+	PrevContent string `gorm:"type:text" json:"-"`
+	PrevExisted bool   `json:"prev_existed"`
+	Undone      bool   `gorm:"index" json:"undone"`
+	Timestamp   int64  `gorm:"index" json:"timestamp"`
+	CreatedAt   time.Time
+}
+
+func (Entry) TableName() string {
+	return "operation_journal"
+}
+
+// Reverter applies the inverse of journaled operations to the workspace.
+type Reverter interface {
+	WriteFile(path, content string) error
+	RemoveFile(path string) error
+	MoveFile(oldPath, newPath string) error
+}
+
+// Service records mutating file operations and reverts them on request
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a journal service backed by db
+func NewService(db *gorm.DB) (*Service, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is nil")
+	}
+	s := &Service{db: db}
+	if err := s.db.AutoMigrate(&Entry{}); err != nil {
+		return nil, err
+	}
+	if err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_operation_journal_undone_time ON operation_journal(undone, timestamp)").Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewBatchID returns an identifier for grouping several entries into one undo step
+func NewBatchID() string {
+	return uuid.NewString()
+}
+
+// Record appends an entry to the journal. An empty BatchID makes the entry its own batch.
+func (s *Service) Record(entry Entry) error {
+	if entry.Op == "" || entry.Path == "" {
+		return fmt.Errorf("journal entry requires op and path")
+	}
+	entry.ID = uuid.NewString()
+	if entry.BatchID == "" {
+		entry.BatchID = entry.ID
+	}
+	entry.Timestamp = time.Now().UnixNano()
+	entry.Undone = false
+	if err := s.db.Create(&entry).Error; err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+func (s *Service) prune() error {
+	var count int64
+	if err := s.db.Model(&Entry{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= maxEntries {
+		return nil
+	}
+	var cutoff Entry
+	if err := s.db.Order("timestamp DESC").Offset(maxEntries).First(&cutoff).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.db.Where("timestamp <= ?", cutoff.Timestamp).Delete(&Entry{}).Error
+}
+
+// ListRecent returns the most recent operations that can still be undone
+func (s *Service) ListRecent(limit int) ([]Entry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var entries []Entry
+	err := s.db.Where("undone = ?", false).Order("timestamp DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// UndoLast reverts the most recent operation batch matching scope. An empty scope or
+// ScopeWorkspace selects the latest batch overall; any other value is treated as a
+// note path (or folder prefix) and selects the latest batch touching it.
+func (s *Service) UndoLast(scope string, r Reverter) ([]Entry, error) {
+	scope = strings.Trim(strings.TrimSpace(scope), "/")
+
+	q := s.db.Where("undone = ?", false)
+	if scope != "" && scope != ScopeWorkspace {
+		prefix := scope + "/%"
+		q = q.Where("path = ? OR new_path = ? OR path LIKE ? OR new_path LIKE ?", scope, scope, prefix, prefix)
+	}
+	var latest Entry
+	if err := q.Order("timestamp DESC").First(&latest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+		return nil, err
+	}
+
+	var batch []Entry
+	if err := s.db.Where("batch_id = ? AND undone = ?", latest.BatchID, false).Order("timestamp DESC").Find(&batch).Error; err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Entry, 0, len(batch))
+	for _, entry := range batch {
+		if err := revert(entry, r); err != nil {
+			return reverted, fmt.Errorf("undo %s %s: %w", entry.Op, entry.Path, err)
+		}
+		if err := s.db.Model(&Entry{}).Where("id = ?", entry.ID).Update("undone", true).Error; err != nil {
+			return reverted, err
+		}
+		entry.Undone = true
+		reverted = append(reverted, entry)
+	}
+	return reverted, nil
+}
+
+// Clear removes all journal entries
+func (s *Service) Clear() error {
+	return s.db.Where("1 = 1").Delete(&Entry{}).Error
+}
+
+func revert(entry Entry, r Reverter) error {
+	switch entry.Op {
+	case OpWrite:
+		if !entry.PrevExisted {
+			return r.RemoveFile(entry.Path)
+		}
+		return r.WriteFile(entry.Path, entry.PrevContent)
+	case OpCreate:
+		return r.RemoveFile(entry.Path)
+	case OpDelete:
+		return r.WriteFile(entry.Path, entry.PrevContent)
+	case OpRename:
+		return r.MoveFile(entry.NewPath, entry.Path)
+	default:
+		return fmt.Errorf("unknown operation %q", entry.Op)
+	}
+}
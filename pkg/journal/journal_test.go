@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type memReverter struct {
+	files map[string]string
+}
+
+func (m *memReverter) WriteFile(path, content string) error {
+	m.files[path] = content
+	return nil
+}
+
+func (m *memReverter) RemoveFile(path string) error {
+	delete(m.files, path)
+	return nil
+}
+
+func (m *memReverter) MoveFile(oldPath, newPath string) error {
+	m.files[newPath] = m.files[oldPath]
+	delete(m.files, oldPath)
+	return nil
+}
+
+func setupJournalTestService(t *testing.T) *Service {
+	t.Helper()
+	tmpDir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tmpDir, "journal.sqlite")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		_ = sqlDB.Close()
+		_ = os.RemoveAll(tmpDir)
+	})
+	svc, err := NewService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func TestUndoLastRevertsBatchesInOrder(t *testing.T) {
+	svc := setupJournalTestService(t)
+	fs := &memReverter{files: map[string]string{"a.md": "v2", "moved.md": "m"}}
+
+	if err := svc.Record(Entry{Op: OpWrite, Path: "a.md", PrevContent: "v1", PrevExisted: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Record(Entry{Op: OpRename, Path: "b.md", NewPath: "moved.md"}); err != nil {
+		t.Fatal(err)
+	}
+	batch := NewBatchID()
+	for _, p := range []string{"dir/x.md", "dir/y.md"} {
+		if err := svc.Record(Entry{BatchID: batch, Op: OpDelete, Path: p, PrevContent: p, PrevExisted: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reverted, err := svc.UndoLast(ScopeWorkspace, fs)
+	if err != nil {
+		t.Fatalf("undo batch failed: %v", err)
+	}
+	if len(reverted) != 2 || fs.files["dir/x.md"] != "dir/x.md" || fs.files["dir/y.md"] != "dir/y.md" {
+		t.Fatalf("expected folder delete to be restored, got %v", fs.files)
+	}
+
+	if _, err := svc.UndoLast("a.md", fs); err != nil {
+		t.Fatalf("scoped undo failed: %v", err)
+	}
+	if fs.files["a.md"] != "v1" {
+		t.Fatalf("expected a.md to be reverted, got %q", fs.files["a.md"])
+	}
+	if _, ok := fs.files["moved.md"]; !ok {
+		t.Fatalf("scoped undo should not touch other paths")
+	}
+
+	if _, err := svc.UndoLast("", fs); err != nil {
+		t.Fatalf("undo rename failed: %v", err)
+	}
+	if _, ok := fs.files["b.md"]; !ok {
+		t.Fatalf("expected rename to be reverted, got %v", fs.files)
+	}
+
+	if _, err := svc.UndoLast("", fs); err == nil {
+		t.Fatalf("expected nothing left to undo")
+	}
+}
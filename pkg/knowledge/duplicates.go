@@ -0,0 +1,134 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"notebit/pkg/database"
+)
+
+// DefaultDuplicateThreshold is the note-level similarity above which notes are reported as near-duplicates
+const DefaultDuplicateThreshold = 0.92
+
+// DuplicatePair is two notes whose note-level embeddings are near-identical
+type DuplicatePair struct {
+	A          string  `json:"a"`
+	B          string  `json:"b"`
+	Similarity float32 `json:"similarity"`
+}
+
+// DuplicateNote is a member of a duplicate cluster
+type DuplicateNote struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+}
+
+// DuplicateCluster is a group of notes connected by near-duplicate pairs
+type DuplicateCluster struct {
+	Notes         []DuplicateNote `json:"notes"`
+	Pairs         []DuplicatePair `json:"pairs"`
+	MaxSimilarity float32         `json:"max_similarity"`
+}
+
+// FindDuplicates compares note-level embeddings across the vault and groups
+// notes whose similarity reaches threshold. progress, if set, is called with
+// the number of notes compared so far.
+func (s *Service) FindDuplicates(ctx context.Context, threshold float32, progress func(done, total int)) ([]DuplicateCluster, error) {
+	if !s.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultDuplicateThreshold
+	}
+
+	vectors, err := s.dbm.Repository().ListFileVectors()
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []DuplicatePair
+	for i := range vectors {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for j := i + 1; j < len(vectors); j++ {
+			if len(vectors[i].Vector) != len(vectors[j].Vector) {
+				continue
+			}
+			// Vectors are unit length, so the dot product is the cosine similarity
+			var sim float32
+			for k, v := range vectors[i].Vector {
+				sim += v * vectors[j].Vector[k]
+			}
+			if sim >= threshold {
+				pairs = append(pairs, DuplicatePair{A: vectors[i].Path, B: vectors[j].Path, Similarity: sim})
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(vectors))
+		}
+	}
+	return clusterDuplicates(vectors, pairs), nil
+}
+
+// clusterDuplicates joins pairs into connected groups, most similar group first
+func clusterDuplicates(vectors []database.FileVector, pairs []DuplicatePair) []DuplicateCluster {
+	titles := make(map[string]string, len(vectors))
+	for _, v := range vectors {
+		titles[v.Path] = v.Title
+	}
+
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(p string) string {
+		if parent[p] == "" || parent[p] == p {
+			parent[p] = p
+			return p
+		}
+		root := find(parent[p])
+		parent[p] = root
+		return root
+	}
+	for _, pair := range pairs {
+		ra, rb := find(pair.A), find(pair.B)
+		if ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	byRoot := make(map[string]*DuplicateCluster)
+	for _, pair := range pairs {
+		root := find(pair.A)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &DuplicateCluster{}
+			byRoot[root] = cluster
+		}
+		cluster.Pairs = append(cluster.Pairs, pair)
+		cluster.MaxSimilarity = max(cluster.MaxSimilarity, pair.Similarity)
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(byRoot))
+	for _, cluster := range byRoot {
+		seen := make(map[string]bool)
+		for _, pair := range cluster.Pairs {
+			for _, path := range []string{pair.A, pair.B} {
+				if !seen[path] {
+					seen[path] = true
+					cluster.Notes = append(cluster.Notes, DuplicateNote{Path: path, Title: titles[path]})
+				}
+			}
+		}
+		sort.Slice(cluster.Notes, func(i, j int) bool { return cluster.Notes[i].Path < cluster.Notes[j].Path })
+		sort.Slice(cluster.Pairs, func(i, j int) bool { return cluster.Pairs[i].Similarity > cluster.Pairs[j].Similarity })
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].MaxSimilarity != clusters[j].MaxSimilarity {
+			return clusters[i].MaxSimilarity > clusters[j].MaxSimilarity
+		}
+		return clusters[i].Notes[0].Path < clusters[j].Notes[0].Path
+	})
+	return clusters
+}
@@ -0,0 +1,34 @@
+package knowledge
+
+import (
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestClusterDuplicates_GroupsConnectedPairs(t *testing.T) {
+	vectors := []database.FileVector{
+		{Path: "a.md", Title: "A"}, {Path: "b.md", Title: "B"}, {Path: "c.md", Title: "C"},
+		{Path: "x.md", Title: "X"}, {Path: "y.md", Title: "Y"},
+	}
+	pairs := []DuplicatePair{
+		{A: "a.md", B: "b.md", Similarity: 0.95},
+		{A: "b.md", B: "c.md", Similarity: 0.93},
+		{A: "x.md", B: "y.md", Similarity: 0.99},
+	}
+
+	clusters := clusterDuplicates(vectors, pairs)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2: %+v", len(clusters), clusters)
+	}
+	if clusters[0].MaxSimilarity != 0.99 || len(clusters[0].Notes) != 2 {
+		t.Fatalf("most similar cluster should come first: %+v", clusters[0])
+	}
+	chain := clusters[1]
+	if len(chain.Notes) != 3 || len(chain.Pairs) != 2 {
+		t.Fatalf("a-b-c chain should form one cluster: %+v", chain)
+	}
+	if chain.Notes[0] != (DuplicateNote{Path: "a.md", Title: "A"}) {
+		t.Fatalf("notes should be sorted and titled: %+v", chain.Notes)
+	}
+}
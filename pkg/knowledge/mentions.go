@@ -0,0 +1,216 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"notebit/pkg/files"
+)
+
+// minMentionRunes skips titles and aliases too short to match meaningfully
+const minMentionRunes = 3
+
+// UnlinkedMention is a plain-text occurrence of a note's title or alias in
+// another note that is not already a link
+type UnlinkedMention struct {
+	Path    string `json:"path"`    // Note containing the mention
+	Title   string `json:"title"`   // Title of that note
+	Text    string `json:"text"`    // Mention as written
+	Line    int    `json:"line"`    // 1-based
+	Column  int    `json:"column"`  // 1-based, in characters
+	Offset  int    `json:"offset"`  // Byte offset in the note
+	Length  int    `json:"length"`  // Byte length of Text
+	Context string `json:"context"` // The line containing the mention
+}
+
+// Spans that already link somewhere or are not prose
+var (
+	markdownLinkSpan = regexp.MustCompile(`!?\[[^\]\n]*\]\([^)\n]*\)`)
+	inlineCodeSpan   = regexp.MustCompile("`[^`\n]+`")
+	urlSpan          = regexp.MustCompile(`https?://\S+`)
+)
+
+// GetUnlinkedMentions finds occurrences of the note's title, file name and
+// front matter aliases in other indexed notes that are not wiki or Markdown
+// links. Candidates come from the chunk table; locations are taken from the
+// files on disk so they can be linked in place.
+func (s *Service) GetUnlinkedMentions(ctx context.Context, notePath string) ([]UnlinkedMention, error) {
+	if !s.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	repo := s.dbm.Repository()
+	file, err := repo.GetFileByPath(notePath)
+	if err != nil {
+		return nil, fmt.Errorf("note is not indexed: %w", err)
+	}
+	note, err := s.fm.ReadFile(notePath)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := mentionTerms(file.Title, strings.TrimSuffix(path.Base(notePath), path.Ext(notePath)), files.NoteAliases(note.Content))
+	if len(terms) == 0 {
+		return []UnlinkedMention{}, nil
+	}
+	candidates, err := repo.PathsMentioning(terms, notePath)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := make([]UnlinkedMention, 0)
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		source, err := s.fm.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		title := candidate
+		if f, err := repo.GetFileByPath(candidate); err == nil && f.Title != "" {
+			title = f.Title
+		}
+		for _, m := range findMentions(source.Content, terms) {
+			m.Path = candidate
+			m.Title = title
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions, nil
+}
+
+// mentionTerms returns the distinct (case-insensitive) names a note can be
+// mentioned by, longest first so the longest name wins at a position
+func mentionTerms(title, fileName string, aliases []string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, name := range append([]string{title, fileName}, aliases...) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if utf8.RuneCountInString(name) < minMentionRunes || seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, name)
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	return terms
+}
+
+// findMentions returns whole-word, case-insensitive occurrences of terms in
+// content outside front matter, code and existing links
+func findMentions(content string, terms []string) []UnlinkedMention {
+	if len(terms) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern := regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	excluded := excludedSpans(content)
+
+	var mentions []UnlinkedMention
+	for _, loc := range pattern.FindAllStringIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		if !isWordBoundary(content, start, end) || overlapsAny(excluded, start, end) {
+			continue
+		}
+		lineStart := strings.LastIndexByte(content[:start], '\n') + 1
+		lineEnd := strings.IndexByte(content[start:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += start
+		}
+		mentions = append(mentions, UnlinkedMention{
+			Text:    content[start:end],
+			Line:    strings.Count(content[:start], "\n") + 1,
+			Column:  utf8.RuneCountInString(content[lineStart:start]) + 1,
+			Offset:  start,
+			Length:  end - start,
+			Context: strings.TrimSpace(strings.TrimSuffix(content[lineStart:lineEnd], "\r")),
+		})
+	}
+	return mentions
+}
+
+// excludedSpans returns byte ranges of front matter, fenced code blocks,
+// inline code, links and URLs
+func excludedSpans(content string) [][2]int {
+	var spans [][2]int
+
+	offset := 0
+	inFrontmatter := false
+	fence := ""
+	fenceStart := 0
+	for i, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 && trimmed == "---":
+			inFrontmatter = true
+			fenceStart = offset
+		case inFrontmatter:
+			if trimmed == "---" {
+				spans = append(spans, [2]int{fenceStart, offset + len(line)})
+				inFrontmatter = false
+			}
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+			fenceStart = offset
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			spans = append(spans, [2]int{fenceStart, offset + len(line)})
+			fence = ""
+		}
+		offset += len(line)
+	}
+	if fence != "" {
+		spans = append(spans, [2]int{fenceStart, len(content)})
+	}
+
+	for _, link := range files.FindWikiLinks(content) {
+		spans = append(spans, [2]int{link.Offset, link.Offset + len(link.Text)})
+	}
+	for _, re := range []*regexp.Regexp{markdownLinkSpan, inlineCodeSpan, urlSpan} {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		}
+	}
+	return spans
+}
+
+func overlapsAny(spans [][2]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && end > span[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordBoundary reports whether content[start:end] is not part of a longer word
+func isWordBoundary(content string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(content[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(content) {
+		r, _ := utf8.DecodeRuneInString(content[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
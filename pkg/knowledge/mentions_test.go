@@ -0,0 +1,41 @@
+package knowledge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentionTerms(t *testing.T) {
+	got := mentionTerms("Project Atlas", "project-atlas", []string{"Atlas", "atlas", "PA"})
+	want := []string{"Project Atlas", "project-atlas", "Atlas"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindMentions_SkipsLinksCodeAndPartialWords(t *testing.T) {
+	content := "---\ntitle: Atlas notes\n---\n" +
+		"Kickoff for project atlas.\n" +
+		"Already linked: [[Atlas]] and [the atlas](atlas.md).\n" +
+		"```\nAtlas in code\n```\n" +
+		"Use `Atlas` inline, see https://example.com/Atlas, not Atlases.\n" +
+		"Über Atlas!"
+
+	mentions := findMentions(content, mentionTerms("Project Atlas", "", []string{"Atlas"}))
+	if len(mentions) != 2 {
+		t.Fatalf("got %d mentions, want 2: %+v", len(mentions), mentions)
+	}
+
+	first := mentions[0]
+	if first.Text != "project atlas" || first.Line != 4 || first.Column != 13 || first.Context != "Kickoff for project atlas." {
+		t.Fatalf("unexpected first mention: %+v", first)
+	}
+	if content[first.Offset:first.Offset+first.Length] != first.Text {
+		t.Fatalf("offset does not point at the mention: %+v", first)
+	}
+
+	second := mentions[1]
+	if second.Text != "Atlas" || second.Line != 10 || second.Column != 6 {
+		t.Fatalf("unexpected second mention: %+v", second)
+	}
+}
@@ -0,0 +1,192 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/database"
+)
+
+// maxRelatedQueryChunks bounds how many of a note's chunks are used as queries
+const maxRelatedQueryChunks = 16
+
+// maxRelatedCacheEntries bounds the related-notes cache before it is reset
+const maxRelatedCacheEntries = 256
+
+// relatedMaxWeight weights a note's best chunk match against its mean match
+const relatedMaxWeight = 0.7
+
+// RelatedNote is a note related to another, scored at note level from its chunk matches
+type RelatedNote struct {
+	Path           string  `json:"path"`
+	Title          string  `json:"title"`
+	Score          float32 `json:"score"`
+	MaxSimilarity  float32 `json:"max_similarity"`
+	MeanSimilarity float32 `json:"mean_similarity"`
+	MatchedChunks  int     `json:"matched_chunks"`
+	Heading        string  `json:"heading"` // Heading of the best matching chunk
+	Excerpt        string  `json:"excerpt"` // Content of the best matching chunk
+}
+
+type relatedCacheKey struct {
+	path  string
+	limit int
+}
+
+type relatedCacheEntry struct {
+	revision    uint64
+	contentHash string
+	notes       []RelatedNote
+}
+
+// GetRelatedNotes returns the notes most related to the note at path, excluding
+// the note itself. The note's centroid and its stored chunk embeddings are
+// used as queries, so a long note is represented as a whole as well as by a
+// sample of its parts; an unembedded note is embedded on the fly. Results
+// are cached until the index changes.
+func (s *Service) GetRelatedNotes(ctx context.Context, path string, limit int) ([]RelatedNote, error) {
+	if !s.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	repo := s.dbm.Repository()
+	revision := repo.GetRevision()
+	file, queries, err := repo.GetFileEmbeddings(path)
+	if err != nil {
+		return nil, fmt.Errorf("note is not indexed: %w", err)
+	}
+
+	key := relatedCacheKey{path: path, limit: limit}
+	s.relatedMu.Lock()
+	if entry, ok := s.relatedCache[key]; ok && entry.revision == revision && entry.contentHash == file.ContentHash {
+		s.relatedMu.Unlock()
+		return entry.notes, nil
+	}
+	s.relatedMu.Unlock()
+
+	if len(queries) == 0 {
+		note, err := s.fm.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content := note.Content
+		if runes := []rune(content); len(runes) > maxFindSimilarContentLength {
+			content = string(runes[:maxFindSimilarContentLength])
+		}
+		resp, err := s.ai.GenerateEmbedding(ai.WithOperation(ctx, ai.OperationSearch), content)
+		if err != nil {
+			return nil, err
+		}
+		queries = [][]float32{resp.Embedding}
+	}
+	if centroid := file.GetCentroid(); len(centroid) > 0 && len(queries) > 1 {
+		queries = append([][]float32{centroid}, sampleQueries(queries, maxRelatedQueryChunks-1)...)
+	} else {
+		queries = sampleQueries(queries, maxRelatedQueryChunks)
+	}
+
+	// Over-fetch: the note's own chunks take some of the top slots
+	matches, err := repo.SearchSimilarBatch(queries, limit*3+maxRelatedQueryChunks)
+	if err != nil {
+		return nil, err
+	}
+	notes := aggregateRelated(matches, path, limit)
+
+	s.relatedMu.Lock()
+	if len(s.relatedCache) >= maxRelatedCacheEntries {
+		s.relatedCache = make(map[relatedCacheKey]relatedCacheEntry)
+	}
+	s.relatedCache[key] = relatedCacheEntry{revision: revision, contentHash: file.ContentHash, notes: notes}
+	s.relatedMu.Unlock()
+	return notes, nil
+}
+
+// sampleQueries picks at most n vectors spread evenly across the note
+func sampleQueries(queries [][]float32, n int) [][]float32 {
+	if len(queries) <= n {
+		return queries
+	}
+	sampled := make([][]float32, n)
+	for i := range sampled {
+		sampled[i] = queries[i*len(queries)/n]
+	}
+	return sampled
+}
+
+// aggregateRelated folds chunk hits from several queries into note-level
+// scores. Each chunk counts once with its best similarity; a note scores a
+// weighted blend of its best chunk and the mean of its matched chunks.
+func aggregateRelated(matches [][]database.SimilarChunk, excludePath string, limit int) []RelatedNote {
+	best := make(map[uint]database.SimilarChunk)
+	for _, hits := range matches {
+		for _, hit := range hits {
+			if hit.File == nil || hit.File.Path == excludePath {
+				continue
+			}
+			if prev, ok := best[hit.ChunkID]; !ok || hit.Similarity > prev.Similarity {
+				best[hit.ChunkID] = hit
+			}
+		}
+	}
+
+	type accumulator struct {
+		note RelatedNote
+		sum  float32
+	}
+	byPath := make(map[string]*accumulator)
+	for _, hit := range best {
+		acc, ok := byPath[hit.File.Path]
+		if !ok {
+			acc = &accumulator{note: RelatedNote{Path: hit.File.Path, Title: hit.File.Title}}
+			byPath[hit.File.Path] = acc
+		}
+		acc.sum += hit.Similarity
+		acc.note.MatchedChunks++
+		if hit.Similarity > acc.note.MaxSimilarity {
+			acc.note.MaxSimilarity = hit.Similarity
+			acc.note.Heading = hit.Heading
+			acc.note.Excerpt = hit.Content
+		}
+	}
+
+	notes := make([]RelatedNote, 0, len(byPath))
+	for _, acc := range byPath {
+		note := acc.note
+		note.MeanSimilarity = acc.sum / float32(note.MatchedChunks)
+		note.Score = relatedMaxWeight*note.MaxSimilarity + (1-relatedMaxWeight)*note.MeanSimilarity
+		notes = append(notes, note)
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Score != notes[j].Score {
+			return notes[i].Score > notes[j].Score
+		}
+		return notes[i].Path < notes[j].Path
+	})
+	if len(notes) > limit {
+		notes = notes[:limit]
+	}
+	return notes
+}
+
+// GetSimilarFiles returns the notes whose centroid is closest to that of the
+// note at path. It compares one vector per note, so it is much cheaper than
+// GetRelatedNotes but blind to a close match on a single section.
+func (s *Service) GetSimilarFiles(path string, limit int) ([]database.FileSimilarity, error) {
+	if !s.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	repo := s.dbm.Repository()
+	centroid, err := repo.GetFileCentroid(path)
+	if err != nil {
+		return nil, fmt.Errorf("note is not indexed: %w", err)
+	}
+	if len(centroid) == 0 {
+		return []database.FileSimilarity{}, nil
+	}
+	return repo.SearchSimilarFiles(centroid, limit, path)
+}
@@ -0,0 +1,46 @@
+package knowledge
+
+import (
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestAggregateRelated_ScoresNotesAndExcludesSelf(t *testing.T) {
+	self := &database.File{Path: "self.md"}
+	a := &database.File{Path: "a.md", Title: "A"}
+	b := &database.File{Path: "b.md", Title: "B"}
+
+	matches := [][]database.SimilarChunk{
+		{
+			{ChunkID: 1, File: self, Similarity: 1},
+			{ChunkID: 10, File: a, Similarity: 0.9, Heading: "Best"},
+			{ChunkID: 11, File: a, Similarity: 0.5},
+			{ChunkID: 20, File: b, Similarity: 0.8},
+		},
+		{
+			// Same chunk seen by a second query counts once, with its best score
+			{ChunkID: 11, File: a, Similarity: 0.7},
+			{ChunkID: 20, File: b, Similarity: 0.6},
+		},
+	}
+
+	notes := aggregateRelated(matches, "self.md", 10)
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want 2: %+v", len(notes), notes)
+	}
+	if notes[0].Path != "a.md" || notes[1].Path != "b.md" {
+		t.Fatalf("unexpected order: %+v", notes)
+	}
+	first := notes[0]
+	if first.MatchedChunks != 2 || first.MaxSimilarity != 0.9 || first.Heading != "Best" {
+		t.Fatalf("unexpected aggregate for a.md: %+v", first)
+	}
+	if diff := first.MeanSimilarity - 0.8; diff > 1e-6 || diff < -1e-6 {
+		t.Fatalf("mean similarity = %v, want 0.8", first.MeanSimilarity)
+	}
+
+	if limited := aggregateRelated(matches, "self.md", 1); len(limited) != 1 {
+		t.Fatalf("limit not applied: %+v", limited)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"notebit/pkg/database"
 	"notebit/pkg/files"
 	"notebit/pkg/indexing"
+	"notebit/pkg/logger"
+	"strings"
+	"sync"
 )
 
 const maxFindSimilarContentLength = 8000
@@ -17,15 +20,19 @@ type Service struct {
 	dbm      *database.Manager
 	ai       *ai.Service
 	pipeline *indexing.IndexingPipeline
+
+	relatedMu    sync.Mutex
+	relatedCache map[relatedCacheKey]relatedCacheEntry
 }
 
 // NewService creates a new knowledge service
 func NewService(fm *files.Manager, dbm *database.Manager, ai *ai.Service, pipeline *indexing.IndexingPipeline) *Service {
 	return &Service{
-		fm:       fm,
-		dbm:      dbm,
-		ai:       ai,
-		pipeline: pipeline,
+		fm:           fm,
+		dbm:          dbm,
+		ai:           ai,
+		pipeline:     pipeline,
+		relatedCache: make(map[relatedCacheKey]relatedCacheEntry),
 	}
 }
 
@@ -43,6 +50,24 @@ func (s *Service) IndexFileWithEmbedding(path string) error {
 
 // ReindexAllWithEmbeddings reindexes all files with embeddings
 func (s *Service) ReindexAllWithEmbeddings() (map[string]interface{}, error) {
+	progress, err := s.StartReindexAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for completion
+	<-progress.Done
+
+	return map[string]interface{}{
+		"total":     progress.Total,
+		"processed": progress.Processed.Load(),
+		"failed":    progress.Errors.Load(),
+	}, nil
+}
+
+// StartReindexAll queues every markdown file for a forced reindex and returns
+// without waiting; cancelling ctx stops files that have not been processed yet
+func (s *Service) StartReindexAll(ctx context.Context) (*indexing.IndexProgress, error) {
 	if s.pipeline == nil {
 		return nil, fmt.Errorf("indexing pipeline not initialized")
 	}
@@ -57,22 +82,10 @@ func (s *Service) ReindexAllWithEmbeddings() (map[string]interface{}, error) {
 	collectFiles(filesList, &mdFiles)
 
 	// Use pipeline's IndexAll for batch processing
-	progress, err := s.pipeline.IndexAll(context.Background(), mdFiles, indexing.IndexOptions{
+	return s.pipeline.IndexAll(ctx, mdFiles, indexing.IndexOptions{
 		ForceReindex:           true,
 		FallbackToMetadataOnly: true,
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	// Wait for completion
-	<-progress.Done
-
-	return map[string]interface{}{
-		"total":     progress.Total,
-		"processed": progress.Processed.Load(),
-		"failed":    progress.Errors.Load(),
-	}, nil
 }
 
 // collectFiles recursively collects all markdown file paths
@@ -94,40 +107,83 @@ type SimilarNote struct {
 	Heading    string  `json:"heading"`
 	Similarity float32 `json:"similarity"`
 	ChunkID    uint    `json:"chunk_id"`
+	// Source is the image the content was read from, "" for note text
+	Source string `json:"source,omitempty"`
 }
 
 // FindSimilar finds semantically similar notes based on content
-func (s *Service) FindSimilar(content string, limit int) ([]SimilarNote, error) {
-	// 1. Check if database is initialized
+func (s *Service) FindSimilar(ctx context.Context, content string, limit int) ([]SimilarNote, error) {
+	return s.FindSimilarInLanguage(ctx, content, "", limit)
+}
+
+// FindSimilarInLanguage finds similar notes like FindSimilar, also searching
+// with the multilingual model configured for language (an ISO 639-1 code,
+// e.g. "zh"), so an English query can reach notes written in it. The
+// language of content itself is always considered.
+func (s *Service) FindSimilarInLanguage(ctx context.Context, content, language string, limit int) ([]SimilarNote, error) {
+	ctx = ai.WithOperation(ctx, ai.OperationSearch)
+	content = searchText(content)
+	resp, err := s.queryEmbedding(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	byModel := make(map[string][]float32)
+	for _, model := range s.ai.QueryModels(ai.DetectLanguage(content), strings.ToLower(strings.TrimSpace(language))) {
+		modelResp, err := s.ai.GenerateEmbeddingWithModel(ctx, model, content)
+		if err != nil {
+			logger.Warn("Embedding query with %s failed: %v", model, err)
+			continue
+		}
+		byModel[model] = modelResp.Embedding
+	}
+
+	chunks, err := s.dbm.Repository().SearchSimilarAcrossModels(resp.Embedding, byModel, limit)
+	if err != nil {
+		return nil, err
+	}
+	return similarNotes(chunks), nil
+}
+
+// FindSimilarImages finds the images embedded in notes whose extracted text
+// or caption best matches description, one result per image
+func (s *Service) FindSimilarImages(ctx context.Context, description string, limit int) ([]SimilarNote, error) {
+	resp, err := s.queryEmbedding(ai.WithOperation(ctx, ai.OperationSearch), searchText(description))
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.dbm.Repository().SearchSimilarImages(resp.Embedding, limit)
+	if err != nil {
+		return nil, err
+	}
+	return similarNotes(chunks), nil
+}
+
+// queryEmbedding embeds content for a similarity search
+func (s *Service) queryEmbedding(ctx context.Context, content string) (*ai.EmbeddingResponse, error) {
 	if !s.dbm.IsInitialized() {
 		return nil, fmt.Errorf("database not initialized")
 	}
-
-	// 2. Check if AI service is healthy
 	status, err := s.ai.GetStatus()
 	if err != nil || !status.ProviderHealthy {
 		return nil, fmt.Errorf("AI service not available")
 	}
+	return s.ai.GenerateEmbedding(ctx, content)
+}
 
-	// 3. Generate embedding for query content
+// searchText cuts content searched for to maxFindSimilarContentLength runes
+func searchText(content string) string {
 	if len(content) > maxFindSimilarContentLength {
 		runes := []rune(content)
 		if len(runes) > maxFindSimilarContentLength {
 			content = string(runes[:maxFindSimilarContentLength])
 		}
 	}
-	resp, err := s.ai.GenerateEmbedding(content)
-	if err != nil {
-		return nil, err
-	}
-
-	// 4. Search similar chunks
-	chunks, err := s.dbm.Repository().SearchSimilar(resp.Embedding, limit)
-	if err != nil {
-		return nil, err
-	}
+	return content
+}
 
-	// 5. Enrich with file information
+// similarNotes enriches search results with their notes, dropping chunks
+// whose note is gone
+func similarNotes(chunks []database.SimilarChunk) []SimilarNote {
 	results := make([]SimilarNote, 0, len(chunks))
 	for _, chunk := range chunks {
 		if chunk.File == nil {
@@ -140,10 +196,18 @@ func (s *Service) FindSimilar(content string, limit int) ([]SimilarNote, error)
 			Heading:    chunk.Heading,
 			Similarity: chunk.Similarity,
 			ChunkID:    chunk.ChunkID,
+			Source:     chunk.Source,
 		})
 	}
+	return results
+}
 
-	return results, nil
+// GetIndexCoverage returns embedding coverage so similarity results can be qualified
+func (s *Service) GetIndexCoverage() (*database.IndexCoverage, error) {
+	if !s.dbm.IsInitialized() {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return s.dbm.Repository().GetIndexCoverage()
 }
 
 // GetSimilarityStatus returns the availability status of semantic search
@@ -161,6 +225,11 @@ func (s *Service) GetSimilarityStatus() (map[string]interface{}, error) {
 		stats, _ = s.dbm.Repository().GetEmbeddingStats()
 	}
 
+	var coverage *database.IndexCoverage
+	if dbInitialized {
+		coverage, _ = s.dbm.Repository().GetIndexCoverage()
+	}
+
 	available := dbInitialized && aiStatus != nil && aiStatus.ProviderHealthy
 
 	var embeddedChunks int64
@@ -184,5 +253,6 @@ func (s *Service) GetSimilarityStatus() (map[string]interface{}, error) {
 		"indexed_chunks": embeddedChunks,
 		"total_chunks":   totalChunks,
 		"vector_engine":  vectorEngine,
+		"coverage":       coverage,
 	}, nil
 }
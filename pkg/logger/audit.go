@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEvent records one data-mutating operation
+type AuditEvent struct {
+	Time    time.Time              `json:"time"`
+	Action  string                 `json:"action"` // e.g. "note.delete", "config.change"
+	Target  string                 `json:"target,omitempty"`
+	Before  map[string]interface{} `json:"before,omitempty"`
+	After   map[string]interface{} `json:"after,omitempty"`
+	TraceID string                 `json:"trace_id,omitempty"`
+}
+
+// AuditFilter selects audit events. Zero fields match everything.
+type AuditFilter struct {
+	Action string    `json:"action"` // exact action or prefix ending in "." (e.g. "note.")
+	Target string    `json:"target"` // case-insensitive substring of the target
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Limit  int       `json:"limit"` // newest events first; 0 means no limit
+}
+
+func (f AuditFilter) matches(e AuditEvent) bool {
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			if !strings.HasPrefix(e.Action, f.Action) {
+				return false
+			}
+		} else if e.Action != f.Action {
+			return false
+		}
+	}
+	if f.Target != "" && !strings.Contains(strings.ToLower(e.Target), strings.ToLower(f.Target)) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// AuditLogger appends events as JSON lines to a file of its own. Unlike the
+// regular log, writes are synchronous and never dropped or rotated away.
+type AuditLogger struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewAuditLogger opens (or creates) the audit file at path
+func NewAuditLogger(path string) (*AuditLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogger{path: path, file: f}, nil
+}
+
+// Record writes event and syncs it to disk before returning
+func (a *AuditLogger) Record(event AuditEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+// Query returns the events matching filter, newest first
+func (a *AuditLogger) Query(filter AuditFilter) ([]AuditEvent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e AuditEvent
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue // skip a line torn by a crash
+		}
+		if filter.matches(e) {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+// Close closes the audit file
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+// Audit records an event on the default logger's audit log. Failures are
+// reported on the regular log since callers have already mutated data.
+func Audit(ctx context.Context, action, target string, before, after map[string]interface{}) {
+	if defaultLogger == nil || defaultLogger.audit == nil {
+		return
+	}
+	event := AuditEvent{Action: action, Target: target, Before: before, After: after, TraceID: GetTraceID(ctx)}
+	if err := defaultLogger.audit.Record(event); err != nil {
+		ErrorWithFields(ctx, map[string]interface{}{"action": action, "target": target, "error": err.Error()}, "Failed to write audit event")
+	}
+}
+
+// QueryAudit reads the default logger's audit log
+func QueryAudit(filter AuditFilter) ([]AuditEvent, error) {
+	if defaultLogger == nil || defaultLogger.audit == nil {
+		return nil, nil
+	}
+	return defaultLogger.audit.Query(filter)
+}
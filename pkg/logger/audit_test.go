@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLoggerQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a, err := NewAuditLogger(path)
+	if err != nil {
+		t.Fatalf("Failed to create audit logger: %v", err)
+	}
+	start := time.Now()
+	events := []AuditEvent{
+		{Action: "note.create", Target: "Inbox/Idea.md", After: map[string]interface{}{"size": 12}},
+		{Action: "config.change", Target: "config.json"},
+		{Action: "note.delete", Target: "Inbox/Idea.md"},
+	}
+	for _, e := range events {
+		if err := a.Record(e); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	a.Close()
+
+	// Reopening appends to the same file
+	a, err = NewAuditLogger(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen audit logger: %v", err)
+	}
+	defer a.Close()
+
+	notes, err := a.Query(AuditFilter{Action: "note.", Target: "idea"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(notes) != 2 || notes[0].Action != "note.delete" || notes[1].Action != "note.create" {
+		t.Fatalf("Expected note events newest first, got %+v", notes)
+	}
+	if notes[1].After["size"] != float64(12) || notes[1].Time.Before(start) {
+		t.Errorf("Metadata or time not kept: %+v", notes[1])
+	}
+
+	limited, _ := a.Query(AuditFilter{Limit: 1})
+	if len(limited) != 1 || limited[0].Action != "note.delete" {
+		t.Errorf("Expected only the newest event, got %+v", limited)
+	}
+	exact, _ := a.Query(AuditFilter{Action: "config.change"})
+	if len(exact) != 1 {
+		t.Errorf("Expected 1 config event, got %d", len(exact))
+	}
+}
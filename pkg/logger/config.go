@@ -193,5 +193,39 @@ func LoadConfigFromEnv(base Config) Config {
 		cfg.KafkaTopic = kafkaTopic
 	}
 
+	// Load redaction settings
+	if redactKeys, ok := os.LookupEnv("LOG_REDACT_KEYS"); ok {
+		cfg.RedactKeys = []string{}
+		for _, key := range strings.Split(redactKeys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.RedactKeys = append(cfg.RedactKeys, key)
+			}
+		}
+	}
+
+	if hashPathsStr := os.Getenv("LOG_HASH_PATHS"); hashPathsStr != "" {
+		cfg.HashPaths = strings.ToLower(hashPathsStr) == "true" || hashPathsStr == "1"
+	}
+
+	// Load OTLP configuration; the endpoint uses the standard OTel variable
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		cfg.OTLPEnabled = true
+		cfg.OTLPEndpoint = endpoint
+	}
+
+	if otlpEnabledStr := os.Getenv("LOG_OTLP_ENABLED"); otlpEnabledStr != "" {
+		cfg.OTLPEnabled = strings.ToLower(otlpEnabledStr) == "true" || otlpEnabledStr == "1"
+	}
+
+	if serviceName := os.Getenv("OTEL_SERVICE_NAME"); serviceName != "" {
+		cfg.OTLPServiceName = serviceName
+	}
+
+	if intervalStr := os.Getenv("OTEL_METRIC_EXPORT_INTERVAL"); intervalStr != "" {
+		if interval, err := strconv.Atoi(intervalStr); err == nil {
+			cfg.OTLPExportIntervalMS = interval
+		}
+	}
+
 	return cfg
 }
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// PanicHandler is called with a recovered panic and its stack, e.g. to write
+// a crash report. It runs on the panicking goroutine.
+type PanicHandler func(r any, stack []byte)
+
+var panicHandler atomic.Value // PanicHandler
+
+// SetPanicHandler installs fn as the handler for ReportPanic; nil removes it
+func SetPanicHandler(fn PanicHandler) {
+	panicHandler.Store(fn)
+}
+
+// ReportPanic logs a recovered panic, flushes the default logger and passes
+// it to the panic handler. It does not re-panic; use it where the panic is
+// turned into an error (e.g. a failed job).
+func ReportPanic(r any, stack []byte) {
+	Error("Panic: %v\n%s", r, stack)
+	if l := GetDefault(); l != nil {
+		l.Flush(time.Second)
+	}
+	if fn, _ := panicHandler.Load().(PanicHandler); fn != nil {
+		fn(r, stack)
+	}
+}
+
+// RecoverPanic is deferred at the top of long-lived goroutines (scheduler
+// loops, watchers). Panics in a goroutine cannot be recovered by main, so
+// this reports them and re-panics so the process still exits with the
+// original stack trace.
+func RecoverPanic() {
+	if r := recover(); r != nil {
+		ReportPanic(r, debug.Stack())
+		panic(r)
+	}
+}
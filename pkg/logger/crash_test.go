@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	var got any
+	var stack []byte
+	SetPanicHandler(func(r any, s []byte) { got, stack = r, s })
+	defer SetPanicHandler(nil)
+
+	done := make(chan any)
+	go func() {
+		defer func() { done <- recover() }()
+		defer RecoverPanic()
+		panic("boom")
+	}()
+
+	if r := <-done; r != "boom" {
+		t.Fatalf("RecoverPanic re-panicked with %v, want the original panic", r)
+	}
+	if got != "boom" || !strings.Contains(string(stack), "TestRecoverPanic") {
+		t.Fatalf("handler got %v with stack:\n%s", got, stack)
+	}
+}
+
+func TestRecoverPanicWithoutPanic(t *testing.T) {
+	called := false
+	SetPanicHandler(func(any, []byte) { called = true })
+	defer SetPanicHandler(nil)
+
+	func() {
+		defer RecoverPanic()
+	}()
+	if called {
+		t.Fatal("handler called without a panic")
+	}
+}
@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +18,8 @@ type Logger struct {
 	logChan      chan LogEntry
 	writer       *FileWriter
 	kafkaWriter  *KafkaWriter
+	otlp         *OTLPExporter
+	audit        *AuditLogger
 	writerMu     sync.Mutex // Protects writer replacement
 	wg           sync.WaitGroup
 	isClosed     atomic.Bool
@@ -62,8 +67,18 @@ func New(cfg Config) (*Logger, error) {
 		return nil, fmt.Errorf("failed to initialize Kafka writer: %w", err)
 	}
 
+	// Initialize audit log if configured
+	var audit *AuditLogger
+	if cfg.AuditFileName != "" {
+		audit, err = NewAuditLogger(filepath.Join(cfg.LogDir, cfg.AuditFileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize audit log: %w", err)
+		}
+	}
+
 	l := &Logger{
 		logChan:     make(chan LogEntry, cfg.AsyncBufferSize),
+		audit:       audit,
 		writer:      fw,
 		kafkaWriter: kw,
 		consoleOut:  os.Stdout,
@@ -74,6 +89,9 @@ func New(cfg Config) (*Logger, error) {
 	}
 	l.config.Store(cfg)
 
+	// Initialize OTLP exporter if enabled
+	l.otlp = NewOTLPExporter(cfg, l.metrics)
+
 	// Start log processor
 	l.wg.Add(1)
 	go l.processLogs()
@@ -167,6 +185,9 @@ func (l *Logger) flushBatchLocked() {
 func (l *Logger) writeEntry(entry LogEntry) {
 	cfg := l.config.Load().(Config)
 
+	// Mask secrets before the entry reaches any output
+	entry = redactEntry(entry, cfg)
+
 	// Format message
 	msg := l.formatEntry(entry, false)
 
@@ -192,6 +213,9 @@ func (l *Logger) writeEntry(entry LogEntry) {
 		// Non-blocking write to Kafka
 		go l.kafkaWriter.Write(entry)
 	}
+
+	// Timed entries become spans
+	l.otlp.Record(entry)
 }
 
 func (l *Logger) formatEntry(entry LogEntry, withColor bool) string {
@@ -426,6 +450,15 @@ func (l *Logger) SetLevel(level Level) {
 	l.config.Store(cfg)
 }
 
+// SetRedaction changes the masked field keys (nil restores the defaults) and
+// whether path fields are hashed
+func (l *Logger) SetRedaction(keys []string, hashPaths bool) {
+	cfg := l.config.Load().(Config)
+	cfg.RedactKeys = keys
+	cfg.HashPaths = hashPaths
+	l.config.Store(cfg)
+}
+
 func (l *Logger) GetLevel() Level {
 	cfg := l.config.Load().(Config)
 	return cfg.Level
@@ -453,6 +486,65 @@ func (l *Logger) SetLogDir(dir string) error {
 	return nil
 }
 
+// Flush writes out queued entries, waiting up to timeout for the queue to
+// drain. Used before the process exits abnormally, when Close may not run.
+func (l *Logger) Flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for len(l.logChan) > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	l.flushBatch()
+}
+
+// tailBlockSize is how much Tail reads at a time, backwards from the end
+const tailBlockSize = 8 * 1024
+
+// Tail returns up to n of the last lines of the current log file. It reads
+// backwards from the end, so it stays cheap on a large log.
+func (l *Logger) Tail(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	cfg := l.config.Load().(Config)
+	f, err := os.Open(filepath.Join(cfg.LogDir, cfg.FileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read blocks from the end until they hold more than n line breaks
+	// (the last one ends the final line) or the whole file is read
+	var data []byte
+	for offset := info.Size(); offset > 0 && bytes.Count(data, []byte("\n")) <= n; {
+		size := min(int64(tailBlockSize), offset)
+		offset -= size
+		block := make([]byte, size)
+		if _, err := f.ReadAt(block, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(block, data...)
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// LogDir returns the directory log files are written to
+func (l *Logger) LogDir() string {
+	return l.config.Load().(Config).LogDir
+}
+
 // GetMetrics returns current logger metrics
 func (l *Logger) GetMetrics() MetricsSnapshot {
 	return l.metrics.GetSnapshot()
@@ -483,7 +575,13 @@ func (l *Logger) Close() {
 		if l.kafkaWriter != nil {
 			l.kafkaWriter.Close()
 		}
+		if l.audit != nil {
+			l.audit.Close()
+		}
 		l.writerMu.Unlock()
+
+		// Final span and metrics export
+		l.otlp.Close()
 	}
 }
 
@@ -611,6 +709,13 @@ func SetLevel(level Level) {
 	}
 }
 
+// SetRedaction changes the redaction settings of the default logger
+func SetRedaction(keys []string, hashPaths bool) {
+	if defaultLogger != nil {
+		defaultLogger.SetRedaction(keys, hashPaths)
+	}
+}
+
 // GetMetrics returns metrics from the default logger
 func GetMetrics() MetricsSnapshot {
 	if defaultLogger != nil {
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -187,3 +188,48 @@ func TestDynamicLevel(t *testing.T) {
 		t.Error("New Debug should be logged")
 	}
 }
+
+func TestTail(t *testing.T) {
+	tmpDir := t.TempDir()
+	l, err := New(Config{Level: INFO, LogDir: tmpDir, FileName: "tail.log", AsyncBufferSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer l.Close()
+
+	// Several blocks of lines, so Tail has to read backwards across blocks
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		b.WriteString(strings.Repeat("x", 20) + " line " + strconv.Itoa(i) + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "tail.log"), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := l.Tail(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"line 1997", "line 1998", "line 1999"}
+	if len(lines) != len(want) {
+		t.Fatalf("Tail(3) = %q", lines)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Fatalf("Tail(3) = %q, want lines ending %q", lines, want)
+		}
+	}
+	if lines, _ := l.Tail(1000); len(lines) != 1000 || !strings.HasSuffix(lines[0], "line 1000") {
+		t.Fatalf("Tail(1000) returned %d lines starting %q", len(lines), lines[0])
+	}
+	if lines, _ := l.Tail(5000); len(lines) != 2000 || !strings.HasSuffix(lines[0], "line 0") {
+		t.Fatalf("Tail(5000) returned %d lines", len(lines))
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "tail.log"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if lines, err := l.Tail(3); err != nil || len(lines) != 0 {
+		t.Fatalf("Tail of an empty log = %q, %v", lines, err)
+	}
+}
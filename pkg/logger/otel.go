@@ -0,0 +1,344 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultOTLPServiceName    = "notebit"
+	defaultOTLPExportInterval = 10 * time.Second
+	otlpSpanBufferSize        = 2048
+	otlpScopeName             = "notebit/pkg/logger"
+)
+
+// OTLPExporter sends timed log entries as spans and the logger Metrics as
+// OTel metrics to an OTLP/HTTP collector, using the JSON encoding so no
+// OpenTelemetry SDK is needed. Export failures never block logging: spans
+// that do not fit the buffer are dropped and counted.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	interval    time.Duration
+	client      *http.Client
+	metrics     *Metrics
+	startTime   time.Time
+
+	mu    sync.Mutex
+	spans []otlpSpan
+
+	droppedSpans atomic.Uint64
+	exportErrors atomic.Uint64
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewOTLPExporter creates an exporter for cfg, or returns nil when OTLP export
+// is disabled. metrics is exported on every interval.
+func NewOTLPExporter(cfg Config, metrics *Metrics) *OTLPExporter {
+	if !cfg.OTLPEnabled || cfg.OTLPEndpoint == "" {
+		return nil
+	}
+	serviceName := cfg.OTLPServiceName
+	if serviceName == "" {
+		serviceName = defaultOTLPServiceName
+	}
+	interval := time.Duration(cfg.OTLPExportIntervalMS) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultOTLPExportInterval
+	}
+	e := &OTLPExporter{
+		endpoint:    strings.TrimSuffix(cfg.OTLPEndpoint, "/"),
+		serviceName: serviceName,
+		interval:    interval,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		metrics:     metrics,
+		startTime:   time.Now(),
+		stopChan:    make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// Record turns an entry into a span if it carries a duration
+func (e *OTLPExporter) Record(entry LogEntry) {
+	if e == nil || entry.Duration <= 0 {
+		return
+	}
+	span := spanFromEntry(entry)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.spans) >= otlpSpanBufferSize {
+		e.droppedSpans.Add(1)
+		return
+	}
+	e.spans = append(e.spans, span)
+}
+
+// Flush exports pending spans and the current metrics
+func (e *OTLPExporter) Flush() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+
+	if len(spans) > 0 {
+		e.post("/v1/traces", e.tracesPayload(spans))
+	}
+	if e.metrics != nil {
+		e.post("/v1/metrics", e.metricsPayload(e.metrics.GetSnapshot(), time.Now()))
+	}
+}
+
+// Close stops the export loop after a final flush
+func (e *OTLPExporter) Close() {
+	if e == nil {
+		return
+	}
+	close(e.stopChan)
+	e.wg.Wait()
+	e.Flush()
+}
+
+func (e *OTLPExporter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) post(path string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		e.exportErrors.Add(1)
+		return
+	}
+	resp, err := e.client.Post(e.endpoint+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		e.exportErrors.Add(1)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		e.exportErrors.Add(1)
+	}
+}
+
+// OTLP/JSON wire types. IDs are hex, 64-bit integers are decimal strings.
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 0 unset, 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"` // 1 = internal
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsInt             string         `json:"asInt"`
+}
+
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	Gauge       *struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge,omitempty"`
+	Sum *struct {
+		DataPoints             []otlpDataPoint `json:"dataPoints"`
+		AggregationTemporality int             `json:"aggregationTemporality"` // 2 = cumulative
+		IsMonotonic            bool            `json:"isMonotonic"`
+	} `json:"sum,omitempty"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpAttribute(key string, value interface{}) otlpKeyValue {
+	switch v := value.(type) {
+	case string:
+		return otlpString(key, v)
+	case bool:
+		return otlpKeyValue{Key: key, Value: otlpValue{BoolValue: &v}}
+	case int:
+		s := strconv.FormatInt(int64(v), 10)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case uint64:
+		s := strconv.FormatUint(v, 10)
+		return otlpKeyValue{Key: key, Value: otlpValue{IntValue: &s}}
+	case float32:
+		f := float64(v)
+		return otlpKeyValue{Key: key, Value: otlpValue{DoubleValue: &f}}
+	case float64:
+		return otlpKeyValue{Key: key, Value: otlpValue{DoubleValue: &v}}
+	default:
+		return otlpString(key, fmt.Sprint(v))
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// spanFromEntry maps a timed entry to a span ending at the entry time. Trace
+// IDs from NewTraceID are already 32 hex characters; others get a fresh ID
+// and are kept as an attribute.
+func spanFromEntry(entry LogEntry) otlpSpan {
+	traceID := strings.ToLower(entry.TraceID)
+	attrs := []otlpKeyValue{
+		otlpString("log.message", entry.Message),
+		otlpString("log.level", entry.Level.String()),
+		otlpString("code.filepath", entry.ClassName),
+		otlpString("code.function", entry.MethodName),
+		otlpAttribute("code.lineno", entry.Line),
+	}
+	if _, err := hex.DecodeString(traceID); err != nil || len(traceID) != 32 {
+		if entry.TraceID != "" {
+			attrs = append(attrs, otlpString("notebit.trace_id", entry.TraceID))
+		}
+		traceID = randomHex(16)
+	}
+	for k, v := range entry.Fields {
+		attrs = append(attrs, otlpAttribute(k, v))
+	}
+	status := otlpStatus{}
+	if entry.Level >= ERROR {
+		status = otlpStatus{Code: 2, Message: entry.Message}
+	}
+	name := entry.MethodName
+	if name == "" {
+		name = entry.Message
+	}
+	return otlpSpan{
+		TraceID:           traceID,
+		SpanID:            randomHex(8),
+		Name:              name,
+		Kind:              1,
+		StartTimeUnixNano: unixNano(entry.Time.Add(-entry.Duration)),
+		EndTimeUnixNano:   unixNano(entry.Time),
+		Attributes:        attrs,
+		Status:            status,
+	}
+}
+
+func (e *OTLPExporter) resource() map[string]any {
+	return map[string]any{"attributes": []otlpKeyValue{otlpString("service.name", e.serviceName)}}
+}
+
+func (e *OTLPExporter) tracesPayload(spans []otlpSpan) map[string]any {
+	return map[string]any{"resourceSpans": []any{map[string]any{
+		"resource": e.resource(),
+		"scopeSpans": []any{map[string]any{
+			"scope": map[string]string{"name": otlpScopeName},
+			"spans": spans,
+		}},
+	}}}
+}
+
+// metricsPayload exposes a Metrics snapshot: counters as cumulative sums
+// since the exporter started, queue length and latencies as gauges
+func (e *OTLPExporter) metricsPayload(snap MetricsSnapshot, now time.Time) map[string]any {
+	start, ts := unixNano(e.startTime), unixNano(now)
+	gauge := func(name, unit, description string, value int64) otlpMetric {
+		m := otlpMetric{Name: name, Unit: unit, Description: description}
+		m.Gauge = &struct {
+			DataPoints []otlpDataPoint `json:"dataPoints"`
+		}{[]otlpDataPoint{{TimeUnixNano: ts, AsInt: strconv.FormatInt(value, 10)}}}
+		return m
+	}
+	sum := func(name, description string, points ...otlpDataPoint) otlpMetric {
+		m := otlpMetric{Name: name, Unit: "1", Description: description}
+		for i := range points {
+			points[i].StartTimeUnixNano, points[i].TimeUnixNano = start, ts
+		}
+		m.Sum = &struct {
+			DataPoints             []otlpDataPoint `json:"dataPoints"`
+			AggregationTemporality int             `json:"aggregationTemporality"`
+			IsMonotonic            bool            `json:"isMonotonic"`
+		}{points, 2, true}
+		return m
+	}
+	count := func(value uint64, attrs ...otlpKeyValue) otlpDataPoint {
+		return otlpDataPoint{Attributes: attrs, AsInt: strconv.FormatUint(value, 10)}
+	}
+
+	metrics := []otlpMetric{
+		sum("logger.logs", "Log entries processed, by level",
+			count(snap.DebugCount, otlpString("level", DEBUG.String())),
+			count(snap.InfoCount, otlpString("level", INFO.String())),
+			count(snap.WarnCount, otlpString("level", WARN.String())),
+			count(snap.ErrorCount, otlpString("level", ERROR.String())),
+			count(snap.FatalCount, otlpString("level", FATAL.String()))),
+		sum("logger.dropped", "Log entries dropped because the queue was full", count(snap.DroppedLogs)),
+		sum("logger.batches", "Batches flushed", count(snap.BatchCount)),
+		sum("logger.otlp.dropped_spans", "Spans dropped because the export buffer was full", count(e.droppedSpans.Load())),
+		sum("logger.otlp.export_errors", "Failed OTLP export requests", count(e.exportErrors.Load())),
+		gauge("logger.queue_length", "1", "Entries waiting in the log queue", snap.QueueLength),
+		gauge("logger.flush_latency.last", "us", "Latency of the last flush", snap.LastFlushLatency),
+		gauge("logger.flush_latency.avg", "us", "Moving average flush latency", snap.AvgFlushLatency),
+		gauge("logger.flush_latency.max", "us", "Maximum flush latency", snap.MaxFlushLatency),
+		gauge("logger.batch_size.avg", "1", "Moving average batch size", snap.AvgBatchSize),
+	}
+	return map[string]any{"resourceMetrics": []any{map[string]any{
+		"resource": e.resource(),
+		"scopeMetrics": []any{map[string]any{
+			"scope":   map[string]string{"name": otlpScopeName},
+			"metrics": metrics,
+		}},
+	}}}
+}
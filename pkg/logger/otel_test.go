@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporterSendsSpansAndMetrics(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] += string(data)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	l, err := New(Config{
+		Level:                INFO,
+		LogDir:               t.TempDir(),
+		FileName:             "otel.log",
+		OTLPEnabled:          true,
+		OTLPEndpoint:         server.URL + "/",
+		OTLPServiceName:      "notebit-test",
+		OTLPExportIntervalMS: 60000,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	traceID := NewTraceID()
+	ctx := WithFields(WithTraceID(context.Background(), traceID), map[string]interface{}{"chunks": 3})
+	l.InfoWithDuration(ctx, 25*time.Millisecond, "Indexed note")
+	l.Info("No duration, no span")
+	l.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var traces struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string         `json:"traceId"`
+					StartTimeUnixNano string         `json:"startTimeUnixNano"`
+					EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+					Attributes        []otlpKeyValue `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal([]byte(bodies["/v1/traces"]), &traces); err != nil {
+		t.Fatalf("Invalid traces payload %q: %v", bodies["/v1/traces"], err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].TraceID != traceID {
+		t.Errorf("Expected trace ID %s, got %s", traceID, spans[0].TraceID)
+	}
+	if spans[0].StartTimeUnixNano >= spans[0].EndTimeUnixNano {
+		t.Errorf("Span does not cover the duration: %s-%s", spans[0].StartTimeUnixNano, spans[0].EndTimeUnixNano)
+	}
+	foundField := false
+	for _, attr := range spans[0].Attributes {
+		if attr.Key == "chunks" && attr.Value.IntValue != nil && *attr.Value.IntValue == "3" {
+			foundField = true
+		}
+	}
+	if !foundField {
+		t.Errorf("Context fields missing from span attributes")
+	}
+
+	metrics := bodies["/v1/metrics"]
+	for _, name := range []string{"notebit-test", "logger.logs", "logger.dropped", "logger.queue_length", "logger.flush_latency.avg"} {
+		if !strings.Contains(metrics, name) {
+			t.Errorf("Metrics payload missing %s", name)
+		}
+	}
+}
+
+func TestNewOTLPExporterDisabled(t *testing.T) {
+	if e := NewOTLPExporter(Config{OTLPEndpoint: "http://localhost:4318"}, NewMetrics()); e != nil {
+		t.Fatal("Expected nil exporter when OTLP is disabled")
+	}
+	var e *OTLPExporter
+	e.Record(LogEntry{Duration: time.Second})
+	e.Close()
+}
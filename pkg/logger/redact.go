@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces masked field values
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactKeys are used when Config.RedactKeys is nil
+var DefaultRedactKeys = []string{"api_key", "apikey", "token", "authorization", "password", "passphrase", "secret", "cookie"}
+
+// Secrets that end up in messages rather than fields, e.g. from a formatted
+// request or error string
+var messageSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`(?i)((?:api[_-]?key|token|password|secret)\s*[=:]\s*)[^\s&,;"']+`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}`),
+}
+
+// redactEntry returns entry with secret fields masked and, when hashPaths is
+// set, path fields hashed. The caller's Fields map is not modified.
+func redactEntry(entry LogEntry, cfg Config) LogEntry {
+	keys := cfg.RedactKeys
+	if keys == nil {
+		keys = DefaultRedactKeys
+	}
+	if len(keys) > 0 {
+		entry.Message = redactMessage(entry.Message)
+	}
+	if len(entry.Fields) > 0 && (len(keys) > 0 || cfg.HashPaths) {
+		entry.Fields = redactFields(entry.Fields, keys, cfg.HashPaths)
+	}
+	return entry
+}
+
+// RedactText masks API keys, bearer tokens and passwords in free text, e.g. a
+// request payload about to be written somewhere
+func RedactText(text string) string {
+	return redactMessage(text)
+}
+
+func redactMessage(msg string) string {
+	for _, re := range messageSecretPatterns {
+		if re.NumSubexp() > 0 {
+			msg = re.ReplaceAllString(msg, "${1}"+RedactedValue)
+		} else {
+			msg = re.ReplaceAllString(msg, RedactedValue)
+		}
+	}
+	return msg
+}
+
+func redactFields(fields map[string]interface{}, keys []string, hashPaths bool) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		switch {
+		case matchesRedactKey(k, keys):
+			out[k] = RedactedValue
+		case hashPaths && isPathKey(k):
+			out[k] = hashPathValue(v)
+		default:
+			if nested, ok := v.(map[string]interface{}); ok {
+				v = redactFields(nested, keys, hashPaths)
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func matchesRedactKey(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range keys {
+		if pattern != "" && strings.Contains(key, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPathKey matches field names such as "path", "old_path" or "file_path"
+func isPathKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "path") || key == "file" || key == "folder"
+}
+
+// hashPathValue replaces a path with a short stable hash, keeping the
+// extension so log readers can still tell notes from attachments
+func hashPathValue(v interface{}) interface{} {
+	switch p := v.(type) {
+	case string:
+		return hashPath(p)
+	case []string:
+		hashed := make([]string, len(p))
+		for i, s := range p {
+			hashed[i] = hashPath(s)
+		}
+		return hashed
+	default:
+		return v
+	}
+}
+
+func hashPath(p string) string {
+	if p == "" {
+		return p
+	}
+	sum := sha256.Sum256([]byte(p))
+	return "path:" + hex.EncodeToString(sum[:6]) + filepath.Ext(p)
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactEntryMasksSecretFields(t *testing.T) {
+	fields := map[string]interface{}{
+		"api_key":       "sk-abcdefghijklmnopqrstuvwxyz",
+		"Authorization": "Bearer abc",
+		"request":       map[string]interface{}{"access_token": "t0k3n", "model": "gpt"},
+		"path":          "Private/Diary.md",
+	}
+	entry := redactEntry(LogEntry{Message: "calling with api_key=sk-live123 and Bearer xyz.abc", Fields: fields}, Config{})
+
+	if entry.Fields["api_key"] != RedactedValue || entry.Fields["Authorization"] != RedactedValue {
+		t.Errorf("Secret fields not masked: %v", entry.Fields)
+	}
+	nested := entry.Fields["request"].(map[string]interface{})
+	if nested["access_token"] != RedactedValue || nested["model"] != "gpt" {
+		t.Errorf("Nested fields not handled: %v", nested)
+	}
+	if entry.Fields["path"] != "Private/Diary.md" {
+		t.Errorf("Path hashed without HashPaths: %v", entry.Fields["path"])
+	}
+	if strings.Contains(entry.Message, "sk-live123") || strings.Contains(entry.Message, "xyz.abc") {
+		t.Errorf("Secret left in message: %s", entry.Message)
+	}
+	if fields["api_key"] == RedactedValue {
+		t.Error("Caller's fields were modified")
+	}
+}
+
+func TestRedactEntryHashesPaths(t *testing.T) {
+	cfg := Config{RedactKeys: []string{}, HashPaths: true}
+	entry := redactEntry(LogEntry{Fields: map[string]interface{}{"old_path": "Private/Diary.md", "token": "kept"}}, cfg)
+
+	hashed, _ := entry.Fields["old_path"].(string)
+	if !strings.HasPrefix(hashed, "path:") || !strings.HasSuffix(hashed, ".md") || strings.Contains(hashed, "Diary") {
+		t.Errorf("Path not hashed: %q", hashed)
+	}
+	if again := redactEntry(LogEntry{Fields: map[string]interface{}{"path": "Private/Diary.md"}}, cfg); again.Fields["path"] != hashed {
+		t.Errorf("Hash not stable: %v vs %v", again.Fields["path"], hashed)
+	}
+	if entry.Fields["token"] != "kept" {
+		t.Errorf("Empty RedactKeys should disable masking, got %v", entry.Fields["token"])
+	}
+}
+
+func TestRedactTextMasksSecretsInPayloads(t *testing.T) {
+	payload := `{"messages":[{"content":"my key is sk-abcdefghijklmnopqrstuv, header Bearer abc.def"}]}`
+	redacted := RedactText(payload)
+	if strings.Contains(redacted, "sk-abcdefghijklmnopqrstuv") || strings.Contains(redacted, "abc.def") {
+		t.Errorf("Secret left in payload: %s", redacted)
+	}
+	if !strings.Contains(redacted, `"messages"`) {
+		t.Errorf("Payload structure lost: %s", redacted)
+	}
+}
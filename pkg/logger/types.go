@@ -67,6 +67,13 @@ type Config struct {
 	KafkaEnabled    bool   // Whether to send logs to Kafka
 	KafkaBrokers    []string // Kafka broker addresses
 	KafkaTopic      string   // Kafka topic name (default: "app-logs")
+	RedactKeys           []string // Field key substrings whose values are masked (nil: DefaultRedactKeys, empty: off)
+	HashPaths            bool     // Replace path fields with a short hash
+	AuditFileName        string // Audit log file in LogDir (e.g., "audit.log"); empty disables auditing
+	OTLPEnabled          bool   // Whether to export spans and metrics over OTLP/HTTP
+	OTLPEndpoint         string // Collector base URL (e.g., "http://localhost:4318")
+	OTLPServiceName      string // service.name resource attribute (default: "notebit")
+	OTLPExportIntervalMS int    // Export interval in milliseconds (default: 10s)
 }
 
 // LogEntry represents a single log message
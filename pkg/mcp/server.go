@@ -0,0 +1,284 @@
+// Package mcp exposes the vault to external AI clients as a Model Context
+// Protocol server. Messages are JSON-RPC 2.0; the server is transport
+// agnostic and is served over HTTP by the local API (see pkg/httpapi).
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ProtocolVersion is the newest MCP revision the server implements
+const ProtocolVersion = "2025-03-26"
+
+// Backend answers the tool calls. It is implemented on top of the knowledge
+// and RAG services by the app.
+type Backend interface {
+	// SearchNotes runs a semantic search over the indexed chunks
+	SearchNotes(ctx context.Context, query string, limit int) (any, error)
+	ReadNote(path string) (string, error)
+	// ListRecent returns the most recently modified notes
+	ListRecent(limit int) (any, error)
+	// QueryRAG answers a question from the notes without touching chat history
+	QueryRAG(ctx context.Context, question string) (answer string, sources any, err error)
+}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Tool describes one tool in tools/list
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// Server handles MCP messages for one vault
+type Server struct {
+	backend Backend
+	version string
+}
+
+// NewServer creates a server reporting version as the server version
+func NewServer(backend Backend, version string) *Server {
+	return &Server{backend: backend, version: version}
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Tools lists the tools the server offers
+func (s *Server) Tools() []Tool {
+	limit := map[string]any{"type": "integer", "description": "Maximum number of results (default 10)", "minimum": 1, "maximum": 50}
+	return []Tool{
+		{
+			Name:        "search_notes",
+			Description: "Semantic search over the user's notes. Returns matching passages with their note path, heading and similarity.",
+			InputSchema: objectSchema(map[string]any{
+				"query": map[string]any{"type": "string", "description": "What to look for, in natural language"},
+				"limit": limit,
+			}, "query"),
+		},
+		{
+			Name:        "read_note",
+			Description: "Read the full markdown content of a note by its vault-relative path.",
+			InputSchema: objectSchema(map[string]any{
+				"path": map[string]any{"type": "string", "description": "Note path as returned by the other tools"},
+			}, "path"),
+		},
+		{
+			Name:        "list_recent",
+			Description: "List the most recently modified notes.",
+			InputSchema: objectSchema(map[string]any{"limit": limit}),
+		},
+		{
+			Name:        "query_rag",
+			Description: "Answer a question using the user's notes as context. Returns the answer and the notes it is based on.",
+			InputSchema: objectSchema(map[string]any{
+				"question": map[string]any{"type": "string"},
+			}, "question"),
+		},
+	}
+}
+
+// HandleMessage processes one JSON-RPC message and returns the encoded
+// response, or nil for notifications
+func (s *Server) HandleMessage(ctx context.Context, data []byte) []byte {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error"}})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return encode(response{JSONRPC: "2.0", ID: idOrNull(req.ID), Error: &rpcError{codeInvalidRequest, "invalid request"}})
+	}
+	if len(req.ID) == 0 {
+		return nil // notification, e.g. notifications/initialized
+	}
+
+	result, rpcErr := s.dispatch(ctx, req)
+	return encode(response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := ProtocolVersion
+		if params.ProtocolVersion != "" && params.ProtocolVersion < ProtocolVersion {
+			version = params.ProtocolVersion // older client, answer in its revision
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "notebit", "version": s.version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.Tools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid params"}
+		}
+		return s.callTool(ctx, params.Name, params.Arguments)
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method}
+	}
+}
+
+// callTool runs a tool. Tool failures are reported in the result so the
+// client's model can see them, protocol problems as JSON-RPC errors.
+func (s *Server) callTool(ctx context.Context, name string, rawArgs json.RawMessage) (any, *rpcError) {
+	var args struct {
+		Query    string `json:"query"`
+		Path     string `json:"path"`
+		Question string `json:"question"`
+		Limit    int    `json:"limit"`
+	}
+	if len(rawArgs) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, &rpcError{codeInvalidParams, "invalid arguments: " + err.Error()}
+		}
+	}
+	if args.Limit <= 0 || args.Limit > 50 {
+		args.Limit = 10
+	}
+
+	var (
+		text string
+		err  error
+	)
+	switch name {
+	case "search_notes":
+		if strings.TrimSpace(args.Query) == "" {
+			return nil, &rpcError{codeInvalidParams, "query is required"}
+		}
+		var results any
+		if results, err = s.backend.SearchNotes(ctx, args.Query, args.Limit); err == nil {
+			text, err = toJSON(results)
+		}
+	case "read_note":
+		if strings.TrimSpace(args.Path) == "" {
+			return nil, &rpcError{codeInvalidParams, "path is required"}
+		}
+		text, err = s.backend.ReadNote(args.Path)
+	case "list_recent":
+		var notes any
+		if notes, err = s.backend.ListRecent(args.Limit); err == nil {
+			text, err = toJSON(notes)
+		}
+	case "query_rag":
+		if strings.TrimSpace(args.Question) == "" {
+			return nil, &rpcError{codeInvalidParams, "question is required"}
+		}
+		var (
+			answer  string
+			sources any
+		)
+		if answer, sources, err = s.backend.QueryRAG(ctx, args.Question); err == nil {
+			var sourcesJSON string
+			if sourcesJSON, err = toJSON(sources); err == nil {
+				text = answer + "\n\nSources:\n" + sourcesJSON
+			}
+		}
+	default:
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + name}
+	}
+
+	if err != nil {
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}}, nil
+}
+
+// ServeHTTP implements the request side of the Streamable HTTP transport:
+// each POST carries one message and gets a JSON response
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out := s.HandleMessage(r.Context(), data)
+	if out == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode result: %w", err)
+	}
+	return string(data), nil
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+func encode(resp response) []byte {
+	data, _ := json.Marshal(resp)
+	return data
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct{}
+
+func (fakeBackend) SearchNotes(ctx context.Context, query string, limit int) (any, error) {
+	return []map[string]any{{"path": "Ideas.md", "limit": limit}}, nil
+}
+
+func (fakeBackend) ReadNote(path string) (string, error) {
+	if path != "Ideas.md" {
+		return "", errors.New("note not found")
+	}
+	return "# Ideas", nil
+}
+
+func (fakeBackend) ListRecent(limit int) (any, error) {
+	return []string{"Ideas.md"}, nil
+}
+
+func (fakeBackend) QueryRAG(ctx context.Context, question string) (string, any, error) {
+	return "Yes.", []string{"Ideas.md"}, nil
+}
+
+func call(t *testing.T, s *Server, msg string) map[string]any {
+	t.Helper()
+	out := s.HandleMessage(context.Background(), []byte(msg))
+	var resp map[string]any
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", out, err)
+	}
+	return resp
+}
+
+func TestServerLifecycleAndTools(t *testing.T) {
+	s := NewServer(fakeBackend{}, "test")
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	result := resp["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Fatalf("expected the client's older revision, got %v", result["protocolVersion"])
+	}
+	if out := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); out != nil {
+		t.Fatalf("notification got a response: %s", out)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	tools := resp["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 4 {
+		t.Fatalf("expected 4 tools, got %d", len(tools))
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"read_note","arguments":{"path":"Ideas.md"}}}`)
+	content := resp["result"].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if content["text"] != "# Ideas" {
+		t.Fatalf("read_note returned %v", content)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"read_note","arguments":{"path":"Missing.md"}}}`)
+	if resp["result"].(map[string]any)["isError"] != true {
+		t.Fatalf("backend error should be a tool error: %v", resp)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"query_rag","arguments":{"question":"Any ideas?"}}}`)
+	text := resp["result"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"].(string)
+	if !strings.HasPrefix(text, "Yes.") || !strings.Contains(text, "Ideas.md") {
+		t.Fatalf("query_rag returned %q", text)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"search_notes","arguments":{}}}`)
+	if resp["error"].(map[string]any)["code"] != float64(codeInvalidParams) {
+		t.Fatalf("missing query should be invalid params: %v", resp)
+	}
+	resp = call(t, s, `{"jsonrpc":"2.0","id":7,"method":"resources/list"}`)
+	if resp["error"].(map[string]any)["code"] != float64(codeMethodNotFound) {
+		t.Fatalf("unknown method: %v", resp)
+	}
+}
@@ -0,0 +1,265 @@
+// Package merge reconciles two edited versions of a note against the
+// version they both started from. Lines changed on one side only are taken
+// as is; lines changed differently on both sides become conflicts for the
+// user to settle.
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hunk kinds
+const (
+	KindSame     = "same"     // unchanged on both sides
+	KindLocal    = "local"    // changed locally only
+	KindRemote   = "remote"   // changed remotely only
+	KindBoth     = "both"     // the same change on both sides
+	KindConflict = "conflict" // different changes to the same lines
+)
+
+// Conflict resolutions accepted by Resolve
+const (
+	TakeLocal  = "local"
+	TakeRemote = "remote"
+	TakeBoth   = "both" // local lines followed by remote lines
+	TakeBase   = "base"
+)
+
+// Hunk is a run of lines with the same outcome. Base, Local and Remote hold
+// the text of the run in each version, including line endings.
+type Hunk struct {
+	Kind     string `json:"kind"`
+	BaseLine int    `json:"base_line"` // 1-based first line in base
+	Base     string `json:"base"`
+	Local    string `json:"local"`
+	Remote   string `json:"remote"`
+}
+
+// Result is a three-way merge. Merged holds the merged text, with conflicts
+// written between git-style markers.
+type Result struct {
+	Hunks     []Hunk `json:"hunks"`
+	Conflicts int    `json:"conflicts"`
+	Merged    string `json:"merged"`
+}
+
+// Clean reports whether the merge needs no decisions
+func (r *Result) Clean() bool {
+	return r.Conflicts == 0
+}
+
+// Conflict markers written into Result.Merged
+const (
+	MarkerLocal  = "<<<<<<< local\n"
+	MarkerSplit  = "=======\n"
+	MarkerRemote = ">>>>>>> remote\n"
+)
+
+// Merge merges local and remote, both derived from base
+func Merge(base, local, remote string) *Result {
+	b, l, r := lines(base), lines(local), lines(remote)
+	toLocal := matchLines(b, l)
+	toRemote := matchLines(b, r)
+
+	result := &Result{Hunks: []Hunk{}}
+	add := func(kind string, bi int, bs, ls, rs []string) {
+		h := Hunk{Kind: kind, BaseLine: bi + 1, Base: join(bs), Local: join(ls), Remote: join(rs)}
+		if last := len(result.Hunks) - 1; kind == KindSame && last >= 0 && result.Hunks[last].Kind == KindSame {
+			result.Hunks[last].Base += h.Base
+			result.Hunks[last].Local += h.Local
+			result.Hunks[last].Remote += h.Remote
+			return
+		}
+		if kind == KindConflict {
+			result.Conflicts++
+		}
+		result.Hunks = append(result.Hunks, h)
+	}
+
+	o, a, c := 0, 0, 0
+	for o < len(b) || a < len(l) || c < len(r) {
+		if o < len(b) && toLocal[o] == a && toRemote[o] == c {
+			add(KindSame, o, b[o:o+1], l[a:a+1], r[c:c+1])
+			o, a, c = o+1, a+1, c+1
+			continue
+		}
+		// Find the next base line kept on both sides; everything before it
+		// is one unstable chunk
+		i := o
+		for i < len(b) && (toLocal[i] < 0 || toRemote[i] < 0) {
+			i++
+		}
+		ja, jc := len(l), len(r)
+		if i < len(b) {
+			ja, jc = toLocal[i], toRemote[i]
+		}
+		bs, ls, rs := b[o:i], l[a:ja], r[c:jc]
+		switch {
+		case equal(ls, bs):
+			add(KindRemote, o, bs, ls, rs)
+		case equal(rs, bs):
+			add(KindLocal, o, bs, ls, rs)
+		case equal(ls, rs):
+			add(KindBoth, o, bs, ls, rs)
+		default:
+			add(KindConflict, o, bs, ls, rs)
+		}
+		o, a, c = i, ja, jc
+	}
+
+	result.Merged = render(result.Hunks, nil)
+	return result
+}
+
+// Resolve builds the final text, settling the conflicts in order with one
+// of TakeLocal, TakeRemote, TakeBoth or TakeBase each
+func Resolve(hunks []Hunk, choices []string) (string, error) {
+	conflicts := 0
+	for _, h := range hunks {
+		if h.Kind == KindConflict {
+			conflicts++
+		}
+	}
+	if len(choices) != conflicts {
+		return "", fmt.Errorf("expected %d conflict resolutions, got %d", conflicts, len(choices))
+	}
+	for _, c := range choices {
+		switch c {
+		case TakeLocal, TakeRemote, TakeBoth, TakeBase:
+		default:
+			return "", fmt.Errorf("unknown resolution %q", c)
+		}
+	}
+	return render(hunks, choices), nil
+}
+
+// render joins the hunks, writing markers for conflicts without a choice
+func render(hunks []Hunk, choices []string) string {
+	var sb strings.Builder
+	n := 0
+	for _, h := range hunks {
+		switch h.Kind {
+		case KindSame, KindLocal, KindBoth:
+			sb.WriteString(h.Local)
+		case KindRemote:
+			sb.WriteString(h.Remote)
+		case KindConflict:
+			choice := ""
+			if n < len(choices) {
+				choice = choices[n]
+			}
+			n++
+			switch choice {
+			case TakeLocal:
+				sb.WriteString(h.Local)
+			case TakeRemote:
+				sb.WriteString(h.Remote)
+			case TakeBoth:
+				sb.WriteString(withNewline(h.Local))
+				sb.WriteString(h.Remote)
+			case TakeBase:
+				sb.WriteString(h.Base)
+			default:
+				sb.WriteString(MarkerLocal)
+				sb.WriteString(withNewline(h.Local))
+				sb.WriteString(MarkerSplit)
+				sb.WriteString(withNewline(h.Remote))
+				sb.WriteString(MarkerRemote)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func withNewline(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		return s + "\n"
+	}
+	return s
+}
+
+// lines splits text after each newline so joining restores it exactly
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	parts := strings.SplitAfter(text, "\n")
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+func join(ls []string) string {
+	return strings.Join(ls, "")
+}
+
+func equal(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxTableCells bounds the LCS table; larger middles count as rewritten
+const maxTableCells = 4 << 20
+
+// matchLines maps each line of base to its line in other, or -1 when it was
+// removed, following a longest common subsequence
+func matchLines(base, other []string) []int {
+	match := make([]int, len(base))
+	for i := range match {
+		match[i] = -1
+	}
+	// Common prefix and suffix cover most edits and keep the table small
+	pre := 0
+	for pre < len(base) && pre < len(other) && base[pre] == other[pre] {
+		match[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(base)-pre && suf < len(other)-pre && base[len(base)-1-suf] == other[len(other)-1-suf] {
+		match[len(base)-1-suf] = len(other) - 1 - suf
+		suf++
+	}
+	b, o := base[pre:len(base)-suf], other[pre:len(other)-suf]
+	n, m := len(b), len(o)
+	if n == 0 || m == 0 || n*m > maxTableCells {
+		return match
+	}
+
+	// lcs[i][j] is the LCS length of b[i:] and o[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	at := func(i, j int) int32 { return lcs[i*(m+1)+j] }
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case b[i] == o[j]:
+				lcs[i*(m+1)+j] = at(i+1, j+1) + 1
+			case at(i+1, j) >= at(i, j+1):
+				lcs[i*(m+1)+j] = at(i+1, j)
+			default:
+				lcs[i*(m+1)+j] = at(i, j+1)
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case b[i] == o[j]:
+			match[pre+i] = pre + j
+			i++
+			j++
+		case at(i+1, j) >= at(i, j+1):
+			i++
+		default:
+			j++
+		}
+	}
+	return match
+}
@@ -0,0 +1,78 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+)
+
+const base = "# Plan\n\nGoals:\n- ship\n- rest\n\nNotes:\nnone\n"
+
+func TestMergeNonOverlappingChanges(t *testing.T) {
+	local := strings.Replace(base, "- ship\n", "- ship v2\n", 1)
+	remote := strings.Replace(base, "none\n", "call Anna\n", 1) + "Footer\n"
+
+	r := Merge(base, local, remote)
+	if !r.Clean() {
+		t.Fatalf("expected a clean merge, got %d conflicts: %+v", r.Conflicts, r.Hunks)
+	}
+	want := "# Plan\n\nGoals:\n- ship v2\n- rest\n\nNotes:\ncall Anna\nFooter\n"
+	if r.Merged != want {
+		t.Fatalf("merged:\n%s\nwant:\n%s", r.Merged, want)
+	}
+	kinds := []string{}
+	for _, h := range r.Hunks {
+		kinds = append(kinds, h.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "same,local,same,remote" {
+		t.Fatalf("hunk kinds: %s", got)
+	}
+}
+
+func TestMergeIdenticalChangeIsNotAConflict(t *testing.T) {
+	edited := strings.Replace(base, "- rest\n", "- sleep\n", 1)
+	r := Merge(base, edited, edited)
+	if !r.Clean() || r.Merged != edited {
+		t.Fatalf("unexpected merge: %+v", r)
+	}
+}
+
+func TestMergeConflictAndResolve(t *testing.T) {
+	local := strings.Replace(base, "- rest\n", "- rest a lot\n", 1)
+	remote := strings.Replace(base, "- rest\n", "- rest a little\n", 1)
+
+	r := Merge(base, local, remote)
+	if r.Conflicts != 1 {
+		t.Fatalf("expected 1 conflict, got %d", r.Conflicts)
+	}
+	var conflict Hunk
+	for _, h := range r.Hunks {
+		if h.Kind == KindConflict {
+			conflict = h
+		}
+	}
+	if conflict.BaseLine != 5 || conflict.Base != "- rest\n" || conflict.Local != "- rest a lot\n" || conflict.Remote != "- rest a little\n" {
+		t.Fatalf("conflict hunk: %+v", conflict)
+	}
+	if !strings.Contains(r.Merged, MarkerLocal+"- rest a lot\n"+MarkerSplit+"- rest a little\n"+MarkerRemote) {
+		t.Fatalf("markers missing:\n%s", r.Merged)
+	}
+
+	resolved, err := Resolve(r.Hunks, []string{TakeBoth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resolved, "- rest a lot\n- rest a little\n") || strings.Contains(resolved, "<<<<") {
+		t.Fatalf("resolved:\n%s", resolved)
+	}
+	if _, err := Resolve(r.Hunks, nil); err == nil {
+		t.Fatal("expected an error for missing resolutions")
+	}
+}
+
+func TestMergeDeleteVersusEdit(t *testing.T) {
+	local := strings.Replace(base, "Notes:\nnone\n", "", 1)
+	remote := strings.Replace(base, "none\n", "something\n", 1)
+	if r := Merge(base, local, remote); r.Conflicts != 1 {
+		t.Fatalf("deleting lines edited on the other side must conflict: %+v", r.Hunks)
+	}
+}
@@ -0,0 +1,171 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"notebit/pkg/ai"
+)
+
+// Note action types the assistant may propose
+const (
+	ActionCreateNote   = "create_note"
+	ActionAppendToNote = "append_to_note"
+	ActionAddTag       = "add_tag"
+)
+
+// NoteAction is a note edit proposed by the assistant. It does nothing until
+// the user confirms it.
+type NoteAction struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
+	Path      string `json:"path,omitempty"` // Target note; for create_note, the title
+	Content   string `json:"content,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Reason    string `json:"reason,omitempty"` // The assistant's explanation for the user
+	CreatedAt int64  `json:"created_at"`       // Unix ms
+}
+
+// ActionStore holds proposed note actions until they are confirmed or rejected
+type ActionStore struct {
+	mu      sync.Mutex
+	pending map[string]NoteAction
+}
+
+// NewActionStore creates an empty action store
+func NewActionStore() *ActionStore {
+	return &ActionStore{pending: make(map[string]NoteAction)}
+}
+
+// Add stores a proposed action, assigning its ID and timestamp
+func (s *ActionStore) Add(action NoteAction) NoteAction {
+	action.ID = strings.Replace(generateMessageID(), "msg_", "act_", 1)
+	action.CreatedAt = time.Now().UnixMilli()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[action.ID] = action
+	return action
+}
+
+// List returns pending actions, oldest first; a non-empty sessionID filters by session
+func (s *ActionStore) List(sessionID string) []NoteAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]NoteAction, 0, len(s.pending))
+	for _, action := range s.pending {
+		if sessionID == "" || action.SessionID == sessionID {
+			out = append(out, action)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+	return out
+}
+
+// Take removes and returns a pending action
+func (s *ActionStore) Take(id string) (NoteAction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	action, ok := s.pending[id]
+	if ok {
+		delete(s.pending, id)
+	}
+	return action, ok
+}
+
+// ActionProposals collects the actions proposed during a single query
+type ActionProposals struct {
+	store     *ActionStore
+	sessionID string
+
+	mu       sync.Mutex
+	proposed []NoteAction
+}
+
+// NewActionProposals returns a collector that adds proposals for sessionID to store
+func NewActionProposals(store *ActionStore, sessionID string) *ActionProposals {
+	return &ActionProposals{store: store, sessionID: sessionID}
+}
+
+// Actions returns the actions proposed so far
+func (p *ActionProposals) Actions() []NoteAction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]NoteAction(nil), p.proposed...)
+}
+
+func (p *ActionProposals) propose(action NoteAction) string {
+	action.SessionID = p.sessionID
+	action = p.store.Add(action)
+	p.mu.Lock()
+	p.proposed = append(p.proposed, action)
+	p.mu.Unlock()
+	return fmt.Sprintf("Proposed action %s. It will only take effect once the user confirms it; do not claim it is done.", action.ID)
+}
+
+// Tools returns the create_note, append_to_note and add_tag tools. Calling
+// them records a proposal instead of editing the vault.
+func (p *ActionProposals) Tools() []Tool {
+	return []Tool{
+		p.tool(ActionCreateNote,
+			"Propose creating a new note. The user must confirm before it is created.",
+			`{"type":"object","properties":{"title":{"type":"string"},"content":{"type":"string","description":"Markdown content"},"reason":{"type":"string"}},"required":["title","content"]}`,
+			func(args actionArgs) (NoteAction, error) {
+				if strings.TrimSpace(args.Title) == "" {
+					return NoteAction{}, fmt.Errorf("title cannot be empty")
+				}
+				return NoteAction{Path: strings.TrimSpace(args.Title), Content: args.Content}, nil
+			}),
+		p.tool(ActionAppendToNote,
+			"Propose appending Markdown to the end of an existing note. The user must confirm before the note changes.",
+			`{"type":"object","properties":{"path":{"type":"string","description":"Note path relative to the vault root"},"content":{"type":"string"},"reason":{"type":"string"}},"required":["path","content"]}`,
+			func(args actionArgs) (NoteAction, error) {
+				if strings.TrimSpace(args.Path) == "" || strings.TrimSpace(args.Content) == "" {
+					return NoteAction{}, fmt.Errorf("path and content are required")
+				}
+				return NoteAction{Path: args.Path, Content: args.Content}, nil
+			}),
+		p.tool(ActionAddTag,
+			"Propose adding a tag to a note's front matter. The user must confirm before the note changes.",
+			`{"type":"object","properties":{"path":{"type":"string"},"tag":{"type":"string","description":"Tag without the leading #"},"reason":{"type":"string"}},"required":["path","tag"]}`,
+			func(args actionArgs) (NoteAction, error) {
+				tag := strings.TrimPrefix(strings.TrimSpace(args.Tag), "#")
+				if strings.TrimSpace(args.Path) == "" || tag == "" {
+					return NoteAction{}, fmt.Errorf("path and tag are required")
+				}
+				return NoteAction{Path: args.Path, Tag: tag}, nil
+			}),
+	}
+}
+
+type actionArgs struct {
+	Title   string `json:"title"`
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Tag     string `json:"tag"`
+	Reason  string `json:"reason"`
+}
+
+func (p *ActionProposals) tool(name, description, schema string, build func(actionArgs) (NoteAction, error)) Tool {
+	return Tool{
+		Definition: ai.NewFunctionTool(name, description, schema),
+		Run: func(ctx context.Context, arguments string) (string, error) {
+			var args actionArgs
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			action, err := build(args)
+			if err != nil {
+				return "", err
+			}
+			action.Type = name
+			action.Reason = args.Reason
+			return p.propose(action), nil
+		},
+	}
+}
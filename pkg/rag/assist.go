@@ -0,0 +1,210 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/database"
+)
+
+// maxAssistContentRunes bounds how much of a note is sent in assist prompts
+const maxAssistContentRunes = 6000
+
+// maxPromptTags bounds how many existing vault tags are listed in the prompt
+const maxPromptTags = 200
+
+// existingTagBoost ranks tags already used in the vault above new ones of similar confidence
+const existingTagBoost = 0.15
+
+// TagSuggestion is a tag proposed for a note
+type TagSuggestion struct {
+	Tag       string  `json:"tag"`
+	Score     float32 `json:"score"`
+	Existing  bool    `json:"existing"`   // Already used elsewhere in the vault
+	NoteCount int     `json:"note_count"` // Notes using the tag, 0 for new tags
+}
+
+const suggestTagsPrompt = `You suggest tags for notes in a personal knowledge base.
+Reply with only a JSON array of objects like {"tag": "example", "confidence": 0.8}, where confidence is between 0 and 1.
+Prefer tags from the existing list whenever one fits; only propose a new tag when none does.
+New tags are lowercase, without "#", with words joined by "-".`
+
+// SuggestTags asks the LLM for tags that fit content, ranked by confidence with
+// a boost for tags already used in the vault. Tags in current are left out.
+func (s *Service) SuggestTags(ctx context.Context, content string, vaultTags []database.TagCount, current []string, limit int) ([]TagSuggestion, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("note is empty")
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+
+	existing := make(map[string]database.TagCount, len(vaultTags))
+	names := make([]string, 0, min(len(vaultTags), maxPromptTags))
+	for i, tag := range vaultTags {
+		existing[strings.ToLower(tag.Name)] = tag
+		if i < maxPromptTags {
+			names = append(names, tag.Name)
+		}
+	}
+	skip := make(map[string]bool, len(current))
+	for _, tag := range current {
+		skip[strings.ToLower(tag)] = true
+	}
+
+	var prompt strings.Builder
+	if len(names) > 0 {
+		prompt.WriteString("Existing tags (most used first): " + strings.Join(names, ", ") + "\n\n")
+	}
+	if len(current) > 0 {
+		prompt.WriteString("Tags already on the note (do not repeat): " + strings.Join(current, ", ") + "\n\n")
+	}
+	prompt.WriteString(fmt.Sprintf("Suggest up to %d tags for this note:\n\n%s", limit, truncateContent(content, maxAssistContentRunes)))
+
+	var reply []struct {
+		Tag        string  `json:"tag"`
+		Confidence float32 `json:"confidence"`
+	}
+	if err := s.completeJSON(ctx, suggestTagsPrompt, prompt.String(), &reply); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	suggestions := make([]TagSuggestion, 0, len(reply))
+	for _, r := range reply {
+		tag := strings.TrimPrefix(strings.TrimSpace(r.Tag), "#")
+		key := strings.ToLower(tag)
+		if tag == "" || skip[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		suggestion := TagSuggestion{Tag: tag, Score: min(max(r.Confidence, 0), 1)}
+		if known, ok := existing[key]; ok {
+			suggestion.Tag = known.Name
+			suggestion.Existing = true
+			suggestion.NoteCount = known.Count
+			suggestion.Score = min(suggestion.Score+existingTagBoost, 1)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+const flashcardsPrompt = `You write study flashcards from a user's note.
+Reply with only a JSON array of objects like {"question": "...", "answer": "..."}.
+Each card tests one fact or idea from the note. Questions stand on their own without the note; answers are short.
+Only use information that is in the note.`
+
+// GenerateFlashcards asks the LLM for up to limit question/answer pairs covering content
+func (s *Service) GenerateFlashcards(ctx context.Context, content string, limit int) ([]database.FlashcardInput, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("note is empty")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	prompt := fmt.Sprintf("Write up to %d flashcards for this note:\n\n%s", limit, truncateContent(content, maxAssistContentRunes))
+
+	var cards []database.FlashcardInput
+	if err := s.completeJSON(ctx, flashcardsPrompt, prompt, &cards); err != nil {
+		return nil, err
+	}
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+	return cards, nil
+}
+
+// complete runs a single-turn completion for an assist feature
+func (s *Service) complete(ctx context.Context, systemPrompt, prompt string, temperature float32) (string, error) {
+	s.mu.RLock()
+	llm := s.llm
+	s.mu.RUnlock()
+	if llm == nil {
+		return "", fmt.Errorf("LLM provider is not configured")
+	}
+
+	completion, err := llm.GenerateCompletion(ai.WithOperation(ctx, ai.OperationAssist), &ai.CompletionRequest{
+		Messages: []ai.ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Model:       s.cfg.GetLLMConfig().Model,
+		Temperature: temperature,
+		MaxTokens:   s.cfg.GetLLMConfig().MaxTokens,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate completion: %w", err)
+	}
+	return completion.Content, nil
+}
+
+// completeJSON runs a completion and decodes the JSON value in the reply into v.
+// Models often wrap JSON in prose or code fences, so the outermost array or
+// object is extracted first.
+func (s *Service) completeJSON(ctx context.Context, systemPrompt, prompt string, v interface{}) error {
+	reply, err := s.complete(ctx, systemPrompt, prompt, 0.2)
+	if err != nil {
+		return err
+	}
+	raw := extractJSON(reply)
+	if raw == "" {
+		return fmt.Errorf("model reply did not contain JSON")
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return fmt.Errorf("failed to parse model reply: %w", err)
+	}
+	return nil
+}
+
+// extractJSON returns the span from the first '[' or '{' to the matching last bracket
+func extractJSON(reply string) string {
+	start := strings.IndexAny(reply, "[{")
+	if start < 0 {
+		return ""
+	}
+	closing := "]"
+	if reply[start] == '{' {
+		closing = "}"
+	}
+	end := strings.LastIndex(reply, closing)
+	if end < start {
+		return ""
+	}
+	return reply[start : end+1]
+}
+
+// maxTitleRunes caps generated session titles
+const maxTitleRunes = 60
+
+const sessionTitlePrompt = `You name chat conversations. Reply with only a short title of at most 8 words that describes the topic, in the language of the conversation, without quotes or trailing punctuation.`
+
+// GenerateSessionTitle asks the LLM for a concise title for a conversation
+// from its first question and answer
+func (s *Service) GenerateSessionTitle(ctx context.Context, question, answer string) (string, error) {
+	prompt := fmt.Sprintf("Question: %s\n\nAnswer: %s", truncateContent(question, 1000), truncateContent(answer, 1500))
+	reply, err := s.complete(ctx, sessionTitlePrompt, prompt, 0.3)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSpace(reply)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimSpace(strings.TrimPrefix(title, "Title:"))
+	title = strings.Trim(title, "\"'“”「」*# ")
+	title = strings.TrimRight(title, ".。!！")
+	if title == "" {
+		return "", fmt.Errorf("model returned an empty title")
+	}
+	return truncateContent(title, maxTitleRunes), nil
+}
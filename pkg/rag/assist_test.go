@@ -0,0 +1,57 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestSuggestTags(t *testing.T) {
+	llm := &replyLLM{reply: "Here you go:\n" + `[
+		{"tag": "#Projects", "confidence": 0.7},
+		{"tag": "golang", "confidence": 0.8},
+		{"tag": "projects", "confidence": 0.9},
+		{"tag": "draft", "confidence": 0.95},
+		{"tag": " ", "confidence": 1},
+		{"tag": "new-idea", "confidence": 1.7},
+		{"tag": "misc", "confidence": 0.1}
+	]`}
+	s := newReplyService(llm)
+	vaultTags := []database.TagCount{{Name: "projects", Count: 12}, {Name: "Golang", Count: 3}}
+
+	got, err := s.SuggestTags(context.Background(), "A note about Go projects", vaultTags, []string{"Draft"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Scores are clamped, existing tags are boosted and keep the vault's
+	// spelling, current tags and repeats are dropped
+	want := []TagSuggestion{
+		{Tag: "new-idea", Score: 1},
+		{Tag: "Golang", Score: 0.95, Existing: true, NoteCount: 3},
+		{Tag: "projects", Score: 0.85, Existing: true, NoteCount: 12},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("SuggestTags = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if diff := got[i].Score - want[i].Score; diff > 1e-6 || diff < -1e-6 {
+			t.Errorf("suggestion %d score = %v, want %v", i, got[i].Score, want[i].Score)
+		}
+		got[i].Score = want[i].Score
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SuggestTags = %+v, want %+v", got, want)
+	}
+
+	prompt := llm.requests[0].Messages[1].Content
+	if !strings.Contains(prompt, "Existing tags (most used first): projects, Golang") || !strings.Contains(prompt, "do not repeat): Draft") {
+		t.Fatalf("prompt = %q", prompt)
+	}
+
+	if _, err := s.SuggestTags(context.Background(), "  ", vaultTags, nil, 3); err == nil {
+		t.Fatal("SuggestTags of an empty note should fail")
+	}
+}
@@ -0,0 +1,76 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/logger"
+)
+
+// maxFollowUps and maxActionItems bound what the follow-up pass returns
+const (
+	maxFollowUps   = 3
+	maxActionItems = 10
+)
+
+// maxFollowUpTokens keeps the follow-up pass cheap
+const maxFollowUpTokens = 400
+
+const followUpsPrompt = `You read a question and the answer it got from a user's notes.
+Reply with only a JSON object like {"follow_ups": ["..."], "action_items": ["..."]}.
+follow_ups: up to 3 short questions the user might ask next, in the language of the question.
+action_items: the concrete tasks or to-dos stated in the answer, each as a short imperative sentence; an empty array if there are none.`
+
+// addFollowUps fills response.FollowUps and response.ActionItems from a
+// second LLM pass. A failure only leaves them empty. Callers hold s.mu.
+func (s *Service) addFollowUps(ctx context.Context, query string, response *ChatResponse) {
+	if s.llm == nil || strings.TrimSpace(response.Content) == "" {
+		return
+	}
+	completion, err := s.llm.GenerateCompletion(ai.WithOperation(ctx, ai.OperationAssist), &ai.CompletionRequest{
+		Messages: []ai.ChatMessage{
+			{Role: "system", Content: followUpsPrompt},
+			{Role: "user", Content: fmt.Sprintf("Question: %s\n\nAnswer: %s", truncateContent(query, 1000), truncateContent(response.Content, maxAssistContentRunes))},
+		},
+		Model:       s.cfg.GetLLMConfig().Model,
+		Temperature: 0.2,
+		MaxTokens:   maxFollowUpTokens,
+	})
+	if err != nil {
+		logger.Warn("Follow-up extraction failed: %v", err)
+		return
+	}
+	var reply struct {
+		FollowUps   []string `json:"follow_ups"`
+		ActionItems []string `json:"action_items"`
+	}
+	raw := extractJSON(completion.Content)
+	if raw == "" || json.Unmarshal([]byte(raw), &reply) != nil {
+		logger.Warn("Follow-up extraction returned no usable JSON")
+		return
+	}
+	response.FollowUps = cleanItems(reply.FollowUps, maxFollowUps)
+	response.ActionItems = cleanItems(reply.ActionItems, maxActionItems)
+}
+
+// cleanItems trims items, drops empty and repeated ones and keeps at most limit
+func cleanItems(items []string, limit int) []string {
+	seen := make(map[string]bool, len(items))
+	var cleaned []string
+	for _, item := range items {
+		item = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(item), "-*•"))
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, item)
+		if len(cleaned) == limit {
+			break
+		}
+	}
+	return cleaned
+}
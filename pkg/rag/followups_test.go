@@ -0,0 +1,38 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAddFollowUps(t *testing.T) {
+	ctx := context.Background()
+	llm := &replyLLM{reply: `{"follow_ups": ["What next?", "what next?", "", "How long?", "Who?", "Why?"], "action_items": ["- Email Sam", "• Book room", "email sam"]}`}
+	response := &ChatResponse{Content: "Email Sam and book a room."}
+	newReplyService(llm).addFollowUps(ctx, "What do I do?", response)
+
+	if want := []string{"What next?", "How long?", "Who?"}; !reflect.DeepEqual(response.FollowUps, want) {
+		t.Errorf("FollowUps = %q, want %q", response.FollowUps, want)
+	}
+	if want := []string{"Email Sam", "Book room"}; !reflect.DeepEqual(response.ActionItems, want) {
+		t.Errorf("ActionItems = %q, want %q", response.ActionItems, want)
+	}
+
+	// Failures and empty answers leave the response as it was
+	for _, tt := range []struct {
+		llm     *replyLLM
+		content string
+	}{
+		{&replyLLM{err: errors.New("offline")}, "answer"},
+		{&replyLLM{reply: "not json"}, "answer"},
+		{&replyLLM{reply: `{"follow_ups": ["x"]}`}, "  "},
+	} {
+		response := &ChatResponse{Content: tt.content}
+		newReplyService(tt.llm).addFollowUps(ctx, "q", response)
+		if response.FollowUps != nil || response.ActionItems != nil {
+			t.Errorf("reply %q for %q: follow-ups %q, actions %q", tt.llm.reply, tt.content, response.FollowUps, response.ActionItems)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+	"notebit/pkg/logger"
+)
+
+const queryVariationsPrompt = `You help search a personal note collection.
+Reply with only a JSON array of %d strings: different phrasings of the user's question as someone might have written about it in their notes, using other words and related terms, in the language of the question.`
+
+// queryVariations asks the LLM for n rephrasings of query. Callers hold s.mu.
+func (s *Service) queryVariations(ctx context.Context, query string, n int) ([]string, error) {
+	if s.llm == nil {
+		return nil, fmt.Errorf("LLM provider is not configured")
+	}
+	completion, err := s.llm.GenerateCompletion(ctx, &ai.CompletionRequest{
+		Messages: []ai.ChatMessage{
+			{Role: "system", Content: fmt.Sprintf(queryVariationsPrompt, n)},
+			{Role: "user", Content: query},
+		},
+		Model:       s.cfg.GetLLMConfig().Model,
+		Temperature: 0.7,
+		MaxTokens:   maxRewriteTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw := extractJSON(completion.Content)
+	if raw == "" {
+		return nil, fmt.Errorf("model reply did not contain JSON")
+	}
+	var reply []string
+	if err := json.Unmarshal([]byte(raw), &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse model reply: %w", err)
+	}
+
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	variations := make([]string, 0, n)
+	for _, v := range reply {
+		v = strings.TrimSpace(v)
+		if key := strings.ToLower(v); v != "" && !seen[key] {
+			seen[key] = true
+			variations = append(variations, v)
+		}
+		if len(variations) == n {
+			break
+		}
+	}
+	return variations, nil
+}
+
+// searchVariations retrieves chunks for n rephrasings of query and merges
+// them into found. Retrieval falls back to found alone if the variations
+// cannot be generated. Callers hold s.mu.
+func (s *Service) searchVariations(ctx context.Context, repo *database.Repository, query string, n int, scope []string, limit int, found []database.SimilarChunk) []database.SimilarChunk {
+	n = min(n, config.MaxMultiQuery)
+	variations, err := s.queryVariations(ctx, query, n)
+	if err == nil && len(variations) == 0 {
+		return found
+	}
+	var embeddings []*ai.EmbeddingResponse
+	if err == nil {
+		embeddings, err = s.ai.GenerateEmbeddingsBatch(ctx, variations)
+	}
+	if err != nil {
+		logger.Warn("Multi-query retrieval skipped: %v", err)
+		return found
+	}
+
+	vectors := make([][]float32, 0, len(embeddings))
+	for _, e := range embeddings {
+		if e != nil && len(e.Embedding) > 0 {
+			vectors = append(vectors, e.Embedding)
+		}
+	}
+	lists := [][]database.SimilarChunk{found}
+	if scope != nil {
+		for _, vec := range vectors {
+			chunks, err := repo.SearchSimilarInPaths(scope, vec, limit)
+			if err != nil {
+				logger.Warn("Multi-query search failed: %v", err)
+				continue
+			}
+			lists = append(lists, chunks)
+		}
+	} else if len(vectors) > 0 {
+		batch, err := repo.SearchSimilarBatch(vectors, limit)
+		if err != nil {
+			logger.Warn("Multi-query search failed: %v", err)
+		}
+		lists = append(lists, batch...)
+	}
+	return dedupeChunks(lists, limit)
+}
+
+// dedupeChunks merges result lists into the limit most similar chunks. A
+// chunk found by several queries keeps its best score, and a chunk repeating
+// the content of another from the same note is dropped.
+func dedupeChunks(lists [][]database.SimilarChunk, limit int) []database.SimilarChunk {
+	type contentKey struct {
+		path    string
+		content string
+	}
+	var all []database.SimilarChunk
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Similarity > all[j].Similarity })
+
+	seenIDs := make(map[uint]bool, len(all))
+	seenContent := make(map[contentKey]bool, len(all))
+	merged := make([]database.SimilarChunk, 0, min(len(all), limit))
+	for _, chunk := range all {
+		if seenIDs[chunk.ChunkID] {
+			continue
+		}
+		seenIDs[chunk.ChunkID] = true
+		if chunk.File != nil {
+			key := contentKey{chunk.File.Path, strings.TrimSpace(chunk.Content)}
+			if seenContent[key] {
+				continue
+			}
+			seenContent[key] = true
+		}
+		merged = append(merged, chunk)
+		if len(merged) == limit {
+			break
+		}
+	}
+	return merged
+}
@@ -0,0 +1,73 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"notebit/pkg/database"
+)
+
+func TestQueryVariations(t *testing.T) {
+	llm := &replyLLM{reply: "Sure:\n```json\n[\"How do I take notes?\", \"Note-taking methods\", \" \", \"note-taking METHODS\", \"Writing things down\", \"Journaling\", \"Extra\"]\n```"}
+	s := newReplyService(llm)
+
+	got, err := s.queryVariations(context.Background(), "how do I take notes?", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The question itself, repeats and blanks are dropped, and at most n kept
+	want := []string{"Note-taking methods", "Writing things down", "Journaling"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("queryVariations = %q, want %q", got, want)
+	}
+	if len(llm.requests) != 1 || llm.requests[0].Messages[1].Content != "how do I take notes?" {
+		t.Fatalf("requests = %+v", llm.requests)
+	}
+
+	for _, llm := range []*replyLLM{{reply: "no json here"}, {reply: `{"not": "an array"}`}, {err: errors.New("offline")}} {
+		if _, err := newReplyService(llm).queryVariations(context.Background(), "q", 3); err == nil {
+			t.Errorf("reply %q, error %v: want an error", llm.reply, llm.err)
+		}
+	}
+	if _, err := NewService(nil, nil, nil, nil).queryVariations(context.Background(), "q", 3); err == nil {
+		t.Error("queryVariations without an LLM should fail")
+	}
+}
+
+func TestDedupeChunks(t *testing.T) {
+	a := &database.File{Path: "a.md"}
+	b := &database.File{Path: "b.md"}
+	lists := [][]database.SimilarChunk{
+		{
+			{ChunkID: 1, Content: "alpha", Similarity: 0.5, File: a},
+			{ChunkID: 2, Content: "beta", Similarity: 0.4, File: a},
+		},
+		{
+			{ChunkID: 1, Content: "alpha", Similarity: 0.9, File: a},   // same chunk, better score
+			{ChunkID: 3, Content: " beta\n", Similarity: 0.3, File: a}, // same content in the same note
+			{ChunkID: 4, Content: "beta", Similarity: 0.35, File: b},   // same content, other note
+			{ChunkID: 5, Content: "gamma", Similarity: 0.1, File: b},
+		},
+	}
+
+	got := dedupeChunks(lists, 10)
+	var ids []uint
+	for _, chunk := range got {
+		ids = append(ids, chunk.ChunkID)
+	}
+	if want := []uint{1, 2, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("chunk ids = %v, want %v", ids, want)
+	}
+	if got[0].Similarity != 0.9 {
+		t.Fatalf("chunk 1 kept similarity %v, want its best 0.9", got[0].Similarity)
+	}
+
+	if got := dedupeChunks(lists, 2); len(got) != 2 || got[1].ChunkID != 2 {
+		t.Fatalf("limited to 2 = %+v", got)
+	}
+	if got := dedupeChunks(nil, 5); len(got) != 0 {
+		t.Fatalf("no lists = %+v", got)
+	}
+}
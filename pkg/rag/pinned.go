@@ -0,0 +1,69 @@
+package rag
+
+import (
+	"notebit/pkg/database"
+)
+
+const (
+	// pinnedCandidateChunks is how many chunks of the pinned notes are ranked against the query
+	pinnedCandidateChunks = 20
+	// pinnedTokenBudget caps the context taken from pinned notes
+	pinnedTokenBudget = 1500
+)
+
+// pinnedContext returns the chunks of the pinned notes most similar to the
+// query, within pinnedTokenBudget. Every note first gets its best chunk, so a
+// long note cannot crowd out the others; remaining budget goes by similarity.
+func pinnedContext(repo *database.Repository, paths []string, queryVector []float32) ([]database.SimilarChunk, error) {
+	candidates, err := repo.SearchSimilarInPaths(paths, queryVector, pinnedCandidateChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []database.SimilarChunk
+	taken := make(map[uint]bool)
+	budget := pinnedTokenBudget
+	take := func(chunk database.SimilarChunk) {
+		// buildContext truncates each chunk to 500 runes
+		cost := len(truncateContent(chunk.Content, 500))/4 + 1
+		if cost > budget && len(selected) > 0 {
+			return
+		}
+		budget -= cost
+		taken[chunk.ChunkID] = true
+		selected = append(selected, chunk)
+	}
+
+	seenNote := make(map[uint]bool)
+	for _, chunk := range candidates {
+		if chunk.File == nil || seenNote[chunk.File.ID] {
+			continue
+		}
+		seenNote[chunk.File.ID] = true
+		take(chunk)
+	}
+	for _, chunk := range candidates {
+		if !taken[chunk.ChunkID] {
+			take(chunk)
+		}
+	}
+	return selected, nil
+}
+
+// mergePinned puts pinned chunks ahead of retrieved ones, dropping duplicates
+func mergePinned(pinned, retrieved []database.SimilarChunk) []database.SimilarChunk {
+	if len(pinned) == 0 {
+		return retrieved
+	}
+	merged := append([]database.SimilarChunk(nil), pinned...)
+	seen := make(map[uint]bool, len(pinned))
+	for _, chunk := range pinned {
+		seen[chunk.ChunkID] = true
+	}
+	for _, chunk := range retrieved {
+		if !seen[chunk.ChunkID] {
+			merged = append(merged, chunk)
+		}
+	}
+	return merged
+}
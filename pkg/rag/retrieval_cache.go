@@ -0,0 +1,127 @@
+package rag
+
+import (
+	"strings"
+	"sync"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/database"
+)
+
+// maxCachedSessions and maxCachedRetrievals bound the retrieval cache
+const (
+	maxCachedSessions   = 32
+	maxCachedRetrievals = 16
+)
+
+// retrievalKey identifies a retrieval by everything that changes its result
+type retrievalKey struct {
+	query        string
+	queryRewrite string
+	multiQuery   int
+	language     string
+	scope        string
+	limit        int
+	// embeddingModels and llmModel name the models the retrieval ran with
+	embeddingModels string
+	llmModel        string
+}
+
+// retrieval is the outcome of embedding a query and searching the index
+type retrieval struct {
+	query     string // the text that was embedded, see RAGConfig.QueryRewrite
+	embedding []float32
+	chunks    []database.SimilarChunk
+}
+
+type cachedRetrieval struct {
+	revision uint64
+	result   retrieval
+}
+
+// retrievalCache remembers the retrievals of each chat session, so asking
+// again or regenerating an answer skips embedding and vector search while
+// the index is unchanged
+type retrievalCache struct {
+	mu       sync.Mutex
+	sessions map[string]map[retrievalKey]cachedRetrieval
+}
+
+func newRetrievalKey(query string, opts QueryOptions, queryRewrite string, multiQuery, limit int) retrievalKey {
+	key := retrievalKey{
+		query:        strings.TrimSpace(query),
+		queryRewrite: queryRewrite,
+		multiQuery:   multiQuery,
+		language:     strings.ToLower(strings.TrimSpace(opts.Language)),
+		limit:        limit,
+	}
+	if opts.ScopePaths != nil {
+		// A nil scope searches everything, an empty one nothing
+		key.scope = "\x00" + strings.Join(opts.ScopePaths, "\x00")
+	}
+	return key
+}
+
+// withModels adds to key the embedding models the query is embedded with
+// and the LLM that rewrites it, so results made with a previous provider or
+// model are not reused
+func (s *Service) withModels(key retrievalKey) retrievalKey {
+	models := append([]string{s.cfg.GetProvider(), s.cfg.GetEmbeddingModel()},
+		s.ai.QueryModels(ai.DetectLanguage(key.query), key.language)...)
+	key.embeddingModels = strings.Join(models, "\x00")
+	if s.llm != nil {
+		key.llmModel = s.llm.Name() + "\x00" + s.cfg.GetLLMConfig().Model
+	}
+	return key
+}
+
+// get returns a copy of the cached retrieval, if it was made at revision
+func (c *retrievalCache) get(sessionID string, key retrievalKey, revision uint64) (retrieval, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.sessions[sessionID][key]
+	if !ok || entry.revision != revision {
+		return retrieval{}, false
+	}
+	result := entry.result
+	result.chunks = append([]database.SimilarChunk(nil), result.chunks...)
+	return result, true
+}
+
+func (c *retrievalCache) put(sessionID string, key retrievalKey, revision uint64, result retrieval) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions == nil || (c.sessions[sessionID] == nil && len(c.sessions) >= maxCachedSessions) {
+		c.sessions = make(map[string]map[retrievalKey]cachedRetrieval)
+	}
+	entries := c.sessions[sessionID]
+	if entries == nil || len(entries) >= maxCachedRetrievals {
+		entries = make(map[retrievalKey]cachedRetrieval)
+		c.sessions[sessionID] = entries
+	}
+	result.chunks = append([]database.SimilarChunk(nil), result.chunks...)
+	entries[key] = cachedRetrieval{revision: revision, result: result}
+}
+
+// clear drops the retrievals of sessionID, or of every session if it is empty
+func (c *retrievalCache) clear(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sessionID == "" {
+		c.sessions = nil
+		return
+	}
+	delete(c.sessions, sessionID)
+}
+
+// ClearRetrievalCache forgets cached retrievals; call it when the index
+// changes. Entries are also checked against the index revision, so this only
+// frees memory early.
+func (s *Service) ClearRetrievalCache() {
+	s.retrievals.clear("")
+}
+
+// ClearSessionRetrievalCache forgets the cached retrievals of a chat session
+func (s *Service) ClearSessionRetrievalCache(sessionID string) {
+	s.retrievals.clear(sessionID)
+}
@@ -0,0 +1,65 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+)
+
+// stubLLM is an LLM provider that is never called
+type stubLLM struct {
+	name string
+}
+
+func (l *stubLLM) GenerateCompletion(ctx context.Context, req *ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	return &ai.CompletionResponse{}, nil
+}
+
+func (l *stubLLM) GenerateCompletionStream(ctx context.Context, req *ai.CompletionRequest) (<-chan *ai.CompletionChunk, error) {
+	return nil, nil
+}
+
+func (l *stubLLM) GetAvailableModels() ([]string, error) { return nil, nil }
+func (l *stubLLM) GetDefaultModel() string               { return "" }
+func (l *stubLLM) ValidateConfig() error                 { return nil }
+func (l *stubLLM) Name() string                          { return l.name }
+
+func TestRetrievalCacheKeyedOnModels(t *testing.T) {
+	cfg := config.New()
+	s := NewService(nil, ai.NewService(cfg), &stubLLM{name: "openai"}, cfg)
+	keyFor := func() retrievalKey {
+		return s.withModels(newRetrievalKey("what is zettelkasten?", QueryOptions{}, "", 0, 5))
+	}
+	found := retrieval{query: "what is zettelkasten?", chunks: []database.SimilarChunk{{ChunkID: 1}}}
+
+	original := keyFor()
+	s.retrievals.put("session", original, 1, found)
+	if _, ok := s.retrievals.get("session", keyFor(), 1); !ok {
+		t.Fatal("unchanged settings missed the cache")
+	}
+	if _, ok := s.retrievals.get("session", original, 2); ok {
+		t.Fatal("retrieval served after the index changed")
+	}
+
+	embeddingModel := cfg.GetEmbeddingModel()
+	cfg.SetEmbeddingModel("another-embedding-model")
+	if _, ok := s.retrievals.get("session", keyFor(), 1); ok {
+		t.Fatal("retrieval served after the embedding model changed")
+	}
+
+	cfg.SetEmbeddingModel(embeddingModel)
+	llmCfg := cfg.GetLLMConfig()
+	llmCfg.Model = "another-chat-model"
+	cfg.SetLLMConfig(llmCfg)
+	if _, ok := s.retrievals.get("session", keyFor(), 1); ok {
+		t.Fatal("retrieval served after the LLM model changed")
+	}
+
+	s.ClearSessionRetrievalCache("session")
+	if _, ok := s.retrievals.get("session", original, 1); ok {
+		t.Fatal("retrieval served after the session cache was cleared")
+	}
+}
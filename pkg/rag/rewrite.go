@@ -0,0 +1,68 @@
+package rag
+
+import (
+	"context"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/logger"
+)
+
+// maxRewriteTokens bounds the reply of the pre-retrieval LLM call
+const maxRewriteTokens = 300
+
+const rewriteQueryPrompt = `You turn questions into search queries for a personal note collection.
+Reply with only the search query: the key terms of the question, spelled-out abbreviations and a few close synonyms, in the language of the question. No explanation, no quotes.`
+
+const hydePrompt = `You write the passage of a personal note that would answer a question.
+Reply with only that passage, about 100 words, in the language of the question. State plausible facts plainly; do not mention that the passage is hypothetical.`
+
+// retrievalQuery returns the text to embed for query under mode (see
+// config.RAGConfig.QueryRewrite). If the LLM call fails the query itself is
+// used, so retrieval still runs. Callers hold s.mu.
+func (s *Service) retrievalQuery(ctx context.Context, query, mode string) string {
+	var systemPrompt string
+	switch mode {
+	case config.QueryRewriteRewrite:
+		systemPrompt = rewriteQueryPrompt
+	case config.QueryRewriteHyDE:
+		systemPrompt = hydePrompt
+	default:
+		return query
+	}
+	if s.llm == nil {
+		return query
+	}
+
+	completion, err := s.llm.GenerateCompletion(ctx, &ai.CompletionRequest{
+		Messages: []ai.ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: query},
+		},
+		Model:       s.cfg.GetLLMConfig().Model,
+		Temperature: 0.2,
+		MaxTokens:   maxRewriteTokens,
+	})
+	if err != nil {
+		logger.Warn("Query %s failed, retrieving with the question itself: %v", mode, err)
+		return query
+	}
+	rewritten := strings.Trim(strings.TrimSpace(completion.Content), `"`)
+	if rewritten == "" {
+		return query
+	}
+	if mode == config.QueryRewriteRewrite {
+		// Keep the question's own wording in case the rewrite dropped a term
+		return query + "\n" + rewritten
+	}
+	return rewritten
+}
+
+// rewrittenOrEmpty reports retrievalQuery only when it differs from query
+func rewrittenOrEmpty(query, retrievalQuery string) string {
+	if retrievalQuery == query {
+		return ""
+	}
+	return retrievalQuery
+}
@@ -0,0 +1,45 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"notebit/pkg/config"
+)
+
+func TestRetrievalQuery(t *testing.T) {
+	ctx := context.Background()
+	query := "what is PKM?"
+	tests := []struct {
+		name  string
+		mode  string
+		llm   *replyLLM
+		want  string
+		calls int
+	}{
+		{"off", config.QueryRewriteOff, &replyLLM{reply: "unused"}, query, 0},
+		{"rewrite keeps the question", config.QueryRewriteRewrite, &replyLLM{reply: ` "personal knowledge management" `}, query + "\npersonal knowledge management", 1},
+		{"hyde replaces the question", config.QueryRewriteHyDE, &replyLLM{reply: "PKM is how I organise notes."}, "PKM is how I organise notes.", 1},
+		{"empty reply", config.QueryRewriteHyDE, &replyLLM{reply: `""`}, query, 1},
+		{"failure", config.QueryRewriteRewrite, &replyLLM{err: errors.New("offline")}, query, 1},
+	}
+	for _, tt := range tests {
+		if got := newReplyService(tt.llm).retrievalQuery(ctx, query, tt.mode); got != tt.want {
+			t.Errorf("%s: retrievalQuery = %q, want %q", tt.name, got, tt.want)
+		}
+		if len(tt.llm.requests) != tt.calls {
+			t.Errorf("%s: %d LLM calls, want %d", tt.name, len(tt.llm.requests), tt.calls)
+		}
+	}
+	if got := NewService(nil, nil, nil, config.New()).retrievalQuery(ctx, query, config.QueryRewriteHyDE); got != query {
+		t.Errorf("without an LLM retrievalQuery = %q, want the question", got)
+	}
+
+	if got := rewrittenOrEmpty(query, query); got != "" {
+		t.Errorf("rewrittenOrEmpty of the question = %q", got)
+	}
+	if got := rewrittenOrEmpty(query, "other"); got != "other" {
+		t.Errorf("rewrittenOrEmpty = %q, want other", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"notebit/pkg/ai"
 	"notebit/pkg/config"
 	"notebit/pkg/database"
+	"notebit/pkg/logger"
 )
 
 // Service handles RAG (Retrieval-Augmented Generation) operations
@@ -21,6 +23,8 @@ type Service struct {
 	ai  *ai.Service
 	llm ai.LLMProvider
 	cfg *config.Config
+
+	retrievals retrievalCache
 }
 
 // ChatMessage represents a message in the conversation
@@ -40,6 +44,8 @@ type ChunkRef struct {
 	Heading    string  `json:"heading"`
 	Similarity float32 `json:"similarity"`
 	ChunkID    uint    `json:"chunk_id"`
+	// Source is the image the content was read from, "" for note text
+	Source string `json:"source,omitempty"`
 }
 
 // ChatResponse represents a response from the RAG service
@@ -48,8 +54,37 @@ type ChatResponse struct {
 	Content    string     `json:"content"`
 	Sources    []ChunkRef `json:"sources"`
 	TokensUsed *int       `json:"tokens_used,omitempty"`
+	// CostUSD is the estimated cost of the completion, 0 for local or unknown models
+	CostUSD float64 `json:"cost_usd,omitempty"`
+	// Coverage is set when retrieval ran over a partially embedded vault
+	Coverage *database.IndexCoverage `json:"coverage,omitempty"`
+	// NoRelevantContext is set when no chunk passed the similarity threshold
+	NoRelevantContext bool `json:"no_relevant_context,omitempty"`
+	// ToolCalls lists the tools the model called while answering
+	ToolCalls []ToolCallRecord `json:"tool_calls,omitempty"`
+	// RetrievalQuery is the text notes were retrieved with when it differs
+	// from the question, see RAGConfig.QueryRewrite
+	RetrievalQuery string `json:"retrieval_query,omitempty"`
+	// Confidence estimates from 0 to 1 how well the retrieved notes support
+	// an answer; it is 0 when the answer is not based on notes
+	Confidence float32 `json:"confidence"`
+	// BestSimilarity is the score of the most similar chunk retrieved, before
+	// the similarity threshold was applied
+	BestSimilarity float32 `json:"best_similarity"`
+	// FollowUps are questions the user may ask next and ActionItems the tasks
+	// the answer contains, both set when RAGConfig.FollowUps is on
+	FollowUps   []string `json:"follow_ups,omitempty"`
+	ActionItems []string `json:"action_items,omitempty"`
 }
 
+// NoRelevantNotesMessage is the reply when no note is similar enough to the
+// query and the LLM is not asked to answer without context
+const NoRelevantNotesMessage = "No relevant notes found for this question."
+
+// GeneralKnowledgeLabel starts answers the LLM gives without note context, so
+// they are not mistaken for answers from the notes
+const GeneralKnowledgeLabel = "_No relevant notes were found; this answer is from general knowledge._\n\n"
+
 // NewService creates a new RAG service
 func NewService(db *database.Manager, aiSvc *ai.Service, llm ai.LLMProvider, cfg *config.Config) *Service {
 	return &Service{
@@ -60,77 +95,353 @@ func NewService(db *database.Manager, aiSvc *ai.Service, llm ai.LLMProvider, cfg
 	}
 }
 
+// QueryOptions adjusts retrieval and prompt construction for a single query
+type QueryOptions struct {
+	// AttachmentContext is user-attached content (e.g. note snapshots) placed ahead of the retrieved chunks
+	AttachmentContext string
+	// ScopePaths restricts retrieval to these notes when non-nil (e.g. a collection)
+	ScopePaths []string
+	// MinSimilarity overrides RAGConfig.MinSimilarity when non-nil
+	MinSimilarity *float32
+	// Tools are offered to the model, which may call them before answering
+	Tools []Tool
+	// Model, Temperature and SystemPrompt override LLMConfig.Model,
+	// RAGConfig.Temperature and RAGConfig.SystemPrompt when set
+	Model        string
+	Temperature  *float32
+	SystemPrompt string
+	// PinnedPaths are notes whose best-matching chunks are always included,
+	// ahead of retrieved chunks and regardless of MinSimilarity
+	PinnedPaths []string
+	// Language hints that relevant notes may be written in this ISO 639-1
+	// language, so its multilingual embedding model is searched as well
+	Language string
+	// QueryRewrite overrides RAGConfig.QueryRewrite when set
+	QueryRewrite string
+	// SessionID caches retrieval for the chat session until the index changes
+	SessionID string
+}
+
 // Query performs a RAG query
 func (s *Service) Query(ctx context.Context, query string) (*ChatResponse, error) {
+	return s.QueryWithOptions(ctx, query, QueryOptions{})
+}
+
+// QueryWithOptions performs a RAG query with attachments and/or a retrieval scope
+func (s *Service) QueryWithOptions(ctx context.Context, query string, opts QueryOptions) (*ChatResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ctx = ai.WithOperation(ctx, ai.OperationRAG)
+	prepared, early, err := s.prepareQuery(ctx, query, opts)
+	if err != nil || early != nil {
+		return early, err
+	}
+
+	// Step 4: Generate completion with context
+	completion, toolCalls, err := s.completeWithTools(ctx, prepared.request, opts.Tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate completion: %w", err)
+	}
+
+	response := prepared.response(completion.Content)
+	response.ToolCalls = toolCalls
+	if completion.TokensUsed != nil {
+		response.TokensUsed = &completion.TokensUsed.TotalTokens
+		response.CostUSD = ai.EstimateCost(s.cfg.GetLLMConfig().Provider, prepared.request.Model, completion.TokensUsed.PromptTokens, completion.TokensUsed.CompletionTokens)
+	}
+	if prepared.followUps {
+		s.addFollowUps(ctx, query, response)
+	}
+	return response, nil
+}
+
+// QueryStream performs a RAG query like QueryWithOptions, passing the answer
+// to onDelta as it is generated. Tools are not supported. If ctx is cancelled
+// mid-answer, the partial response is returned together with ctx.Err().
+func (s *Service) QueryStream(ctx context.Context, query string, opts QueryOptions, onDelta func(delta string)) (*ChatResponse, error) {
+	if len(opts.Tools) > 0 {
+		return nil, fmt.Errorf("tools are not supported in streaming queries")
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	ctx = ai.WithOperation(ctx, ai.OperationRAG)
+	prepared, early, err := s.prepareQuery(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	if early != nil {
+		onDelta(early.Content)
+		return early, nil
+	}
+	if prepared.generalKnowledge {
+		onDelta(GeneralKnowledgeLabel)
+	}
+
+	chunks, err := s.llm.GenerateCompletionStream(ctx, prepared.request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate completion: %w", err)
+	}
+	var content strings.Builder
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			return prepared.response(content.String()), chunk.Error
+		}
+		if chunk.Content != "" {
+			content.WriteString(chunk.Content)
+			onDelta(chunk.Content)
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return prepared.response(content.String()), err
+	}
+	response := prepared.response(content.String())
+	if prepared.followUps {
+		s.addFollowUps(ctx, query, response)
+	}
+	return response, nil
+}
+
+// preparedQuery is a RAG query after retrieval, ready for completion
+type preparedQuery struct {
+	request           *ai.CompletionRequest
+	sources           []ChunkRef
+	noRelevantContext bool
+	// generalKnowledge marks answers given without notes or tools to find them
+	generalKnowledge bool
+	coverage         *database.IndexCoverage
+	retrievalQuery   string
+	confidence       float32
+	bestSimilarity   float32
+	followUps        bool
+}
+
+func (p *preparedQuery) response(content string) *ChatResponse {
+	if p.generalKnowledge {
+		content = GeneralKnowledgeLabel + content
+	}
+	return &ChatResponse{
+		MessageID:         generateMessageID(),
+		Content:           content,
+		Sources:           p.sources,
+		Coverage:          p.coverage,
+		NoRelevantContext: p.noRelevantContext,
+		RetrievalQuery:    p.retrievalQuery,
+		Confidence:        p.confidence,
+		BestSimilarity:    p.bestSimilarity,
+	}
+}
+
+// prepareQuery embeds the query, retrieves context and builds the completion
+// request. When no note is relevant and the model should not be asked, it
+// returns the final response instead. Callers hold s.mu.
+func (s *Service) prepareQuery(ctx context.Context, query string, opts QueryOptions) (*preparedQuery, *ChatResponse, error) {
 	if strings.TrimSpace(query) == "" {
-		return nil, fmt.Errorf("query cannot be empty")
+		return nil, nil, fmt.Errorf("query cannot be empty")
 	}
 
 	if !s.db.IsInitialized() {
-		return nil, fmt.Errorf("database is not initialized")
+		return nil, nil, fmt.Errorf("database is not initialized")
 	}
 
 	if s.llm == nil {
-		return nil, fmt.Errorf("LLM provider is not configured")
+		return nil, nil, fmt.Errorf("LLM provider is not configured")
 	}
 
-	// Step 1: Generate query embedding
-	queryEmbedding, err := s.ai.GenerateEmbedding(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	ragConfig := s.cfg.GetRAGConfig()
+	if opts.QueryRewrite != "" {
+		ragConfig.QueryRewrite = opts.QueryRewrite
 	}
 
-	// Step 2: Search for similar chunks
 	repo := s.db.Repository()
-	ragConfig := s.cfg.GetRAGConfig()
+	if opts.Temperature != nil {
+		ragConfig.Temperature = *opts.Temperature
+	}
+	if opts.SystemPrompt != "" {
+		ragConfig.SystemPrompt = opts.SystemPrompt
+	}
+	model := s.cfg.GetLLMConfig().Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
 
 	limit := ragConfig.MaxContextChunks
 	if limit <= 0 {
 		limit = 5 // Default
 	}
 
-	similarChunks, err := repo.SearchSimilar(queryEmbedding.Embedding, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search similar chunks: %w", err)
+	// Steps 1 and 2: Embed the query and search for similar chunks, unless
+	// the session retrieved the same query since the index last changed
+	revision := repo.GetRevision()
+	key := s.withModels(newRetrievalKey(query, opts, ragConfig.QueryRewrite, ragConfig.MultiQuery, limit))
+	found, cached := retrieval{}, false
+	if opts.SessionID != "" {
+		found, cached = s.retrievals.get(opts.SessionID, key, revision)
 	}
-	if len(similarChunks) == 0 {
-		return nil, fmt.Errorf("knowledge base has no indexed context yet, please save or reindex notes first")
+	if !cached {
+		var err error
+		if found, err = s.retrieve(ctx, repo, query, opts, ragConfig, limit); err != nil {
+			return nil, nil, err
+		}
+		if opts.SessionID != "" {
+			s.retrievals.put(opts.SessionID, key, revision, found)
+		}
+	}
+	retrievalQuery, similarChunks := found.query, found.chunks
+
+	var pinnedChunks []database.SimilarChunk
+	if len(opts.PinnedPaths) > 0 {
+		var err error
+		pinnedChunks, err = pinnedContext(repo, opts.PinnedPaths, found.embedding)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load pinned notes: %w", err)
+		}
+	}
+	attachmentContext := strings.TrimSpace(opts.AttachmentContext)
+	if len(similarChunks) == 0 && len(pinnedChunks) == 0 && attachmentContext == "" {
+		return nil, nil, fmt.Errorf("knowledge base has no indexed context yet, please save or reindex notes first")
+	}
+
+	minSimilarity := ragConfig.MinSimilarity
+	if opts.MinSimilarity != nil {
+		minSimilarity = *opts.MinSimilarity
+	}
+	bestSimilarity := topSimilarity(similarChunks)
+	similarChunks = mergePinned(pinnedChunks, filterBySimilarity(similarChunks, minSimilarity))
+	noRelevantContext := len(similarChunks) == 0 && attachmentContext == ""
+	// With tools the model can still search for itself, so it is always asked
+	if noRelevantContext && ragConfig.NoContextBehavior != config.NoContextAnswer && len(opts.Tools) == 0 {
+		return nil, &ChatResponse{
+			MessageID:         generateMessageID(),
+			Content:           NoRelevantNotesMessage,
+			Sources:           []ChunkRef{},
+			Coverage:          partialCoverage(repo),
+			RetrievalQuery:    rewrittenOrEmpty(query, retrievalQuery),
+			NoRelevantContext: true,
+			BestSimilarity:    bestSimilarity,
+		}, nil
 	}
 
 	// Step 3: Build context from retrieved chunks
-	ragContext := s.buildContext(similarChunks)
+	ragContext := "No relevant notes were found; answer from general knowledge and say so."
+	if !noRelevantContext {
+		ragContext = s.buildContext(similarChunks)
+	}
+	if attachmentContext != "" {
+		ragContext = attachmentContext + "\n\n" + ragContext
+	}
 
-	// Step 4: Generate completion with context
-	messages := s.buildMessages(query, ragContext, ragConfig)
+	return &preparedQuery{
+		request: &ai.CompletionRequest{
+			Messages:    s.buildMessages(query, ragContext, ragConfig),
+			Model:       model,
+			Temperature: ragConfig.Temperature,
+			MaxTokens:   s.cfg.GetLLMConfig().MaxTokens,
+		},
+		sources:           s.buildSources(similarChunks),
+		noRelevantContext: noRelevantContext,
+		generalKnowledge:  noRelevantContext && len(opts.Tools) == 0,
+		coverage:          partialCoverage(repo),
+		retrievalQuery:    rewrittenOrEmpty(query, retrievalQuery),
+		confidence:        retrievalConfidence(similarChunks),
+		bestSimilarity:    bestSimilarity,
+		followUps:         ragConfig.FollowUps,
+	}, nil, nil
+}
 
-	completion, err := s.llm.GenerateCompletion(&ai.CompletionRequest{
-		Messages:    messages,
-		Model:       s.cfg.GetLLMConfig().Model,
-		Temperature: ragConfig.Temperature,
-		MaxTokens:   s.cfg.GetLLMConfig().MaxTokens,
-	})
+// retrieve embeds query, optionally rewritten, and searches for the limit
+// chunks most similar to it and its variations
+func (s *Service) retrieve(ctx context.Context, repo *database.Repository, query string, opts QueryOptions, ragConfig config.RAGConfig, limit int) (retrieval, error) {
+	retrievalQuery := s.retrievalQuery(ctx, query, ragConfig.QueryRewrite)
+	queryEmbedding, err := s.ai.GenerateEmbedding(ctx, retrievalQuery)
+	if err != nil {
+		return retrieval{}, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
 
+	var similarChunks []database.SimilarChunk
+	if opts.ScopePaths != nil {
+		similarChunks, err = repo.SearchSimilarInPaths(opts.ScopePaths, queryEmbedding.Embedding, limit)
+	} else {
+		byModel := s.languageEmbeddings(ctx, retrievalQuery, opts.Language)
+		similarChunks, err = repo.SearchSimilarAcrossModels(queryEmbedding.Embedding, byModel, limit)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate completion: %w", err)
+		return retrieval{}, fmt.Errorf("failed to search similar chunks: %w", err)
 	}
+	if ragConfig.MultiQuery > 0 {
+		similarChunks = s.searchVariations(ctx, repo, query, ragConfig.MultiQuery, opts.ScopePaths, limit, similarChunks)
+	}
+	return retrieval{query: retrievalQuery, embedding: queryEmbedding.Embedding, chunks: similarChunks}, nil
+}
 
-	// Step 5: Build response with sources
-	sources := s.buildSources(similarChunks)
+// languageEmbeddings embeds query with the models configured for its own
+// language and the hinted one. A model that fails is left out of the search.
+func (s *Service) languageEmbeddings(ctx context.Context, query, language string) map[string][]float32 {
+	byModel := make(map[string][]float32)
+	for _, model := range s.ai.QueryModels(ai.DetectLanguage(query), strings.ToLower(strings.TrimSpace(language))) {
+		resp, err := s.ai.GenerateEmbeddingWithModel(ctx, model, query)
+		if err != nil {
+			logger.Warn("Embedding query with %s failed: %v", model, err)
+			continue
+		}
+		byModel[model] = resp.Embedding
+	}
+	return byModel
+}
 
-	var tokensUsed *int
-	if completion.TokensUsed != nil {
-		tokensUsed = &completion.TokensUsed.TotalTokens
+// filterBySimilarity drops chunks scoring below minSimilarity
+func filterBySimilarity(chunks []database.SimilarChunk, minSimilarity float32) []database.SimilarChunk {
+	if minSimilarity <= 0 {
+		return chunks
 	}
+	kept := chunks[:0]
+	for _, chunk := range chunks {
+		if chunk.Similarity >= minSimilarity {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept
+}
 
-	return &ChatResponse{
-		MessageID:  generateMessageID(),
-		Content:    completion.Content,
-		Sources:    sources,
-		TokensUsed: tokensUsed,
-	}, nil
+// topSimilarity returns the highest score among chunks, 0 if there are none
+func topSimilarity(chunks []database.SimilarChunk) float32 {
+	var best float32
+	for _, chunk := range chunks {
+		best = max(best, chunk.Similarity)
+	}
+	return best
+}
+
+// retrievalConfidence scores context chunks from 0 to 1: mostly by the best
+// match, and partly by how many of the top three agree with it
+func retrievalConfidence(chunks []database.SimilarChunk) float32 {
+	if len(chunks) == 0 {
+		return 0
+	}
+	scores := make([]float32, 0, len(chunks))
+	for _, chunk := range chunks {
+		scores = append(scores, chunk.Similarity)
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i] > scores[j] })
+	var sum float32
+	for _, score := range scores[:min(len(scores), 3)] {
+		sum += score
+	}
+	confidence := 0.7*scores[0] + 0.3*sum/3
+	return min(max(confidence, 0), 1)
+}
+
+// partialCoverage returns index coverage when some notes are not yet embedded, nil otherwise
+func partialCoverage(repo *database.Repository) *database.IndexCoverage {
+	coverage, err := repo.GetIndexCoverage()
+	if err != nil || !coverage.Partial {
+		return nil
+	}
+	return coverage
 }
 
 // buildContext creates context string from chunks
@@ -149,6 +460,9 @@ func (s *Service) buildContext(chunks []database.SimilarChunk) string {
 		if chunk.Heading != "" {
 			sb.WriteString(fmt.Sprintf(" > %s", chunk.Heading))
 		}
+		if chunk.Source != "" {
+			sb.WriteString(" (from image)")
+		}
 		sb.WriteString("\n")
 
 		// Add content (truncated if too long)
@@ -187,6 +501,7 @@ func (s *Service) buildSources(chunks []database.SimilarChunk) []ChunkRef {
 			Heading:    chunk.Heading,
 			Similarity: chunk.Similarity,
 			ChunkID:    chunk.ChunkID,
+			Source:     chunk.Source,
 		}
 		if chunk.File != nil {
 			ref.Path = chunk.File.Path
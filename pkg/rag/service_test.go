@@ -0,0 +1,117 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/config"
+	"notebit/pkg/database"
+)
+
+// replyLLM answers every completion with reply, or fails with err, and
+// records the requests it got
+type replyLLM struct {
+	stubLLM
+	reply    string
+	err      error
+	requests []*ai.CompletionRequest
+}
+
+func (l *replyLLM) GenerateCompletion(ctx context.Context, req *ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	l.requests = append(l.requests, req)
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &ai.CompletionResponse{Content: l.reply}, nil
+}
+
+func newReplyService(llm *replyLLM) *Service {
+	cfg := config.New()
+	return NewService(nil, ai.NewService(cfg), llm, cfg)
+}
+
+func scored(scores ...float32) []database.SimilarChunk {
+	chunks := make([]database.SimilarChunk, len(scores))
+	for i, score := range scores {
+		chunks[i] = database.SimilarChunk{ChunkID: uint(i + 1), Similarity: score}
+	}
+	return chunks
+}
+
+func TestRetrievalConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []database.SimilarChunk
+		want   float32
+	}{
+		{"none", nil, 0},
+		{"one match", scored(0.9), 0.7*0.9 + 0.3*0.9/3},
+		{"top three agree", scored(0.6, 0.9, 0.9, 0.9), 0.7*0.9 + 0.3*0.9},
+		{"capped", scored(1.5, 1.5, 1.5), 1},
+		{"negative", scored(-0.5), 0},
+	}
+	for _, tt := range tests {
+		got := retrievalConfidence(tt.chunks)
+		if diff := got - tt.want; diff > 1e-6 || diff < -1e-6 {
+			t.Errorf("%s: retrievalConfidence = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := topSimilarity(scored(0.2, 0.8, 0.5)); got != 0.8 {
+		t.Errorf("topSimilarity = %v, want 0.8", got)
+	}
+	if got := topSimilarity(nil); got != 0 {
+		t.Errorf("topSimilarity of nothing = %v, want 0", got)
+	}
+}
+
+func TestPreparedQueryLabelsGeneralKnowledge(t *testing.T) {
+	general := &preparedQuery{generalKnowledge: true, noRelevantContext: true}
+	if got := general.response("Paris.").Content; got != GeneralKnowledgeLabel+"Paris." {
+		t.Fatalf("general knowledge answer = %q", got)
+	}
+	fromNotes := &preparedQuery{confidence: 0.8, bestSimilarity: 0.9}
+	response := fromNotes.response("Paris.")
+	if response.Content != "Paris." || strings.Contains(response.Content, GeneralKnowledgeLabel) {
+		t.Fatalf("answer from notes = %q", response.Content)
+	}
+	if response.Confidence != 0.8 || response.BestSimilarity != 0.9 {
+		t.Fatalf("response scores = %v, %v", response.Confidence, response.BestSimilarity)
+	}
+}
+
+func TestFilterBySimilarity(t *testing.T) {
+	ids := func(chunks []database.SimilarChunk) []uint {
+		var out []uint
+		for _, chunk := range chunks {
+			out = append(out, chunk.ChunkID)
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		threshold float32
+		want      []uint
+	}{
+		{"off", 0, []uint{1, 2, 3, 4}},
+		{"negative is off", -1, []uint{1, 2, 3, 4}},
+		{"keeps scores at the threshold", 0.5, []uint{1, 3}},
+		{"drops everything", 0.95, nil},
+	}
+	for _, tt := range tests {
+		// Order is kept, so retrieval ranking survives filtering
+		chunks := scored(0.9, 0.2, 0.5, 0.49)
+		if got := ids(filterBySimilarity(chunks, tt.threshold)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: filterBySimilarity = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Pinned chunks are merged in ahead of retrieval regardless of score
+	pinned := []database.SimilarChunk{{ChunkID: 4, Similarity: 0}}
+	merged := mergePinned(pinned, filterBySimilarity(scored(0.9, 0.2, 0.5, 0.49), 0.5))
+	if got := ids(merged); !reflect.DeepEqual(got, []uint{4, 1, 3}) {
+		t.Errorf("merged = %v, want [4 1 3]", got)
+	}
+}
@@ -0,0 +1,177 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"notebit/pkg/ai"
+	"notebit/pkg/logger"
+)
+
+// maxToolRounds bounds how many times the model may call tools before it must answer
+const maxToolRounds = 4
+
+// maxToolResultRunes keeps a single tool result from flooding the context window
+const maxToolResultRunes = 4000
+
+// Tool is a function the model may call while answering a query
+type Tool struct {
+	Definition ai.Tool
+	// Run executes a call; arguments is the JSON object produced by the model
+	Run func(ctx context.Context, arguments string) (string, error)
+}
+
+// ToolCallRecord describes a tool call made while answering a query
+type ToolCallRecord struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SearchNotesTool lets the model run its own semantic searches over the vault
+func (s *Service) SearchNotesTool() Tool {
+	return Tool{
+		Definition: ai.NewFunctionTool(
+			"search_notes",
+			"Search the user's notes by meaning. Returns matching note paths, titles and excerpts.",
+			`{"type":"object","properties":{"query":{"type":"string","description":"What to search for"},"limit":{"type":"integer","description":"Maximum results (default 5)"}},"required":["query"]}`,
+		),
+		Run: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				Query string `json:"query"`
+				Limit int    `json:"limit"`
+			}
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Query) == "" {
+				return "", fmt.Errorf("query cannot be empty")
+			}
+			if args.Limit <= 0 || args.Limit > 20 {
+				args.Limit = 5
+			}
+
+			embedding, err := s.ai.GenerateEmbedding(ctx, args.Query)
+			if err != nil {
+				return "", err
+			}
+			chunks, err := s.db.Repository().SearchSimilar("", embedding.Embedding, args.Limit)
+			if err != nil {
+				return "", err
+			}
+			if len(chunks) == 0 {
+				return "No matching notes.", nil
+			}
+
+			var sb strings.Builder
+			for _, chunk := range chunks {
+				if chunk.File != nil {
+					sb.WriteString(fmt.Sprintf("- %s (%s)", chunk.File.Path, chunk.File.Title))
+				}
+				if chunk.Heading != "" {
+					sb.WriteString(" > " + chunk.Heading)
+				}
+				sb.WriteString(fmt.Sprintf(" [similarity %.2f]\n  %s\n", chunk.Similarity, truncateContent(chunk.Content, 300)))
+			}
+			return sb.String(), nil
+		},
+	}
+}
+
+// OpenNoteTool lets the model read a whole note; read returns a note's content by vault path
+func OpenNoteTool(read func(path string) (string, error)) Tool {
+	return Tool{
+		Definition: ai.NewFunctionTool(
+			"open_note",
+			"Read the full content of a note by its path in the vault.",
+			`{"type":"object","properties":{"path":{"type":"string","description":"Note path relative to the vault root"}},"required":["path"]}`,
+		),
+		Run: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				Path string `json:"path"`
+			}
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Path) == "" {
+				return "", fmt.Errorf("path cannot be empty")
+			}
+			return read(args.Path)
+		},
+	}
+}
+
+// completeWithTools runs the completion, executing tool calls and feeding their
+// results back until the model answers or maxToolRounds is reached
+func (s *Service) completeWithTools(ctx context.Context, req *ai.CompletionRequest, tools []Tool) (*ai.CompletionResponse, []ToolCallRecord, error) {
+	if len(tools) == 0 {
+		completion, err := s.llm.GenerateCompletion(ctx, req)
+		return completion, nil, err
+	}
+
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Definition.Function.Name] = tool
+		req.Tools = append(req.Tools, tool.Definition)
+	}
+
+	var records []ToolCallRecord
+	usage := &ai.TokenUsage{}
+	for round := 0; ; round++ {
+		if round == maxToolRounds {
+			// Out of rounds: withhold the tools so the model has to answer
+			req.Tools = nil
+		}
+		completion, err := s.llm.GenerateCompletion(ctx, req)
+		if err != nil {
+			return nil, records, err
+		}
+		if completion.TokensUsed != nil {
+			usage.PromptTokens += completion.TokensUsed.PromptTokens
+			usage.CompletionTokens += completion.TokensUsed.CompletionTokens
+			usage.TotalTokens += completion.TokensUsed.TotalTokens
+		}
+		if len(completion.ToolCalls) == 0 || req.Tools == nil {
+			completion.TokensUsed = usage
+			return completion, records, nil
+		}
+
+		req.Messages = append(req.Messages, ai.ChatMessage{
+			Role:      "assistant",
+			Content:   completion.Content,
+			ToolCalls: completion.ToolCalls,
+		})
+		for _, call := range completion.ToolCalls {
+			result, record := runTool(ctx, byName, call)
+			records = append(records, record)
+			req.Messages = append(req.Messages, ai.ChatMessage{
+				Role:       "tool",
+				Content:    result,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
+
+// runTool executes one call; failures are reported back to the model as the result
+func runTool(ctx context.Context, tools map[string]Tool, call ai.ToolCall) (string, ToolCallRecord) {
+	record := ToolCallRecord{Name: call.Function.Name, Arguments: call.Function.Arguments}
+	tool, ok := tools[call.Function.Name]
+	if !ok {
+		record.Error = "unknown tool"
+		return fmt.Sprintf("Error: unknown tool %q", call.Function.Name), record
+	}
+
+	result, err := tool.Run(ctx, call.Function.Arguments)
+	if err != nil {
+		logger.WarnWithFields(ctx, map[string]interface{}{
+			"tool":  call.Function.Name,
+			"error": err.Error(),
+		}, "Tool call failed")
+		record.Error = err.Error()
+		return "Error: " + err.Error(), record
+	}
+	return truncateContent(result, maxToolResultRunes), record
+}
@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notebit/pkg/ai"
+)
+
+// scriptedLLM answers completions with replies in turn and records the tools
+// each request offered
+type scriptedLLM struct {
+	stubLLM
+	replies []*ai.CompletionResponse
+	offered [][]string
+	seen    [][]ai.ChatMessage
+}
+
+func (l *scriptedLLM) GenerateCompletion(ctx context.Context, req *ai.CompletionRequest) (*ai.CompletionResponse, error) {
+	var names []string
+	for _, tool := range req.Tools {
+		names = append(names, tool.Function.Name)
+	}
+	l.offered = append(l.offered, names)
+	l.seen = append(l.seen, append([]ai.ChatMessage(nil), req.Messages...))
+	if len(l.replies) == 0 {
+		return nil, errors.New("no more replies")
+	}
+	reply := l.replies[0]
+	l.replies = l.replies[1:]
+	return reply, nil
+}
+
+func toolCall(id, name, arguments string) ai.ToolCall {
+	return ai.ToolCall{ID: id, Type: "function", Function: ai.ToolCallFunction{Name: name, Arguments: arguments}}
+}
+
+func echoTool() Tool {
+	return Tool{
+		Definition: ai.NewFunctionTool("echo", "Echo text", `{"type":"object"}`),
+		Run: func(ctx context.Context, arguments string) (string, error) {
+			if arguments == "fail" {
+				return "", errors.New("echo failed")
+			}
+			return "echo " + arguments, nil
+		},
+	}
+}
+
+func TestCompleteWithToolsDispatchesCalls(t *testing.T) {
+	llm := &scriptedLLM{replies: []*ai.CompletionResponse{
+		{
+			ToolCalls: []ai.ToolCall{
+				toolCall("1", "echo", "hi"),
+				toolCall("2", "missing", "{}"),
+				toolCall("3", "echo", "fail"),
+			},
+			TokensUsed: &ai.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		},
+		{Content: "done", TokensUsed: &ai.TokenUsage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}},
+	}}
+	s := NewService(nil, nil, llm, nil)
+	req := &ai.CompletionRequest{Messages: []ai.ChatMessage{{Role: "user", Content: "q"}}}
+
+	completion, records, err := s.completeWithTools(context.Background(), req, []Tool{echoTool()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Content != "done" || *completion.TokensUsed != (ai.TokenUsage{PromptTokens: 30, CompletionTokens: 5, TotalTokens: 35}) {
+		t.Fatalf("completion = %q, usage %+v", completion.Content, completion.TokensUsed)
+	}
+	want := []ToolCallRecord{
+		{Name: "echo", Arguments: "hi"},
+		{Name: "missing", Arguments: "{}", Error: "unknown tool"},
+		{Name: "echo", Arguments: "fail", Error: "echo failed"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %+v, want %+v", records, want)
+	}
+
+	// Every call is answered with a tool message, errors included
+	second := llm.seen[1]
+	var results []string
+	for _, m := range second[2:] {
+		if m.Role != "tool" {
+			t.Fatalf("message %+v, want a tool result", m)
+		}
+		results = append(results, m.ToolCallID+": "+m.Content)
+	}
+	wantResults := []string{"1: echo hi", `2: Error: unknown tool "missing"`, "3: Error: echo failed"}
+	if second[1].Role != "assistant" || !reflect.DeepEqual(results, wantResults) {
+		t.Fatalf("tool messages = %q, want %q", results, wantResults)
+	}
+}
+
+func TestCompleteWithToolsStopsAfterMaxRounds(t *testing.T) {
+	llm := &scriptedLLM{}
+	for i := 0; i <= maxToolRounds; i++ {
+		llm.replies = append(llm.replies, &ai.CompletionResponse{
+			Content:   fmt.Sprintf("round %d", i),
+			ToolCalls: []ai.ToolCall{toolCall(fmt.Sprint(i), "echo", "again")},
+		})
+	}
+	s := NewService(nil, nil, llm, nil)
+	req := &ai.CompletionRequest{Messages: []ai.ChatMessage{{Role: "user", Content: "q"}}}
+
+	completion, records, err := s.completeWithTools(context.Background(), req, []Tool{echoTool()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != maxToolRounds || completion.Content != fmt.Sprintf("round %d", maxToolRounds) {
+		t.Fatalf("%d calls, answer %q", len(records), completion.Content)
+	}
+	// The last request offers no tools, so the model has to answer
+	if last := llm.offered[len(llm.offered)-1]; last != nil {
+		t.Fatalf("last request offered %q", last)
+	}
+	if first := llm.offered[0]; !reflect.DeepEqual(first, []string{"echo"}) {
+		t.Fatalf("first request offered %q", first)
+	}
+}
+
+func TestToolArgumentValidation(t *testing.T) {
+	ctx := context.Background()
+	search := NewService(nil, nil, nil, nil).SearchNotesTool()
+	for _, args := range []string{"not json", `{"query": "  "}`, `{"limit": 3}`} {
+		if _, err := search.Run(ctx, args); err == nil {
+			t.Errorf("search_notes(%s) succeeded", args)
+		}
+	}
+
+	var read []string
+	open := OpenNoteTool(func(path string) (string, error) {
+		read = append(read, path)
+		return strings.Repeat("x", maxToolResultRunes+100), nil
+	})
+	for _, args := range []string{"[1]", `{"path": ""}`} {
+		if _, err := open.Run(ctx, args); err == nil {
+			t.Errorf("open_note(%s) succeeded", args)
+		}
+	}
+	if len(read) != 0 {
+		t.Fatalf("invalid calls read %q", read)
+	}
+
+	// Long results are cut before they reach the model
+	result, record := runTool(ctx, map[string]Tool{"open_note": open}, toolCall("1", "open_note", `{"path": "a.md"}`))
+	if record.Error != "" || len([]rune(result)) != maxToolResultRunes || !reflect.DeepEqual(read, []string{"a.md"}) {
+		t.Fatalf("open_note = %d runes, record %+v, read %q", len([]rune(result)), record, read)
+	}
+}
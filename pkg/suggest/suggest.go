@@ -0,0 +1,191 @@
+// Package suggest ranks completions for text typed in the editor, such as
+// wiki link targets and tags, with fuzzy matching fast enough to run on
+// every keystroke.
+package suggest
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Score bands, best first; scores within a band never reach the next one
+const (
+	scoreExact       = 5000
+	scorePrefix      = 4000
+	scoreWordPrefix  = 3000
+	scoreSubstring   = 2000
+	scoreSubsequence = 1000
+	bandWidth        = 999
+)
+
+// Item is something to suggest, matched by any of its keys
+type Item struct {
+	Keys   []string // Texts the query is matched against, e.g. a title and aliases
+	Weight int      // Ranks items that match equally well, higher first
+}
+
+// Match is an item that matched a query
+type Match struct {
+	Item  int // Index of the item
+	Key   int // Index of the item's best matching key
+	Score int
+}
+
+// Index holds items to search, with their keys folded once up front
+type Index struct {
+	items []Item
+	keys  [][][]rune
+}
+
+// NewIndex builds an index of items
+func NewIndex(items []Item) *Index {
+	x := &Index{items: items, keys: make([][][]rune, len(items))}
+	for i, item := range items {
+		x.keys[i] = make([][]rune, len(item.Keys))
+		for j, key := range item.Keys {
+			x.keys[i][j] = fold(key)
+		}
+	}
+	return x
+}
+
+// Len returns the number of items in the index
+func (x *Index) Len() int {
+	return len(x.items)
+}
+
+// Search returns the best limit matches for query, best first. An empty
+// query matches every item by weight. limit <= 0 returns all matches.
+func (x *Index) Search(query string, limit int) []Match {
+	return x.SearchBoosted(query, limit, nil)
+}
+
+// SearchBoosted is Search with boost(item) added to the score of each
+// matching item, e.g. to rank recently used items higher
+func (x *Index) SearchBoosted(query string, limit int, boost func(item int) int) []Match {
+	q := fold(strings.TrimSpace(query))
+	var matches []Match
+	for i, keys := range x.keys {
+		best := Match{Item: i, Key: -1}
+		for j, key := range keys {
+			if score, ok := score(q, key); ok && (best.Key < 0 || score > best.Score) {
+				best.Key, best.Score = j, score
+			}
+		}
+		if best.Key < 0 {
+			continue
+		}
+		if boost != nil {
+			best.Score += boost(i)
+		}
+		matches = append(matches, best)
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		ma, mb := matches[a], matches[b]
+		if ma.Score != mb.Score {
+			return ma.Score > mb.Score
+		}
+		if wa, wb := x.items[ma.Item].Weight, x.items[mb.Item].Weight; wa != wb {
+			return wa > wb
+		}
+		return x.items[ma.Item].Keys[ma.Key] < x.items[mb.Item].Keys[mb.Key]
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Score reports how well query matches text, higher being better, and
+// whether it matches at all. Matching ignores case; the query's characters
+// must appear in text in order, and score best as the whole text, a prefix,
+// the start of a word or a contiguous run, in that order.
+func Score(query, text string) (int, bool) {
+	return score(fold(strings.TrimSpace(query)), fold(text))
+}
+
+func score(q, text []rune) (int, bool) {
+	if len(q) == 0 {
+		return 0, true
+	}
+	if len(q) > len(text) {
+		return 0, false
+	}
+	// Shorter texts rank higher within a band, as they are closer matches
+	slack := bandWidth - min(len(text)-len(q), bandWidth)
+	if pos := index(text, q); pos >= 0 {
+		switch {
+		case pos == 0 && len(text) == len(q):
+			return scoreExact, true
+		case pos == 0:
+			return scorePrefix + slack, true
+		}
+		for p := pos; p >= 0; p = nextIndex(text, q, p) {
+			if isWordStart(text, p) {
+				return scoreWordPrefix + slack - min(p, slack), true
+			}
+		}
+		return scoreSubstring + slack - min(pos, slack), true
+	}
+
+	// Subsequence: reward runs of consecutive characters and word starts
+	bonus := 0
+	last := -1
+	ti := 0
+	for _, r := range q {
+		for ti < len(text) && text[ti] != r {
+			ti++
+		}
+		if ti == len(text) {
+			return 0, false
+		}
+		if ti == last+1 {
+			bonus += 8
+		}
+		if isWordStart(text, ti) {
+			bonus += 10
+		}
+		last = ti
+		ti++
+	}
+	return scoreSubsequence + min(bonus+slack/4, bandWidth), true
+}
+
+// fold lowercases s for case-insensitive matching
+func fold(s string) []rune {
+	return []rune(strings.ToLower(s))
+}
+
+func index(text, q []rune) int {
+	return nextIndex(text, q, -1)
+}
+
+// nextIndex returns the position of q in text after after, or -1
+func nextIndex(text, q []rune, after int) int {
+	for i := after + 1; i+len(q) <= len(text); i++ {
+		if hasPrefix(text[i:], q) {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasPrefix(text, q []rune) bool {
+	for i, r := range q {
+		if text[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordStart reports whether a word starts at text[i]: after a space,
+// punctuation or path separator
+func isWordStart(text []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := text[i-1]
+	return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+}
@@ -0,0 +1,79 @@
+package suggest
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScoreOrder(t *testing.T) {
+	ranked := []string{"meeting", "meeting notes", "team meeting", "premeeting", "my eeting ninja"}
+	prev := 1 << 30
+	for _, text := range ranked {
+		score, ok := Score("Meeting", text)
+		if text == "my eeting ninja" {
+			score, ok = Score("meetn", text)
+		}
+		if !ok {
+			t.Fatalf("%q should match", text)
+		}
+		if score >= prev {
+			t.Errorf("%q scored %d, not below the previous %d", text, score, prev)
+		}
+		prev = score
+	}
+	if _, ok := Score("xyz", "meeting"); ok {
+		t.Error("unrelated text should not match")
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	x := NewIndex([]Item{
+		{Keys: []string{"Project Plan", "plan"}, Weight: 1},
+		{Keys: []string{"Planets"}, Weight: 5},
+		{Keys: []string{"Daily/2024-01-01"}},
+		{Keys: []string{"Explanation"}, Weight: 9},
+	})
+
+	matches := x.Search("plan", 10)
+	got := make([]int, len(matches))
+	for i, m := range matches {
+		got[i] = m.Item
+	}
+	// The alias "plan" is exact; "Planets" a prefix; "Explanation" a substring
+	if fmt.Sprint(got) != "[0 1 3]" {
+		t.Fatalf("Search(plan) = %v", got)
+	}
+	if matches[0].Key != 1 {
+		t.Errorf("best key = %d, want the alias", matches[0].Key)
+	}
+
+	if all := x.Search("", 2); len(all) != 2 || all[0].Item != 3 || all[1].Item != 1 {
+		t.Errorf("empty query should list by weight, got %+v", all)
+	}
+	if m := x.Search("d2401", 0); len(m) != 1 || m[0].Item != 2 {
+		t.Errorf("fuzzy search = %+v", m)
+	}
+
+	// A large enough boost lifts a weaker match to the top
+	boosted := x.SearchBoosted("plan", 10, func(item int) int {
+		if item == 3 {
+			return 5000
+		}
+		return 0
+	})
+	if boosted[0].Item != 3 {
+		t.Errorf("boosted search = %+v", boosted)
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	items := make([]Item, 20000)
+	for i := range items {
+		items[i] = Item{Keys: []string{fmt.Sprintf("Note number %d about topic %d", i, i%97)}}
+	}
+	x := NewIndex(items)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Search("topic 42", 20)
+	}
+}
@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+	"time"
+)
+
+// stopPushTimeout bounds the push that follows the commit made by Stop, which
+// runs in the background so closing a vault never waits on the remote
+const stopPushTimeout = 15 * time.Second
+
+// AutoCommitter commits the vault once saves have paused for a while, so a
+// burst of edits becomes one commit
+type AutoCommitter struct {
+	git      *Git
+	delay    time.Duration
+	push     bool
+	onResult func(hash string, err error)
+
+	mu      gosync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewAutoCommitter commits delay after the last Touch, then pushes if push
+// is set. onResult (optional) receives the outcome of every attempt.
+func NewAutoCommitter(git *Git, delay time.Duration, push bool, onResult func(hash string, err error)) *AutoCommitter {
+	return &AutoCommitter{git: git, delay: delay, push: push, onResult: onResult}
+}
+
+// Touch records a change and restarts the delay
+func (c *AutoCommitter) Touch() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(c.delay, c.commit)
+}
+
+// Stop cancels the pending commit, or makes it right away if flush is set.
+// The push after a flushed commit runs in the background, bounded by
+// stopPushTimeout; the commit itself is done when Stop returns.
+func (c *AutoCommitter) Stop(flush bool) {
+	c.mu.Lock()
+	pending := c.timer != nil && c.timer.Stop()
+	c.timer = nil
+	c.stopped = true
+	c.mu.Unlock()
+	if !pending || !flush {
+		return
+	}
+	hash, err := c.git.CommitAll(context.Background(), c.message())
+	if err != nil || hash == "" || !c.push {
+		c.report(hash, err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), stopPushTimeout)
+		defer cancel()
+		c.report(hash, c.pushCommitted(ctx))
+	}()
+}
+
+func (c *AutoCommitter) commit() {
+	ctx := context.Background()
+	hash, err := c.git.CommitAll(ctx, c.message())
+	if err == nil && hash != "" && c.push {
+		err = c.pushCommitted(ctx)
+	}
+	c.report(hash, err)
+}
+
+func (c *AutoCommitter) message() string {
+	return "Auto-commit " + time.Now().Format("2006-01-02 15:04")
+}
+
+// pushCommitted pushes a commit that was just made
+func (c *AutoCommitter) pushCommitted(ctx context.Context) error {
+	if err := c.git.Push(ctx); err != nil {
+		return errors.Join(errors.New("committed but push failed"), err)
+	}
+	return nil
+}
+
+func (c *AutoCommitter) report(hash string, err error) {
+	if c.onResult != nil {
+		c.onResult(hash, err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package sync keeps a vault in step with other machines. It currently
+// drives a git working copy by shelling out to the git CLI, the way users
+// already sync vaults by hand.
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+)
+
+// ErrGitNotFound is returned when no git executable is on the PATH
+var ErrGitNotFound = errors.New("git executable not found")
+
+// ErrNotRepository is returned for a vault that is not a git working copy
+var ErrNotRepository = errors.New("vault is not a git repository")
+
+// dataPathspec keeps the vault's index and chat databases out of commits
+const dataPathspec = ":(exclude)data"
+
+// backupPathspec keeps the .bak copies of saved notes out of commits
+const backupPathspec = ":(exclude,glob)**/.*.bak"
+
+const gitTimeout = 2 * time.Minute
+
+// ConflictError reports files left with merge conflicts, e.g. after a pull
+type ConflictError struct {
+	Files []string `json:"files"`
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("merge conflicts in %d file(s): %s", len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// GitFile is one changed path in the working copy
+type GitFile struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"` // set for renames
+	Index    string `json:"index"`               // staged status letter, "." if unchanged
+	WorkTree string `json:"work_tree"`           // unstaged status letter, "?" for untracked
+}
+
+// GitStatus summarizes the working copy
+type GitStatus struct {
+	Branch    string    `json:"branch"`
+	Upstream  string    `json:"upstream,omitempty"`
+	Ahead     int       `json:"ahead"`
+	Behind    int       `json:"behind"`
+	Changes   []GitFile `json:"changes"`
+	Conflicts []string  `json:"conflicts"`
+	HasRemote bool      `json:"has_remote"`
+}
+
+// Git runs git commands in a vault. Commands are serialized so an
+// auto-commit never races a pull.
+type Git struct {
+	dir string
+	bin string
+	mu  gosync.Mutex
+}
+
+// NewGit returns a Git for the working copy at dir
+func NewGit(dir string) (*Git, error) {
+	bin, err := exec.LookPath("git")
+	if err != nil {
+		return nil, ErrGitNotFound
+	}
+	g := &Git{dir: dir, bin: bin}
+	if _, err := g.run(context.Background(), "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, ErrNotRepository
+	}
+	return g, nil
+}
+
+// Dir returns the working copy directory
+func (g *Git) Dir() string {
+	return g.dir
+}
+
+func (g *Git) run(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, g.bin, args...)
+	cmd.Dir = g.dir
+	// Never block on a credential or editor prompt; there is no terminal
+	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_EDITOR=true")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		return stdout.String(), fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return stdout.String(), nil
+}
+
+// Status returns the branch, its distance from upstream and the changed files
+func (g *Git) Status(ctx context.Context) (*GitStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status(ctx)
+}
+
+func (g *Git) status(ctx context.Context) (*GitStatus, error) {
+	out, err := g.run(ctx, "status", "--porcelain=v2", "--branch", "-z", "--", ".", dataPathspec, backupPathspec)
+	if err != nil {
+		return nil, err
+	}
+	status := parseStatus(out)
+	if remotes, err := g.run(ctx, "remote"); err == nil {
+		status.HasRemote = strings.TrimSpace(remotes) != ""
+	}
+	return status, nil
+}
+
+// parseStatus reads `git status --porcelain=v2 --branch -z` output
+func parseStatus(out string) *GitStatus {
+	status := &GitStatus{Changes: []GitFile{}, Conflicts: []string{}}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		switch {
+		case strings.HasPrefix(rec, "# branch.head "):
+			status.Branch = strings.TrimPrefix(rec, "# branch.head ")
+		case strings.HasPrefix(rec, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(rec, "# branch.upstream ")
+		case strings.HasPrefix(rec, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(rec, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(rec, "1 "):
+			// 1 XY sub mH mI mW hH hI path
+			if f := strings.SplitN(rec, " ", 9); len(f) == 9 {
+				status.Changes = append(status.Changes, GitFile{Path: f[8], Index: f[1][:1], WorkTree: f[1][1:]})
+			}
+		case strings.HasPrefix(rec, "2 "):
+			// 2 XY sub mH mI mW hH hI Xscore path, then origPath as its own record
+			if f := strings.SplitN(rec, " ", 10); len(f) == 10 {
+				file := GitFile{Path: f[9], Index: f[1][:1], WorkTree: f[1][1:]}
+				if i+1 < len(records) {
+					i++
+					file.OrigPath = records[i]
+				}
+				status.Changes = append(status.Changes, file)
+			}
+		case strings.HasPrefix(rec, "u "):
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if f := strings.SplitN(rec, " ", 11); len(f) == 11 {
+				status.Conflicts = append(status.Conflicts, f[10])
+				status.Changes = append(status.Changes, GitFile{Path: f[10], Index: f[1][:1], WorkTree: f[1][1:]})
+			}
+		case strings.HasPrefix(rec, "? "):
+			status.Changes = append(status.Changes, GitFile{Path: rec[2:], Index: ".", WorkTree: "?"})
+		}
+	}
+	return status
+}
+
+// CommitAll stages every change except the data directory and commits it,
+// running the working copy's commit hooks. It returns the new commit hash,
+// or "" when there was nothing to commit.
+func (g *Git) CommitAll(ctx context.Context, message string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status, err := g.status(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(status.Conflicts) > 0 {
+		return "", &ConflictError{Files: status.Conflicts}
+	}
+	if len(status.Changes) == 0 {
+		return "", nil
+	}
+	if strings.TrimSpace(message) == "" {
+		message = "Update notes"
+	}
+	if _, err := g.run(ctx, "add", "-A", "--", ".", dataPathspec, backupPathspec); err != nil {
+		return "", err
+	}
+	if _, err := g.run(ctx, "commit", "-m", message); err != nil {
+		return "", err
+	}
+	hash, err := g.run(ctx, "rev-parse", "HEAD")
+	return strings.TrimSpace(hash), err
+}
+
+// Pull merges the upstream branch. Merge conflicts are left in the working
+// copy for the user and reported as a *ConflictError.
+func (g *Git) Pull(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, pullErr := g.run(ctx, "pull", "--no-rebase", "--no-edit")
+	if pullErr == nil {
+		return nil
+	}
+	if status, err := g.status(ctx); err == nil && len(status.Conflicts) > 0 {
+		return &ConflictError{Files: status.Conflicts}
+	}
+	return pullErr
+}
+
+// Push pushes the current branch, setting its upstream on the first push
+func (g *Git) Push(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status, err := g.status(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.HasRemote {
+		return errors.New("no git remote configured")
+	}
+	if status.Upstream == "" {
+		_, err = g.run(ctx, "push", "--set-upstream", "origin", "HEAD")
+		return err
+	}
+	_, err = g.run(ctx, "push")
+	return err
+}
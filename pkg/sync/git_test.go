@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitCommitAllSkipsDataDirectory(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, "Ideas.md"), "# Ideas")
+	writeFile(t, filepath.Join(dir, "data", "notebit.sqlite"), "db")
+	writeFile(t, filepath.Join(dir, ".Ideas.md.bak"), "# Old ideas")
+
+	g, err := NewGit(dir)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	ctx := context.Background()
+	status, err := g.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(status.Changes) != 1 || status.Changes[0].Path != "Ideas.md" || status.Changes[0].WorkTree != "?" {
+		t.Fatalf("unexpected changes: %+v", status.Changes)
+	}
+
+	hash, err := g.CommitAll(ctx, "Add ideas")
+	if err != nil || hash == "" {
+		t.Fatalf("CommitAll: %q %v", hash, err)
+	}
+	if status, _ = g.Status(ctx); len(status.Changes) != 0 {
+		t.Fatalf("changes left after commit: %+v", status.Changes)
+	}
+	if hash, err = g.CommitAll(ctx, "Nothing"); err != nil || hash != "" {
+		t.Fatalf("empty commit: %q %v", hash, err)
+	}
+	if err := g.Push(ctx); err == nil {
+		t.Fatal("push without a remote should fail")
+	}
+}
+
+func TestNewGitRejectsPlainFolder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := NewGit(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("expected ErrNotRepository, got %v", err)
+	}
+}
+
+func TestAutoCommitterDebounces(t *testing.T) {
+	dir := initRepo(t)
+	g, err := NewGit(dir)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	results := make(chan string, 4)
+	c := NewAutoCommitter(g, 50*time.Millisecond, false, func(hash string, err error) {
+		if err != nil {
+			t.Errorf("auto-commit: %v", err)
+		}
+		results <- hash
+	})
+	for i := 0; i < 3; i++ {
+		writeFile(t, filepath.Join(dir, "Note.md"), string(rune('a'+i)))
+		c.Touch()
+	}
+	select {
+	case hash := <-results:
+		if hash == "" {
+			t.Fatal("expected a commit")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("auto-commit did not run")
+	}
+	c.Stop(true)
+	if len(results) != 0 {
+		t.Fatalf("expected a single commit for the burst, got %d more", len(results))
+	}
+}
+
+func TestAutoCommitterStopPushesInBackground(t *testing.T) {
+	dir := initRepo(t)
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "--bare", remote},
+		{"-C", dir, "remote", "add", "origin", remote},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	g, err := NewGit(dir)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	results := make(chan error, 1)
+	c := NewAutoCommitter(g, time.Hour, true, func(hash string, err error) {
+		if hash == "" {
+			t.Error("expected a commit")
+		}
+		results <- err
+	})
+	writeFile(t, filepath.Join(dir, "Note.md"), "pending")
+	c.Touch()
+	c.Stop(true)
+
+	// The commit is made before Stop returns
+	if status, _ := g.Status(context.Background()); len(status.Changes) != 0 {
+		t.Fatalf("changes left after Stop: %+v", status.Changes)
+	}
+	select {
+	case err := <-results:
+		if err != nil {
+			t.Fatalf("push after Stop: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("push after Stop did not finish")
+	}
+}
+
+func TestGitCommitAllRunsHooks(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, ".git", "hooks", "pre-commit"), "#!/bin/sh\necho rejected >&2\nexit 1\n")
+	if err := os.Chmod(filepath.Join(dir, ".git", "hooks", "pre-commit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "Ideas.md"), "# Ideas")
+	g, err := NewGit(dir)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	if _, err := g.CommitAll(context.Background(), "Add ideas"); err == nil {
+		t.Fatal("expected the pre-commit hook to reject the commit")
+	}
+}
+
+func TestParseStatusConflicts(t *testing.T) {
+	out := "# branch.head main\x00# branch.upstream origin/main\x00# branch.ab +2 -1\x00" +
+		"u UU N... 100644 100644 100644 100644 a b c Plan.md\x00" +
+		"2 R. N... 100644 100644 100644 a b R100 New name.md\x00Old name.md\x00"
+	status := parseStatus(out)
+	if status.Branch != "main" || status.Ahead != 2 || status.Behind != 1 {
+		t.Fatalf("branch info: %+v", status)
+	}
+	if len(status.Conflicts) != 1 || status.Conflicts[0] != "Plan.md" {
+		t.Fatalf("conflicts: %+v", status.Conflicts)
+	}
+	if len(status.Changes) != 2 || status.Changes[1].Path != "New name.md" || status.Changes[1].OrigPath != "Old name.md" {
+		t.Fatalf("rename: %+v", status.Changes)
+	}
+}
@@ -0,0 +1,418 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"notebit/pkg/merge"
+)
+
+// FileState is what a file looked like on both sides after it was last synced
+type FileState struct {
+	Path       string
+	LocalHash  string
+	RemoteETag string
+	SyncedAt   time.Time
+}
+
+// StateStore persists FileStates between syncs, e.g. in the vault database
+type StateStore interface {
+	ListSyncStates() ([]FileState, error)
+	SaveSyncState(state FileState) error
+	DeleteSyncState(path string) error
+}
+
+// SyncResult reports what a sync changed
+type SyncResult struct {
+	Uploaded       []string  `json:"uploaded"`
+	Downloaded     []string  `json:"downloaded"`
+	DeletedLocal   []string  `json:"deleted_local"`
+	DeletedRemote  []string  `json:"deleted_remote"`
+	Merged         []string  `json:"merged"`    // changed on both sides and merged cleanly
+	Conflicts      []string  `json:"conflicts"` // conflict copies written next to the note
+	Errors         []string  `json:"errors"`
+	StartedAt      time.Time `json:"started_at"`
+	DurationMillis int64     `json:"duration_ms"`
+}
+
+// Changed reports whether the sync touched any local file
+func (r *SyncResult) Changed() bool {
+	return len(r.Downloaded)+len(r.DeletedLocal)+len(r.Merged)+len(r.Conflicts) > 0
+}
+
+// Mirror keeps a vault and a WebDAV collection in step. Changes are detected
+// against the state of the last sync: local files by content hash, remote
+// files by etag. When both sides changed, notes are merged line by line
+// against the last synced version (see KeepBases); when that is not possible
+// the remote version is saved as a conflict copy and the local version wins.
+type Mirror struct {
+	dir      string
+	remote   *WebDAV
+	state    StateStore
+	folders  []string
+	basesDir string
+	now      func() time.Time
+}
+
+// NewMirror syncs the vault at dir with remote. folders limits the sync to
+// those vault folders; empty means the whole vault.
+func NewMirror(dir string, remote *WebDAV, state StateStore, folders []string) *Mirror {
+	var cleaned []string
+	for _, f := range folders {
+		if f = strings.Trim(filepath.ToSlash(strings.TrimSpace(f)), "/"); f != "" {
+			cleaned = append(cleaned, f)
+		}
+	}
+	return &Mirror{dir: dir, remote: remote, state: state, folders: cleaned, now: time.Now}
+}
+
+// KeepBases stores the last synced version of each note below dir, so
+// notes changed on both sides can be merged instead of copied
+func (m *Mirror) KeepBases(dir string) {
+	m.basesDir = dir
+}
+
+// included reports whether a vault relative path takes part in the sync
+func (m *Mirror) included(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	if rel == "data" || strings.HasPrefix(rel, "data/") {
+		return false // the vault's own databases
+	}
+	if len(m.folders) == 0 {
+		return true
+	}
+	for _, f := range m.folders {
+		if rel == f || strings.HasPrefix(rel, f+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type localFile struct {
+	hash string
+}
+
+func (m *Mirror) scanLocal() (map[string]localFile, error) {
+	local := make(map[string]localFile)
+	err := filepath.WalkDir(m.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == m.dir {
+			return nil
+		}
+		rel, err := filepath.Rel(m.dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || rel == "data" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !m.included(rel) {
+			return nil
+		}
+		hash, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		local[rel] = localFile{hash: hash}
+		return nil
+	})
+	return local, err
+}
+
+func hashFile(p string) (string, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sync runs one two-way sync. Errors on single files are collected in the
+// result; an error is returned only when the sync could not run at all.
+func (m *Mirror) Sync(ctx context.Context) (*SyncResult, error) {
+	result := &SyncResult{
+		Uploaded: []string{}, Downloaded: []string{}, DeletedLocal: []string{},
+		DeletedRemote: []string{}, Merged: []string{}, Conflicts: []string{}, Errors: []string{},
+		StartedAt: m.now(),
+	}
+	local, err := m.scanLocal()
+	if err != nil {
+		return nil, fmt.Errorf("scan vault: %w", err)
+	}
+	remoteFiles, remoteDirs, err := m.remote.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote := make(map[string]RemoteFile, len(remoteFiles))
+	for _, f := range remoteFiles {
+		if m.included(f.Path) {
+			remote[f.Path] = f
+		}
+	}
+	states, err := m.state.ListSyncStates()
+	if err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	known := make(map[string]FileState, len(states))
+	for _, s := range states {
+		known[s.Path] = s
+	}
+
+	paths := make(map[string]bool)
+	for p := range local {
+		paths[p] = true
+	}
+	for p := range remote {
+		paths[p] = true
+	}
+	for p := range known {
+		if m.included(p) {
+			paths[p] = true
+		}
+	}
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	for _, p := range sorted {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		l, hasLocal := local[p]
+		r, hasRemote := remote[p]
+		s, hasState := known[p]
+		if err := m.syncFile(ctx, p, l, hasLocal, r, hasRemote, s, hasState, remoteDirs, result); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", p, err))
+		}
+	}
+	result.DurationMillis = m.now().Sub(result.StartedAt).Milliseconds()
+	return result, nil
+}
+
+func (m *Mirror) syncFile(ctx context.Context, p string, l localFile, hasLocal bool, r RemoteFile, hasRemote bool, s FileState, hasState bool, dirs map[string]bool, result *SyncResult) error {
+	localChanged := hasLocal && (!hasState || l.hash != s.LocalHash)
+	// Servers that send no etag on upload get the listed one as the baseline
+	remoteChanged := hasRemote && (!hasState || (s.RemoteETag != "" && r.ETag != s.RemoteETag))
+
+	switch {
+	case !hasLocal && !hasRemote:
+		return m.forget(p)
+
+	case hasLocal && !hasRemote:
+		if hasState && !localChanged {
+			// Deleted on the server since the last sync
+			if err := os.Remove(m.localPath(p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			result.DeletedLocal = append(result.DeletedLocal, p)
+			return m.forget(p)
+		}
+		return m.upload(ctx, p, "", dirs, result)
+
+	case !hasLocal && hasRemote:
+		if hasState && !remoteChanged {
+			// Deleted locally since the last sync
+			if err := m.remote.Delete(ctx, p, r.ETag); err != nil {
+				return err
+			}
+			result.DeletedRemote = append(result.DeletedRemote, p)
+			return m.forget(p)
+		}
+		return m.download(ctx, p, result)
+
+	case localChanged && remoteChanged:
+		return m.resolveConflict(ctx, p, l, r, dirs, result)
+	case localChanged:
+		return m.upload(ctx, p, r.ETag, dirs, result)
+	case remoteChanged:
+		return m.download(ctx, p, result)
+	case s.RemoteETag == "" && r.ETag != "":
+		s.RemoteETag = r.ETag
+		return m.state.SaveSyncState(s)
+	}
+	return nil
+}
+
+func (m *Mirror) localPath(rel string) string {
+	return filepath.Join(m.dir, filepath.FromSlash(rel))
+}
+
+func (m *Mirror) upload(ctx context.Context, p, ifMatch string, dirs map[string]bool, result *SyncResult) error {
+	data, err := os.ReadFile(m.localPath(p))
+	if err != nil {
+		return err
+	}
+	if err := m.remote.MkdirAll(ctx, p, dirs); err != nil {
+		return err
+	}
+	etag, err := m.remote.Put(ctx, p, data, ifMatch)
+	if err != nil {
+		return err
+	}
+	result.Uploaded = append(result.Uploaded, p)
+	return m.synced(p, data, etag)
+}
+
+// download replaces the local copy of p with the remote file
+func (m *Mirror) download(ctx context.Context, p string, result *SyncResult) error {
+	data, etag, err := m.remote.Get(ctx, p)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(m.localPath(p), data); err != nil {
+		return err
+	}
+	result.Downloaded = append(result.Downloaded, p)
+	return m.synced(p, data, etag)
+}
+
+// synced records p as identical on both sides with content data
+func (m *Mirror) synced(p string, data []byte, etag string) error {
+	if bp := m.basePath(p); bp != "" {
+		if err := writeFileAtomic(bp, data); err != nil {
+			return err
+		}
+	}
+	return m.state.SaveSyncState(FileState{Path: p, LocalHash: hashBytes(data), RemoteETag: etag, SyncedAt: m.now()})
+}
+
+// forget drops p, gone from both sides, from the sync state
+func (m *Mirror) forget(p string) error {
+	if bp := m.basePath(p); bp != "" {
+		if err := os.Remove(bp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return m.state.DeleteSyncState(p)
+}
+
+// basePath is where the last synced version of note p is kept, or "" when
+// bases are not kept for p
+func (m *Mirror) basePath(p string) string {
+	if m.basesDir == "" || !strings.EqualFold(path.Ext(p), ".md") {
+		return ""
+	}
+	return filepath.Join(m.basesDir, filepath.FromSlash(p))
+}
+
+// resolveConflict handles a file changed on both sides. Identical contents
+// just record the new state and notes with a base are merged; otherwise the
+// remote version is kept as a conflict copy and the local version is uploaded.
+func (m *Mirror) resolveConflict(ctx context.Context, p string, l localFile, r RemoteFile, dirs map[string]bool, result *SyncResult) error {
+	data, etag, err := m.remote.Get(ctx, p)
+	if err != nil {
+		return err
+	}
+	if hashBytes(data) == l.hash {
+		return m.synced(p, data, etagOr(etag, r.ETag))
+	}
+	if merged, ok := m.merge(p, data); ok {
+		if err := writeFileAtomic(m.localPath(p), merged); err != nil {
+			return err
+		}
+		if err := m.upload(ctx, p, etag, dirs, result); err != nil {
+			return err
+		}
+		result.Merged = append(result.Merged, p)
+		return nil
+	}
+	copyPath := m.conflictPath(p)
+	if err := writeFileAtomic(m.localPath(copyPath), data); err != nil {
+		return err
+	}
+	result.Conflicts = append(result.Conflicts, copyPath)
+	return m.upload(ctx, p, "", dirs, result)
+}
+
+// merge three-way merges the local note p with remote content against the
+// kept base, reporting false when there is no base or the edits overlap
+func (m *Mirror) merge(p string, remote []byte) ([]byte, bool) {
+	bp := m.basePath(p)
+	if bp == "" {
+		return nil, false
+	}
+	base, err := os.ReadFile(bp)
+	if err != nil {
+		return nil, false
+	}
+	local, err := os.ReadFile(m.localPath(p))
+	if err != nil {
+		return nil, false
+	}
+	result := merge.Merge(string(base), string(local), string(remote))
+	if !result.Clean() {
+		return nil, false
+	}
+	return []byte(result.Merged), true
+}
+
+func etagOr(etag, fallback string) string {
+	if etag != "" {
+		return etag
+	}
+	return fallback
+}
+
+// conflictPath names the conflict copy of p, e.g.
+// "Plan (conflict 2026-01-02 1504).md"
+func (m *Mirror) conflictPath(p string) string {
+	ext := path.Ext(p)
+	base := strings.TrimSuffix(p, ext)
+	stamp := m.now().Format("2006-01-02 1504")
+	candidate := fmt.Sprintf("%s (conflict %s)%s", base, stamp, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(m.localPath(candidate)); errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (conflict %s %d)%s", base, stamp, i, ext)
+	}
+}
+
+// writeFileAtomic replaces a file through a temporary file so a failed
+// download never leaves a truncated note
+func writeFileAtomic(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".sync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
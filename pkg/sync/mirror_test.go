@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+type memState map[string]FileState
+
+func (m memState) ListSyncStates() ([]FileState, error) {
+	states := make([]FileState, 0, len(m))
+	for _, s := range m {
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+func (m memState) SaveSyncState(s FileState) error { m[s.Path] = s; return nil }
+
+func (m memState) DeleteSyncState(path string) error { delete(m, path); return nil }
+
+func newTestMirror(t *testing.T, folders ...string) (*Mirror, *WebDAV, string) {
+	t.Helper()
+	srv := httptest.NewServer(&webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()})
+	t.Cleanup(srv.Close)
+	remote, err := NewWebDAV(srv.URL+"/Notes", "", "", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	dir := t.TempDir()
+	return NewMirror(dir, remote, memState{}, folders), remote, dir
+}
+
+func mustSync(t *testing.T, m *Mirror) *SyncResult {
+	t.Helper()
+	result, err := m.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("sync errors: %v", result.Errors)
+	}
+	return result
+}
+
+func readRemote(t *testing.T, remote *WebDAV, p string) string {
+	t.Helper()
+	data, _, err := remote.Get(context.Background(), p)
+	if err != nil {
+		t.Fatalf("get %s: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestMirrorUploadsDownloadsAndDeletes(t *testing.T) {
+	m, remote, dir := newTestMirror(t)
+	ctx := context.Background()
+	writeFile(t, filepath.Join(dir, "Projects", "Plan one.md"), "plan")
+	writeFile(t, filepath.Join(dir, "data", "notebit.sqlite"), "db")
+	writeFile(t, filepath.Join(dir, ".obsidian", "app.json"), "{}")
+
+	result := mustSync(t, m)
+	if len(result.Uploaded) != 1 || result.Uploaded[0] != "Projects/Plan one.md" {
+		t.Fatalf("uploaded: %v", result.Uploaded)
+	}
+	if got := readRemote(t, remote, "Projects/Plan one.md"); got != "plan" {
+		t.Fatalf("remote content %q", got)
+	}
+
+	// Nothing changed: nothing to do
+	if result = mustSync(t, m); len(result.Uploaded)+len(result.Downloaded) != 0 {
+		t.Fatalf("expected no transfers, got %+v", result)
+	}
+
+	// A file added on another machine comes down
+	if _, err := remote.Put(ctx, "Inbox.md", []byte("from phone"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if result = mustSync(t, m); len(result.Downloaded) != 1 {
+		t.Fatalf("downloaded: %v", result.Downloaded)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "Inbox.md")); string(data) != "from phone" {
+		t.Fatalf("local content %q", data)
+	}
+
+	// Deleting locally deletes remotely, and the other way round
+	os.Remove(filepath.Join(dir, "Inbox.md"))
+	if err := remote.Delete(ctx, "Projects/Plan one.md", ""); err != nil {
+		t.Fatal(err)
+	}
+	result = mustSync(t, m)
+	if len(result.DeletedRemote) != 1 || len(result.DeletedLocal) != 1 {
+		t.Fatalf("deletes: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Projects", "Plan one.md")); !os.IsNotExist(err) {
+		t.Fatal("expected local delete")
+	}
+}
+
+func TestMirrorKeepsConflictCopy(t *testing.T) {
+	m, remote, dir := newTestMirror(t)
+	ctx := context.Background()
+	writeFile(t, filepath.Join(dir, "Plan.md"), "v1")
+	mustSync(t, m)
+
+	writeFile(t, filepath.Join(dir, "Plan.md"), "local edit")
+	if _, err := remote.Put(ctx, "Plan.md", []byte("remote edit"), ""); err != nil {
+		t.Fatal(err)
+	}
+	result := mustSync(t, m)
+	if len(result.Conflicts) != 1 || !strings.HasPrefix(result.Conflicts[0], "Plan (conflict ") {
+		t.Fatalf("conflicts: %v", result.Conflicts)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, result.Conflicts[0])); string(data) != "remote edit" {
+		t.Fatalf("conflict copy %q", data)
+	}
+	if got := readRemote(t, remote, "Plan.md"); got != "local edit" {
+		t.Fatalf("local version should win, remote has %q", got)
+	}
+}
+
+func TestMirrorSelectiveFolders(t *testing.T) {
+	m, remote, dir := newTestMirror(t, "Work/")
+	writeFile(t, filepath.Join(dir, "Work", "a.md"), "a")
+	writeFile(t, filepath.Join(dir, "Private", "b.md"), "b")
+	if _, err := remote.Put(context.Background(), "Other.md", []byte("o"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	result := mustSync(t, m)
+	if len(result.Uploaded) != 1 || result.Uploaded[0] != "Work/a.md" || len(result.Downloaded) != 0 {
+		t.Fatalf("unexpected transfers: %+v", result)
+	}
+}
+
+func TestMirrorMergesNonOverlappingEdits(t *testing.T) {
+	m, remote, dir := newTestMirror(t)
+	m.KeepBases(filepath.Join(dir, "data", "sync-base"))
+	ctx := context.Background()
+	writeFile(t, filepath.Join(dir, "Plan.md"), "one\ntwo\nthree\n")
+	mustSync(t, m)
+
+	writeFile(t, filepath.Join(dir, "Plan.md"), "one local\ntwo\nthree\n")
+	if _, err := remote.Put(ctx, "Plan.md", []byte("one\ntwo\nthree remote\n"), ""); err != nil {
+		t.Fatal(err)
+	}
+	result := mustSync(t, m)
+	if len(result.Merged) != 1 || len(result.Conflicts) != 0 {
+		t.Fatalf("expected a clean merge: %+v", result)
+	}
+	want := "one local\ntwo\nthree remote\n"
+	if data, _ := os.ReadFile(filepath.Join(dir, "Plan.md")); string(data) != want {
+		t.Fatalf("local content %q", data)
+	}
+	if got := readRemote(t, remote, "Plan.md"); got != want {
+		t.Fatalf("remote content %q", got)
+	}
+}
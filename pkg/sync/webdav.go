@@ -0,0 +1,266 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrPreconditionFailed is returned when a remote file changed since it was
+// last listed
+var ErrPreconditionFailed = errors.New("remote file changed")
+
+// RemoteFile is one file on the WebDAV server
+type RemoteFile struct {
+	Path string // relative to the client root, slash separated
+	ETag string
+	Size int64
+}
+
+// WebDAV is a minimal WebDAV client rooted at a collection URL, e.g. a
+// Nextcloud folder at https://host/remote.php/dav/files/<user>/Notes
+type WebDAV struct {
+	root     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewWebDAV creates a client for the collection at rawURL. client is used
+// for every request so the proxy settings apply.
+func NewWebDAV(rawURL, username, password string, client *http.Client) (*WebDAV, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid WebDAV URL: %q", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebDAV{root: u, username: username, password: password, client: client}, nil
+}
+
+func (w *WebDAV) url(rel string) string {
+	u := *w.root
+	u.Path = w.root.Path + strings.TrimPrefix(rel, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+func (w *WebDAV) do(ctx context.Context, method, rel string, body io.Reader, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, w.url(rel), body)
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" || w.password != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webdav %s %s: %w", method, rel, err)
+	}
+	return resp, nil
+}
+
+func statusError(method, rel string, resp *http.Response) error {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("webdav %s %s: %s", method, rel, resp.Status)
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getetag/><d:getcontentlength/></d:prop></d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ETag          string `xml:"getetag"`
+				ContentLength int64  `xml:"getcontentlength"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// Check verifies the root collection is reachable, creating it if missing
+func (w *WebDAV) Check(ctx context.Context) error {
+	resp, err := w.do(ctx, "PROPFIND", "", strings.NewReader(propfindBody), map[string]string{"Depth": "0", "Content-Type": "application/xml"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusMultiStatus, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return w.Mkdir(ctx, "")
+	default:
+		return statusError("PROPFIND", "/", resp)
+	}
+}
+
+// List returns every file below the root and the set of folders seen. Folders
+// are walked one level at a time since many servers refuse Depth: infinity.
+func (w *WebDAV) List(ctx context.Context) ([]RemoteFile, map[string]bool, error) {
+	var files []RemoteFile
+	dirs := map[string]bool{"": true}
+	queue := []string{""}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		resp, err := w.do(ctx, "PROPFIND", dir, strings.NewReader(propfindBody), map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode != http.StatusMultiStatus {
+			err := statusError("PROPFIND", dir, resp)
+			resp.Body.Close()
+			return nil, nil, err
+		}
+		var ms multistatus
+		err = xml.NewDecoder(resp.Body).Decode(&ms)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("webdav PROPFIND %s: %w", dir, err)
+		}
+		for _, r := range ms.Responses {
+			rel, ok := w.relative(r.Href)
+			if !ok || rel == strings.TrimSuffix(dir, "/") {
+				continue
+			}
+			for _, ps := range r.Propstat {
+				if !strings.Contains(ps.Status, " 200 ") {
+					continue
+				}
+				if ps.Prop.ResourceType.Collection != nil {
+					if !dirs[rel] {
+						dirs[rel] = true
+						queue = append(queue, rel+"/")
+					}
+				} else {
+					files = append(files, RemoteFile{Path: rel, ETag: ps.Prop.ETag, Size: ps.Prop.ContentLength})
+				}
+				break
+			}
+		}
+	}
+	return files, dirs, nil
+}
+
+// relative maps an href from a multistatus response to a root relative path
+func (w *WebDAV) relative(href string) (string, bool) {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path // absolute URLs and already decoded paths
+	}
+	if !strings.HasPrefix(href, w.root.Path) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(href, w.root.Path), "/"), true
+}
+
+// Get downloads a file and returns its content and etag
+func (w *WebDAV) Get(ctx context.Context, rel string) ([]byte, string, error) {
+	resp, err := w.do(ctx, http.MethodGet, rel, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", statusError("GET", rel, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header.Get("ETag"), err
+}
+
+// Put uploads a file. With ifMatch set the upload fails with
+// ErrPreconditionFailed if the remote etag differs. It returns the new etag
+// when the server reports one.
+func (w *WebDAV) Put(ctx context.Context, rel string, data []byte, ifMatch string) (string, error) {
+	header := map[string]string{"Content-Type": "application/octet-stream"}
+	if ifMatch != "" {
+		header["If-Match"] = ifMatch
+	}
+	resp, err := w.do(ctx, http.MethodPut, rel, bytes.NewReader(data), header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return resp.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		return "", ErrPreconditionFailed
+	default:
+		return "", statusError("PUT", rel, resp)
+	}
+}
+
+// Delete removes a file. A file that is already gone is not an error.
+func (w *WebDAV) Delete(ctx context.Context, rel, ifMatch string) error {
+	var header map[string]string
+	if ifMatch != "" {
+		header = map[string]string{"If-Match": ifMatch}
+	}
+	resp, err := w.do(ctx, http.MethodDelete, rel, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	default:
+		return statusError("DELETE", rel, resp)
+	}
+}
+
+// Mkdir creates a collection
+func (w *WebDAV) Mkdir(ctx context.Context, rel string) error {
+	if rel != "" {
+		rel = strings.TrimSuffix(rel, "/") + "/"
+	}
+	resp, err := w.do(ctx, "MKCOL", rel, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 405 means the collection already exists
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil
+	}
+	return statusError("MKCOL", rel, resp)
+}
+
+// MkdirAll creates the parent folders of rel not in known, adding them to it
+func (w *WebDAV) MkdirAll(ctx context.Context, rel string, known map[string]bool) error {
+	dir := path.Dir(rel)
+	if dir == "." || known[dir] {
+		return nil
+	}
+	if err := w.MkdirAll(ctx, dir, known); err != nil {
+		return err
+	}
+	if err := w.Mkdir(ctx, dir); err != nil {
+		return err
+	}
+	known[dir] = true
+	return nil
+}
@@ -0,0 +1,359 @@
+package urlmeta
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements hold no readable page text
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Svg: true, atom.Iframe: true,
+	atom.Figure: true, atom.Dialog: true, atom.Head: true,
+}
+
+// skipped reports whether n holds no readable page text. A header is only
+// skipped at page level: inside an article, section or main it holds the
+// title of that content.
+func skipped(n *html.Node) bool {
+	if n.DataAtom != atom.Header {
+		return skippedElements[n.DataAtom]
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		switch p.DataAtom {
+		case atom.Article, atom.Section, atom.Main:
+			return false
+		}
+	}
+	return true
+}
+
+// extract fills meta from the head and main text of doc, loaded from base
+func extract(doc *html.Node, base *url.URL, meta *Metadata) {
+	props := make(map[string]string)
+	var title, canonical string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if title == "" {
+					title = collapse(textOf(n))
+				}
+			case atom.Meta:
+				key := strings.ToLower(attr(n, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(n, "name"))
+				}
+				if content := collapse(attr(n, "content")); key != "" && content != "" {
+					if _, ok := props[key]; !ok {
+						props[key] = content
+					}
+				}
+			case atom.Link:
+				if canonical == "" && hasToken(attr(n, "rel"), "canonical") {
+					canonical = attr(n, "href")
+				}
+			case atom.Body:
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	meta.Title = first(props["og:title"], props["twitter:title"], title, meta.Title)
+	meta.Description = first(props["og:description"], props["description"], props["twitter:description"])
+	meta.SiteName = first(props["og:site_name"], props["application-name"], meta.SiteName)
+	if image := first(props["og:image"], props["twitter:image"]); image != "" {
+		meta.Image = resolve(base, image)
+	}
+	if c := first(props["og:url"], canonical); c != "" {
+		if resolved := resolve(base, c); strings.HasPrefix(resolved, "http") {
+			meta.FinalURL = resolved
+		}
+	}
+	meta.Excerpt = markdown(mainContent(doc), base, maxExcerptRunes)
+}
+
+// mainContent returns the element most likely to hold the page text: the
+// first article, else main, else body
+func mainContent(doc *html.Node) *html.Node {
+	var article, main, body *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.DataAtom == atom.Article && article == nil:
+				article = n
+			case (n.DataAtom == atom.Main || attr(n, "role") == "main") && main == nil:
+				main = n
+			case n.DataAtom == atom.Body && body == nil:
+				body = n
+			}
+			if skipped(n) {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil && article == nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	switch {
+	case article != nil:
+		return article
+	case main != nil:
+		return main
+	default:
+		return body
+	}
+}
+
+// blockElements start a new Markdown block; other elements are inline
+var blockElements = map[atom.Atom]bool{
+	atom.Html: true, atom.Body: true, atom.P: true, atom.Div: true, atom.Section: true,
+	atom.Article: true, atom.Main: true, atom.Header: true, atom.Center: true, atom.Address: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Hr: true, atom.Table: true,
+	atom.Thead: true, atom.Tbody: true, atom.Tfoot: true, atom.Tr: true, atom.Td: true,
+	atom.Th: true, atom.Caption: true, atom.Details: true, atom.Summary: true,
+	atom.Figcaption: true,
+}
+
+// HTMLToMarkdown converts an HTML document or fragment, such as the content
+// of a feed entry, to Markdown. Relative links are resolved against baseURL.
+// The result stops at a block boundary once maxRunes is reached; 0 means no
+// limit.
+func HTMLToMarkdown(fragment, baseURL string, maxRunes int) string {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		base = &url.URL{}
+	}
+	return markdown(doc, base, maxRunes)
+}
+
+// markdown renders the leading blocks of root as Markdown, stopping at a
+// block boundary once limit runes are reached (0 = no limit)
+func markdown(root *html.Node, base *url.URL, limit int) string {
+	if root == nil {
+		return ""
+	}
+	var blocks []string
+	size := 0
+	add := func(block string) bool {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			return true
+		}
+		n := len([]rune(block))
+		if limit <= 0 {
+			blocks = append(blocks, block)
+			return true
+		}
+		if size > 0 && size+n > limit {
+			return false
+		}
+		if n > limit {
+			block = string([]rune(block)[:limit]) + "…"
+		}
+		blocks = append(blocks, block)
+		size += n
+		return size < limit
+	}
+
+	// run collects inline content between blocks, e.g. text directly in a div
+	var run []*html.Node
+	flush := func() bool {
+		var b strings.Builder
+		for _, n := range run {
+			b.WriteString(inline(n, base))
+		}
+		run = run[:0]
+		return add(collapse(b.String()))
+	}
+
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		switch n.Type {
+		case html.TextNode:
+			run = append(run, n)
+			return true
+		case html.ElementNode, html.DocumentNode:
+		default:
+			return true
+		}
+		if skipped(n) || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" {
+			return true
+		}
+		if n.Type == html.ElementNode && !blockElements[n.DataAtom] {
+			run = append(run, n)
+			return true
+		}
+		if !flush() {
+			return false
+		}
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			level := int(n.Data[1] - '0')
+			return add(strings.Repeat("#", level) + " " + collapse(inline(n, base)))
+		case atom.P:
+			return add(collapse(inline(n, base)))
+		case atom.Blockquote:
+			return add("> " + collapse(inline(n, base)))
+		case atom.Pre:
+			return add("```\n" + strings.Trim(textOf(n), "\n") + "\n```")
+		case atom.Hr:
+			return add("---")
+		case atom.Ul, atom.Ol:
+			var items []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && c.DataAtom == atom.Li {
+					marker := "- "
+					if n.DataAtom == atom.Ol {
+						marker = "1. "
+					}
+					if text := collapse(inline(c, base)); text != "" {
+						items = append(items, marker+text)
+					}
+				}
+			}
+			return add(strings.Join(items, "\n"))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if !walk(c) {
+				return false
+			}
+		}
+		return flush()
+	}
+	walk(root)
+	return strings.Join(blocks, "\n\n")
+}
+
+// inline renders the inline content of n as Markdown
+func inline(n *html.Node, base *url.URL) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case html.ElementNode:
+		default:
+			return
+		}
+		if skipped(n) {
+			return
+		}
+		switch n.DataAtom {
+		case atom.Br:
+			b.WriteString(" ")
+			return
+		case atom.Img:
+			return
+		case atom.A:
+			text := collapse(textOf(n))
+			href := attr(n, "href")
+			if text == "" {
+				return
+			}
+			if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+				b.WriteString(text)
+				return
+			}
+			b.WriteString("[" + text + "](" + resolve(base, href) + ")")
+			return
+		case atom.Code:
+			b.WriteString("`" + textOf(n) + "`")
+			return
+		case atom.Strong, atom.B:
+			if text := collapse(textOf(n)); text != "" {
+				b.WriteString("**" + text + "**")
+			}
+			return
+		case atom.Em, atom.I:
+			if text := collapse(textOf(n)); text != "" {
+				b.WriteString("*" + text + "*")
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// textOf returns the text inside n, without script and style content
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style) {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// collapse replaces runs of whitespace with single spaces
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
@@ -0,0 +1,341 @@
+// Package urlmeta fetches the metadata of web pages, their title,
+// description, site name and a Markdown excerpt, for bookmark notes.
+package urlmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// fetchTimeout bounds one page fetch
+	fetchTimeout = 15 * time.Second
+	// maxPageBytes bounds how much of a page is read
+	maxPageBytes = 2 << 20
+	// maxExcerptRunes bounds the Markdown excerpt
+	maxExcerptRunes = 1500
+	// hostInterval is the least time between two requests to one host
+	hostInterval = 2 * time.Second
+	// cacheTTL is how long fetched metadata is reused
+	cacheTTL = 10 * time.Minute
+	// maxCacheEntries bounds the metadata cache
+	maxCacheEntries = 200
+)
+
+const userAgent = "Mozilla/5.0 (compatible; Notebit bookmark fetcher)"
+
+// maxRedirects bounds the redirects followed for one page
+const maxRedirects = 10
+
+// ErrInvalidURL is returned for URLs that are not absolute http(s) URLs
+var ErrInvalidURL = errors.New("invalid URL")
+
+// errPrivateAddress is returned for pages on loopback, link-local or private
+// networks, which a pasted link must not be able to reach
+var errPrivateAddress = errors.New("refusing to fetch a local or private network address")
+
+// Metadata describes a web page
+type Metadata struct {
+	URL         string `json:"url"`       // The URL that was asked for
+	FinalURL    string `json:"final_url"` // Canonical URL, or the URL after redirects
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	SiteName    string `json:"site_name"`
+	Image       string `json:"image"`   // Absolute URL of the preview image
+	Excerpt     string `json:"excerpt"` // Start of the main text as Markdown
+	ContentType string `json:"content_type"`
+	// Partial is set when the page could not be fetched or read; the other
+	// fields then hold what can be derived from the URL alone
+	Partial bool   `json:"partial"`
+	Error   string `json:"error,omitempty"`
+	Fetched int64  `json:"fetched"` // Unix ms
+}
+
+type cacheEntry struct {
+	meta    *Metadata
+	expires time.Time
+}
+
+// Fetcher fetches page metadata. It waits between requests to one host and
+// remembers recent results, so repeated pastes of a link stay cheap.
+type Fetcher struct {
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	next  map[string]time.Time // host -> earliest time of the next request
+	cache map[string]cacheEntry
+}
+
+// NewFetcher creates a fetcher using client, or when nil a default client
+// that refuses local and private network addresses
+func NewFetcher(client *http.Client) *Fetcher {
+	if client == nil {
+		client = publicClient()
+	}
+	return &Fetcher{
+		client: client,
+		now:    time.Now,
+		next:   make(map[string]time.Time),
+		cache:  make(map[string]cacheEntry),
+	}
+}
+
+// Fetch returns the metadata of the page at rawURL. Only invalid URLs and a
+// cancelled ctx are errors: when the page cannot be fetched, e.g. offline,
+// Fetch returns partial metadata derived from the URL with Error set.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	key := u.String()
+	if meta := f.cached(key); meta != nil {
+		return meta, nil
+	}
+
+	if err := f.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+	meta, err := f.fetch(ctx, u)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		meta = fallback(u)
+		meta.Error = describeError(err)
+		meta.Fetched = f.now().UnixMilli()
+		// Failures are not cached, so a retry once back online succeeds
+		return meta, nil
+	}
+	meta.Fetched = f.now().UnixMilli()
+	f.store(key, meta)
+	return meta, nil
+}
+
+// publicClient returns a client that only connects to public addresses. The
+// dialer checks every resolved address, so a host name pointing at the local
+// network is refused as well as a redirect to one.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivate}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy resolves hosts itself, out of reach of the dialer check
+	transport.Proxy = nil
+	return &http.Client{Timeout: fetchTimeout, Transport: transport, CheckRedirect: checkRedirect}
+}
+
+// checkRedirect refuses redirects to local addresses known without
+// resolving the host; the dialer catches the rest
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	host := req.URL.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// refusePrivate is a net.Dialer Control function refusing connections to
+// local and private addresses
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// parseURL accepts absolute http(s) URLs and bare domains like example.com/a
+func parseURL(rawURL string) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, ErrInvalidURL
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	// Credentials in a link are not fetched, and catch mailto: addresses
+	// taken for bare domains
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawURL)
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u, nil
+}
+
+func (f *Fetcher) cached(key string) *Metadata {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.cache[key]
+	if !ok || f.now().After(entry.expires) {
+		delete(f.cache, key)
+		return nil
+	}
+	meta := *entry.meta
+	return &meta
+}
+
+func (f *Fetcher) store(key string, meta *Metadata) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if len(f.cache) >= maxCacheEntries {
+		for k, entry := range f.cache {
+			if now.After(entry.expires) {
+				delete(f.cache, k)
+			}
+		}
+		if len(f.cache) >= maxCacheEntries {
+			// Still full of live entries: drop an arbitrary one
+			for k := range f.cache {
+				delete(f.cache, k)
+				break
+			}
+		}
+	}
+	stored := *meta
+	f.cache[key] = cacheEntry{meta: &stored, expires: now.Add(cacheTTL)}
+}
+
+// wait reserves the next request slot of host and sleeps until it
+func (f *Fetcher) wait(ctx context.Context, host string) error {
+	f.mu.Lock()
+	now := f.now()
+	at := f.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	f.next[host] = at.Add(hostInterval)
+	for h, t := range f.next {
+		if t.Before(now) {
+			delete(f.next, h)
+		}
+	}
+	f.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (f *Fetcher) fetch(ctx context.Context, u *url.URL) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	meta := fallback(u)
+	meta.Partial = false
+	meta.FinalURL = resp.Request.URL.String()
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	meta.ContentType = mediaType
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		// A PDF, image or the like: the URL is all there is to describe it
+		return meta, nil
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxPageBytes), contentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+	extract(doc, resp.Request.URL, meta)
+	return meta, nil
+}
+
+// fallback is the metadata that can be derived from the URL alone
+func fallback(u *url.URL) *Metadata {
+	site := strings.TrimPrefix(u.Hostname(), "www.")
+	title := site
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		last := p[strings.LastIndex(p, "/")+1:]
+		if unescaped, err := url.PathUnescape(last); err == nil {
+			last = unescaped
+		}
+		last = strings.TrimSuffix(last, ".html")
+		last = strings.NewReplacer("-", " ", "_", " ").Replace(last)
+		if last = strings.TrimSpace(last); last != "" {
+			title = last
+		}
+	}
+	return &Metadata{
+		URL:      u.String(),
+		FinalURL: u.String(),
+		Title:    title,
+		SiteName: site,
+		Partial:  true,
+	}
+}
+
+// describeError turns a fetch error into a short message for the user
+func describeError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errPrivateAddress):
+		return errPrivateAddress.Error()
+	case errors.As(err, &dnsErr):
+		return "could not resolve host; you may be offline"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,231 @@
+package urlmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testPage = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Designing Data Pipelines">
+<meta name="description" content="  A practical   guide. ">
+<meta property="og:site_name" content="Example Blog">
+<meta property="og:image" content="/img/cover.png">
+<link rel="canonical" href="/posts/pipelines">
+</head><body>
+<nav><a href="/">Home</a></nav>
+<article>
+<h1>Designing Data Pipelines</h1>
+<p>Pipelines move <strong>data</strong> between <a href="/systems">systems</a>.</p>
+<script>var tracking = 1;</script>
+<ul><li>Extract</li><li>Load</li></ul>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestFetch_ExtractsMetadata(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	f := NewFetcher(server.Client())
+	meta, err := f.Fetch(context.Background(), server.URL+"/p?id=1#top")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Partial || meta.Error != "" {
+		t.Fatalf("expected complete metadata, got %+v", meta)
+	}
+	if meta.Title != "Designing Data Pipelines" || meta.Description != "A practical guide." || meta.SiteName != "Example Blog" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if meta.URL != server.URL+"/p?id=1" || meta.FinalURL != server.URL+"/posts/pipelines" || meta.Image != server.URL+"/img/cover.png" {
+		t.Errorf("unexpected URLs: %+v", meta)
+	}
+	want := "# Designing Data Pipelines\n\nPipelines move **data** between [systems](" + server.URL + "/systems).\n\n- Extract\n- Load"
+	if meta.Excerpt != want {
+		t.Errorf("excerpt = %q, want %q", meta.Excerpt, want)
+	}
+
+	if _, err := f.Fetch(context.Background(), server.URL+"/p?id=1"); err != nil {
+		t.Fatalf("second Fetch failed: %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server hit %d times, want once thanks to the cache", n)
+	}
+}
+
+func TestFetch_OfflineReturnsPartialMetadata(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	addr := server.URL
+	server.Close()
+
+	f := NewFetcher(nil)
+	meta, err := f.Fetch(context.Background(), addr+"/notes/my-first_post.html")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if !meta.Partial || meta.Error == "" || meta.Title != "my first post" {
+		t.Errorf("unexpected fallback metadata: %+v", meta)
+	}
+}
+
+func TestFetch_HTTPErrorAndNonHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	}))
+	defer server.Close()
+
+	f := NewFetcher(server.Client())
+	meta, err := f.Fetch(context.Background(), server.URL+"/missing")
+	if err != nil || !meta.Partial || !strings.Contains(meta.Error, "404") {
+		t.Fatalf("expected partial metadata with a 404 error, got %+v, %v", meta, err)
+	}
+	// Another name for the same server, so the request is not spaced out
+	other := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	meta, err = f.Fetch(context.Background(), other+"/paper.pdf")
+	if err != nil || meta.Partial || meta.ContentType != "application/pdf" || meta.Title != "paper.pdf" {
+		t.Fatalf("unexpected metadata of a PDF: %+v, %v", meta, err)
+	}
+}
+
+func TestFetch_RateLimitHonorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	f := NewFetcher(server.Client())
+	if _, err := f.Fetch(context.Background(), server.URL+"/a"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.Fetch(ctx, server.URL+"/b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second request to wait past the deadline, got %v", err)
+	}
+}
+
+func TestFetch_InvalidURL(t *testing.T) {
+	f := NewFetcher(nil)
+	for _, raw := range []string{"", "ftp://example.com/file", "mailto:someone@example.com"} {
+		if _, err := f.Fetch(context.Background(), raw); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Fetch(%q) error = %v, want ErrInvalidURL", raw, err)
+		}
+	}
+}
+
+func TestFetch_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testPage))
+	}))
+	defer server.Close()
+
+	f := NewFetcher(nil)
+	meta, err := f.Fetch(context.Background(), server.URL+"/admin")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if !meta.Partial || meta.Error != errPrivateAddress.Error() {
+		t.Errorf("expected the loopback server to be refused, got %+v", meta)
+	}
+}
+
+func TestFetch_RefusesRedirectToPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The test server itself is on loopback, so only the redirect is checked
+	client := server.Client()
+	client.CheckRedirect = checkRedirect
+	for _, target := range []string{
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/",
+		"http://localhost:8080/",
+		"http://[::1]/",
+	} {
+		f := NewFetcher(client)
+		meta, err := f.Fetch(context.Background(), server.URL+"/r?to="+target)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if !meta.Partial || meta.Error != errPrivateAddress.Error() {
+			t.Errorf("redirect to %s: got %+v", target, meta)
+		}
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	tests := []struct {
+		address string
+		refused bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1::]:443", false},
+		{"127.0.0.1:80", true},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", true},
+		{"192.168.1.1:80", true},
+		{"169.254.169.254:80", true},
+		{"0.0.0.0:80", true},
+		{"[::1]:80", true},
+		{"[fe80::1]:80", true},
+		{"[fd00::1]:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+	}
+	for _, tt := range tests {
+		err := refusePrivate("tcp", tt.address, nil)
+		if refused := errors.Is(err, errPrivateAddress); refused != tt.refused {
+			t.Errorf("refusePrivate(%s) = %v, want refused %v", tt.address, err, tt.refused)
+		}
+	}
+}
+
+func TestHTMLToMarkdown_Headers(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "page header skipped",
+			html: `<header><a href="/">Site</a> menu</header><p>Body text.</p>`,
+			want: "Body text.",
+		},
+		{
+			name: "article header kept",
+			html: `<header>Site</header><article><header><h1>Title</h1><p>By Ann</p></header><p>Body.</p></article>`,
+			want: "# Title\n\nBy Ann\n\nBody.",
+		},
+		{
+			name: "section header kept",
+			html: `<main><section><header><h2>Part</h2></header>Text</section></main>`,
+			want: "## Part\n\nText",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToMarkdown(tt.html, "https://example.com/", 0); got != tt.want {
+				t.Errorf("HTMLToMarkdown = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
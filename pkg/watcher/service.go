@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"notebit/pkg/files"
 	"notebit/pkg/indexing"
 	"notebit/pkg/logger"
 
@@ -31,6 +32,9 @@ type Service struct {
 
 	// Worker pool
 	workerSem chan struct{}
+
+	// audioHandler is told about new or changed audio files
+	audioHandler func(path string)
 }
 
 // FileEvent represents a file system event
@@ -90,6 +94,14 @@ func (s *Service) SetWorkerCount(n int) {
 	s.workerSem = make(chan struct{}, n)
 }
 
+// SetAudioHandler registers fn to be called with the relative path of audio
+// files created or changed in the vault; nil ignores audio files
+func (s *Service) SetAudioHandler(fn func(path string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audioHandler = fn
+}
+
 func (s *Service) SetLogger(logger Logger) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -133,6 +145,7 @@ func (s *Service) Stop() error {
 
 // eventLoop processes fsnotify events
 func (s *Service) eventLoop() {
+	defer logger.RecoverPanic()
 	for {
 		select {
 		case event, ok := <-s.watcher.Events:
@@ -159,6 +172,7 @@ func (s *Service) eventLoop() {
 
 // workerLoop processes file events from the queue
 func (s *Service) workerLoop() {
+	defer logger.RecoverPanic()
 	for {
 		select {
 		case event := <-s.eventQueue:
@@ -177,8 +191,8 @@ func (s *Service) workerLoop() {
 
 // handleEvent handles a single fsnotify event
 func (s *Service) handleEvent(event fsnotify.Event) {
-	// Skip if not a markdown file
-	if !isMarkdownFile(event.Name) {
+	// Skip if not a markdown file, or an audio file someone listens for
+	if !isMarkdownFile(event.Name) && !(files.IsAudioFile(event.Name) && s.handlesAudio()) {
 		return
 	}
 
@@ -239,6 +253,13 @@ func (s *Service) processFile(path string, op fsnotify.Op) {
 		return
 	}
 
+	if !isMarkdownFile(path) {
+		if op&(fsnotify.Create|fsnotify.Write) != 0 {
+			s.handleAudio(path)
+		}
+		return
+	}
+
 	// Handle different operation types
 	switch {
 	case op&fsnotify.Remove == fsnotify.Remove:
@@ -265,6 +286,22 @@ func (s *Service) handleWrite(path string) {
 	})
 }
 
+// handleAudio passes a created or changed audio file to the audio handler
+func (s *Service) handleAudio(path string) {
+	s.mu.RLock()
+	fn := s.audioHandler
+	s.mu.RUnlock()
+	if fn != nil {
+		fn(path)
+	}
+}
+
+func (s *Service) handlesAudio() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.audioHandler != nil
+}
+
 // handleRemove handles file deletion
 func (s *Service) handleRemove(path string) {
 	if s.pipeline == nil {